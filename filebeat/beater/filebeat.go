@@ -139,6 +139,19 @@ func newBeater(b *beat.Beat, plugins PluginFactory, rawConfig *conf.C) (beat.Bea
 				return data
 			})
 
+		b.Manager.RegisterDiagnosticHook("data_path_metrics",
+			"Metrics from active inputs, the publisher pipeline, the queue and the output.",
+			"data_path_metrics.json", "application/json", func() []byte {
+				data, err := inputmon.AllComponentsSnapshotJSON(
+					b.Info.Monitoring.NamespaceRegistry(),
+					b.Info.Monitoring.StatsRegistry.GetRegistry("libbeat"))
+				if err != nil {
+					b.Info.Logger.Warnw("Failed to collect data path metric snapshot for Agent diagnostics.", "error", err)
+					return []byte(err.Error())
+				}
+				return data
+			})
+
 		b.Manager.RegisterDiagnosticHook(
 			"registry",
 			"Filebeat's registry",
@@ -45,7 +45,8 @@ type commonInputConfig struct {
 	KeepNull             bool                    `config:"keep_null"`
 
 	PublisherPipeline struct {
-		DisableHost bool `config:"disable_host"` // Disable addition of host.name.
+		DisableHost     bool `config:"disable_host"`     // Disable addition of host.name.
+		MinimalMetadata bool `config:"minimal_metadata"` // Disable addition of all automatic metadata.
 	} `config:"publisher_pipeline"`
 
 	// implicit event fields
@@ -155,7 +156,8 @@ func newCommonConfigEditor(
 		setOptional(meta, "pipeline", config.Pipeline)
 		setOptional(fields, "fileset.name", config.Fileset)
 		setOptional(fields, "service.type", serviceType)
-		if !clientCfg.Processing.DisableType {
+		minimalMetadata := clientCfg.Processing.MinimalMetadata || config.PublisherPipeline.MinimalMetadata
+		if !clientCfg.Processing.DisableType && !minimalMetadata {
 			setOptional(fields, "input.type", config.Type)
 		}
 		if config.Module != "" {
@@ -187,6 +189,7 @@ func newCommonConfigEditor(
 		clientCfg.Processing.Processor = procs
 		clientCfg.Processing.KeepNull = config.KeepNull
 		clientCfg.Processing.DisableHost = config.PublisherPipeline.DisableHost
+		clientCfg.Processing.MinimalMetadata = minimalMetadata
 
 		return clientCfg, nil
 	}, nil
@@ -195,6 +195,8 @@ func newSaramaConfig(config kafkaInputConfig) (*sarama.Config, error) {
 			Realm:              config.Kerberos.Realm,
 			DisablePAFXFAST:    !config.Kerberos.EnableFAST,
 		}
+	} else if config.Sasl.IsOAuth() {
+		config.Sasl.ConfigureSarama(k)
 	} else if config.Username != "" {
 		k.Net.SASL.Enable = true
 		k.Net.SASL.User = config.Username
@@ -66,6 +66,53 @@ func NewWithDefaultRoutes(log *logp.Logger, config *config.C, reg LookupFunc) (*
 	return api, nil
 }
 
+// HealthFunc reports whether a component is ready, together with details
+// about its state.
+type HealthFunc func() (bool, mapstr.M)
+
+// MakeHealthHandler creates a handler to be used as readiness probe. It
+// responds with 200 if the component is ready, and 503 otherwise. The body
+// contains the details of the component state.
+func MakeHealthHandler(health HealthFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ready, details := health()
+		data := mapstr.M{"ready": ready}
+		data.Update(details)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		prettyPrint(w, data, r.URL)
+	}
+}
+
+// PipelinesFunc reports the processor chains of the publisher pipeline
+// clients.
+type PipelinesFunc func() mapstr.M
+
+// MakePipelinesHandler creates a handler reporting the processors applied by
+// each client connected to the publisher pipeline, for debugging.
+func MakePipelinesHandler(pipelines PipelinesFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		prettyPrint(w, pipelines(), r.URL)
+	}
+}
+
+// ClientsFunc reports the clients connected to the publisher pipeline.
+type ClientsFunc func() mapstr.M
+
+// MakeClientsHandler creates a handler reporting the configuration and the
+// in-flight events of each client connected to the publisher pipeline, for
+// diagnostics.
+func MakeClientsHandler(clients ClientsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		prettyPrint(w, clients(), r.URL)
+	}
+}
+
 func makeRootAPIHandler(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestHealthHandler(t *testing.T) {
+	for name, test := range map[string]struct {
+		ready  bool
+		status int
+	}{
+		"ready":     {ready: true, status: http.StatusOK},
+		"not ready": {ready: false, status: http.StatusServiceUnavailable},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := MakeHealthHandler(func() (bool, mapstr.M) {
+				return test.ready, mapstr.M{"output": mapstr.M{"connected": 1}}
+			})
+
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, test.ready, body["ready"])
+			assert.Equal(t, map[string]interface{}{"connected": float64(1)}, body["output"])
+		})
+	}
+}
+
+func TestPipelinesHandler(t *testing.T) {
+	handler := MakePipelinesHandler(func() mapstr.M {
+		return mapstr.M{"clients": []mapstr.M{
+			{"id": 1, "processors": []string{"add_tags=global"}},
+		}}
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/pipelines", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": float64(1), "processors": []interface{}{"add_tags=global"}},
+	}, body["clients"])
+}
+
+func TestClientsHandler(t *testing.T) {
+	handler := MakeClientsHandler(func() mapstr.M {
+		return mapstr.M{"clients": []mapstr.M{
+			{"id": 1, "publish_mode": "drop_if_full", "processors": []string{}, "in_flight": 3},
+		}}
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/clients", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"id":           float64(1),
+			"publish_mode": "drop_if_full",
+			"processors":   []interface{}{},
+			"in_flight":    float64(3),
+		},
+	}, body["clients"])
+}
@@ -40,6 +40,7 @@ const FlagField = "log.flags"
 const (
 	TimestampFieldKey = "@timestamp"
 	MetadataFieldKey  = "@metadata"
+	SequenceFieldKey  = "@sequence"
 	ErrorFieldKey     = "error"
 	metadataKeyPrefix = MetadataFieldKey + "."
 	metadataKeyOffset = len(metadataKeyPrefix)
@@ -55,6 +56,11 @@ type Event struct {
 	Fields     mapstr.M
 	Private    interface{} // for beats private use
 	TimeSeries bool        // true if the event contains timeseries data
+
+	// Sequence is a per-client sequence number of the event, 0 if not set.
+	// It is kept unchanged when the event is retried, so outputs can use it
+	// for idempotent delivery.
+	Sequence uint64
 }
 
 var (
@@ -76,11 +82,18 @@ func (e *Event) SetID(id string) {
 //
 // Use `@timestamp` key for getting the event timestamp.
 // Use `@metadata.*` keys for getting the event metadata fields.
+// Use `@sequence` key for getting the event sequence number.
 // If `@metadata` key is used then `ErrMetadataAccess` is returned.
 func (e *Event) GetValue(key string) (interface{}, error) {
 	if key == TimestampFieldKey {
 		return e.Timestamp, nil
 	}
+	if key == SequenceFieldKey {
+		if e.Sequence == 0 {
+			return nil, mapstr.ErrKeyNotFound
+		}
+		return e.Sequence, nil
+	}
 	if key == MetadataFieldKey {
 		return nil, ErrMetadataAccess
 	}
@@ -107,6 +120,7 @@ func (e *Event) Clone() *Event {
 		Fields:     e.Fields.Clone(),
 		Private:    e.Private,
 		TimeSeries: e.TimeSeries,
+		Sequence:   e.Sequence,
 	}
 }
 
@@ -46,6 +46,7 @@ func TestEvent(t *testing.T) {
 
 	event := &Event{
 		Timestamp: time.Now(),
+		Sequence:  42,
 		Meta: mapstr.M{
 			"a.b":             "c",
 			"metaLevel0Map":   metadataNestedMap,
@@ -146,6 +147,11 @@ func TestEvent(t *testing.T) {
 				key:  TimestampFieldKey,
 				exp:  event.Timestamp,
 			},
+			{
+				name: SequenceFieldKey,
+				key:  SequenceFieldKey,
+				exp:  uint64(42),
+			},
 			{
 				name:   "no acess to metadata key",
 				key:    MetadataFieldKey,
@@ -18,8 +18,12 @@
 package beat
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	"github.com/jonboulle/clockwork"
+
 	"github.com/elastic/elastic-agent-libs/mapstr"
 )
 
@@ -42,6 +46,68 @@ type Client interface {
 	Close() error
 }
 
+// ContextClient is an optional extension of Client for clients that can bound
+// the time spent publishing a batch of events. Batch oriented inputs can use
+// it to fail fast if the queue does not accept the events in time.
+type ContextClient interface {
+	Client
+
+	// PublishAllWithContext publishes the events in order, until all events
+	// have been published or ctx is done. It returns one PublishResult per
+	// event. If ctx is done before all events have been handed to the queue,
+	// the remaining events are not published and ctx.Err() is returned.
+	PublishAllWithContext(ctx context.Context, events []Event) ([]PublishResult, error)
+}
+
+// PublishResult reports the outcome of publishing a single event with
+// ContextClient.PublishAllWithContext.
+type PublishResult struct {
+	// Published is set if the event entered the queue. If a processor split
+	// the event, all resulting events must have entered the queue.
+	Published bool
+
+	// Filtered is set if the event was dropped by the processors.
+	Filtered bool
+
+	// Err is set if the event could not be published because ctx was done.
+	Err error
+}
+
+// StatsClient is an optional extension of Client for clients that keep track
+// of the delivery of their own events.
+type StatsClient interface {
+	Client
+
+	// Stats returns a consistent snapshot of the client's cumulative event
+	// counts since it connected.
+	Stats() ClientStats
+}
+
+// ClientStats holds the cumulative event counts of a single client.
+type ClientStats struct {
+	// Received is the number of events passed to the client, including the
+	// events created by processors splitting an event.
+	Received uint64
+
+	// Published is the number of events that entered the queue.
+	Published uint64
+
+	// Filtered is the number of events dropped by the processors.
+	Filtered uint64
+
+	// Dropped is the number of events that could not be published to the
+	// queue, because it was full or the client was closed.
+	Dropped uint64
+
+	// Acked is the number of published events acknowledged by the outputs.
+	Acked uint64
+
+	// DropRatio is the fraction of the events received during the last
+	// complete minute that were dropped by the processors. It is 0 until
+	// the first minute is complete.
+	DropRatio float64
+}
+
 // ClientConfig defines common configuration options one can pass to
 // Pipeline.ConnectWith to control the clients behavior and provide ACK support.
 type ClientConfig struct {
@@ -60,6 +126,22 @@ type ClientConfig struct {
 
 	// ClientListener configures callbacks for monitoring pipeline clients
 	ClientListener ClientListener
+
+	// AssignSequence makes the client assign a monotonic sequence number to
+	// events published without one (Event.Sequence is 0). Sequence numbers
+	// set by the caller are kept, and numbering continues after the highest
+	// one seen.
+	AssignSequence bool
+
+	// SessionField is the field the client session ID is written to in every
+	// event published by the client, to correlate all events of a client
+	// session. No session ID is written if SessionField is empty.
+	SessionField string
+
+	// SessionID is the client session ID. If empty, a random ID is generated
+	// on connect, unique to the client. An explicit ID is kept by clients
+	// connected again with the same configuration, for example on reconnect.
+	SessionID string
 }
 
 // EventListener can be registered with a Client when connecting to the pipeline.
@@ -74,6 +156,8 @@ type EventListener interface {
 	// AddEvent is called after the processors have handled the event. If the
 	// event has been dropped by the processor `published` will be set to false.
 	// This allows the ACKer to do some bookkeeping for dropped events.
+	// If a processor splits the event into multiple events, AddEvent is called
+	// once for each of the events created.
 	AddEvent(event Event, published bool)
 
 	// ACKEvents ack events from the output and pipeline queue are forwarded to ACKEvents.
@@ -89,6 +173,25 @@ type EventListener interface {
 	ClientClosed()
 }
 
+// EventIDListener is an optional extension of EventListener for clients that
+// need to know exactly which events have been ACKed, when outputs acknowledge
+// events out of order.
+// If the EventListener passed to ConnectWith implements EventIDListener, the
+// pipeline assigns an ID to every published event and calls ACKEventIDs
+// instead of ACKEvents.
+type EventIDListener interface {
+	EventListener
+
+	// EventIDAssigned is called after AddEvent with the ID assigned to the
+	// published event, right before it is handed to the queue. IDs are
+	// increasing. If the queue rejects the event its ID is never ACKed.
+	EventIDAssigned(id uint64)
+
+	// ACKEventIDs reports the IDs of ACKed events. Every ID is reported exactly
+	// once, but not necessarily in the order the events were published.
+	ACKEventIDs(ids []uint64)
+}
+
 // ProcessingConfig provides additional event processing settings a client can
 // pass to the publisher pipeline on Connect.
 type ProcessingConfig struct {
@@ -122,6 +225,28 @@ type ProcessingConfig struct {
 	// Disables the addition of input.type
 	DisableType bool
 
+	// MinimalMetadata disables all metadata added to events automatically, for
+	// inputs publishing self-contained events. It implies DisableHost and
+	// DisableType, disables the event normalization and the addition of the
+	// agent, ecs and other builtin fields.
+	MinimalMetadata bool
+
+	// FieldAllowlist removes all fields not matching any of the listed
+	// fields after all processors have been run. Entries ending with '*'
+	// match all fields with the given prefix. The event timestamp and
+	// metadata are always preserved.
+	FieldAllowlist []string
+
+	// MaxProcessingTime limits the time the processors can spend on a single
+	// event. Processors are not interrupted, the limit is checked before
+	// each processor is run. Once exceeded, the remaining processors are
+	// skipped and the event is dropped. 0 disables the limit.
+	MaxProcessingTime time.Duration
+
+	// Clock measures MaxProcessingTime. The publisher pipeline sets it to its
+	// own clock if nil.
+	Clock clockwork.Clock
+
 	// Private contains additional information to be passed to the processing
 	// pipeline builder.
 	Private interface{}
@@ -138,6 +263,19 @@ type ClientListener interface {
 	DroppedOnPublish(Event) // event has been dropped, while waiting for the queue
 }
 
+// CongestionListener is an optional extension of ClientListener.
+// If the ClientListener passed to ConnectWith implements CongestionListener,
+// the pipeline informs it about output congestion.
+type CongestionListener interface {
+	ClientListener
+
+	// OutputCongested is called when the pipeline detects that the outputs
+	// ACK events at a considerably lower rate than events are published, and
+	// again once the congestion is resolved. Inputs can use this signal to
+	// slow down. The callback must not block.
+	OutputCongested(congested bool)
+}
+
 type ProcessorList interface {
 	Processor
 	Close() error
@@ -171,6 +309,19 @@ const (
 	DropIfFull
 )
 
+func (m PublishMode) String() string {
+	switch m {
+	case DefaultGuarantees:
+		return "default"
+	case GuaranteedSend:
+		return "guaranteed_send"
+	case DropIfFull:
+		return "drop_if_full"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(m))
+	}
+}
+
 type CombinedClientListener struct {
 	A, B ClientListener
 }
@@ -204,3 +355,12 @@ func (c *CombinedClientListener) DroppedOnPublish(event Event) {
 	c.A.DroppedOnPublish(event)
 	c.B.DroppedOnPublish(event)
 }
+
+func (c *CombinedClientListener) OutputCongested(congested bool) {
+	if l, ok := c.A.(CongestionListener); ok {
+		l.OutputCongested(congested)
+	}
+	if l, ok := c.B.(CongestionListener); ok {
+		l.OutputCongested(congested)
+	}
+}
@@ -41,6 +41,7 @@ var (
 			Period:  10 * time.Second,
 			Enabled: false,
 		},
+		Timeout: 30 * time.Second,
 	}
 
 	// configScans measures how many times the config dir was scanned for
@@ -59,6 +60,12 @@ type DynamicConfig struct {
 	// If path is a relative path, it is relative to the ${path.config}
 	Path   string `config:"path"`
 	Reload Reload `config:"reload"`
+
+	// If URL is set, the configs are fetched from it instead of the files
+	// matching Path. The response must contain a YAML list of configs.
+	URL     string            `config:"url"`
+	Headers map[string]string `config:"headers"`
+	Timeout time.Duration     `config:"timeout"`
 }
 
 // Reload defines reload behavior and frequency
@@ -98,6 +105,7 @@ type Reloader struct {
 	pipeline beat.PipelineConnector
 	config   DynamicConfig
 	path     string
+	remote   *RemoteWatcher
 	done     chan struct{}
 	wg       sync.WaitGroup
 	logger   *logp.Logger
@@ -113,10 +121,16 @@ func NewReloader(logger *logp.Logger, pipeline beat.PipelineConnector, cfg *conf
 		path = paths.Resolve(paths.Config, path)
 	}
 
+	var remote *RemoteWatcher
+	if conf.URL != "" {
+		remote = NewRemoteWatcher(conf.URL, conf.Headers, conf.Timeout, logger)
+	}
+
 	return &Reloader{
 		pipeline: pipeline,
 		config:   conf,
 		path:     path,
+		remote:   remote,
 		done:     make(chan struct{}),
 		logger:   logger,
 	}
@@ -129,18 +143,28 @@ func (rl *Reloader) Check(runnerFactory RunnerFactory) error {
 		return nil
 	}
 
-	rl.logger.Debugf("Checking module configs from: %s", rl.path)
-	gw := NewGlobWatcher(rl.path, rl.logger)
+	var configs []*reload.ConfigWithMeta
+	if rl.remote != nil {
+		rl.logger.Debugf("Checking module configs from: %s", rl.config.URL)
+		var err error
+		configs, _, err = rl.scanRemote()
+		if err != nil {
+			return fmt.Errorf("fetching configs: %w", err)
+		}
+	} else {
+		rl.logger.Debugf("Checking module configs from: %s", rl.path)
+		gw := NewGlobWatcher(rl.path, rl.logger)
 
-	files, _, err := gw.Scan()
-	if err != nil {
-		return fmt.Errorf("fetching config files: %w", err)
-	}
+		files, _, err := gw.Scan()
+		if err != nil {
+			return fmt.Errorf("fetching config files: %w", err)
+		}
 
-	// Load all config objects
-	configs, err := rl.loadConfigs(files)
-	if err != nil {
-		return fmt.Errorf("loading configs: %w", err)
+		// Load all config objects
+		configs, err = rl.loadConfigs(files)
+		if err != nil {
+			return fmt.Errorf("loading configs: %w", err)
+		}
 	}
 
 	rl.logger.Debugf("Number of module configs found: %v", len(configs))
@@ -152,7 +176,7 @@ func (rl *Reloader) Check(runnerFactory RunnerFactory) error {
 			continue
 		}
 
-		if err = runnerFactory.CheckConfig(c.Config); err != nil {
+		if err := runnerFactory.CheckConfig(c.Config); err != nil {
 			return err
 		}
 	}
@@ -194,25 +218,39 @@ func (rl *Reloader) Run(runnerFactory RunnerFactory) {
 			rl.logger.Debug("Scan for new config files")
 			configScans.Add(1)
 
-			files, updated, err := gw.Scan()
-			if err != nil {
-				// In most cases of error, updated == false, so will continue
-				// to next iteration below
-				rl.logger.Errorf("Error fetching new config files: %v", err)
-			}
-
-			// if there are no changes, skip this reload unless forceReload is set.
-			if !updated && !forceReload {
-				continue
+			var configs []*reload.ConfigWithMeta
+			if rl.remote != nil {
+				var updated bool
+				var err error
+				configs, updated, err = rl.scanRemote()
+				if err != nil {
+					// The configs of the last successful fetch are kept.
+					rl.logger.Errorf("Error fetching new configs: %v", err)
+				}
+				if !updated && !forceReload {
+					continue
+				}
+			} else {
+				files, updated, err := gw.Scan()
+				if err != nil {
+					// In most cases of error, updated == false, so will continue
+					// to next iteration below
+					rl.logger.Errorf("Error fetching new config files: %v", err)
+				}
+
+				// if there are no changes, skip this reload unless forceReload is set.
+				if !updated && !forceReload {
+					continue
+				}
+
+				// Load all config objects
+				configs, _ = rl.loadConfigs(files)
 			}
 			configReloads.Add(1)
 
-			// Load all config objects
-			configs, _ := rl.loadConfigs(files)
-
 			rl.logger.Debugf("Number of module configs found: %v", len(configs))
 
-			err = list.Reload(configs)
+			err := list.Reload(configs)
 			// Force reload on the next iteration if and only if the error
 			// can be retried.
 			// Errors are already logged by list.Reload, so we don't need to
@@ -245,16 +283,25 @@ func (rl *Reloader) Load(runnerFactory RunnerFactory) {
 	// Stop all running modules when method finishes
 	defer list.Stop()
 
-	gw := NewGlobWatcher(rl.path, rl.logger)
+	var configs []*reload.ConfigWithMeta
+	if rl.remote != nil {
+		var err error
+		configs, _, err = rl.scanRemote()
+		if err != nil {
+			rl.logger.Errorf("Error fetching configs: %v", err)
+		}
+	} else {
+		gw := NewGlobWatcher(rl.path, rl.logger)
 
-	rl.logger.Debug("Scan for config files")
-	files, _, err := gw.Scan()
-	if err != nil {
-		rl.logger.Errorf("Error fetching new config files: %v", err)
-	}
+		rl.logger.Debug("Scan for config files")
+		files, _, err := gw.Scan()
+		if err != nil {
+			rl.logger.Errorf("Error fetching new config files: %v", err)
+		}
 
-	// Load all config objects
-	configs, _ := rl.loadConfigs(files)
+		// Load all config objects
+		configs, _ = rl.loadConfigs(files)
+	}
 
 	rl.logger.Debugf("Number of module configs found: %v", len(configs))
 
@@ -286,6 +333,18 @@ func (rl *Reloader) loadConfigs(files []string) ([]*reload.ConfigWithMeta, error
 	return result, errs.Err()
 }
 
+// scanRemote fetches the configs from the configured URL, returning whether
+// they changed since the last fetch. On errors, the configs of the last
+// successful fetch are returned.
+func (rl *Reloader) scanRemote() ([]*reload.ConfigWithMeta, bool, error) {
+	configs, updated, err := rl.remote.Scan()
+	result := make([]*reload.ConfigWithMeta, 0, len(configs))
+	for _, c := range configs {
+		result = append(result, &reload.ConfigWithMeta{Config: c})
+	}
+	return result, updated, err
+}
+
 // Stop stops the reloader and waits for all modules to properly stop
 func (rl *Reloader) Stop() {
 	close(rl.done)
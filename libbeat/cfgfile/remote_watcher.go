@@ -0,0 +1,111 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cfgfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// RemoteWatcher fetches a list of configs from a URL, such as a config server
+// serving module configs in the same format as the files in modules.d.
+type RemoteWatcher struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	logger  *logp.Logger
+
+	// ETag and body of the last successful fetch, used to detect changes.
+	etag string
+	body []byte
+
+	// The configs of the last successful fetch.
+	configs []*config.C
+}
+
+func NewRemoteWatcher(url string, headers map[string]string, timeout time.Duration, logger *logp.Logger) *RemoteWatcher {
+	return &RemoteWatcher{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+		logger:  logger,
+	}
+}
+
+// Scan fetches the configs from the URL. It returns the configs, a boolean if
+// they changed since the last scan and potential errors.
+// Changes are detected using the ETag returned by the server, if the server
+// does not support it the content is compared instead. If fetching or parsing
+// the configs fails, the configs of the last successful scan are returned.
+func (rw *RemoteWatcher) Scan() ([]*config.C, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rw.url, nil)
+	if err != nil {
+		return rw.configs, false, err
+	}
+	for k, v := range rw.headers {
+		req.Header.Set(k, v)
+	}
+	if rw.etag != "" {
+		req.Header.Set("If-None-Match", rw.etag)
+	}
+
+	resp, err := rw.client.Do(req)
+	if err != nil {
+		return rw.configs, false, fmt.Errorf("fetching configs from %s: %w", rw.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return rw.configs, false, nil
+	default:
+		return rw.configs, false, fmt.Errorf("fetching configs from %s: unexpected status %s", rw.url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return rw.configs, false, fmt.Errorf("reading configs from %s: %w", rw.url, err)
+	}
+	etag := resp.Header.Get("ETag")
+	if rw.body != nil && bytes.Equal(body, rw.body) {
+		rw.etag = etag
+		return rw.configs, false, nil
+	}
+
+	rawConfig, err := config.NewConfigWithYAML(body, rw.url)
+	if err != nil {
+		return rw.configs, false, fmt.Errorf("invalid config from %s: %w", rw.url, err)
+	}
+	var configs []*config.C
+	if err := rawConfig.Unpack(&configs); err != nil {
+		return rw.configs, false, fmt.Errorf("error reading configuration from %s: %w", rw.url, err)
+	}
+
+	rw.logger.Debugf("Fetched %d configs from %s", len(configs), rw.url)
+	rw.etag = etag
+	rw.body = body
+	rw.configs = configs
+	return configs, true, nil
+}
@@ -0,0 +1,128 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cfgfile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// configServer serves body with the given ETag, or a failure status if set.
+type configServer struct {
+	mu     sync.Mutex
+	body   string
+	etag   string
+	status int
+	auth   string
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = r.Header.Get("Authorization")
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	if s.etag != "" {
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+	}
+	_, _ = w.Write([]byte(s.body))
+}
+
+func (s *configServer) set(body, etag string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag, s.status = body, etag, status
+}
+
+func moduleNames(t *testing.T, configs []*config.C) []string {
+	names := []string{}
+	for _, c := range configs {
+		name, err := c.String("module", -1)
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestRemoteWatcher(t *testing.T) {
+	server := &configServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	rw := NewRemoteWatcher(httpServer.URL, map[string]string{"Authorization": "Bearer token"}, time.Second, logp.NewTestingLogger(t, ""))
+
+	server.set("- module: system\n- module: nginx\n", `"v1"`, 0)
+	configs, updated, err := rw.Scan()
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []string{"system", "nginx"}, moduleNames(t, configs))
+	assert.Equal(t, "Bearer token", server.auth)
+
+	// Unchanged ETag.
+	configs, updated, err = rw.Scan()
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, []string{"system", "nginx"}, moduleNames(t, configs))
+
+	// Changed content.
+	server.set("- module: redis\n", `"v2"`, 0)
+	configs, updated, err = rw.Scan()
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []string{"redis"}, moduleNames(t, configs))
+
+	// Failed fetches and invalid configs keep the last good configs.
+	server.set("", "", http.StatusInternalServerError)
+	configs, updated, err = rw.Scan()
+	assert.Error(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, []string{"redis"}, moduleNames(t, configs))
+
+	server.set("module: [", `"v3"`, 0)
+	configs, updated, err = rw.Scan()
+	assert.Error(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, []string{"redis"}, moduleNames(t, configs))
+
+	// Without ETag the content is compared.
+	server.set("- module: redis\n", "", 0)
+	_, updated, err = rw.Scan()
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	server.set("- module: mysql\n", "", 0)
+	configs, updated, err = rw.Scan()
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []string{"mysql"}, moduleNames(t, configs))
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/beats/v7/libbeat/cmd/export"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+const maskedValue = "xxxxx"
+
+func genConfigCmd(settings instance.Settings) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	configCmd.AddCommand(genConfigDumpCmd(settings))
+
+	return configCmd
+}
+
+func genConfigDumpCmd(settings instance.Settings) *cobra.Command {
+	var format string
+	command := &cobra.Command{
+		Use:   "dump",
+		Short: "Print the effective configuration, with secrets masked",
+		Long: "Loads and merges all configuration files and -E overwrites the same way\n" +
+			"the Beat does on startup, and prints the resolved configuration to stdout.\n" +
+			"Keystore references are not resolved and sensitive settings like passwords\n" +
+			"are masked.",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			return dumpConfig(settings, format)
+		}),
+	}
+	command.Flags().StringVar(&format, "format", "yaml", "Output format, one of yaml or json")
+	return command
+}
+
+func dumpConfig(settings instance.Settings, format string) error {
+	content, err := export.LoadConfig(settings)
+	if err != nil {
+		return err
+	}
+
+	res, err := formatConfig(content, format)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(res)
+	return err
+}
+
+// formatConfig serializes the configuration in the requested format,
+// masking all secrets.
+func formatConfig(content map[string]interface{}, format string) ([]byte, error) {
+	config.ApplyLoggingMask(content)
+	maskSecrets(content)
+
+	switch format {
+	case "yaml":
+		return yaml.Marshal(content)
+	case "json":
+		res, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(res, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported format '%s', expected yaml or json", format)
+	}
+}
+
+func maskSecrets(c interface{}) {
+	switch cfg := c.(type) {
+	case map[string]interface{}:
+		for k, v := range cfg {
+			if common.IsSensitiveSetting(k) {
+				cfg[k] = maskedValue
+			} else {
+				maskSecrets(v)
+			}
+		}
+	case []interface{}:
+		for _, elem := range cfg {
+			maskSecrets(elem)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+func TestFormatConfigMasksSecrets(t *testing.T) {
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"name": "test",
+		"output.elasticsearch": map[string]interface{}{
+			"hosts":    []string{"https://localhost:9200"},
+			"username": "elastic",
+			"password": "changeme",
+			"api_key":  "id:key",
+		},
+		"filebeat.inputs": []interface{}{
+			map[string]interface{}{
+				"type":                         "aws-s3",
+				"secret_access_key":            "secret",
+				"access_key_id":                "id",
+				"queue_url":                    "https://sqs",
+				"number_of_workers":            5,
+				"expand_event_list_from_field": "Records",
+			},
+		},
+		"processors": []interface{}{
+			map[string]interface{}{
+				"redact": map[string]interface{}{
+					"salt": "pepper",
+				},
+			},
+		},
+	})
+
+	unpack := func() map[string]interface{} {
+		var content map[string]interface{}
+		require.NoError(t, cfg.Unpack(&content))
+		return content
+	}
+
+	res, err := formatConfig(unpack(), "json")
+	require.NoError(t, err)
+
+	var content map[string]interface{}
+	require.NoError(t, json.Unmarshal(res, &content))
+
+	es := content["output"].(map[string]interface{})["elasticsearch"].(map[string]interface{})
+	assert.Equal(t, "elastic", es["username"])
+	assert.Equal(t, maskedValue, es["password"])
+	assert.Equal(t, maskedValue, es["api_key"])
+	assert.Equal(t, []interface{}{maskedValue}, es["hosts"])
+
+	input := content["filebeat"].(map[string]interface{})["inputs"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, maskedValue, input["secret_access_key"])
+	assert.Equal(t, "id", input["access_key_id"])
+	assert.Equal(t, "test", content["name"])
+
+	redact := content["processors"].([]interface{})[0].(map[string]interface{})["redact"].(map[string]interface{})
+	assert.Equal(t, maskedValue, redact["salt"])
+
+	res, err = formatConfig(unpack(), "yaml")
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(res, &content))
+	assert.NotContains(t, string(res), "changeme")
+	assert.NotContains(t, string(res), "pepper")
+
+	_, err = formatConfig(unpack(), "toml")
+	assert.Error(t, err)
+}
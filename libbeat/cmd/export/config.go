@@ -18,6 +18,7 @@
 package export
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -39,16 +40,9 @@ func GenExportConfigCmd(settings instance.Settings) *cobra.Command {
 }
 
 func exportConfig(settings instance.Settings) error {
-	settings.DisableConfigResolver = true
-	b, err := instance.NewInitializedBeat(settings)
+	config, err := LoadConfig(settings)
 	if err != nil {
-		fatalfInitCmd(err)
-	}
-
-	var config map[string]interface{}
-	err = b.RawConfig.Unpack(&config)
-	if err != nil {
-		fatalf("Error unpacking config: %+v.", err)
+		fatalf("Error exporting config: %+v.", err)
 	}
 	res, err := yaml.Marshal(config)
 	if err != nil {
@@ -58,3 +52,21 @@ func exportConfig(settings instance.Settings) error {
 	os.Stdout.Write(res)
 	return nil
 }
+
+// LoadConfig loads and merges the configuration the same way the Beat does
+// on startup. Variables like keystore references are not resolved, so the
+// configuration returned does not contain the secrets stored in the keystore.
+func LoadConfig(settings instance.Settings) (map[string]interface{}, error) {
+	settings.DisableConfigResolver = true
+	b, err := instance.NewInitializedBeat(settings)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing beat: %w", err)
+	}
+
+	var config map[string]interface{}
+	err = b.RawConfig.Unpack(&config)
+	if err != nil {
+		return nil, fmt.Errorf("error unpacking config: %w", err)
+	}
+	return config, nil
+}
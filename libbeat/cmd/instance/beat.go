@@ -32,6 +32,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
@@ -57,6 +58,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/instrumentation"
 	"github.com/elastic/beats/v7/libbeat/kibana"
 	"github.com/elastic/beats/v7/libbeat/management"
+	"github.com/elastic/beats/v7/libbeat/monitoring/inputmon"
 	"github.com/elastic/beats/v7/libbeat/monitoring/report"
 	"github.com/elastic/beats/v7/libbeat/monitoring/report/log"
 	"github.com/elastic/beats/v7/libbeat/outputs"
@@ -115,6 +117,15 @@ type beatConfig struct {
 	MaxProcs  int    `config:"max_procs"`
 	GCPercent int    `config:"gc_percent"`
 
+	// InputMetricsWarmup is the grace period during which the metrics of a
+	// newly started input report it as warming up.
+	InputMetricsWarmup time.Duration `config:"input_metrics.warmup_period" validate:"min=0"`
+
+	// ShutdownGracePeriod is the time given to flush the queue to the outputs
+	// on shutdown, after the inputs have been stopped. If 0, the publisher
+	// pipeline is closed first, with its default WaitClose.
+	ShutdownGracePeriod time.Duration `config:"shutdown.grace_period" validate:"min=0"`
+
 	Seccomp  *config.C `config:"seccomp"`
 	Features *config.C `config:"features"`
 
@@ -627,6 +638,28 @@ func (b *Beat) createBeater(bt beat.Creator) (beat.Beater, error) {
 	return beater, nil
 }
 
+// gracefulShutdown stops the beater first, so no new events are published,
+// then waits for the events in the queue to be flushed to the outputs. The
+// whole shutdown takes at most gracePeriod.
+func (b *Beat) gracefulShutdown(beater beat.Beater, runDone <-chan struct{}, gracePeriod time.Duration) {
+	deadline := time.Now().Add(gracePeriod)
+	b.Info.Logger.Infof("Stopping %s, flushing the queue for up to %v", b.Info.Beat, gracePeriod)
+
+	beater.Stop()
+	select {
+	case <-runDone:
+	case <-time.After(gracePeriod):
+		b.Info.Logger.Warnf("%s did not stop within the shutdown grace period", b.Info.Beat)
+	}
+
+	switch p := b.Publisher.(type) {
+	case *pipeline.Pipeline:
+		_ = p.Shutdown(max(time.Until(deadline), 0))
+	case io.Closer:
+		p.Close()
+	}
+}
+
 func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 	logger := b.Info.Logger
 	defer func() {
@@ -701,6 +734,20 @@ func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 		return err
 	}
 
+	if b.API != nil {
+		if p, ok := b.Publisher.(*pipeline.Pipeline); ok {
+			if err := b.API.AttachHandler("/health", api.MakeHealthHandler(p.Health)); err != nil {
+				return fmt.Errorf("failed to attach health handler: %w", err)
+			}
+			if err := b.API.AttachHandler("/pipelines", api.MakePipelinesHandler(p.ClientProcessors)); err != nil {
+				return fmt.Errorf("failed to attach pipelines handler: %w", err)
+			}
+			if err := b.API.AttachHandler("/clients", api.MakeClientsHandler(p.Clients)); err != nil {
+				return fmt.Errorf("failed to attach clients handler: %w", err)
+			}
+		}
+	}
+
 	r, err := b.setupMonitoring(settings)
 	if err != nil {
 		return err
@@ -734,10 +781,21 @@ func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 
 	// stopBeat must be idempotent since it will be called both from a signal and by the manager.
 	// Since publisher.Close is not safe to be called more than once this is necessary.
-	var once sync.Once
+	var (
+		once         sync.Once
+		runDone      = make(chan struct{})
+		shutdownDone = make(chan struct{})
+		shuttingDown atomic.Bool
+	)
 	stopBeat := func() {
 		once.Do(func() {
 			b.Instrumentation.Tracer().Close()
+			if gracePeriod := b.Config.ShutdownGracePeriod; gracePeriod > 0 {
+				shuttingDown.Store(true)
+				defer close(shutdownDone)
+				b.gracefulShutdown(beater, runDone, gracePeriod)
+				return
+			}
 			// If the publisher has a Close() method, call it before stopping the beater.
 			if c, ok := b.Publisher.(io.Closer); ok {
 				c.Close()
@@ -758,6 +816,11 @@ func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 	logger.Infof("%s start running.", b.Info.Beat)
 
 	err = beater.Run(&b.Beat)
+	close(runDone)
+	if shuttingDown.Load() {
+		// Wait for the queue to be flushed before exiting.
+		<-shutdownDone
+	}
 	if b.shouldReexec {
 		if err := b.reexec(); err != nil {
 			return fmt.Errorf("could not restart %s: %w", b.Info.Beat, err)
@@ -1113,6 +1176,10 @@ func (b *Beat) configure(settings Settings) error {
 		logger.Infof("Set gc percentage to: %v", gcPercent)
 		debug.SetGCPercent(gcPercent)
 	}
+	if warmup := b.Config.InputMetricsWarmup; warmup > 0 {
+		logger.Infof("Set input metrics warmup period to: %v", warmup)
+		inputmon.SetWarmupPeriod(warmup)
+	}
 
 	b.Info.Monitoring.Namespace = monitoring.GetNamespace("dataset")
 
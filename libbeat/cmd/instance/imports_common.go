@@ -29,22 +29,47 @@ import (
 	_ "github.com/elastic/beats/v7/libbeat/processors/add_locale"
 	_ "github.com/elastic/beats/v7/libbeat/processors/add_observer_metadata"
 	_ "github.com/elastic/beats/v7/libbeat/processors/add_process_metadata"
+	_ "github.com/elastic/beats/v7/libbeat/processors/add_tenant"
+	_ "github.com/elastic/beats/v7/libbeat/processors/add_timezone"
 	_ "github.com/elastic/beats/v7/libbeat/processors/communityid"
 	_ "github.com/elastic/beats/v7/libbeat/processors/convert"
+	_ "github.com/elastic/beats/v7/libbeat/processors/counter_rate"
+	_ "github.com/elastic/beats/v7/libbeat/processors/csv"
 	_ "github.com/elastic/beats/v7/libbeat/processors/decode_duration"
+	_ "github.com/elastic/beats/v7/libbeat/processors/decode_field"
+	_ "github.com/elastic/beats/v7/libbeat/processors/decode_logfmt"
+	_ "github.com/elastic/beats/v7/libbeat/processors/decode_query_string"
 	_ "github.com/elastic/beats/v7/libbeat/processors/decode_xml"
 	_ "github.com/elastic/beats/v7/libbeat/processors/decode_xml_wineventlog"
+	_ "github.com/elastic/beats/v7/libbeat/processors/decompress"
 	_ "github.com/elastic/beats/v7/libbeat/processors/dissect"
 	_ "github.com/elastic/beats/v7/libbeat/processors/dns"
 	_ "github.com/elastic/beats/v7/libbeat/processors/extract_array"
+	_ "github.com/elastic/beats/v7/libbeat/processors/field_length"
 	_ "github.com/elastic/beats/v7/libbeat/processors/fingerprint"
+	_ "github.com/elastic/beats/v7/libbeat/processors/flatten"
+	_ "github.com/elastic/beats/v7/libbeat/processors/flood_control"
+	_ "github.com/elastic/beats/v7/libbeat/processors/geohash"
+	_ "github.com/elastic/beats/v7/libbeat/processors/limit_cardinality"
+	_ "github.com/elastic/beats/v7/libbeat/processors/lookup"
 	_ "github.com/elastic/beats/v7/libbeat/processors/move_fields"
+	_ "github.com/elastic/beats/v7/libbeat/processors/moving_average"
+	_ "github.com/elastic/beats/v7/libbeat/processors/multiline"
+	_ "github.com/elastic/beats/v7/libbeat/processors/normalize_ip"
+	_ "github.com/elastic/beats/v7/libbeat/processors/percentile_rank"
 	_ "github.com/elastic/beats/v7/libbeat/processors/ratelimit"
+	_ "github.com/elastic/beats/v7/libbeat/processors/redact"
+	_ "github.com/elastic/beats/v7/libbeat/processors/regex_extract"
 	_ "github.com/elastic/beats/v7/libbeat/processors/registered_domain"
 	_ "github.com/elastic/beats/v7/libbeat/processors/script"
+	_ "github.com/elastic/beats/v7/libbeat/processors/split"
+	_ "github.com/elastic/beats/v7/libbeat/processors/split_metrics"
 	_ "github.com/elastic/beats/v7/libbeat/processors/syslog"
+	_ "github.com/elastic/beats/v7/libbeat/processors/timestamp_diff"
+	_ "github.com/elastic/beats/v7/libbeat/processors/timestamp_window"
 	_ "github.com/elastic/beats/v7/libbeat/processors/translate_ldap_attribute"
 	_ "github.com/elastic/beats/v7/libbeat/processors/translate_sid"
 	_ "github.com/elastic/beats/v7/libbeat/processors/urldecode"
+	_ "github.com/elastic/beats/v7/libbeat/processors/validate_ecs"
 	_ "github.com/elastic/beats/v7/libbeat/publisher/includes" // Register publisher pipeline modules
 )
@@ -0,0 +1,145 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/publisher/pipeline"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
+)
+
+type replayOptions struct {
+	path   string
+	rate   float64
+	limit  int
+	pretty bool
+}
+
+func genQueueCmd(settings instance.Settings) *cobra.Command {
+	queueCmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Inspect the disk queue",
+	}
+
+	queueCmd.AddCommand(genQueueReplayCmd(settings))
+
+	return queueCmd
+}
+
+func genQueueReplayCmd(settings instance.Settings) *cobra.Command {
+	var options replayOptions
+	command := &cobra.Command{
+		Use:   "replay",
+		Short: "Print the events pending in the disk queue",
+		Long: "Reads the events of the disk queue that have not been acknowledged by the\n" +
+			"output yet, oldest first, and prints them to stdout as JSON at a limited rate.\n" +
+			"The queue is not modified, so this can be used while the Beat is running.",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			return replayQueue(settings, options)
+		}),
+	}
+	command.Flags().StringVar(&options.path, "path", "", "Directory of the disk queue, defaults to the path of the configured disk queue")
+	command.Flags().Float64Var(&options.rate, "rate", 10, "Maximum number of events printed per second, 0 for no limit")
+	command.Flags().IntVar(&options.limit, "limit", 0, "Maximum number of events printed, 0 for all pending events")
+	command.Flags().BoolVar(&options.pretty, "pretty", false, "Pretty print the events")
+	return command
+}
+
+func replayQueue(settings instance.Settings, options replayOptions) error {
+	b, err := instance.NewInitializedBeat(settings)
+	if err != nil {
+		return fmt.Errorf("error initializing beat: %w", err)
+	}
+
+	queueSettings, err := diskQueueSettings(b)
+	if err != nil {
+		return err
+	}
+	if options.path != "" {
+		queueSettings.Path = options.path
+	}
+
+	reader, err := diskqueue.NewReader(b.Info.Logger, queueSettings)
+	if err != nil {
+		return fmt.Errorf("error opening disk queue: %w", err)
+	}
+	defer reader.Close()
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if options.rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(options.rate), 1)
+	}
+	encoder := json.New(b.Info.Version, json.Config{Pretty: options.pretty})
+
+	count := 0
+	for options.limit <= 0 || count < options.limit {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading disk queue: %w", err)
+		}
+		if err := limiter.Wait(context.Background()); err != nil {
+			return err
+		}
+
+		serialized, err := encoder.Encode(b.Info.Beat, &event.Content)
+		if err != nil {
+			return fmt.Errorf("error encoding event: %w", err)
+		}
+		if _, err := os.Stdout.Write(append(serialized, '\n')); err != nil {
+			return err
+		}
+		count++
+	}
+	fmt.Fprintf(os.Stderr, "Replayed %d events\n", count)
+	return nil
+}
+
+// diskQueueSettings returns the settings of the disk queue configured for the
+// output or the whole Beat, or the default settings if no disk queue is
+// configured.
+func diskQueueSettings(b *instance.Beat) (diskqueue.Settings, error) {
+	queueConfig := b.Config.Pipeline.Queue
+	if b.Config.Output.IsSet() {
+		outputConfig := pipeline.Config{}
+		if err := b.Config.Output.Config().Unpack(&outputConfig); err != nil {
+			return diskqueue.Settings{}, fmt.Errorf("error unpacking output queue settings: %w", err)
+		}
+		if outputConfig.Queue.IsSet() {
+			queueConfig = outputConfig.Queue
+		}
+	}
+
+	if !queueConfig.IsSet() || queueConfig.Name() != diskqueue.QueueType {
+		return diskqueue.DefaultSettings(), nil
+	}
+	return diskqueue.SettingsForUserConfig(queueConfig.Config())
+}
@@ -39,7 +39,9 @@ type BeatsRootCmd struct {
 	VersionCmd    *cobra.Command
 	CompletionCmd *cobra.Command
 	ExportCmd     *cobra.Command
+	ConfigCmd     *cobra.Command
 	TestCmd       *cobra.Command
+	QueueCmd      *cobra.Command
 	KeystoreCmd   *cobra.Command
 }
 
@@ -69,7 +71,9 @@ func GenRootCmdWithSettings(beatCreator beat.Creator, settings instance.Settings
 
 	rootCmd.RunCmd = genRunCmd(settings, beatCreator)
 	rootCmd.ExportCmd = genExportCmd(settings)
+	rootCmd.ConfigCmd = genConfigCmd(settings)
 	rootCmd.TestCmd = genTestCmd(settings, beatCreator)
+	rootCmd.QueueCmd = genQueueCmd(settings)
 	rootCmd.SetupCmd = genSetupCmd(settings, beatCreator)
 	rootCmd.KeystoreCmd = genKeystoreCmd(settings)
 	rootCmd.VersionCmd = GenVersionCmd(settings)
@@ -104,7 +108,9 @@ func GenRootCmdWithSettings(beatCreator beat.Creator, settings instance.Settings
 	rootCmd.AddCommand(rootCmd.VersionCmd)
 	rootCmd.AddCommand(rootCmd.CompletionCmd)
 	rootCmd.AddCommand(rootCmd.ExportCmd)
+	rootCmd.AddCommand(rootCmd.ConfigCmd)
 	rootCmd.AddCommand(rootCmd.TestCmd)
+	rootCmd.AddCommand(rootCmd.QueueCmd)
 	if rootCmd.KeystoreCmd != nil {
 		rootCmd.AddCommand(rootCmd.KeystoreCmd)
 	}
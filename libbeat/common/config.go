@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/file"
@@ -49,6 +50,23 @@ var configOpts = []ucfg.Option{
 	ucfg.VarExp,
 }
 
+// SensitiveSettings lists setting names whose values are masked when a
+// configuration is logged or printed, in addition to the ones masked by
+// config.ApplyLoggingMask.
+var SensitiveSettings = []string{"api_key", "secret", "client_secret", "token", "access_token", "secret_access_key", "salt"}
+
+// IsSensitiveSetting reports whether the value of the setting name must be
+// masked. The name is compared case-insensitively.
+func IsSensitiveSetting(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range SensitiveSettings {
+		if name == sensitive {
+			return true
+		}
+	}
+	return false
+}
+
 const (
 	selectorConfig             = "config"
 	selectorConfigWithPassword = "config-with-passwords"
@@ -78,6 +78,13 @@ func TestEventFormatString(t *testing.T) {
 			"value",
 			[]string{"nested.key"},
 		},
+		{
+			"expand event sequence",
+			"%{[@sequence]}",
+			beat.Event{Sequence: 42},
+			"42",
+			[]string{"@sequence"},
+		},
 		{
 			"multiple event fields",
 			"%{[key1]} - %{[key2]}",
@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/elastic/sarama"
+)
+
+// oauth2TokenRefreshMargin is the time before the expiry of a token at which
+// a new token is requested.
+const oauth2TokenRefreshMargin = time.Minute
+
+// OAuth2Config configures the OAuth2 client credentials flow used to obtain
+// tokens for the OAUTHBEARER SASL mechanism.
+type OAuth2Config struct {
+	TokenURL       string              `config:"token_url"`
+	ClientID       string              `config:"client.id"`
+	ClientSecret   string              `config:"client.secret"`
+	Scopes         []string            `config:"scopes"`
+	EndpointParams map[string][]string `config:"endpoint_params"`
+}
+
+func (c *OAuth2Config) validate() error {
+	if c.TokenURL == "" {
+		return errors.New("sasl.oauth2.token_url must be set when using the OAUTHBEARER mechanism")
+	}
+	if c.ClientID == "" || c.ClientSecret == "" {
+		return errors.New("sasl.oauth2.client.id and sasl.oauth2.client.secret must be set when using the OAUTHBEARER mechanism")
+	}
+	return nil
+}
+
+// oauth2TokenProvider implements sarama.AccessTokenProvider. Tokens are
+// cached and refreshed oauth2TokenRefreshMargin before they expire.
+type oauth2TokenProvider struct {
+	source oauth2.TokenSource
+}
+
+func newOAuth2TokenProvider(c OAuth2Config) *oauth2TokenProvider {
+	config := &clientcredentials.Config{
+		ClientID:       c.ClientID,
+		ClientSecret:   c.ClientSecret,
+		TokenURL:       c.TokenURL,
+		Scopes:         c.Scopes,
+		EndpointParams: c.EndpointParams,
+	}
+	return &oauth2TokenProvider{
+		source: oauth2.ReuseTokenSourceWithExpiry(nil, clientCredentialsSource{config}, oauth2TokenRefreshMargin),
+	}
+}
+
+func (p *oauth2TokenProvider) Token() (*sarama.AccessToken, error) {
+	token, err := p.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain OAuth2 token: %w", err)
+	}
+	return &sarama.AccessToken{Token: token.AccessToken}, nil
+}
+
+// clientCredentialsSource requests a new token on every call, caching is
+// done by the wrapping oauth2.ReuseTokenSourceWithExpiry.
+type clientCredentialsSource struct {
+	config *clientcredentials.Config
+}
+
+func (s clientCredentialsSource) Token() (*oauth2.Token, error) {
+	return s.config.Token(context.Background())
+}
@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kafka
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/sarama"
+)
+
+func TestOAuth2TokenProvider(t *testing.T) {
+	var (
+		requests  atomic.Int32
+		expiresIn atomic.Int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "kafka", r.Form.Get("scope"))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d}`, n, expiresIn.Load())
+	}))
+	defer server.Close()
+
+	cfg := SaslConfig{
+		SaslMechanism: "oauthbearer",
+		OAuth2: OAuth2Config{
+			TokenURL:     server.URL,
+			ClientID:     "id",
+			ClientSecret: "secret",
+			Scopes:       []string{"kafka"},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	config := sarama.NewConfig()
+	cfg.ConfigureSarama(config)
+	require.True(t, config.Net.SASL.Enable)
+	require.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), config.Net.SASL.Mechanism)
+	provider := config.Net.SASL.TokenProvider
+	require.NotNil(t, provider)
+
+	// tokens are reused until they are about to expire
+	expiresIn.Store(3600)
+	for i := 0; i < 3; i++ {
+		token, err := provider.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token.Token)
+	}
+
+	// tokens expiring within the refresh margin are refreshed
+	expiresIn.Store(30)
+	provider = newOAuth2TokenProvider(cfg.OAuth2)
+	for i := 2; i < 4; i++ {
+		token, err := provider.Token()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("token-%d", i), token.Token)
+	}
+}
+
+func TestOAuth2Validate(t *testing.T) {
+	cfg := SaslConfig{SaslMechanism: saslTypeOAuth}
+	require.Error(t, cfg.Validate())
+
+	cfg.OAuth2.TokenURL = "https://localhost/token"
+	require.Error(t, cfg.Validate())
+
+	cfg.OAuth2.ClientID = "id"
+	cfg.OAuth2.ClientSecret = "secret"
+	require.NoError(t, cfg.Validate())
+}
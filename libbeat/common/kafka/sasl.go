@@ -25,15 +25,23 @@ import (
 )
 
 type SaslConfig struct {
-	SaslMechanism string `config:"mechanism"`
+	SaslMechanism string       `config:"mechanism"`
+	OAuth2        OAuth2Config `config:"oauth2"`
 }
 
 const (
 	saslTypePlaintext   = sarama.SASLTypePlaintext
 	saslTypeSCRAMSHA256 = sarama.SASLTypeSCRAMSHA256
 	saslTypeSCRAMSHA512 = sarama.SASLTypeSCRAMSHA512
+	saslTypeOAuth       = sarama.SASLTypeOAuth
 )
 
+// IsOAuth returns true if the OAUTHBEARER mechanism is configured. Unlike the
+// other mechanisms it does not require a username and password.
+func (c *SaslConfig) IsOAuth() bool {
+	return strings.ToUpper(c.SaslMechanism) == saslTypeOAuth
+}
+
 func (c *SaslConfig) ConfigureSarama(config *sarama.Config) {
 	switch strings.ToUpper(c.SaslMechanism) { // try not to force users to use all upper case
 	case "":
@@ -49,8 +57,13 @@ func (c *SaslConfig) ConfigureSarama(config *sarama.Config) {
 		config.Net.SASL.Handshake = true
 		config.Net.SASL.Mechanism = sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512)
 		config.Net.SASL.SCRAMClientGeneratorFunc = scramClient(saslTypeSCRAMSHA512)
+	case saslTypeOAuth:
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Handshake = true
+		config.Net.SASL.Mechanism = sarama.SASLMechanism(sarama.SASLTypeOAuth)
+		config.Net.SASL.TokenProvider = newOAuth2TokenProvider(c.OAuth2)
 	default:
 		// This should never happen because `SaslMechanism` is checked on `Validate()`, keeping a panic to detect it earlier if it happens.
-		panic(fmt.Sprintf("not valid SASL mechanism '%v', only supported with PLAIN|SCRAM-SHA-512|SCRAM-SHA-256", c.SaslMechanism))
+		panic(fmt.Sprintf("not valid SASL mechanism '%v', only supported with PLAIN|SCRAM-SHA-512|SCRAM-SHA-256|OAUTHBEARER", c.SaslMechanism))
 	}
 }
@@ -29,8 +29,10 @@ import (
 func (c *SaslConfig) Validate() error {
 	switch strings.ToUpper(c.SaslMechanism) { // try not to force users to use all upper case
 	case "", saslTypePlaintext:
+	case saslTypeOAuth:
+		return c.OAuth2.validate()
 	default:
-		return fmt.Errorf("not valid SASL mechanism '%v', only supported with PLAIN", c.SaslMechanism)
+		return fmt.Errorf("not valid SASL mechanism '%v', only supported with PLAIN|OAUTHBEARER", c.SaslMechanism)
 	}
 	return nil
 }
@@ -324,6 +324,22 @@ func (conn *Connection) Ping() (ESPingData, error) {
 	return response, nil
 }
 
+// Warmup establishes the network connection to Elasticsearch by sending a HEAD
+// request, keeping the connection open for later requests. Unlike Connect, the
+// version is not queried and no callbacks are run.
+func (conn *Connection) Warmup(ctx context.Context) error {
+	if conn.log == nil {
+		conn.log = logp.NewLogger("esclientleg")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, conn.URL, nil)
+	if err != nil {
+		return err
+	}
+	_, _, err = conn.execHTTPRequest(req)
+	return err
+}
+
 // Close closes any idle connections from the HTTP client.
 func (conn *Connection) Close() error {
 	conn.HTTP.CloseIdleConnections()
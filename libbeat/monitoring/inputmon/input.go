@@ -20,7 +20,11 @@ package inputmon
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent-libs/monitoring"
@@ -42,6 +46,15 @@ import (
 //
 // Deprecated. Use NewMetricsRegistry instead.
 func NewInputRegistry(inputType, inputID string, optionalParent *monitoring.Registry) (reg *monitoring.Registry, cancel func()) {
+	reg, cancel, _ = NewInputRegistryWithStatus(inputType, inputID, optionalParent)
+	return reg, cancel
+}
+
+// NewInputRegistryWithStatus works like NewInputRegistry, additionally
+// reporting whether the returned registry is visible. visible is false when
+// the metrics are null-routed because inputType or inputID is empty, so the
+// caller can warn that the input metrics won't be collected.
+func NewInputRegistryWithStatus(inputType, inputID string, optionalParent *monitoring.Registry) (reg *monitoring.Registry, cancel func(), visible bool) {
 	// Log the registration to ease tracking down duplicate ID registrations.
 	// Logged at INFO rather than DEBUG since it is not in a hot path and having
 	// the information available by default can short-circuit requests for debug
@@ -56,9 +69,11 @@ func NewInputRegistry(inputType, inputID string, optionalParent *monitoring.Regi
 
 	// If an ID has not been assigned to an input then metrics cannot be exposed
 	// in the global metric registry. The returned registry still behaves the same.
+	visible = true
 	if (inputID == "" || inputType == "") && parentRegistry == globalRegistry() {
 		// Null route metrics without ID or input type.
 		parentRegistry = monitoring.NewRegistry()
+		visible = false
 	}
 
 	// Sanitize dots from the id because they created nested objects within
@@ -79,6 +94,8 @@ func NewInputRegistry(inputType, inputID string, optionalParent *monitoring.Regi
 
 	monitoring.NewString(reg, "input").Set(inputType)
 	monitoring.NewString(reg, "id").Set(inputID)
+	registeredTypes.add(inputType)
+	registerWarmup(reg)
 
 	log.Infow("registering",
 		"input_type", inputType,
@@ -91,7 +108,71 @@ func NewInputRegistry(inputType, inputID string, optionalParent *monitoring.Regi
 			"input_id", inputID,
 			"registry_id", registryID)
 		parentRegistry.Remove(registryID)
+	}, visible
+}
+
+// warmupPeriod is the grace period after an input registered its metrics
+// during which it reports that it is warming up. It is disabled when zero.
+var warmupPeriod atomic.Int64
+
+// SetWarmupPeriod sets the grace period after an input registered its metrics
+// during which it reports 'is_warming_up: true'. Consumers of the metrics can
+// use it to suppress staleness or throughput alerts while inputs start up.
+// When period is positive, input registries created afterwards also record
+// their 'start_time'. A zero period, the default, disables both metrics.
+func SetWarmupPeriod(period time.Duration) {
+	warmupPeriod.Store(int64(period))
+}
+
+// WarmupPeriod returns the grace period set by SetWarmupPeriod.
+func WarmupPeriod() time.Duration {
+	return time.Duration(warmupPeriod.Load())
+}
+
+// registerWarmup records the start time of an input in reg, together with an
+// 'is_warming_up' flag computed against the warmup period.
+func registerWarmup(reg *monitoring.Registry) {
+	if WarmupPeriod() <= 0 {
+		return
+	}
+	start := time.Now()
+	monitoring.NewTimestamp(reg, "start_time").Set(start)
+	monitoring.NewFunc(reg, "is_warming_up", func(_ monitoring.Mode, v monitoring.Visitor) {
+		v.OnBool(time.Since(start) < WarmupPeriod())
+	})
+}
+
+// registeredTypes holds every input type that registered metrics. Types are
+// never removed, so they stay discoverable after all instances stopped.
+var registeredTypes = inputTypeSet{types: map[string]struct{}{}}
+
+type inputTypeSet struct {
+	mu    sync.Mutex
+	types map[string]struct{}
+}
+
+func (s *inputTypeSet) add(inputType string) {
+	if inputType == "" {
+		return
 	}
+	s.mu.Lock()
+	s.types[inputType] = struct{}{}
+	s.mu.Unlock()
+}
+
+// RegisteredInputTypes returns the sorted list of input types that have
+// registered metrics since the process started, including types without
+// any running instance.
+func RegisteredInputTypes() []string {
+	registeredTypes.mu.Lock()
+	defer registeredTypes.mu.Unlock()
+
+	types := make([]string, 0, len(registeredTypes.types))
+	for t := range registeredTypes.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
 }
 
 func sanitizeID(id string) string {
@@ -110,6 +191,44 @@ func MetricSnapshotJSON(reg *monitoring.Registry) ([]byte, error) {
 	return json.MarshalIndent(filteredSnapshot(globalRegistry(), reg, ""), "", "  ")
 }
 
+// AllComponentsSnapshot is a snapshot of the metrics of the whole data path:
+// the inputs, the publisher pipeline, its queue and the output. Sections
+// without any metrics are omitted when encoded.
+type AllComponentsSnapshot struct {
+	Inputs   []map[string]any `json:"inputs"`
+	Pipeline map[string]any   `json:"pipeline,omitempty"`
+	Queue    map[string]any   `json:"queue,omitempty"`
+	Output   map[string]any   `json:"output,omitempty"`
+}
+
+// AllComponentsSnapshotJSON returns the input metrics, as returned by
+// MetricSnapshotJSON, together with the pipeline, queue and output metrics
+// found in libbeatReg, encoded as a JSON object (pretty formatted). libbeatReg
+// is the 'libbeat' registry of the beat stats and may be nil, as may reg.
+func AllComponentsSnapshotJSON(reg, libbeatReg *monitoring.Registry) ([]byte, error) {
+	snapshot := AllComponentsSnapshot{
+		Inputs: filteredSnapshot(globalRegistry(), reg, ""),
+	}
+
+	if libbeatReg != nil {
+		snapshot.Pipeline = registrySnapshot(libbeatReg.GetRegistry("pipeline"))
+		snapshot.Output = registrySnapshot(libbeatReg.GetRegistry("output"))
+		if queue, ok := snapshot.Pipeline["queue"].(map[string]any); ok {
+			snapshot.Queue = queue
+			delete(snapshot.Pipeline, "queue")
+		}
+	}
+
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+func registrySnapshot(reg *monitoring.Registry) map[string]any {
+	if reg == nil {
+		return nil
+	}
+	return monitoring.CollectStructSnapshot(reg, monitoring.Full, false)
+}
+
 // NewMetricsRegistry creates a monitoring.Registry for an input.
 //
 // The metric registry is created on parent using inputID as the name,
@@ -141,6 +260,8 @@ func NewMetricsRegistry(
 	// HTTP monitoring endpoint.
 	monitoring.NewString(reg, "input").Set(inputType)
 	monitoring.NewString(reg, "id").Set(inputID)
+	registeredTypes.add(inputType)
+	registerWarmup(reg)
 
 	log.Named("metric_registry").Infow("registering",
 		"registry_id", registryID,
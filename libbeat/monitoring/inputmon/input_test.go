@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -83,6 +84,31 @@ func TestNewInputMonitor(t *testing.T) {
 	}
 }
 
+func TestNewInputRegistryWithStatus(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          string
+		id             string
+		optionalParent *monitoring.Registry
+		visible        bool
+	}{
+		{name: "global", input: "foo-input", id: "my-id", visible: true},
+		{name: "global without id", input: "foo-input", visible: false},
+		{name: "global without input", id: "my-id", visible: false},
+		{name: "custom parent", input: "foo-input", id: "my-id", optionalParent: monitoring.NewRegistry(), visible: true},
+		{name: "custom parent without id", input: "foo-input", optionalParent: monitoring.NewRegistry(), visible: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg, unreg, visible := NewInputRegistryWithStatus(tc.input, tc.id, tc.optionalParent)
+			defer unreg()
+			assert.NotNil(t, reg)
+			assert.Equal(t, tc.visible, visible)
+		})
+	}
+}
+
 func TestMetricSnapshotJSON(t *testing.T) {
 	require.NoError(t, globalRegistry().Clear())
 	t.Cleanup(func() {
@@ -244,3 +270,77 @@ func TestMetricSnapshotJSON_regNil(t *testing.T) {
 	require.NoError(t, err, "MetricSnapshotJSON should not return an error")
 	assert.Equal(t, "[]", string(got))
 }
+
+func TestAllComponentsSnapshotJSON(t *testing.T) {
+	err := globalRegistry().Clear()
+	require.NoError(t, err, "could not clear global registry")
+
+	inputs := monitoring.NewRegistry()
+	NewMetricsRegistry("input-id", "input-type", inputs, logp.NewLogger("test"))
+
+	libbeat := monitoring.NewRegistry()
+	monitoring.NewUint(libbeat, "pipeline.events.total").Set(3)
+	monitoring.NewUint(libbeat, "pipeline.queue.filled.events").Set(2)
+	monitoring.NewUint(libbeat, "output.events.acked").Set(1)
+
+	got, err := AllComponentsSnapshotJSON(inputs, libbeat)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"inputs": [{"id": "input-id", "input": "input-type"}],
+		"pipeline": {"events": {"total": 3}},
+		"queue": {"filled": {"events": 2}},
+		"output": {"events": {"acked": 1}}
+	}`, string(got))
+}
+
+func TestAllComponentsSnapshotJSON_emptySections(t *testing.T) {
+	err := globalRegistry().Clear()
+	require.NoError(t, err, "could not clear global registry")
+
+	got, err := AllComponentsSnapshotJSON(nil, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"inputs": []}`, string(got))
+
+	libbeat := monitoring.NewRegistry()
+	libbeat.NewRegistry("pipeline")
+	got, err = AllComponentsSnapshotJSON(nil, libbeat)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"inputs": []}`, string(got))
+}
+
+func TestWarmupPeriod(t *testing.T) {
+	t.Cleanup(func() { SetWarmupPeriod(0) })
+
+	parent := monitoring.NewRegistry()
+	reg := NewMetricsRegistry("no-warmup", "foo-input", parent, logp.NewLogger("test"))
+	assert.Nil(t, reg.Get("is_warming_up"), "warmup metrics must be disabled by default")
+	assert.Nil(t, reg.Get("start_time"), "warmup metrics must be disabled by default")
+
+	SetWarmupPeriod(50 * time.Millisecond)
+	reg = NewMetricsRegistry("warmup", "foo-input", parent, logp.NewLogger("test"))
+
+	snapshot := monitoring.CollectStructSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, true, snapshot["is_warming_up"])
+	assert.NotEmpty(t, snapshot["start_time"])
+
+	assert.Eventually(t, func() bool {
+		snapshot := monitoring.CollectStructSnapshot(reg, monitoring.Full, false)
+		return snapshot["is_warming_up"] == false
+	}, 5*time.Second, 10*time.Millisecond, "input must stop warming up after the warmup period")
+}
+
+func TestRegisteredInputTypes(t *testing.T) {
+	_, cancel := NewInputRegistry("registered-type-b", "registered-id-b", monitoring.NewRegistry())
+	cancel()
+
+	parent := monitoring.NewRegistry()
+	log := logp.NewLogger("test")
+	NewMetricsRegistry("registered-id-a", "registered-type-a", parent, log)
+	CancelMetricsRegistry("registered-id-a", "registered-type-a", parent, log)
+
+	types := RegisteredInputTypes()
+	assert.Subset(t, types, []string{"registered-type-a", "registered-type-b"},
+		"types must stay registered after all instances unregistered")
+	assert.NotContains(t, types, "")
+	assert.IsIncreasing(t, types)
+}
@@ -0,0 +1,103 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package cbor provides a codec serializing events to CBOR.
+package cbor
+
+import (
+	"bytes"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/go-structform/cborl"
+	"github.com/elastic/go-structform/gotype"
+)
+
+// Encoder for serializing a beat.Event to CBOR.
+type Encoder struct {
+	buf    bytes.Buffer
+	folder *gotype.Iterator
+
+	version string
+	config  Config
+}
+
+// Config is used to pass encoding parameters to New.
+type Config struct {
+	LocalTime bool
+}
+
+var defaultConfig = Config{
+	LocalTime: false,
+}
+
+func init() {
+	codec.RegisterType("cbor", func(info beat.Info, cfg *config.C) (codec.Codec, error) {
+		config := defaultConfig
+		if cfg != nil {
+			if err := cfg.Unpack(&config); err != nil {
+				return nil, err
+			}
+		}
+
+		return New(info.Version, config), nil
+	})
+}
+
+// New creates a new CBOR Encoder.
+func New(version string, config Config) *Encoder {
+	e := &Encoder{version: version, config: config}
+	e.reset()
+	return e
+}
+
+func (e *Encoder) reset() {
+	visitor := cborl.NewVisitor(&e.buf)
+
+	var err error
+
+	// create new encoder with custom time.Time encoding
+	e.folder, err = gotype.NewIterator(visitor,
+		gotype.Folders(
+			codec.MakeUTCOrLocalTimestampEncoder(e.config.LocalTime),
+			codec.MakeBCTimestampEncoder(),
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Encode serializes a beat event to CBOR. It adds additional metadata in the
+// `@metadata` namespace. The returned slice is only valid until the next call
+// to Encode.
+func (e *Encoder) Encode(index string, event *beat.Event) ([]byte, error) {
+	e.buf.Reset()
+	err := e.folder.Fold(makeEvent(index, e.version, event))
+	if err != nil {
+		e.reset()
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// FileExtension implements codec.BinaryCodec. Files written with the CBOR
+// codec hold a CBOR sequence (RFC 8742) of events.
+func (e *Encoder) FileExtension() string {
+	return "cbor"
+}
@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cbor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/go-structform/cborl"
+	"github.com/elastic/go-structform/gotype"
+)
+
+func decode(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+
+	var out map[string]interface{}
+	unfolder, err := gotype.NewUnfolder(nil)
+	require.NoError(t, err)
+	require.NoError(t, unfolder.SetTarget(&out))
+	require.NoError(t, cborl.NewParser(unfolder).Parse(data))
+	return out
+}
+
+func TestCborCodecRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 3, 7, 11, 6, 39, 123000000, time.UTC)
+	enc := New("1.2.3", defaultConfig)
+
+	data, err := enc.Encode("test", &beat.Event{
+		Timestamp: ts,
+		Meta:      mapstr.M{"pipeline": "p"},
+		Fields: mapstr.M{
+			"message":  "hello",
+			"count":    42,
+			"negative": -7,
+			"ratio":    0.5,
+			"ok":       true,
+			"tags":     []string{"a", "b"},
+			"nested": mapstr.M{
+				"inner": mapstr.M{"value": uint64(1 << 40)},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Integers are decoded using the smallest type holding the value.
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp": "2024-03-07T11:06:39.123Z",
+		"@metadata": map[string]interface{}{
+			"beat":     "test",
+			"type":     "_doc",
+			"version":  "1.2.3",
+			"pipeline": "p",
+		},
+		"message":  "hello",
+		"count":    uint8(42),
+		"negative": int8(-7),
+		"ratio":    0.5,
+		"ok":       true,
+		"tags":     []interface{}{"a", "b"},
+		"nested": map[string]interface{}{
+			"inner": map[string]interface{}{"value": uint64(1 << 40)},
+		},
+	}, decode(t, data))
+}
+
+func TestCborCodecRegistered(t *testing.T) {
+	cfg := config.MustNewConfigFrom(mapstr.M{"cbor": mapstr.M{}})
+	var codecConfig codec.Config
+	require.NoError(t, cfg.Unpack(&codecConfig))
+
+	c, err := codec.CreateEncoder(beat.Info{Version: "1.2.3"}, codecConfig)
+	require.NoError(t, err)
+	assert.Implements(t, (*codec.BinaryCodec)(nil), c)
+}
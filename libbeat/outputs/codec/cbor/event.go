@@ -0,0 +1,43 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cbor
+
+import (
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+// makeEvent builds the document serialized for an event, with the same layout
+// as the json codec. A map is used instead of a struct with inlined fields,
+// as the CBOR visitor can't encode inlined maps.
+func makeEvent(index, version string, in *beat.Event) map[string]interface{} {
+	meta := make(map[string]interface{}, len(in.Meta)+3)
+	meta["beat"] = index
+	meta["type"] = "_doc"
+	meta["version"] = version
+	for k, v := range in.Meta {
+		meta[k] = v
+	}
+
+	doc := make(map[string]interface{}, len(in.Fields)+2)
+	doc["@timestamp"] = in.Timestamp
+	doc["@metadata"] = meta
+	for k, v := range in.Fields {
+		doc[k] = v
+	}
+	return doc
+}
@@ -22,3 +22,14 @@ import "github.com/elastic/beats/v7/libbeat/beat"
 type Codec interface {
 	Encode(index string, event *beat.Event) ([]byte, error)
 }
+
+// BinaryCodec is implemented by codecs producing a binary encoding. Encoded
+// events are self-delimiting, outputs writing a stream of events must not add
+// line separators between them.
+type BinaryCodec interface {
+	Codec
+
+	// FileExtension returns the extension used for files holding a stream of
+	// encoded events.
+	FileExtension() string
+}
@@ -18,6 +18,10 @@
 package console
 
 import (
+	"fmt"
+	"os"
+	"sync"
+
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/elastic-agent-libs/config"
 )
@@ -30,6 +34,90 @@ type Config struct {
 
 	BatchSize int
 	Queue     config.Namespace `config:"queue"`
+
+	// Buffer decouples publishing from writing to stdout, so a slow
+	// terminal or pipe does not stall the pipeline.
+	Buffer bufferConfig `config:"buffer"`
+
+	// Target selects where events are written: "stdout", "stderr" or "fd",
+	// the file descriptor FD.
+	Target string `config:"target"`
+	FD     int    `config:"fd" validate:"min=0"`
+}
+
+type bufferConfig struct {
+	// Size is the number of lines to buffer. 0 disables the buffer.
+	Size int `config:"size" validate:"min=0"`
+
+	// Mode is the policy applied when the buffer is full: "block" waits
+	// for free space, "drop" discards the line.
+	Mode string `config:"mode"`
+}
+
+const (
+	bufferModeBlock = "block"
+	bufferModeDrop  = "drop"
+)
+
+const (
+	targetStdout = "stdout"
+	targetStderr = "stderr"
+	targetFD     = "fd"
+)
+
+var defaultConfig = Config{
+	Buffer: bufferConfig{
+		Mode: bufferModeBlock,
+	},
+	Target: targetStdout,
+}
+
+func (c *Config) Validate() error {
+	switch c.Target {
+	case targetStdout, targetStderr, targetFD:
+		return nil
+	default:
+		return fmt.Errorf("invalid target %q, must be one of %q, %q or %q", c.Target, targetStdout, targetStderr, targetFD)
+	}
+}
+
+// output returns the file events are written to.
+func (c *Config) output() *os.File {
+	switch c.Target {
+	case targetStderr:
+		return os.Stderr
+	case targetFD:
+		return fdFile(c.FD)
+	default:
+		return os.Stdout
+	}
 }
 
-var defaultConfig = Config{}
+// fdFiles keeps the files created for file descriptors. An os.File closes
+// its descriptor once garbage collected, which would break the outputs
+// created by a reload for the same descriptor.
+var fdFiles = struct {
+	sync.Mutex
+	files map[int]*os.File
+}{files: map[int]*os.File{}}
+
+// fdFile returns the file for the file descriptor fd.
+func fdFile(fd int) *os.File {
+	fdFiles.Lock()
+	defer fdFiles.Unlock()
+	f, ok := fdFiles.files[fd]
+	if !ok {
+		f = os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+		fdFiles.files[fd] = f
+	}
+	return f
+}
+
+func (c *bufferConfig) Validate() error {
+	switch c.Mode {
+	case bufferModeBlock, bufferModeDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid buffer mode %q, must be one of %q or %q", c.Mode, bufferModeBlock, bufferModeDrop)
+	}
+}
@@ -20,9 +20,13 @@ package console
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/outputs"
@@ -40,8 +44,45 @@ type console struct {
 	writer   *bufio.Writer
 	codec    codec.Codec
 	index    string
+
+	// Set if the lines are written to out asynchronously via a bounded buffer.
+	lines      chan bufferedLine
+	dropOnFull bool
+	dropped    atomic.Uint64
+	wg         sync.WaitGroup
+
+	// closeMu guards sending to lines against Close closing the channel.
+	closeMu sync.RWMutex
+	closed  bool
+
+	// writeMu guards writer if lines are written by the writer go-routine.
+	writeMu sync.Mutex
+
+	// Serialization cost of the batch being published.
+	serializationTime  time.Duration
+	serializationBytes int
+}
+
+// bufferedBatch tracks a batch whose lines are waiting in the buffer. The
+// batch is ACKed once the last of its lines has been written and flushed.
+type bufferedBatch struct {
+	batch  publisher.Batch
+	events int
+
+	// pending counts the lines in the buffer, plus one reference held by
+	// Publish until all lines of the batch have been buffered.
+	pending   atomic.Int64
+	dropped   atomic.Int64
+	cancelled atomic.Bool
+}
+
+type bufferedLine struct {
+	line  []byte
+	batch *bufferedBatch
 }
 
+var errClosed = errors.New("console output is closed")
+
 func init() {
 	outputs.RegisterType("console", makeConsole)
 }
@@ -72,12 +113,15 @@ func makeConsole(
 	}
 
 	index := beat.Beat
-	c, err := newConsole(index, observer, enc, beat.Logger)
+	c, err := newConsole(index, config.output(), observer, enc, beat.Logger)
 	if err != nil {
 		return outputs.Fail(fmt.Errorf("console output initialization failed with: %w", err))
 	}
+	if config.Buffer.Size > 0 {
+		c.startBuffer(config.Buffer.Size, config.Buffer.Mode == bufferModeDrop)
+	}
 
-	// check stdout actually being available
+	// check the target actually being available
 	if runtime.GOOS != "windows" {
 		if _, err = c.out.Stat(); err != nil {
 			err = fmt.Errorf("console output initialization failed with: %w", err)
@@ -88,22 +132,99 @@ func makeConsole(
 	return outputs.Success(config.Queue, config.BatchSize, 0, nil, c)
 }
 
-func newConsole(index string, observer outputs.Observer, codec codec.Codec, logger *logp.Logger) (*console, error) {
-	c := &console{log: logger.Named("console"), out: os.Stdout, codec: codec, observer: observer, index: index}
+func newConsole(index string, out *os.File, observer outputs.Observer, codec codec.Codec, logger *logp.Logger) (*console, error) {
+	c := &console{log: logger.Named("console"), out: out, codec: codec, observer: observer, index: index}
 	c.writer = bufio.NewWriterSize(c.out, 8*1024)
 	return c, nil
 }
 
-func (c *console) Close() error { return nil }
-func (c *console) Publish(_ context.Context, batch publisher.Batch) error {
+// startBuffer makes the console write lines from a background go-routine.
+// If dropOnFull is set, lines are dropped instead of blocking the publisher
+// once size lines are waiting to be written.
+func (c *console) startBuffer(size int, dropOnFull bool) {
+	c.lines = make(chan bufferedLine, size)
+	c.dropOnFull = dropOnFull
+	c.startWriter()
+}
+
+func (c *console) startWriter() {
+	c.wg.Add(1)
+	go c.runWriter()
+}
+
+func (c *console) runWriter() {
+	defer c.wg.Done()
+	for item := range c.lines {
+		c.writeMu.Lock()
+		err := c.writeBuffer(item.line)
+		c.writeMu.Unlock()
+
+		if err != nil {
+			c.observer.WriteError(err)
+			c.log.Errorf("Unable to publish events to console: %+v", err)
+			item.batch.dropped.Add(1)
+		} else {
+			c.observer.WriteBytes(len(item.line))
+		}
+		c.release(item.batch)
+	}
+}
+
+// release drops a reference to the buffered batch. The last reference
+// flushes the lines written and resolves the batch.
+func (c *console) release(b *bufferedBatch) {
+	if b.pending.Add(-1) != 0 {
+		return
+	}
+
+	c.writeMu.Lock()
+	c.writer.Flush()
+	c.writeMu.Unlock()
+
+	if b.cancelled.Load() {
+		b.batch.Cancelled()
+		return
+	}
+	b.batch.ACK()
+
+	dropped := int(b.dropped.Load())
+	c.observer.PermanentErrors(dropped)
+	c.observer.AckedEvents(b.events - dropped)
+}
+
+func (c *console) Close() error {
+	if c.lines != nil {
+		// Senders blocked on a full buffer hold closeMu, the writer keeps
+		// draining the buffer until they are done.
+		c.closeMu.Lock()
+		if !c.closed {
+			c.closed = true
+			close(c.lines)
+		}
+		c.closeMu.Unlock()
+		c.wg.Wait()
+		if dropped := c.dropped.Load(); dropped > 0 {
+			c.log.Warnf("Console output dropped %d lines because the buffer was full", dropped)
+		}
+	}
+	return nil
+}
+
+func (c *console) Publish(ctx context.Context, batch publisher.Batch) error {
 	st := c.observer
 	events := batch.Events()
 	st.NewBatch(len(events))
 
+	c.serializationTime, c.serializationBytes = 0, 0
+	defer func() { st.ReportSerialization(c.serializationTime, c.serializationBytes) }()
+
+	if c.lines != nil {
+		return c.publishBuffered(ctx, batch, events)
+	}
+
 	dropped := 0
 	for i := range events {
-		ok := c.publishEvent(&events[i])
-		if !ok {
+		if !c.publishEvent(&events[i]) {
 			dropped++
 		}
 	}
@@ -117,17 +238,50 @@ func (c *console) Publish(_ context.Context, batch publisher.Batch) error {
 	return nil
 }
 
+// publishBuffered passes the lines of the batch to the writer go-routine.
+// The batch is ACKed by the writer once all its lines have been flushed.
+func (c *console) publishBuffered(ctx context.Context, batch publisher.Batch, events []publisher.Event) error {
+	b := &bufferedBatch{batch: batch, events: len(events)}
+	b.pending.Store(1)
+	defer c.release(b)
+
+	for i := range events {
+		serializedEvent, ok := c.encode(&events[i])
+		if !ok {
+			b.dropped.Add(1)
+			continue
+		}
+		if err := c.bufferLine(ctx, b, serializedEvent); err != nil {
+			// The output is shutting down while blocked on a full buffer.
+			b.cancelled.Store(true)
+			return err
+		}
+	}
+	return nil
+}
+
 var nl = []byte("\n")
 
-func (c *console) publishEvent(event *publisher.Event) bool {
+func (c *console) encode(event *publisher.Event) ([]byte, bool) {
+	begin := time.Now()
 	serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+	c.serializationTime += time.Since(begin)
 	if err != nil {
 		if !event.Guaranteed() {
-			return false
+			return nil, false
 		}
 
 		c.log.Errorf("Unable to encode event: %+v", err)
 		c.log.Debugf("Failed event: %v", event)
+		return nil, false
+	}
+	c.serializationBytes += len(serializedEvent)
+	return serializedEvent, true
+}
+
+func (c *console) publishEvent(event *publisher.Event) bool {
+	serializedEvent, ok := c.encode(event)
+	if !ok {
 		return false
 	}
 
@@ -147,6 +301,42 @@ func (c *console) publishEvent(event *publisher.Event) bool {
 	return true
 }
 
+// bufferLine passes a copy of the encoded event to the writer go-routine, as
+// the codec may reuse its buffer.
+func (c *console) bufferLine(ctx context.Context, b *bufferedBatch, serializedEvent []byte) error {
+	line := make([]byte, 0, len(serializedEvent)+len(nl))
+	line = append(line, serializedEvent...)
+	line = append(line, nl...)
+	item := bufferedLine{line: line, batch: b}
+
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	if c.closed {
+		return errClosed
+	}
+
+	b.pending.Add(1)
+	if c.dropOnFull {
+		select {
+		case c.lines <- item:
+		default:
+			b.pending.Add(-1)
+			b.dropped.Add(1)
+			c.dropped.Add(1)
+			c.log.Debug("Dropping event, console buffer is full")
+		}
+		return nil
+	}
+
+	select {
+	case c.lines <- item:
+		return nil
+	case <-ctx.Done():
+		b.pending.Add(-1)
+		return ctx.Err()
+	}
+}
+
 func (c *console) writeBuffer(buf []byte) error {
 	written := 0
 	for written < len(buf) {
@@ -27,6 +27,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
@@ -36,6 +37,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
 	"github.com/elastic/beats/v7/libbeat/outputs/outest"
 	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent-libs/mapstr"
 )
@@ -129,9 +131,141 @@ func TestConsoleOutput(t *testing.T) {
 	}
 }
 
+func TestConsoleOutputBuffered(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	enc := format.New(fmtstr.MustCompileEvent("%{[event]}"))
+	batch := outest.NewBatch(
+		beat.Event{Fields: event("event", "one")},
+		beat.Event{Fields: event("event", "two")},
+	)
+
+	lines, err := withStdout(func() {
+		c, _ := newConsole("test", os.Stdout, outputs.NewNilObserver(), enc, logger)
+		c.startBuffer(1, false)
+		assert.NoError(t, c.Publish(context.Background(), batch))
+		assert.NoError(t, c.Close())
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", lines)
+	if assert.Len(t, batch.Signals, 1) {
+		assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	}
+}
+
+func TestConsoleOutputBufferDropsWhenFull(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	enc := format.New(fmtstr.MustCompileEvent("%{[event]}"))
+	batch := outest.NewBatch(
+		beat.Event{Fields: event("event", "one")},
+		beat.Event{Fields: event("event", "two")},
+		beat.Event{Fields: event("event", "three")},
+	)
+
+	lines, err := withStdout(func() {
+		c, _ := newConsole("test", os.Stdout, outputs.NewNilObserver(), enc, logger)
+		// No writer is running, so the buffer fills up after the first line.
+		c.lines = make(chan bufferedLine, 1)
+		c.dropOnFull = true
+
+		assert.NoError(t, c.Publish(context.Background(), batch))
+		assert.Equal(t, uint64(2), c.dropped.Load())
+		assert.Empty(t, batch.Signals, "the batch must not be ACKed before its lines are written")
+
+		c.startWriter()
+		assert.NoError(t, c.Close())
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "one\n", lines)
+	if assert.Len(t, batch.Signals, 1) {
+		assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	}
+}
+
+func TestConsoleOutputBufferBlockCancelled(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	enc := format.New(fmtstr.MustCompileEvent("%{[event]}"))
+	c, _ := newConsole("test", os.Stdout, outputs.NewNilObserver(), enc, logger)
+	// No writer is running, so sending to the buffer blocks.
+	c.lines = make(chan bufferedLine)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	batch := outest.NewBatch(
+		beat.Event{Fields: event("event", "one")},
+		beat.Event{Fields: event("event", "two")},
+	)
+	assert.ErrorIs(t, c.Publish(ctx, batch), context.Canceled)
+	if assert.Len(t, batch.Signals, 1) {
+		assert.Equal(t, outest.BatchCancelled, batch.Signals[0].Tag)
+	}
+}
+
+func TestConsoleOutputBufferPublishAfterClose(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	enc := format.New(fmtstr.MustCompileEvent("%{[event]}"))
+	c, _ := newConsole("test", os.Stdout, outputs.NewNilObserver(), enc, logger)
+	c.startBuffer(1, false)
+	require.NoError(t, c.Close())
+
+	batch := outest.NewBatch(beat.Event{Fields: event("event", "one")})
+	assert.ErrorIs(t, c.Publish(context.Background(), batch), errClosed)
+	if assert.Len(t, batch.Signals, 1) {
+		assert.Equal(t, outest.BatchCancelled, batch.Signals[0].Tag)
+	}
+}
+
+func TestConsoleOutputTarget(t *testing.T) {
+	publish := func(t *testing.T, cfg map[string]interface{}) {
+		cfg["codec.format.string"] = "%{[event]}"
+		group, err := makeConsole(nil, beat.Info{Beat: "test", Logger: logp.NewTestingLogger(t, "")},
+			outputs.NewNilObserver(), config.MustNewConfigFrom(cfg))
+		require.NoError(t, err)
+		batch := outest.NewBatch(beat.Event{Fields: event("event", "one")})
+		assert.NoError(t, group.Clients[0].Publish(context.Background(), batch))
+		assert.NoError(t, group.Clients[0].Close())
+	}
+	read := func(t *testing.T, fn func(w *os.File)) string {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer r.Close()
+		fn(w)
+		w.Close()
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	t.Run("stderr", func(t *testing.T) {
+		stdout, err := withStdout(func() {
+			stderr := read(t, func(w *os.File) {
+				orig := os.Stderr
+				os.Stderr = w
+				defer func() { os.Stderr = orig }()
+				publish(t, map[string]interface{}{"target": "stderr"})
+			})
+			assert.Equal(t, "one\n", stderr)
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, stdout)
+	})
+
+	t.Run("file descriptor", func(t *testing.T) {
+		out := read(t, func(w *os.File) {
+			publish(t, map[string]interface{}{"target": "fd", "fd": int(w.Fd())})
+		})
+		assert.Equal(t, "one\n", out)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		_, err := makeConsole(nil, beat.Info{Beat: "test", Logger: logp.NewTestingLogger(t, "")},
+			outputs.NewNilObserver(), config.MustNewConfigFrom(map[string]interface{}{"target": "file"}))
+		assert.ErrorContains(t, err, "invalid target")
+	})
+}
+
 func run(codec codec.Codec, logger *logp.Logger, batches ...publisher.Batch) (string, error) {
 	return withStdout(func() {
-		c, _ := newConsole("test", outputs.NewNilObserver(), codec, logger)
+		c, _ := newConsole("test", os.Stdout, outputs.NewNilObserver(), codec, logger)
 		for _, b := range batches {
 			c.Publish(context.Background(), b)
 		}
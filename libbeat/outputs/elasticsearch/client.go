@@ -59,6 +59,14 @@ type Client struct {
 	// forwarded to this index. Otherwise, they will be dropped.
 	deadLetterIndex string
 
+	// If groupByIndex is set, the events of a batch are sent in one bulk
+	// request per target index.
+	groupByIndex bool
+
+	// If deduplicator is set, events whose fingerprint was indexed recently
+	// are not sent.
+	deduplicator *deduplicator
+
 	log                    *logp.Logger
 	pLogIndex              *periodic.Doer
 	pLogIndexTryDeadLetter *periodic.Doer
@@ -79,6 +87,14 @@ type clientSettings struct {
 	// If deadLetterIndex is set, events with bulk-ingest errors will be
 	// forwarded to this index. Otherwise, they will be dropped.
 	deadLetterIndex string
+
+	// If groupByIndex is set, the events of a batch are sent in one bulk
+	// request per target index.
+	groupByIndex bool
+
+	// If deduplicator is set, events whose fingerprint was indexed recently
+	// are not sent. It is shared by all the clients of an output.
+	deduplicator *deduplicator
 }
 
 type bulkResultStats struct {
@@ -192,6 +208,8 @@ func NewClient(
 		pipelineSelector: pipeline,
 		observer:         observer,
 		deadLetterIndex:  s.deadLetterIndex,
+		groupByIndex:     s.groupByIndex,
+		deduplicator:     s.deduplicator,
 
 		log:                    log,
 		pLogDeadLetter:         pLogDeadLetter,
@@ -235,6 +253,8 @@ func (client *Client) Clone() *Client {
 			indexSelector:    client.indexSelector,
 			pipelineSelector: client.pipelineSelector,
 			deadLetterIndex:  client.deadLetterIndex,
+			groupByIndex:     client.groupByIndex,
+			deduplicator:     client.deduplicator,
 		},
 		nil, // XXX: do not pass connection callback?
 		client.log,
@@ -248,8 +268,19 @@ func (client *Client) Publish(ctx context.Context, batch publisher.Batch) error
 	span.Context.SetLabel("events_original", len(batch.Events()))
 	client.observer.NewBatch(len(batch.Events()))
 
+	// Events indexed recently are acknowledged without sending them again.
+	events, duplicates := client.deduplicator.dropDuplicates(batch.Events())
+	if duplicates > 0 {
+		span.Context.SetLabel("events_deduplicated", duplicates)
+		client.observer.DeduplicatedEvents(duplicates)
+	}
+
+	if client.groupByIndex {
+		return client.publishByIndex(ctx, batch, events)
+	}
+
 	// Create and send the bulk request.
-	bulkResult := client.doBulkRequest(ctx, batch)
+	bulkResult := client.doBulkRequest(ctx, events)
 	span.Context.SetLabel("events_encoded", len(bulkResult.events))
 	if bulkResult.connErr != nil {
 		// If there was a connection-level error there is no per-item response,
@@ -272,20 +303,118 @@ func (client *Client) Publish(ctx context.Context, batch publisher.Batch) error
 	return nil
 }
 
-// Encode a batch's events into a bulk publish request, send the request to
+// publishByIndex sends the events of batch in one bulk request per target
+// index, so failures of one request only retry the events of its index.
+// If a request gets no response from Elasticsearch, the events of the
+// indices not sent yet are retried as well.
+func (client *Client) publishByIndex(ctx context.Context, batch publisher.Batch, events []publisher.Event) error {
+	var (
+		eventsToRetry []publisher.Event
+		connErr       error
+		connLost      bool
+	)
+	for _, indexEvents := range groupEventsByIndex(events) {
+		if connLost {
+			client.observer.RetryableErrors(len(indexEvents))
+			eventsToRetry = append(eventsToRetry, indexEvents...)
+			continue
+		}
+		var (
+			status int
+			err    error
+		)
+		eventsToRetry, status, err = client.publishIndexEvents(ctx, indexEvents, eventsToRetry)
+		if err != nil {
+			connErr = err
+			connLost = status == 0
+		}
+	}
+
+	if connErr != nil {
+		err := apm.CaptureError(ctx, fmt.Errorf("failed to perform any bulk index operations: %w", connErr))
+		err.Send()
+		client.log.Error(err)
+	}
+	if len(eventsToRetry) > 0 {
+		batch.RetryEvents(eventsToRetry)
+	} else {
+		batch.ACK()
+	}
+	return connErr
+}
+
+// publishIndexEvents sends events in a single bulk request and returns
+// eventsToRetry with the events to retry appended. Events rejected as too
+// large are split in halves and sent again, a single event rejected as too
+// large is dropped. If the request failed, the HTTP status and the error are
+// returned as well. The status is 0 if there was no response.
+func (client *Client) publishIndexEvents(
+	ctx context.Context,
+	events []publisher.Event,
+	eventsToRetry []publisher.Event,
+) ([]publisher.Event, int, error) {
+	bulkResult := client.doBulkRequest(ctx, events)
+	if bulkResult.connErr == nil {
+		failed, stats := client.bulkCollectPublishFails(bulkResult)
+		stats.reportToObserver(client.observer)
+		return append(eventsToRetry, failed...), 0, nil
+	}
+
+	events = bulkResult.events
+	if bulkResult.status != http.StatusRequestEntityTooLarge {
+		client.observer.RetryableErrors(len(events))
+		return append(eventsToRetry, events...), bulkResult.status, bulkResult.connErr
+	}
+	if len(events) <= 1 {
+		client.observer.PermanentErrors(len(events))
+		client.log.Error(errPayloadTooLarge)
+		return eventsToRetry, 0, nil
+	}
+
+	client.observer.BatchSplit()
+	half := len(events) / 2
+	eventsToRetry, status, err := client.publishIndexEvents(ctx, events[:half], eventsToRetry)
+	if err != nil {
+		client.observer.RetryableErrors(len(events) - half)
+		return append(eventsToRetry, events[half:]...), status, err
+	}
+	return client.publishIndexEvents(ctx, events[half:], eventsToRetry)
+}
+
+// groupEventsByIndex splits events by target index, in the order the indices
+// first appear. Events that are not encoded, or failed encoding, are grouped
+// together and dropped when the request is encoded.
+func groupEventsByIndex(events []publisher.Event) [][]publisher.Event {
+	var groups [][]publisher.Event
+	indices := map[string]int{}
+	for _, event := range events {
+		var index string
+		if encoded, ok := event.EncodedEvent.(*encodedEvent); ok && encoded.err == nil {
+			index = encoded.index
+		}
+		i, ok := indices[index]
+		if !ok {
+			i = len(groups)
+			indices[index] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], event)
+	}
+	return groups
+}
+
+// Encode events into a bulk publish request, send the request to
 // Elasticsearch, and return the resulting metadata.
 // Reports the network request latency to the client's metrics observer.
-// The events list in the result will be shorter than the original batch if
+// The events list in the result will be shorter than rawEvents if
 // some events couldn't be encoded. In this case, the removed events will
 // be reported to the Client's metrics observer via PermanentErrors.
 func (client *Client) doBulkRequest(
 	ctx context.Context,
-	batch publisher.Batch,
+	rawEvents []publisher.Event,
 ) bulkResult {
 	var result bulkResult
 
-	rawEvents := batch.Events()
-
 	// encode events into bulk request buffer, dropping failed elements from
 	// events slice
 	resultEvents, bulkItems := client.bulkEncodePublishRequest(client.conn.GetVersion(), rawEvents)
@@ -348,6 +477,10 @@ func (client *Client) handleBulkResultError(
 func (client *Client) bulkEncodePublishRequest(version version.V, data []publisher.Event) ([]publisher.Event, []interface{}) {
 	okEvents := data[:0]
 	bulkItems := []interface{}{}
+	var (
+		encodingTime  time.Duration
+		encodingBytes int
+	)
 	for i := range data {
 		if data[i].EncodedEvent == nil {
 			client.log.Error("Elasticsearch output received unencoded publisher.Event")
@@ -373,8 +506,18 @@ func (client *Client) bulkEncodePublishRequest(version version.V, data []publish
 			// knows not to re-encode it
 			bulkItems = append(bulkItems, meta, eslegclient.RawEncoding{Encoding: event.encoding})
 		}
+		if event.encodingTime > 0 {
+			encodingTime += event.encodingTime
+			encodingBytes += len(event.encoding)
+			event.encodingTime = 0
+		}
 		okEvents = append(okEvents, data[i])
 	}
+	if encodingBytes > 0 {
+		// Events are encoded when entering the queue, the cost of encoding
+		// the events of the batch is reported when it is first sent.
+		client.observer.ReportSerialization(encodingTime, encodingBytes)
+	}
 	return okEvents, bulkItems
 }
 
@@ -486,6 +629,7 @@ func (client *Client) applyItemStatus(
 			stats.deadLetter++
 		} else {
 			stats.acked++
+			client.deduplicator.markIndexed(encodedEvent.fingerprint)
 		}
 		return false // no retry needed
 	}
@@ -494,6 +638,7 @@ func (client *Client) applyItemStatus(
 		// 409 is used to indicate there is already an event with the same ID, or
 		// with identical Time Series Data Stream dimensions when TSDS is active.
 		stats.duplicates++
+		client.deduplicator.markIndexed(encodedEvent.fingerprint)
 		return false // no retry needed
 	}
 
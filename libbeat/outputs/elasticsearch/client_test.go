@@ -281,6 +281,130 @@ func TestPublish(t *testing.T) {
 	})
 }
 
+// fieldIndexSelector selects the index from the index field of the event.
+type fieldIndexSelector struct{}
+
+func (fieldIndexSelector) Select(event *beat.Event) (string, error) {
+	index, err := event.GetValue("index")
+	if err != nil {
+		return "", err
+	}
+	return index.(string), nil
+}
+
+func TestPublishGroupByIndex(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	makeGroupingTestClient := func(t *testing.T, url string) (*Client, *monitoring.Registry) {
+		reg := monitoring.NewRegistry()
+		client, err := NewClient(
+			clientSettings{
+				observer:      outputs.NewStats(reg),
+				connection:    eslegclient.ConnectionSettings{URL: url},
+				indexSelector: fieldIndexSelector{},
+				groupByIndex:  true,
+			},
+			nil,
+			logger,
+		)
+		require.NoError(t, err)
+		return client, reg
+	}
+	makeEvents := func(indices ...string) []publisher.Event {
+		events := make([]publisher.Event, len(indices))
+		for i, index := range indices {
+			events[i] = publisher.Event{Content: beat.Event{Fields: mapstr.M{"index": index, "field": i}}}
+		}
+		return events
+	}
+	// respondOK acknowledges every item of the bulk request.
+	respondOK := func(w http.ResponseWriter, body string) {
+		items := make([]string, strings.Count(body, `"_index"`))
+		for i := range items {
+			items[i] = `{"index":{"status":200}}`
+		}
+		_, _ = io.WriteString(w, `{"items":[`+strings.Join(items, ",")+`]}`)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("only retries the events of the failed index", func(t *testing.T) {
+		var requests []string
+		esMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body := string(b)
+			requests = append(requests, body)
+			if strings.Contains(body, `"_index":"bad"`) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			respondOK(w, body)
+		}))
+		defer esMock.Close()
+		client, reg := makeGroupingTestClient(t, esMock.URL)
+
+		events := makeEvents("good", "bad", "good", "other")
+		batch := encodeBatch(client, &batchMock{events: events})
+		err := client.Publish(ctx, batch)
+
+		require.Error(t, err, "the error should be reported for the output to back off")
+		assert.Len(t, requests, 3, "one bulk request per index")
+		assert.False(t, batch.ack)
+		assert.Equal(t, []publisher.Event{events[1]}, batch.retryEvents)
+		assertRegistryUint(t, reg, "events.acked", 3, "events of the other indices should be acked")
+		assertRegistryUint(t, reg, "events.failed", 1, "only the event of the failed index should be retried")
+	})
+
+	t.Run("connection errors retry the remaining indices", func(t *testing.T) {
+		esMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body := string(b)
+			if strings.Contains(body, `"_index":"bad"`) {
+				// Close the connection without a response.
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			respondOK(w, body)
+		}))
+		defer esMock.Close()
+		client, reg := makeGroupingTestClient(t, esMock.URL)
+
+		events := makeEvents("good", "bad", "other")
+		batch := encodeBatch(client, &batchMock{events: events})
+		err := client.Publish(ctx, batch)
+
+		require.Error(t, err)
+		assert.Equal(t, []publisher.Event{events[1], events[2]}, batch.retryEvents)
+		assertRegistryUint(t, reg, "events.acked", 1, "events sent before the error should be acked")
+		assertRegistryUint(t, reg, "events.failed", 2, "the events not sent should be retried")
+	})
+
+	t.Run("splits indices too large", func(t *testing.T) {
+		esMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body := string(b)
+			if strings.Count(body, `"_index"`) > 1 || strings.Contains(body, `"field":3`) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			respondOK(w, body)
+		}))
+		defer esMock.Close()
+		client, reg := makeGroupingTestClient(t, esMock.URL)
+
+		events := makeEvents("large", "large", "large", "large")
+		batch := encodeBatch(client, &batchMock{events: events})
+		err := client.Publish(ctx, batch)
+
+		require.NoError(t, err)
+		assert.True(t, batch.ack)
+		assert.False(t, batch.didSplit, "the batch should be split by the client")
+		assertRegistryUint(t, reg, "events.acked", 3, "the events small enough should be acked")
+		assertRegistryUint(t, reg, "events.dropped", 1, "the single event too large should be dropped")
+	})
+}
+
 func assertRegistryUint(t *testing.T, reg *monitoring.Registry, key string, expected uint64, message string) {
 	t.Helper()
 	value := reg.Get(key).(*monitoring.Uint)
@@ -963,6 +1087,49 @@ func TestBulkEncodeEvents(t *testing.T) {
 	}
 }
 
+func TestBulkEncodeReportsSerialization(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	info := beat.Info{
+		IndexPrefix: "test",
+		Version:     version.GetDefaultVersion(),
+		Logger:      logger,
+	}
+	im, err := idxmgmt.DefaultSupport(info, c.NewConfig())
+	require.NoError(t, err)
+	index, pipeline, err := buildSelectors(im, info, c.NewConfig())
+	require.NoError(t, err)
+
+	reg := monitoring.NewRegistry()
+	client, err := NewClient(
+		clientSettings{
+			observer:         outputs.NewStats(reg),
+			indexSelector:    index,
+			pipelineSelector: pipeline,
+		},
+		nil,
+		logger,
+	)
+	require.NoError(t, err)
+
+	events := encodeEvents(client, []publisher.Event{
+		{Content: beat.Event{Timestamp: time.Now(), Fields: mapstr.M{"message": "test 1"}}},
+		{Content: beat.Event{Timestamp: time.Now(), Fields: mapstr.M{"message": "test 2"}}},
+	})
+	size := 0
+	for _, event := range events {
+		size += len(event.EncodedEvent.(*encodedEvent).encoding)
+	}
+
+	// The serialization of events sent again is not reported twice.
+	client.bulkEncodePublishRequest(*libversion.MustNew(info.Version), events)
+	client.bulkEncodePublishRequest(*libversion.MustNew(info.Version), events)
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.EqualValues(t, 1, snapshot.Ints["serialization.bytes.histogram.count"])
+	assert.EqualValues(t, size, snapshot.Ints["serialization.bytes.histogram.max"])
+	assert.EqualValues(t, 1, snapshot.Ints["serialization.duration_us.histogram.count"])
+}
+
 func TestBulkEncodeEventsWithOpType(t *testing.T) {
 	cases := []mapstr.M{
 		{"_id": "111", "op_type": e.OpTypeIndex, "message": "test 1", "bulkIndex": 0},
@@ -1118,7 +1285,7 @@ func TestBulkRequestHasFilterPath(t *testing.T) {
 		client := makePublishTestClient(t, esMock.URL, nil)
 
 		batch := encodeBatch(client, &batchMock{events: []publisher.Event{event1}})
-		result := client.doBulkRequest(ctx, batch)
+		result := client.doBulkRequest(ctx, batch.Events())
 		require.NoError(t, result.connErr)
 		// Only param should be the standard filter path
 		require.Equal(t, len(reqParams), 1, "Only bulk request param should be standard filter path")
@@ -1146,7 +1313,7 @@ func TestBulkRequestHasFilterPath(t *testing.T) {
 		client := makePublishTestClient(t, esMock.URL, configParams)
 
 		batch := encodeBatch(client, &batchMock{events: []publisher.Event{event1}})
-		result := client.doBulkRequest(ctx, batch)
+		result := client.doBulkRequest(ctx, batch.Events())
 		require.NoError(t, result.connErr)
 		require.Equal(t, len(reqParams), 2, "Bulk request should include configured parameter and standard filter path")
 		require.Equal(t, filterPathValue, reqParams.Get(filterPathKey), "Bulk request should include standard filter path")
@@ -45,6 +45,12 @@ type ElasticsearchConfig struct {
 	AllowOlderVersion  bool              `config:"allow_older_versions"`
 	Queue              config.Namespace  `config:"queue"`
 
+	IndexSanitizer indexSanitizerConfig `config:"index_sanitizer"`
+	DocumentID     documentIDConfig     `config:"document_id"`
+	Warmup         warmupConfig         `config:"warmup"`
+	GroupByIndex   bool                 `config:"group_by_index"`
+	Deduplication  deduplicationConfig  `config:"deduplication"`
+
 	Transport httpcommon.HTTPTransportSettings `config:",inline"`
 }
 
@@ -74,8 +80,11 @@ var (
 			Init: 1 * time.Second,
 			Max:  60 * time.Second,
 		},
-		BulkMaxSize: defaultBulkSize,
-		Transport:   esDefaultTransportSettings(),
+		BulkMaxSize:    defaultBulkSize,
+		IndexSanitizer: defaultIndexSanitizerConfig,
+		Warmup:         defaultWarmupConfig,
+		Deduplication:  defaultDeduplicationConfig,
+		Transport:      esDefaultTransportSettings(),
 	}
 )
 
@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// deduplicationConfig configures the suppression of events whose fingerprint
+// has been indexed recently.
+type deduplicationConfig struct {
+	Enabled bool          `config:"enabled"`
+	Field   string        `config:"field"`
+	Window  time.Duration `config:"window"`
+	MaxKeys int           `config:"max_keys"`
+}
+
+var defaultDeduplicationConfig = deduplicationConfig{
+	Enabled: false,
+	Field:   "fingerprint",
+	Window:  5 * time.Minute,
+	MaxKeys: 100000,
+}
+
+func (c *deduplicationConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Field == "" {
+		return errors.New("deduplication.field must be set")
+	}
+	if c.Window <= 0 {
+		return errors.New("deduplication.window must be greater than 0")
+	}
+	if c.MaxKeys <= 0 {
+		return errors.New("deduplication.max_keys must be greater than 0")
+	}
+	return nil
+}
+
+// deduplicator remembers the fingerprints of the events indexed within the
+// configured window, so events delivered again are not sent a second time.
+// Fingerprints are only recorded once Elasticsearch accepted the event, so
+// events that failed are always retried. Fingerprints are kept in full, an
+// event is never suppressed because of a different fingerprint. If more
+// fingerprints are indexed within the window than fit into the cache, the
+// least recently seen ones are evicted and their duplicates are sent again.
+// A deduplicator is shared by all the clients of an output.
+type deduplicator struct {
+	field  string
+	window time.Duration
+	now    func() time.Time
+
+	// indexed maps fingerprints to the time they were last indexed.
+	mu      sync.Mutex
+	indexed *lru.Cache[string, time.Time]
+}
+
+// newDeduplicator returns the deduplicator for cfg, or nil if deduplication
+// is disabled.
+func newDeduplicator(cfg deduplicationConfig) (*deduplicator, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	indexed, err := lru.New[string, time.Time](cfg.MaxKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &deduplicator{
+		field:   cfg.Field,
+		window:  cfg.Window,
+		now:     time.Now,
+		indexed: indexed,
+	}, nil
+}
+
+// fingerprint returns the fingerprint of event, or an empty string if the
+// event has none. Only string fingerprints are supported.
+func (d *deduplicator) fingerprint(event *beat.Event) string {
+	if d == nil {
+		return ""
+	}
+	v, err := event.GetValue(d.field)
+	if err != nil {
+		return ""
+	}
+	fingerprint, _ := v.(string)
+	return fingerprint
+}
+
+// dropDuplicates returns the events whose fingerprint was not indexed within
+// the window and the number of events dropped. events is not modified, as the
+// batch may still be split and retried as a whole.
+func (d *deduplicator) dropDuplicates(events []publisher.Event) ([]publisher.Event, int) {
+	if d == nil {
+		return events, 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	kept := make([]publisher.Event, 0, len(events))
+	for _, event := range events {
+		if d.isDuplicate(event, now) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	return kept, len(events) - len(kept)
+}
+
+func (d *deduplicator) isDuplicate(event publisher.Event, now time.Time) bool {
+	encoded, ok := event.EncodedEvent.(*encodedEvent)
+	if !ok || encoded.err != nil || encoded.deadLetter || encoded.fingerprint == "" {
+		return false
+	}
+	indexedAt, ok := d.indexed.Get(encoded.fingerprint)
+	if !ok {
+		return false
+	}
+	if now.Sub(indexedAt) >= d.window {
+		d.indexed.Remove(encoded.fingerprint)
+		return false
+	}
+	return true
+}
+
+// markIndexed records that the event with the given fingerprint has been
+// indexed.
+func (d *deduplicator) markIndexed(fingerprint string) {
+	if d == nil || fingerprint == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.indexed.Add(fingerprint, d.now())
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/esleg/eslegclient"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestDeduplicationConfig(t *testing.T) {
+	unpack := func(settings mapstr.M) error {
+		cfg := defaultConfig
+		return config.MustNewConfigFrom(settings).Unpack(&cfg)
+	}
+
+	assert.NoError(t, unpack(mapstr.M{"deduplication.enabled": true}))
+	assert.NoError(t, unpack(mapstr.M{"deduplication.window": 0}), "settings are not validated if disabled")
+	assert.Error(t, unpack(mapstr.M{"deduplication.enabled": true, "deduplication.field": ""}))
+	assert.Error(t, unpack(mapstr.M{"deduplication.enabled": true, "deduplication.window": 0}))
+	assert.Error(t, unpack(mapstr.M{"deduplication.enabled": true, "deduplication.max_keys": 0}))
+
+	d, err := newDeduplicator(defaultDeduplicationConfig)
+	require.NoError(t, err)
+	assert.Nil(t, d, "deduplication is disabled by default")
+}
+
+func TestDeduplicator(t *testing.T) {
+	now := time.Now()
+	newTestDeduplicator := func(t *testing.T, maxKeys int) *deduplicator {
+		cfg := defaultDeduplicationConfig
+		cfg.Enabled = true
+		cfg.Window = time.Minute
+		cfg.MaxKeys = maxKeys
+		d, err := newDeduplicator(cfg)
+		require.NoError(t, err)
+		d.now = func() time.Time { return now }
+		return d
+	}
+	makeEvents := func(fingerprints ...string) []publisher.Event {
+		events := make([]publisher.Event, len(fingerprints))
+		for i, fingerprint := range fingerprints {
+			events[i] = publisher.Event{EncodedEvent: &encodedEvent{fingerprint: fingerprint}}
+		}
+		return events
+	}
+
+	t.Run("drops events indexed within the window", func(t *testing.T) {
+		d := newTestDeduplicator(t, 10)
+		d.markIndexed("a")
+
+		events, dropped := d.dropDuplicates(makeEvents("a", "b", "", "a"))
+		assert.Equal(t, 2, dropped)
+		assert.Equal(t, makeEvents("b", ""), events)
+
+		now = now.Add(time.Minute)
+		events, dropped = d.dropDuplicates(makeEvents("a"))
+		assert.Zero(t, dropped, "fingerprints expire after the window")
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("evicted fingerprints are sent again", func(t *testing.T) {
+		d := newTestDeduplicator(t, 2)
+		d.markIndexed("a")
+		d.markIndexed("b")
+		d.markIndexed("c")
+
+		events, dropped := d.dropDuplicates(makeEvents("a", "b", "c", "d"))
+		assert.Equal(t, 2, dropped)
+		assert.Equal(t, makeEvents("a", "d"), events)
+	})
+
+	t.Run("fingerprint", func(t *testing.T) {
+		d := newTestDeduplicator(t, 10)
+		assert.Equal(t, "abc", d.fingerprint(&beat.Event{Fields: mapstr.M{"fingerprint": "abc"}}))
+		assert.Empty(t, d.fingerprint(&beat.Event{Fields: mapstr.M{"fingerprint": 123}}))
+		assert.Empty(t, d.fingerprint(&beat.Event{Fields: mapstr.M{}}))
+	})
+}
+
+func TestPublishDeduplication(t *testing.T) {
+	var (
+		requests []string
+		status   = http.StatusInternalServerError
+	)
+	esMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body := string(b)
+		requests = append(requests, body)
+		items := make([]string, strings.Count(body, `"_index"`))
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"create":{"status":%d}}`, status)
+		}
+		_, _ = io.WriteString(w, `{"items":[`+strings.Join(items, ",")+`]}`)
+	}))
+	defer esMock.Close()
+
+	reg := monitoring.NewRegistry()
+	cfg := defaultDeduplicationConfig
+	cfg.Enabled = true
+	deduplicator, err := newDeduplicator(cfg)
+	require.NoError(t, err)
+	client, err := NewClient(
+		clientSettings{
+			observer:      outputs.NewStats(reg),
+			connection:    eslegclient.ConnectionSettings{URL: esMock.URL},
+			indexSelector: testIndexSelector{},
+			deduplicator:  deduplicator,
+		},
+		nil,
+		logp.NewTestingLogger(t, ""),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	publish := func(fingerprints ...string) *batchMock {
+		events := make([]publisher.Event, len(fingerprints))
+		for i, fingerprint := range fingerprints {
+			events[i] = publisher.Event{Content: beat.Event{Fields: mapstr.M{"fingerprint": fingerprint}}}
+		}
+		batch := encodeBatch(client, &batchMock{events: events})
+		_ = client.Publish(ctx, batch)
+		return batch
+	}
+
+	batch := publish("a", "b")
+	assert.Len(t, batch.retryEvents, 2)
+	assert.Len(t, requests, 1)
+
+	status = http.StatusOK
+	batch = publish("a", "b")
+	assert.True(t, batch.ack, "events that failed should not be deduplicated")
+	assert.Len(t, requests, 2)
+
+	batch = publish("a", "c")
+	assert.True(t, batch.ack)
+	require.Len(t, requests, 3)
+	assert.NotContains(t, requests[2], `"fingerprint":"a"`)
+	assert.Contains(t, requests[2], `"fingerprint":"c"`)
+
+	batch = publish("b")
+	assert.True(t, batch.ack)
+	assert.Len(t, requests, 3, "no request should be sent if all events are duplicates")
+
+	assertRegistryUint(t, reg, "events.deduplicated", 2, "duplicates should be counted")
+	assertRegistryUint(t, reg, "events.acked", 3, "")
+	assertRegistryUint(t, reg, "events.active", 0, "duplicates should not be active")
+}
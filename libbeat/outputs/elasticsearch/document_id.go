@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// documentIDConfig configures the generation of document IDs from the event
+// contents. At most one of Fields and Format can be set.
+type documentIDConfig struct {
+	// Fields whose values are hashed together into the document ID.
+	Fields []string `config:"fields"`
+	// Format string expanded into the document ID.
+	Format *fmtstr.EventFormatString `config:"format"`
+}
+
+func (c *documentIDConfig) Validate() error {
+	if len(c.Fields) > 0 && c.Format != nil {
+		return errors.New("document_id.fields and document_id.format can not be used together")
+	}
+	return nil
+}
+
+// documentIDGenerator derives the document ID of events without an explicit
+// @metadata._id, such that re-ingesting the same event overwrites or
+// deduplicates the existing document. Events missing any of the configured
+// fields get no ID and are indexed with an auto-generated one.
+type documentIDGenerator struct {
+	fields []string
+	format *fmtstr.EventFormatString
+}
+
+// newDocumentIDGenerator returns the generator for cfg, or nil if document
+// IDs are not generated.
+func newDocumentIDGenerator(cfg documentIDConfig) *documentIDGenerator {
+	if len(cfg.Fields) == 0 && cfg.Format == nil {
+		return nil
+	}
+	return &documentIDGenerator{fields: cfg.Fields, format: cfg.Format}
+}
+
+// ID returns the document ID for event, or an empty string if the event
+// lacks the fields the ID is derived from.
+func (g *documentIDGenerator) ID(event *beat.Event) string {
+	if g == nil {
+		return ""
+	}
+	if g.format != nil {
+		id, err := g.format.Run(event)
+		if err != nil {
+			return ""
+		}
+		return id
+	}
+
+	hash := sha256.New()
+	for _, field := range g.fields {
+		v, err := event.GetValue(field)
+		if err != nil {
+			return ""
+		}
+		switch vv := v.(type) {
+		case map[string]interface{}, mapstr.M, []interface{}:
+			return ""
+		case time.Time:
+			// Ensure timestamps hash the same regardless of their location.
+			v = vv.UTC()
+		}
+		fmt.Fprintf(hash, "|%v|%v", field, v)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/beat/events"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestDocumentIDGenerator(t *testing.T) {
+	ts := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	event := &beat.Event{
+		Timestamp: ts,
+		Fields: mapstr.M{
+			"host":    mapstr.M{"name": "web-1"},
+			"message": "hello",
+			"offset":  42,
+			"time":    ts.In(time.FixedZone("CET", 3600)),
+		},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := newDocumentIDGenerator(documentIDConfig{})
+		assert.Nil(t, g)
+		assert.Empty(t, g.ID(event))
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		g := newDocumentIDGenerator(documentIDConfig{Fields: []string{"host.name", "offset"}})
+		id := g.ID(event)
+		assert.Len(t, id, 64)
+
+		// The same content always yields the same ID.
+		assert.Equal(t, id, g.ID(event.Clone()))
+
+		other := event.Clone()
+		other.Fields["offset"] = 43
+		assert.NotEqual(t, id, g.ID(other))
+	})
+
+	t.Run("timestamps are hashed in UTC", func(t *testing.T) {
+		g := newDocumentIDGenerator(documentIDConfig{Fields: []string{"time"}})
+		utc := event.Clone()
+		utc.Fields["time"] = ts
+		assert.Equal(t, g.ID(utc), g.ID(event))
+	})
+
+	t.Run("missing or non-scalar fields", func(t *testing.T) {
+		assert.Empty(t, newDocumentIDGenerator(documentIDConfig{Fields: []string{"message", "missing"}}).ID(event))
+		assert.Empty(t, newDocumentIDGenerator(documentIDConfig{Fields: []string{"host"}}).ID(event))
+	})
+
+	t.Run("format", func(t *testing.T) {
+		g := newDocumentIDGenerator(documentIDConfig{
+			Format: fmtstr.MustCompileEvent("%{[host.name]}-%{[offset]}"),
+		})
+		assert.Equal(t, "web-1-42", g.ID(event))
+
+		g = newDocumentIDGenerator(documentIDConfig{
+			Format: fmtstr.MustCompileEvent("%{[host.name]}-%{[missing]}"),
+		})
+		assert.Empty(t, g.ID(event))
+	})
+}
+
+func TestDocumentIDConfig(t *testing.T) {
+	cfg := defaultConfig
+	err := config.MustNewConfigFrom(mapstr.M{
+		"document_id.fields": []string{"message"},
+		"document_id.format": "%{[message]}",
+	}).Unpack(&cfg)
+	assert.ErrorContains(t, err, "can not be used together")
+
+	cfg = defaultConfig
+	err = config.MustNewConfigFrom(mapstr.M{
+		"document_id.format": "%{[message]}",
+	}).Unpack(&cfg)
+	require.NoError(t, err)
+	assert.NotNil(t, newDocumentIDGenerator(cfg.DocumentID))
+}
+
+func TestEncodeEntryDocumentID(t *testing.T) {
+	g := newDocumentIDGenerator(documentIDConfig{Format: fmtstr.MustCompileEvent("%{[message]}")})
+	encoder := newEventEncoder(true, testIndexSelector{}, nil, g, nil)
+
+	encode := func(event beat.Event) string {
+		encoded, _ := encoder.EncodeEntry(publisher.Event{Content: event})
+		return encoded.(publisher.Event).EncodedEvent.(*encodedEvent).id
+	}
+
+	assert.Equal(t, "hello", encode(beat.Event{Fields: mapstr.M{"message": "hello"}}))
+	assert.Equal(t, "explicit", encode(beat.Event{
+		Fields: mapstr.M{"message": "hello"},
+		Meta:   mapstr.M{events.FieldMetaID: "explicit"},
+	}), "@metadata._id takes precedence over the generated ID")
+	assert.Empty(t, encode(beat.Event{Fields: mapstr.M{}}), "events without the fields get an auto-generated ID")
+}
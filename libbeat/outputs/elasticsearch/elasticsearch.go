@@ -72,6 +72,17 @@ func makeES(
 		return outputs.Fail(err)
 	}
 
+	if esConfig.IndexSanitizer.Enabled {
+		// Events whose index name is empty after sanitizing are routed to
+		// the default index of the beat.
+		defaultIndexSelector, err := im.BuildSelector(config.NewConfig())
+		if err != nil {
+			return outputs.Fail(err)
+		}
+		indexSelector = newSanitizingIndexSelector(
+			indexSelector, defaultIndexSelector, esConfig.IndexSanitizer, observer)
+	}
+
 	hosts, err := outputs.ReadHostList(cfg)
 	if err != nil {
 		return outputs.Fail(err)
@@ -87,10 +98,19 @@ func makeES(
 		params = nil
 	}
 
+	// The deduplicator is shared by all clients, so events are not sent
+	// again to a different host.
+	deduplicator, err := newDeduplicator(esConfig.Deduplication)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
 	encoderFactory := newEventEncoderFactory(
-		esConfig.EscapeHTML, indexSelector, pipelineSelector)
+		esConfig.EscapeHTML, indexSelector, pipelineSelector,
+		newDocumentIDGenerator(esConfig.DocumentID), deduplicator)
 
 	clients := make([]outputs.NetworkClient, len(hosts))
+	esClients := make([]*Client, len(hosts))
 	for i, host := range hosts {
 		esURL, err := common.MakeURL(esConfig.Protocol, esConfig.Path, host, 9200)
 		if err != nil {
@@ -98,8 +118,7 @@ func makeES(
 			return outputs.Fail(err)
 		}
 
-		var client outputs.NetworkClient
-		client, err = NewClient(clientSettings{
+		esClient, err := NewClient(clientSettings{
 			connection: eslegclient.ConnectionSettings{
 				URL:              esURL,
 				Beatname:         beatInfo.Beat,
@@ -120,15 +139,19 @@ func makeES(
 			pipelineSelector: pipelineSelector,
 			observer:         observer,
 			deadLetterIndex:  deadLetterIndex,
+			groupByIndex:     esConfig.GroupByIndex,
+			deduplicator:     deduplicator,
 		}, &connectCallbackRegistry, log)
 		if err != nil {
 			return outputs.Fail(err)
 		}
+		esClients[i] = esClient
 
-		client = outputs.WithBackoff(client, esConfig.Backoff.Init, esConfig.Backoff.Max)
-		clients[i] = client
+		clients[i] = outputs.WithBackoff(esClient, esConfig.Backoff.Init, esConfig.Backoff.Max)
 	}
 
+	warmupClients(log, esClients, esConfig.Warmup)
+
 	return outputs.SuccessNet(esConfig.Queue, esConfig.LoadBalance, esConfig.BulkMaxSize, esConfig.MaxRetries, encoderFactory, clients)
 }
 
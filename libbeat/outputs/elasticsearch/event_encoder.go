@@ -37,6 +37,8 @@ type eventEncoder struct {
 	enc              eslegclient.BodyEncoder
 	pipelineSelector *outil.Selector
 	indexSelector    outputs.IndexSelector
+	idGenerator      *documentIDGenerator
+	deduplicator     *deduplicator
 }
 
 type encodedEvent struct {
@@ -64,21 +66,33 @@ type encodedEvent struct {
 	pipeline string
 	index    string
 	encoding []byte
+
+	// fingerprint is the value of the deduplication field, or empty if
+	// deduplication is disabled.
+	fingerprint string
+
+	// encodingTime is the time taken to encode the event. It is reset once
+	// reported, so events sent again are not reported twice.
+	encodingTime time.Duration
 }
 
 func newEventEncoderFactory(
 	escapeHTML bool,
 	indexSelector outputs.IndexSelector,
 	pipelineSelector *outil.Selector,
+	idGenerator *documentIDGenerator,
+	deduplicator *deduplicator,
 ) queue.EncoderFactory {
 	return func() queue.Encoder {
-		return newEventEncoder(escapeHTML, indexSelector, pipelineSelector)
+		return newEventEncoder(escapeHTML, indexSelector, pipelineSelector, idGenerator, deduplicator)
 	}
 }
 
 func newEventEncoder(escapeHTML bool,
 	indexSelector outputs.IndexSelector,
 	pipelineSelector *outil.Selector,
+	idGenerator *documentIDGenerator,
+	deduplicator *deduplicator,
 ) queue.Encoder {
 	buf := bytes.NewBuffer(nil)
 	enc := eslegclient.NewJSONEncoder(buf, escapeHTML)
@@ -87,6 +101,8 @@ func newEventEncoder(escapeHTML bool,
 		enc:              enc,
 		pipelineSelector: pipelineSelector,
 		indexSelector:    indexSelector,
+		idGenerator:      idGenerator,
+		deduplicator:     deduplicator,
 	}
 }
 
@@ -124,7 +140,11 @@ func (pe *eventEncoder) encodeRawEvent(e *beat.Event) *encodedEvent {
 	}
 
 	id, _ := events.GetMetaStringValue(*e, events.FieldMetaID)
+	if id == "" {
+		id = pe.idGenerator.ID(e)
+	}
 
+	begin := time.Now()
 	err = pe.enc.Marshal(e)
 	if err != nil {
 		return &encodedEvent{err: fmt.Errorf("failed to encode event for output: %w", err)}
@@ -133,13 +153,15 @@ func (pe *eventEncoder) encodeRawEvent(e *beat.Event) *encodedEvent {
 	bytes := make([]byte, len(bufBytes))
 	copy(bytes, bufBytes)
 	return &encodedEvent{
-		id:        id,
-		meta:      e.Meta,
-		timestamp: e.Timestamp,
-		opType:    opType,
-		pipeline:  pipeline,
-		index:     index,
-		encoding:  bytes,
+		id:           id,
+		meta:         e.Meta,
+		timestamp:    e.Timestamp,
+		opType:       opType,
+		pipeline:     pipeline,
+		index:        index,
+		encoding:     bytes,
+		fingerprint:  pe.deduplicator.fingerprint(e),
+		encodingTime: time.Since(begin),
 	}
 }
 
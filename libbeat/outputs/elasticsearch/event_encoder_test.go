@@ -40,7 +40,7 @@ func (testIndexSelector) Select(event *beat.Event) (string, error) {
 func TestEncodeEntry(t *testing.T) {
 	indexSelector := testIndexSelector{}
 
-	encoder := newEventEncoder(true, indexSelector, nil)
+	encoder := newEventEncoder(true, indexSelector, nil, nil, nil)
 
 	metaFields := mapstr.M{
 		events.FieldMetaOpType:   "create",
@@ -125,6 +125,8 @@ func encodeEvents(client *Client, events []publisher.Event) []publisher.Event {
 		client.conn.EscapeHTML,
 		client.indexSelector,
 		client.pipelineSelector,
+		nil,
+		client.deduplicator,
 	)
 	for i := range events {
 		// Skip encoding if there's already encoded data present
@@ -143,6 +145,8 @@ func encodeEvent(client *Client, event publisher.Event) publisher.Event {
 		client.conn.EscapeHTML,
 		client.indexSelector,
 		client.pipelineSelector,
+		nil,
+		client.deduplicator,
 	)
 	encoded, _ := encoder.EncodeEntry(event)
 	return encoded.(publisher.Event)
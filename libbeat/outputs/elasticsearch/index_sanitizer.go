@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+)
+
+// maxIndexNameBytes is the maximum length of an index name accepted by
+// Elasticsearch.
+const maxIndexNameBytes = 255
+
+// indexIllegalChars lists the characters Elasticsearch does not accept in an
+// index name. ':' is deprecated upstream and replaced as well.
+const indexIllegalChars = `\/*?"<>| ,#:`
+
+// indexSanitizerConfig configures the sanitizing of computed index names.
+type indexSanitizerConfig struct {
+	Enabled     bool   `config:"enabled"`
+	Replacement string `config:"replacement"`
+}
+
+var defaultIndexSanitizerConfig = indexSanitizerConfig{
+	Enabled:     false,
+	Replacement: "_",
+}
+
+func (c *indexSanitizerConfig) Validate() error {
+	if c.Replacement == "" {
+		return nil
+	}
+	if strings.ContainsAny(c.Replacement, indexIllegalChars) || strings.ToLower(c.Replacement) != c.Replacement {
+		return fmt.Errorf("index_sanitizer.replacement '%v' is not valid in an index name", c.Replacement)
+	}
+	return nil
+}
+
+// sanitizingIndexSelector wraps an index selector, making the selected index
+// names conform to the Elasticsearch index naming rules. If nothing is left
+// of a name after sanitizing, the event is routed to the index selected by
+// fallback.
+type sanitizingIndexSelector struct {
+	selector    outputs.IndexSelector
+	fallback    outputs.IndexSelector
+	replacement string
+	observer    outputs.Observer
+}
+
+func newSanitizingIndexSelector(
+	selector, fallback outputs.IndexSelector,
+	cfg indexSanitizerConfig,
+	observer outputs.Observer,
+) outputs.IndexSelector {
+	if !cfg.Enabled {
+		return selector
+	}
+	if observer == nil {
+		observer = outputs.NewNilObserver()
+	}
+	return &sanitizingIndexSelector{
+		selector:    selector,
+		fallback:    fallback,
+		replacement: cfg.Replacement,
+		observer:    observer,
+	}
+}
+
+func (s *sanitizingIndexSelector) Select(event *beat.Event) (string, error) {
+	index, err := s.selector.Select(event)
+	if err != nil || index == "" {
+		return index, err
+	}
+
+	sanitized := sanitizeIndexName(index, s.replacement)
+	if sanitized == index {
+		return index, nil
+	}
+	s.observer.IndexSanitized()
+
+	if sanitized == "" {
+		if s.fallback == nil {
+			return "", fmt.Errorf("index name '%v' is empty after sanitizing", index)
+		}
+		fallback, err := s.fallback.Select(event)
+		if err != nil {
+			return "", err
+		}
+		sanitized = sanitizeIndexName(fallback, s.replacement)
+		if sanitized == "" {
+			return "", fmt.Errorf("index name '%v' and default index '%v' are empty after sanitizing", index, fallback)
+		}
+	}
+	return sanitized, nil
+}
+
+// sanitizeIndexName lowercases name, replaces illegal characters, strips the
+// prefixes Elasticsearch rejects and truncates the result to the maximum
+// index name length. An empty string is returned if no valid name remains.
+func sanitizeIndexName(name, replacement string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range strings.ToLower(name) {
+		if r == utf8.RuneError || strings.ContainsRune(indexIllegalChars, r) {
+			b.WriteString(replacement)
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	sanitized := strings.TrimLeft(b.String(), "-_+")
+	if len(sanitized) > maxIndexNameBytes {
+		sanitized = sanitized[:maxIndexNameBytes]
+		for !utf8.ValidString(sanitized) {
+			sanitized = sanitized[:len(sanitized)-1]
+		}
+	}
+	if sanitized == "." || sanitized == ".." {
+		return ""
+	}
+	return sanitized
+}
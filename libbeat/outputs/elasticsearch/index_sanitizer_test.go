@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestSanitizeIndexName(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		expected string
+	}{
+		"valid name":          {"logs-2024.01.01", "logs-2024.01.01"},
+		"uppercase":           {"Logs-App", "logs-app"},
+		"illegal characters":  {`a\b/c*d?e"f<g>h|i j,k#l:m`, "a_b_c_d_e_f_g_h_i_j_k_l_m"},
+		"leading prefixes":    {"-_+logs", "logs"},
+		"prefix from replace": {"#logs", "logs"},
+		"dot":                 {".", ""},
+		"dot dot":             {"..", ""},
+		"only illegal":        {"***", ""},
+		"hidden index":        {".logs", ".logs"},
+		"too long":            {strings.Repeat("a", 300), strings.Repeat("a", 255)},
+		"too long multibyte":  {strings.Repeat("a", 254) + "ü", strings.Repeat("a", 254)},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, sanitizeIndexName(test.name, "_"))
+		})
+	}
+}
+
+func TestIndexSanitizerConfigValidate(t *testing.T) {
+	for _, replacement := range []string{"", "_", "-", "x"} {
+		cfg := indexSanitizerConfig{Replacement: replacement}
+		assert.NoError(t, cfg.Validate(), "replacement %q", replacement)
+	}
+	for _, replacement := range []string{"*", " ", "X", "#"} {
+		cfg := indexSanitizerConfig{Replacement: replacement}
+		assert.Error(t, cfg.Validate(), "replacement %q", replacement)
+	}
+}
+
+func TestSanitizingIndexSelector(t *testing.T) {
+	expr, err := outil.FmtSelectorExpr(fmtstr.MustCompileEvent("%{[index]}"), "", outil.SelectorKeepCase)
+	require.NoError(t, err)
+	index := outil.MakeSelector(expr)
+	fallback := outil.MakeSelector(outil.ConstSelectorExpr("default", outil.SelectorLowerCase))
+
+	cases := map[string]struct {
+		index     string
+		expected  string
+		sanitized uint64
+	}{
+		"unchanged":       {"logs", "logs", 0},
+		"sanitized":       {"My Logs", "my_logs", 1},
+		"empty fallback":  {"__", "default", 1},
+		"invalid to dots": {"..", "default", 1},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg := monitoring.NewRegistry()
+			stats := outputs.NewStats(reg)
+			sel := newSanitizingIndexSelector(index, fallback,
+				indexSanitizerConfig{Enabled: true, Replacement: "_"}, stats)
+
+			got, err := sel.Select(&beat.Event{Fields: mapstr.M{"index": test.index}})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+
+			sanitized := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false).Ints["events.index_sanitized"]
+			assert.Equal(t, test.sanitized, uint64(sanitized))
+		})
+	}
+}
+
+func TestSanitizingIndexSelectorDisabled(t *testing.T) {
+	sel := newSanitizingIndexSelector(testIndexSelector{}, nil, indexSanitizerConfig{}, nil)
+	assert.Equal(t, testIndexSelector{}, sel)
+}
+
+func TestIndexSanitizerConfigDefault(t *testing.T) {
+	esConfig := defaultConfig
+	require.NoError(t, config.MustNewConfigFrom(mapstr.M{
+		"index_sanitizer.enabled": true,
+	}).Unpack(&esConfig))
+	assert.True(t, esConfig.IndexSanitizer.Enabled)
+	assert.Equal(t, "_", esConfig.IndexSanitizer.Replacement)
+}
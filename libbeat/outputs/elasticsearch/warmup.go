@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// warmupConfig configures the connections established before the output
+// starts publishing.
+type warmupConfig struct {
+	Connections int           `config:"connections" validate:"min=0"`
+	Timeout     time.Duration `config:"timeout" validate:"min=0"`
+}
+
+var defaultWarmupConfig = warmupConfig{
+	Connections: 0,
+	Timeout:     10 * time.Second,
+}
+
+// warmupClients establishes the connections of up to cfg.Connections clients
+// concurrently. It returns once all connections have been established or
+// failed, or after cfg.Timeout, returning the number of connections
+// established. Clients failing the warmup connect lazily when publishing.
+func warmupClients(log *logp.Logger, clients []*Client, cfg warmupConfig) int {
+	n := min(cfg.Connections, len(clients))
+	if n <= 0 {
+		return 0
+	}
+
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	var (
+		wg    sync.WaitGroup
+		ready atomic.Int64
+	)
+	for _, client := range clients[:n] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.conn.Warmup(ctx); err != nil {
+				log.Warnf("Failed to warm up connection to %v: %v", client.conn.URL, err)
+				return
+			}
+			ready.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	log.Infof("Warmed up %d of %d connections to Elasticsearch", ready.Load(), n)
+	return int(ready.Load())
+}
@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/esleg/eslegclient"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func TestWarmupClients(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	blocked := make(chan struct{})
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-blocked:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slowServer.Close()
+	defer close(blocked)
+
+	makeClients := func(urls ...string) []*Client {
+		var clients []*Client
+		for _, url := range urls {
+			client, err := NewClient(
+				clientSettings{
+					observer:   outputs.NewNilObserver(),
+					connection: eslegclient.ConnectionSettings{URL: url},
+				},
+				nil,
+				logger,
+			)
+			require.NoError(t, err)
+			clients = append(clients, client)
+		}
+		return clients
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		requests.Store(0)
+		clients := makeClients(server.URL, server.URL)
+		ready := warmupClients(logger, clients, defaultWarmupConfig)
+		assert.Equal(t, 0, ready)
+		assert.Equal(t, int64(0), requests.Load())
+	})
+
+	t.Run("limited to the configured connections", func(t *testing.T) {
+		requests.Store(0)
+		clients := makeClients(server.URL, server.URL, server.URL)
+		ready := warmupClients(logger, clients, warmupConfig{Connections: 2, Timeout: time.Second})
+		assert.Equal(t, 2, ready)
+		assert.Equal(t, int64(2), requests.Load())
+	})
+
+	t.Run("limited to the available clients", func(t *testing.T) {
+		requests.Store(0)
+		clients := makeClients(server.URL)
+		ready := warmupClients(logger, clients, warmupConfig{Connections: 4, Timeout: time.Second})
+		assert.Equal(t, 1, ready)
+		assert.Equal(t, int64(1), requests.Load())
+	})
+
+	t.Run("proceeds after timeout", func(t *testing.T) {
+		requests.Store(0)
+		clients := makeClients(server.URL, slowServer.URL)
+		start := time.Now()
+		ready := warmupClients(logger, clients, warmupConfig{Connections: 2, Timeout: 100 * time.Millisecond})
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Equal(t, 1, ready)
+		assert.Equal(t, int64(1), requests.Load())
+	})
+}
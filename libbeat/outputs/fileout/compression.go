@@ -0,0 +1,179 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fileout
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/elastic/elastic-agent-libs/file"
+)
+
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// compressionExtensions maps the supported compression algorithms to the
+// suffix appended to the file extension.
+var compressionExtensions = map[string]string{
+	compressionGzip: ".gz",
+	compressionZstd: ".zst",
+}
+
+// noRotationSize disables the size based rotation of file.Rotator, so the
+// compressedWriter can rotate only once a compressed stream is finalized.
+const noRotationSize = uint(math.MaxInt32)
+
+// flushWriter is a compressing writer that can flush pending data without
+// finalizing the stream.
+type flushWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func newCompressor(compression string, level int, w io.Writer) (flushWriter, error) {
+	switch compression {
+	case compressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case compressionZstd:
+		encoderLevel := zstd.SpeedDefault
+		if level != 0 {
+			if level < 1 || level > 22 {
+				return nil, fmt.Errorf("invalid zstd compression level %d, must be between 1 and 22", level)
+			}
+			encoderLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		// A single encoder goroutine keeps the memory usage bounded by the
+		// window of the compression level.
+		return zstd.NewWriter(w,
+			zstd.WithEncoderLevel(encoderLevel),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported compression '%s'", compression)
+	}
+}
+
+// compressedWriter compresses all data written to it, and rotates the
+// underlying file once the compressed size reaches maxSize. On rotation the
+// compressed stream is finalized, so every rotated file is a complete and
+// valid compressed file. Memory usage is bounded by the compressor's window.
+type compressedWriter struct {
+	rotator     *file.Rotator
+	compression string
+	level       int
+	maxSize     uint64
+
+	compressor flushWriter
+	// written counts the compressed bytes written to the active file.
+	written uint64
+}
+
+func newCompressedWriter(rotator *file.Rotator, compression string, level int, maxSize uint64) (*compressedWriter, error) {
+	// Create a compressor once to validate the settings.
+	if _, err := newCompressor(compression, level, io.Discard); err != nil {
+		return nil, err
+	}
+
+	return &compressedWriter{
+		rotator:     rotator,
+		compression: compression,
+		level:       level,
+		maxSize:     maxSize,
+	}, nil
+}
+
+// Write compresses data into the active file, and rotates it if the size
+// limit is reached.
+func (w *compressedWriter) Write(data []byte) (int, error) {
+	if w.compressor == nil {
+		compressor, err := newCompressor(w.compression, w.level, countingWriter{w})
+		if err != nil {
+			return 0, err
+		}
+		w.compressor = compressor
+	}
+
+	n, err := w.compressor.Write(data)
+	if err != nil {
+		return n, err
+	}
+
+	if w.written >= w.maxSize {
+		if err := w.finalize(); err != nil {
+			return n, err
+		}
+		if err := w.rotator.Rotate(); err != nil {
+			return n, fmt.Errorf("failed to rotate file: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Flush writes pending compressed data to the active file, without
+// finalizing the compressed stream.
+func (w *compressedWriter) Flush() error {
+	if w.compressor == nil {
+		return nil
+	}
+	if err := w.compressor.Flush(); err != nil {
+		return fmt.Errorf("failed to flush compressed data: %w", err)
+	}
+	return nil
+}
+
+// Close finalizes the compressed stream and closes the active file.
+func (w *compressedWriter) Close() error {
+	if err := w.finalize(); err != nil {
+		_ = w.rotator.Close()
+		return err
+	}
+	return w.rotator.Close()
+}
+
+func (w *compressedWriter) finalize() error {
+	if w.compressor == nil {
+		return nil
+	}
+	err := w.compressor.Close()
+	w.compressor = nil
+	w.written = 0
+	if err != nil {
+		return fmt.Errorf("failed to finalize compressed stream: %w", err)
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w *compressedWriter
+}
+
+func (c countingWriter) Write(data []byte) (int, error) {
+	n, err := c.w.rotator.Write(data)
+	c.w.written += uint64(n)
+	return n, err
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fileout
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/file"
+)
+
+func TestCompressedWriterRotation(t *testing.T) {
+	levels := map[string]int{
+		compressionGzip: gzip.BestSpeed,
+		compressionZstd: 1,
+	}
+	for compression, level := range levels {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			extension := "ndjson" + compressionExtensions[compression]
+			rotator, err := file.NewFileRotator(
+				filepath.Join(dir, "out"),
+				file.MaxSizeBytes(noRotationSize),
+				file.MaxBackups(100),
+				file.Extension(extension),
+			)
+			require.NoError(t, err)
+
+			w, err := newCompressedWriter(rotator, compression, level, 1024)
+			require.NoError(t, err)
+
+			const lines = 2000
+			for i := 0; i < lines; i++ {
+				_, err := fmt.Fprintf(w, "{\"message\":\"event %d with some random payload %x\"}\n", i, i*7919)
+				require.NoError(t, err)
+			}
+			require.NoError(t, w.Close())
+
+			files, err := filepath.Glob(filepath.Join(dir, "out-*."+extension))
+			require.NoError(t, err)
+			assert.Greater(t, len(files), 1, "expected files to be rotated")
+
+			// Every file must be a complete compressed stream and no line
+			// may be lost.
+			total := 0
+			for _, name := range files {
+				total += countCompressedLines(t, name, compression)
+			}
+			assert.Equal(t, lines, total)
+		})
+	}
+}
+
+func TestCompressedWriterFlush(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := file.NewFileRotator(
+		filepath.Join(dir, "out"),
+		file.MaxSizeBytes(noRotationSize),
+		file.Extension("ndjson.gz"),
+	)
+	require.NoError(t, err)
+
+	w, err := newCompressedWriter(rotator, compressionGzip, 0, 1024*1024)
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("{\"message\":\"hello\"}\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	files, err := filepath.Glob(filepath.Join(dir, "out-*.ndjson.gz"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// The flushed data is readable before the stream is finalized.
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "{\"message\":\"hello\"}\n", line)
+}
+
+func TestCompressedWriterInvalidSettings(t *testing.T) {
+	_, err := newCompressedWriter(nil, "lz4", 0, 1024)
+	assert.Error(t, err)
+
+	_, err = newCompressedWriter(nil, compressionGzip, 42, 1024)
+	assert.Error(t, err)
+
+	_, err = newCompressedWriter(nil, compressionZstd, 23, 1024)
+	assert.Error(t, err)
+}
+
+func countCompressedLines(t *testing.T, name, compression string) int {
+	t.Helper()
+
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var r io.ReadCloser
+	switch compression {
+	case compressionGzip:
+		r, err = gzip.NewReader(f)
+	case compressionZstd:
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(f)
+		if err == nil {
+			r = d.IOReadCloser()
+		}
+	default:
+		t.Fatalf("unknown compression %s", compression)
+	}
+	require.NoError(t, err)
+
+	count := 0
+	reader := bufio.NewReader(r)
+	for {
+		_, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "file %s is not a valid %s stream", name, compression)
+		count++
+	}
+	require.NoError(t, r.Close())
+	return count
+}
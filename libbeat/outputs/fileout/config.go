@@ -34,6 +34,9 @@ type fileOutConfig struct {
 	Permissions     uint32            `config:"permissions"`
 	RotateOnStartup bool              `config:"rotate_on_startup"`
 	Queue           config.Namespace  `config:"queue"`
+
+	Compression      string `config:"compression"`
+	CompressionLevel int    `config:"compression_level"`
 }
 
 func defaultConfig() fileOutConfig {
@@ -64,5 +67,11 @@ func (c *fileOutConfig) Validate() error {
 			file.MaxBackupsLimit)
 	}
 
+	if c.Compression != "" {
+		if _, ok := compressionExtensions[c.Compression]; !ok {
+			return fmt.Errorf("unsupported compression '%s'", c.Compression)
+		}
+	}
+
 	return nil
 }
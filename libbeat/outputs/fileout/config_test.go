@@ -91,6 +91,36 @@ func TestConfig(t *testing.T) {
 				assert.Nil(t, err)
 			},
 		},
+		"config given with gzip compression": {
+			config: config.MustNewConfigFrom(mapstr.M{
+				"compression":       "gzip",
+				"compression_level": 9,
+			}),
+			assertion: func(t *testing.T, actual *fileOutConfig, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "gzip", actual.Compression)
+				assert.Equal(t, 9, actual.CompressionLevel)
+			},
+		},
+		"config given with zstd compression": {
+			config: config.MustNewConfigFrom(mapstr.M{
+				"compression":       "zstd",
+				"compression_level": 19,
+			}),
+			assertion: func(t *testing.T, actual *fileOutConfig, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "zstd", actual.Compression)
+				assert.Equal(t, 19, actual.CompressionLevel)
+			},
+		},
+		"config given with unsupported compression": {
+			config: config.MustNewConfigFrom(mapstr.M{
+				"compression": "lz4",
+			}),
+			assertion: func(t *testing.T, actual *fileOutConfig, err error) {
+				assert.ErrorContains(t, err, "unsupported compression 'lz4'")
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			isWindowsPath = test.useWindowsPath
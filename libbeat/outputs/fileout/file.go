@@ -20,6 +20,7 @@ package fileout
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -44,6 +45,13 @@ type fileOutput struct {
 	observer outputs.Observer
 	rotator  *file.Rotator
 	codec    codec.Codec
+
+	// delimiter is written after each event, it is empty for binary codecs.
+	delimiter []byte
+
+	// writer is either the rotator, or a compressedWriter wrapping it.
+	writer     io.WriteCloser
+	compressed *compressedWriter
 }
 
 // makeFileout instantiates a new file output instance.
@@ -85,33 +93,56 @@ func (out *fileOutput) init(beat beat.Info, c fileOutConfig) error {
 	out.filePath = path
 
 	var err error
+	out.codec, err = codec.CreateEncoder(beat, c.Codec)
+	if err != nil {
+		return err
+	}
+
+	maxSize, extension := c.RotateEveryKb*1024, "ndjson"
+	out.delimiter = []byte{'\n'}
+	if binary, ok := out.codec.(codec.BinaryCodec); ok {
+		extension = binary.FileExtension()
+		out.delimiter = nil
+	}
+	if c.Compression != "" {
+		// The compressedWriter triggers rotations itself, after finalizing
+		// the compressed stream.
+		maxSize = noRotationSize
+		extension += compressionExtensions[c.Compression]
+	}
+
 	out.rotator, err = file.NewFileRotator(
 		path,
-		file.MaxSizeBytes(c.RotateEveryKb*1024),
+		file.MaxSizeBytes(maxSize),
 		file.MaxBackups(c.NumberOfFiles),
 		file.Permissions(os.FileMode(c.Permissions)),
 		file.RotateOnStartup(c.RotateOnStartup),
+		file.Extension(extension),
 		file.WithLogger(beat.Logger.Named("rotator").With(logp.Namespace("rotator"))),
 	)
 	if err != nil {
 		return err
 	}
 
-	out.codec, err = codec.CreateEncoder(beat, c.Codec)
-	if err != nil {
-		return err
+	out.writer = out.rotator
+	if c.Compression != "" {
+		out.compressed, err = newCompressedWriter(out.rotator, c.Compression, c.CompressionLevel, uint64(c.RotateEveryKb)*1024)
+		if err != nil {
+			return err
+		}
+		out.writer = out.compressed
 	}
 
 	out.log.Infof("Initialized file output. "+
-		"path=%v max_size_bytes=%v max_backups=%v permissions=%v",
-		path, c.RotateEveryKb*1024, c.NumberOfFiles, os.FileMode(c.Permissions))
+		"path=%v max_size_bytes=%v max_backups=%v permissions=%v compression=%v",
+		path, c.RotateEveryKb*1024, c.NumberOfFiles, os.FileMode(c.Permissions), c.Compression)
 
 	return nil
 }
 
 // Implement Outputer
 func (out *fileOutput) Close() error {
-	return out.rotator.Close()
+	return out.writer.Close()
 }
 
 func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
@@ -122,11 +153,17 @@ func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
 	st.NewBatch(len(events))
 
 	dropped := 0
+	var (
+		serializationTime  time.Duration
+		serializationBytes int
+	)
 
 	for i := range events {
 		event := &events[i]
 
+		begin := time.Now()
 		serializedEvent, err := out.codec.Encode(out.beat.Beat, &event.Content)
+		serializationTime += time.Since(begin)
 		if err != nil {
 			if event.Guaranteed() {
 				out.log.Errorf("Failed to serialize the event: %+v", err)
@@ -139,9 +176,10 @@ func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
 			dropped++
 			continue
 		}
+		serializationBytes += len(serializedEvent)
 
-		begin := time.Now()
-		if _, err = out.rotator.Write(append(serializedEvent, '\n')); err != nil {
+		begin = time.Now()
+		if _, err = out.writer.Write(append(serializedEvent, out.delimiter...)); err != nil {
 			st.WriteError(err)
 
 			if event.Guaranteed() {
@@ -154,10 +192,19 @@ func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
 			continue
 		}
 
-		st.WriteBytes(len(serializedEvent) + 1)
+		st.WriteBytes(len(serializedEvent) + len(out.delimiter))
 		took := time.Since(begin)
 		st.ReportLatency(took)
 	}
+	st.ReportSerialization(serializationTime, serializationBytes)
+
+	if out.compressed != nil {
+		// Make the batch available in the file, without waiting for the
+		// compressor's buffers to fill up.
+		if err := out.compressed.Flush(); err != nil {
+			out.log.Errorf("Flushing compressed events to file failed with: %+v", err)
+		}
+	}
 
 	st.PermanentErrors(dropped)
 
@@ -18,3 +18,73 @@
 //go:build !integration
 
 package fileout
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/cbor"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/go-structform/cborl"
+	"github.com/elastic/go-structform/gotype"
+)
+
+func TestFileOutputBinaryCodec(t *testing.T) {
+	dir := t.TempDir()
+	info := beat.Info{Beat: "test", Version: "1.2.3", Logger: logp.NewLogger("")}
+	cfg := config.MustNewConfigFrom(mapstr.M{
+		"path":       dir,
+		"filename":   "out",
+		"codec.cbor": mapstr.M{},
+	})
+
+	group, err := makeFileout(nil, info, outputs.NewNilObserver(), cfg)
+	require.NoError(t, err)
+	client := group.Clients[0]
+	defer client.Close()
+
+	events := []beat.Event{
+		{Fields: mapstr.M{"message": "first"}},
+		{Fields: mapstr.M{"message": "second"}},
+	}
+	require.NoError(t, client.Publish(context.Background(), outest.NewBatch(events...)))
+
+	files, err := filepath.Glob(filepath.Join(dir, "out*.cbor"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	// Events are written back to back as a CBOR sequence, without newlines.
+	// Map keys are not encoded in a stable order, so compare decoded events.
+	enc := cbor.New(info.Version, cbor.Config{})
+	for i := range events {
+		encoded, err := enc.Encode(info.Beat, &events[i])
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(content), len(encoded))
+		assert.Equal(t, decodeCBOR(t, encoded), decodeCBOR(t, content[:len(encoded)]))
+		content = content[len(encoded):]
+	}
+	assert.Empty(t, content)
+}
+
+func decodeCBOR(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+
+	var out map[string]interface{}
+	unfolder, err := gotype.NewUnfolder(nil)
+	require.NoError(t, err)
+	require.NoError(t, unfolder.SetTarget(&out))
+	require.NoError(t, cborl.Parse(data, unfolder))
+	return out
+}
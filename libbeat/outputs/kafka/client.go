@@ -180,21 +180,29 @@ func (c *client) Publish(_ context.Context, batch publisher.Batch) error {
 		batch:  batch,
 	}
 
+	var (
+		serializationTime  time.Duration
+		serializationBytes int
+	)
 	ch := c.producer.Input()
 	for i := range events {
 		d := &events[i]
+		begin := time.Now()
 		msg, err := c.getEventMessage(d)
+		serializationTime += time.Since(begin)
 		if err != nil {
 			c.log.Errorf("Dropping event: %+v", err)
 			ref.done()
 			c.observer.PermanentErrors(1)
 			continue
 		}
+		serializationBytes += len(msg.value)
 
 		msg.ref = ref
 		msg.initProducerMessage()
 		ch <- &msg.msg
 	}
+	c.observer.ReportSerialization(serializationTime, serializationBytes)
 
 	return nil
 }
@@ -248,6 +248,9 @@ func newSaramaConfig(log *logp.Logger, config *kafkaConfig) (*sarama.Config, err
 			DisablePAFXFAST:    !enableFAST,
 		}
 
+	case config.Sasl.IsOAuth():
+		config.Sasl.ConfigureSarama(k)
+
 	case config.Username != "":
 		k.Net.SASL.Enable = true
 		k.Net.SASL.User = config.Username
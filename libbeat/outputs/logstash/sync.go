@@ -122,7 +122,10 @@ func (c *syncClient) Publish(_ context.Context, batch publisher.Batch) error {
 			select {
 			case <-c.ticker.C:
 				if err := c.reconnect(); err != nil {
-					batch.Retry()
+					// Events sent before the reconnect have already been
+					// ACKed by logstash, only retry the remaining ones.
+					batch.RetryEvents(events)
+					st.RetryableErrors(len(events))
 					return err
 				}
 
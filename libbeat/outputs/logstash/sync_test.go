@@ -25,12 +25,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common/transport/transptest"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/outest"
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
 	"github.com/elastic/elastic-agent-libs/transport"
+	v2 "github.com/elastic/go-lumber/server/v2"
 )
 
 type testSyncDriver struct {
@@ -60,6 +65,50 @@ func TestClientStructuredEvent(t *testing.T) {
 	testStructuredEvent(t, makeTestClient)
 }
 
+func TestClientRetriesUnackedEventsOnReconnectFailure(t *testing.T) {
+	mock := transptest.NewMockServerTCP(t, 1*time.Second, "", nil)
+	server, _ := v2.NewWithListener(mock.Listener)
+	defer server.Close()
+
+	transp, err := mock.Connect()
+	require.NoError(t, err)
+	defer transp.Close()
+
+	config := defaultConfig()
+	config.Timeout = 1 * time.Second
+	config.TTL = 100 * time.Millisecond
+	config.SlowStart = true
+	client, err := newSyncClient(beat.Info{Logger: logp.NewLogger("")}, transp, outputs.NewNilObserver(), &config)
+	require.NoError(t, err)
+	driver := newClientTestDriver(client)
+
+	events := make([]beat.Event, 2*defaultStartMaxWindowSize)
+	for i := range events {
+		events[i] = beat.Event{Fields: mapstr.M{"n": i}}
+	}
+	batch := outest.NewBatch(events...)
+	driver.Publish(batch)
+
+	// Receive the first window, then make the reconnect triggered by the
+	// expired TTL fail before ACKing it.
+	received := server.Receive()
+	require.Len(t, received.Events, defaultStartMaxWindowSize)
+	mock.Listener.Close()
+	time.Sleep(2 * config.TTL)
+	received.ACK()
+
+	driver.Stop()
+	returns := driver.Returns()
+	require.Len(t, returns, 1)
+	require.Error(t, returns[0].err)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, defaultStartMaxWindowSize)
+	for i, event := range batch.Signals[0].Events {
+		assert.Equal(t, defaultStartMaxWindowSize+i, event.Content.Fields["n"])
+	}
+}
+
 func newClientServerTCP(t *testing.T, to time.Duration) *clientServer {
 	return &clientServer{transptest.NewMockServerTCP(t, to, "", nil)}
 }
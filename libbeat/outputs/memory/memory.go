@@ -0,0 +1,244 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package memory provides an output recording published batches in memory,
+// for tests. It is not included in the beats, tests using it must import it
+// to register the "memory" output type:
+//
+//	import _ "github.com/elastic/beats/v7/libbeat/outputs/memory"
+//
+// Configured with `output.memory.id: my-test`, the output created by the
+// pipeline can be retrieved with Get("my-test").
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func init() {
+	outputs.RegisterType("memory", makeMemory)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Output{}
+)
+
+// Get returns the latest output created with the given id, or nil if there
+// is none.
+func Get(id string) *Output {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return registry[id]
+}
+
+// Output records the batches published to it.
+type Output struct {
+	id        string
+	manualACK bool
+	log       *logp.Logger
+	observer  outputs.Observer
+
+	mu      sync.Mutex
+	batches []*Batch
+	closed  bool
+	// updated is closed and replaced whenever a batch is recorded.
+	updated chan struct{}
+}
+
+// Batch is a batch recorded by the output.
+type Batch struct {
+	out   *Output
+	batch publisher.Batch
+
+	// Events holds the contents of the batch events.
+	Events []beat.Event
+
+	mu     sync.Mutex
+	result BatchResult
+}
+
+// BatchResult is how a batch was handled by the output.
+type BatchResult uint8
+
+const (
+	// BatchPending batches have not been ACKed, retried or dropped yet.
+	BatchPending BatchResult = iota
+	BatchACKed
+	BatchRetried
+	BatchDropped
+)
+
+func makeMemory(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *config.C,
+) (outputs.Group, error) {
+	moConfig := defaultConfig()
+	if err := cfg.Unpack(&moConfig); err != nil {
+		return outputs.Fail(err)
+	}
+
+	out := &Output{
+		id:        moConfig.ID,
+		manualACK: moConfig.ManualACK,
+		log:       beat.Logger.Named("memory"),
+		observer:  observer,
+		updated:   make(chan struct{}),
+	}
+
+	registryMu.Lock()
+	registry[out.id] = out
+	registryMu.Unlock()
+
+	out.log.Infof("Initialized memory output %s", out.id)
+	return outputs.Success(moConfig.Queue, moConfig.BatchSize, moConfig.MaxRetries, nil, out)
+}
+
+// Close implements outputs.Client.
+func (out *Output) Close() error {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.closed = true
+	return nil
+}
+
+// Publish implements outputs.Client. It records the batch and ACKs it, unless
+// manual_ack is set.
+func (out *Output) Publish(_ context.Context, batch publisher.Batch) error {
+	pubEvents := batch.Events()
+	events := make([]beat.Event, len(pubEvents))
+	for i, e := range pubEvents {
+		events[i] = e.Content
+	}
+	out.observer.NewBatch(len(events))
+
+	b := &Batch{out: out, batch: batch, Events: events}
+	out.mu.Lock()
+	out.batches = append(out.batches, b)
+	close(out.updated)
+	out.updated = make(chan struct{})
+	out.mu.Unlock()
+
+	if !out.manualACK {
+		b.ACK()
+	}
+	return nil
+}
+
+func (out *Output) String() string {
+	return "memory(" + out.id + ")"
+}
+
+// Closed returns true if the pipeline closed the output.
+func (out *Output) Closed() bool {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return out.closed
+}
+
+// Batches returns the recorded batches, in the order they were published.
+// Retried batches are recorded again when they are published again.
+func (out *Output) Batches() []*Batch {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return append([]*Batch(nil), out.batches...)
+}
+
+// Events returns the events of all recorded batches that have been ACKed,
+// in the order they were published.
+func (out *Output) Events() []beat.Event {
+	var events []beat.Event
+	for _, b := range out.Batches() {
+		if b.Result() == BatchACKed {
+			events = append(events, b.Events...)
+		}
+	}
+	return events
+}
+
+// WaitForBatches blocks until at least n batches have been recorded or ctx is
+// done, and returns the recorded batches.
+func (out *Output) WaitForBatches(ctx context.Context, n int) ([]*Batch, error) {
+	for {
+		out.mu.Lock()
+		batches := append([]*Batch(nil), out.batches...)
+		updated := out.updated
+		out.mu.Unlock()
+
+		if len(batches) >= n {
+			return batches, nil
+		}
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return batches, fmt.Errorf("waiting for %d batches, got %d: %w", n, len(batches), ctx.Err())
+		}
+	}
+}
+
+// Result returns how the batch was handled.
+func (b *Batch) Result() BatchResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.result
+}
+
+// ACK acknowledges the batch. Only the first ACK, Retry or Drop call of a
+// batch has an effect.
+func (b *Batch) ACK() {
+	if b.complete(BatchACKed) {
+		b.out.observer.AckedEvents(len(b.Events))
+		b.batch.ACK()
+	}
+}
+
+// Retry returns the batch to the pipeline to be published again, like an
+// output does on retryable errors.
+func (b *Batch) Retry() {
+	if b.complete(BatchRetried) {
+		b.out.observer.RetryableErrors(len(b.Events))
+		b.batch.Retry()
+	}
+}
+
+// Drop drops the batch, like an output does on permanent errors.
+func (b *Batch) Drop() {
+	if b.complete(BatchDropped) {
+		b.out.observer.PermanentErrors(len(b.Events))
+		b.batch.Drop()
+	}
+}
+
+func (b *Batch) complete(result BatchResult) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.result != BatchPending {
+		return false
+	}
+	b.result = result
+	return true
+}
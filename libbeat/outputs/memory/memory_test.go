@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func makeTestOutput(t *testing.T, cfg mapstr.M) (*Output, outputs.Group) {
+	t.Helper()
+	factory := outputs.FindFactory("memory")
+	require.NotNil(t, factory, "the memory output must be registered")
+
+	group, err := factory(nil, beat.Info{Logger: logp.NewTestingLogger(t, "")}, outputs.NewNilObserver(), config.MustNewConfigFrom(cfg))
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+
+	out := Get(cfg["id"].(string))
+	require.NotNil(t, out)
+	require.Same(t, out, group.Clients[0])
+	return out, group
+}
+
+func TestMemoryOutputAutoACK(t *testing.T) {
+	out, group := makeTestOutput(t, mapstr.M{"id": "auto", "bulk_max_size": 10})
+	assert.Equal(t, 10, group.BatchSize)
+	assert.Equal(t, 3, group.Retry)
+
+	batch := outest.NewBatch(beat.Event{Fields: mapstr.M{"n": 1}}, beat.Event{Fields: mapstr.M{"n": 2}})
+	require.NoError(t, out.Publish(context.Background(), batch))
+
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+	require.Len(t, out.Batches(), 1)
+	assert.Equal(t, BatchACKed, out.Batches()[0].Result())
+	assert.Equal(t, []beat.Event{{Fields: mapstr.M{"n": 1}}, {Fields: mapstr.M{"n": 2}}}, out.Events())
+
+	require.NoError(t, group.Clients[0].Close())
+	assert.True(t, out.Closed())
+}
+
+func TestMemoryOutputManualACK(t *testing.T) {
+	out, _ := makeTestOutput(t, mapstr.M{"id": "manual", "manual_ack": true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := out.WaitForBatches(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	first := outest.NewBatch(beat.Event{Fields: mapstr.M{"n": 1}})
+	second := outest.NewBatch(beat.Event{Fields: mapstr.M{"n": 2}})
+	third := outest.NewBatch(beat.Event{Fields: mapstr.M{"n": 3}})
+	go func() {
+		for _, b := range []*outest.Batch{first, second, third} {
+			_ = out.Publish(context.Background(), b)
+		}
+	}()
+
+	batches, err := out.WaitForBatches(context.Background(), 3)
+	require.NoError(t, err)
+	for _, b := range batches {
+		assert.Equal(t, BatchPending, b.Result())
+	}
+	assert.Empty(t, out.Events(), "pending batches must not be reported as ACKed")
+
+	batches[0].ACK()
+	batches[1].Retry()
+	batches[2].Drop()
+	// Only the first call has an effect.
+	batches[2].ACK()
+
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, first.Signals)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchRetry}}, second.Signals)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchDrop}}, third.Signals)
+	assert.Equal(t, BatchDropped, batches[2].Result())
+	assert.Equal(t, []beat.Event{{Fields: mapstr.M{"n": 1}}}, out.Events())
+}
+
+func TestMemoryOutputRequiresID(t *testing.T) {
+	_, err := makeMemory(nil, beat.Info{Logger: logp.NewTestingLogger(t, "")}, outputs.NewNilObserver(), config.MustNewConfigFrom(mapstr.M{}))
+	assert.Error(t, err)
+}
@@ -63,6 +63,13 @@ type Stats struct {
 	// These events are also included in eventsFailed.
 	eventsTooMany *monitoring.Uint
 
+	// Number of events whose index name had to be sanitized.
+	eventsIndexSanitized *monitoring.Uint
+
+	// Number of events not sent because an event with the same fingerprint
+	// was indexed recently.
+	eventsDeduplicated *monitoring.Uint
+
 	// Output batch stats
 
 	// Number of times a batch was split for being too large
@@ -78,6 +85,12 @@ type Stats struct {
 	readErrors *monitoring.Uint // total number of errors while waiting for response on output
 
 	sendLatencyMillis metrics.Sample
+
+	//
+	// Output serialization stats
+	//
+	serializationMicros metrics.Sample // time taken to serialize a batch
+	serializationBytes  metrics.Sample // size of a serialized batch
 }
 
 // NewStats creates a new Stats instance using a backing monitoring registry.
@@ -95,6 +108,9 @@ func NewStats(reg *monitoring.Registry) *Stats {
 		eventsActive:     monitoring.NewUint(reg, "events.active"),
 		eventsTooMany:    monitoring.NewUint(reg, "events.toomany"),
 
+		eventsIndexSanitized: monitoring.NewUint(reg, "events.index_sanitized"),
+		eventsDeduplicated:   monitoring.NewUint(reg, "events.deduplicated"),
+
 		batchesSplit: monitoring.NewUint(reg, "batches.split"),
 
 		writeBytes:  monitoring.NewUint(reg, "write.bytes"),
@@ -104,8 +120,13 @@ func NewStats(reg *monitoring.Registry) *Stats {
 		readErrors: monitoring.NewUint(reg, "read.errors"),
 
 		sendLatencyMillis: metrics.NewUniformSample(1024),
+
+		serializationMicros: metrics.NewUniformSample(1024),
+		serializationBytes:  metrics.NewUniformSample(1024),
 	}
 	_ = adapter.NewGoMetrics(reg, "write.latency", adapter.Accept).Register("histogram", metrics.NewHistogram(obj.sendLatencyMillis))
+	_ = adapter.NewGoMetrics(reg, "serialization.duration_us", adapter.Accept).Register("histogram", metrics.NewHistogram(obj.serializationMicros))
+	_ = adapter.NewGoMetrics(reg, "serialization.bytes", adapter.Accept).Register("histogram", metrics.NewHistogram(obj.serializationBytes))
 	return obj
 }
 
@@ -122,6 +143,15 @@ func (s *Stats) ReportLatency(time time.Duration) {
 	s.sendLatencyMillis.Update(time.Milliseconds())
 }
 
+// ReportSerialization updates the serialization duration and size histograms
+// with the cost of serializing a batch.
+func (s *Stats) ReportSerialization(took time.Duration, bytes int) {
+	if s != nil {
+		s.serializationMicros.Update(took.Microseconds())
+		s.serializationBytes.Update(int64(bytes))
+	}
+}
+
 // AckedEvents updates active and acked event metrics.
 func (s *Stats) AckedEvents(n int) {
 	if s != nil {
@@ -171,6 +201,21 @@ func (s *Stats) BatchSplit() {
 	}
 }
 
+// IndexSanitized updates the number of events whose index name was sanitized.
+func (s *Stats) IndexSanitized() {
+	if s != nil {
+		s.eventsIndexSanitized.Inc()
+	}
+}
+
+// DeduplicatedEvents updates the active and deduplicated event metrics.
+func (s *Stats) DeduplicatedEvents(n int) {
+	if s != nil {
+		s.eventsDeduplicated.Add(uint64(n))
+		s.eventsActive.Sub(uint64(n))
+	}
+}
+
 // ErrTooMany updates the number of Too Many Requests responses reported by the output.
 func (s *Stats) ErrTooMany(n int) {
 	if s != nil {
@@ -33,12 +33,18 @@ type Observer interface {
 
 	BatchSplit() // report a batch was split for being too large to ingest
 
+	IndexSanitized() // report an index name was rewritten to be valid
+
+	DeduplicatedEvents(int) // report number of events not sent for having been indexed recently
+
 	WriteError(error) // report an I/O error on write
 	WriteBytes(int)   // report number of bytes being written
 	ReadError(error)  // report an I/O error on read
 	ReadBytes(int)    // report number of bytes being read
 
 	ReportLatency(time.Duration) // report the duration a send to the output takes
+
+	ReportSerialization(time.Duration, int) // report the duration and number of bytes of serializing a batch
 }
 
 type emptyObserver struct{}
@@ -50,16 +56,19 @@ func NewNilObserver() Observer {
 	return nilObserver
 }
 
-func (*emptyObserver) NewBatch(int)                  {}
-func (*emptyObserver) ReportLatency(_ time.Duration) {}
-func (*emptyObserver) AckedEvents(int)               {}
-func (*emptyObserver) DeadLetterEvents(int)          {}
-func (*emptyObserver) DuplicateEvents(int)           {}
-func (*emptyObserver) RetryableErrors(int)           {}
-func (*emptyObserver) PermanentErrors(int)           {}
-func (*emptyObserver) BatchSplit()                   {}
-func (*emptyObserver) WriteError(error)              {}
-func (*emptyObserver) WriteBytes(int)                {}
-func (*emptyObserver) ReadError(error)               {}
-func (*emptyObserver) ReadBytes(int)                 {}
-func (*emptyObserver) ErrTooMany(int)                {}
+func (*emptyObserver) NewBatch(int)                           {}
+func (*emptyObserver) ReportLatency(_ time.Duration)          {}
+func (*emptyObserver) ReportSerialization(time.Duration, int) {}
+func (*emptyObserver) AckedEvents(int)                        {}
+func (*emptyObserver) DeadLetterEvents(int)                   {}
+func (*emptyObserver) DuplicateEvents(int)                    {}
+func (*emptyObserver) RetryableErrors(int)                    {}
+func (*emptyObserver) PermanentErrors(int)                    {}
+func (*emptyObserver) BatchSplit()                            {}
+func (*emptyObserver) IndexSanitized()                        {}
+func (*emptyObserver) DeduplicatedEvents(int)                 {}
+func (*emptyObserver) WriteError(error)                       {}
+func (*emptyObserver) WriteBytes(int)                         {}
+func (*emptyObserver) ReadError(error)                        {}
+func (*emptyObserver) ReadBytes(int)                          {}
+func (*emptyObserver) ErrTooMany(int)                         {}
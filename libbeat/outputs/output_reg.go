@@ -60,6 +60,12 @@ type Group struct {
 	Retry        int
 	QueueFactory queue.QueueFactory
 
+	// GuaranteedRetry is the number of retries after which events published
+	// with the GuaranteedSend mode are dropped. 0 means they are retried
+	// forever. It is set by Load from the guaranteed_max_retries setting
+	// shared by all outputs.
+	GuaranteedRetry int
+
 	// If the output supports early encoding (where events are converted to their
 	// output-serialized form before entering the queue) it should provide an
 	// encoder factory here. Events will be processed using the resulting encoders
@@ -106,5 +112,20 @@ func Load(
 	if stats == nil {
 		stats = NewNilObserver()
 	}
-	return factory(im, info, stats, config)
+
+	var settings struct {
+		GuaranteedMaxRetries int `config:"guaranteed_max_retries" validate:"min=0"`
+	}
+	if config != nil {
+		if err := config.Unpack(&settings); err != nil {
+			return Group{}, fmt.Errorf("invalid %v output settings: %w", name, err)
+		}
+	}
+
+	group, err := factory(im, info, stats, config)
+	if err != nil {
+		return group, err
+	}
+	group.GuaranteedRetry = settings.GuaranteedMaxRetries
+	return group, nil
 }
@@ -0,0 +1,49 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+func TestLoadGuaranteedMaxRetries(t *testing.T) {
+	RegisterType("test_guaranteed_retries", func(IndexManager, beat.Info, Observer, *config.C) (Group, error) {
+		return Group{BatchSize: 10, Retry: 3}, nil
+	})
+	defer delete(outputReg, "test_guaranteed_retries")
+
+	group, err := Load(nil, beat.Info{}, nil, "test_guaranteed_retries", config.NewConfig())
+	require.NoError(t, err)
+	assert.Equal(t, 3, group.Retry)
+	assert.Zero(t, group.GuaranteedRetry, "guaranteed events must be retried forever by default")
+
+	group, err = Load(nil, beat.Info{}, nil, "test_guaranteed_retries",
+		config.MustNewConfigFrom(map[string]interface{}{"guaranteed_max_retries": 5}))
+	require.NoError(t, err)
+	assert.Equal(t, 5, group.GuaranteedRetry)
+
+	_, err = Load(nil, beat.Info{}, nil, "test_guaranteed_retries",
+		config.MustNewConfigFrom(map[string]interface{}{"guaranteed_max_retries": -1}))
+	assert.Error(t, err)
+}
@@ -59,3 +59,16 @@ type Connectable interface {
 	// forever.
 	Connect(context.Context) error
 }
+
+// OutputStateListener is notified when an output client connects to or
+// disconnects from its sink. Callbacks are run by the output worker and must
+// not block.
+type OutputStateListener interface {
+	// OnConnect is called when the client established a connection, after
+	// being disconnected.
+	OnConnect(host string)
+
+	// OnDisconnect is called when a connected client lost its connection
+	// because publishing failed with err.
+	OnDisconnect(host string, err error)
+}
@@ -229,7 +229,7 @@ func (c *client) publishEventsBulk(conn redis.Conn, command string) publishFn {
 		args := make([]interface{}, 1, len(data)+1)
 		args[0] = dest
 
-		okEvents, args := serializeEvents(c.log, args, 1, data, c.index, c.codec)
+		okEvents, args := c.serializeEvents(args, 1, data)
 		c.observer.PermanentErrors(len(data) - len(okEvents))
 		if (len(args) - 1) == 0 {
 			return nil, nil
@@ -255,7 +255,7 @@ func (c *client) publishEventsPipeline(conn redis.Conn, command string) publishF
 	return func(key outil.Selector, data []publisher.Event) ([]publisher.Event, error) {
 		var okEvents []publisher.Event
 		serialized := make([]interface{}, 0, len(data))
-		okEvents, serialized = serializeEvents(c.log, serialized, 0, data, c.index, c.codec)
+		okEvents, serialized = c.serializeEvents(serialized, 0, data)
 		c.observer.PermanentErrors(len(data) - len(okEvents))
 		if len(serialized) == 0 {
 			return nil, nil
@@ -308,6 +308,26 @@ func (c *client) publishEventsPipeline(conn redis.Conn, command string) publishF
 	}
 }
 
+// serializeEvents serializes the events to the client's codec and reports the
+// cost of serializing them to the observer.
+func (c *client) serializeEvents(
+	to []interface{},
+	i int,
+	data []publisher.Event,
+) ([]publisher.Event, []interface{}) {
+	begin := time.Now()
+	n := len(to)
+	okEvents, to := serializeEvents(c.log, to, i, data, c.index, c.codec)
+	took := time.Since(begin)
+
+	bytes := 0
+	for _, serialized := range to[n:] {
+		bytes += len(serialized.([]byte))
+	}
+	c.observer.ReportSerialization(took, bytes)
+	return okEvents, to
+}
+
 func serializeEvents(
 	log *logp.Logger,
 	to []interface{},
@@ -0,0 +1,259 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/testing"
+)
+
+// client splits batches by route and publishes the events to the clients of
+// the routed outputs.
+type client struct {
+	routes  *routes
+	clients []outputs.Client
+}
+
+func newClient(r *routes, clients []outputs.Client) *client {
+	return &client{routes: r, clients: clients}
+}
+
+// Connect connects all routed clients that support it.
+func (c *client) Connect(ctx context.Context) error {
+	for i, client := range c.clients {
+		if conn, ok := client.(outputs.Connectable); ok {
+			if err := conn.Connect(ctx); err != nil {
+				return fmt.Errorf("failed to connect output '%v': %w", c.routes.names[i], err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *client) Close() error {
+	var errs []error
+	for i, client := range c.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close output '%v': %w", c.routes.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Publish publishes the events of each route as a separate batch. The batch
+// is resolved once all routed outputs resolved their part of it. Sub-batches
+// split by an output are published again before Publish returns.
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	if len(events) == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	routed := make([][]publisher.Event, len(c.clients))
+	for _, event := range events {
+		route, event := c.routes.unwrap(event)
+		routed[route] = append(routed[route], event)
+	}
+
+	tracker := &batchTracker{parent: batch, total: len(events), publishing: true}
+	var subs []*subBatch
+	for route, events := range routed {
+		if len(events) > 0 {
+			subs = append(subs, &subBatch{tracker: tracker, route: route, events: events})
+		}
+	}
+	tracker.pending = len(subs)
+
+	var errs []error
+	for len(subs) > 0 {
+		for _, sub := range subs {
+			if err := c.clients[sub.route].Publish(ctx, sub); err != nil {
+				errs = append(errs, fmt.Errorf("output '%v': %w", c.routes.names[sub.route], err))
+			}
+		}
+		subs = tracker.takeSplits()
+	}
+	return errors.Join(errs...)
+}
+
+func (c *client) Test(d testing.Driver) {
+	for i, client := range c.clients {
+		t, ok := client.(testing.Testable)
+		d.Run(fmt.Sprintf("Output %s", c.routes.names[i]), func(d testing.Driver) {
+			if !ok {
+				d.Fatal("output", errors.New("client doesn't support testing"))
+			}
+			t.Test(d)
+		})
+	}
+}
+
+func (c *client) String() string {
+	names := make([]string, len(c.clients))
+	for i, client := range c.clients {
+		names[i] = c.routes.names[i] + "=" + client.String()
+	}
+	return "router(" + strings.Join(names, ",") + ")"
+}
+
+// batchTracker resolves a batch split between the routed outputs once all
+// parts are resolved. Events to be retried are retried together. If events
+// have been dropped by an output and no events are retried, the batch is
+// dropped.
+type batchTracker struct {
+	parent publisher.Batch
+	total  int
+
+	mu        sync.Mutex
+	pending   int
+	retry     []publisher.Event
+	dropped   int
+	cancelled int
+
+	// splits holds the sub-batches created by SplitRetry, until they are
+	// published by client.Publish. Sub-batches can only be split while
+	// publishing is set.
+	splits     []*subBatch
+	publishing bool
+}
+
+// splitRetry replaces b with two sub-batches holding half of its events
+// each. It returns false if the batch is not being published anymore.
+func (t *batchTracker) splitRetry(b *subBatch) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.publishing {
+		return false
+	}
+
+	mid := len(b.events) / 2
+	t.pending++
+	t.splits = append(t.splits,
+		&subBatch{tracker: t, route: b.route, events: b.events[:mid]},
+		&subBatch{tracker: t, route: b.route, events: b.events[mid:]},
+	)
+	return true
+}
+
+// takeSplits returns the sub-batches to be published again. If there are
+// none, sub-batches can not be split anymore.
+func (t *batchTracker) takeSplits() []*subBatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	splits := t.splits
+	t.splits = nil
+	if len(splits) == 0 {
+		t.publishing = false
+	}
+	return splits
+}
+
+func (t *batchTracker) resolve(retry []publisher.Event, dropped, cancelled int) {
+	t.mu.Lock()
+	t.retry = append(t.retry, retry...)
+	t.dropped += dropped
+	t.cancelled += cancelled
+	t.pending--
+	done := t.pending == 0
+	t.mu.Unlock()
+
+	if !done {
+		return
+	}
+	switch {
+	case t.cancelled == t.total:
+		t.parent.Cancelled()
+	case len(t.retry) > 0:
+		t.parent.RetryEvents(t.retry)
+	case t.dropped > 0:
+		t.parent.Drop()
+	default:
+		t.parent.ACK()
+	}
+}
+
+// subBatch holds the events of a batch routed to one output.
+type subBatch struct {
+	tracker *batchTracker
+	route   int
+	events  []publisher.Event
+	once    sync.Once
+}
+
+func (b *subBatch) Events() []publisher.Event {
+	return b.events
+}
+
+func (b *subBatch) ACK() {
+	b.resolve(nil, 0, 0)
+}
+
+func (b *subBatch) Drop() {
+	b.resolve(nil, len(b.events), 0)
+}
+
+func (b *subBatch) Retry() {
+	b.RetryEvents(b.events)
+}
+
+func (b *subBatch) RetryEvents(events []publisher.Event) {
+	b.resolve(b.wrap(events), 0, 0)
+}
+
+// SplitRetry splits the events in two sub-batches that are published to the
+// output again. If the router is not publishing the batch anymore, the events
+// are retried without being split.
+func (b *subBatch) SplitRetry() bool {
+	if len(b.events) < 2 {
+		return false
+	}
+	b.once.Do(func() {
+		if !b.tracker.splitRetry(b) {
+			b.tracker.resolve(b.wrap(b.events), 0, 0)
+		}
+	})
+	return true
+}
+
+func (b *subBatch) Cancelled() {
+	b.resolve(b.wrap(b.events), 0, len(b.events))
+}
+
+func (b *subBatch) resolve(retry []publisher.Event, dropped, cancelled int) {
+	b.once.Do(func() {
+		b.tracker.resolve(retry, dropped, cancelled)
+	})
+}
+
+// wrap restores the route of events returned to the queue.
+func (b *subBatch) wrap(events []publisher.Event) []publisher.Event {
+	wrapped := make([]publisher.Event, len(events))
+	for i, event := range events {
+		event.EncodedEvent = &routedEvent{route: b.route, encoded: event.EncodedEvent}
+		wrapped[i] = event
+	}
+	return wrapped
+}
@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package router
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+type routerConfig struct {
+	Queue config.Namespace `config:"queue"`
+
+	// Field holding the name of the output an event is sent to.
+	Field string `config:"field" validate:"required"`
+
+	// Default is the output receiving events without a matching output name.
+	Default string `config:"default" validate:"required"`
+
+	// Outputs maps the output names to the output configurations.
+	Outputs map[string]config.Namespace `config:"outputs" validate:"required"`
+}
+
+// Validate checks the default output and the routed outputs are configured.
+func (c *routerConfig) Validate() error {
+	if len(c.Outputs) == 0 {
+		return fmt.Errorf("no outputs configured")
+	}
+	if _, ok := c.Outputs[c.Default]; !ok {
+		return fmt.Errorf("default output '%v' is not configured", c.Default)
+	}
+	for name, out := range c.Outputs {
+		if !out.IsSet() {
+			return fmt.Errorf("output '%v' has no type configured", name)
+		}
+		if out.Name() == outputType {
+			return fmt.Errorf("output '%v' can not be of type %v", name, outputType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+const outputType = "router"
+
+func init() {
+	outputs.RegisterType(outputType, makeRouter)
+}
+
+// routes selects the output of an event by the value of a field.
+type routes struct {
+	field string
+	names []string
+	index map[string]int
+	def   int
+}
+
+// routedEvent wraps the encoded form of an event together with the index
+// of the output it is routed to. The route is selected when the event is
+// encoded, as encoders might clear the event content.
+type routedEvent struct {
+	route   int
+	encoded interface{}
+}
+
+func makeRouter(
+	im outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *config.C,
+) (outputs.Group, error) {
+	log := beat.Logger.Named(outputType)
+
+	var rc routerConfig
+	if err := cfg.Unpack(&rc); err != nil {
+		return outputs.Fail(err)
+	}
+
+	r := &routes{
+		field: rc.Field,
+		index: map[string]int{},
+	}
+	for name := range rc.Outputs {
+		r.names = append(r.names, name)
+	}
+	sort.Strings(r.names)
+
+	groups := make([]outputs.Group, len(r.names))
+	for i, name := range r.names {
+		r.index[name] = i
+		out := rc.Outputs[name]
+		group, err := outputs.Load(im, beat, observer, out.Name(), out.Config())
+		if err == nil && len(group.Clients) == 0 {
+			err = errors.New("no clients created")
+		}
+		if err == nil && group.QueueFactory != nil {
+			err = errors.New("queue settings are not supported for routed outputs, configure the queue of the router output instead")
+		}
+		if err != nil {
+			closeGroups(groups[:i])
+			closeClients(group.Clients)
+			return outputs.Fail(fmt.Errorf("failed to load output '%v': %w", name, err))
+		}
+		groups[i] = group
+	}
+	r.def = r.index[rc.Default]
+
+	// Every router client owns one client of each routed output. Outputs
+	// with more clients than others are limited to the lowest number of
+	// clients.
+	count := len(groups[0].Clients)
+	for _, group := range groups[1:] {
+		count = min(count, len(group.Clients))
+	}
+	clients := make([]outputs.Client, count)
+	for i := range clients {
+		routed := make([]outputs.Client, len(groups))
+		for j, group := range groups {
+			routed[j] = group.Clients[i]
+		}
+		clients[i] = newClient(r, routed)
+	}
+	for j, group := range groups {
+		if len(group.Clients) > count {
+			log.Infof("Output '%v' is limited to %d workers by the router", r.names[j], count)
+			closeClients(group.Clients[count:])
+		}
+	}
+
+	// The router splits batches between the outputs, so batches must not
+	// be larger than accepted by any of them.
+	batchSize := 0
+	for _, group := range groups {
+		if group.BatchSize > 0 && (batchSize == 0 || group.BatchSize < batchSize) {
+			batchSize = group.BatchSize
+		}
+	}
+
+	encoders := make([]queue.EncoderFactory, len(groups))
+	for i, group := range groups {
+		encoders[i] = group.EncoderFactory
+	}
+
+	log.Infof("Initialized router output, routing events by '%v' to %v (default '%v')", r.field, r.names, rc.Default)
+	group, err := outputs.Success(rc.Queue, batchSize, groups[r.def].Retry, r.encoderFactory(encoders), clients...)
+	if err != nil {
+		closeGroups(groups)
+	}
+	return group, err
+}
+
+// route returns the index of the output the event is routed to.
+func (r *routes) route(event *beat.Event) int {
+	v, err := event.GetValue(r.field)
+	if err != nil {
+		return r.def
+	}
+	name, ok := v.(string)
+	if !ok {
+		return r.def
+	}
+	if i, ok := r.index[name]; ok {
+		return i
+	}
+	return r.def
+}
+
+// unwrap returns the route of a queued event and the event as expected by
+// the routed output.
+func (r *routes) unwrap(event publisher.Event) (int, publisher.Event) {
+	if routed, ok := event.EncodedEvent.(*routedEvent); ok {
+		event.EncodedEvent = routed.encoded
+		return routed.route, event
+	}
+	return r.route(&event.Content), event
+}
+
+// encoderFactory returns a factory creating encoders that select the route
+// of each event and apply the encoder of the routed output, if any.
+func (r *routes) encoderFactory(factories []queue.EncoderFactory) queue.EncoderFactory {
+	return func() queue.Encoder {
+		enc := &encoder{routes: r, encoders: make([]queue.Encoder, len(factories))}
+		for i, factory := range factories {
+			if factory != nil {
+				enc.encoders[i] = factory()
+			}
+		}
+		return enc
+	}
+}
+
+type encoder struct {
+	routes   *routes
+	encoders []queue.Encoder
+}
+
+func (e *encoder) EncodeEntry(entry queue.Entry) (queue.Entry, int) {
+	event, ok := entry.(publisher.Event)
+	if !ok {
+		return entry, 0
+	}
+
+	route := e.routes.route(&event.Content)
+	size := 0
+	if enc := e.encoders[route]; enc != nil {
+		var encoded queue.Entry
+		encoded, size = enc.EncodeEntry(event)
+		if event, ok = encoded.(publisher.Event); !ok {
+			return encoded, size
+		}
+	}
+	event.EncodedEvent = &routedEvent{route: route, encoded: event.EncodedEvent}
+	return event, size
+}
+
+func closeGroups(groups []outputs.Group) {
+	for _, group := range groups {
+		closeClients(group.Clients)
+	}
+}
+
+func closeClients(clients []outputs.Client) {
+	for _, client := range clients {
+		_ = client.Close()
+	}
+}
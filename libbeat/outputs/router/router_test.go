@@ -0,0 +1,357 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package router
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const testOutputType = "router_test"
+
+// testClients collects the clients created by the test output by name.
+var testClients = struct {
+	sync.Mutex
+	byName map[string][]*testClient
+}{byName: map[string][]*testClient{}}
+
+func init() {
+	outputs.RegisterType(testOutputType, makeTestOutput)
+}
+
+type testOutputConfig struct {
+	Name    string `config:"name"`
+	Clients int    `config:"clients"`
+	Encode  bool   `config:"encode"`
+}
+
+func makeTestOutput(_ outputs.IndexManager, _ beat.Info, _ outputs.Observer, cfg *config.C) (outputs.Group, error) {
+	c := testOutputConfig{Clients: 1}
+	if err := cfg.Unpack(&c); err != nil {
+		return outputs.Fail(err)
+	}
+
+	testClients.Lock()
+	defer testClients.Unlock()
+	clients := make([]outputs.Client, c.Clients)
+	testClients.byName[c.Name] = nil
+	for i := range clients {
+		client := &testClient{name: c.Name}
+		testClients.byName[c.Name] = append(testClients.byName[c.Name], client)
+		clients[i] = client
+	}
+
+	var encoder queue.EncoderFactory
+	if c.Encode {
+		encoder = func() queue.Encoder { return testEncoder{} }
+	}
+	return outputs.Success(config.Namespace{}, 10, 3, encoder, clients...)
+}
+
+type testClient struct {
+	name    string
+	closed  bool
+	batches []publisher.Batch
+	resolve func(publisher.Batch)
+}
+
+func (c *testClient) Close() error   { c.closed = true; return nil }
+func (c *testClient) String() string { return c.name }
+
+func (c *testClient) Publish(_ context.Context, batch publisher.Batch) error {
+	c.batches = append(c.batches, batch)
+	if c.resolve != nil {
+		c.resolve(batch)
+	} else {
+		batch.ACK()
+	}
+	return nil
+}
+
+// testEncoder encodes events to their message and clears the content.
+type testEncoder struct{}
+
+func (testEncoder) EncodeEntry(entry queue.Entry) (queue.Entry, int) {
+	event := entry.(publisher.Event) //nolint:errcheck // always an event in tests
+	msg, _ := event.Content.GetValue("message")
+	event.EncodedEvent = msg
+	event.Content = beat.Event{}
+	return event, 1
+}
+
+func loadRouter(t *testing.T, cfg mapstr.M) outputs.Group {
+	t.Helper()
+	group, err := outputs.Load(nil, beat.Info{Logger: logp.NewTestingLogger(t, "")}, nil, outputType, config.MustNewConfigFrom(cfg))
+	require.NoError(t, err)
+	return group
+}
+
+func testRouterConfig(outputs ...string) mapstr.M {
+	cfg := mapstr.M{
+		"field":   "fields.output",
+		"default": outputs[0],
+		"outputs": mapstr.M{},
+	}
+	for _, name := range outputs {
+		cfg["outputs"].(mapstr.M)[name] = mapstr.M{ //nolint:errcheck // set above
+			testOutputType: mapstr.M{"name": name},
+		}
+	}
+	return cfg
+}
+
+func clientsOf(name string) []*testClient {
+	testClients.Lock()
+	defer testClients.Unlock()
+	return testClients.byName[name]
+}
+
+func event(msg, output string) beat.Event {
+	fields := mapstr.M{"message": msg}
+	if output != "" {
+		fields["fields"] = mapstr.M{"output": output}
+	}
+	return beat.Event{Fields: fields}
+}
+
+func messages(events []publisher.Event) []interface{} {
+	var msgs []interface{}
+	for _, e := range events {
+		if e.EncodedEvent != nil {
+			msgs = append(msgs, e.EncodedEvent)
+			continue
+		}
+		msg, _ := e.Content.GetValue("message")
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestRouterConfig(t *testing.T) {
+	tests := map[string]mapstr.M{
+		"no field": {
+			"default": "es",
+			"outputs": mapstr.M{"es": mapstr.M{testOutputType: mapstr.M{}}},
+		},
+		"no outputs": {
+			"field":   "fields.output",
+			"default": "es",
+		},
+		"default not configured": {
+			"field":   "fields.output",
+			"default": "siem",
+			"outputs": mapstr.M{"es": mapstr.M{testOutputType: mapstr.M{}}},
+		},
+		"nested router": {
+			"field":   "fields.output",
+			"default": "es",
+			"outputs": mapstr.M{"es": mapstr.M{outputType: mapstr.M{}}},
+		},
+		"unknown output type": {
+			"field":   "fields.output",
+			"default": "es",
+			"outputs": mapstr.M{"es": mapstr.M{"unknown": mapstr.M{}}},
+		},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := outputs.Load(nil, beat.Info{Logger: logp.NewTestingLogger(t, "")}, nil, outputType, config.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRouterRoutesEvents(t *testing.T) {
+	group := loadRouter(t, testRouterConfig("es", "siem"))
+	require.Len(t, group.Clients, 1)
+	es, siem := clientsOf("es")[0], clientsOf("siem")[0]
+
+	batch := outest.NewBatch(
+		event("a", "siem"),
+		event("b", ""),
+		event("c", "unknown"),
+		event("d", "siem"),
+	)
+	require.NoError(t, group.Clients[0].Publish(context.Background(), batch))
+
+	require.Len(t, es.batches, 1)
+	require.Len(t, siem.batches, 1)
+	assert.Equal(t, []interface{}{"b", "c"}, messages(es.batches[0].Events()))
+	assert.Equal(t, []interface{}{"a", "d"}, messages(siem.batches[0].Events()))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	require.NoError(t, group.Clients[0].Close())
+	assert.True(t, es.closed)
+	assert.True(t, siem.closed)
+}
+
+func TestRouterEncodesEvents(t *testing.T) {
+	cfg := testRouterConfig("es", "siem")
+	//nolint:errcheck // test config
+	cfg.Put("outputs.siem."+testOutputType+".encode", true)
+	group := loadRouter(t, cfg)
+	require.NotNil(t, group.EncoderFactory)
+	es, siem := clientsOf("es")[0], clientsOf("siem")[0]
+
+	encoder := group.EncoderFactory()
+	var events []publisher.Event
+	for _, e := range []beat.Event{event("a", "siem"), event("b", "")} {
+		encoded, _ := encoder.EncodeEntry(publisher.Event{Content: e})
+		events = append(events, encoded.(publisher.Event)) //nolint:errcheck // always an event
+	}
+
+	batch := &testBatch{events: events}
+	require.NoError(t, group.Clients[0].Publish(context.Background(), batch))
+
+	// Encoded events are routed to their output even if the content has
+	// been cleared by the encoder.
+	assert.Equal(t, []interface{}{"a"}, messages(siem.batches[0].Events()))
+	assert.Equal(t, []interface{}{"b"}, messages(es.batches[0].Events()))
+	assert.Nil(t, es.batches[0].Events()[0].EncodedEvent)
+}
+
+func TestRouterResolvesBatch(t *testing.T) {
+	resolvers := map[string]func(publisher.Batch){
+		"ack":       func(b publisher.Batch) { b.ACK() },
+		"drop":      func(b publisher.Batch) { b.Drop() },
+		"retry":     func(b publisher.Batch) { b.Retry() },
+		"cancelled": func(b publisher.Batch) { b.Cancelled() },
+	}
+
+	tests := map[string]struct {
+		es, siem string
+		expected outest.BatchSignalTag
+		retried  []interface{}
+	}{
+		"all acked":      {es: "ack", siem: "ack", expected: outest.BatchACK},
+		"all dropped":    {es: "drop", siem: "drop", expected: outest.BatchDrop},
+		"some dropped":   {es: "ack", siem: "drop", expected: outest.BatchDrop},
+		"all cancelled":  {es: "cancelled", siem: "cancelled", expected: outest.BatchCancelled},
+		"some retried":   {es: "ack", siem: "retry", expected: outest.BatchRetryEvents, retried: []interface{}{"a"}},
+		"some cancelled": {es: "cancelled", siem: "ack", expected: outest.BatchRetryEvents, retried: []interface{}{"b"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			group := loadRouter(t, testRouterConfig("es", "siem"))
+			clientsOf("es")[0].resolve = resolvers[test.es]
+			clientsOf("siem")[0].resolve = resolvers[test.siem]
+
+			batch := outest.NewBatch(event("a", "siem"), event("b", ""))
+			require.NoError(t, group.Clients[0].Publish(context.Background(), batch))
+
+			require.Len(t, batch.Signals, 1)
+			sig := batch.Signals[0]
+			assert.Equal(t, test.expected, sig.Tag)
+			if test.retried == nil {
+				return
+			}
+
+			// Retried events keep their route.
+			r := group.Clients[0].(*client).routes //nolint:errcheck // router client
+			require.Len(t, sig.Events, len(test.retried))
+			for i, e := range sig.Events {
+				route, e := r.unwrap(e)
+				msg, _ := e.Content.GetValue("message")
+				assert.Equal(t, test.retried[i], msg)
+				assert.Equal(t, r.route(&e.Content), route)
+			}
+		})
+	}
+}
+
+func TestRouterSplitRetry(t *testing.T) {
+	group := loadRouter(t, testRouterConfig("es", "siem"))
+	es, siem := clientsOf("es")[0], clientsOf("siem")[0]
+	// es only accepts batches with a single event.
+	es.resolve = func(b publisher.Batch) {
+		if len(b.Events()) > 1 {
+			assert.True(t, b.SplitRetry())
+			return
+		}
+		b.ACK()
+	}
+
+	batch := outest.NewBatch(
+		event("a", ""),
+		event("b", ""),
+		event("c", ""),
+		event("d", "siem"),
+	)
+	require.NoError(t, group.Clients[0].Publish(context.Background(), batch))
+
+	var published []interface{}
+	for _, b := range es.batches {
+		if len(b.Events()) == 1 {
+			published = append(published, messages(b.Events())...)
+		}
+	}
+	assert.ElementsMatch(t, []interface{}{"a", "b", "c"}, published)
+	assert.Len(t, siem.batches, 1)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	// Single events can not be split.
+	es.resolve = func(b publisher.Batch) {
+		if !b.SplitRetry() {
+			b.Drop()
+		}
+	}
+	batch = outest.NewBatch(event("a", ""))
+	require.NoError(t, group.Clients[0].Publish(context.Background(), batch))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchDrop}}, batch.Signals)
+}
+
+func TestRouterClientCount(t *testing.T) {
+	cfg := testRouterConfig("es", "siem")
+	//nolint:errcheck // test config
+	cfg.Put("outputs.es."+testOutputType+".clients", 3)
+	//nolint:errcheck // test config
+	cfg.Put("outputs.siem."+testOutputType+".clients", 2)
+	group := loadRouter(t, cfg)
+
+	assert.Len(t, group.Clients, 2)
+	assert.Equal(t, 10, group.BatchSize)
+	assert.Equal(t, 3, group.Retry)
+	es := clientsOf("es")
+	assert.False(t, es[0].closed)
+	assert.False(t, es[1].closed)
+	assert.True(t, es[2].closed, "clients exceeding the router clients are closed")
+}
+
+// testBatch is a publisher.Batch holding events that already passed the
+// router encoder.
+type testBatch struct {
+	outest.Batch
+	events []publisher.Event
+}
+
+func (b *testBatch) Events() []publisher.Event { return b.events }
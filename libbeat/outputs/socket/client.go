@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package socket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/transport"
+)
+
+var errNotConnected = errors.New("not connected")
+
+// client writes events as newline delimited lines to a Unix domain socket.
+// Write errors close the connection, the pipeline reconnects the client
+// with backoff.
+type client struct {
+	log      *logp.Logger
+	path     string
+	timeout  time.Duration
+	observer outputs.Observer
+	codec    codec.Codec
+	index    string
+
+	dialer transport.Dialer
+	conn   net.Conn
+	buf    bytes.Buffer
+}
+
+func newClient(
+	logger *logp.Logger,
+	path string,
+	timeout time.Duration,
+	observer outputs.Observer,
+	codec codec.Codec,
+	index string,
+) *client {
+	dialer := &net.Dialer{Timeout: timeout}
+	return &client{
+		log:      logger.Named("socket"),
+		path:     path,
+		timeout:  timeout,
+		observer: observer,
+		codec:    codec,
+		index:    index,
+		// The bytes written and write errors are reported to the observer.
+		dialer: transport.StatsDialer(transport.DialerFunc(dialer.DialContext), observer),
+	}
+}
+
+func (c *client) Connect(ctx context.Context) error {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+
+	conn, err := c.dialer.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %v: %w", c.path, err)
+	}
+	c.conn = conn
+	c.log.Infof("Connected to %v", c.path)
+	return nil
+}
+
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Publish writes the events of batch in a single write. Events that can not
+// be encoded are dropped. If the write fails, the events written completely
+// are acknowledged and the others are retried after reconnecting.
+func (c *client) Publish(_ context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	if c.conn == nil {
+		c.observer.RetryableErrors(len(events))
+		batch.Retry()
+		return errNotConnected
+	}
+
+	// ends holds the offset of the end of the line of each encoded event.
+	c.buf.Reset()
+	encoded := make([]publisher.Event, 0, len(events))
+	ends := make([]int, 0, len(events))
+	begin := time.Now()
+	for i := range events {
+		line, err := c.codec.Encode(c.index, &events[i].Content)
+		if err != nil {
+			c.log.Errorf("Failed to encode event: %v", err)
+			continue
+		}
+		c.buf.Write(line)
+		c.buf.WriteByte('\n')
+		encoded = append(encoded, events[i])
+		ends = append(ends, c.buf.Len())
+	}
+	c.observer.ReportSerialization(time.Since(begin), c.buf.Len())
+	c.observer.PermanentErrors(len(events) - len(encoded))
+
+	if c.timeout > 0 {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	n, err := c.conn.Write(c.buf.Bytes())
+	if err != nil {
+		written := sort.SearchInts(ends, n+1)
+		c.observer.AckedEvents(written)
+		c.observer.RetryableErrors(len(encoded) - written)
+		if written < len(encoded) {
+			batch.RetryEvents(encoded[written:])
+		} else {
+			batch.ACK()
+		}
+		_ = c.Close()
+		return fmt.Errorf("failed to write events to %v: %w", c.path, err)
+	}
+
+	c.observer.AckedEvents(len(encoded))
+	batch.ACK()
+	return nil
+}
+
+func (c *client) String() string {
+	return "socket(unix://" + c.path + ")"
+}
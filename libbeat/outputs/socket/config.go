@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package socket
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+type socketConfig struct {
+	// Path of the Unix domain socket events are written to.
+	Path string `config:"path" validate:"required"`
+
+	Codec codec.Config `config:"codec"`
+
+	// Timeout limits the time spent connecting and writing a batch.
+	Timeout time.Duration `config:"timeout" validate:"min=0"`
+
+	BulkMaxSize int              `config:"bulk_max_size"`
+	MaxRetries  int              `config:"max_retries" validate:"min=-1"`
+	Backoff     Backoff          `config:"backoff"`
+	Queue       config.Namespace `config:"queue"`
+}
+
+type Backoff struct {
+	Init time.Duration
+	Max  time.Duration
+}
+
+var defaultConfig = socketConfig{
+	Timeout:     30 * time.Second,
+	BulkMaxSize: 2048,
+	MaxRetries:  3,
+	Backoff: Backoff{
+		Init: 1 * time.Second,
+		Max:  60 * time.Second,
+	},
+}
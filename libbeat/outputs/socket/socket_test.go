@@ -0,0 +1,175 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package socket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/format"
+	jsoncodec "github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// listen returns a Unix socket listener with a path short enough for all
+// platforms.
+func listen(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix domain sockets are not tested on Windows")
+	}
+	dir, err := os.MkdirTemp("", "socket")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "out.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	return l, path
+}
+
+func TestClientPublish(t *testing.T) {
+	l, path := listen(t)
+	reg := monitoring.NewRegistry()
+	c := newClient(logp.NewTestingLogger(t, ""), path, 0, outputs.NewStats(reg),
+		jsoncodec.New("1.2.3", jsoncodec.Config{}), "test")
+	require.NoError(t, c.Connect(context.Background()))
+	defer c.Close()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: mapstr.M{"message": "first"}},
+		beat.Event{Fields: mapstr.M{"message": "second"}},
+	)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	scanner := bufio.NewScanner(conn)
+	for _, expected := range []string{"first", "second"} {
+		require.True(t, scanner.Scan())
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		assert.Equal(t, expected, event["message"])
+	}
+
+	assert.Equal(t, uint64(2), reg.Get("events.acked").(*monitoring.Uint).Get())
+	assert.NotZero(t, reg.Get("write.bytes").(*monitoring.Uint).Get(), "the bytes written should be reported")
+}
+
+func TestClientEncodingError(t *testing.T) {
+	l, path := listen(t)
+	reg := monitoring.NewRegistry()
+	c := newClient(logp.NewTestingLogger(t, ""), path, 0, outputs.NewStats(reg),
+		format.New(fmtstr.MustCompileEvent("%{[message]}")), "test")
+	require.NoError(t, c.Connect(context.Background()))
+	defer c.Close()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: mapstr.M{}},
+		beat.Event{Fields: mapstr.M{"message": "valid"}},
+	)
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	scanner := bufio.NewScanner(conn)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, "valid", scanner.Text())
+	assert.Equal(t, uint64(1), reg.Get("events.dropped").(*monitoring.Uint).Get())
+	assert.Equal(t, uint64(1), reg.Get("events.acked").(*monitoring.Uint).Get())
+}
+
+func TestClientWriteError(t *testing.T) {
+	l, path := listen(t)
+	reg := monitoring.NewRegistry()
+	c := newClient(logp.NewTestingLogger(t, ""), path, 0, outputs.NewStats(reg),
+		jsoncodec.New("1.2.3", jsoncodec.Config{}), "test")
+	require.NoError(t, c.Connect(context.Background()))
+	defer c.Close()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	conn.Close()
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: mapstr.M{"message": "first"}},
+		beat.Event{Fields: mapstr.M{"message": "second"}},
+	)
+	require.Error(t, c.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 2, "events not written should be retried")
+	assert.Equal(t, uint64(2), reg.Get("events.failed").(*monitoring.Uint).Get())
+	assert.Equal(t, uint64(1), reg.Get("write.errors").(*monitoring.Uint).Get())
+
+	// The client reconnects to the socket.
+	require.NoError(t, c.Connect(context.Background()))
+	conn, err = l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+}
+
+func TestClientNotConnected(t *testing.T) {
+	_, path := listen(t)
+	c := newClient(logp.NewTestingLogger(t, ""), path, 0, outputs.NewNilObserver(),
+		jsoncodec.New("1.2.3", jsoncodec.Config{}), "test")
+
+	batch := outest.NewBatch(beat.Event{Fields: mapstr.M{"message": "retried"}})
+	assert.Error(t, c.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetry, batch.Signals[0].Tag)
+
+	require.NoError(t, os.Remove(path))
+	assert.Error(t, c.Connect(context.Background()), "connecting to a missing socket should fail")
+}
+
+func TestMakeSocket(t *testing.T) {
+	_, err := makeSocket(nil, beat.Info{Logger: logp.NewTestingLogger(t, "")}, outputs.NewNilObserver(), config.MustNewConfigFrom(mapstr.M{}))
+	assert.Error(t, err, "the path is required")
+
+	group, err := makeSocket(nil, beat.Info{Logger: logp.NewTestingLogger(t, "")}, outputs.NewNilObserver(), config.MustNewConfigFrom(mapstr.M{
+		"path": "/run/sidecar.sock",
+	}))
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+	assert.Equal(t, "backoff(socket(unix:///run/sidecar.sock))", group.Clients[0].String())
+	assert.Equal(t, defaultConfig.BulkMaxSize, group.BatchSize)
+}
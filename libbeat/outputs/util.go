@@ -23,7 +23,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/management"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
-	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+	_ "github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
 	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
 )
@@ -39,25 +39,14 @@ func Fail(err error) (Group, error) { return Group{}, err }
 func Success(cfg config.Namespace, batchSize, retry int, encoderFactory queue.EncoderFactory, clients ...Client) (Group, error) {
 	var q queue.QueueFactory
 	if cfg.IsSet() && cfg.Config().Enabled() {
-		switch cfg.Name() {
-		case memqueue.QueueType:
-			settings, err := memqueue.SettingsForUserConfig(cfg.Config())
-			if err != nil {
-				return Group{}, fmt.Errorf("unable to get memory queue settings: %w", err)
-			}
-			q = memqueue.FactoryForSettings(settings)
-		case diskqueue.QueueType:
-			if management.UnderAgent() {
-				logger := logp.NewLogger("output")
-				logger.Warn("Disk queue configuration found while running under agent: this configuration is unsupported and in technical preview.")
-			}
-			settings, err := diskqueue.SettingsForUserConfig(cfg.Config())
-			if err != nil {
-				return Group{}, fmt.Errorf("unable to get disk queue settings: %w", err)
-			}
-			q = diskqueue.FactoryForSettings(settings)
-		default:
-			return Group{}, fmt.Errorf("unknown queue type: %s", cfg.Name())
+		if cfg.Name() == diskqueue.QueueType && management.UnderAgent() {
+			logger := logp.NewLogger("output")
+			logger.Warn("Disk queue configuration found while running under agent: this configuration is unsupported and in technical preview.")
+		}
+		var err error
+		q, err = queue.Load(cfg.Name(), cfg.Config())
+		if err != nil {
+			return Group{}, fmt.Errorf("unable to get %s queue settings: %w", cfg.Name(), err)
 		}
 	}
 	return Group{
@@ -41,6 +41,8 @@ type decodeJSONFields struct {
 	expandKeys    bool
 	overwriteKeys bool
 	addErrorKey   bool
+	ignoreFailure bool
+	tagOnFailure  []string
 	processArray  bool
 	documentID    string
 	target        *string
@@ -53,6 +55,8 @@ type config struct {
 	ExpandKeys    bool     `config:"expand_keys"`
 	OverwriteKeys bool     `config:"overwrite_keys"`
 	AddErrorKey   bool     `config:"add_error_key"`
+	IgnoreFailure bool     `config:"ignore_failure"` // Ignore errors when a field is not valid JSON.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when a field is not valid JSON.
 	ProcessArray  bool     `config:"process_array"`
 	Target        *string  `config:"target"`
 	DocumentID    string   `config:"document_id"`
@@ -70,7 +74,8 @@ func init() {
 	processors.RegisterPlugin("decode_json_fields",
 		checks.ConfigChecked(NewDecodeJSONFields,
 			checks.RequireFields("fields"),
-			checks.AllowedFields("fields", "max_depth", "overwrite_keys", "add_error_key", "process_array", "target", "when", "document_id", "expand_keys")))
+			checks.AllowedFields("fields", "max_depth", "overwrite_keys", "add_error_key", "process_array", "target", "when", "document_id", "expand_keys",
+				"ignore_failure", "tag_on_failure")))
 
 	jsprocessor.RegisterPlugin("DecodeJSONFields", NewDecodeJSONFields)
 }
@@ -92,6 +97,8 @@ func NewDecodeJSONFields(c *cfg.C) (beat.Processor, error) {
 		expandKeys:    config.ExpandKeys,
 		overwriteKeys: config.OverwriteKeys,
 		addErrorKey:   config.AddErrorKey,
+		ignoreFailure: config.IgnoreFailure,
+		tagOnFailure:  config.TagOnFailure,
 		processArray:  config.ProcessArray,
 		documentID:    config.DocumentID,
 		target:        config.Target,
@@ -121,7 +128,16 @@ func (f *decodeJSONFields) Run(event *beat.Event) (*beat.Event, error) {
 		err = unmarshal(f.maxDepth, text, &output, f.processArray)
 		if err != nil {
 			f.logger.Debugf("Error trying to unmarshal %s", text)
-			errs = append(errs, err.Error())
+			// The field is left as is, so fields that may or may not
+			// contain JSON can be tagged instead of failing the event.
+			if len(f.tagOnFailure) > 0 {
+				if tagErr := mapstr.AddTags(event.Fields, f.tagOnFailure); tagErr != nil {
+					f.logger.Debugw("Failed to add failure tags.", "error", tagErr)
+				}
+			}
+			if !f.ignoreFailure {
+				errs = append(errs, err.Error())
+			}
 			event.SetErrorWithOption(fmt.Sprintf("parsing input as JSON: %s", err.Error()), f.addErrorKey, text, field)
 			continue
 		}
@@ -579,6 +579,44 @@ func TestAddErrorToEventOnUnmarshalError(t *testing.T) {
 	assert.NotNil(t, errObj["message"])
 }
 
+func TestTagOnFailure(t *testing.T) {
+	config := conf.MustNewConfigFrom(map[string]interface{}{
+		"fields":         []string{"msg"},
+		"process_array":  true,
+		"ignore_failure": true,
+		"tag_on_failure": []string{"_json_parse_failure"},
+	})
+	p, err := NewDecodeJSONFields(config)
+	require.NoError(t, err)
+
+	// Valid JSON is decoded and not tagged.
+	actual, err := p.Run(&beat.Event{Fields: mapstr.M{"msg": `[{"a":1}]`}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"msg":[{"a":1}]}`, actual.Fields.String())
+
+	// Other strings are kept and tagged.
+	actual, err = p.Run(&beat.Event{Fields: mapstr.M{"msg": "11:38:04,323 |-INFO testing"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{
+		"msg":  "11:38:04,323 |-INFO testing",
+		"tags": []string{"_json_parse_failure"},
+	}, actual.Fields)
+
+	// Without ignore_failure the error is still returned.
+	config = conf.MustNewConfigFrom(map[string]interface{}{
+		"fields":         []string{"msg"},
+		"tag_on_failure": []string{"_json_parse_failure"},
+	})
+	p, err = NewDecodeJSONFields(config)
+	require.NoError(t, err)
+	actual, err = p.Run(&beat.Event{Fields: mapstr.M{"msg": "{broken"}})
+	assert.Error(t, err)
+	assert.Equal(t, mapstr.M{
+		"msg":  "{broken",
+		"tags": []string{"_json_parse_failure"},
+	}, actual.Fields)
+}
+
 func getActualValue(t *testing.T, config *conf.C, input mapstr.M) mapstr.M {
 	log := logp.NewLogger("decode_json_fields_test")
 
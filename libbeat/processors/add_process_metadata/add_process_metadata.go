@@ -104,7 +104,7 @@ func New(cfg *conf.C) (beat.Processor, error) {
 		return nil, fmt.Errorf("fail to unpack the %v configuration: %w", processorName, err)
 	}
 
-	return newProcessMetadataProcessorWithProvider(config, &procCache, false)
+	return newProcessMetadataProcessorWithProvider(config, processCacheFor(config), false)
 }
 
 // NewWithCache construct a new add_process_metadata processor with cache for container IDs.
@@ -115,7 +115,7 @@ func NewWithCache(cfg *conf.C) (beat.Processor, error) {
 		return nil, fmt.Errorf("fail to unpack the %v configuration: %w", processorName, err)
 	}
 
-	return newProcessMetadataProcessorWithProvider(config, &procCache, true)
+	return newProcessMetadataProcessorWithProvider(config, processCacheFor(config), true)
 }
 
 func NewWithConfig(opts ...ConfigOption) (beat.Processor, error) {
@@ -125,7 +125,7 @@ func NewWithConfig(opts ...ConfigOption) (beat.Processor, error) {
 		o(&cfg)
 	}
 
-	return newProcessMetadataProcessorWithProvider(cfg, &procCache, true)
+	return newProcessMetadataProcessorWithProvider(cfg, processCacheFor(cfg), true)
 }
 
 func newProcessMetadataProcessorWithProvider(config config, provider processMetadataProvider, withCache bool) (proc beat.Processor, err error) {
@@ -212,6 +212,9 @@ func (p *addProcessMetadata) Run(event *beat.Event) (*beat.Event, error) {
 			case errors.Is(err, mapstr.ErrKeyNotFound):
 				continue
 			case errors.Is(err, ErrNoProcess):
+				if p.config.IgnoreMissingProcess {
+					return event, nil
+				}
 				return event, err
 			default:
 				return event, fmt.Errorf("error applying %s processor: %w", processorName, err)
@@ -327,6 +330,16 @@ func (p *addProcessMetadata) getContainerID(pid int) (string, error) {
 	return cid, nil
 }
 
+// processCacheFor returns the process cache to use with the given config. The
+// shared cache is used unless a different process_cache_expire_time is set.
+func processCacheFor(config config) processMetadataProvider {
+	if config.ProcessCacheExpireTime == cacheExpiration {
+		return &procCache
+	}
+	cache := newProcessCache(config.ProcessCacheExpireTime, cacheCapacity, cacheEvictionEffort, gosysinfoProvider{})
+	return &cache
+}
+
 type addProcessMetadataCloser struct {
 	addProcessMetadata
 }
@@ -340,9 +353,10 @@ func (p *addProcessMetadataCloser) Close() error {
 
 // String returns the processor representation formatted as a string
 func (p *addProcessMetadata) String() string {
-	return fmt.Sprintf("%v=[match_pids=%v, mappings=%v, ignore_missing=%v, overwrite_fields=%v, restricted_fields=%v, host_path=%v, cgroup_prefixes=%v]",
-		processorName, p.config.MatchPIDs, p.mappings, p.config.IgnoreMissing,
-		p.config.OverwriteKeys, p.config.RestrictedFields, p.config.HostPath, p.config.CgroupPrefixes)
+	return fmt.Sprintf("%v=[match_pids=%v, mappings=%v, ignore_missing=%v, ignore_missing_process=%v, overwrite_fields=%v, restricted_fields=%v, host_path=%v, cgroup_prefixes=%v, process_cache_expire_time=%v]",
+		processorName, p.config.MatchPIDs, p.mappings, p.config.IgnoreMissing, p.config.IgnoreMissingProcess,
+		p.config.OverwriteKeys, p.config.RestrictedFields, p.config.HostPath, p.config.CgroupPrefixes,
+		p.config.ProcessCacheExpireTime)
 }
 
 func (p *processMetadata) toMap() mapstr.M {
@@ -561,6 +561,19 @@ func TestAddProcessMetadata(t *testing.T) {
 			},
 			err: ErrNoProcess,
 		},
+		{
+			description: "process not found ignored",
+			config: mapstr.M{
+				"match_pids":             []string{"ppid"},
+				"ignore_missing_process": true,
+			},
+			event: mapstr.M{
+				"ppid": 42,
+			},
+			expected: mapstr.M{
+				"ppid": 42,
+			},
+		},
 		{
 			description: "lookup first PID",
 			config: mapstr.M{
@@ -1096,6 +1109,35 @@ func TestBadProcess(t *testing.T) {
 	assert.Equal(t, ev.Fields, result.Fields)
 }
 
+func TestProcessCacheExpireTime(t *testing.T) {
+	config, err := conf.NewConfigFrom(mapstr.M{
+		"match_pids":                []string{"self_pid"},
+		"process_cache_expire_time": "5m",
+		"ignore_missing_process":    true,
+	})
+	require.NoError(t, err)
+	proc, err := New(config)
+	require.NoError(t, err)
+
+	unwrapped, _ := proc.(*addProcessMetadata)
+	cache, ok := unwrapped.provider.(*processCache)
+	require.True(t, ok)
+	assert.NotSame(t, &procCache, cache)
+	assert.Equal(t, 5*time.Minute, cache.expiration)
+
+	// The process doesn't exist, but the event is published untouched.
+	ev := beat.Event{
+		Fields: mapstr.M{
+			"self_pid": 0,
+		},
+	}
+	result, err := proc.Run(&ev)
+	require.NoError(t, err)
+	assert.Equal(t, ev.Fields, result.Fields)
+
+	assert.Same(t, &procCache, processCacheFor(defaultConfig()))
+}
+
 func TestPIDToInt(t *testing.T) {
 	const intIs64bit = unsafe.Sizeof(int(0)) == unsafe.Sizeof(int64(0))
 	for _, test := range []struct {
@@ -33,6 +33,9 @@ type config struct {
 	// IgnoreMissing: Ignore errors if event has no PID field.
 	IgnoreMissing bool `config:"ignore_missing"`
 
+	// IgnoreMissingProcess: Ignore errors if the PID doesn't belong to a running process.
+	IgnoreMissingProcess bool `config:"ignore_missing_process"`
+
 	// OverwriteKeys allow target_fields to overwrite existing fields.
 	OverwriteKeys bool `config:"overwrite_keys"`
 
@@ -60,6 +63,10 @@ type config struct {
 	// CgroupCacheExpireTime is the length of time before cgroup cache elements expire in seconds,
 	// set to 0 to disable the cgroup cache
 	CgroupCacheExpireTime time.Duration `config:"cgroup_cache_expire_time"`
+
+	// ProcessCacheExpireTime is the length of time before process cache elements expire,
+	// set to 0 to disable the process cache
+	ProcessCacheExpireTime time.Duration `config:"process_cache_expire_time" validate:"min=0"`
 }
 
 func (c *config) Validate() error {
@@ -116,12 +123,13 @@ func init() {
 
 func defaultConfig() config {
 	return config{
-		IgnoreMissing:         true,
-		OverwriteKeys:         false,
-		RestrictedFields:      false,
-		MatchPIDs:             []string{"process.pid", "process.parent.pid"},
-		HostPath:              "/",
-		CgroupCacheExpireTime: cacheExpiration,
+		IgnoreMissing:          true,
+		OverwriteKeys:          false,
+		RestrictedFields:       false,
+		MatchPIDs:              []string{"process.pid", "process.parent.pid"},
+		HostPath:               "/",
+		CgroupCacheExpireTime:  cacheExpiration,
+		ProcessCacheExpireTime: cacheExpiration,
 	}
 }
 
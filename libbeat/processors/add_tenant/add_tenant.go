@@ -0,0 +1,173 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_tenant
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+)
+
+const processorName = "add_tenant"
+
+// defaultCIDRField is the field CIDR rules are evaluated on by default.
+const defaultCIDRField = "source.ip"
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("rules"),
+			checks.AllowedFields("rules", "target_field", "default", "when")))
+}
+
+type addTenant struct {
+	config
+
+	rules []rule
+	// tries holds the networks of the CIDR rules, by field.
+	tries map[string]*prefixTrie
+}
+
+type rule struct {
+	tenant string
+	field  string
+	cidr   bool
+	values map[string]struct{}
+}
+
+// New constructs a new add_tenant processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	p := &addTenant{
+		config: config,
+		tries:  map[string]*prefixTrie{},
+	}
+	for i, rc := range config.Rules {
+		r := rule{tenant: rc.Tenant, field: rc.Field}
+		if len(rc.CIDR) > 0 {
+			r.cidr = true
+			if r.field == "" {
+				r.field = defaultCIDRField
+			}
+			trie := p.tries[r.field]
+			if trie == nil {
+				trie = newPrefixTrie()
+				p.tries[r.field] = trie
+			}
+			for _, cidr := range rc.CIDR {
+				// Prefixes are validated when unpacking the configuration.
+				prefix, _ := netip.ParsePrefix(cidr)
+				trie.insert(prefix, i)
+			}
+		} else {
+			r.values = make(map[string]struct{}, len(rc.Equals))
+			for _, v := range rc.Equals {
+				r.values[v] = struct{}{}
+			}
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+// Run sets the tenant of the first rule matching the event, or the default
+// tenant if no rule matches. Rules on missing or invalid fields don't match.
+func (p *addTenant) Run(event *beat.Event) (*beat.Event, error) {
+	tenant := p.Default
+
+	// cidrMatches caches the first matching CIDR rule of each field, as a
+	// single lookup finds it among all the rules of the field.
+	var cidrMatches map[string]int
+	for i, r := range p.rules {
+		matched := false
+		if r.cidr {
+			match, found := cidrMatches[r.field]
+			if !found {
+				match = p.lookup(event, r.field)
+				if cidrMatches == nil {
+					cidrMatches = map[string]int{}
+				}
+				cidrMatches[r.field] = match
+			}
+			matched = match == i
+		} else {
+			matched = r.equals(event)
+		}
+		if matched {
+			tenant = r.tenant
+			break
+		}
+	}
+
+	if tenant == "" {
+		return event, nil
+	}
+	if _, err := event.PutValue(p.TargetField, tenant); err != nil {
+		return event, fmt.Errorf("failed to set field %s: %w", p.TargetField, err)
+	}
+	return event, nil
+}
+
+// lookup returns the index of the first CIDR rule on field matching the IP
+// address of the event, or -1 if none matches.
+func (p *addTenant) lookup(event *beat.Event, field string) int {
+	v, err := event.GetValue(field)
+	if err != nil {
+		return -1
+	}
+	s, ok := v.(string)
+	if !ok {
+		return -1
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return -1
+	}
+	return p.tries[field].lookup(addr)
+}
+
+func (r rule) equals(event *beat.Event) bool {
+	v, err := event.GetValue(r.field)
+	if err != nil {
+		return false
+	}
+	s, ok := v.(string)
+	if !ok {
+		s = fmt.Sprint(v)
+	}
+	_, found := r.values[s]
+	return found
+}
+
+func (p *addTenant) String() string {
+	tenants := make([]string, 0, len(p.rules))
+	for _, r := range p.rules {
+		tenants = append(tenants, r.tenant)
+	}
+	return fmt.Sprintf("%v=[rules=[%v], target_field=%v, default=%v]",
+		processorName, strings.Join(tenants, ", "), p.TargetField, p.Default)
+}
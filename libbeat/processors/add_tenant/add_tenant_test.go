@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_tenant
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestPrefixTrie(t *testing.T) {
+	trie := newPrefixTrie()
+	trie.insert(netip.MustParsePrefix("10.0.0.0/8"), 3)
+	trie.insert(netip.MustParsePrefix("10.1.0.0/16"), 1)
+	trie.insert(netip.MustParsePrefix("10.1.2.0/24"), 2)
+	trie.insert(netip.MustParsePrefix("10.1.2.3/32"), 0)
+	trie.insert(netip.MustParsePrefix("::ffff:192.168.0.0/112"), 4)
+	trie.insert(netip.MustParsePrefix("2001:db8::/32"), 5)
+	trie.insert(netip.MustParsePrefix("0.0.0.0/0"), 6)
+
+	cases := map[string]int{
+		"10.1.2.3":           0,
+		"10.1.2.4":           1, // Rule 1 comes before rule 2 and 3.
+		"10.2.0.1":           3,
+		"192.168.10.1":       4,
+		"::ffff:192.168.0.1": 4,
+		"172.16.0.1":         6,
+		"2001:db8::1":        5,
+		"2001:db9::1":        -1,
+	}
+	for addr, want := range cases {
+		assert.Equal(t, want, trie.lookup(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestAddTenant(t *testing.T) {
+	rules := []mapstr.M{
+		{"tenant": "db", "cidr": []string{"10.1.0.0/16"}},
+		{"tenant": "web", "field": "host.name", "equals": []string{"web-1", "web-2"}},
+		{"tenant": "internal", "cidr": []string{"10.0.0.0/8", "192.168.0.0/16"}},
+		{"tenant": "partner", "field": "destination.ip", "cidr": []string{"2001:db8::/32"}},
+	}
+	cases := map[string]struct {
+		config mapstr.M
+		fields mapstr.M
+		want   mapstr.M
+	}{
+		"first cidr rule": {
+			fields: mapstr.M{"source.ip": "10.1.2.3", "host.name": "web-1"},
+			want:   mapstr.M{"source.ip": "10.1.2.3", "host.name": "web-1", "tenant": mapstr.M{"id": "db"}},
+		},
+		"equals rule before a cidr rule": {
+			fields: mapstr.M{"source.ip": "10.2.0.1", "host.name": "web-2"},
+			want:   mapstr.M{"source.ip": "10.2.0.1", "host.name": "web-2", "tenant": mapstr.M{"id": "web"}},
+		},
+		"later cidr rule": {
+			fields: mapstr.M{"source.ip": "192.168.1.1"},
+			want:   mapstr.M{"source.ip": "192.168.1.1", "tenant": mapstr.M{"id": "internal"}},
+		},
+		"other field": {
+			fields: mapstr.M{"source.ip": "172.16.0.1", "destination.ip": "2001:db8::10"},
+			want:   mapstr.M{"source.ip": "172.16.0.1", "destination.ip": "2001:db8::10", "tenant": mapstr.M{"id": "partner"}},
+		},
+		"no match": {
+			fields: mapstr.M{"source.ip": "172.16.0.1"},
+			want:   mapstr.M{"source.ip": "172.16.0.1"},
+		},
+		"invalid address": {
+			fields: mapstr.M{"source.ip": "not an address"},
+			want:   mapstr.M{"source.ip": "not an address"},
+		},
+		"default tenant": {
+			config: mapstr.M{"default": "shared", "target_field": "organization.id"},
+			fields: mapstr.M{"message": "hello"},
+			want:   mapstr.M{"message": "hello", "organization": mapstr.M{"id": "shared"}},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := mapstr.M{"rules": rules}
+			config.Update(c.config)
+			p, err := New(conf.MustNewConfigFrom(config))
+			require.NoError(t, err)
+
+			fields := mapstr.M{}
+			for k, v := range c.fields {
+				_, err := fields.Put(k, v)
+				require.NoError(t, err)
+			}
+			want := mapstr.M{}
+			for k, v := range c.want {
+				_, err := want.Put(k, v)
+				require.NoError(t, err)
+			}
+
+			event, err := p.Run(&beat.Event{Fields: fields})
+			require.NoError(t, err)
+			assert.Equal(t, want, event.Fields)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]mapstr.M{
+		"no rules":        {"rules": []mapstr.M{}},
+		"no tenant":       {"rules": []mapstr.M{{"cidr": []string{"10.0.0.0/8"}}}},
+		"no condition":    {"rules": []mapstr.M{{"tenant": "a", "field": "x"}}},
+		"both conditions": {"rules": []mapstr.M{{"tenant": "a", "field": "x", "cidr": []string{"10.0.0.0/8"}, "equals": []string{"y"}}}},
+		"equals no field": {"rules": []mapstr.M{{"tenant": "a", "equals": []string{"y"}}}},
+		"invalid cidr":    {"rules": []mapstr.M{{"tenant": "a", "cidr": []string{"10.0.0.0/33"}}}},
+		"empty target":    {"rules": []mapstr.M{{"tenant": "a", "cidr": []string{"10.0.0.0/8"}}}, "target_field": ""},
+	}
+	for name, config := range cases {
+		_, err := New(conf.MustNewConfigFrom(config))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_tenant
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+type config struct {
+	Rules       []ruleConfig `config:"rules"`        // Rules evaluated in order, the first matching rule sets the tenant.
+	TargetField string       `config:"target_field"` // Field the tenant is written to.
+	Default     string       `config:"default"`      // Tenant of events matching no rule. Events are not modified if empty.
+}
+
+type ruleConfig struct {
+	Tenant string   `config:"tenant"` // Tenant of the events matching the rule.
+	Field  string   `config:"field"`  // Field the rule is evaluated on.
+	CIDR   []string `config:"cidr"`   // Networks the IP address in field must be part of.
+	Equals []string `config:"equals"` // Values field must be equal to.
+}
+
+func defaultConfig() config {
+	return config{
+		TargetField: "tenant.id",
+	}
+}
+
+func (c *config) Validate() error {
+	if len(c.Rules) == 0 {
+		return errors.New("at least one rule must be configured")
+	}
+	if c.TargetField == "" {
+		return errors.New("target_field must not be empty")
+	}
+	for i := range c.Rules {
+		if err := c.Rules[i].validate(); err != nil {
+			return fmt.Errorf("invalid rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *ruleConfig) validate() error {
+	if r.Tenant == "" {
+		return errors.New("tenant must not be empty")
+	}
+	if (len(r.CIDR) == 0) == (len(r.Equals) == 0) {
+		return errors.New("exactly one of cidr or equals must be set")
+	}
+	if len(r.Equals) > 0 && r.Field == "" {
+		return errors.New("field must be set for equals rules")
+	}
+	for _, cidr := range r.CIDR {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("invalid cidr: %w", err)
+		}
+	}
+	return nil
+}
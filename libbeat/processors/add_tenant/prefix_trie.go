@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_tenant
+
+import "net/netip"
+
+// prefixTrie is a binary trie of network prefixes, indexed by the bits of the
+// addresses. Looking up an address walks the prefixes containing it from the
+// shortest to the longest one, so its cost depends on the address length and
+// not on the number of prefixes.
+type prefixTrie struct {
+	v4, v6 *trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	// rule is the index of the first rule of the prefix ending at this node,
+	// or -1 if no prefix ends here.
+	rule int
+}
+
+func newPrefixTrie() *prefixTrie {
+	return &prefixTrie{
+		v4: &trieNode{rule: -1},
+		v6: &trieNode{rule: -1},
+	}
+}
+
+// insert adds the prefix for the given rule. If the prefix was already added
+// by another rule, the rule with the lowest index is kept.
+func (t *prefixTrie) insert(prefix netip.Prefix, rule int) {
+	prefix = unmapPrefix(prefix.Masked())
+	node := t.root(prefix.Addr())
+	addr := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		b := bit(addr, i)
+		if node.children[b] == nil {
+			node.children[b] = &trieNode{rule: -1}
+		}
+		node = node.children[b]
+	}
+	if node.rule < 0 || rule < node.rule {
+		node.rule = rule
+	}
+}
+
+// lookup returns the lowest rule index of all prefixes containing addr, or -1
+// if no prefix contains it.
+func (t *prefixTrie) lookup(addr netip.Addr) int {
+	addr = addr.Unmap()
+	node := t.root(addr)
+	bytes := addr.AsSlice()
+	match := -1
+	for i := 0; node != nil; i++ {
+		if node.rule >= 0 && (match < 0 || node.rule < match) {
+			match = node.rule
+		}
+		if i == addr.BitLen() {
+			break
+		}
+		node = node.children[bit(bytes, i)]
+	}
+	return match
+}
+
+func (t *prefixTrie) root(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// unmapPrefix converts prefixes of IPv4-mapped IPv6 addresses to IPv4
+// prefixes, as addresses are unmapped on lookup.
+func unmapPrefix(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr()
+	if !addr.Is4In6() {
+		return prefix
+	}
+	bits := prefix.Bits() - 96
+	if bits < 0 {
+		bits = 0
+	}
+	return netip.PrefixFrom(addr.Unmap(), bits).Masked()
+}
+
+func bit(addr []byte, i int) int {
+	return int(addr[i/8]>>(7-i%8)) & 1
+}
@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_timezone
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "add_timezone"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("target", "refresh_interval", "when")))
+}
+
+// zoneState is the time zone of the host at the time it was looked up.
+type zoneState struct {
+	name   string
+	offset string
+	dst    bool
+}
+
+type addTimezone struct {
+	config    config
+	clock     clockwork.Clock
+	localZone func() (string, *time.Location)
+
+	mutex     sync.Mutex
+	state     zoneState
+	refreshed time.Time
+
+	log       *logp.Logger
+	refreshes *monitoring.Int
+}
+
+// New constructs a new add_timezone processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &addTimezone{
+		config:    config,
+		clock:     clockwork.NewRealClock(),
+		localZone: localZone,
+		log:       log,
+		refreshes: monitoring.NewInt(reg, "refreshes"),
+	}, nil
+}
+
+// Run writes the host's time zone to the event. event.timezone is set to the
+// IANA name of the time zone, or to its UTC offset if the name is unknown.
+func (p *addTimezone) Run(event *beat.Event) (*beat.Event, error) {
+	state := p.zone()
+
+	timezone := state.name
+	if timezone == "" {
+		timezone = state.offset
+	}
+	if _, err := event.PutValue("event.timezone", timezone); err != nil {
+		return event, fmt.Errorf("failed to put event.timezone: %w", err)
+	}
+	if _, err := event.PutValue(p.config.Target+".name", state.name); err != nil {
+		return event, fmt.Errorf("failed to put time zone name: %w", err)
+	}
+	if _, err := event.PutValue(p.config.Target+".offset", state.offset); err != nil {
+		return event, fmt.Errorf("failed to put time zone offset: %w", err)
+	}
+	if _, err := event.PutValue(p.config.Target+".dst", state.dst); err != nil {
+		return event, fmt.Errorf("failed to put time zone DST state: %w", err)
+	}
+	return event, nil
+}
+
+// zone returns the cached time zone, looking it up again once the refresh
+// interval has passed, so changes of the host's time zone and DST
+// transitions are picked up.
+func (p *addTimezone) zone() zoneState {
+	now := p.clock.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.refreshed.IsZero() && now.Sub(p.refreshed) < p.config.RefreshInterval {
+		return p.state
+	}
+
+	name, loc := p.localZone()
+	t := now.In(loc)
+	_, offset := t.Zone()
+	state := zoneState{name: name, offset: formatOffset(offset), dst: t.IsDST()}
+	if state != p.state {
+		p.log.Debugf("time zone is %v (UTC offset %v, DST %v)", state.name, state.offset, state.dst)
+	}
+	p.state = state
+	p.refreshed = now
+	p.refreshes.Inc()
+	return state
+}
+
+// formatOffset formats an offset in seconds east of UTC as [+-]hh:mm.
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+func (p *addTimezone) String() string {
+	return fmt.Sprintf("%v=[target=%v, refresh_interval=%v]",
+		processorName, p.config.Target, p.config.RefreshInterval)
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_timezone
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestAddTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"refresh_interval": "1m",
+	}))
+	require.NoError(t, err)
+	// One minute before the switch to daylight saving time.
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 3, 10, 6, 59, 0, 0, time.UTC))
+	lookups := 0
+	p.(*addTimezone).clock = clock
+	p.(*addTimezone).localZone = func() (string, *time.Location) {
+		lookups++
+		return "America/New_York", newYork
+	}
+
+	run := func() mapstr.M {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{}})
+		require.NoError(t, err)
+		return event.Fields
+	}
+
+	assert.Equal(t, mapstr.M{
+		"event": mapstr.M{"timezone": "America/New_York"},
+		"host": mapstr.M{"timezone": mapstr.M{
+			"name":   "America/New_York",
+			"offset": "-05:00",
+			"dst":    false,
+		}},
+	}, run())
+
+	clock.Advance(59 * time.Second)
+	run()
+	assert.Equal(t, 1, lookups, "the time zone should be cached")
+
+	clock.Advance(time.Second)
+	fields := run()
+	assert.Equal(t, 2, lookups, "the time zone should be refreshed")
+	offset, _ := fields.GetValue("host.timezone.offset")
+	assert.Equal(t, "-04:00", offset)
+	dst, _ := fields.GetValue("host.timezone.dst")
+	assert.Equal(t, true, dst)
+}
+
+func TestAddTimezoneUnknownName(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"target": "tz",
+	}))
+	require.NoError(t, err)
+	p.(*addTimezone).localZone = func() (string, *time.Location) {
+		return "", time.FixedZone("", -(9*3600 + 30*60))
+	}
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{
+		"event": mapstr.M{"timezone": "-09:30"},
+		"tz":    mapstr.M{"name": "", "offset": "-09:30", "dst": false},
+	}, event.Fields, "the offset should be used if the name is unknown")
+}
+
+func TestConfig(t *testing.T) {
+	_, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"refresh_interval": 0,
+	}))
+	assert.Error(t, err)
+
+	_, err = New(conf.MustNewConfigFrom(map[string]interface{}{
+		"target": "",
+	}))
+	assert.Error(t, err)
+}
+
+func TestLocalZoneName(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("time zone links are not used on Windows")
+	}
+	dir := t.TempDir()
+	zoneinfo := filepath.Join(dir, "zoneinfo", "Europe")
+	require.NoError(t, os.MkdirAll(zoneinfo, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(zoneinfo, "Berlin"), nil, 0o644))
+	link := filepath.Join(dir, "localtime")
+	require.NoError(t, os.Symlink(filepath.Join(zoneinfo, "Berlin"), link))
+
+	assert.Equal(t, "Europe/Berlin", zoneNameFromPath(link))
+	assert.Empty(t, zoneNameFromPath(filepath.Join(dir, "missing")))
+
+	t.Setenv("TZ", "Asia/Tokyo")
+	assert.Equal(t, "Asia/Tokyo", localZoneName())
+	t.Setenv("TZ", ":"+link)
+	assert.Equal(t, "Europe/Berlin", localZoneName())
+	t.Setenv("TZ", "")
+	assert.Equal(t, "UTC", localZoneName())
+}
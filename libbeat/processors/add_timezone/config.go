@@ -0,0 +1,46 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_timezone
+
+import (
+	"errors"
+	"time"
+)
+
+type config struct {
+	// Target is the field the time zone name, UTC offset and DST state are
+	// written to.
+	Target string `config:"target" validate:"required"`
+	// RefreshInterval is the time the time zone is cached for before it is
+	// looked up again.
+	RefreshInterval time.Duration `config:"refresh_interval"`
+}
+
+func defaultConfig() config {
+	return config{
+		Target:          "host.timezone",
+		RefreshInterval: time.Minute,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.RefreshInterval <= 0 {
+		return errors.New("refresh_interval must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_timezone
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	localtimePath = "/etc/localtime"
+	timezonePath  = "/etc/timezone"
+)
+
+// localZone returns the IANA name and the location of the host's time zone.
+// The name is empty if it can not be determined, in which case the location
+// is the one of the process.
+func localZone() (string, *time.Location) {
+	name := localZoneName()
+	if name == "" {
+		return "", time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", time.Local
+	}
+	return name, loc
+}
+
+// localZoneName determines the IANA name of the host's time zone from the TZ
+// environment variable, the /etc/localtime link or the /etc/timezone file, in
+// this order. It returns an empty string if the name is unknown.
+func localZoneName() string {
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		tz = strings.TrimPrefix(tz, ":")
+		switch {
+		case tz == "":
+			return "UTC"
+		case filepath.IsAbs(tz):
+			return zoneNameFromPath(tz)
+		default:
+			return tz
+		}
+	}
+	if name := zoneNameFromPath(localtimePath); name != "" {
+		return name
+	}
+	if b, err := os.ReadFile(timezonePath); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	return ""
+}
+
+// zoneNameFromPath returns the IANA name of the time zone file path links
+// to, for example Europe/Berlin for /usr/share/zoneinfo/Europe/Berlin.
+func zoneNameFromPath(path string) string {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+	target = filepath.ToSlash(target)
+	const dir = "zoneinfo/"
+	i := strings.LastIndex(target, dir)
+	if i < 0 {
+		return ""
+	}
+	return target[i+len(dir):]
+}
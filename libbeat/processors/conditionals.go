@@ -73,9 +73,35 @@ func NewConditionRule(
 	if cond == nil {
 		return p, nil
 	}
+	if s, ok := p.(Splitter); ok {
+		return &whenSplitter{WhenProcessor{cond, p}, s}, nil
+	}
 	return &WhenProcessor{cond, p}, nil
 }
 
+// whenSplitter is a WhenProcessor executing a Splitter.
+type whenSplitter struct {
+	WhenProcessor
+	s Splitter
+}
+
+// RunSplit executes the Splitter if the condition is true.
+func (r *whenSplitter) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	if !(r.condition).Check(event) {
+		return []*beat.Event{event}, nil
+	}
+	return r.s.RunSplit(event)
+}
+
+// Flush returns the events held back by the Splitter if it is a Flusher.
+// Buffered events are not checked against the condition again.
+func (r *whenSplitter) Flush() []*beat.Event {
+	if f, ok := r.s.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
 // Run executes this WhenProcessor.
 func (r *WhenProcessor) Run(event *beat.Event) (*beat.Event, error) {
 	if !(r.condition).Check(event) {
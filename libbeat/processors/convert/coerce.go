@@ -0,0 +1,223 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+const coerceLogName = "processor.coerce"
+
+// coerceInstanceID is used to assign each coerce instance a unique monitoring
+// namespace.
+var coerceInstanceID atomic.Uint32
+
+func init() {
+	processors.RegisterPlugin("coerce",
+		checks.ConfigChecked(NewCoerce,
+			checks.RequireFields("fields"),
+			checks.AllowedFields("fields", "on_failure", "tags", "when")))
+}
+
+type coerceConfig struct {
+	// Fields maps field names to the type their values are coerced to. Nested
+	// objects are flattened, so both dotted keys and nested keys are accepted.
+	Fields    mapstr.M      `config:"fields" validate:"required"`
+	OnFailure failureAction `config:"on_failure"`
+	Tags      []string      `config:"tags"`
+}
+
+func defaultCoerceConfig() coerceConfig {
+	return coerceConfig{
+		OnFailure: failureTag,
+		Tags:      []string{"_coerce_failure"},
+	}
+}
+
+type failureAction uint8
+
+// Actions taken on values that can not be coerced.
+const (
+	// failureTag keeps the value and tags the event.
+	failureTag failureAction = iota
+	// failureDrop removes the value from the event.
+	failureDrop
+)
+
+var failureActionNames = map[failureAction]string{
+	failureTag:  "tag",
+	failureDrop: "drop",
+}
+
+func (a failureAction) String() string {
+	return failureActionNames[a]
+}
+
+func (a *failureAction) Unpack(s string) error {
+	s = strings.ToLower(s)
+	for action, name := range failureActionNames {
+		if s == name {
+			*a = action
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid on_failure action: %v", s)
+}
+
+type coercion struct {
+	field string
+	typ   dataType
+}
+
+type coerceProcessor struct {
+	fields    []coercion
+	onFailure failureAction
+	tags      []string
+
+	log      *logp.Logger
+	coerced  *monitoring.Int
+	failures *monitoring.Int
+}
+
+// NewCoerce constructs a new coerce processor.
+func NewCoerce(cfg *conf.C) (beat.Processor, error) {
+	c := defaultCoerceConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, fmt.Errorf("fail to unpack the coerce processor configuration: %w", err)
+	}
+
+	fields, err := parseCoercions(c.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid coerce processor configuration: %w", err)
+	}
+
+	var (
+		id  = int(coerceInstanceID.Add(1))
+		log = logp.NewLogger(coerceLogName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(coerceLogName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &coerceProcessor{
+		fields:    fields,
+		onFailure: c.OnFailure,
+		tags:      c.Tags,
+		log:       log,
+		coerced:   monitoring.NewInt(reg, "coerced"),
+		failures:  monitoring.NewInt(reg, "failures"),
+	}, nil
+}
+
+// parseCoercions returns the coercions of the fields setting, sorted by
+// field name.
+func parseCoercions(fields mapstr.M) ([]coercion, error) {
+	flat := fields.Flatten()
+	coercions := make([]coercion, 0, len(flat))
+	for field, v := range flat {
+		name, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("type of field [%v] must be a string", field)
+		}
+		var typ dataType
+		if err := typ.Unpack(name); err != nil {
+			return nil, fmt.Errorf("field [%v]: %w", field, err)
+		}
+		if typ == unset {
+			return nil, fmt.Errorf("field [%v]: invalid data type: %v", field, name)
+		}
+		coercions = append(coercions, coercion{field: field, typ: typ})
+	}
+	if len(coercions) == 0 {
+		return nil, errors.New("no fields to coerce")
+	}
+	sort.Slice(coercions, func(i, j int) bool {
+		return coercions[i].field < coercions[j].field
+	})
+	return coercions, nil
+}
+
+// Run converts the values of the configured fields to their declared type.
+// Missing fields are ignored. Values that can not be coerced are kept and the
+// event is tagged, or they are removed, depending on on_failure.
+func (p *coerceProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	failed := false
+	for _, c := range p.fields {
+		v, err := event.GetValue(c.field)
+		if err != nil {
+			continue
+		}
+
+		coerced, err := coerceValue(c.typ, v)
+		if err != nil {
+			p.failures.Inc()
+			failed = true
+			p.log.Debugf("Failed to coerce field [%v] to %v: %v", c.field, c.typ, err)
+			if p.onFailure == failureDrop {
+				_ = event.Delete(c.field)
+			}
+			continue
+		}
+
+		p.coerced.Inc()
+		if _, err := event.PutValue(c.field, coerced); err != nil {
+			return event, fmt.Errorf("failed to put field [%v]: %w", c.field, err)
+		}
+	}
+
+	if failed && p.onFailure == failureTag && len(p.tags) > 0 {
+		if event.Fields == nil {
+			event.Fields = mapstr.M{}
+		}
+		if err := mapstr.AddTags(event.Fields, p.tags); err != nil {
+			return event, fmt.Errorf("failed to add tags: %w", err)
+		}
+	}
+	return event, nil
+}
+
+// coerceValue converts a scalar value to typ. Objects and arrays can not be
+// coerced.
+func coerceValue(typ dataType, value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil:
+		return nil, errors.New("value is null")
+	case map[string]interface{}, mapstr.M, []interface{}:
+		return nil, fmt.Errorf("value of type %T is not a scalar", value)
+	}
+	return transformType(typ, value)
+}
+
+func (p *coerceProcessor) String() string {
+	fields := make([]string, 0, len(p.fields))
+	for _, c := range p.fields {
+		fields = append(fields, c.field+":"+c.typ.String())
+	}
+	return fmt.Sprintf("coerce=[fields=%v, on_failure=%v]", strings.Join(fields, ","), p.onFailure)
+}
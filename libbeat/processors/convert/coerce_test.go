@@ -0,0 +1,128 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func newTestCoerce(t *testing.T, cfg mapstr.M) *coerceProcessor {
+	t.Helper()
+	p, err := NewCoerce(conf.MustNewConfigFrom(cfg))
+	require.NoError(t, err)
+	return p.(*coerceProcessor)
+}
+
+func TestCoerce(t *testing.T) {
+	fields := mapstr.M{
+		"http.response.status_code": "long",
+		"http": mapstr.M{
+			"response": mapstr.M{"bytes": "long"},
+		},
+		"user.id":  "string",
+		"duration": "double",
+		"success":  "boolean",
+	}
+
+	t.Run("coerces values", func(t *testing.T) {
+		p := newTestCoerce(t, mapstr.M{"fields": fields})
+		evt, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"http": mapstr.M{
+				"response": mapstr.M{"status_code": "200", "bytes": 1024.0},
+			},
+			"user":     mapstr.M{"id": 42},
+			"duration": "1.5",
+			"success":  "true",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, mapstr.M{
+			"http": mapstr.M{
+				"response": mapstr.M{"status_code": int64(200), "bytes": int64(1024)},
+			},
+			"user":     mapstr.M{"id": "42"},
+			"duration": 1.5,
+			"success":  true,
+		}, evt.Fields)
+		assert.Equal(t, int64(5), p.coerced.Get())
+		assert.Zero(t, p.failures.Get())
+	})
+
+	t.Run("missing fields are ignored", func(t *testing.T) {
+		p := newTestCoerce(t, mapstr.M{"fields": fields})
+		evt, err := p.Run(&beat.Event{Fields: mapstr.M{"message": "hello"}})
+		require.NoError(t, err)
+		assert.Equal(t, mapstr.M{"message": "hello"}, evt.Fields)
+	})
+
+	t.Run("tag on failure", func(t *testing.T) {
+		p := newTestCoerce(t, mapstr.M{"fields": fields})
+		evt, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"http":     mapstr.M{"response": mapstr.M{"status_code": "OK"}},
+			"user":     mapstr.M{"id": mapstr.M{"name": "alice"}},
+			"duration": "1.5",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, mapstr.M{
+			"http":     mapstr.M{"response": mapstr.M{"status_code": "OK"}},
+			"user":     mapstr.M{"id": mapstr.M{"name": "alice"}},
+			"duration": 1.5,
+			"tags":     []string{"_coerce_failure"},
+		}, evt.Fields)
+		assert.Equal(t, int64(2), p.failures.Get())
+		assert.Equal(t, int64(1), p.coerced.Get())
+	})
+
+	t.Run("drop on failure", func(t *testing.T) {
+		p := newTestCoerce(t, mapstr.M{"fields": fields, "on_failure": "drop"})
+		evt, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"http":    mapstr.M{"response": mapstr.M{"status_code": "OK"}},
+			"success": "maybe",
+			"user":    mapstr.M{"id": "alice"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, mapstr.M{
+			"http": mapstr.M{"response": mapstr.M{}},
+			"user": mapstr.M{"id": "alice"},
+		}, evt.Fields)
+		assert.Equal(t, int64(2), p.failures.Get())
+	})
+}
+
+func TestCoerceConfig(t *testing.T) {
+	for name, cfg := range map[string]mapstr.M{
+		"missing fields":  {},
+		"invalid type":    {"fields": mapstr.M{"a": "date"}},
+		"non-string type": {"fields": mapstr.M{"a": 1}},
+		"invalid action":  {"fields": mapstr.M{"a": "long"}, "on_failure": "ignore"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewCoerce(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+
+	p := newTestCoerce(t, mapstr.M{"fields": mapstr.M{"b": "long", "a.c": "boolean"}})
+	assert.Equal(t, "coerce=[fields=a.c:boolean,b:long, on_failure=tag]", p.String())
+}
@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package counter_rate
+
+import (
+	"errors"
+	"time"
+)
+
+type config struct {
+	// Fields lists the monotonic counter fields to compute rates for.
+	Fields []string `config:"fields" validate:"required"`
+	// Keys lists the fields identifying the entity a counter belongs to.
+	Keys []string `config:"keys"`
+	// TargetSuffix is appended to the counter field name to build the
+	// name of the rate field.
+	TargetSuffix string `config:"target_suffix"`
+	// Per is the time unit the rate is computed for.
+	Per time.Duration `config:"per"`
+	// MaxKeys limits the number of tracked entities. The least recently
+	// used entities are evicted first.
+	MaxKeys int `config:"max_keys" validate:"min=1"`
+}
+
+func defaultConfig() config {
+	return config{
+		TargetSuffix: "_rate",
+		Per:          time.Second,
+		MaxKeys:      10000,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.TargetSuffix == "" {
+		return errors.New("target_suffix must not be empty")
+	}
+	if c.Per <= 0 {
+		return errors.New("per must be a positive duration")
+	}
+	return nil
+}
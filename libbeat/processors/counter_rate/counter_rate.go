@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package counter_rate
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "counter_rate"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("fields"),
+			checks.AllowedFields("fields", "keys", "target_suffix", "per", "max_keys")))
+}
+
+// sample is the last seen value of the counters of an entity.
+type sample struct {
+	timestamp time.Time
+	values    map[string]float64
+}
+
+type counterRate struct {
+	config config
+
+	mutex sync.Mutex
+	state *lru.Cache[uint64, sample]
+
+	log     *logp.Logger
+	resets  *monitoring.Int
+	evicted *monitoring.Int
+}
+
+// New constructs a new counter_rate processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	p := &counterRate{
+		config:  config,
+		log:     log,
+		resets:  monitoring.NewInt(reg, "resets"),
+		evicted: monitoring.NewInt(reg, "evicted"),
+	}
+
+	state, err := lru.NewWithEvict(config.MaxKeys, func(uint64, sample) {
+		p.evicted.Inc()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v processor state: %w", processorName, err)
+	}
+	p.state = state
+
+	return p, nil
+}
+
+// Run computes the rate of each configured counter since the last event of
+// the same entity. No rate is added for the first event of an entity. If a
+// counter decreased, it was reset and its rate is 0.
+func (p *counterRate) Run(event *beat.Event) (*beat.Event, error) {
+	key, err := util.FieldsHash(event, p.config.Keys)
+	if err != nil {
+		return event, fmt.Errorf("could not make key: %w", err)
+	}
+
+	current := sample{
+		timestamp: event.Timestamp,
+		values:    make(map[string]float64, len(p.config.Fields)),
+	}
+	for _, field := range p.config.Fields {
+		value, err := event.GetValue(field)
+		if err != nil {
+			continue
+		}
+		if v, ok := util.ToFloat(value); ok {
+			current.values[field] = v
+		}
+	}
+	if len(current.values) == 0 {
+		return event, nil
+	}
+
+	p.mutex.Lock()
+	previous, found := p.state.Get(key)
+	p.state.Add(key, current)
+	p.mutex.Unlock()
+
+	if !found {
+		return event, nil
+	}
+
+	elapsed := current.timestamp.Sub(previous.timestamp)
+	if elapsed <= 0 {
+		return event, nil
+	}
+
+	for field, value := range current.values {
+		prev, ok := previous.values[field]
+		if !ok {
+			continue
+		}
+		rate := 0.0
+		if value < prev {
+			p.resets.Inc()
+			p.log.Debugf("counter %s was reset for key %d, reporting a zero rate", field, key)
+		} else {
+			rate = (value - prev) / elapsed.Seconds() * p.config.Per.Seconds()
+		}
+		if _, err := event.PutValue(field+p.config.TargetSuffix, rate); err != nil {
+			return event, fmt.Errorf("failed to put rate of %s: %w", field, err)
+		}
+	}
+
+	return event, nil
+}
+
+func (p *counterRate) String() string {
+	return fmt.Sprintf("%v=[fields=%v, keys=%v, per=%v]",
+		processorName, p.config.Fields, p.config.Keys, p.config.Per)
+}
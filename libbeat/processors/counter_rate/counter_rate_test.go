@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package counter_rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestCounterRate(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"fields": []string{"network.in.bytes", "network.out.bytes"},
+		"keys":   []string{"host.name"},
+	}))
+	require.NoError(t, err)
+
+	start := time.Now()
+	run := func(offset time.Duration, host string, in, out interface{}) mapstr.M {
+		t.Helper()
+		event, err := p.Run(&beat.Event{
+			Timestamp: start.Add(offset),
+			Fields: mapstr.M{
+				"host":    mapstr.M{"name": host},
+				"network": mapstr.M{"in": mapstr.M{"bytes": in}, "out": mapstr.M{"bytes": out}},
+			},
+		})
+		require.NoError(t, err)
+		return event.Fields
+	}
+
+	// first sample of each host has no rate
+	fields := run(0, "a", 100, 1000)
+	assert.False(t, hasKey(fields, "network.in.bytes_rate"))
+	run(0, "b", 0, 0)
+
+	fields = run(10*time.Second, "a", 200, 1500)
+	assertRate(t, fields, "network.in.bytes_rate", 10)
+	assertRate(t, fields, "network.out.bytes_rate", 50)
+
+	// other hosts are tracked independently
+	fields = run(5*time.Second, "b", uint64(50), 10.0)
+	assertRate(t, fields, "network.in.bytes_rate", 10)
+	assertRate(t, fields, "network.out.bytes_rate", 2)
+
+	// counter reset produces a zero rate
+	fields = run(20*time.Second, "a", 10, 2500)
+	assertRate(t, fields, "network.in.bytes_rate", 0)
+	assertRate(t, fields, "network.out.bytes_rate", 100)
+	assert.EqualValues(t, 1, p.(*counterRate).resets.Get())
+
+	// after a reset the rate is computed from the new value
+	fields = run(30*time.Second, "a", 110, 2500)
+	assertRate(t, fields, "network.in.bytes_rate", 10)
+}
+
+func TestCounterRatePerAndEviction(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"fields":        []string{"count"},
+		"keys":          []string{"id"},
+		"per":           "1m",
+		"target_suffix": "_per_minute",
+		"max_keys":      1,
+	}))
+	require.NoError(t, err)
+
+	start := time.Now()
+	run := func(offset time.Duration, id string, count int) *beat.Event {
+		event, err := p.Run(&beat.Event{
+			Timestamp: start.Add(offset),
+			Fields:    mapstr.M{"id": id, "count": count},
+		})
+		require.NoError(t, err)
+		return event
+	}
+
+	run(0, "a", 0)
+	event := run(30*time.Second, "a", 10)
+	rate, err := event.GetValue("count_per_minute")
+	require.NoError(t, err)
+	assert.InDelta(t, 20.0, rate, 0.0001)
+
+	// "b" evicts "a"
+	run(30*time.Second, "b", 0)
+	event = run(time.Minute, "a", 20)
+	assert.False(t, hasKey(event.Fields, "count_per_minute"))
+	assert.EqualValues(t, 2, p.(*counterRate).evicted.Get())
+}
+
+func hasKey(fields mapstr.M, key string) bool {
+	ok, _ := fields.HasKey(key)
+	return ok
+}
+
+func assertRate(t *testing.T, fields mapstr.M, key string, expected float64) {
+	t.Helper()
+	rate, err := fields.GetValue(key)
+	require.NoError(t, err)
+	assert.InDelta(t, expected, rate, 0.0001)
+}
@@ -0,0 +1,73 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+type config struct {
+	Field         string   `config:"field"`          // Source field holding the CSV line.
+	Target        string   `config:"target"`         // Field the columns are written to. Defaults to the event root.
+	Columns       []string `config:"columns"`        // Names of the columns.
+	Header        bool     `config:"header"`         // Read the column names from the first line.
+	Separator     string   `config:"separator"`      // Character separating the columns.
+	Quote         string   `config:"quote"`          // Character quoting columns.
+	OverwriteKeys bool     `config:"overwrite_keys"` // Overwrite fields already present in the event.
+	IgnoreMissing bool     `config:"ignore_missing"` // Ignore errors when the source field is missing.
+	IgnoreFailure bool     `config:"ignore_failure"` // Ignore errors when the source field can not be parsed.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when the source field can not be parsed.
+}
+
+func defaultConfig() config {
+	return config{
+		Field:        "message",
+		Separator:    ",",
+		Quote:        `"`,
+		TagOnFailure: []string{"_csv_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	if len(c.Columns) == 0 && !c.Header {
+		return errors.New("either columns or header must be set")
+	}
+	if len(c.Columns) > 0 && c.Header {
+		return errors.New("columns and header can not be used together")
+	}
+	for _, column := range c.Columns {
+		if column == "" {
+			return errors.New("column names must not be empty")
+		}
+	}
+	if utf8.RuneCountInString(c.Separator) != 1 {
+		return fmt.Errorf("separator must be a single character, got '%s'", c.Separator)
+	}
+	if utf8.RuneCountInString(c.Quote) != 1 {
+		return fmt.Errorf("quote must be a single character, got '%s'", c.Quote)
+	}
+	if c.Separator == c.Quote {
+		return fmt.Errorf("separator and quote must be different, both are '%s'", c.Separator)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "csv"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("field", "target", "columns", "header", "separator", "quote",
+				"overwrite_keys", "ignore_missing", "ignore_failure", "tag_on_failure", "when")))
+}
+
+type csvProcessor struct {
+	config
+	separator, quote rune
+	log              *logp.Logger
+
+	// In header mode, the column names are read from the first line.
+	mutex   sync.Mutex
+	columns []string
+}
+
+// New constructs a new csv processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	separator, _ := utf8.DecodeRuneInString(config.Separator)
+	quote, _ := utf8.DecodeRuneInString(config.Quote)
+	return &csvProcessor{
+		config:    config,
+		separator: separator,
+		quote:     quote,
+		log:       logp.NewLogger(logName),
+		columns:   config.Columns,
+	}, nil
+}
+
+// Run parses the source field and writes each column to the field named
+// after it. Lines not matching the number of columns are tagged and left
+// unchanged. In header mode, the first line only sets the column names and
+// the event is dropped.
+func (p *csvProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if p.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		p.tag(event)
+		return p.failure(event, fmt.Errorf("field %s is not of string type", p.Field))
+	}
+
+	values, err := parseLine(s, p.separator, p.quote)
+	if err != nil {
+		p.tag(event)
+		return p.failure(event, fmt.Errorf("failed to parse CSV from field %s: %w", p.Field, err))
+	}
+
+	columns, isHeader := p.columnNames(values)
+	if isHeader {
+		return nil, nil
+	}
+	if len(values) != len(columns) {
+		p.tag(event)
+		return p.failure(event, fmt.Errorf("line in field %s has %d columns, expected %d", p.Field, len(values), len(columns)))
+	}
+
+	for i, column := range columns {
+		field := column
+		if p.Target != "" {
+			field = p.Target + "." + column
+		}
+		if !p.OverwriteKeys {
+			if exists, _ := event.Fields.HasKey(field); exists {
+				continue
+			}
+		}
+		if _, err := event.PutValue(field, values[i]); err != nil {
+			p.tag(event)
+			return p.failure(event, fmt.Errorf("failed to set field %s: %w", field, err))
+		}
+	}
+	return event, nil
+}
+
+// columnNames returns the names of the columns. In header mode, the first
+// line parsed sets the names, isHeader is true then.
+func (p *csvProcessor) columnNames(values []string) (columns []string, isHeader bool) {
+	if !p.Header {
+		return p.columns, false
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.columns == nil {
+		p.columns = values
+		return values, true
+	}
+	return p.columns, false
+}
+
+// tag adds the failure tags to the event.
+func (p *csvProcessor) tag(event *beat.Event) {
+	if len(p.TagOnFailure) == 0 {
+		return
+	}
+	if err := mapstr.AddTags(event.Fields, p.TagOnFailure); err != nil {
+		p.log.Debugw("Failed to add failure tags.", "error", err)
+	}
+}
+
+// failure returns err, unless failures are ignored.
+func (p *csvProcessor) failure(event *beat.Event, err error) (*beat.Event, error) {
+	if p.IgnoreFailure {
+		return event, nil
+	}
+	return event, err
+}
+
+func (p *csvProcessor) String() string {
+	columns := "<header>"
+	if !p.Header {
+		columns = strings.Join(p.Columns, ",")
+	}
+	return fmt.Sprintf("%v=[field=%v, target=%v, columns=%v, separator=%v, quote=%v]",
+		processorName, p.Field, p.Target, columns, p.Separator, p.Quote)
+}
@@ -0,0 +1,188 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package csv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := map[string]struct {
+		line      string
+		separator rune
+		quote     rune
+		expected  []string
+		err       error
+	}{
+		"simple":                 {line: "a,b,c", expected: []string{"a", "b", "c"}},
+		"empty columns":          {line: ",b,", expected: []string{"", "b", ""}},
+		"empty line":             {line: "", expected: []string{""}},
+		"trailing line break":    {line: "a,b\r\n", expected: []string{"a", "b"}},
+		"quoted separator":       {line: `a,"b,c",d`, expected: []string{"a", "b,c", "d"}},
+		"escaped quote":          {line: `"say ""hi""",b`, expected: []string{`say "hi"`, "b"}},
+		"quoted line break":      {line: "\"a\nb\",c", expected: []string{"a\nb", "c"}},
+		"empty quoted column":    {line: `"",b`, expected: []string{"", "b"}},
+		"quoted last column":     {line: `a,"b"`, expected: []string{"a", "b"}},
+		"custom separator":       {line: "a;b c;d", separator: ';', expected: []string{"a", "b c", "d"}},
+		"custom quote":           {line: "'a,b',c", quote: '\'', expected: []string{"a,b", "c"}},
+		"multibyte separator":    {line: "a→b", separator: '→', expected: []string{"a", "b"}},
+		"unterminated quote":     {line: `a,"b`, err: errUnterminatedQuote},
+		"bare quote":             {line: `a,b"c`, err: errBareQuote},
+		"text after quoted":      {line: `"a"b,c`, err: errExtraneousQuote},
+		"other quote is literal": {line: `a,b"c`, quote: '\'', expected: []string{"a", `b"c`}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			separator, quote := test.separator, test.quote
+			if separator == 0 {
+				separator = ','
+			}
+			if quote == 0 {
+				quote = '"'
+			}
+			columns, err := parseLine(test.line, separator, quote)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, columns)
+		})
+	}
+}
+
+func TestCSV(t *testing.T) {
+	tests := map[string]struct {
+		config   mapstr.M
+		input    mapstr.M
+		expected mapstr.M
+		err      bool
+	}{
+		"columns": {
+			config: mapstr.M{"columns": []string{"user.name", "event.action", "text"}},
+			input:  mapstr.M{"message": `alice,login,"hello, world"`},
+			expected: mapstr.M{
+				"message": `alice,login,"hello, world"`,
+				"user":    mapstr.M{"name": "alice"},
+				"event":   mapstr.M{"action": "login"},
+				"text":    "hello, world",
+			},
+		},
+		"target": {
+			config:   mapstr.M{"columns": []string{"a", "b"}, "target": "csv"},
+			input:    mapstr.M{"message": "1,2"},
+			expected: mapstr.M{"message": "1,2", "csv": mapstr.M{"a": "1", "b": "2"}},
+		},
+		"existing fields are kept": {
+			config:   mapstr.M{"columns": []string{"a", "b"}},
+			input:    mapstr.M{"message": "1,2", "a": "x"},
+			expected: mapstr.M{"message": "1,2", "a": "x", "b": "2"},
+		},
+		"overwrite keys": {
+			config:   mapstr.M{"columns": []string{"a", "b"}, "overwrite_keys": true},
+			input:    mapstr.M{"message": "1,2", "a": "x"},
+			expected: mapstr.M{"message": "1,2", "a": "1", "b": "2"},
+		},
+		"wrong column count": {
+			config:   mapstr.M{"columns": []string{"a", "b"}},
+			input:    mapstr.M{"message": "1,2,3"},
+			expected: mapstr.M{"message": "1,2,3", "tags": []string{"_csv_failure"}},
+			err:      true,
+		},
+		"wrong column count ignored": {
+			config:   mapstr.M{"columns": []string{"a", "b"}, "ignore_failure": true, "tag_on_failure": []string{"bad_csv"}},
+			input:    mapstr.M{"message": "1"},
+			expected: mapstr.M{"message": "1", "tags": []string{"bad_csv"}},
+		},
+		"invalid line": {
+			config:   mapstr.M{"columns": []string{"a", "b"}},
+			input:    mapstr.M{"message": `1,"2`},
+			expected: mapstr.M{"message": `1,"2`, "tags": []string{"_csv_failure"}},
+			err:      true,
+		},
+		"missing field": {
+			config:   mapstr.M{"columns": []string{"a"}, "field": "line"},
+			input:    mapstr.M{"message": "1"},
+			expected: mapstr.M{"message": "1"},
+			err:      true,
+		},
+		"ignore missing": {
+			config:   mapstr.M{"columns": []string{"a"}, "field": "line", "ignore_missing": true},
+			input:    mapstr.M{"message": "1"},
+			expected: mapstr.M{"message": "1"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(conf.MustNewConfigFrom(test.config))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: test.input})
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			require.NotNil(t, event)
+			assert.Equal(t, test.expected, event.Fields)
+		})
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"header": true, "separator": ";"}))
+	require.NoError(t, err)
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"message": "host;status"}})
+	require.NoError(t, err)
+	assert.Nil(t, event, "the header line should be dropped")
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"message": "a;200"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"message": "a;200", "host": "a", "status": "200"}, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"message": "b"}})
+	assert.Error(t, err)
+	assert.Equal(t, mapstr.M{"message": "b", "tags": []string{"_csv_failure"}}, event.Fields)
+}
+
+func TestCSVConfig(t *testing.T) {
+	tests := map[string]mapstr.M{
+		"no columns":           {},
+		"columns and header":   {"columns": []string{"a"}, "header": true},
+		"empty column":         {"columns": []string{"a", ""}},
+		"long separator":       {"columns": []string{"a"}, "separator": "::"},
+		"empty quote":          {"columns": []string{"a"}, "quote": ""},
+		"same separator/quote": {"columns": []string{"a"}, "separator": "'", "quote": "'"},
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(config))
+			assert.Error(t, err)
+		})
+	}
+}
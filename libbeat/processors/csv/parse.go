@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package csv
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errUnterminatedQuote = errors.New("quoted column is not terminated")
+	errBareQuote         = errors.New("quote in unquoted column")
+	errExtraneousQuote   = errors.New("unexpected character after quoted column")
+)
+
+// parseLine splits a CSV line into its columns as specified by RFC 4180,
+// with a configurable separator and quote character. Quoted columns can hold
+// separators and line breaks, a quote within a quoted column is escaped by
+// doubling it. A trailing line break is ignored.
+func parseLine(line string, separator, quote rune) ([]string, error) {
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	var columns []string
+	var column strings.Builder
+	for {
+		column.Reset()
+		if r, size := utf8.DecodeRuneInString(line); size > 0 && r == quote {
+			// Quoted column, read up to the closing quote.
+			line = line[size:]
+			for {
+				i := strings.IndexRune(line, quote)
+				if i < 0 {
+					return nil, errUnterminatedQuote
+				}
+				column.WriteString(line[:i])
+				line = line[i+utf8.RuneLen(quote):]
+				if r, size := utf8.DecodeRuneInString(line); size > 0 && r == quote {
+					// Escaped quote
+					column.WriteRune(quote)
+					line = line[size:]
+					continue
+				}
+				break
+			}
+			columns = append(columns, column.String())
+			if line == "" {
+				return columns, nil
+			}
+			r, size := utf8.DecodeRuneInString(line)
+			if r != separator {
+				return nil, errExtraneousQuote
+			}
+			line = line[size:]
+			continue
+		}
+
+		// Unquoted column, read up to the next separator.
+		end := strings.IndexRune(line, separator)
+		value := line
+		if end >= 0 {
+			value = line[:end]
+		}
+		if strings.ContainsRune(value, quote) {
+			return nil, errBareQuote
+		}
+		columns = append(columns, value)
+		if end < 0 {
+			return columns, nil
+		}
+		line = line[end+utf8.RuneLen(separator):]
+	}
+}
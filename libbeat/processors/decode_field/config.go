@@ -0,0 +1,54 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_field
+
+import "fmt"
+
+const (
+	encodingURL       = "url"
+	encodingBase64    = "base64"
+	encodingBase64URL = "base64url"
+)
+
+type config struct {
+	Field         string   `config:"field"`          // Source field containing the encoded value.
+	TargetField   string   `config:"target_field"`   // Field the decoded value is written to. Defaults to field.
+	Encoding      string   `config:"encoding"`       // Encoding of the value, url, base64 or base64url.
+	DecodeJSON    bool     `config:"decode_json"`    // Parse the decoded value as JSON.
+	IgnoreMissing bool     `config:"ignore_missing"` // Ignore errors when the source field is missing.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when the value can not be decoded.
+}
+
+func defaultConfig() config {
+	return config{
+		TagOnFailure: []string{"_decode_field_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	switch c.Encoding {
+	case encodingURL, encodingBase64, encodingBase64URL:
+	default:
+		return fmt.Errorf("invalid encoding %q, must be one of %q, %q or %q",
+			c.Encoding, encodingURL, encodingBase64, encodingBase64URL)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_field
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "decode_field"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("field", "encoding"),
+			checks.AllowedFields("field", "target_field", "encoding", "decode_json",
+				"ignore_missing", "tag_on_failure", "when")))
+}
+
+type decodeField struct {
+	config
+
+	log *logp.Logger
+}
+
+// New constructs a new decode_field processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	if config.TargetField == "" {
+		config.TargetField = config.Field
+	}
+
+	return &decodeField{
+		config: config,
+		log:    logp.NewLogger(logName),
+	}, nil
+}
+
+// Run decodes the value of the source field and writes it to the target
+// field. Values that can not be decoded are not modified and the event is
+// tagged instead.
+func (p *decodeField) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if p.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return p.failure(event, fmt.Errorf("field %s is not of string type", p.Field))
+	}
+
+	decoded, err := p.decode(s)
+	if err != nil {
+		return p.failure(event, fmt.Errorf("failed to decode field %s: %w", p.Field, err))
+	}
+
+	var value interface{} = decoded
+	if p.DecodeJSON {
+		if value, err = decodeJSON(decoded); err != nil {
+			return p.failure(event, fmt.Errorf("failed to decode JSON in field %s: %w", p.Field, err))
+		}
+	}
+
+	if _, err := event.PutValue(p.TargetField, value); err != nil {
+		return event, fmt.Errorf("failed to set field %s: %w", p.TargetField, err)
+	}
+	return event, nil
+}
+
+func (p *decodeField) decode(s string) (string, error) {
+	switch p.Encoding {
+	case encodingURL:
+		return url.QueryUnescape(s)
+	case encodingBase64:
+		b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+		return string(b), err
+	case encodingBase64URL:
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		return string(b), err
+	default:
+		// Not reached, the encoding is validated with the config.
+		return "", fmt.Errorf("unsupported encoding %q", p.Encoding)
+	}
+}
+
+// decodeJSON parses a single JSON value. Objects are returned as mapstr.M.
+func decodeJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("multiple json elements found")
+	}
+
+	if m, ok := v.(map[string]interface{}); ok {
+		jsontransform.TransformNumbers(m)
+		return mapstr.M(m), nil
+	}
+	return v, nil
+}
+
+// failure tags the event, leaving it unmodified otherwise.
+func (p *decodeField) failure(event *beat.Event, err error) (*beat.Event, error) {
+	p.log.Debugw("Failed to decode field.", "error", err)
+	if len(p.TagOnFailure) > 0 {
+		if tagErr := mapstr.AddTags(event.Fields, p.TagOnFailure); tagErr != nil {
+			p.log.Debugw("Failed to add failure tags.", "error", tagErr)
+		}
+	}
+	return event, nil
+}
+
+func (p *decodeField) String() string {
+	return fmt.Sprintf("%v=[field=%v, target_field=%v, encoding=%v, decode_json=%v]",
+		processorName, p.Field, p.TargetField, p.Encoding, p.DecodeJSON)
+}
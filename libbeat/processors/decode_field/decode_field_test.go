@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_field
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestDecodeField(t *testing.T) {
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"url in place": {
+			config: mapstr.M{"encoding": "url"},
+			fields: mapstr.M{"value": "a%20b%2Fc+d"},
+			want:   mapstr.M{"value": "a b/c d"},
+		},
+		"base64 to target": {
+			config: mapstr.M{"encoding": "base64", "target_field": "decoded"},
+			fields: mapstr.M{"value": "aGVsbG8gd29ybGQ="},
+			want:   mapstr.M{"value": "aGVsbG8gd29ybGQ=", "decoded": "hello world"},
+		},
+		"base64 without padding": {
+			config: mapstr.M{"encoding": "base64"},
+			fields: mapstr.M{"value": "aGVsbG8gd29ybGQ"},
+			want:   mapstr.M{"value": "hello world"},
+		},
+		"base64url": {
+			config: mapstr.M{"encoding": "base64url"},
+			fields: mapstr.M{"value": "Pz8_Pw"},
+			want:   mapstr.M{"value": "????"},
+		},
+		"json object": {
+			config: mapstr.M{"encoding": "base64", "decode_json": true},
+			fields: mapstr.M{"value": "eyJhIjogMSwgImIiOiAieCJ9"},
+			want:   mapstr.M{"value": mapstr.M{"a": int64(1), "b": "x"}},
+		},
+		"invalid encoding is tagged": {
+			config: mapstr.M{"encoding": "base64"},
+			fields: mapstr.M{"value": "not base64!"},
+			want:   mapstr.M{"value": "not base64!", "tags": []string{"_decode_field_failure"}},
+		},
+		"invalid url encoding is tagged": {
+			config: mapstr.M{"encoding": "url", "tag_on_failure": []string{"bad_url"}},
+			fields: mapstr.M{"value": "100%"},
+			want:   mapstr.M{"value": "100%", "tags": []string{"bad_url"}},
+		},
+		"invalid json is tagged": {
+			config: mapstr.M{"encoding": "url", "decode_json": true},
+			fields: mapstr.M{"value": "%7Bnot%20json"},
+			want:   mapstr.M{"value": "%7Bnot%20json", "tags": []string{"_decode_field_failure"}},
+		},
+		"not a string is tagged": {
+			config: mapstr.M{"encoding": "url"},
+			fields: mapstr.M{"value": 42},
+			want:   mapstr.M{"value": 42, "tags": []string{"_decode_field_failure"}},
+		},
+		"missing": {
+			config:  mapstr.M{"encoding": "url"},
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"missing ignored": {
+			config: mapstr.M{"encoding": "url", "ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := mapstr.M{"field": "value"}
+			c.DeepUpdate(tc.config)
+			p, err := New(conf.MustNewConfigFrom(c))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: tc.fields})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, event.Fields)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, c := range map[string]mapstr.M{
+		"missing field":    {"encoding": "url"},
+		"missing encoding": {"field": "value"},
+		"invalid encoding": {"field": "value", "encoding": "hex"},
+	} {
+		_, err := New(conf.MustNewConfigFrom(c))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,183 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_logfmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		in   string
+		want []pair
+		err  string
+	}{
+		"empty": {
+			in: "  ",
+		},
+		"bare values": {
+			in:   `level=info status=200 path=/index.html`,
+			want: []pair{{"level", "info"}, {"status", "200"}, {"path", "/index.html"}},
+		},
+		"quoted values": {
+			in:   `msg="hello \"world\"\tagain" empty="" unicode="äö ü"`,
+			want: []pair{{"msg", "hello \"world\"\tagain"}, {"empty", ""}, {"unicode", "äö ü"}},
+		},
+		"keys without values": {
+			in:   `debug  user= done`,
+			want: []pair{{"debug", true}, {"user", ""}, {"done", true}},
+		},
+		"unknown escapes are kept": {
+			in:   `path="C:\temp\x"`,
+			want: []pair{{"path", "C:\temp\\x"}},
+		},
+		"missing key": {
+			in:  `a=1 =2`,
+			err: "missing key at position 4",
+		},
+		"unterminated quote": {
+			in:  `msg="hello`,
+			err: "unterminated quoted value starting at position 4",
+		},
+		"quote in key": {
+			in:  `a"b=1`,
+			err: "unexpected quote in key",
+		},
+		"quote in bare value": {
+			in:  `a=b"c"`,
+			err: "unexpected quote in value",
+		},
+		"text after quoted value": {
+			in:  `a="b"c`,
+			err: "unexpected character after quoted value",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			pairs, err := parse(c.in)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.want, pairs)
+		})
+	}
+}
+
+func TestDecodeLogfmt(t *testing.T) {
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"root": {
+			fields: mapstr.M{"message": `level=info msg="user logged in" user.id=42`},
+			want: mapstr.M{
+				"message": `level=info msg="user logged in" user.id=42`,
+				"level":   "info",
+				"msg":     "user logged in",
+				"user":    mapstr.M{"id": "42"},
+			},
+		},
+		"target": {
+			config: mapstr.M{"target": "app", "field": "log"},
+			fields: mapstr.M{"log": `level=warn`},
+			want:   mapstr.M{"log": `level=warn`, "app": mapstr.M{"level": "warn"}},
+		},
+		"duplicate keys last wins": {
+			config: mapstr.M{"target": "kv"},
+			fields: mapstr.M{"message": `tag=a tag=b`},
+			want:   mapstr.M{"message": `tag=a tag=b`, "kv": mapstr.M{"tag": "b"}},
+		},
+		"duplicate keys array": {
+			config: mapstr.M{"target": "kv", "duplicate_keys": "array"},
+			fields: mapstr.M{"message": `tag=a tag=b tag=c`},
+			want:   mapstr.M{"message": `tag=a tag=b tag=c`, "kv": mapstr.M{"tag": []interface{}{"a", "b", "c"}}},
+		},
+		"existing fields kept": {
+			fields: mapstr.M{"message": `message=other level=info`},
+			want:   mapstr.M{"message": `message=other level=info`, "level": "info"},
+		},
+		"existing fields overwritten": {
+			config: mapstr.M{"overwrite_keys": true},
+			fields: mapstr.M{"message": `message=other`},
+			want:   mapstr.M{"message": "other"},
+		},
+		"parse failure": {
+			fields:  mapstr.M{"message": `level=info msg="broken`},
+			want:    mapstr.M{"message": `level=info msg="broken`, "tags": []string{"_logfmt_parse_failure"}},
+			wantErr: true,
+		},
+		"ignored parse failure": {
+			config: mapstr.M{"ignore_failure": true, "tag_on_failure": []string{"bad"}},
+			fields: mapstr.M{"message": `=x`},
+			want:   mapstr.M{"message": `=x`, "tags": []string{"bad"}},
+		},
+		"not a string": {
+			fields:  mapstr.M{"message": 42},
+			want:    mapstr.M{"message": 42, "tags": []string{"_logfmt_parse_failure"}},
+			wantErr: true,
+		},
+		"missing field": {
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"ignored missing field": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := c.config
+			if cfg == nil {
+				cfg = mapstr.M{}
+			}
+			p, err := New(conf.MustNewConfigFrom(cfg))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: c.fields})
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.want, event.Fields)
+		})
+	}
+}
+
+func TestDecodeLogfmtConfig(t *testing.T) {
+	_, err := New(conf.MustNewConfigFrom(mapstr.M{"duplicate_keys": "first"}))
+	assert.ErrorContains(t, err, "invalid duplicate_keys")
+	_, err = New(conf.MustNewConfigFrom(mapstr.M{"field": ""}))
+	assert.Error(t, err)
+}
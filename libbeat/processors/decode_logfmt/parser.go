@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_logfmt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// pair is a key-value pair of a logfmt message. Keys without a value are
+// reported with the value true.
+type pair struct {
+	key   string
+	value interface{}
+}
+
+// parse splits a logfmt message into its key-value pairs. Pairs are
+// separated by whitespace, values can be quoted using double quotes, with
+// backslash escapes.
+func parse(s string) ([]pair, error) {
+	var pairs []pair
+	i := 0
+	for {
+		i = skipSpace(s, i)
+		if i == len(s) {
+			return pairs, nil
+		}
+
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '"' {
+			i++
+		}
+		key := s[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("missing key at position %d", start)
+		}
+		if i < len(s) && s[i] == '"' {
+			return nil, fmt.Errorf("unexpected quote in key at position %d", i)
+		}
+		if i == len(s) || s[i] != '=' {
+			pairs = append(pairs, pair{key: key, value: true})
+			continue
+		}
+		i++ // skip '='
+
+		var value string
+		if i < len(s) && s[i] == '"' {
+			var err error
+			value, i, err = parseQuoted(s, i)
+			if err != nil {
+				return nil, err
+			}
+			if i < len(s) && !isSpace(s[i]) {
+				return nil, fmt.Errorf("unexpected character after quoted value at position %d", i)
+			}
+		} else {
+			start = i
+			for i < len(s) && !isSpace(s[i]) {
+				if s[i] == '"' {
+					return nil, fmt.Errorf("unexpected quote in value at position %d", i)
+				}
+				i++
+			}
+			value = s[start:i]
+		}
+		pairs = append(pairs, pair{key: key, value: value})
+	}
+}
+
+// parseQuoted parses the quoted value starting at s[i] and returns the
+// unescaped value and the position after the closing quote.
+func parseQuoted(s string, i int) (string, int, error) {
+	start := i
+	i++ // skip opening quote
+
+	var sb strings.Builder
+	for i < len(s) {
+		switch c := s[i]; c {
+		case '"':
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, errors.New("unterminated escape sequence")
+			}
+			switch e := s[i+1]; e {
+			case '"', '\\', '/':
+				sb.WriteByte(e)
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				// Unknown escape sequences are kept as is.
+				sb.WriteByte(c)
+				sb.WriteByte(e)
+			}
+			i += 2
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			sb.WriteString(s[i : i+size])
+			i += size
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted value starting at position %d", start)
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_query_string
+
+import "fmt"
+
+const (
+	duplicateKeysLast  = "last"
+	duplicateKeysArray = "array"
+)
+
+type config struct {
+	Field         string   `config:"field"`          // Source field containing the URL or query string.
+	Target        string   `config:"target"`         // Field the parameters are written to. Defaults to the event root.
+	DuplicateKeys string   `config:"duplicate_keys"` // How repeated parameters are handled, last or array.
+	OverwriteKeys bool     `config:"overwrite_keys"` // Overwrite fields already present in the event.
+	IgnoreMissing bool     `config:"ignore_missing"` // Ignore errors when the source field is missing.
+	IgnoreFailure bool     `config:"ignore_failure"` // Ignore errors when parsing the query string.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when the query string cannot be parsed.
+}
+
+func defaultConfig() config {
+	return config{
+		Field:         "url.original",
+		DuplicateKeys: duplicateKeysArray,
+		TagOnFailure:  []string{"_query_string_parse_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	switch c.DuplicateKeys {
+	case duplicateKeysLast, duplicateKeysArray:
+	default:
+		return fmt.Errorf("invalid duplicate_keys '%s', must be one of %s or %s", c.DuplicateKeys, duplicateKeysLast, duplicateKeysArray)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_query_string
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "decode_query_string"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("field", "target", "duplicate_keys", "overwrite_keys",
+				"ignore_missing", "ignore_failure", "tag_on_failure", "when")))
+}
+
+type decodeQueryString struct {
+	config
+	log *logp.Logger
+}
+
+// New constructs a new decode_query_string processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	return &decodeQueryString{
+		config: config,
+		log:    logp.NewLogger(logName),
+	}, nil
+}
+
+// Run parses the query string of the URL in the source field and adds its
+// parameters to the event. If the query string can not be parsed, no fields
+// are added and the event is tagged.
+func (p *decodeQueryString) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if p.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return p.failure(event, fmt.Errorf("field %s is not of string type", p.Field))
+	}
+
+	params, err := url.ParseQuery(queryString(s))
+	if err != nil {
+		return p.failure(event, fmt.Errorf("failed to parse query string in field %s: %w", p.Field, err))
+	}
+
+	for key, values := range params {
+		if key == "" {
+			continue
+		}
+		field := key
+		if p.Target != "" {
+			field = p.Target + "." + key
+		}
+		if !p.OverwriteKeys {
+			if exists, _ := event.Fields.HasKey(field); exists {
+				continue
+			}
+		}
+
+		var value interface{} = values[len(values)-1]
+		if p.DuplicateKeys == duplicateKeysArray && len(values) > 1 {
+			value = values
+		}
+		if _, err := event.PutValue(field, value); err != nil {
+			return p.failure(event, fmt.Errorf("failed to set field %s: %w", field, err))
+		}
+	}
+	return event, nil
+}
+
+// queryString returns the query string of s, which is either a URL or a bare
+// query string. The fragment is removed. URLs without a query string return
+// an empty string.
+func queryString(s string) string {
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		return s[i+1:]
+	}
+	if strings.HasPrefix(s, "/") || strings.Contains(s, "://") {
+		return ""
+	}
+	return s
+}
+
+// failure tags the event and returns err, unless failures are ignored.
+func (p *decodeQueryString) failure(event *beat.Event, err error) (*beat.Event, error) {
+	if len(p.TagOnFailure) > 0 {
+		if tagErr := mapstr.AddTags(event.Fields, p.TagOnFailure); tagErr != nil {
+			p.log.Debugw("Failed to add failure tags.", "error", tagErr)
+		}
+	}
+	if p.IgnoreFailure {
+		return event, nil
+	}
+	return event, err
+}
+
+func (p *decodeQueryString) String() string {
+	return fmt.Sprintf("%v=[field=%v, target=%v, duplicate_keys=%v]",
+		processorName, p.Field, p.Target, p.DuplicateKeys)
+}
@@ -0,0 +1,151 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decode_query_string
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestQueryString(t *testing.T) {
+	cases := map[string]string{
+		"a=1&b=2":                               "a=1&b=2",
+		"/search?q=beats&page=2":                "q=beats&page=2",
+		"https://example.com/p?q=1#section":     "q=1",
+		"https://example.com/p":                 "",
+		"/index.html":                           "",
+		"/index.html#?notaquery":                "",
+		"https://example.com/p?":                "",
+		"https://example.com/p?redirect=/x?y=1": "redirect=/x?y=1",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, queryString(in), in)
+	}
+}
+
+func TestDecodeQueryString(t *testing.T) {
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"url to target": {
+			config: mapstr.M{"target": "params"},
+			fields: mapstr.M{"url": mapstr.M{"original": "/search?q=hello%20world&lang=en+US"}},
+			want: mapstr.M{
+				"url":    mapstr.M{"original": "/search?q=hello%20world&lang=en+US"},
+				"params": mapstr.M{"q": "hello world", "lang": "en US"},
+			},
+		},
+		"query string to root": {
+			config: mapstr.M{"field": "query"},
+			fields: mapstr.M{"query": "user.id=42&empty=&flag"},
+			want: mapstr.M{
+				"query": "user.id=42&empty=&flag",
+				"user":  mapstr.M{"id": "42"},
+				"empty": "",
+				"flag":  "",
+			},
+		},
+		"repeated keys array": {
+			config: mapstr.M{"field": "query", "target": "params"},
+			fields: mapstr.M{"query": "tag=a&tag=b&tag=c"},
+			want:   mapstr.M{"query": "tag=a&tag=b&tag=c", "params": mapstr.M{"tag": []string{"a", "b", "c"}}},
+		},
+		"repeated keys last wins": {
+			config: mapstr.M{"field": "query", "target": "params", "duplicate_keys": "last"},
+			fields: mapstr.M{"query": "tag=a&tag=b"},
+			want:   mapstr.M{"query": "tag=a&tag=b", "params": mapstr.M{"tag": "b"}},
+		},
+		"url without query string": {
+			config: mapstr.M{"target": "params"},
+			fields: mapstr.M{"url": mapstr.M{"original": "https://example.com/index.html"}},
+			want:   mapstr.M{"url": mapstr.M{"original": "https://example.com/index.html"}},
+		},
+		"existing fields kept": {
+			config: mapstr.M{"field": "query"},
+			fields: mapstr.M{"query": "query=other&a=1"},
+			want:   mapstr.M{"query": "query=other&a=1", "a": "1"},
+		},
+		"existing fields overwritten": {
+			config: mapstr.M{"field": "query", "overwrite_keys": true},
+			fields: mapstr.M{"query": "query=other"},
+			want:   mapstr.M{"query": "other"},
+		},
+		"invalid escape": {
+			config:  mapstr.M{"field": "query", "target": "params"},
+			fields:  mapstr.M{"query": "a=1&b=%zz"},
+			want:    mapstr.M{"query": "a=1&b=%zz", "tags": []string{"_query_string_parse_failure"}},
+			wantErr: true,
+		},
+		"ignored parse failure": {
+			config: mapstr.M{"field": "query", "ignore_failure": true, "tag_on_failure": []string{"bad"}},
+			fields: mapstr.M{"query": "a=1;b=2"},
+			want:   mapstr.M{"query": "a=1;b=2", "tags": []string{"bad"}},
+		},
+		"not a string": {
+			config:  mapstr.M{"field": "query"},
+			fields:  mapstr.M{"query": 42},
+			want:    mapstr.M{"query": 42, "tags": []string{"_query_string_parse_failure"}},
+			wantErr: true,
+		},
+		"missing field": {
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"ignored missing field": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := c.config
+			if cfg == nil {
+				cfg = mapstr.M{}
+			}
+			p, err := New(conf.MustNewConfigFrom(cfg))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: c.fields})
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.want, event.Fields)
+		})
+	}
+}
+
+func TestDecodeQueryStringConfig(t *testing.T) {
+	_, err := New(conf.MustNewConfigFrom(mapstr.M{"duplicate_keys": "first"}))
+	assert.ErrorContains(t, err, "invalid duplicate_keys")
+	_, err = New(conf.MustNewConfigFrom(mapstr.M{"field": ""}))
+	assert.Error(t, err)
+}
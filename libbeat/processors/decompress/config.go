@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decompress
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
+)
+
+const (
+	codecGzip = "gzip"
+	codecZstd = "zstd"
+)
+
+type config struct {
+	Field          string           `config:"field"`            // Source field containing the compressed payload.
+	Target         string           `config:"target"`           // Field the decompressed payload is written to. Defaults to field.
+	Codec          string           `config:"codec"`            // Compression format of the payload, gzip or zstd.
+	Base64         bool             `config:"base64"`           // Base64 decode the payload before decompressing it.
+	MaxBytes       cfgtype.ByteSize `config:"max_bytes"`        // Maximum size of the decompressed payload.
+	DropOnOverflow bool             `config:"drop_on_overflow"` // Drop events exceeding max_bytes instead of tagging them.
+	IgnoreMissing  bool             `config:"ignore_missing"`   // Ignore errors when the source field is missing.
+	IgnoreFailure  bool             `config:"ignore_failure"`   // Ignore errors when decompressing the payload.
+	TagOnFailure   []string         `config:"tag_on_failure"`   // Tags to append when the payload cannot be decompressed.
+}
+
+func defaultConfig() config {
+	return config{
+		Field:        "message",
+		Codec:        codecGzip,
+		MaxBytes:     10 * 1024 * 1024,
+		TagOnFailure: []string{"_decompress_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	switch c.Codec {
+	case codecGzip, codecZstd:
+	default:
+		return fmt.Errorf("invalid codec '%s', must be one of %s or %s", c.Codec, codecGzip, codecZstd)
+	}
+	if c.MaxBytes <= 0 {
+		return fmt.Errorf("max_bytes must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decompress
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "decompress"
+const logName = "processor." + processorName
+
+// errTooLarge is returned when the decompressed payload exceeds max_bytes.
+var errTooLarge = errors.New("decompressed payload exceeds max_bytes")
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("field", "target", "codec", "base64", "max_bytes", "drop_on_overflow",
+				"ignore_missing", "ignore_failure", "tag_on_failure", "when")))
+}
+
+type decompress struct {
+	config
+	log *logp.Logger
+}
+
+// New constructs a new decompress processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	if config.Target == "" {
+		config.Target = config.Field
+	}
+
+	return &decompress{
+		config: config,
+		log:    logp.NewLogger(logName),
+	}, nil
+}
+
+// Run decompresses the payload in the source field and writes it to the
+// target field. Events whose decompressed payload exceeds max_bytes are
+// tagged, or dropped if drop_on_overflow is set.
+func (p *decompress) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if p.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+	}
+
+	var payload []byte
+	switch v := v.(type) {
+	case string:
+		payload = []byte(v)
+	case []byte:
+		payload = v
+	default:
+		return p.failure(event, fmt.Errorf("field %s is not of string type", p.Field))
+	}
+
+	if p.Base64 {
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(payload)))
+		n, err := base64.StdEncoding.Decode(decoded, payload)
+		if err != nil {
+			return p.failure(event, fmt.Errorf("failed to base64 decode field %s: %w", p.Field, err))
+		}
+		payload = decoded[:n]
+	}
+
+	data, err := p.decompress(payload)
+	if err != nil {
+		if errors.Is(err, errTooLarge) && p.DropOnOverflow {
+			p.log.Debugf("Dropping event with decompressed field %s larger than %d bytes.", p.Field, p.MaxBytes)
+			return nil, nil
+		}
+		return p.failure(event, fmt.Errorf("failed to decompress field %s: %w", p.Field, err))
+	}
+
+	if _, err := event.PutValue(p.Target, string(data)); err != nil {
+		return p.failure(event, fmt.Errorf("failed to set field %s: %w", p.Target, err))
+	}
+	return event, nil
+}
+
+// decompress decodes payload using the configured codec. At most MaxBytes are
+// read from the decoder, such that compressed payloads expanding to a huge
+// size do not exhaust memory.
+func (p *decompress) decompress(payload []byte) ([]byte, error) {
+	var r io.Reader
+	switch p.Codec {
+	case codecGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	case codecZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(payload),
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(p.MaxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > int64(p.MaxBytes) {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// failure tags the event and returns err, unless failures are ignored.
+func (p *decompress) failure(event *beat.Event, err error) (*beat.Event, error) {
+	if len(p.TagOnFailure) > 0 {
+		if tagErr := mapstr.AddTags(event.Fields, p.TagOnFailure); tagErr != nil {
+			p.log.Debugw("Failed to add failure tags.", "error", tagErr)
+		}
+	}
+	if p.IgnoreFailure {
+		return event, nil
+	}
+	return event, err
+}
+
+func (p *decompress) String() string {
+	return fmt.Sprintf("%v=[field=%v, target=%v, codec=%v, base64=%v, max_bytes=%v]",
+		processorName, p.Field, p.Target, p.Codec, p.Base64, p.MaxBytes)
+}
@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decompress
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func gzipped(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func zstded(t *testing.T, s string) string {
+	w, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer w.Close()
+	return string(w.EncodeAll([]byte(s), nil))
+}
+
+func TestDecompress(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"gzip in place": {
+			fields: mapstr.M{"message": gzipped(t, "hello")},
+			want:   mapstr.M{"message": "hello"},
+		},
+		"gzip bytes": {
+			fields: mapstr.M{"message": []byte(gzipped(t, "hello"))},
+			want:   mapstr.M{"message": "hello"},
+		},
+		"zstd to target": {
+			config: mapstr.M{"codec": "zstd", "field": "payload", "target": "event.original"},
+			fields: mapstr.M{"payload": zstded(t, "hello")},
+			want:   mapstr.M{"payload": zstded(t, "hello"), "event": mapstr.M{"original": "hello"}},
+		},
+		"base64": {
+			config: mapstr.M{"base64": true},
+			fields: mapstr.M{"message": base64.StdEncoding.EncodeToString([]byte(gzipped(t, "hello")))},
+			want:   mapstr.M{"message": "hello"},
+		},
+		"invalid base64": {
+			config:  mapstr.M{"base64": true},
+			fields:  mapstr.M{"message": "!!!"},
+			want:    mapstr.M{"message": "!!!", "tags": []string{"_decompress_failure"}},
+			wantErr: true,
+		},
+		"not compressed": {
+			fields:  mapstr.M{"message": "hello"},
+			want:    mapstr.M{"message": "hello", "tags": []string{"_decompress_failure"}},
+			wantErr: true,
+		},
+		"gzip too large": {
+			config:  mapstr.M{"max_bytes": "1KiB"},
+			fields:  mapstr.M{"message": gzipped(t, large)},
+			want:    mapstr.M{"message": gzipped(t, large), "tags": []string{"_decompress_failure"}},
+			wantErr: true,
+		},
+		"zstd too large ignored": {
+			config: mapstr.M{"codec": "zstd", "max_bytes": "1KiB", "ignore_failure": true, "tag_on_failure": []string{"bomb"}},
+			fields: mapstr.M{"message": zstded(t, large)},
+			want:   mapstr.M{"message": zstded(t, large), "tags": []string{"bomb"}},
+		},
+		"exactly max_bytes": {
+			config: mapstr.M{"max_bytes": "2KiB"},
+			fields: mapstr.M{"message": gzipped(t, large)},
+			want:   mapstr.M{"message": large},
+		},
+		"not a string": {
+			fields:  mapstr.M{"message": 42},
+			want:    mapstr.M{"message": 42, "tags": []string{"_decompress_failure"}},
+			wantErr: true,
+		},
+		"missing field": {
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"ignored missing field": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := c.config
+			if cfg == nil {
+				cfg = mapstr.M{}
+			}
+			p, err := New(conf.MustNewConfigFrom(cfg))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: c.fields})
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.want, event.Fields)
+		})
+	}
+}
+
+func TestDecompressDropOnOverflow(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"max_bytes": 1024, "drop_on_overflow": true}))
+	require.NoError(t, err)
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"message": gzipped(t, strings.Repeat("a", 2048))}})
+	assert.NoError(t, err)
+	assert.Nil(t, event)
+}
+
+func TestDecompressConfig(t *testing.T) {
+	_, err := New(conf.MustNewConfigFrom(mapstr.M{"codec": "lz4"}))
+	assert.ErrorContains(t, err, "invalid codec")
+	_, err = New(conf.MustNewConfigFrom(mapstr.M{"max_bytes": 0}))
+	assert.ErrorContains(t, err, "max_bytes")
+	_, err = New(conf.MustNewConfigFrom(mapstr.M{"field": ""}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package field_length
+
+import "fmt"
+
+const (
+	unitBytes      = "bytes"
+	unitCharacters = "characters"
+)
+
+type config struct {
+	Field         string `config:"field"`           // Source field to measure.
+	TargetField   string `config:"target_field"`    // Field the length is written to.
+	Unit          string `config:"unit"`            // Unit of string lengths, bytes or characters.
+	IgnoreMissing bool   `config:"ignore_missing"`  // Skip events without the source field.
+	ZeroIfMissing bool   `config:"zero_if_missing"` // Write 0 for events without the source field.
+}
+
+func defaultConfig() config {
+	return config{
+		Unit: unitBytes,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	if c.TargetField == "" {
+		return fmt.Errorf("target_field must not be empty")
+	}
+	switch c.Unit {
+	case unitBytes, unitCharacters:
+	default:
+		return fmt.Errorf("invalid unit %q, must be %q or %q", c.Unit, unitBytes, unitCharacters)
+	}
+	return nil
+}
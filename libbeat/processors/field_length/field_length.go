@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package field_length
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "field_length"
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("field", "target_field"),
+			checks.AllowedFields("field", "target_field", "unit", "ignore_missing", "zero_if_missing", "when")))
+}
+
+type fieldLength struct {
+	config
+}
+
+// New constructs a new field_length processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	return &fieldLength{config: config}, nil
+}
+
+// Run writes the length of the source field to the target field. The length
+// of a string is its number of bytes or characters, depending on the unit. The
+// length of an array or object is its number of elements.
+func (p *fieldLength) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if !errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+		}
+		switch {
+		case p.ZeroIfMissing:
+			v = ""
+		case p.IgnoreMissing:
+			return event, nil
+		default:
+			return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+		}
+	}
+
+	n, err := p.length(v)
+	if err != nil {
+		return event, fmt.Errorf("could not compute the length of field %s: %w", p.Field, err)
+	}
+	if _, err := event.PutValue(p.TargetField, n); err != nil {
+		return event, fmt.Errorf("failed to set field %s: %w", p.TargetField, err)
+	}
+	return event, nil
+}
+
+func (p *fieldLength) length(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case string:
+		if p.Unit == unitCharacters {
+			return utf8.RuneCountInString(v), nil
+		}
+		return len(v), nil
+	case []byte:
+		if p.Unit == unitCharacters {
+			return utf8.RuneCount(v), nil
+		}
+		return len(v), nil
+	case mapstr.M:
+		return len(v), nil
+	case nil:
+		return 0, nil
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len(), nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func (p *fieldLength) String() string {
+	return fmt.Sprintf("%v=[field=%v, target_field=%v, unit=%v]",
+		processorName, p.Field, p.TargetField, p.Unit)
+}
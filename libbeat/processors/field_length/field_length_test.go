@@ -0,0 +1,103 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package field_length
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestFieldLength(t *testing.T) {
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"bytes": {
+			fields: mapstr.M{"message": "héllo"},
+			want:   mapstr.M{"message": "héllo", "message_length": 6},
+		},
+		"characters": {
+			config: mapstr.M{"unit": "characters"},
+			fields: mapstr.M{"message": "héllo"},
+			want:   mapstr.M{"message": "héllo", "message_length": 5},
+		},
+		"array": {
+			fields: mapstr.M{"message": []string{"a", "b", "c"}},
+			want:   mapstr.M{"message": []string{"a", "b", "c"}, "message_length": 3},
+		},
+		"object": {
+			fields: mapstr.M{"message": mapstr.M{"a": 1, "b": 2}},
+			want:   mapstr.M{"message": mapstr.M{"a": 1, "b": 2}, "message_length": 2},
+		},
+		"missing": {
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"missing ignored": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+		"missing as zero": {
+			config: mapstr.M{"zero_if_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{"message_length": 0},
+		},
+		"unsupported type": {
+			fields:  mapstr.M{"message": 42},
+			want:    mapstr.M{"message": 42},
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := mapstr.M{"field": "message", "target_field": "message_length"}
+			c.DeepUpdate(tc.config)
+			p, err := New(conf.MustNewConfigFrom(c))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: tc.fields})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, event.Fields)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, c := range map[string]mapstr.M{
+		"missing field":        {"target_field": "length"},
+		"missing target_field": {"field": "message"},
+		"invalid unit":         {"field": "message", "target_field": "length", "unit": "runes"},
+	} {
+		_, err := New(conf.MustNewConfigFrom(c))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flatten
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Ways arrays are flattened.
+const (
+	arraysKeep  = "keep"
+	arraysIndex = "index"
+	arraysJSON  = "json"
+)
+
+type config struct {
+	// Fields are the objects flattened into top-level fields. If empty, all
+	// fields of the event are flattened.
+	Fields []string `config:"fields"`
+	// Arrays configures how arrays are flattened: keep them as they are,
+	// flatten their elements with the index as key, or JSON encode them.
+	Arrays string `config:"arrays"`
+	// MaxDepth is the maximum number of key segments of flattened keys.
+	// Values nested deeper are kept as they are.
+	MaxDepth int `config:"max_depth"`
+	// IgnoreMissing ignores missing fields instead of returning an error.
+	IgnoreMissing bool `config:"ignore_missing"`
+}
+
+func defaultConfig() config {
+	return config{
+		Arrays:   arraysKeep,
+		MaxDepth: 10,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.MaxDepth < 1 {
+		return errors.New("max_depth must be at least 1")
+	}
+	switch c.Arrays {
+	case arraysKeep, arraysIndex, arraysJSON:
+	default:
+		return fmt.Errorf("invalid arrays '%s', must be one of %s, %s or %s", c.Arrays, arraysKeep, arraysIndex, arraysJSON)
+	}
+	return nil
+}
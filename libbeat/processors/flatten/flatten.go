@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flatten
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "flatten"
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("fields", "arrays", "max_depth", "ignore_missing", "when")))
+}
+
+type flatten struct {
+	config config
+}
+
+// New constructs a new flatten processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	return &flatten{config: config}, nil
+}
+
+// Run replaces nested objects with top-level fields whose keys are the dotted
+// paths of the nested values.
+func (p *flatten) Run(event *beat.Event) (*beat.Event, error) {
+	if event.Fields == nil {
+		return event, nil
+	}
+
+	out := mapstr.M{}
+	if len(p.config.Fields) == 0 {
+		for k, v := range event.Fields {
+			if err := p.flatten(k, v, 1, out); err != nil {
+				return event, err
+			}
+		}
+		event.Fields = out
+		return event, nil
+	}
+
+	for _, field := range p.config.Fields {
+		v, err := event.GetValue(field)
+		if err != nil {
+			if p.config.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+				continue
+			}
+			return event, fmt.Errorf("could not fetch value for field %s: %w", field, err)
+		}
+		if err := p.flatten(field, v, strings.Count(field, ".")+1, out); err != nil {
+			return event, err
+		}
+		_ = event.Delete(field)
+	}
+	for k, v := range out {
+		event.Fields[k] = v
+	}
+	return event, nil
+}
+
+// flatten writes value to out under key, or the values nested in it under
+// their dotted keys. depth is the number of segments of key.
+func (p *flatten) flatten(key string, value interface{}, depth int, out mapstr.M) error {
+	if depth >= p.config.MaxDepth {
+		out[key] = value
+		return nil
+	}
+
+	switch v := value.(type) {
+	case mapstr.M:
+		return p.flattenObject(key, v, depth, out)
+	case map[string]interface{}:
+		return p.flattenObject(key, v, depth, out)
+	case string, []byte:
+		out[key] = value
+		return nil
+	}
+
+	if p.config.Arrays == arraysKeep || value == nil {
+		out[key] = value
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		out[key] = value
+		return nil
+	}
+
+	if p.config.Arrays == arraysJSON {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode array %s: %w", key, err)
+		}
+		out[key] = string(encoded)
+		return nil
+	}
+	if rv.Len() == 0 {
+		out[key] = value
+		return nil
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := p.flatten(key+"."+strconv.Itoa(i), rv.Index(i).Interface(), depth+1, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *flatten) flattenObject(key string, obj map[string]interface{}, depth int, out mapstr.M) error {
+	if len(obj) == 0 {
+		// Keep empty objects, they have no keys to be flattened into.
+		out[key] = mapstr.M{}
+		return nil
+	}
+	for k, v := range obj {
+		if err := p.flatten(key+"."+k, v, depth+1, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *flatten) String() string {
+	return fmt.Sprintf("%v=[fields=%v, arrays=%v, max_depth=%v]",
+		processorName, p.config.Fields, p.config.Arrays, p.config.MaxDepth)
+}
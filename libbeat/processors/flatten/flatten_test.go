@@ -0,0 +1,143 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flatten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func testEvent() *beat.Event {
+	return &beat.Event{Fields: mapstr.M{
+		"message": "hello",
+		"http": mapstr.M{
+			"request":  mapstr.M{"method": "GET", "headers": map[string]interface{}{}},
+			"response": mapstr.M{"status_code": 200},
+		},
+		"tags":  []string{"a", "b"},
+		"hosts": []interface{}{mapstr.M{"name": "web-1"}, mapstr.M{"name": "web-2"}},
+	}}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := map[string]struct {
+		config mapstr.M
+		want   mapstr.M
+	}{
+		"all fields": {
+			config: mapstr.M{},
+			want: mapstr.M{
+				"message":                   "hello",
+				"http.request.method":       "GET",
+				"http.request.headers":      mapstr.M{},
+				"http.response.status_code": 200,
+				"tags":                      []string{"a", "b"},
+				"hosts":                     []interface{}{mapstr.M{"name": "web-1"}, mapstr.M{"name": "web-2"}},
+			},
+		},
+		"arrays with index": {
+			config: mapstr.M{"fields": []string{"tags", "hosts"}, "arrays": "index"},
+			want: mapstr.M{
+				"message": "hello",
+				"http": mapstr.M{
+					"request":  mapstr.M{"method": "GET", "headers": map[string]interface{}{}},
+					"response": mapstr.M{"status_code": 200},
+				},
+				"tags.0":       "a",
+				"tags.1":       "b",
+				"hosts.0.name": "web-1",
+				"hosts.1.name": "web-2",
+			},
+		},
+		"arrays as json": {
+			config: mapstr.M{"fields": []string{"hosts"}, "arrays": "json"},
+			want: mapstr.M{
+				"message": "hello",
+				"http": mapstr.M{
+					"request":  mapstr.M{"method": "GET", "headers": map[string]interface{}{}},
+					"response": mapstr.M{"status_code": 200},
+				},
+				"tags":  []string{"a", "b"},
+				"hosts": `[{"name":"web-1"},{"name":"web-2"}]`,
+			},
+		},
+		"nested field": {
+			config: mapstr.M{"fields": []string{"http.request"}},
+			want: mapstr.M{
+				"message":              "hello",
+				"http":                 mapstr.M{"response": mapstr.M{"status_code": 200}},
+				"http.request.method":  "GET",
+				"http.request.headers": mapstr.M{},
+				"tags":                 []string{"a", "b"},
+				"hosts":                []interface{}{mapstr.M{"name": "web-1"}, mapstr.M{"name": "web-2"}},
+			},
+		},
+		"max depth": {
+			config: mapstr.M{"fields": []string{"http"}, "max_depth": 2},
+			want: mapstr.M{
+				"message":       "hello",
+				"http.request":  mapstr.M{"method": "GET", "headers": map[string]interface{}{}},
+				"http.response": mapstr.M{"status_code": 200},
+				"tags":          []string{"a", "b"},
+				"hosts":         []interface{}{mapstr.M{"name": "web-1"}, mapstr.M{"name": "web-2"}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(conf.MustNewConfigFrom(tc.config))
+			require.NoError(t, err)
+
+			event, err := p.Run(testEvent())
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, event.Fields)
+		})
+	}
+}
+
+func TestFlattenMissingField(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"fields": []string{"missing"}}))
+	require.NoError(t, err)
+	_, err = p.Run(testEvent())
+	assert.Error(t, err)
+
+	p, err = New(conf.MustNewConfigFrom(mapstr.M{"fields": []string{"missing"}, "ignore_missing": true}))
+	require.NoError(t, err)
+	event, err := p.Run(testEvent())
+	require.NoError(t, err)
+	assert.Equal(t, testEvent().Fields, event.Fields)
+}
+
+func TestFlattenConfig(t *testing.T) {
+	for name, cfg := range map[string]mapstr.M{
+		"invalid arrays": {"arrays": "split"},
+		"zero max depth": {"max_depth": 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
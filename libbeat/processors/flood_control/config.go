@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flood_control
+
+import (
+	"errors"
+	"time"
+)
+
+type config struct {
+	// Fields lists the fields whose values identify similar events.
+	Fields []string `config:"fields"`
+	// Limit is the number of events admitted per key and window.
+	Limit int `config:"limit" validate:"min=1"`
+	// Window is the duration of a time window.
+	Window time.Duration `config:"window"`
+	// Target is the field the suppression summary is written to.
+	Target string `config:"target"`
+	// MaxKeys limits the number of tracked keys. The least recently
+	// used keys are evicted first.
+	MaxKeys int `config:"max_keys" validate:"min=1"`
+}
+
+func defaultConfig() config {
+	return config{
+		Fields:  []string{"message"},
+		Limit:   10,
+		Window:  time.Minute,
+		Target:  "flood_control",
+		MaxKeys: 10000,
+	}
+}
+
+func (c *config) Validate() error {
+	if len(c.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+	if c.Window <= 0 {
+		return errors.New("window must be a positive duration")
+	}
+	if c.Target == "" {
+		return errors.New("target must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flood_control
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "flood_control"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("fields", "limit", "window", "target", "max_keys")))
+}
+
+// window holds the admission state of a key for the current time window.
+type window struct {
+	start      time.Time
+	admitted   int
+	suppressed int
+
+	// The values of the key fields, set when the first event of the window
+	// is suppressed. They are added to the summary event.
+	fields mapstr.M
+	// Set while the window is in the list of windows to summarize.
+	summarizing bool
+}
+
+type floodControl struct {
+	config config
+	clock  clockwork.Clock
+
+	mutex sync.Mutex
+	state *lru.Cache[uint64, *window]
+
+	// The windows with suppressed events, summarized when they close or when
+	// the processor is flushed. nextClose is the time the first of them
+	// closes.
+	summarizing []*window
+	nextClose   time.Time
+
+	log        *logp.Logger
+	suppressed *monitoring.Int
+	evicted    *monitoring.Int
+}
+
+// New constructs a new flood_control processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	p := &floodControl{
+		config:     config,
+		clock:      clockwork.NewRealClock(),
+		log:        log,
+		suppressed: monitoring.NewInt(reg, "suppressed"),
+		evicted:    monitoring.NewInt(reg, "evicted"),
+	}
+
+	// Evicted windows with suppressed events are still summarized, they are
+	// kept in the summarizing list.
+	state, err := lru.NewWithEvict(config.MaxKeys, func(uint64, *window) {
+		p.evicted.Inc()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v processor state: %w", processorName, err)
+	}
+	p.state = state
+
+	return p, nil
+}
+
+// Run admits the first events of each key per time window and drops the
+// rest. The summaries of the windows with dropped events are returned by
+// RunSplit and Flush.
+func (p *floodControl) Run(event *beat.Event) (*beat.Event, error) {
+	key, err := util.FieldsHash(event, p.config.Fields)
+	if err != nil {
+		return event, fmt.Errorf("could not make key: %w", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.admit(key, event, p.clock.Now()) {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// RunSplit returns the summaries of the windows closed since the last event,
+// followed by the event if it is admitted.
+//
+// Windows are closed on a best-effort basis only: the summary of a window is
+// returned once the next event is processed after the window closed, or when
+// the client is closed.
+func (p *floodControl) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	key, err := util.FieldsHash(event, p.config.Fields)
+	if err != nil {
+		return []*beat.Event{event}, fmt.Errorf("could not make key: %w", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var out []*beat.Event
+	now := p.clock.Now()
+	if !p.nextClose.IsZero() && !now.Before(p.nextClose) {
+		out = p.summarize(func(w *window) bool {
+			return now.Sub(w.start) >= p.config.Window
+		})
+	}
+	if p.admit(key, event, now) {
+		out = append(out, event)
+	}
+	return out, nil
+}
+
+// Flush returns the summaries of all windows with suppressed events, the
+// windows are not closed. When the processor is shared by multiple clients,
+// the summaries are returned by the first client closed.
+func (p *floodControl) Flush() []*beat.Event {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.summarize(func(*window) bool { return true })
+}
+
+// HoldsEvents returns false, the events returned by Flush are created by the
+// processor, so it can be shared by multiple clients.
+func (p *floodControl) HoldsEvents() bool {
+	return false
+}
+
+// admit reports whether the event is admitted in the window of key.
+// Must be called with the mutex held.
+func (p *floodControl) admit(key uint64, event *beat.Event, now time.Time) bool {
+	// A closed window with suppressed events is kept in the summarizing list
+	// until it is summarized, the key starts a new window.
+	w, found := p.state.Get(key)
+	if !found || now.Sub(w.start) >= p.config.Window {
+		w = &window{start: now}
+		p.state.Add(key, w)
+	}
+
+	if w.admitted < p.config.Limit {
+		w.admitted++
+		return true
+	}
+
+	w.suppressed++
+	p.suppressed.Inc()
+	if !w.summarizing {
+		w.summarizing = true
+		w.fields = p.keyFields(event)
+		p.summarizing = append(p.summarizing, w)
+		if end := w.start.Add(p.config.Window); p.nextClose.IsZero() || end.Before(p.nextClose) {
+			p.nextClose = end
+		}
+	}
+	return false
+}
+
+// summarize returns a summary event for each window with suppressed events
+// for which selected returns true, and removes them from the summarizing
+// list.
+// Must be called with the mutex held.
+func (p *floodControl) summarize(selected func(*window) bool) []*beat.Event {
+	var events []*beat.Event
+	kept := p.summarizing[:0]
+	p.nextClose = time.Time{}
+	for _, w := range p.summarizing {
+		if !selected(w) {
+			kept = append(kept, w)
+			if end := w.start.Add(p.config.Window); p.nextClose.IsZero() || end.Before(p.nextClose) {
+				p.nextClose = end
+			}
+			continue
+		}
+		events = append(events, p.summary(w))
+		w.suppressed = 0
+		w.fields = nil
+		w.summarizing = false
+	}
+	clear(p.summarizing[len(kept):])
+	p.summarizing = kept
+	return events
+}
+
+// summary creates the event reporting the suppressed events of w.
+func (p *floodControl) summary(w *window) *beat.Event {
+	// Put only fails if the target is below a key field with a scalar
+	// value, the summary is still reported in the processor metrics.
+	if _, err := w.fields.Put(p.config.Target+".suppressed_count", w.suppressed); err != nil {
+		p.log.Debugf("failed to put suppressed count: %v", err)
+	}
+	_, _ = w.fields.Put(p.config.Target+".window.start", w.start)
+	return &beat.Event{
+		Timestamp: p.clock.Now(),
+		Fields:    w.fields,
+	}
+}
+
+// keyFields returns a copy of the values of the key fields of event.
+func (p *floodControl) keyFields(event *beat.Event) mapstr.M {
+	fields := mapstr.M{}
+	for _, field := range p.config.Fields {
+		if value, err := event.GetValue(field); err == nil {
+			_, _ = fields.Put(field, value)
+		}
+	}
+	return fields.Clone()
+}
+
+func (p *floodControl) String() string {
+	return fmt.Sprintf("%v=[fields=%v, limit=%v, window=%v]",
+		processorName, p.config.Fields, p.config.Limit, p.config.Window)
+}
@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flood_control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestFloodControl(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"limit":  2,
+		"window": "1m",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*floodControl).clock = clock
+	start := clock.Now()
+
+	run := func(message string) []*beat.Event {
+		t.Helper()
+		events, err := p.(processors.Splitter).RunSplit(&beat.Event{Fields: mapstr.M{"message": message}})
+		require.NoError(t, err)
+		return events
+	}
+
+	// The first events of each key are admitted, the rest is dropped.
+	assert.Len(t, run("error"), 1)
+	assert.Len(t, run("error"), 1)
+	assert.Empty(t, run("error"))
+	assert.Empty(t, run("error"))
+	assert.Empty(t, run("error"))
+	assert.Len(t, run("other"), 1, "other keys are limited independently")
+	assert.EqualValues(t, 3, p.(*floodControl).suppressed.Get())
+
+	// Once the window closed, the next event is preceded by the summary.
+	clock.Advance(time.Minute)
+	events := run("other")
+	require.Len(t, events, 2)
+	assert.Equal(t, mapstr.M{
+		"message": "error",
+		"flood_control": mapstr.M{
+			"suppressed_count": 3,
+			"window":           mapstr.M{"start": start},
+		},
+	}, events[0].Fields)
+	assert.Equal(t, clock.Now(), events[0].Timestamp)
+	assert.Equal(t, mapstr.M{"message": "other"}, events[1].Fields)
+
+	assert.Len(t, run("error"), 1, "a new window is started")
+	assert.Len(t, run("other"), 1, "suppressed events are reported once")
+}
+
+func TestFloodControlFlush(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"limit": 1,
+	}))
+	require.NoError(t, err)
+	p.(*floodControl).clock = clockwork.NewFakeClock()
+	assert.False(t, processors.HoldsEvents(p), "summaries can be flushed by any client")
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Run(&beat.Event{Fields: mapstr.M{"message": "error"}})
+		require.NoError(t, err)
+	}
+
+	// The suppressed events of the open window are reported on flush.
+	events := p.(processors.Flusher).Flush()
+	require.Len(t, events, 1)
+	count, err := events[0].GetValue("flood_control.suppressed_count")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Empty(t, p.(processors.Flusher).Flush(), "suppressed events are reported once")
+}
+
+func TestFloodControlFields(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"fields": []string{"host.name", "log.level"},
+		"limit":  1,
+		"target": "storm",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*floodControl).clock = clock
+
+	run := func(host, level string) *beat.Event {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"host":    mapstr.M{"name": host},
+			"log":     mapstr.M{"level": level},
+			"message": "ignored",
+		}})
+		require.NoError(t, err)
+		return event
+	}
+
+	assert.NotNil(t, run("a", "error"))
+	assert.Nil(t, run("a", "error"))
+	assert.NotNil(t, run("a", "info"))
+	assert.NotNil(t, run("b", "error"))
+
+	events := p.(processors.Flusher).Flush()
+	require.Len(t, events, 1)
+	assert.Equal(t, mapstr.M{
+		"host": mapstr.M{"name": "a"},
+		"log":  mapstr.M{"level": "error"},
+		"storm": mapstr.M{
+			"suppressed_count": 1,
+			"window":           mapstr.M{"start": clock.Now()},
+		},
+	}, events[0].Fields)
+}
+
+func TestFloodControlConfig(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"zero limit":      {"limit": 0},
+		"negative window": {"window": "-1s"},
+		"empty target":    {"target": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geohash
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxPrecision is the longest geohash supported, locating a point within a
+// few centimeters.
+const maxPrecision = 12
+
+type config struct {
+	// LatitudeField holds the latitude in degrees.
+	LatitudeField string `config:"latitude_field"`
+	// LongitudeField holds the longitude in degrees.
+	LongitudeField string `config:"longitude_field"`
+	// TargetField receives the geohash.
+	TargetField string `config:"target_field"`
+	// Precision is the number of characters of the geohash.
+	Precision int `config:"precision"`
+	// IgnoreMissing leaves events without coordinates unchanged instead of
+	// tagging them.
+	IgnoreMissing bool `config:"ignore_missing"`
+	// Tags added to events with missing or invalid coordinates.
+	Tags []string `config:"tags"`
+}
+
+func defaultConfig() config {
+	return config{
+		LatitudeField:  "geo.location.lat",
+		LongitudeField: "geo.location.lon",
+		TargetField:    "geo.geohash",
+		Precision:      maxPrecision,
+		Tags:           []string{"_geohash_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.LatitudeField == "" || c.LongitudeField == "" || c.TargetField == "" {
+		return errors.New("latitude_field, longitude_field and target_field must not be empty")
+	}
+	if c.Precision < 1 || c.Precision > maxPrecision {
+		return fmt.Errorf("precision must be between 1 and %d", maxPrecision)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geohash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "geohash"
+const logName = "processor." + processorName
+
+// base32 is the geohash alphabet.
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var errMissing = errors.New("field not found")
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("latitude_field", "longitude_field", "target_field",
+				"precision", "ignore_missing", "tags", "when")))
+}
+
+type geohash struct {
+	config config
+
+	log      *logp.Logger
+	failures *monitoring.Int
+}
+
+// New constructs a new geohash processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &geohash{
+		config:   config,
+		log:      log,
+		failures: monitoring.NewInt(reg, "failures"),
+	}, nil
+}
+
+// Run writes the geohash of the event's coordinates to the target field.
+// Events with missing or invalid coordinates are tagged.
+func (p *geohash) Run(event *beat.Event) (*beat.Event, error) {
+	lat, latErr := p.coordinate(event, p.config.LatitudeField, 90)
+	lon, lonErr := p.coordinate(event, p.config.LongitudeField, 180)
+	if err := errors.Join(latErr, lonErr); err != nil {
+		if p.config.IgnoreMissing && errors.Is(latErr, errMissing) && errors.Is(lonErr, errMissing) {
+			return event, nil
+		}
+		return p.fail(event, err)
+	}
+
+	if _, err := event.PutValue(p.config.TargetField, encode(lat, lon, p.config.Precision)); err != nil {
+		return p.fail(event, fmt.Errorf("failed to set field %s: %w", p.config.TargetField, err))
+	}
+	return event, nil
+}
+
+// coordinate reads a coordinate in degrees from field, checking it is within
+// [-limit, limit].
+func (p *geohash) coordinate(event *beat.Event, field string, limit float64) (float64, error) {
+	v, err := event.GetValue(field)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, errMissing)
+	}
+
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case string:
+		f, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: invalid coordinate %q", field, v)
+		}
+	default:
+		return 0, fmt.Errorf("%s: unsupported type %T", field, v)
+	}
+
+	if math.IsNaN(f) || f < -limit || f > limit {
+		return 0, fmt.Errorf("%s: coordinate %v out of range [-%v, %v]", field, f, limit, limit)
+	}
+	return f, nil
+}
+
+func (p *geohash) fail(event *beat.Event, err error) (*beat.Event, error) {
+	p.failures.Inc()
+	p.log.Debugf("Failed to compute geohash: %v", err)
+	if len(p.config.Tags) == 0 {
+		return event, nil
+	}
+	if event.Fields == nil {
+		event.Fields = mapstr.M{}
+	}
+	if err := mapstr.AddTags(event.Fields, p.config.Tags); err != nil {
+		return event, fmt.Errorf("failed to add tags: %w", err)
+	}
+	return event, nil
+}
+
+// encode returns the geohash of the given coordinates with precision
+// characters. Bits alternate between longitude and latitude, starting with
+// longitude, each bit halving the remaining interval.
+func encode(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	hash := make([]byte, 0, precision)
+	even := true
+	for len(hash) < precision {
+		var ch byte
+		for bit := 4; bit >= 0; bit-- {
+			r, v := &latRange, lat
+			if even {
+				r, v = &lonRange, lon
+			}
+			mid := (r[0] + r[1]) / 2
+			if v >= mid {
+				ch |= 1 << bit
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+		hash = append(hash, base32[ch])
+	}
+	return string(hash)
+}
+
+func (p *geohash) String() string {
+	return fmt.Sprintf("%v=[latitude_field=%v, longitude_field=%v, target_field=%v, precision=%v]",
+		processorName, p.config.LatitudeField, p.config.LongitudeField, p.config.TargetField, p.config.Precision)
+}
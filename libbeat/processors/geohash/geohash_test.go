@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geohash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{48.8583, 2.2945, 8, "u09tunqu"},
+		{-33.8568, 151.2153, 6, "r3gx2u"},
+		{0, 0, 5, "s0000"},
+		{-90, -180, 4, "0000"},
+		{90, 180, 4, "zzzz"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, encode(tc.lat, tc.lon, tc.precision), "lat=%v lon=%v", tc.lat, tc.lon)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"precision": 8,
+	}))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		in   mapstr.M
+		want mapstr.M
+	}{
+		"numbers": {
+			in: mapstr.M{"geo": mapstr.M{"location": mapstr.M{"lat": 48.8583, "lon": 2.2945}}},
+			want: mapstr.M{"geo": mapstr.M{
+				"location": mapstr.M{"lat": 48.8583, "lon": 2.2945},
+				"geohash":  "u09tunqu",
+			}},
+		},
+		"strings": {
+			in: mapstr.M{"geo": mapstr.M{"location": mapstr.M{"lat": "48.8583", "lon": "2.2945"}}},
+			want: mapstr.M{"geo": mapstr.M{
+				"location": mapstr.M{"lat": "48.8583", "lon": "2.2945"},
+				"geohash":  "u09tunqu",
+			}},
+		},
+		"out of range": {
+			in: mapstr.M{"geo": mapstr.M{"location": mapstr.M{"lat": 91.0, "lon": 2.2945}}},
+			want: mapstr.M{
+				"geo":  mapstr.M{"location": mapstr.M{"lat": 91.0, "lon": 2.2945}},
+				"tags": []string{"_geohash_failure"},
+			},
+		},
+		"not a number": {
+			in: mapstr.M{"geo": mapstr.M{"location": mapstr.M{"lat": "north", "lon": 2.2945}}},
+			want: mapstr.M{
+				"geo":  mapstr.M{"location": mapstr.M{"lat": "north", "lon": 2.2945}},
+				"tags": []string{"_geohash_failure"},
+			},
+		},
+		"missing": {
+			in: mapstr.M{"message": "hello"},
+			want: mapstr.M{
+				"message": "hello",
+				"tags":    []string{"_geohash_failure"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			event, err := p.Run(&beat.Event{Fields: tc.in})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, event.Fields)
+		})
+	}
+	assert.EqualValues(t, 3, p.(*geohash).failures.Get())
+}
+
+func TestGeohashIgnoreMissing(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"latitude_field":  "lat",
+		"longitude_field": "lon",
+		"target_field":    "hash",
+		"ignore_missing":  true,
+	}))
+	require.NoError(t, err)
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"message": "hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"message": "hello"}, event.Fields)
+
+	// Events with a single coordinate are still tagged.
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"lat": 1.5}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"lat": 1.5, "tags": []string{"_geohash_failure"}}, event.Fields)
+}
+
+func TestGeohashConfig(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"zero precision":    {"precision": 0},
+		"precision too big": {"precision": 13},
+		"empty target":      {"target_field": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package limit_cardinality
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	actionDrop = "drop"
+	actionHash = "hash"
+)
+
+type config struct {
+	// Fields lists the fields whose cardinality is limited.
+	Fields []string `config:"fields" validate:"required"`
+	// MaxCardinality is the number of distinct values per field and window
+	// above which the field's values are limited.
+	MaxCardinality int `config:"max_cardinality" validate:"min=1"`
+	// Window is the duration after which the observed values are reset.
+	Window time.Duration `config:"window"`
+	// Action is applied to the values of a field exceeding the cardinality,
+	// either drop or hash.
+	Action string `config:"action"`
+}
+
+func defaultConfig() config {
+	return config{
+		MaxCardinality: 1000,
+		Window:         time.Hour,
+		Action:         actionDrop,
+	}
+}
+
+func (c *config) Validate() error {
+	if len(c.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+	if c.Window <= 0 {
+		return errors.New("window must be a positive duration")
+	}
+	switch c.Action {
+	case actionDrop, actionHash:
+	default:
+		return fmt.Errorf("invalid action %q, must be %v or %v", c.Action, actionDrop, actionHash)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package limit_cardinality
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "limit_cardinality"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("fields"),
+			checks.AllowedFields("fields", "max_cardinality", "window", "action", "when")))
+}
+
+// fieldState tracks the distinct values of a field in the current window.
+type fieldState struct {
+	sketch   *sketch
+	exceeded bool
+}
+
+type limitCardinality struct {
+	config config
+	clock  clockwork.Clock
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	fields      []fieldState
+
+	log     *logp.Logger
+	limited *monitoring.Int
+}
+
+// New constructs a new limit_cardinality processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	p := &limitCardinality{
+		config:  config,
+		clock:   clockwork.NewRealClock(),
+		fields:  make([]fieldState, len(config.Fields)),
+		log:     log,
+		limited: monitoring.NewInt(reg, "limited"),
+	}
+	for i := range p.fields {
+		p.fields[i].sketch = newSketch()
+	}
+	p.windowStart = p.clock.Now()
+	return p, nil
+}
+
+// Run records the values of the configured fields. Once the estimated number
+// of distinct values of a field exceeds max_cardinality, its values are
+// dropped or hashed until the end of the window.
+func (p *limitCardinality) Run(event *beat.Event) (*beat.Event, error) {
+	for i, field := range p.config.Fields {
+		value, err := event.GetValue(field)
+		if err != nil {
+			if errors.Is(err, mapstr.ErrKeyNotFound) {
+				continue
+			}
+			return event, fmt.Errorf("error getting value of field '%v': %w", field, err)
+		}
+
+		str := fmt.Sprint(value)
+		hash := xxhash.Sum64String(str)
+		if !p.observe(i, hash) {
+			continue
+		}
+
+		p.limited.Inc()
+		if p.config.Action == actionHash {
+			_, err = event.PutValue(field, strconv.FormatUint(hash, 16))
+		} else {
+			err = event.Delete(field)
+		}
+		if err != nil {
+			return event, fmt.Errorf("failed to limit field '%v': %w", field, err)
+		}
+	}
+	return event, nil
+}
+
+// observe adds the hash of a value of the i-th field to the field's sketch
+// and reports whether the field exceeds the cardinality in the current window.
+func (p *limitCardinality) observe(i int, hash uint64) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if now := p.clock.Now(); now.Sub(p.windowStart) >= p.config.Window {
+		p.windowStart = now
+		for j := range p.fields {
+			p.fields[j].sketch.reset()
+			p.fields[j].exceeded = false
+		}
+	}
+
+	state := &p.fields[i]
+	if state.exceeded {
+		return true
+	}
+	if state.sketch.add(hash) && math.Round(state.sketch.estimate()) > float64(p.config.MaxCardinality) {
+		state.exceeded = true
+		p.log.Warnf("Field '%v' exceeds %d distinct values, its values are limited with action '%v' until the end of the window",
+			p.config.Fields[i], p.config.MaxCardinality, p.config.Action)
+	}
+	return state.exceeded
+}
+
+func (p *limitCardinality) String() string {
+	return fmt.Sprintf("%v=[fields=%v, max_cardinality=%v, window=%v, action=%v]",
+		processorName, p.config.Fields, p.config.MaxCardinality, p.config.Window, p.config.Action)
+}
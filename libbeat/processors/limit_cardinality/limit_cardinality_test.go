@@ -0,0 +1,123 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package limit_cardinality
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestLimitCardinality(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"fields":          []string{"request.id", "log.level"},
+		"max_cardinality": 10,
+		"window":          "1h",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*limitCardinality).clock = clock
+	p.(*limitCardinality).windowStart = clock.Now()
+
+	run := func(id int) mapstr.M {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"request": mapstr.M{"id": id},
+			"log":     mapstr.M{"level": "info"},
+		}})
+		require.NoError(t, err)
+		return event.Fields
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, i, run(i)["request"].(mapstr.M)["id"])
+	}
+
+	// Once the field exceeds the cardinality, all of its values are dropped,
+	// including the values seen before. Other fields are not affected.
+	fields := run(10)
+	assert.Equal(t, mapstr.M{"request": mapstr.M{}, "log": mapstr.M{"level": "info"}}, fields)
+	fields = run(0)
+	assert.Equal(t, mapstr.M{"request": mapstr.M{}, "log": mapstr.M{"level": "info"}}, fields)
+	assert.EqualValues(t, 2, p.(*limitCardinality).limited.Get())
+
+	// Events without the field are not affected.
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"message": "hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"message": "hello"}, event.Fields)
+
+	// The observed values are reset in the next window.
+	clock.Advance(time.Hour)
+	assert.Equal(t, 11, run(11)["request"].(mapstr.M)["id"])
+}
+
+func TestLimitCardinalityHash(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"fields":          []string{"request.id"},
+		"max_cardinality": 1,
+		"action":          "hash",
+	}))
+	require.NoError(t, err)
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"request": mapstr.M{"id": "a"}}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"request": mapstr.M{"id": "a"}}, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"request": mapstr.M{"id": "b"}}})
+	require.NoError(t, err)
+	want := strconv.FormatUint(xxhash.Sum64String("b"), 16)
+	assert.Equal(t, mapstr.M{"request": mapstr.M{"id": want}}, event.Fields)
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no fields":       {"fields": []string{}},
+		"zero limit":      {"fields": []string{"a"}, "max_cardinality": 0},
+		"negative window": {"fields": []string{"a"}, "window": "-1s"},
+		"invalid action":  {"fields": []string{"a"}, "action": "truncate"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSketchEstimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		s := newSketch()
+		for i := 0; i < n; i++ {
+			s.add(xxhash.Sum64String(strconv.Itoa(i)))
+		}
+		// Adding the same values again does not change the sketch.
+		for i := 0; i < n; i++ {
+			assert.False(t, s.add(xxhash.Sum64String(strconv.Itoa(i))))
+		}
+		assert.InEpsilon(t, n, s.estimate(), 0.05, "cardinality %d", n)
+	}
+}
@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lookup
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	formatCSV  = "csv"
+	formatYAML = "yaml"
+)
+
+type config struct {
+	// File is the path of the table file.
+	File string `config:"file"`
+	// Format of the table file, csv or yaml. By default it is derived from
+	// the file extension.
+	Format string `config:"format"`
+	// KeyField is the event field joined with the key column of the table.
+	KeyField string `config:"key_field"`
+	// KeyColumn is the table column holding the keys.
+	KeyColumn string `config:"key_column"`
+	// Columns lists the table columns added to the event. All columns but
+	// the key column are added if empty.
+	Columns []string `config:"columns"`
+	// Target is the field the columns are added to. Columns are added to
+	// the event root if empty.
+	Target        string `config:"target"`
+	OverwriteKeys bool   `config:"overwrite_keys"`
+	IgnoreMissing bool   `config:"ignore_missing"`
+	// TagOnMiss is added to the tags of events whose key is not found in
+	// the table.
+	TagOnMiss string `config:"tag_on_miss"`
+	// ReloadPeriod is the interval at which the file is checked for
+	// changes. 0 disables reloading.
+	ReloadPeriod time.Duration `config:"reload_period" validate:"min=0"`
+}
+
+func defaultConfig() config {
+	return config{
+		KeyColumn:    "key",
+		ReloadPeriod: time.Minute,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.File == "" {
+		return errors.New("file must be set")
+	}
+	if c.KeyField == "" {
+		return errors.New("key_field must be set")
+	}
+	if c.KeyColumn == "" {
+		return errors.New("key_column must not be empty")
+	}
+	switch c.Format {
+	case "", formatCSV, formatYAML:
+	default:
+		return fmt.Errorf("unsupported format '%s', must be one of csv or yaml", c.Format)
+	}
+	return nil
+}
+
+// tableFormat returns the format of the table file. Files with a .csv
+// extension are read as CSV, all others as YAML, unless configured otherwise.
+func (c *config) tableFormat() string {
+	if c.Format != "" {
+		return c.Format
+	}
+	if strings.EqualFold(filepath.Ext(c.File), ".csv") {
+		return formatCSV
+	}
+	return formatYAML
+}
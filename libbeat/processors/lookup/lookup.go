@@ -0,0 +1,174 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lookup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "lookup"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("file", "key_field"),
+			checks.AllowedFields("file", "format", "key_field", "key_column", "columns", "target",
+				"overwrite_keys", "ignore_missing", "tag_on_miss", "reload_period", "when")))
+}
+
+type lookup struct {
+	config config
+	log    *logp.Logger
+	table  atomic.Pointer[table]
+
+	// nextCheck is the time in unix nanoseconds the table file is checked
+	// for changes next.
+	nextCheck atomic.Int64
+
+	// reload state, protected by mutex.
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+
+	now func() time.Time
+}
+
+// New constructs a new lookup processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	p := &lookup{
+		config: config,
+		log:    logp.NewLogger(logName),
+		now:    time.Now,
+	}
+
+	info, err := os.Stat(config.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table file for %v processor: %w", processorName, err)
+	}
+	t, err := loadTable(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table for %v processor: %w", processorName, err)
+	}
+	p.table.Store(&t)
+	p.nextCheck.Store(p.now().Add(config.ReloadPeriod).UnixNano())
+	p.modTime, p.size = info.ModTime(), info.Size()
+
+	return p, nil
+}
+
+// Run adds the columns of the table row matching the key field to the event.
+func (p *lookup) Run(event *beat.Event) (*beat.Event, error) {
+	p.reload()
+
+	key, err := event.GetValue(p.config.KeyField)
+	if err != nil {
+		if p.config.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for key field %s: %w", p.config.KeyField, err)
+	}
+
+	row, found := (*p.table.Load())[keyString(key)]
+	if !found {
+		if p.config.TagOnMiss != "" {
+			if err := mapstr.AddTags(event.Fields, []string{p.config.TagOnMiss}); err != nil {
+				return event, fmt.Errorf("failed to add tag %s: %w", p.config.TagOnMiss, err)
+			}
+		}
+		return event, nil
+	}
+
+	for column, value := range row.Clone() {
+		field := column
+		if p.config.Target != "" {
+			field = p.config.Target + "." + column
+		}
+		if !p.config.OverwriteKeys {
+			if exists, _ := event.Fields.HasKey(field); exists {
+				continue
+			}
+		}
+		if _, err := event.PutValue(field, value); err != nil {
+			return event, fmt.Errorf("failed to set field %s: %w", field, err)
+		}
+	}
+	return event, nil
+}
+
+// reload loads the table again if the table file changed since the last
+// check. Failures are logged and the previous table is kept.
+func (p *lookup) reload() {
+	if p.config.ReloadPeriod <= 0 {
+		return
+	}
+
+	now := p.now()
+	if now.UnixNano() < p.nextCheck.Load() {
+		return
+	}
+	// Only one event checks the file, the others use the current table.
+	if !p.mutex.TryLock() {
+		return
+	}
+	defer p.mutex.Unlock()
+	if now.UnixNano() < p.nextCheck.Load() {
+		return
+	}
+	p.nextCheck.Store(now.Add(p.config.ReloadPeriod).UnixNano())
+
+	info, err := os.Stat(p.config.File)
+	if err != nil {
+		p.log.Warnf("Failed to check table file %s for changes: %v", p.config.File, err)
+		return
+	}
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return
+	}
+
+	t, err := loadTable(p.config)
+	if err != nil {
+		p.log.Errorf("Failed to reload table file, keeping the previous table: %v", err)
+		return
+	}
+	p.table.Store(&t)
+	p.modTime, p.size = info.ModTime(), info.Size()
+	p.log.Infof("Reloaded table file %s with %d rows", p.config.File, len(t))
+}
+
+func (p *lookup) String() string {
+	return fmt.Sprintf("%v=[file=%v, key_field=%v, key_column=%v, target=%v]",
+		processorName, p.config.File, p.config.KeyField, p.config.KeyColumn, p.config.Target)
+}
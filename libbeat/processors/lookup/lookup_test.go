@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lookup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const csvTable = `key,datacenter,rack
+host-1,dc-east,r1
+host-2,dc-west,r7
+`
+
+const yamlTable = `
+- key: host-1
+  datacenter: dc-east
+  location:
+    rack: r1
+- key: 42
+  datacenter: dc-west
+`
+
+func writeTable(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func newLookup(t *testing.T, cfg map[string]interface{}) *lookup {
+	t.Helper()
+	p, err := New(conf.MustNewConfigFrom(cfg))
+	require.NoError(t, err)
+	return p.(*lookup)
+}
+
+func TestLookupCSV(t *testing.T) {
+	p := newLookup(t, map[string]interface{}{
+		"file":        writeTable(t, "hosts.csv", csvTable),
+		"key_field":   "host.id",
+		"target":      "host",
+		"tag_on_miss": "lookup_miss",
+	})
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"host": mapstr.M{"id": "host-2"}}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"host": mapstr.M{"id": "host-2", "datacenter": "dc-west", "rack": "r7"}}, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"host": mapstr.M{"id": "host-3"}}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"host": mapstr.M{"id": "host-3"}, "tags": []string{"lookup_miss"}}, event.Fields)
+}
+
+func TestLookupYAML(t *testing.T) {
+	p := newLookup(t, map[string]interface{}{
+		"file":      writeTable(t, "hosts.yml", yamlTable),
+		"key_field": "host_id",
+		"columns":   []string{"location"},
+	})
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"host_id": "host-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"host_id": "host-1", "location": mapstr.M{"rack": "r1"}}, event.Fields)
+
+	// Keys are compared by their string representation.
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"host_id": 42}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"host_id": 42}, event.Fields, "no columns selected in row")
+
+	// The table rows are not modified by later changes to the event.
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"host_id": "host-1"}})
+	require.NoError(t, err)
+	event.Fields["location"].(mapstr.M)["rack"] = "changed"
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"host_id": "host-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"rack": "r1"}, event.Fields["location"])
+}
+
+func TestLookupExistingFields(t *testing.T) {
+	file := writeTable(t, "hosts.csv", csvTable)
+	event := func() *beat.Event {
+		return &beat.Event{Fields: mapstr.M{"id": "host-1", "datacenter": "old"}}
+	}
+
+	p := newLookup(t, map[string]interface{}{"file": file, "key_field": "id"})
+	out, err := p.Run(event())
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"id": "host-1", "datacenter": "old", "rack": "r1"}, out.Fields)
+
+	p = newLookup(t, map[string]interface{}{"file": file, "key_field": "id", "overwrite_keys": true})
+	out, err = p.Run(event())
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"id": "host-1", "datacenter": "dc-east", "rack": "r1"}, out.Fields)
+}
+
+func TestLookupMissingKeyField(t *testing.T) {
+	file := writeTable(t, "hosts.csv", csvTable)
+
+	p := newLookup(t, map[string]interface{}{"file": file, "key_field": "id"})
+	_, err := p.Run(&beat.Event{Fields: mapstr.M{}})
+	assert.Error(t, err)
+
+	p = newLookup(t, map[string]interface{}{"file": file, "key_field": "id", "ignore_missing": true})
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{}})
+	assert.NoError(t, err)
+	assert.Equal(t, mapstr.M{}, event.Fields)
+}
+
+func TestLookupReload(t *testing.T) {
+	file := writeTable(t, "hosts.csv", csvTable)
+	p := newLookup(t, map[string]interface{}{
+		"file":          file,
+		"key_field":     "id",
+		"reload_period": "1m",
+	})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	datacenter := func() interface{} {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{"id": "host-1"}})
+		require.NoError(t, err)
+		return event.Fields["datacenter"]
+	}
+
+	require.NoError(t, os.WriteFile(file, []byte("key,datacenter\nhost-1,dc-north\n"), 0o600))
+	require.NoError(t, os.Chtimes(file, now, now.Add(time.Hour)))
+	assert.Equal(t, "dc-east", datacenter(), "the file is not checked before the reload period passed")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "dc-north", datacenter())
+
+	// Invalid tables are not loaded.
+	require.NoError(t, os.WriteFile(file, []byte("datacenter\ndc-south\n"), 0o600))
+	require.NoError(t, os.Chtimes(file, now, now.Add(2*time.Hour)))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "dc-north", datacenter())
+}
+
+func TestLookupConfig(t *testing.T) {
+	file := writeTable(t, "hosts.csv", csvTable)
+	for name, cfg := range map[string]map[string]interface{}{
+		"missing file":     {"key_field": "id"},
+		"missing key":      {"file": file},
+		"unknown format":   {"file": file, "key_field": "id", "format": "xml"},
+		"file not found":   {"file": file + ".missing", "key_field": "id"},
+		"no key column":    {"file": file, "key_field": "id", "key_column": "host"},
+		"duplicate keys":   {"file": writeTable(t, "dup.csv", "key,a\nx,1\nx,2\n"), "key_field": "id"},
+		"invalid csv rows": {"file": writeTable(t, "bad.csv", "key,a\nx,1,2\n"), "key_field": "id"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lookup
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// table maps the keys of a lookup table to the columns of their rows.
+type table map[string]mapstr.M
+
+// loadTable reads the table file configured. Only the columns configured are
+// kept in the rows.
+func loadTable(c config) (table, error) {
+	f, err := os.Open(c.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []mapstr.M
+	switch c.tableFormat() {
+	case formatCSV:
+		rows, err = readCSV(f)
+	default:
+		rows, err = readYAML(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table file %s: %w", c.File, err)
+	}
+
+	t := make(table, len(rows))
+	for i, row := range rows {
+		key, ok := row[c.KeyColumn]
+		if !ok || key == nil {
+			return nil, fmt.Errorf("row %d of table file %s has no key column '%s'", i+1, c.File, c.KeyColumn)
+		}
+		delete(row, c.KeyColumn)
+		if len(c.Columns) > 0 {
+			for column := range row {
+				if !slices.Contains(c.Columns, column) {
+					delete(row, column)
+				}
+			}
+		}
+
+		k := keyString(key)
+		if _, exists := t[k]; exists {
+			return nil, fmt.Errorf("duplicate key '%s' in table file %s", k, c.File)
+		}
+		t[k] = row
+	}
+	return t, nil
+}
+
+// readCSV reads a CSV table. The first record holds the column names.
+func readCSV(r io.Reader) ([]mapstr.M, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rows []mapstr.M
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(mapstr.M, len(header))
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readYAML reads a YAML or JSON table, given as a list of objects.
+func readYAML(r io.Reader) ([]mapstr.M, error) {
+	var rows []map[string]interface{}
+	if err := yaml.NewDecoder(r).Decode(&rows); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	out := make([]mapstr.M, len(rows))
+	for i, row := range rows {
+		out[i] = toMapStr(row)
+	}
+	return out, nil
+}
+
+func toMapStr(m map[string]interface{}) mapstr.M {
+	out := make(mapstr.M, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = toMapStr(nested)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// keyString returns the key used to look up v. Keys of all types are
+// compared by their string representation.
+func keyString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package moving_average
+
+import (
+	"errors"
+	"time"
+)
+
+type config struct {
+	// Field is the numeric field to average.
+	Field string `config:"field" validate:"required"`
+	// Target is the field the moving average is written to. Defaults to
+	// Field with the "_avg" suffix.
+	Target string `config:"target"`
+	// WindowSize is the number of recent values averaged per entity.
+	WindowSize int `config:"window_size" validate:"min=1"`
+	// Keys lists the fields identifying the entity a value belongs to.
+	Keys []string `config:"keys"`
+	// MaxKeys limits the number of tracked entities. The least recently
+	// used entities are evicted first.
+	MaxKeys int `config:"max_keys" validate:"min=1"`
+	// TTL is the time after which the window of an entity not seen anymore
+	// is reset. Zero disables expiry.
+	TTL time.Duration `config:"ttl"`
+}
+
+func defaultConfig() config {
+	return config{
+		WindowSize: 10,
+		MaxKeys:    10000,
+		TTL:        5 * time.Minute,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.TTL < 0 {
+		return errors.New("ttl must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package moving_average
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "moving_average"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("field"),
+			checks.AllowedFields("field", "target", "window_size", "keys", "max_keys", "ttl", "when")))
+}
+
+// window holds the most recent values of an entity in a ring buffer.
+type window struct {
+	values   []float64
+	next     int
+	count    int
+	lastSeen time.Time
+}
+
+func (w *window) add(value float64) {
+	w.values[w.next] = value
+	w.next = (w.next + 1) % len(w.values)
+	if w.count < len(w.values) {
+		w.count++
+	}
+}
+
+func (w *window) average() float64 {
+	var sum float64
+	for _, v := range w.values[:w.count] {
+		sum += v
+	}
+	return sum / float64(w.count)
+}
+
+type movingAverage struct {
+	config config
+	clock  clockwork.Clock
+
+	mutex sync.Mutex
+	state *lru.Cache[uint64, *window]
+
+	log     *logp.Logger
+	expired *monitoring.Int
+	evicted *monitoring.Int
+}
+
+// New constructs a new moving_average processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	if config.Target == "" {
+		config.Target = config.Field + "_avg"
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	p := &movingAverage{
+		config:  config,
+		clock:   clockwork.NewRealClock(),
+		log:     log,
+		expired: monitoring.NewInt(reg, "expired"),
+		evicted: monitoring.NewInt(reg, "evicted"),
+	}
+
+	state, err := lru.NewWithEvict(config.MaxKeys, func(uint64, *window) {
+		p.evicted.Inc()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v processor state: %w", processorName, err)
+	}
+	p.state = state
+
+	return p, nil
+}
+
+// Run adds the value of the field to the window of the event's entity and
+// writes the average of the window to the target field. Events without a
+// numeric value are not modified.
+func (p *movingAverage) Run(event *beat.Event) (*beat.Event, error) {
+	value, err := event.GetValue(p.config.Field)
+	if err != nil {
+		if errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("error getting value of field '%v': %w", p.config.Field, err)
+	}
+	v, ok := util.ToFloat(value)
+	if !ok {
+		return event, nil
+	}
+
+	key, err := util.FieldsHash(event, p.config.Keys)
+	if err != nil {
+		return event, fmt.Errorf("could not make key: %w", err)
+	}
+
+	now := p.clock.Now()
+
+	p.mutex.Lock()
+	w, found := p.state.Get(key)
+	if !found {
+		w = &window{values: make([]float64, p.config.WindowSize)}
+		p.state.Add(key, w)
+	} else if p.config.TTL > 0 && now.Sub(w.lastSeen) >= p.config.TTL {
+		p.expired.Inc()
+		p.log.Debugf("resetting expired window of key %d", key)
+		w.next = 0
+		w.count = 0
+	}
+	w.lastSeen = now
+	w.add(v)
+	avg := w.average()
+	p.mutex.Unlock()
+
+	if _, err := event.PutValue(p.config.Target, avg); err != nil {
+		return event, fmt.Errorf("failed to put moving average: %w", err)
+	}
+	return event, nil
+}
+
+func (p *movingAverage) String() string {
+	return fmt.Sprintf("%v=[field=%v, target=%v, window_size=%v, keys=%v, ttl=%v]",
+		processorName, p.config.Field, p.config.Target, p.config.WindowSize, p.config.Keys, p.config.TTL)
+}
@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package moving_average
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestMovingAverage(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":       "cpu.pct",
+		"keys":        []string{"host.name"},
+		"window_size": 3,
+		"ttl":         "1m",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*movingAverage).clock = clock
+
+	run := func(host string, value interface{}) interface{} {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"host": mapstr.M{"name": host},
+			"cpu":  mapstr.M{"pct": value},
+		}})
+		require.NoError(t, err)
+		avg, _ := event.GetValue("cpu.pct_avg")
+		return avg
+	}
+
+	assert.Equal(t, 1.0, run("a", 1))
+	assert.Equal(t, 10.0, run("b", 10), "every host has its own window")
+	assert.Equal(t, 1.5, run("a", 2))
+	assert.Equal(t, 2.0, run("a", 3.0))
+	// The oldest value leaves the window once it is full.
+	assert.Equal(t, 4.0, run("a", int64(7)))
+
+	// Non numeric values are ignored.
+	assert.Nil(t, run("a", "high"))
+
+	// The window is reset if the host has not been seen within the TTL.
+	clock.Advance(time.Minute)
+	assert.Equal(t, 5.0, run("a", 5))
+	assert.EqualValues(t, 1, p.(*movingAverage).expired.Get())
+}
+
+func TestMovingAverageTarget(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":  "latency",
+		"target": "latency_smoothed",
+	}))
+	require.NoError(t, err)
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{"latency": 42}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"latency": 42, "latency_smoothed": 42.0}, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"message": "no latency"}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"message": "no latency"}, event.Fields)
+}
+
+func TestMovingAverageMaxKeys(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":    "value",
+		"keys":     []string{"id"},
+		"max_keys": 1,
+	}))
+	require.NoError(t, err)
+
+	run := func(id string, value int) interface{} {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{"id": id, "value": value}})
+		require.NoError(t, err)
+		avg, _ := event.GetValue("value_avg")
+		return avg
+	}
+
+	run("a", 1)
+	run("b", 2)
+	// The window of a has been evicted.
+	assert.Equal(t, 3.0, run("a", 3))
+	assert.EqualValues(t, 2, p.(*movingAverage).evicted.Get())
+}
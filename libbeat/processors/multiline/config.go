@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package multiline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/match"
+)
+
+const (
+	matchAfter  = "after"
+	matchBefore = "before"
+)
+
+type config struct {
+	// Pattern matched against the field of every event.
+	Pattern *match.Matcher `config:"pattern" validate:"required"`
+	// Negate inverts the result of the pattern.
+	Negate bool `config:"negate"`
+	// Match selects whether matching lines are appended to the previous
+	// line (after) or prepended to the next line (before).
+	Match string `config:"match"`
+	// MaxLines is the number of lines after which a joined event is
+	// emitted, even if more lines would match.
+	MaxLines int `config:"max_lines" validate:"min=1"`
+	// Timeout after which a pending joined event is emitted once the next
+	// event is processed.
+	Timeout time.Duration `config:"timeout" validate:"positive"`
+	// Field holds the lines to join.
+	Field string `config:"field"`
+	// KeyField identifies the source of the events. Only events with the
+	// same key are joined.
+	KeyField string `config:"key_field"`
+}
+
+func defaultConfig() config {
+	return config{
+		Match:    matchAfter,
+		MaxLines: 500,
+		Timeout:  5 * time.Second,
+		Field:    "message",
+		KeyField: "log.file.path",
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	switch c.Match {
+	case matchAfter, matchBefore:
+	default:
+		return fmt.Errorf("invalid match '%s', must be one of %s or %s", c.Match, matchAfter, matchBefore)
+	}
+	return nil
+}
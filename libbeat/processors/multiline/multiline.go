@@ -0,0 +1,273 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package multiline
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "multiline"
+const logName = "processor." + processorName
+
+var errMultilineNotSupported = errors.New("multiline processor can only join events when run by the publisher pipeline")
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("pattern"),
+			checks.AllowedFields("pattern", "negate", "match", "max_lines", "timeout", "field", "key_field", "when")))
+}
+
+type multiline struct {
+	config config
+	clock  clockwork.Clock
+
+	mu      sync.Mutex
+	pending map[string]*pending
+	seq     uint64
+
+	log      *logp.Logger
+	joined   *monitoring.Int
+	timeouts *monitoring.Int
+}
+
+// pending holds the lines of a joined event that has not been emitted yet.
+type pending struct {
+	event   *beat.Event
+	lines   []string
+	updated time.Time
+	seq     uint64
+
+	// private is the Private field of the last line joined. The events held
+	// back are reported to the pipeline without it, so the input only gets
+	// the state of the lines once the joined event has been published.
+	private interface{}
+}
+
+// New constructs a new multiline processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &multiline{
+		config:   config,
+		clock:    clockwork.NewRealClock(),
+		pending:  map[string]*pending{},
+		log:      log,
+		joined:   monitoring.NewInt(reg, "joined"),
+		timeouts: monitoring.NewInt(reg, "timeouts"),
+	}, nil
+}
+
+// Run can not hold back events. The event is returned unchanged together
+// with an error.
+func (p *multiline) Run(event *beat.Event) (*beat.Event, error) {
+	return event, errMultilineNotSupported
+}
+
+// RunSplit adds the event to the joined event of its source and returns the
+// joined events that are complete, if any. Events without a string in field
+// are returned unchanged.
+//
+// The timeout is checked on a best-effort basis only: pending events that
+// timed out are emitted once the next event is processed, or when the client
+// is closed.
+func (p *multiline) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	out := p.expired(now)
+
+	v, err := event.GetValue(p.config.Field)
+	if err != nil {
+		return append(out, event), nil
+	}
+	line, ok := v.(string)
+	if !ok {
+		return append(out, event), nil
+	}
+
+	key := p.key(event)
+	matched := p.config.Pattern.MatchString(line) != p.config.Negate
+	buf := p.pending[key]
+
+	switch p.config.Match {
+	case matchAfter:
+		if matched && buf != nil {
+			buf.add(event, line, now)
+		} else {
+			if buf != nil {
+				out = append(out, p.emit(key))
+			}
+			buf = p.start(key, event, line, now)
+		}
+	case matchBefore:
+		if buf != nil {
+			buf.add(event, line, now)
+		} else {
+			buf = p.start(key, event, line, now)
+		}
+		if !matched {
+			return append(out, p.emit(key)), nil
+		}
+	}
+
+	if len(buf.lines) >= p.config.MaxLines {
+		out = append(out, p.emit(key))
+	}
+	return out, nil
+}
+
+// Flush returns all pending joined events, in the order they were started.
+func (p *multiline) Flush() []*beat.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.emitWhere(func(*pending) bool { return true })
+}
+
+// expired returns the joined events not updated within the timeout.
+// Must be called with the mutex held.
+func (p *multiline) expired(now time.Time) []*beat.Event {
+	events := p.emitWhere(func(buf *pending) bool {
+		return now.Sub(buf.updated) >= p.config.Timeout
+	})
+	p.timeouts.Add(int64(len(events)))
+	return events
+}
+
+// emitWhere returns the joined events of the pending buffers for which
+// selected returns true, in the order they were started.
+// Must be called with the mutex held.
+func (p *multiline) emitWhere(selected func(*pending) bool) []*beat.Event {
+	var keys []string
+	for key, buf := range p.pending {
+		if selected(buf) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return p.pending[keys[i]].seq < p.pending[keys[j]].seq
+	})
+
+	events := make([]*beat.Event, 0, len(keys))
+	for _, key := range keys {
+		events = append(events, p.emit(key))
+	}
+	return events
+}
+
+// start begins a new joined event for key.
+// Must be called with the mutex held.
+func (p *multiline) start(key string, event *beat.Event, line string, now time.Time) *pending {
+	p.seq++
+	buf := &pending{
+		event:   event,
+		private: event.Private,
+		lines:   []string{line},
+		updated: now,
+		seq:     p.seq,
+	}
+	event.Private = nil
+	p.pending[key] = buf
+	return buf
+}
+
+// add appends the line of event to the joined event. The joined event takes
+// over the Private field of event.
+func (buf *pending) add(event *beat.Event, line string, now time.Time) {
+	buf.lines = append(buf.lines, line)
+	buf.updated = now
+	buf.private = event.Private
+	event.Private = nil
+}
+
+// emit removes the pending joined event of key and returns it with the lines
+// joined into field.
+// Must be called with the mutex held.
+func (p *multiline) emit(key string) *beat.Event {
+	buf := p.pending[key]
+	delete(p.pending, key)
+
+	event := buf.event
+	event.Private = buf.private
+	if len(buf.lines) == 1 {
+		return event
+	}
+
+	p.joined.Inc()
+	if _, err := event.PutValue(p.config.Field, strings.Join(buf.lines, "\n")); err != nil {
+		p.log.Debugf("Failed to set joined lines in field %s: %v", p.config.Field, err)
+		return event
+	}
+	if err := mapstr.AddTagsWithKey(event.Fields, "log.flags", []string{"multiline"}); err != nil {
+		p.log.Debugf("Failed to add multiline flag: %v", err)
+	}
+	return event
+}
+
+// key returns the source of the event. Events without key_field share the
+// empty key.
+func (p *multiline) key(event *beat.Event) string {
+	if p.config.KeyField == "" {
+		return ""
+	}
+	v, err := event.GetValue(p.config.KeyField)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func (p *multiline) String() string {
+	return fmt.Sprintf("%v=[pattern=%v, negate=%v, match=%v, max_lines=%v, timeout=%v, field=%v, key_field=%v]",
+		processorName, p.config.Pattern, p.config.Negate, p.config.Match,
+		p.config.MaxLines, p.config.Timeout, p.config.Field, p.config.KeyField)
+}
@@ -0,0 +1,203 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package multiline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func newTestProcessor(t *testing.T, settings map[string]interface{}) (*multiline, clockwork.FakeClock) {
+	t.Helper()
+	p, err := New(conf.MustNewConfigFrom(settings))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*multiline).clock = clock
+	return p.(*multiline), clock
+}
+
+// run processes the lines of source and returns the messages of the events
+// emitted.
+func run(t *testing.T, p *multiline, source string, lines ...string) []string {
+	t.Helper()
+	var messages []string
+	for _, line := range lines {
+		events, err := p.RunSplit(&beat.Event{Fields: mapstr.M{
+			"message": line,
+			"log":     mapstr.M{"file": mapstr.M{"path": source}},
+		}})
+		require.NoError(t, err)
+		messages = append(messages, eventMessages(t, events)...)
+	}
+	return messages
+}
+
+func eventMessages(t *testing.T, events []*beat.Event) []string {
+	t.Helper()
+	var messages []string
+	for _, event := range events {
+		message, err := event.GetValue("message")
+		require.NoError(t, err)
+		messages = append(messages, message.(string))
+	}
+	return messages
+}
+
+func TestMultilineAfter(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\s`,
+		"match":   "after",
+	})
+
+	assert.Empty(t, run(t, p, "a.log", "Exception", "  at foo", "  at bar"))
+	assert.Equal(t, []string{"Exception\n  at foo\n  at bar"}, run(t, p, "a.log", "next"))
+	assert.Equal(t, []string{"next"}, eventMessages(t, p.Flush()))
+	assert.Empty(t, p.Flush())
+	assert.EqualValues(t, 1, p.joined.Get())
+}
+
+func TestMultilineBefore(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `\\$`,
+		"match":   "before",
+	})
+
+	assert.Empty(t, run(t, p, "a.log", `first \`, `second \`))
+	assert.Equal(t, []string{"first \\\nsecond \\\nthird"}, run(t, p, "a.log", "third"))
+	assert.Equal(t, []string{"single"}, run(t, p, "a.log", "single"))
+}
+
+func TestMultilineNegate(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\[`,
+		"negate":  true,
+		"match":   "after",
+	})
+
+	assert.Empty(t, run(t, p, "a.log", "[1] start", "continued"))
+	assert.Equal(t, []string{"[1] start\ncontinued"}, run(t, p, "a.log", "[2] start"))
+}
+
+func TestMultilineKeyField(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\s`,
+	})
+
+	assert.Empty(t, run(t, p, "a.log", "a"))
+	assert.Empty(t, run(t, p, "b.log", "b"))
+	assert.Empty(t, run(t, p, "a.log", "  a continued"))
+	assert.Empty(t, run(t, p, "b.log", "  b continued"))
+	assert.Equal(t, []string{"a\n  a continued", "b\n  b continued"}, eventMessages(t, p.Flush()))
+}
+
+func TestMultilineMaxLines(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern":   `^\s`,
+		"max_lines": 2,
+	})
+
+	assert.Equal(t, []string{"a\n  1"}, run(t, p, "a.log", "a", "  1"))
+	assert.Empty(t, run(t, p, "a.log", "  2"))
+	assert.Equal(t, []string{"  2"}, eventMessages(t, p.Flush()))
+}
+
+func TestMultilineTimeout(t *testing.T) {
+	p, clock := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\s`,
+		"timeout": "10s",
+	})
+
+	assert.Empty(t, run(t, p, "a.log", "a", "  1"))
+	assert.Empty(t, run(t, p, "b.log", "b"))
+
+	// The timeout is checked when the next event is processed.
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, []string{"a\n  1", "b"}, run(t, p, "c.log", "c"))
+	assert.EqualValues(t, 2, p.timeouts.Get())
+}
+
+func TestMultilinePrivate(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\s`,
+	})
+
+	var held []*beat.Event
+	for i, line := range []string{"Exception", "  at foo", "  at bar"} {
+		event := &beat.Event{Fields: mapstr.M{"message": line}, Private: i}
+		events, err := p.RunSplit(event)
+		require.NoError(t, err)
+		require.Empty(t, events)
+		held = append(held, event)
+	}
+	for _, event := range held {
+		assert.Nil(t, event.Private, "held events must not be reported with their state")
+	}
+
+	events, err := p.RunSplit(&beat.Event{Fields: mapstr.M{"message": "next"}, Private: 3})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Private, "the joined event must carry the state of its last line")
+
+	events = p.Flush()
+	require.Len(t, events, 1)
+	assert.Equal(t, 3, events[0].Private)
+}
+
+func TestMultilineWithoutField(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\s`,
+	})
+
+	event := &beat.Event{Fields: mapstr.M{"other": "value"}}
+	events, err := p.RunSplit(event)
+	require.NoError(t, err)
+	assert.Equal(t, []*beat.Event{event}, events)
+}
+
+func TestMultilineRun(t *testing.T) {
+	p, _ := newTestProcessor(t, map[string]interface{}{
+		"pattern": `^\s`,
+	})
+
+	event := &beat.Event{Fields: mapstr.M{"message": "value"}}
+	out, err := p.Run(event)
+	assert.ErrorIs(t, err, errMultilineNotSupported)
+	assert.Equal(t, event, out)
+}
+
+func TestMultilineConfig(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing pattern": {"match": "after"},
+		"invalid match":   {"pattern": "^a", "match": "both"},
+		"empty field":     {"pattern": "^a", "field": ""},
+		"zero max_lines":  {"pattern": "^a", "max_lines": 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package normalize_ip
+
+import "fmt"
+
+type config struct {
+	Field         string   `config:"field"`          // Source field containing the IP address.
+	TargetField   string   `config:"target_field"`   // Field the normalized address is written to. Defaults to field.
+	PortField     string   `config:"port_field"`     // Field the port is written to, if the value has one.
+	UnmapIPv4     bool     `config:"unmap_ipv4"`     // Convert IPv4-mapped IPv6 addresses to IPv4.
+	IgnoreMissing bool     `config:"ignore_missing"` // Ignore errors when the source field is missing.
+	IgnoreFailure bool     `config:"ignore_failure"` // Ignore errors when the address is invalid.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when the address is invalid.
+}
+
+func defaultConfig() config {
+	return config{
+		UnmapIPv4:    true,
+		TagOnFailure: []string{"_normalize_ip_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package normalize_ip
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "normalize_ip"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("field"),
+			checks.AllowedFields("field", "target_field", "port_field", "unmap_ipv4",
+				"ignore_missing", "ignore_failure", "tag_on_failure", "when")))
+}
+
+type normalizeIP struct {
+	config
+
+	log        *logp.Logger
+	normalized *monitoring.Int
+	invalid    *monitoring.Int
+}
+
+// New constructs a new normalize_ip processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	if config.TargetField == "" {
+		config.TargetField = config.Field
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &normalizeIP{
+		config:     config,
+		log:        log,
+		normalized: monitoring.NewInt(reg, "normalized"),
+		invalid:    monitoring.NewInt(reg, "invalid"),
+	}, nil
+}
+
+// Run parses the IP address in the source field and writes it in canonical
+// form to the target field. A port following the address is removed and
+// written to the port field, if configured. Invalid addresses are not
+// modified and the event is tagged.
+func (p *normalizeIP) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if p.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return p.failure(event, fmt.Errorf("field %s is not of string type", p.Field))
+	}
+
+	addr, port, err := parse(s)
+	if err != nil {
+		return p.failure(event, fmt.Errorf("invalid IP address in field %s: %w", p.Field, err))
+	}
+	if p.UnmapIPv4 {
+		addr = addr.Unmap()
+	}
+
+	if _, err := event.PutValue(p.TargetField, addr.String()); err != nil {
+		return p.failure(event, fmt.Errorf("failed to set field %s: %w", p.TargetField, err))
+	}
+	if p.PortField != "" && port >= 0 {
+		if _, err := event.PutValue(p.PortField, port); err != nil {
+			return p.failure(event, fmt.Errorf("failed to set field %s: %w", p.PortField, err))
+		}
+	}
+	p.normalized.Inc()
+	return event, nil
+}
+
+// parse parses an IP address, optionally followed by a port, as in
+// "10.0.0.1:80" or "[::1]:80". The port is -1 if s has none. Zones of IPv6
+// addresses are removed and zero-padded IPv4 octets are read as decimal.
+func parse(s string) (netip.Addr, int, error) {
+	host, port := strings.TrimSpace(s), -1
+	var portStr string
+	hasPort := false
+	if rest, ok := strings.CutPrefix(host, "["); ok {
+		host, rest, ok = strings.Cut(rest, "]")
+		if !ok {
+			return netip.Addr{}, -1, fmt.Errorf("missing ']' in '%s'", s)
+		}
+		if rest != "" {
+			if portStr, hasPort = strings.CutPrefix(rest, ":"); !hasPort {
+				return netip.Addr{}, -1, fmt.Errorf("unexpected '%s' after address", rest)
+			}
+		}
+	} else if strings.Count(host, ":") == 1 {
+		host, portStr, hasPort = strings.Cut(host, ":")
+	}
+	if hasPort {
+		n, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return netip.Addr{}, -1, fmt.Errorf("invalid port '%s'", portStr)
+		}
+		port = int(n)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		var ok bool
+		if addr, ok = parsePaddedIPv4(host); !ok {
+			return netip.Addr{}, -1, err
+		}
+	}
+	return addr.WithZone(""), port, nil
+}
+
+// parsePaddedIPv4 parses IPv4 addresses with zero-padded octets, like
+// 010.001.000.001, which netip rejects as they could be read as octal.
+func parsePaddedIPv4(s string) (netip.Addr, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return netip.Addr{}, false
+	}
+	var ip [4]byte
+	for i, part := range parts {
+		if part == "" || len(part) > 3 {
+			return netip.Addr{}, false
+		}
+		n, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		ip[i] = byte(n)
+	}
+	return netip.AddrFrom4(ip), true
+}
+
+// failure counts the invalid value, tags the event and returns err, unless
+// failures are ignored.
+func (p *normalizeIP) failure(event *beat.Event, err error) (*beat.Event, error) {
+	p.invalid.Inc()
+	if len(p.TagOnFailure) > 0 {
+		if tagErr := mapstr.AddTags(event.Fields, p.TagOnFailure); tagErr != nil {
+			p.log.Debugw("Failed to add failure tags.", "error", tagErr)
+		}
+	}
+	if p.IgnoreFailure {
+		return event, nil
+	}
+	return event, err
+}
+
+func (p *normalizeIP) String() string {
+	return fmt.Sprintf("%v=[field=%v, target_field=%v, port_field=%v]",
+		processorName, p.Field, p.TargetField, p.PortField)
+}
@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package normalize_ip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		addr string
+		port int
+	}{
+		"10.0.0.1":                    {"10.0.0.1", -1},
+		" 10.0.0.1 ":                  {"10.0.0.1", -1},
+		"010.001.000.100":             {"10.1.0.100", -1},
+		"10.0.0.1:8080":               {"10.0.0.1", 8080},
+		"2001:0DB8:0000:0000::0001":   {"2001:db8::1", -1},
+		"2001:db8:0:0:1:0:0:1":        {"2001:db8::1:0:0:1", -1},
+		"[2001:db8::1]:443":           {"2001:db8::1", 443},
+		"[2001:db8::1]":               {"2001:db8::1", -1},
+		"fe80::1%eth0":                {"fe80::1", -1},
+		"fe80::":                      {"fe80::", -1},
+		"::ffff:192.168.1.1":          {"::ffff:192.168.1.1", -1},
+		"[::ffff:c0a8:101]:53":        {"::ffff:192.168.1.1", 53},
+		"0000:0000:0000:0000:0:0:0:0": {"::", -1},
+	}
+	for in, want := range cases {
+		addr, port, err := parse(in)
+		if assert.NoError(t, err, in) {
+			assert.Equal(t, want.addr, addr.String(), in)
+			assert.Equal(t, want.port, port, in)
+		}
+	}
+
+	for _, in := range []string{"", "10.0.0", "10.0.0.256", "10.0.0.1:", "10.0.0.1:http", "10.0.0.1:70000", "[::1", "[::1]x", "0010.0.0.1", "example.com"} {
+		_, _, err := parse(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"in place": {
+			fields: mapstr.M{"ip": "2001:DB8:0:0:0:0:0:1"},
+			want:   mapstr.M{"ip": "2001:db8::1"},
+		},
+		"ipv4-mapped addresses are unmapped": {
+			fields: mapstr.M{"ip": "::ffff:10.0.0.1"},
+			want:   mapstr.M{"ip": "10.0.0.1"},
+		},
+		"ipv4-mapped addresses kept": {
+			config: mapstr.M{"unmap_ipv4": false},
+			fields: mapstr.M{"ip": "::ffff:10.0.0.1"},
+			want:   mapstr.M{"ip": "::ffff:10.0.0.1"},
+		},
+		"port to separate field": {
+			config: mapstr.M{"target_field": "source.ip", "port_field": "source.port"},
+			fields: mapstr.M{"ip": "[::1]:8080"},
+			want:   mapstr.M{"ip": "[::1]:8080", "source": mapstr.M{"ip": "::1", "port": 8080}},
+		},
+		"port stripped": {
+			fields: mapstr.M{"ip": "10.0.0.1:8080"},
+			want:   mapstr.M{"ip": "10.0.0.1"},
+		},
+		"invalid address": {
+			fields:  mapstr.M{"ip": "10.0.0.300"},
+			want:    mapstr.M{"ip": "10.0.0.300", "tags": []string{"_normalize_ip_failure"}},
+			wantErr: true,
+		},
+		"ignored failure": {
+			config: mapstr.M{"ignore_failure": true, "tag_on_failure": []string{"bad_ip"}},
+			fields: mapstr.M{"ip": 42},
+			want:   mapstr.M{"ip": 42, "tags": []string{"bad_ip"}},
+		},
+		"missing field": {
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"ignored missing field": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := mapstr.M{"field": "ip"}
+			cfg.Update(c.config)
+			p, err := New(conf.MustNewConfigFrom(cfg))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: c.fields})
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.want, event.Fields)
+		})
+	}
+}
+
+func TestNormalizeIPMetrics(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"field": "ip", "ignore_failure": true}))
+	require.NoError(t, err)
+
+	for _, ip := range []string{"10.0.0.1", "::1", "not an ip"} {
+		_, err := p.Run(&beat.Event{Fields: mapstr.M{"ip": ip}})
+		require.NoError(t, err)
+	}
+	n := p.(*normalizeIP)
+	assert.Equal(t, int64(2), n.normalized.Get())
+	assert.Equal(t, int64(1), n.invalid.Get())
+}
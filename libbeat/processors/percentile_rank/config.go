@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package percentile_rank
+
+import (
+	"errors"
+	"time"
+)
+
+type config struct {
+	// Field is the numeric field to rank.
+	Field string `config:"field" validate:"required"`
+	// Target is the field the percentile rank is written to. Defaults to
+	// Field with the "_percentile_rank" suffix.
+	Target string `config:"target"`
+	// Keys lists the fields identifying the entity a value belongs to.
+	Keys []string `config:"keys"`
+	// Window is the time span of the recent values a value is ranked against.
+	Window time.Duration `config:"window"`
+	// SampleSize is the maximum number of recent values kept per entity.
+	// The oldest values are discarded first.
+	SampleSize int `config:"sample_size" validate:"min=1"`
+	// MaxKeys limits the number of tracked entities. The least recently
+	// used entities are evicted first.
+	MaxKeys int `config:"max_keys" validate:"min=1"`
+}
+
+func defaultConfig() config {
+	return config{
+		Window:     5 * time.Minute,
+		SampleSize: 1000,
+		MaxKeys:    10000,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Window <= 0 {
+		return errors.New("window must be greater than 0")
+	}
+	return nil
+}
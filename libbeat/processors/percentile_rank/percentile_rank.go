@@ -0,0 +1,198 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package percentile_rank
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "percentile_rank"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("field"),
+			checks.AllowedFields("field", "target", "keys", "window", "sample_size", "max_keys", "when")))
+}
+
+// sample holds the most recent values of an entity in a ring buffer, in the
+// order they were seen.
+type sample struct {
+	values []float64
+	times  []time.Time
+	first  int
+	count  int
+}
+
+func newSample(size int) *sample {
+	return &sample{values: make([]float64, size), times: make([]time.Time, size)}
+}
+
+// expire discards the values seen before cutoff.
+func (s *sample) expire(cutoff time.Time) {
+	for s.count > 0 && s.times[s.first].Before(cutoff) {
+		s.first = (s.first + 1) % len(s.values)
+		s.count--
+	}
+}
+
+// add appends a value, discarding the oldest value if the sample is full.
+func (s *sample) add(value float64, now time.Time) {
+	i := (s.first + s.count) % len(s.values)
+	s.values[i] = value
+	s.times[i] = now
+	if s.count < len(s.values) {
+		s.count++
+	} else {
+		s.first = (s.first + 1) % len(s.values)
+	}
+}
+
+// rank returns the percentile rank of value within the sample: the
+// percentage of values below value, counting values equal to it as half.
+// It returns false if the sample is empty.
+func (s *sample) rank(value float64) (float64, bool) {
+	if s.count == 0 {
+		return 0, false
+	}
+	var below, equal int
+	for i := 0; i < s.count; i++ {
+		switch v := s.values[(s.first+i)%len(s.values)]; {
+		case v < value:
+			below++
+		case v == value:
+			equal++
+		}
+	}
+	return 100 * (float64(below) + float64(equal)/2) / float64(s.count), true
+}
+
+type percentileRank struct {
+	config config
+	clock  clockwork.Clock
+
+	mutex sync.Mutex
+	state *lru.Cache[uint64, *sample]
+
+	log     *logp.Logger
+	evicted *monitoring.Int
+}
+
+// New constructs a new percentile_rank processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+	if config.Target == "" {
+		config.Target = config.Field + "_percentile_rank"
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	p := &percentileRank{
+		config:  config,
+		clock:   clockwork.NewRealClock(),
+		log:     log,
+		evicted: monitoring.NewInt(reg, "evicted"),
+	}
+
+	state, err := lru.NewWithEvict(config.MaxKeys, func(uint64, *sample) {
+		p.evicted.Inc()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v processor state: %w", processorName, err)
+	}
+	p.state = state
+
+	return p, nil
+}
+
+// Run writes the percentile rank of the value of the field within the recent
+// values of the event's entity to the target field, and adds the value to the
+// entity's sample. The first value of an entity gets no rank. Events without
+// a numeric value are not modified.
+func (p *percentileRank) Run(event *beat.Event) (*beat.Event, error) {
+	value, err := event.GetValue(p.config.Field)
+	if err != nil {
+		if errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("error getting value of field '%v': %w", p.config.Field, err)
+	}
+	v, ok := util.ToFloat(value)
+	if !ok {
+		return event, nil
+	}
+
+	key, err := util.FieldsHash(event, p.config.Keys)
+	if err != nil {
+		return event, fmt.Errorf("could not make key: %w", err)
+	}
+
+	now := p.clock.Now()
+
+	p.mutex.Lock()
+	s, found := p.state.Get(key)
+	if !found {
+		s = newSample(p.config.SampleSize)
+		p.state.Add(key, s)
+	}
+	s.expire(now.Add(-p.config.Window))
+	rank, ok := s.rank(v)
+	s.add(v, now)
+	p.mutex.Unlock()
+
+	if !ok {
+		return event, nil
+	}
+	if _, err := event.PutValue(p.config.Target, rank); err != nil {
+		return event, fmt.Errorf("failed to put percentile rank: %w", err)
+	}
+	return event, nil
+}
+
+func (p *percentileRank) String() string {
+	return fmt.Sprintf("%v=[field=%v, target=%v, keys=%v, window=%v, sample_size=%v]",
+		processorName, p.config.Field, p.config.Target, p.config.Keys, p.config.Window, p.config.SampleSize)
+}
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package percentile_rank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestPercentileRank(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":  "latency",
+		"keys":   []string{"host.name"},
+		"window": "1m",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*percentileRank).clock = clock
+
+	run := func(host string, value interface{}) interface{} {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{
+			"host":    mapstr.M{"name": host},
+			"latency": value,
+		}})
+		require.NoError(t, err)
+		rank, _ := event.GetValue("latency_percentile_rank")
+		return rank
+	}
+
+	assert.Nil(t, run("a", 10), "the first value has nothing to be ranked against")
+	assert.Nil(t, run("b", 100), "every host has its own sample")
+	assert.Equal(t, 100.0, run("a", 20))
+	assert.Equal(t, 0.0, run("a", int64(5)))
+	// 10 is above 5 and equal to 10, out of 5, 10 and 20.
+	assert.Equal(t, 50.0, run("a", 10.0))
+	assert.Equal(t, 50.0, run("b", 100))
+
+	// Non numeric values are ignored.
+	assert.Nil(t, run("a", "slow"))
+
+	// Values older than the window are discarded.
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, 100.0, run("a", 30))
+	clock.Advance(31 * time.Second)
+	assert.Equal(t, 0.0, run("a", 1), "only 30 is in the window")
+}
+
+func TestPercentileRankSampleSize(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":       "value",
+		"target":      "rank",
+		"sample_size": 2,
+	}))
+	require.NoError(t, err)
+
+	run := func(value int) interface{} {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{"value": value}})
+		require.NoError(t, err)
+		rank, _ := event.GetValue("rank")
+		return rank
+	}
+
+	run(1)
+	run(2)
+	run(3)
+	// 1 has been discarded, 4 is ranked against 2 and 3.
+	assert.Equal(t, 100.0, run(4))
+	assert.Equal(t, 25.0, run(3), "3 is above none and equal to one of 3 and 4")
+}
+
+func TestPercentileRankMaxKeys(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":    "value",
+		"keys":     []string{"id"},
+		"max_keys": 1,
+	}))
+	require.NoError(t, err)
+
+	run := func(id string, value int) interface{} {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{"id": id, "value": value}})
+		require.NoError(t, err)
+		rank, _ := event.GetValue("value_percentile_rank")
+		return rank
+	}
+
+	run("a", 1)
+	run("b", 2)
+	// The sample of a has been evicted.
+	assert.Nil(t, run("a", 3))
+	assert.EqualValues(t, 2, p.(*percentileRank).evicted.Get())
+}
+
+func TestPercentileRankConfig(t *testing.T) {
+	_, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":  "value",
+		"window": 0,
+	}))
+	assert.Error(t, err)
+
+	_, err = New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":       "value",
+		"sample_size": 0,
+	}))
+	assert.Error(t, err)
+}
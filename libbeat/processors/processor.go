@@ -25,6 +25,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
 )
 
@@ -53,6 +54,59 @@ func Close(p beat.Processor) error {
 	return nil
 }
 
+// Splitter defines the interface for processors that can replace an event
+// with multiple events. The publisher pipeline runs the processors following
+// a Splitter on each of the events returned and publishes all of them.
+type Splitter interface {
+	beat.Processor
+
+	// RunSplit processes the event and returns the events replacing it.
+	// If the event has been dropped no events are returned.
+	RunSplit(event *beat.Event) ([]*beat.Event, error)
+}
+
+// Flusher defines the interface for processors that hold back events, for
+// example to merge them with later events. The publisher pipeline calls Flush
+// when a client is closed and publishes the events returned, so buffered
+// events are not lost.
+type Flusher interface {
+	beat.Processor
+
+	// Flush returns the events held back by the processor and clears its
+	// buffers.
+	Flush() []*beat.Event
+}
+
+// HoldsEvents reports whether the processor holds back events to be returned
+// by Flush. Conditional processors report whether the processor they run
+// holds back events. Flushers only returning events they create, like
+// summaries of the events processed, implement `HoldsEvents() bool` to
+// report that they do not hold back events.
+func HoldsEvents(p beat.Processor) bool {
+	if w, ok := p.(*whenSplitter); ok {
+		p = w.s
+	}
+	if h, ok := p.(interface{ HoldsEvents() bool }); ok {
+		return h.HoldsEvents()
+	}
+	_, ok := p.(Flusher)
+	return ok
+}
+
+// RunSplit runs the processor on the event. If the processor implements the
+// Splitter interface all events created are returned, otherwise the result of
+// Run is returned as a single event.
+func RunSplit(p beat.Processor, event *beat.Event) ([]*beat.Event, error) {
+	if s, ok := p.(Splitter); ok {
+		return s.RunSplit(event)
+	}
+	event, err := p.Run(event)
+	if event == nil {
+		return nil, err
+	}
+	return []*beat.Event{event}, err
+}
+
 // NewList creates a new empty processor list.
 // Additional processors can be added to the List field.
 func NewList(log *logp.Logger) *Processors {
@@ -100,7 +154,7 @@ func New(config PluginConfig) (*Processors, error) {
 			return nil, fmt.Errorf("the processor action %s does not exist. Valid actions: %v", actionName, strings.Join(validActions, ", "))
 		}
 
-		common.PrintConfigDebugf(actionCfg, "Configure processor action '%v' with:", actionName)
+		common.PrintConfigDebugf(maskSensitiveSettings(actionCfg), "Configure processor action '%v' with:", actionName)
 		constructor := gen.Plugin()
 		plugin, err := constructor(actionCfg)
 		if err != nil {
@@ -116,6 +170,21 @@ func New(config PluginConfig) (*Processors, error) {
 	return procs, nil
 }
 
+// maskSensitiveSettings returns a copy of the processor configuration with the
+// values of common.SensitiveSettings masked, for logging.
+func maskSensitiveSettings(cfg *config.C) *config.C {
+	masked := config.NewConfig()
+	if err := masked.Merge(cfg); err != nil {
+		return config.NewConfig()
+	}
+	for _, name := range common.SensitiveSettings {
+		if masked.HasField(name) {
+			_ = masked.SetString(name, -1, "xxxxx")
+		}
+	}
+	return masked
+}
+
 // AddProcessor adds a single Processor to Processors
 func (procs *Processors) AddProcessor(p beat.Processor) {
 	procs.List = append(procs.List, p)
@@ -18,20 +18,18 @@
 package ratelimit
 
 import (
-	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"sync/atomic"
 
 	"github.com/jonboulle/clockwork"
-	"github.com/mitchellh/hashstructure"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
 	c "github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
-	"github.com/elastic/elastic-agent-libs/mapstr"
 	"github.com/elastic/elastic-agent-libs/monitoring"
 )
 
@@ -68,6 +66,9 @@ func new(cfg *c.C) (beat.Processor, error) {
 		return nil, fmt.Errorf("could not set default configuration: %w", err)
 	}
 
+	// Sort the fields so the key of an event does not depend on their order.
+	sort.Strings(config.Fields)
+
 	algoConfig := algoConfig{
 		limit:  config.Limit,
 		config: *config.Algorithm.Config(),
@@ -101,7 +102,7 @@ func new(cfg *c.C) (beat.Processor, error) {
 // Run applies the configured rate limit to the given event. If the event is within the
 // configured rate limit, it is returned as-is. If not, nil is returned.
 func (p *rateLimit) Run(event *beat.Event) (*beat.Event, error) {
-	key, err := p.makeKey(event)
+	key, err := util.FieldsHash(event, p.config.Fields)
 	if err != nil {
 		return nil, fmt.Errorf("could not make key: %w", err)
 	}
@@ -122,29 +123,6 @@ func (p *rateLimit) String() string {
 	)
 }
 
-func (p *rateLimit) makeKey(event *beat.Event) (uint64, error) {
-	if len(p.config.Fields) == 0 {
-		return 0, nil
-	}
-
-	sort.Strings(p.config.Fields)
-	values := make([]string, len(p.config.Fields))
-	for _, field := range p.config.Fields {
-		value, err := event.GetValue(field)
-		if err != nil {
-			if !errors.Is(err, mapstr.ErrKeyNotFound) {
-				return 0, fmt.Errorf("error getting value of field '%v': %w", field, err)
-			}
-
-			value = ""
-		}
-
-		values = append(values, fmt.Sprintf("%v", value))
-	}
-
-	return hashstructure.Hash(values, nil)
-}
-
 // setClock allows test code to inject a fake clock
 // TODO: remove this method and move tests that use it to algorithm level.
 func (p *rateLimit) setClock(c clockwork.Clock) {
@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// mode defines how the value of a field is redacted.
+type mode uint8
+
+const (
+	modeMask mode = iota
+	modeDrop
+	modeHash
+)
+
+var modeNames = map[string]mode{
+	"mask": modeMask,
+	"drop": modeDrop,
+	"hash": modeHash,
+}
+
+// Unpack creates the mode from the given string.
+func (m *mode) Unpack(str string) error {
+	v, found := modeNames[strings.ToLower(str)]
+	if !found {
+		return fmt.Errorf("invalid redact mode '%s', must be one of mask, drop or hash", str)
+	}
+	*m = v
+	return nil
+}
+
+func (m mode) String() string {
+	for name, v := range modeNames {
+		if v == m {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+type config struct {
+	Fields []fieldConfig `config:"fields" validate:"required"`
+	Salt   string        `config:"salt"`
+	Mask   string        `config:"mask"`
+}
+
+type fieldConfig struct {
+	Field string  `config:"field" validate:"required"`
+	Mode  mode    `config:"mode"`
+	Mask  *string `config:"mask"`
+}
+
+func defaultConfig() config {
+	return config{
+		Mask: "[REDACTED]",
+	}
+}
+
+// Validate checks a salt is configured if a field is hashed.
+func (c *config) Validate() error {
+	for _, f := range c.Fields {
+		if f.Mode == modeHash && c.Salt == "" {
+			return errors.New("a salt is required to hash fields")
+		}
+	}
+	return nil
+}
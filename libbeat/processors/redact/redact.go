@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type redactProcessor struct {
+	fields []fieldConfig
+	mask   string
+	salt   []byte
+}
+
+func init() {
+	processors.RegisterPlugin("redact",
+		checks.ConfigChecked(New,
+			checks.RequireFields("fields"),
+			checks.AllowedFields("fields", "salt", "mask", "when")))
+}
+
+// New builds a new redact processor.
+func New(c *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack the redact configuration: %w", err)
+	}
+	return &redactProcessor{
+		fields: config.Fields,
+		mask:   config.Mask,
+		salt:   []byte(config.Salt),
+	}, nil
+}
+
+// Run redacts the configured fields. Fields missing from the event are
+// ignored. Fields that can not be hashed are removed from the event.
+func (p *redactProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	var errs []error
+	for _, f := range p.fields {
+		v, err := event.GetValue(f.Field)
+		if err != nil {
+			if !errors.Is(err, mapstr.ErrKeyNotFound) {
+				errs = append(errs, fmt.Errorf("failed to get field %s: %w", f.Field, err))
+			}
+			continue
+		}
+
+		switch f.Mode {
+		case modeDrop:
+			err = event.Delete(f.Field)
+		case modeMask:
+			mask := p.mask
+			if f.Mask != nil {
+				mask = *f.Mask
+			}
+			_, err = event.PutValue(f.Field, mask)
+		case modeHash:
+			var hash string
+			hash, err = p.hash(v)
+			if err == nil {
+				_, err = event.PutValue(f.Field, hash)
+			}
+		}
+		if err != nil {
+			// Never let a value that failed to be redacted through.
+			_ = event.Delete(f.Field)
+			errs = append(errs, fmt.Errorf("failed to redact field %s, the field has been removed: %w", f.Field, err))
+		}
+	}
+	return event, errors.Join(errs...)
+}
+
+// hash returns the hex encoded HMAC-SHA256 of the value keyed by the salt.
+// Strings are hashed as is, any other value is JSON encoded first, so equal
+// values always result in the same hash.
+func (p *redactProcessor) hash(v interface{}) (string, error) {
+	var data []byte
+	if s, ok := v.(string); ok {
+		data = []byte(s)
+	} else {
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return "", err
+		}
+	}
+
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// String does not include the salt, so it is never logged.
+func (p *redactProcessor) String() string {
+	fields := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		fields[i] = f.Field + ":" + f.Mode.String()
+	}
+	return fmt.Sprintf("redact={fields=[%s]}", strings.Join(fields, ", "))
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestRedact(t *testing.T) {
+	hash := func(s string) string {
+		mac := hmac.New(sha256.New, []byte("pepper"))
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := map[string]struct {
+		config   mapstr.M
+		input    mapstr.M
+		expected mapstr.M
+		err      bool
+	}{
+		"mask by default": {
+			config: mapstr.M{
+				"fields": []mapstr.M{{"field": "user.email"}},
+			},
+			input:    mapstr.M{"user": mapstr.M{"email": "jane@example.com", "id": 1}},
+			expected: mapstr.M{"user": mapstr.M{"email": "[REDACTED]", "id": 1}},
+		},
+		"custom masks": {
+			config: mapstr.M{
+				"mask": "***",
+				"fields": []mapstr.M{
+					{"field": "user.email", "mode": "mask"},
+					{"field": "user.name", "mode": "mask", "mask": "anonymous"},
+				},
+			},
+			input:    mapstr.M{"user": mapstr.M{"email": "jane@example.com", "name": "jane"}},
+			expected: mapstr.M{"user": mapstr.M{"email": "***", "name": "anonymous"}},
+		},
+		"drop": {
+			config: mapstr.M{
+				"fields": []mapstr.M{{"field": "user.password", "mode": "drop"}},
+			},
+			input:    mapstr.M{"user": mapstr.M{"name": "jane", "password": "secret"}},
+			expected: mapstr.M{"user": mapstr.M{"name": "jane"}},
+		},
+		"hash": {
+			config: mapstr.M{
+				"salt":   "pepper",
+				"fields": []mapstr.M{{"field": "user.email", "mode": "hash"}},
+			},
+			input:    mapstr.M{"user": mapstr.M{"email": "jane@example.com"}},
+			expected: mapstr.M{"user": mapstr.M{"email": hash("jane@example.com")}},
+		},
+		"hash non string values": {
+			config: mapstr.M{
+				"salt": "pepper",
+				"fields": []mapstr.M{
+					{"field": "user.id", "mode": "hash"},
+					{"field": "user.roles", "mode": "hash"},
+				},
+			},
+			input: mapstr.M{"user": mapstr.M{"id": 42, "roles": []string{"admin"}}},
+			expected: mapstr.M{"user": mapstr.M{
+				"id":    hash("42"),
+				"roles": hash(`["admin"]`),
+			}},
+		},
+		"missing fields are ignored": {
+			config: mapstr.M{
+				"fields": []mapstr.M{
+					{"field": "user.email"},
+					{"field": "source.ip", "mode": "drop"},
+				},
+			},
+			input:    mapstr.M{"message": "hello"},
+			expected: mapstr.M{"message": "hello"},
+		},
+		"field that can not be hashed is removed": {
+			config: mapstr.M{
+				"salt":   "pepper",
+				"fields": []mapstr.M{{"field": "user.token", "mode": "hash"}},
+			},
+			input:    mapstr.M{"user": mapstr.M{"name": "jane", "token": func() {}}},
+			expected: mapstr.M{"user": mapstr.M{"name": "jane"}},
+			err:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(conf.MustNewConfigFrom(test.config))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: test.input})
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expected, event.Fields)
+		})
+	}
+}
+
+func TestRedactHashIsJoinable(t *testing.T) {
+	newProcessor := func(salt string) beat.Processor {
+		p, err := New(conf.MustNewConfigFrom(mapstr.M{
+			"salt":   salt,
+			"fields": []mapstr.M{{"field": "email", "mode": "hash"}},
+		}))
+		require.NoError(t, err)
+		return p
+	}
+	redact := func(p beat.Processor, email string) interface{} {
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{"email": email}})
+		require.NoError(t, err)
+		return event.Fields["email"]
+	}
+
+	p := newProcessor("salt")
+	assert.Equal(t, redact(p, "jane@example.com"), redact(p, "jane@example.com"))
+	assert.NotEqual(t, redact(p, "jane@example.com"), redact(p, "john@example.com"))
+	assert.NotEqual(t, redact(p, "jane@example.com"), redact(newProcessor("other"), "jane@example.com"))
+}
+
+func TestRedactConfig(t *testing.T) {
+	tests := map[string]mapstr.M{
+		"no fields": {},
+		"hash without salt": {
+			"fields": []mapstr.M{{"field": "user.email", "mode": "hash"}},
+		},
+		"invalid mode": {
+			"fields": []mapstr.M{{"field": "user.email", "mode": "encrypt"}},
+		},
+		"field without name": {
+			"fields": []mapstr.M{{"mode": "drop"}},
+		},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(config))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRedactStringOmitsSalt(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{
+		"salt":   "very-secret-salt",
+		"fields": []mapstr.M{{"field": "user.email", "mode": "hash"}},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "redact={fields=[user.email:hash]}", p.String())
+}
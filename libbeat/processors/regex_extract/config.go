@@ -0,0 +1,52 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package regex_extract
+
+import "fmt"
+
+type config struct {
+	Field         string   `config:"field"`          // Source field the pattern is applied to.
+	Pattern       string   `config:"pattern"`        // Regular expression with named capture groups.
+	Target        string   `config:"target"`         // Field the groups are written to. Defaults to the event root.
+	OverwriteKeys bool     `config:"overwrite_keys"` // Overwrite fields already present in the event.
+	IgnoreMissing bool     `config:"ignore_missing"` // Ignore errors when the source field is missing.
+	IgnoreFailure bool     `config:"ignore_failure"` // Ignore errors when the source field can not be processed.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when the pattern does not match.
+}
+
+func defaultConfig() config {
+	return config{
+		TagOnFailure: []string{"_regex_extract_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	re, err := compile(c.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", c.Pattern, err)
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("pattern '%s' has no named capture groups", c.Pattern)
+}
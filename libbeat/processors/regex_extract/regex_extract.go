@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package regex_extract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "regex_extract"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("field", "pattern"),
+			checks.AllowedFields("field", "pattern", "target", "overwrite_keys",
+				"ignore_missing", "ignore_failure", "tag_on_failure", "when")))
+}
+
+// patterns caches the compiled patterns, such that processors configured with
+// the same pattern, for example one per input, share the compiled regexp.
+var patterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: map[string]*regexp.Regexp{}}
+
+// compile returns the compiled pattern, compiling it on first use.
+func compile(pattern string) (*regexp.Regexp, error) {
+	patterns.Lock()
+	defer patterns.Unlock()
+	if re, ok := patterns.compiled[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.compiled[pattern] = re
+	return re, nil
+}
+
+type regexExtract struct {
+	config
+	re  *regexp.Regexp
+	log *logp.Logger
+}
+
+// New constructs a new regex_extract processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	re, err := compile(config.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %v pattern: %w", processorName, err)
+	}
+
+	return &regexExtract{
+		config: config,
+		re:     re,
+		log:    logp.NewLogger(logName),
+	}, nil
+}
+
+// Run applies the pattern to the source field and writes each named group
+// that participated in the match to a field of the same name. Events not
+// matching the pattern are tagged and returned unchanged.
+func (p *regexExtract) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		if p.IgnoreMissing && errors.Is(err, mapstr.ErrKeyNotFound) {
+			return event, nil
+		}
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.Field, err)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		p.tag(event)
+		return p.failure(event, fmt.Errorf("field %s is not of string type", p.Field))
+	}
+
+	match := p.re.FindStringSubmatchIndex(s)
+	if match == nil {
+		p.tag(event)
+		return event, nil
+	}
+
+	for i, name := range p.re.SubexpNames() {
+		if name == "" || match[2*i] < 0 {
+			continue
+		}
+		field := name
+		if p.Target != "" {
+			field = p.Target + "." + name
+		}
+		if !p.OverwriteKeys {
+			if exists, _ := event.Fields.HasKey(field); exists {
+				continue
+			}
+		}
+		if _, err := event.PutValue(field, s[match[2*i]:match[2*i+1]]); err != nil {
+			p.tag(event)
+			return p.failure(event, fmt.Errorf("failed to set field %s: %w", field, err))
+		}
+	}
+	return event, nil
+}
+
+// tag adds the failure tags to the event.
+func (p *regexExtract) tag(event *beat.Event) {
+	if len(p.TagOnFailure) == 0 {
+		return
+	}
+	if err := mapstr.AddTags(event.Fields, p.TagOnFailure); err != nil {
+		p.log.Debugw("Failed to add failure tags.", "error", err)
+	}
+}
+
+// failure returns err, unless failures are ignored.
+func (p *regexExtract) failure(event *beat.Event, err error) (*beat.Event, error) {
+	if p.IgnoreFailure {
+		return event, nil
+	}
+	return event, err
+}
+
+func (p *regexExtract) String() string {
+	return fmt.Sprintf("%v=[field=%v, pattern=%v, target=%v]",
+		processorName, p.Field, p.Pattern, p.Target)
+}
@@ -0,0 +1,146 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package regex_extract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const testPattern = `^(?P<client>\S+) (?P<method>[A-Z]+) (?P<path>\S+)(?: (?P<status>\d{3}))?`
+
+func TestRegexExtract(t *testing.T) {
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    mapstr.M
+		wantErr bool
+	}{
+		"groups to root": {
+			fields: mapstr.M{"message": "10.0.0.1 GET /index.html 200"},
+			want: mapstr.M{
+				"message": "10.0.0.1 GET /index.html 200",
+				"client":  "10.0.0.1",
+				"method":  "GET",
+				"path":    "/index.html",
+				"status":  "200",
+			},
+		},
+		"groups to target": {
+			config: mapstr.M{"target": "http"},
+			fields: mapstr.M{"message": "10.0.0.1 GET /index.html"},
+			want: mapstr.M{
+				"message": "10.0.0.1 GET /index.html",
+				"http":    mapstr.M{"client": "10.0.0.1", "method": "GET", "path": "/index.html"},
+			},
+		},
+		"unnamed groups ignored": {
+			config: mapstr.M{"pattern": `user=(?P<user_name>\w+) (\d+)`},
+			fields: mapstr.M{"message": "user=alice 42"},
+			want:   mapstr.M{"message": "user=alice 42", "user_name": "alice"},
+		},
+		"no match is tagged": {
+			fields: mapstr.M{"message": "garbage"},
+			want:   mapstr.M{"message": "garbage", "tags": []string{"_regex_extract_failure"}},
+		},
+		"no match with custom tags": {
+			config: mapstr.M{"tag_on_failure": []string{"unparsed"}},
+			fields: mapstr.M{"message": "garbage"},
+			want:   mapstr.M{"message": "garbage", "tags": []string{"unparsed"}},
+		},
+		"existing fields kept": {
+			fields: mapstr.M{"message": "10.0.0.1 GET /", "method": "POST"},
+			want:   mapstr.M{"message": "10.0.0.1 GET /", "method": "POST", "client": "10.0.0.1", "path": "/"},
+		},
+		"existing fields overwritten": {
+			config: mapstr.M{"overwrite_keys": true},
+			fields: mapstr.M{"message": "10.0.0.1 GET /", "method": "POST"},
+			want:   mapstr.M{"message": "10.0.0.1 GET /", "method": "GET", "client": "10.0.0.1", "path": "/"},
+		},
+		"not a string": {
+			fields:  mapstr.M{"message": 42},
+			want:    mapstr.M{"message": 42, "tags": []string{"_regex_extract_failure"}},
+			wantErr: true,
+		},
+		"ignored failure": {
+			config: mapstr.M{"ignore_failure": true},
+			fields: mapstr.M{"message": 42},
+			want:   mapstr.M{"message": 42, "tags": []string{"_regex_extract_failure"}},
+		},
+		"missing field": {
+			fields:  mapstr.M{},
+			want:    mapstr.M{},
+			wantErr: true,
+		},
+		"ignored missing field": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{},
+			want:   mapstr.M{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := mapstr.M{"field": "message", "pattern": testPattern}
+			cfg.Update(c.config)
+			p, err := New(conf.MustNewConfigFrom(cfg))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: c.fields})
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.want, event.Fields)
+		})
+	}
+}
+
+func TestRegexExtractPatternCache(t *testing.T) {
+	cfg := conf.MustNewConfigFrom(mapstr.M{"field": "message", "pattern": testPattern})
+	p1, err := New(cfg)
+	require.NoError(t, err)
+	p2, err := New(cfg)
+	require.NoError(t, err)
+	assert.Same(t, p1.(*regexExtract).re, p2.(*regexExtract).re)
+}
+
+func TestRegexExtractConfig(t *testing.T) {
+	cases := map[string]struct {
+		config mapstr.M
+		err    string
+	}{
+		"missing pattern": {config: mapstr.M{"field": "message"}, err: "no named capture groups"},
+		"invalid pattern": {config: mapstr.M{"field": "message", "pattern": `(?P<a>`}, err: "invalid pattern"},
+		"no named groups": {config: mapstr.M{"field": "message", "pattern": `(\d+)`}, err: "no named capture groups"},
+		"empty field":     {config: mapstr.M{"field": "", "pattern": `(?P<a>\d+)`}, err: "field must not be empty"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(c.config))
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
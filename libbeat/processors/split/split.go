@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package split
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+)
+
+type config struct {
+	Field       string `config:"field" validate:"required"`
+	TargetField string `config:"target_field"`
+}
+
+type splitProcessor struct {
+	config
+}
+
+var errSplitNotSupported = errors.New("split processor can only split events when run by the publisher pipeline")
+
+func init() {
+	processors.RegisterPlugin("split",
+		checks.ConfigChecked(New,
+			checks.RequireFields("field"),
+			checks.AllowedFields("field", "target_field", "when")))
+}
+
+// New builds a new split processor.
+func New(c *conf.C) (beat.Processor, error) {
+	var config config
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack the split configuration: %w", err)
+	}
+	if config.TargetField == "" {
+		config.TargetField = config.Field
+	}
+	return &splitProcessor{config: config}, nil
+}
+
+// Run can not return multiple events. The event is returned unchanged
+// together with an error if it would be split.
+func (p *splitProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	if _, ok := p.array(event); ok {
+		return event, errSplitNotSupported
+	}
+	return event, nil
+}
+
+// RunSplit replaces the event with one event per element of the array in
+// field. Events without the field or with a field that is not a non-empty
+// array are returned unchanged.
+func (p *splitProcessor) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	array, ok := p.array(event)
+	if !ok {
+		return []*beat.Event{event}, nil
+	}
+
+	// Remove the array, so it is not copied into every event.
+	if err := event.Delete(p.config.Field); err != nil {
+		return []*beat.Event{event}, fmt.Errorf("failed to remove field %s: %w", p.config.Field, err)
+	}
+
+	events := make([]*beat.Event, 0, array.Len())
+	for i := 0; i < array.Len(); i++ {
+		child := event.Clone()
+		if _, err := child.PutValue(p.config.TargetField, array.Index(i).Interface()); err != nil {
+			// restore the original event
+			_, _ = event.PutValue(p.config.Field, array.Interface())
+			return []*beat.Event{event}, fmt.Errorf("failed to set field %s: %w", p.config.TargetField, err)
+		}
+		events = append(events, child)
+	}
+	return events, nil
+}
+
+// array returns the array to split. ok is false if the field is missing, is
+// not an array or is empty.
+func (p *splitProcessor) array(event *beat.Event) (array reflect.Value, ok bool) {
+	v, err := event.GetValue(p.config.Field)
+	if err != nil || v == nil {
+		return reflect.Value{}, false
+	}
+	array = reflect.ValueOf(v)
+	if array.Kind() != reflect.Slice || array.Len() == 0 {
+		return reflect.Value{}, false
+	}
+	return array, true
+}
+
+func (p *splitProcessor) String() string {
+	return fmt.Sprintf("split={field=%s, target_field=%s}", p.config.Field, p.config.TargetField)
+}
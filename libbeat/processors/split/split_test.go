@@ -0,0 +1,163 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package split
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestSplit(t *testing.T) {
+	tests := map[string]struct {
+		config   mapstr.M
+		input    mapstr.M
+		expected []mapstr.M
+	}{
+		"array of objects": {
+			config: mapstr.M{"field": "records"},
+			input: mapstr.M{
+				"message": "batch",
+				"records": []interface{}{
+					mapstr.M{"id": 1},
+					mapstr.M{"id": 2},
+				},
+			},
+			expected: []mapstr.M{
+				{"message": "batch", "records": mapstr.M{"id": 1}},
+				{"message": "batch", "records": mapstr.M{"id": 2}},
+			},
+		},
+		"target field": {
+			config: mapstr.M{"field": "batch.records", "target_field": "record"},
+			input: mapstr.M{
+				"batch": mapstr.M{"id": "x", "records": []string{"a", "b"}},
+			},
+			expected: []mapstr.M{
+				{"batch": mapstr.M{"id": "x"}, "record": "a"},
+				{"batch": mapstr.M{"id": "x"}, "record": "b"},
+			},
+		},
+		"single element": {
+			config:   mapstr.M{"field": "records"},
+			input:    mapstr.M{"records": []interface{}{"a"}},
+			expected: []mapstr.M{{"records": "a"}},
+		},
+		"missing field": {
+			config:   mapstr.M{"field": "records"},
+			input:    mapstr.M{"message": "hello"},
+			expected: []mapstr.M{{"message": "hello"}},
+		},
+		"not an array": {
+			config:   mapstr.M{"field": "records"},
+			input:    mapstr.M{"records": "a"},
+			expected: []mapstr.M{{"records": "a"}},
+		},
+		"empty array": {
+			config:   mapstr.M{"field": "records"},
+			input:    mapstr.M{"records": []interface{}{}},
+			expected: []mapstr.M{{"records": []interface{}{}}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(conf.MustNewConfigFrom(test.config))
+			require.NoError(t, err)
+
+			events, err := processors.RunSplit(p, &beat.Event{Fields: test.input})
+			require.NoError(t, err)
+
+			var actual []mapstr.M
+			for _, event := range events {
+				actual = append(actual, event.Fields)
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSplitMetadata(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"field": "records"}))
+	require.NoError(t, err)
+
+	events, err := processors.RunSplit(p, &beat.Event{
+		Meta:   mapstr.M{"_id": "parent"},
+		Fields: mapstr.M{"records": []interface{}{1, 2}},
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// every event owns its metadata
+	events[0].Meta["_id"] = "changed"
+	assert.Equal(t, mapstr.M{"_id": "parent"}, events[1].Meta)
+}
+
+func TestSplitError(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"field": "records", "target_field": "message.text"}))
+	require.NoError(t, err)
+
+	input := mapstr.M{"message": "hello", "records": []interface{}{"a", "b"}}
+	events, err := processors.RunSplit(p, &beat.Event{Fields: input.Clone()})
+	assert.Error(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, input, events[0].Fields)
+}
+
+func TestSplitRun(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"field": "records"}))
+	require.NoError(t, err)
+
+	input := mapstr.M{"records": []interface{}{"a", "b"}}
+	event, err := p.Run(&beat.Event{Fields: input.Clone()})
+	assert.ErrorIs(t, err, errSplitNotSupported)
+	assert.Equal(t, input, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"records": "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, mapstr.M{"records": "a"}, event.Fields)
+}
+
+func TestSplitWhen(t *testing.T) {
+	c := conf.MustNewConfigFrom(mapstr.M{
+		"field": "records",
+		"when":  mapstr.M{"equals": mapstr.M{"type": "batch"}},
+	})
+	p, err := processors.NewConditional(New)(c)
+	require.NoError(t, err)
+
+	events, err := processors.RunSplit(p, &beat.Event{Fields: mapstr.M{
+		"type":    "batch",
+		"records": []interface{}{"a", "b"},
+	}})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = processors.RunSplit(p, &beat.Event{Fields: mapstr.M{
+		"type":    "single",
+		"records": []interface{}{"a", "b"},
+	}})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package split_metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type config struct {
+	Metrics    []string `config:"metrics" validate:"required"`
+	Dimensions []string `config:"dimensions"`
+	NameField  string   `config:"name_field"`
+	ValueField string   `config:"value_field"`
+}
+
+type splitMetrics struct {
+	config
+}
+
+var errSplitNotSupported = errors.New("split_metrics processor can only split events when run by the publisher pipeline")
+
+func init() {
+	processors.RegisterPlugin("split_metrics",
+		checks.ConfigChecked(New,
+			checks.RequireFields("metrics"),
+			checks.AllowedFields("metrics", "dimensions", "name_field", "value_field", "when")))
+}
+
+// New builds a new split_metrics processor.
+func New(c *conf.C) (beat.Processor, error) {
+	config := config{
+		NameField:  "metric.name",
+		ValueField: "metric.value",
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack the split_metrics configuration: %w", err)
+	}
+	if config.NameField == config.ValueField {
+		return nil, fmt.Errorf("split_metrics name_field and value_field must be different, both are %s", config.NameField)
+	}
+	return &splitMetrics{config: config}, nil
+}
+
+// Run can not return multiple events. An event with a single metric is
+// replaced, an event with more metrics is returned unchanged together with
+// an error.
+func (p *splitMetrics) Run(event *beat.Event) (*beat.Event, error) {
+	events, err := p.RunSplit(event)
+	if err != nil {
+		return event, err
+	}
+	if len(events) > 1 {
+		return event, errSplitNotSupported
+	}
+	return events[0], nil
+}
+
+// RunSplit replaces the event with one event per metric field present. Each
+// event created holds the name and the value of its metric, together with the
+// dimensions, the timestamp and the metadata of the original event. Events
+// without any of the metrics are returned unchanged.
+func (p *splitMetrics) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	var events []*beat.Event
+	for _, metric := range p.config.Metrics {
+		value, err := event.GetValue(metric)
+		if err != nil {
+			// Missing metrics are skipped
+			continue
+		}
+
+		child, err := p.newEvent(event)
+		if err != nil {
+			return []*beat.Event{event}, err
+		}
+		if _, err := child.PutValue(p.config.NameField, metric); err != nil {
+			return []*beat.Event{event}, fmt.Errorf("failed to set field %s: %w", p.config.NameField, err)
+		}
+		if _, err := child.PutValue(p.config.ValueField, cloneValue(value)); err != nil {
+			return []*beat.Event{event}, fmt.Errorf("failed to set field %s: %w", p.config.ValueField, err)
+		}
+		events = append(events, child)
+	}
+
+	if len(events) == 0 {
+		return []*beat.Event{event}, nil
+	}
+	return events, nil
+}
+
+// newEvent creates an event with the dimensions of event.
+func (p *splitMetrics) newEvent(event *beat.Event) (*beat.Event, error) {
+	child := &beat.Event{
+		Timestamp:  event.Timestamp,
+		Meta:       event.Meta.Clone(),
+		Fields:     mapstr.M{},
+		Private:    event.Private,
+		TimeSeries: event.TimeSeries,
+	}
+	for _, dimension := range p.config.Dimensions {
+		value, err := event.GetValue(dimension)
+		if err != nil {
+			// Missing dimensions are left out
+			continue
+		}
+		if _, err := child.PutValue(dimension, cloneValue(value)); err != nil {
+			return nil, fmt.Errorf("failed to set field %s: %w", dimension, err)
+		}
+	}
+	return child, nil
+}
+
+// cloneValue copies objects, so the events created don't share them.
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case mapstr.M:
+		return v.Clone()
+	case map[string]interface{}:
+		return mapstr.M(v).Clone()
+	}
+	return value
+}
+
+func (p *splitMetrics) String() string {
+	return fmt.Sprintf("split_metrics={metrics=%s, dimensions=%s, name_field=%s, value_field=%s}",
+		strings.Join(p.config.Metrics, ","), strings.Join(p.config.Dimensions, ","),
+		p.config.NameField, p.config.ValueField)
+}
@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package split_metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestSplitMetrics(t *testing.T) {
+	tests := map[string]struct {
+		config   mapstr.M
+		input    mapstr.M
+		expected []mapstr.M
+	}{
+		"metrics with dimensions": {
+			config: mapstr.M{
+				"metrics":    []string{"system.cpu.pct", "system.memory.pct"},
+				"dimensions": []string{"host.name", "service.type"},
+			},
+			input: mapstr.M{
+				"host":    mapstr.M{"name": "a", "ip": "10.0.0.1"},
+				"service": mapstr.M{"type": "system"},
+				"system":  mapstr.M{"cpu": mapstr.M{"pct": 0.5}, "memory": mapstr.M{"pct": 0.25}},
+			},
+			expected: []mapstr.M{
+				{
+					"host":    mapstr.M{"name": "a"},
+					"service": mapstr.M{"type": "system"},
+					"metric":  mapstr.M{"name": "system.cpu.pct", "value": 0.5},
+				},
+				{
+					"host":    mapstr.M{"name": "a"},
+					"service": mapstr.M{"type": "system"},
+					"metric":  mapstr.M{"name": "system.memory.pct", "value": 0.25},
+				},
+			},
+		},
+		"custom name and value fields": {
+			config: mapstr.M{
+				"metrics":     []string{"rx", "tx"},
+				"name_field":  "name",
+				"value_field": "value",
+			},
+			input: mapstr.M{"rx": 1, "tx": 2},
+			expected: []mapstr.M{
+				{"name": "rx", "value": 1},
+				{"name": "tx", "value": 2},
+			},
+		},
+		"missing metrics and dimensions are skipped": {
+			config: mapstr.M{
+				"metrics":    []string{"rx", "tx", "errors"},
+				"dimensions": []string{"interface", "vlan"},
+			},
+			input: mapstr.M{"interface": "eth0", "rx": 1, "errors": 0},
+			expected: []mapstr.M{
+				{"interface": "eth0", "metric": mapstr.M{"name": "rx", "value": 1}},
+				{"interface": "eth0", "metric": mapstr.M{"name": "errors", "value": 0}},
+			},
+		},
+		"no metrics": {
+			config:   mapstr.M{"metrics": []string{"rx", "tx"}},
+			input:    mapstr.M{"message": "hello"},
+			expected: []mapstr.M{{"message": "hello"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(conf.MustNewConfigFrom(test.config))
+			require.NoError(t, err)
+
+			events, err := processors.RunSplit(p, &beat.Event{Fields: test.input})
+			require.NoError(t, err)
+
+			var actual []mapstr.M
+			for _, event := range events {
+				actual = append(actual, event.Fields)
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSplitMetricsKeepsEventData(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{
+		"metrics":    []string{"rx", "tx"},
+		"dimensions": []string{"labels"},
+	}))
+	require.NoError(t, err)
+
+	ts := time.Now()
+	events, err := processors.RunSplit(p, &beat.Event{
+		Timestamp: ts,
+		Meta:      mapstr.M{"_id": "parent"},
+		Fields:    mapstr.M{"labels": mapstr.M{"env": "prod"}, "rx": 1, "tx": 2},
+		Private:   "state",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	for _, event := range events {
+		assert.Equal(t, ts, event.Timestamp)
+		assert.Equal(t, "state", event.Private)
+	}
+
+	// every event owns its metadata and dimensions
+	events[0].Meta["_id"] = "changed"
+	_, _ = events[0].PutValue("labels.env", "changed")
+	assert.Equal(t, mapstr.M{"_id": "parent"}, events[1].Meta)
+	assert.Equal(t, mapstr.M{"env": "prod"}, events[1].Fields["labels"])
+}
+
+func TestSplitMetricsRun(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{"metrics": []string{"rx", "tx"}}))
+	require.NoError(t, err)
+
+	input := mapstr.M{"rx": 1, "tx": 2}
+	event, err := p.Run(&beat.Event{Fields: input.Clone()})
+	assert.ErrorIs(t, err, errSplitNotSupported)
+	assert.Equal(t, input, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{"rx": 1, "message": "hello"}})
+	assert.NoError(t, err)
+	assert.Equal(t, mapstr.M{"metric": mapstr.M{"name": "rx", "value": 1}}, event.Fields)
+}
+
+func TestSplitMetricsConfig(t *testing.T) {
+	_, err := New(conf.MustNewConfigFrom(mapstr.M{}))
+	assert.Error(t, err)
+
+	_, err = New(conf.MustNewConfigFrom(mapstr.M{
+		"metrics":     []string{"rx"},
+		"name_field":  "metric",
+		"value_field": "metric",
+	}))
+	assert.Error(t, err)
+}
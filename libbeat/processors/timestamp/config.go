@@ -28,6 +28,9 @@ type config struct {
 	IgnoreFailure  bool              `config:"ignore_failure"`              // Ignore errors when parsing the timestamp.
 	TestTimestamps []string          `config:"test"`                        // A list of timestamps that must parse successfully when loading the processor.
 	ID             string            `config:"id"`                          // An identifier for this processor. Useful for debugging.
+	LayoutField    string            `config:"layout_field"`                // Field to record the layout that parsed the time value.
+	TagOnFailure   []string          `config:"tag_on_failure"`              // Tags to append when the time value cannot be parsed.
+	RawField       string            `config:"raw_field"`                   // Field to retain the raw time value in when it cannot be parsed.
 }
 
 func defaultConfig() config {
@@ -69,7 +69,7 @@ func newFromConfig(c config) (*processor, error) {
 
 	// Execute user provided built-in tests.
 	for _, test := range c.TestTimestamps {
-		ts, err := p.parseValue(test)
+		ts, _, err := p.parseValue(test)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse test timestamp: %w", err)
 		}
@@ -95,8 +95,9 @@ func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
 	}
 
 	// Try to convert the value to a time.Time.
-	ts, err := p.tryToTime(val)
+	ts, layout, err := p.tryToTime(val)
 	if err != nil {
+		p.markFailure(event, val)
 		if p.IgnoreFailure {
 			return event, nil
 		}
@@ -112,27 +113,53 @@ func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
 		return event, err
 	}
 
+	if p.LayoutField != "" && layout != "" {
+		_, err = event.PutValue(p.LayoutField, layout)
+		if err != nil && !p.IgnoreFailure {
+			return event, err
+		}
+	}
+
 	return event, nil
 }
 
-func (p *processor) tryToTime(value interface{}) (time.Time, error) {
+// markFailure tags the event and retains the raw time value as configured
+// when the value could not be parsed.
+func (p *processor) markFailure(event *beat.Event, val interface{}) {
+	if len(p.TagOnFailure) > 0 {
+		if err := mapstr.AddTags(event.Fields, p.TagOnFailure); err != nil {
+			p.log.Debugw("Failed to add failure tags.", "error", err)
+		}
+	}
+	if p.RawField != "" {
+		if _, err := event.PutValue(p.RawField, val); err != nil {
+			p.log.Debugw("Failed to retain raw time value.", "error", err)
+		}
+	}
+}
+
+// tryToTime converts value to a time.Time. The layout that parsed the value is
+// returned as well, it is empty if value already was a time.
+func (p *processor) tryToTime(value interface{}) (time.Time, string, error) {
 	switch v := value.(type) {
 	case time.Time:
-		return v, nil
+		return v, "", nil
 	case common.Time:
-		return time.Time(v), nil
+		return time.Time(v), "", nil
 	default:
 		return p.parseValue(v)
 	}
 }
 
-func (p *processor) parseValue(v interface{}) (time.Time, error) {
+// parseValue parses v using the first layout that succeeds, in the configured
+// order, and returns the time together with the matching layout.
+func (p *processor) parseValue(v interface{}) (time.Time, string, error) {
 	detailedErr := &parseError{}
 
 	for _, layout := range p.Layouts {
 		ts, err := p.parseValueByLayout(v, layout)
 		if err == nil {
-			return ts, nil
+			return ts, layout, nil
 		}
 		var parseError *time.ParseError
 		if errors.As(err, &parseError) {
@@ -152,7 +179,7 @@ func (p *processor) parseValue(v interface{}) (time.Time, error) {
 			p.log.Debugw("Failure parsing time field.", "error", detailedErr)
 		}
 	}
-	return time.Time{}, detailedErr
+	return time.Time{}, "", detailedErr
 }
 
 func (p *processor) parseValueByLayout(v interface{}, layout string) (time.Time, error) {
@@ -189,6 +189,66 @@ func TestIgnoreFailure(t *testing.T) {
 	assert.Zero(t, evt.Timestamp)
 }
 
+func TestLayoutField(t *testing.T) {
+	c := defaultConfig()
+	c.Field = "ts"
+	c.Layouts = []string{time.RFC3339, time.Kitchen, "UNIX"}
+	c.LayoutField = "ts_layout"
+
+	p, err := newFromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, layout := range []string{time.RFC3339, time.Kitchen} {
+		evt := &beat.Event{Fields: mapstr.M{"ts": expected.Format(layout)}}
+		_, err = p.Run(evt)
+		if assert.NoError(t, err) {
+			assert.Equal(t, layout, evt.Fields["ts_layout"])
+		}
+	}
+
+	evt := &beat.Event{Fields: mapstr.M{"ts": expected.Unix()}}
+	_, err = p.Run(evt)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "UNIX", evt.Fields["ts_layout"])
+	}
+
+	// Values that already are times don't record a layout.
+	evt = &beat.Event{Fields: mapstr.M{"ts": expected}}
+	_, err = p.Run(evt)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, evt.Fields, "ts_layout")
+	}
+}
+
+func TestTagOnFailure(t *testing.T) {
+	c := defaultConfig()
+	c.Field = "ts"
+	c.Layouts = []string{time.RFC3339}
+	c.TagOnFailure = []string{"_timestamp_parse_failure"}
+	c.RawField = "ts_raw"
+	c.IgnoreFailure = true
+
+	p, err := newFromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := &beat.Event{Fields: mapstr.M{"ts": "not a time"}}
+	_, err = p.Run(evt)
+	assert.NoError(t, err)
+	assert.Zero(t, evt.Timestamp)
+	assert.Equal(t, []string{"_timestamp_parse_failure"}, evt.Fields["tags"])
+	assert.Equal(t, "not a time", evt.Fields["ts_raw"])
+
+	evt = &beat.Event{Fields: mapstr.M{"ts": expected.Format(time.RFC3339)}}
+	_, err = p.Run(evt)
+	assert.NoError(t, err)
+	assert.NotContains(t, evt.Fields, "tags")
+	assert.NotContains(t, evt.Fields, "ts_raw")
+}
+
 func TestBuiltInTest(t *testing.T) {
 	c := defaultConfig()
 	c.Field = "ts"
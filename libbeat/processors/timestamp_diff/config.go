@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package timestamp_diff
+
+import (
+	"fmt"
+	"time"
+)
+
+// units maps the supported units of the difference and of numeric epoch
+// values to their duration.
+var units = map[string]time.Duration{
+	"nanoseconds":  time.Nanosecond,
+	"microseconds": time.Microsecond,
+	"milliseconds": time.Millisecond,
+	"seconds":      time.Second,
+	"minutes":      time.Minute,
+	"hours":        time.Hour,
+}
+
+type config struct {
+	StartField    string   `config:"start_field"`    // Field containing the start timestamp.
+	EndField      string   `config:"end_field"`      // Field containing the end timestamp.
+	TargetField   string   `config:"target_field"`   // Field the difference is written to.
+	Unit          string   `config:"unit"`           // Unit of the difference.
+	Layouts       []string `config:"layouts"`        // Layouts used to parse string timestamps.
+	EpochUnit     string   `config:"epoch_unit"`     // Unit of numeric epoch timestamps.
+	IgnoreMissing bool     `config:"ignore_missing"` // Ignore events missing one of the timestamp fields.
+	IgnoreFailure bool     `config:"ignore_failure"` // Ignore errors when parsing the timestamps.
+	TagOnFailure  []string `config:"tag_on_failure"` // Tags to append when a timestamp is missing or cannot be parsed.
+}
+
+func defaultConfig() config {
+	return config{
+		TargetField:  "event.duration",
+		Unit:         "nanoseconds",
+		Layouts:      []string{time.RFC3339Nano},
+		EpochUnit:    "seconds",
+		TagOnFailure: []string{"_timestamp_diff_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.StartField == "" || c.EndField == "" {
+		return fmt.Errorf("start_field and end_field must not be empty")
+	}
+	if c.TargetField == "" {
+		return fmt.Errorf("target_field must not be empty")
+	}
+	if _, ok := units[c.Unit]; !ok {
+		return fmt.Errorf("invalid unit '%s'", c.Unit)
+	}
+	if _, ok := units[c.EpochUnit]; !ok {
+		return fmt.Errorf("invalid epoch_unit '%s'", c.EpochUnit)
+	}
+	if len(c.Layouts) == 0 {
+		return fmt.Errorf("layouts must not be empty")
+	}
+	return nil
+}
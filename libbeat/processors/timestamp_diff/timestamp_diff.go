@@ -0,0 +1,164 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package timestamp_diff
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const processorName = "timestamp_diff"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.RequireFields("start_field", "end_field"),
+			checks.AllowedFields("start_field", "end_field", "target_field", "unit", "layouts",
+				"epoch_unit", "ignore_missing", "ignore_failure", "tag_on_failure", "when")))
+}
+
+type timestampDiff struct {
+	config
+	unit      time.Duration
+	epochUnit time.Duration
+	log       *logp.Logger
+}
+
+// New constructs a new timestamp_diff processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	return &timestampDiff{
+		config:    config,
+		unit:      units[config.Unit],
+		epochUnit: units[config.EpochUnit],
+		log:       logp.NewLogger(logName),
+	}, nil
+}
+
+// Run writes the time elapsed between the start and end timestamps to the
+// target field. Nanoseconds are written as an integer, other units as a
+// float. If a timestamp is missing or can not be parsed, the event is tagged
+// and the target field is not written.
+func (p *timestampDiff) Run(event *beat.Event) (*beat.Event, error) {
+	start, err := p.timestamp(event, p.StartField)
+	if err != nil {
+		return p.failure(event, err)
+	}
+	end, err := p.timestamp(event, p.EndField)
+	if err != nil {
+		return p.failure(event, err)
+	}
+
+	d := end.Sub(start)
+	var value interface{} = d.Nanoseconds()
+	if p.unit != time.Nanosecond {
+		value = float64(d) / float64(p.unit)
+	}
+	if _, err := event.PutValue(p.TargetField, value); err != nil {
+		return p.failure(event, fmt.Errorf("failed to set field %s: %w", p.TargetField, err))
+	}
+	return event, nil
+}
+
+// errMissing is returned by timestamp for a missing field.
+var errMissing = errors.New("missing timestamp")
+
+// timestamp returns the time in field. Numeric values are epoch timestamps in
+// the configured epoch unit, strings are parsed with the configured layouts.
+func (p *timestampDiff) timestamp(event *beat.Event, field string) (time.Time, error) {
+	v, err := event.GetValue(field)
+	if err != nil {
+		if errors.Is(err, mapstr.ErrKeyNotFound) {
+			return time.Time{}, fmt.Errorf("%w in field %s", errMissing, field)
+		}
+		return time.Time{}, fmt.Errorf("could not fetch value for field %s: %w", field, err)
+	}
+
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case common.Time:
+		return time.Time(t), nil
+	case string:
+		for _, layout := range p.Layouts {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts, nil
+			}
+		}
+		// Strings not matching any layout can still hold an epoch.
+		if ts, ok := p.epoch(v); ok {
+			return ts, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to parse timestamp '%s' in field %s", t, field)
+	}
+
+	if ts, ok := p.epoch(v); ok {
+		return ts, nil
+	}
+	return time.Time{}, fmt.Errorf("unexpected type %T for timestamp field %s", v, field)
+}
+
+// epoch converts a numeric value in the configured epoch unit to a time.
+func (p *timestampDiff) epoch(v interface{}) (time.Time, bool) {
+	if n, ok := common.TryToInt(v); ok {
+		return time.Unix(0, 0).Add(time.Duration(n) * p.epochUnit), true
+	}
+	if f, ok := common.TryToFloat64(v); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		// Split the value, to keep the precision of the fractional part.
+		whole, frac := math.Modf(f)
+		return time.Unix(0, 0).Add(time.Duration(whole)*p.epochUnit + time.Duration(frac*float64(p.epochUnit))), true
+	}
+	return time.Time{}, false
+}
+
+// failure tags the event and returns err, unless failures are ignored.
+// Events missing a timestamp are not modified if missing fields are ignored.
+func (p *timestampDiff) failure(event *beat.Event, err error) (*beat.Event, error) {
+	if p.IgnoreMissing && errors.Is(err, errMissing) {
+		return event, nil
+	}
+	if len(p.TagOnFailure) > 0 {
+		if tagErr := mapstr.AddTags(event.Fields, p.TagOnFailure); tagErr != nil {
+			p.log.Debugw("Failed to add failure tags.", "error", tagErr)
+		}
+	}
+	if p.IgnoreFailure {
+		return event, nil
+	}
+	return event, err
+}
+
+func (p *timestampDiff) String() string {
+	return fmt.Sprintf("%v=[start_field=%v, end_field=%v, target_field=%v, unit=%v]",
+		processorName, p.StartField, p.EndField, p.TargetField, p.Unit)
+}
@@ -0,0 +1,161 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package timestamp_diff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestTimestampDiff(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		config  mapstr.M
+		fields  mapstr.M
+		want    interface{}
+		tagged  bool
+		wantErr bool
+	}{
+		"date strings in nanoseconds": {
+			fields: mapstr.M{"start": "2024-05-01T10:00:00Z", "end": "2024-05-01T10:00:01.5+00:00"},
+			want:   int64(1500 * time.Millisecond),
+		},
+		"times in seconds": {
+			config: mapstr.M{"unit": "seconds"},
+			fields: mapstr.M{"start": start, "end": common.Time(start.Add(90 * time.Second))},
+			want:   90.0,
+		},
+		"epoch seconds in milliseconds": {
+			config: mapstr.M{"unit": "milliseconds"},
+			fields: mapstr.M{"start": 1714557600, "end": 1714557600.25},
+			want:   250.0,
+		},
+		"epoch milliseconds": {
+			config: mapstr.M{"epoch_unit": "milliseconds", "unit": "seconds"},
+			fields: mapstr.M{"start": int64(1714557600000), "end": "1714557660000"},
+			want:   60.0,
+		},
+		"custom layouts": {
+			config: mapstr.M{"layouts": []string{time.DateTime, time.RFC3339}, "unit": "minutes"},
+			fields: mapstr.M{"start": "2024-05-01 10:00:00", "end": "2024-05-01T10:30:00Z"},
+			want:   30.0,
+		},
+		"negative difference": {
+			config: mapstr.M{"unit": "seconds"},
+			fields: mapstr.M{"start": start.Add(time.Second), "end": start},
+			want:   -1.0,
+		},
+		"unparseable value": {
+			fields:  mapstr.M{"start": "yesterday", "end": start},
+			tagged:  true,
+			wantErr: true,
+		},
+		"unsupported type": {
+			fields:  mapstr.M{"start": true, "end": start},
+			tagged:  true,
+			wantErr: true,
+		},
+		"ignored failure": {
+			config: mapstr.M{"ignore_failure": true},
+			fields: mapstr.M{"start": "yesterday", "end": start},
+			tagged: true,
+		},
+		"missing field": {
+			fields:  mapstr.M{"start": start},
+			tagged:  true,
+			wantErr: true,
+		},
+		"ignored missing field": {
+			config: mapstr.M{"ignore_missing": true},
+			fields: mapstr.M{"start": start},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := mapstr.M{"start_field": "start", "end_field": "end"}
+			cfg.Update(c.config)
+			p, err := New(conf.MustNewConfigFrom(cfg))
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: c.fields.Clone()})
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			v, err := event.GetValue("event.duration")
+			if c.want == nil {
+				assert.Error(t, err, "duration must not be set")
+			} else {
+				require.NoError(t, err)
+				assert.InDelta(t, c.want, v, 1e-9)
+				assert.IsType(t, c.want, v)
+			}
+
+			tags, _ := event.GetValue("tags")
+			if c.tagged {
+				assert.Equal(t, []string{"_timestamp_diff_failure"}, tags)
+			} else {
+				assert.Nil(t, tags)
+			}
+		})
+	}
+}
+
+func TestTimestampDiffTimestampField(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(mapstr.M{
+		"start_field":  "event.start",
+		"end_field":    "@timestamp",
+		"target_field": "took",
+		"unit":         "milliseconds",
+	}))
+	require.NoError(t, err)
+
+	now := time.Now()
+	event, err := p.Run(&beat.Event{
+		Timestamp: now,
+		Fields:    mapstr.M{"event": mapstr.M{"start": now.Add(-time.Second)}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1000.0, event.Fields["took"])
+}
+
+func TestTimestampDiffConfig(t *testing.T) {
+	cases := map[string]mapstr.M{
+		"missing end field":  {"start_field": "a"},
+		"invalid unit":       {"start_field": "a", "end_field": "b", "unit": "days"},
+		"invalid epoch unit": {"start_field": "a", "end_field": "b", "epoch_unit": "weeks"},
+	}
+	for name, cfg := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
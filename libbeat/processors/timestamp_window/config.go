@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package timestamp_window
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	actionDrop = "drop"
+	actionTag  = "tag"
+)
+
+type config struct {
+	// Field is the timestamp field checked against the window.
+	Field string `config:"field"`
+	// MaxAge is how far in the past timestamps are accepted, 0 disables the
+	// check.
+	MaxAge time.Duration `config:"max_age"`
+	// MaxFuture is how far in the future timestamps are accepted, 0
+	// disables the check.
+	MaxFuture time.Duration `config:"max_future"`
+	// Action applied to events outside of the window, drop or tag.
+	Action string `config:"action"`
+	// Tags added to events outside of the window if Action is tag.
+	Tags []string `config:"tags"`
+}
+
+func defaultConfig() config {
+	return config{
+		Field:  "@timestamp",
+		Action: actionDrop,
+		Tags:   []string{"_timestamp_out_of_window"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	if c.MaxAge < 0 || c.MaxFuture < 0 {
+		return errors.New("max_age and max_future must not be negative")
+	}
+	if c.MaxAge == 0 && c.MaxFuture == 0 {
+		return errors.New("at least one of max_age and max_future must be set")
+	}
+	switch c.Action {
+	case actionDrop:
+	case actionTag:
+		if len(c.Tags) == 0 {
+			return errors.New("tags must not be empty with action tag")
+		}
+	default:
+		return fmt.Errorf("invalid action '%s', must be one of %s or %s", c.Action, actionDrop, actionTag)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package timestamp_window
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "timestamp_window"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("field", "max_age", "max_future", "action", "tags", "when")))
+}
+
+type timestampWindow struct {
+	config config
+	clock  clockwork.Clock
+
+	log     *logp.Logger
+	dropped *monitoring.Int
+	tagged  *monitoring.Int
+}
+
+// New constructs a new timestamp_window processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &timestampWindow{
+		config:  config,
+		clock:   clockwork.NewRealClock(),
+		log:     log,
+		dropped: monitoring.NewInt(reg, "dropped"),
+		tagged:  monitoring.NewInt(reg, "tagged"),
+	}, nil
+}
+
+// Run drops or tags events whose timestamp is older than max_age or further
+// than max_future in the future.
+func (p *timestampWindow) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.config.Field)
+	if err != nil {
+		return event, fmt.Errorf("could not fetch value for field %s: %w", p.config.Field, err)
+	}
+
+	var ts time.Time
+	switch v := v.(type) {
+	case time.Time:
+		ts = v
+	case common.Time:
+		ts = time.Time(v)
+	default:
+		return event, fmt.Errorf("field %s is not a timestamp", p.config.Field)
+	}
+
+	if p.inWindow(ts) {
+		return event, nil
+	}
+
+	if p.config.Action == actionDrop {
+		p.log.Debugf("Dropping event with %s %v outside of the accepted window.", p.config.Field, ts)
+		p.dropped.Inc()
+		return nil, nil
+	}
+	p.tagged.Inc()
+	if event.Fields == nil {
+		event.Fields = mapstr.M{}
+	}
+	if err := mapstr.AddTags(event.Fields, p.config.Tags); err != nil {
+		return event, fmt.Errorf("failed to add tags: %w", err)
+	}
+	return event, nil
+}
+
+func (p *timestampWindow) inWindow(ts time.Time) bool {
+	now := p.clock.Now()
+	if p.config.MaxAge > 0 && ts.Before(now.Add(-p.config.MaxAge)) {
+		return false
+	}
+	if p.config.MaxFuture > 0 && ts.After(now.Add(p.config.MaxFuture)) {
+		return false
+	}
+	return true
+}
+
+func (p *timestampWindow) String() string {
+	return fmt.Sprintf("%v=[field=%v, max_age=%v, max_future=%v, action=%v]",
+		processorName, p.config.Field, p.config.MaxAge, p.config.MaxFuture, p.config.Action)
+}
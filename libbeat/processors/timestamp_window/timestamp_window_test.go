@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package timestamp_window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestTimestampWindowDrop(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"max_age":    "720h",
+		"max_future": "1h",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClockAt(time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC))
+	p.(*timestampWindow).clock = clock
+	now := clock.Now()
+
+	run := func(ts time.Time) *beat.Event {
+		t.Helper()
+		event, err := p.Run(&beat.Event{Timestamp: ts, Fields: mapstr.M{"message": "hello"}})
+		require.NoError(t, err)
+		return event
+	}
+
+	assert.NotNil(t, run(now))
+	assert.NotNil(t, run(now.Add(-719*time.Hour)))
+	assert.NotNil(t, run(now.Add(59*time.Minute)))
+	assert.Nil(t, run(time.Unix(0, 0)), "epoch 0 is dropped")
+	assert.Nil(t, run(now.Add(-721*time.Hour)))
+	assert.Nil(t, run(now.AddDate(10, 0, 0)), "timestamps far in the future are dropped")
+	assert.EqualValues(t, 3, p.(*timestampWindow).dropped.Get())
+	assert.EqualValues(t, 0, p.(*timestampWindow).tagged.Get())
+}
+
+func TestTimestampWindowTag(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":      "event.created",
+		"max_future": "1h",
+		"action":     "tag",
+	}))
+	require.NoError(t, err)
+	clock := clockwork.NewFakeClock()
+	p.(*timestampWindow).clock = clock
+
+	event, err := p.Run(&beat.Event{Fields: mapstr.M{
+		"event": mapstr.M{"created": common.Time(clock.Now().Add(2 * time.Hour))},
+	}})
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	tags, err := event.GetValue("tags")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_timestamp_out_of_window"}, tags)
+
+	event, err = p.Run(&beat.Event{Fields: mapstr.M{
+		"event": mapstr.M{"created": clock.Now().AddDate(-50, 0, 0)},
+	}})
+	require.NoError(t, err)
+	_, err = event.GetValue("tags")
+	assert.ErrorIs(t, err, mapstr.ErrKeyNotFound, "old events are accepted without max_age")
+	assert.EqualValues(t, 1, p.(*timestampWindow).tagged.Get())
+}
+
+func TestTimestampWindowErrors(t *testing.T) {
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"field":   "created",
+		"max_age": "1h",
+	}))
+	require.NoError(t, err)
+
+	_, err = p.Run(&beat.Event{Fields: mapstr.M{}})
+	assert.Error(t, err)
+	_, err = p.Run(&beat.Event{Fields: mapstr.M{"created": "yesterday"}})
+	assert.ErrorContains(t, err, "is not a timestamp")
+}
+
+func TestTimestampWindowConfig(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"no window":      {},
+		"negative":       {"max_age": "-1h"},
+		"invalid action": {"max_age": "1h", "action": "delete"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
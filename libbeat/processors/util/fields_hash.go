@@ -0,0 +1,54 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mitchellh/hashstructure"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// FieldsHash returns a hash of the values of fields in event, such that
+// processors keeping state per group of events can use it as the key of the
+// group. Values are compared by their string representation, missing fields
+// are hashed as empty strings. It returns 0 if fields is empty.
+func FieldsHash(event *beat.Event, fields []string) (uint64, error) {
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, err := event.GetValue(field)
+		if err != nil {
+			if !errors.Is(err, mapstr.ErrKeyNotFound) {
+				return 0, fmt.Errorf("error getting value of field '%v': %w", field, err)
+			}
+
+			value = ""
+		}
+
+		values = append(values, fmt.Sprintf("%v", value))
+	}
+
+	return hashstructure.Hash(values, nil)
+}
@@ -0,0 +1,52 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestFieldsHash(t *testing.T) {
+	fields := []string{"host.name", "service"}
+	hash := func(event mapstr.M) uint64 {
+		t.Helper()
+		h, err := FieldsHash(&beat.Event{Fields: event}, fields)
+		require.NoError(t, err)
+		return h
+	}
+
+	a := hash(mapstr.M{"host": mapstr.M{"name": "a"}, "service": "web", "message": "1"})
+	assert.Equal(t, a, hash(mapstr.M{"host": mapstr.M{"name": "a"}, "service": "web", "message": "2"}),
+		"events with the same values must have the same hash")
+	assert.NotEqual(t, a, hash(mapstr.M{"host": mapstr.M{"name": "b"}, "service": "web"}))
+	assert.Equal(t, hash(mapstr.M{"service": "web"}), hash(mapstr.M{"host": mapstr.M{"name": ""}, "service": "web"}),
+		"missing fields must be hashed as empty values")
+
+	h, err := FieldsHash(&beat.Event{Fields: mapstr.M{"service": "web"}}, nil)
+	require.NoError(t, err)
+	assert.Zero(t, h)
+
+	_, err = FieldsHash(&beat.Event{Fields: mapstr.M{"host": "a"}}, fields)
+	assert.Error(t, err, "getting a field below a value must fail")
+}
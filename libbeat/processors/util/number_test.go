@@ -0,0 +1,46 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToFloat(t *testing.T) {
+	for _, value := range []interface{}{
+		int(-2), int8(-2), int16(-2), int32(-2), int64(-2),
+		float32(-2), float64(-2), json.Number("-2"),
+	} {
+		f, ok := ToFloat(value)
+		assert.True(t, ok, "%T", value)
+		assert.Equal(t, -2.0, f, "%T", value)
+	}
+	for _, value := range []interface{}{uint(2), uint8(2), uint16(2), uint32(2), uint64(2)} {
+		f, ok := ToFloat(value)
+		assert.True(t, ok, "%T", value)
+		assert.Equal(t, 2.0, f, "%T", value)
+	}
+
+	for _, value := range []interface{}{"2", json.Number("two"), true, nil} {
+		_, ok := ToFloat(value)
+		assert.False(t, ok, "%#v", value)
+	}
+}
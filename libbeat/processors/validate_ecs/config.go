@@ -0,0 +1,114 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package validate_ecs
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const (
+	actionTag  = "tag"
+	actionDrop = "drop"
+	actionLog  = "log"
+)
+
+type config struct {
+	// Fields maps field names to their expected ECS data type.
+	Fields mapstr.M `config:"fields"`
+	// FieldsFile is a YAML file containing additional field to type mappings.
+	FieldsFile string `config:"fields_file"`
+	// OnViolation sets the action taken for events violating the schema.
+	OnViolation string `config:"on_violation"`
+	// Tag is added to events violating the schema if OnViolation is "tag".
+	Tag string `config:"tag"`
+}
+
+func defaultConfig() config {
+	return config{
+		OnViolation: actionTag,
+		Tag:         "_ecs_validation_failure",
+	}
+}
+
+func (c *config) Validate() error {
+	switch c.OnViolation {
+	case actionTag, actionDrop, actionLog:
+	default:
+		return fmt.Errorf("invalid on_violation action '%s', expected one of %s, %s or %s",
+			c.OnViolation, actionTag, actionDrop, actionLog)
+	}
+
+	if len(c.Fields) == 0 && c.FieldsFile == "" {
+		return fmt.Errorf("one of fields or fields_file must be set")
+	}
+	return nil
+}
+
+// schema loads the field to type mappings from the configured fields and
+// fields_file. Nested and dotted field names are both accepted. Mappings
+// from fields take precedence over the ones from fields_file.
+func (c *config) schema() (map[string]fieldType, error) {
+	raw := mapstr.M{}
+	if c.FieldsFile != "" {
+		contents, err := os.ReadFile(c.FieldsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fields_file: %w", err)
+		}
+		var fromFile map[string]interface{}
+		if err := yaml.Unmarshal(contents, &fromFile); err != nil {
+			return nil, fmt.Errorf("failed to parse fields_file %s: %w", c.FieldsFile, err)
+		}
+		raw.DeepUpdate(toMapStr(fromFile))
+	}
+	raw.DeepUpdate(c.Fields)
+
+	schema := make(map[string]fieldType, len(raw))
+	for field, value := range raw.Flatten() {
+		name, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("type of field '%s' must be a string, got %T", field, value)
+		}
+		typ, ok := fieldTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported type '%s' for field '%s'", name, field)
+		}
+		schema[field] = typ
+	}
+	return schema, nil
+}
+
+// toMapStr converts the untyped maps produced by the YAML decoder to mapstr.M.
+func toMapStr(in map[string]interface{}) mapstr.M {
+	out := make(mapstr.M, len(in))
+	for k, v := range in {
+		if m, ok := v.(map[interface{}]interface{}); ok {
+			nested := make(map[string]interface{}, len(m))
+			for nk, nv := range m {
+				nested[fmt.Sprint(nk)] = nv
+			}
+			v = toMapStr(nested)
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package validate_ecs
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// fieldType checks if a value can be indexed into a field of a given ECS
+// data type.
+type fieldType func(value interface{}) bool
+
+var fieldTypes = map[string]fieldType{
+	"keyword":          isString,
+	"constant_keyword": isString,
+	"wildcard":         isString,
+	"text":             isString,
+	"match_only_text":  isString,
+	"long":             isInteger,
+	"integer":          isInteger,
+	"short":            isInteger,
+	"byte":             isInteger,
+	"unsigned_long":    isUnsignedInteger,
+	"double":           isNumber,
+	"float":            isNumber,
+	"half_float":       isNumber,
+	"scaled_float":     isNumber,
+	"boolean":          isBoolean,
+	"ip":               isIP,
+	"date":             isDate,
+	"object":           isObject,
+	"flattened":        isObject,
+	"nested":           isObject,
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func isInteger(v interface{}) bool {
+	switch n := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	case float32:
+		return float64(n) == math.Trunc(float64(n))
+	case float64:
+		return n == math.Trunc(n)
+	case json.Number:
+		_, err := n.Int64()
+		return err == nil
+	}
+	return false
+}
+
+func isUnsignedInteger(v interface{}) bool {
+	switch n := v.(type) {
+	case uint, uint8, uint16, uint32, uint64:
+		return true
+	case int:
+		return n >= 0
+	case int8:
+		return n >= 0
+	case int16:
+		return n >= 0
+	case int32:
+		return n >= 0
+	case int64:
+		return n >= 0
+	}
+	f, _ := util.ToFloat(v)
+	return isInteger(v) && f >= 0
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := util.ToFloat(v)
+	return ok
+}
+
+func isBoolean(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
+func isIP(v interface{}) bool {
+	switch ip := v.(type) {
+	case net.IP:
+		return true
+	case string:
+		return net.ParseIP(ip) != nil
+	}
+	return false
+}
+
+func isDate(v interface{}) bool {
+	switch d := v.(type) {
+	case time.Time, common.Time:
+		return true
+	case string:
+		_, err := time.Parse(time.RFC3339Nano, d)
+		return err == nil
+	case int, int64, uint64, float64:
+		// epoch_millis
+		return true
+	}
+	return false
+}
+
+func isObject(v interface{}) bool {
+	switch v.(type) {
+	case mapstr.M, map[string]interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package validate_ecs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID atomic.Uint32
+
+const processorName = "validate_ecs"
+const logName = "processor." + processorName
+
+func init() {
+	processors.RegisterPlugin(processorName,
+		checks.ConfigChecked(New,
+			checks.AllowedFields("fields", "fields_file", "on_violation", "tag")))
+}
+
+type metrics struct {
+	violations *monitoring.Int
+	dropped    *monitoring.Int
+}
+
+type validateECS struct {
+	config config
+	schema map[string]fieldType
+	// fields holds the names of the validated fields in sorted order, so
+	// violations are reported in a stable order.
+	fields []string
+
+	log     *logp.Logger
+	metrics metrics
+}
+
+// New constructs a new validate_ecs processor.
+func New(cfg *conf.C) (beat.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("failed to unpack %v processor configuration: %w", processorName, err)
+	}
+
+	schema, err := config.schema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %v processor schema: %w", processorName, err)
+	}
+
+	fields := make([]string, 0, len(schema))
+	for field := range schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var (
+		id  = int(instanceID.Add(1))
+		log = logp.NewLogger(logName).With("instance_id", id)
+		reg = monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+	)
+
+	return &validateECS{
+		config: config,
+		schema: schema,
+		fields: fields,
+		log:    log,
+		metrics: metrics{
+			violations: monitoring.NewInt(reg, "violations"),
+			dropped:    monitoring.NewInt(reg, "dropped"),
+		},
+	}, nil
+}
+
+// Run checks the configured fields of the event against their expected
+// types and applies the on_violation action if any field does not match.
+func (p *validateECS) Run(event *beat.Event) (*beat.Event, error) {
+	var violations []string
+	for _, field := range p.fields {
+		value, err := event.GetValue(field)
+		if err != nil {
+			if errors.Is(err, mapstr.ErrKeyNotFound) {
+				continue
+			}
+			return event, fmt.Errorf("failed to get field '%s': %w", field, err)
+		}
+		if value == nil || matches(p.schema[field], value) {
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("%s (%T)", field, value))
+	}
+
+	if len(violations) == 0 {
+		return event, nil
+	}
+	p.metrics.violations.Add(int64(len(violations)))
+
+	switch p.config.OnViolation {
+	case actionDrop:
+		p.log.Debugf("Dropping event with ECS type violations in fields: %s", strings.Join(violations, ", "))
+		p.metrics.dropped.Inc()
+		return nil, nil
+	case actionLog:
+		p.log.Warnf("Event has ECS type violations in fields: %s", strings.Join(violations, ", "))
+	default:
+		if err := mapstr.AddTags(event.Fields, []string{p.config.Tag}); err != nil {
+			return event, fmt.Errorf("failed to add tag: %w", err)
+		}
+	}
+	return event, nil
+}
+
+// matches checks the value, or each element if the value is an array,
+// against the field type.
+func matches(typ fieldType, value interface{}) bool {
+	if list, ok := value.([]interface{}); ok {
+		for _, v := range list {
+			if v != nil && !typ(v) {
+				return false
+			}
+		}
+		return true
+	}
+	if list, ok := value.([]string); ok {
+		for _, v := range list {
+			if !typ(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return typ(value)
+}
+
+func (p *validateECS) String() string {
+	return fmt.Sprintf("%v=[fields=%v, on_violation=%v]", processorName, len(p.fields), p.config.OnViolation)
+}
@@ -0,0 +1,161 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package validate_ecs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestValidateECS(t *testing.T) {
+	fields := map[string]interface{}{
+		"http.response.status_code": "long",
+		"source": map[string]interface{}{
+			"ip": "ip",
+		},
+		"event.duration": "long",
+		"tags":           "keyword",
+	}
+
+	testCases := []struct {
+		description string
+		config      map[string]interface{}
+		input       mapstr.M
+		output      mapstr.M
+		violations  int64
+	}{
+		{
+			description: "valid event",
+			config:      map[string]interface{}{"fields": fields},
+			input: mapstr.M{
+				"http":   mapstr.M{"response": mapstr.M{"status_code": 200}},
+				"source": mapstr.M{"ip": "10.0.0.1"},
+			},
+			output: mapstr.M{
+				"http":   mapstr.M{"response": mapstr.M{"status_code": 200}},
+				"source": mapstr.M{"ip": "10.0.0.1"},
+			},
+		},
+		{
+			description: "string in integer field is tagged",
+			config:      map[string]interface{}{"fields": fields},
+			input: mapstr.M{
+				"http": mapstr.M{"response": mapstr.M{"status_code": "OK"}},
+			},
+			output: mapstr.M{
+				"http": mapstr.M{"response": mapstr.M{"status_code": "OK"}},
+				"tags": []string{"_ecs_validation_failure"},
+			},
+			violations: 1,
+		},
+		{
+			description: "custom tag",
+			config:      map[string]interface{}{"fields": fields, "tag": "invalid"},
+			input: mapstr.M{
+				"source":         mapstr.M{"ip": "localhost"},
+				"event.duration": 1.5,
+			},
+			output: mapstr.M{
+				"source":         mapstr.M{"ip": "localhost"},
+				"event.duration": 1.5,
+				"tags":           []string{"invalid"},
+			},
+			violations: 2,
+		},
+		{
+			description: "array values are validated per element",
+			config:      map[string]interface{}{"fields": fields, "on_violation": "log"},
+			input: mapstr.M{
+				"source": mapstr.M{"ip": []interface{}{"10.0.0.1", "x"}},
+			},
+			output: mapstr.M{
+				"source": mapstr.M{"ip": []interface{}{"10.0.0.1", "x"}},
+			},
+			violations: 1,
+		},
+		{
+			description: "violating event is dropped",
+			config:      map[string]interface{}{"fields": fields, "on_violation": "drop"},
+			input: mapstr.M{
+				"http": mapstr.M{"response": mapstr.M{"status_code": true}},
+			},
+			output:     nil,
+			violations: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			p, err := New(conf.MustNewConfigFrom(tc.config))
+			require.NoError(t, err)
+
+			out, err := p.Run(&beat.Event{Fields: tc.input})
+			require.NoError(t, err)
+			if tc.output == nil {
+				assert.Nil(t, out)
+			} else {
+				assert.Equal(t, tc.output, out.Fields)
+			}
+			assert.Equal(t, tc.violations, p.(*validateECS).metrics.violations.Get())
+		})
+	}
+}
+
+func TestValidateECSFieldsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte("http.response.status_code: long\nsource:\n  port: long\n"), 0o600))
+
+	p, err := New(conf.MustNewConfigFrom(map[string]interface{}{
+		"fields_file": path,
+		"fields":      map[string]interface{}{"source.port": "keyword"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http.response.status_code", "source.port"}, p.(*validateECS).fields)
+
+	out, err := p.Run(&beat.Event{Fields: mapstr.M{
+		"http":   mapstr.M{"response": mapstr.M{"status_code": "404"}},
+		"source": mapstr.M{"port": "80"},
+	}})
+	require.NoError(t, err)
+	tags, _ := out.GetValue("tags")
+	assert.Equal(t, []string{"_ecs_validation_failure"}, tags)
+	assert.EqualValues(t, 1, p.(*validateECS).metrics.violations.Get())
+}
+
+func TestValidateECSConfigErrors(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"no fields":        {},
+		"unknown action":   {"fields": map[string]interface{}{"a": "long"}, "on_violation": "panic"},
+		"unknown type":     {"fields": map[string]interface{}{"a": "number"}},
+		"missing file":     {"fields_file": "/does/not/exist.yml"},
+		"non-string types": {"fields": map[string]interface{}{"a": 1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(conf.MustNewConfigFrom(cfg))
+			assert.Error(t, err)
+		})
+	}
+}
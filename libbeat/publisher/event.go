@@ -53,6 +53,25 @@ type Batch interface {
 	Cancelled()
 }
 
+// EventIDBatch is an optional extension of Batch for outputs that deliver
+// events in parallel and want to acknowledge them individually, in any order.
+type EventIDBatch interface {
+	Batch
+
+	// ACKEventIDs acknowledges the events with the given IDs. Acknowledged
+	// events are removed from the batch, so a later Retry only resends the
+	// remaining ones. Once all events have been acknowledged the batch is
+	// complete and no other signal method must be called.
+	// Events without an ID (ID is 0) can only be acknowledged with ACK.
+	ACKEventIDs(ids []uint64)
+}
+
+// EventIDACKer receives the IDs of events acknowledged individually by an
+// output.
+type EventIDACKer interface {
+	ACKEventIDs(ids []uint64)
+}
+
 // Event is used by the publisher pipeline and broker to pass additional
 // meta-data to the consumers/outputs.
 type Event struct {
@@ -65,6 +84,14 @@ type Event struct {
 	// to free the unencoded data. The updated event will be provided to
 	// output workers when calling Publish.
 	EncodedEvent interface{}
+
+	// ID is assigned by the pipeline if the publishing client requested
+	// per-event acknowledgements. IDs are unique and increasing within the
+	// pipeline. IDs are not persisted by the disk queue.
+	ID uint64
+
+	// IDACKer is informed when the output acknowledges the event by ID.
+	IDACKer EventIDACKer
 }
 
 // EventFlags provides additional flags/option types  for used with the outputs.
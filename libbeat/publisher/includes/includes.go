@@ -19,6 +19,7 @@ package includes
 
 import (
 	// import queue types
+	_ "github.com/elastic/beats/v7/libbeat/outputs/codec/cbor"
 	_ "github.com/elastic/beats/v7/libbeat/outputs/codec/format"
 	_ "github.com/elastic/beats/v7/libbeat/outputs/codec/json"
 	_ "github.com/elastic/beats/v7/libbeat/outputs/console"
@@ -29,6 +30,8 @@ import (
 	_ "github.com/elastic/beats/v7/libbeat/outputs/logstash"
 	_ "github.com/elastic/beats/v7/libbeat/outputs/otelconsumer"
 	_ "github.com/elastic/beats/v7/libbeat/outputs/redis"
+	_ "github.com/elastic/beats/v7/libbeat/outputs/router"
+	_ "github.com/elastic/beats/v7/libbeat/outputs/socket"
 	_ "github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
 	_ "github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
 )
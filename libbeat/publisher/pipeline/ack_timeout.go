@@ -0,0 +1,182 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// ackTimeouts expires the ACK timeouts of the batches in flight. A single
+// go-routine waits on the clock for the earliest timeout, the timeouts of
+// batches completed by the output are removed right away.
+type ackTimeouts struct {
+	clock clockwork.Clock
+	log   *logp.Logger
+
+	mutex   sync.Mutex
+	pending ackTimerHeap
+
+	// wakeup signals the go-routine that the earliest timeout changed.
+	wakeup chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// ackTimer is the ACK timeout of a batch sent to an output.
+type ackTimer struct {
+	owner    *ackTimeouts
+	batch    *ttlBatch
+	id       int
+	timeout  time.Duration
+	deadline time.Time
+
+	// index is the position of the timer in the heap, -1 once it has been
+	// removed.
+	index int
+}
+
+func newACKTimeouts(clock clockwork.Clock, log *logp.Logger) *ackTimeouts {
+	t := &ackTimeouts{
+		clock:  clock,
+		log:    log,
+		wakeup: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.run()
+	}()
+	return t
+}
+
+// start adds the ACK timeout of the batch. The timer returned must be stopped
+// once the output completes the batch.
+func (t *ackTimeouts) start(batch *ttlBatch, id int, timeout time.Duration) *ackTimer {
+	timer := &ackTimer{
+		owner:    t,
+		batch:    batch,
+		id:       id,
+		timeout:  timeout,
+		deadline: t.clock.Now().Add(timeout),
+	}
+
+	t.mutex.Lock()
+	heap.Push(&t.pending, timer)
+	earliest := timer.index == 0
+	t.mutex.Unlock()
+
+	if earliest {
+		select {
+		case t.wakeup <- struct{}{}:
+		default:
+		}
+	}
+	return timer
+}
+
+// stop removes the timer, its batch does not expire anymore.
+func (timer *ackTimer) stop() {
+	t := timer.owner
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if timer.index >= 0 {
+		heap.Remove(&t.pending, timer.index)
+	}
+}
+
+func (t *ackTimeouts) close() {
+	if t == nil {
+		return
+	}
+	close(t.done)
+	t.wg.Wait()
+}
+
+func (t *ackTimeouts) run() {
+	for {
+		var expired <-chan time.Time
+		t.mutex.Lock()
+		if len(t.pending) > 0 {
+			expired = t.clock.After(t.pending[0].deadline.Sub(t.clock.Now()))
+		}
+		t.mutex.Unlock()
+
+		select {
+		case <-expired:
+			t.expire()
+		case <-t.wakeup:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// expire removes the timers whose deadline passed and requeues the events of
+// their batches.
+func (t *ackTimeouts) expire() {
+	var expired []*ackTimer
+	t.mutex.Lock()
+	now := t.clock.Now()
+	for len(t.pending) > 0 && !t.pending[0].deadline.After(now) {
+		//nolint:errcheck // the heap only holds timers
+		expired = append(expired, heap.Pop(&t.pending).(*ackTimer))
+	}
+	t.mutex.Unlock()
+
+	for _, timer := range expired {
+		timer.batch.expireACKTimeout(timer.id, timer.timeout, t.log)
+	}
+}
+
+var _ heap.Interface = (*ackTimerHeap)(nil)
+
+// ackTimerHeap orders the timers by their deadline, it implements
+// heap.Interface.
+type ackTimerHeap []*ackTimer
+
+func (h ackTimerHeap) Len() int           { return len(h) }
+func (h ackTimerHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+
+func (h ackTimerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ackTimerHeap) Push(x any) {
+	//nolint:errcheck // the heap only holds timers
+	timer := x.(*ackTimer)
+	timer.index = len(*h)
+	*h = append(*h, timer)
+}
+
+func (h *ackTimerHeap) Pop() any {
+	old := *h
+	timer := old[len(old)-1]
+	old[len(old)-1] = nil
+	timer.index = -1
+	*h = old[:len(old)-1]
+	return timer
+}
@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func TestACKTimeouts(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	timeouts := newACKTimeouts(clock, logp.NewTestingLogger(t, ""))
+	defer timeouts.close()
+
+	retryer := chanRetryer{ch: make(chan *ttlBatch, 3)}
+	newBatch := func(events int) *ttlBatch {
+		return &ttlBatch{
+			events:  make([]publisher.Event, events),
+			retryer: retryer,
+			done:    func() {},
+		}
+	}
+	pending := func() int {
+		timeouts.mutex.Lock()
+		defer timeouts.mutex.Unlock()
+		return len(timeouts.pending)
+	}
+
+	// The timeouts expire in the order of their deadlines, not in the order
+	// they were started.
+	late := newBatch(1)
+	late.startACKTimeout(timeouts, 3*time.Minute)
+	early := newBatch(2)
+	early.startACKTimeout(timeouts, time.Minute)
+	acked := newBatch(3)
+	acked.startACKTimeout(timeouts, 2*time.Minute)
+	require.Equal(t, 3, pending())
+
+	// Completing a batch stops its timer.
+	acked.ACK()
+	assert.Equal(t, 2, pending())
+
+	clock.Advance(time.Minute)
+	select {
+	case requeued := <-retryer.ch:
+		assert.Len(t, requeued.events, 2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not requeued after its ACK timeout")
+	}
+
+	clock.Advance(2 * time.Minute)
+	select {
+	case requeued := <-retryer.ch:
+		assert.Len(t, requeued.events, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not requeued after its ACK timeout")
+	}
+	assert.Zero(t, pending())
+	assert.Len(t, retryer.ch, 0)
+}
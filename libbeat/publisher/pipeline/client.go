@@ -18,34 +18,72 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/jonboulle/clockwork"
+
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/processors"
 	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/processing"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/elastic-agent-libs/logp"
 )
 
 // client connects a beat with the processors and pipeline queue.
 type client struct {
+	id         uint64
 	logger     *logp.Logger
 	processors beat.Processor
 	producer   queue.Producer
 	mutex      sync.Mutex
 	waiter     *clientCloseWaiter
 
-	eventFlags publisher.EventFlags
-	canDrop    bool
+	publishMode beat.PublishMode
+	eventFlags  publisher.EventFlags
+	canDrop     bool
+
+	// Number of events published to the queue and not acknowledged yet.
+	inFlight atomic.Int64
+
+	// Cumulative event counts reported by Stats.
+	stats *clientStats
 
 	// Open state, signaling, and sync primitives for coordinating client Close.
 	isOpen atomic.Bool // set to false during shutdown, such that no new events will be accepted anymore.
 
 	observer       observer
+	events         *eventCounter
+	congestion     *congestionMonitor
+	droppedEvents  *droppedEventLogger
+	clients        *clientLimiter
+	registry       *clientRegistry
 	eventListener  beat.EventListener
 	clientListener beat.ClientListener
+
+	// Set if the EventListener wants events to be ACKed by ID.
+	idTracker *eventIDTracker
+	eventIDs  *atomic.Uint64
+
+	// Records the duration of publish calls, nil if metrics are disabled.
+	latency *latencyHistogram
+
+	// Set if the client assigns sequence numbers to events.
+	assignSequence bool
+	sequence       uint64
+
+	// Set if the client writes its session ID to events.
+	sessionField string
+	sessionID    string
+
+	// If chunkSize is set, the client calls yield after every chunkSize
+	// events of a PublishAll call.
+	chunkSize int
+	yield     func()
 }
 
 type clientCloseWaiter struct {
@@ -55,14 +93,16 @@ type clientCloseWaiter struct {
 	signalAll  chan struct{} // ack loop notifies `close` that all events have been acked
 	signalDone chan struct{} // shutdown handler telling `wait` that shutdown has been completed
 	waitClose  time.Duration
+	clock      clockwork.Clock
 }
 
 func (c *client) PublishAll(events []beat.Event) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	for _, e := range events {
-		c.publish(e)
+	for i, e := range events {
+		c.yieldAfterChunk(i)
+		c.publish(context.Background(), e)
 	}
 }
 
@@ -70,54 +110,145 @@ func (c *client) Publish(e beat.Event) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.publish(e)
+	c.publish(context.Background(), e)
+}
+
+// PublishAllWithContext publishes events until all events have been handed to
+// the queue or ctx is done. Events not attempted because ctx is done are not
+// reported to the client's listeners.
+func (c *client) PublishAllWithContext(ctx context.Context, events []beat.Event) ([]beat.PublishResult, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	results := make([]beat.PublishResult, len(events))
+	for i, e := range events {
+		if err := ctx.Err(); err != nil {
+			for j := i; j < len(results); j++ {
+				results[j].Err = err
+			}
+			return results, err
+		}
+		c.yieldAfterChunk(i)
+		results[i] = c.publish(ctx, e)
+	}
+	return results, nil
+}
+
+// yieldAfterChunk lets other goroutines run before the i-th event of a
+// PublishAll call if it starts a new chunk, such that a single call with many
+// events does not monopolize the processors and the queue. The client mutex is
+// released while yielding, so other publishers of the client can publish their
+// events between the chunks. The events of the call stay in order.
+// Must be called with the client mutex held.
+func (c *client) yieldAfterChunk(i int) {
+	if c.chunkSize > 0 && i > 0 && i%c.chunkSize == 0 {
+		c.mutex.Unlock()
+		c.yield()
+		c.mutex.Lock()
+	}
 }
 
-func (c *client) publish(e beat.Event) {
-	var (
-		event   = &e
-		publish = true
-	)
+func (c *client) publish(ctx context.Context, e beat.Event) beat.PublishResult {
+	event := &e
+
+	if c.latency != nil {
+		start := c.latency.clock.Now()
+		defer func() { c.latency.record(c.latency.clock.Since(start)) }()
+	}
 
 	c.onNewEvent()
 
 	if !c.isOpen.Load() {
 		// client is closing down -> report event as dropped and return
-		c.onDroppedOnPublish(e)
-		return
+		c.onDroppedOnPublish(ctx, e)
+		return beat.PublishResult{}
 	}
 
-	if c.processors != nil {
-		var err error
+	if c.assignSequence {
+		c.nextSequence(event)
+	}
 
-		event, err = c.processors.Run(event)
-		publish = event != nil
+	if c.sessionField != "" {
+		if _, err := event.PutValue(c.sessionField, c.sessionID); err != nil {
+			c.logger.Debugf("Failed to set the client session ID in %v: %v", c.sessionField, err)
+		}
+	}
+
+	if c.processors != nil {
+		events, err := processors.RunSplit(c.processors, event)
 		if err != nil {
 			// If we introduce a dead-letter queue, this is where we should
 			// route the event to it.
+			if errors.Is(err, processing.ErrDeadlineExceeded) {
+				c.observer.timedOutEvent()
+			}
 			c.logger.Errorf("Failed to publish event: %v", err)
 		}
+
+		if len(events) > 1 {
+			// The event has been split by a processor. Every event created
+			// is reported and ACKed on its own.
+			for range events[1:] {
+				c.onNewEvent()
+			}
+			published := true
+			for _, event := range events {
+				if !c.publishProcessed(ctx, e, event) {
+					published = false
+				}
+			}
+			return c.publishResult(ctx, published, false)
+		}
+
+		event = nil
+		if len(events) == 1 {
+			event = events[0]
+		}
 	}
 
-	if event != nil {
+	published := c.publishProcessed(ctx, e, event)
+	return c.publishResult(ctx, published, event == nil)
+}
+
+func (c *client) publishResult(ctx context.Context, published, filtered bool) beat.PublishResult {
+	result := beat.PublishResult{Published: published, Filtered: filtered}
+	if !published && !filtered {
+		result.Err = ctx.Err()
+	}
+	return result
+}
+
+// publishProcessed pushes a processed event into the queue and reports whether
+// it has been published. If event is nil the original event e is reported as
+// filtered out.
+// Must be called with the client mutex held.
+func (c *client) publishProcessed(ctx context.Context, e beat.Event, event *beat.Event) bool {
+	publish := event != nil
+	if publish {
 		e = *event
 	}
 
 	c.eventListener.AddEvent(e, publish)
 	if !publish {
 		c.onFilteredOut()
-		return
+		return false
 	}
 
-	e = *event
 	pubEvent := publisher.Event{
 		Content: e,
 		Flags:   c.eventFlags,
 	}
+	if c.idTracker != nil {
+		pubEvent.ID = c.eventIDs.Add(1)
+		pubEvent.IDACKer = c.idTracker
+		c.idTracker.add(pubEvent.ID)
+	}
 
 	var published bool
 	if c.canDrop {
 		_, published = c.producer.TryPublish(pubEvent)
+	} else if p, ok := c.producer.(queue.ContextProducer); ok && ctx.Done() != nil {
+		_, published = p.PublishWithContext(ctx, pubEvent)
 	} else {
 		_, published = c.producer.Publish(pubEvent)
 	}
@@ -125,13 +256,30 @@ func (c *client) publish(e beat.Event) {
 	if published {
 		c.onPublished()
 	} else {
-		c.onDroppedOnPublish(e)
+		if c.idTracker != nil {
+			c.idTracker.cancel(pubEvent.ID)
+		}
+		c.onDroppedOnPublish(ctx, e)
+	}
+	return published
+}
+
+// nextSequence assigns the next sequence number to the event if it has none,
+// or continues the numbering after the sequence number set by the caller.
+// Must be called with the client mutex held.
+func (c *client) nextSequence(event *beat.Event) {
+	if event.Sequence == 0 {
+		c.sequence++
+		event.Sequence = c.sequence
+	} else if event.Sequence > c.sequence {
+		c.sequence = event.Sequence
 	}
 }
 
 func (c *client) Close() error {
 	if c.isOpen.Swap(false) {
 		// Only do shutdown handling the first time Close is called
+		c.flushProcessors()
 		c.onClosing()
 
 		c.logger.Debug("client: closing acker")
@@ -158,40 +306,77 @@ func (c *client) Close() error {
 	return nil
 }
 
+// flushProcessors publishes the events still held back by the processors.
+func (c *client) flushProcessors() {
+	f, ok := c.processors.(processors.Flusher)
+	if !ok {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, event := range f.Flush() {
+		c.onNewEvent()
+		c.publishProcessed(context.Background(), *event, event)
+	}
+}
+
 func (c *client) onClosing() {
 	c.clientListener.Closing()
 }
 
 func (c *client) onClosed() {
+	c.congestion.removeClient(c)
+	c.registry.remove(c)
+	c.clients.release()
+	c.observer.removeClientLatency(c.id)
 	c.observer.clientClosed()
 	c.clientListener.Closed()
 }
 
 func (c *client) onNewEvent() {
+	c.stats.newEvent()
 	c.observer.newEvent()
 	c.clientListener.NewEvent()
 }
 
 func (c *client) onPublished() {
+	c.inFlight.Add(1)
+	c.stats.published()
 	c.observer.publishedEvent()
+	c.events.eventPublished()
 	c.clientListener.Published()
 }
 
 func (c *client) onFilteredOut() {
+	c.stats.filtered()
 	c.observer.filteredEvent()
 	c.clientListener.Filtered()
 }
 
-func (c *client) onDroppedOnPublish(e beat.Event) {
+func (c *client) onDroppedOnPublish(ctx context.Context, e beat.Event) {
+	c.stats.dropped()
 	c.observer.failedPublishEvent()
+	if c.droppedEvents != nil {
+		reason := "queue closed"
+		if !c.isOpen.Load() {
+			reason = "client closed"
+		} else if ctx.Err() != nil {
+			reason = "publish deadline exceeded"
+		} else if c.canDrop {
+			reason = "queue full"
+		}
+		c.droppedEvents.eventDropped(e, reason)
+	}
 	c.clientListener.DroppedOnPublish(e)
 }
 
-func newClientCloseWaiter(timeout time.Duration) *clientCloseWaiter {
+func newClientCloseWaiter(clock clockwork.Clock, timeout time.Duration) *clientCloseWaiter {
 	return &clientCloseWaiter{
 		signalAll:  make(chan struct{}, 1),
 		signalDone: make(chan struct{}),
 		waitClose:  timeout,
+		clock:      clock,
 	}
 }
 
@@ -236,7 +421,7 @@ func (w *clientCloseWaiter) signalClose() {
 
 		select {
 		case <-w.signalAll:
-		case <-time.After(w.waitClose):
+		case <-w.clock.After(w.waitClose):
 		}
 	}()
 }
@@ -0,0 +1,62 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrTooManyClients is returned by (*Pipeline).ConnectWith if the maximum
+// number of clients configured is connected already.
+var ErrTooManyClients = errors.New("too many pipeline clients")
+
+// clientLimiter limits the number of clients connected to the pipeline at
+// the same time, protecting the Beat from inputs leaking clients.
+// All methods are safe to call on a nil clientLimiter.
+type clientLimiter struct {
+	max   int64
+	count atomic.Int64
+}
+
+// newClientLimiter returns a clientLimiter accepting up to limit clients. If limit
+// is 0 the number of clients is not limited.
+func newClientLimiter(limit int) *clientLimiter {
+	return &clientLimiter{max: int64(limit)}
+}
+
+// acquire reserves a slot for a new client. It returns false if the maximum
+// number of clients is connected already.
+func (l *clientLimiter) acquire() bool {
+	if l == nil {
+		return true
+	}
+	if n := l.count.Add(1); l.max > 0 && n > l.max {
+		l.count.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release frees the slot of a closed client.
+func (l *clientLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.count.Add(-1)
+}
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+func TestClientLimiter(t *testing.T) {
+	t.Run("nil limiter", func(t *testing.T) {
+		var l *clientLimiter
+		assert.True(t, l.acquire())
+		l.release()
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		l := newClientLimiter(0)
+		for i := 0; i < 100; i++ {
+			require.True(t, l.acquire())
+		}
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		l := newClientLimiter(2)
+		require.True(t, l.acquire())
+		require.True(t, l.acquire())
+		assert.False(t, l.acquire())
+		assert.Equal(t, int64(2), l.count.Load())
+
+		l.release()
+		assert.True(t, l.acquire())
+	})
+}
+
+func TestPipelineMaxClients(t *testing.T) {
+	pipeline := makePipeline(t, Settings{MaxClients: 2}, makeTestQueue())
+	defer pipeline.Close()
+
+	c1, err := pipeline.ConnectWith(beat.ClientConfig{})
+	require.NoError(t, err)
+	c2, err := pipeline.ConnectWith(beat.ClientConfig{})
+	require.NoError(t, err)
+
+	_, err = pipeline.ConnectWith(beat.ClientConfig{})
+	require.ErrorIs(t, err, ErrTooManyClients)
+
+	// Closing a client frees its slot, closing it twice does not.
+	require.NoError(t, c1.Close())
+	require.NoError(t, c1.Close())
+	c3, err := pipeline.Connect()
+	require.NoError(t, err)
+	_, err = pipeline.Connect()
+	require.ErrorIs(t, err, ErrTooManyClients)
+
+	require.NoError(t, c2.Close())
+	require.NoError(t, c3.Close())
+}
@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/publisher/processing"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// clientRegistry keeps track of the clients connected to the pipeline, such
+// that their configuration can be inspected for debugging.
+// All methods are safe to call on a nil clientRegistry.
+type clientRegistry struct {
+	mutex   sync.Mutex
+	lastID  uint64
+	clients map[uint64]*client
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{clients: map[uint64]*client{}}
+}
+
+// add registers a newly connected client and assigns its ID.
+func (r *clientRegistry) add(c *client) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lastID++
+	c.id = r.lastID
+	r.clients[c.id] = c
+}
+
+// remove unregisters a closed client.
+func (r *clientRegistry) remove(c *client) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.clients, c.id)
+}
+
+// list returns the registered clients ordered by ID.
+func (r *clientRegistry) list() []*client {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	clients := make([]*client, 0, len(r.clients))
+	for _, c := range r.clients {
+		clients = append(clients, c)
+	}
+	slices.SortFunc(clients, func(a, b *client) int {
+		return cmp.Compare(a.id, b.id)
+	})
+	return clients
+}
+
+// Processors returns the descriptions of the processors applied to the events
+// published by the client, in the order they are applied.
+func (c *client) Processors() []string {
+	return processing.Chain(c.processors)
+}
+
+// ClientProcessors reports the processor chain of every client connected to
+// the pipeline. Clients are identified by an ID assigned on connect.
+func (p *Pipeline) ClientProcessors() mapstr.M {
+	clients := []mapstr.M{}
+	for _, c := range p.registry.list() {
+		clients = append(clients, mapstr.M{
+			"id":         c.id,
+			"processors": c.Processors(),
+		})
+	}
+	return mapstr.M{"clients": clients}
+}
+
+// Clients reports every client connected to the pipeline, with its publish
+// mode, its processor chain, the number of events published to the queue
+// and not acknowledged yet and the fraction of events filtered out by the
+// processors during the last complete minute. The clients are not blocked while reporting.
+func (p *Pipeline) Clients() mapstr.M {
+	clients := []mapstr.M{}
+	for _, c := range p.registry.list() {
+		info := mapstr.M{
+			"id":           c.id,
+			"publish_mode": c.publishMode.String(),
+			"processors":   c.Processors(),
+			"in_flight":    c.inFlight.Load(),
+			"drop_ratio":   c.stats.snapshot().DropRatio,
+		}
+		if c.sessionField != "" {
+			info["session_id"] = c.sessionID
+		}
+		clients = append(clients, info)
+	}
+	return mapstr.M{"clients": clients}
+}
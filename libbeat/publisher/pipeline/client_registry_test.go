@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/actions"
+	"github.com/elastic/beats/v7/libbeat/publisher/processing"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestPipelineClientProcessors(t *testing.T) {
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"add_tags": map[string]interface{}{"tags": []string{"global"}}},
+		},
+	})
+	support, err := processing.MakeDefaultSupport(false, nil)(beat.Info{}, logp.L(), cfg)
+	require.NoError(t, err)
+
+	pipeline := makePipeline(t, Settings{Processors: support}, makeTestQueue())
+	defer pipeline.Close()
+
+	assert.Equal(t, mapstr.M{"clients": []mapstr.M{}}, pipeline.ClientProcessors())
+
+	c1, err := pipeline.Connect()
+	require.NoError(t, err)
+
+	clientProcessors := processors.NewList(nil)
+	clientProcessors.AddProcessor(actions.NewAddTags("tags", []string{"client"}))
+	c2, err := pipeline.ConnectWith(beat.ClientConfig{
+		Processing: beat.ProcessingConfig{Processor: clientProcessors},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"add_tags=global"}, c1.(*client).Processors())
+	assert.Equal(t, mapstr.M{"clients": []mapstr.M{
+		{"id": uint64(1), "processors": []string{"add_tags=global"}},
+		{"id": uint64(2), "processors": []string{"add_tags=client", "add_tags=global"}},
+	}}, pipeline.ClientProcessors())
+
+	// Closed clients are not reported anymore.
+	require.NoError(t, c1.Close())
+	assert.Equal(t, mapstr.M{"clients": []mapstr.M{
+		{"id": uint64(2), "processors": []string{"add_tags=client", "add_tags=global"}},
+	}}, pipeline.ClientProcessors())
+	require.NoError(t, c2.Close())
+}
+
+func TestPipelineClients(t *testing.T) {
+	var acks []func(int)
+	pipeline := makePipeline(t, Settings{}, &testQueue{
+		producer: func(cfg queue.ProducerConfig) queue.Producer {
+			acks = append(acks, cfg.ACK)
+			return &testProducer{
+				publish: func(bool, queue.Entry) (queue.EntryID, bool) {
+					return 0, true
+				},
+			}
+		},
+	})
+	defer pipeline.Close()
+
+	assert.Equal(t, mapstr.M{"clients": []mapstr.M{}}, pipeline.Clients())
+
+	c1, err := pipeline.ConnectWith(beat.ClientConfig{PublishMode: beat.GuaranteedSend})
+	require.NoError(t, err)
+	c2, err := pipeline.ConnectWith(beat.ClientConfig{PublishMode: beat.DropIfFull})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		c1.Publish(beat.Event{Fields: mapstr.M{"i": i}})
+	}
+	c2.Publish(beat.Event{})
+	require.Len(t, acks, 2)
+	acks[0](2)
+
+	assert.Equal(t, mapstr.M{"clients": []mapstr.M{
+		{"id": uint64(1), "publish_mode": "guaranteed_send", "processors": []string{}, "in_flight": int64(1), "drop_ratio": 0.0},
+		{"id": uint64(2), "publish_mode": "drop_if_full", "processors": []string{}, "in_flight": int64(1), "drop_ratio": 0.0},
+	}}, pipeline.Clients())
+
+	require.NoError(t, c1.Close())
+	require.NoError(t, c2.Close())
+	assert.Equal(t, mapstr.M{"clients": []mapstr.M{}}, pipeline.Clients())
+}
@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+// dropRatioWindow is the duration of the windows the drop ratio of a client
+// is computed over.
+const dropRatioWindow = time.Minute
+
+// clientStats counts the events of a single client. The counters are guarded
+// by a mutex, such that Stats always returns a consistent snapshot.
+type clientStats struct {
+	mutex sync.Mutex
+	stats beat.ClientStats
+
+	// The drop ratio is computed over fixed windows of dropRatioWindow,
+	// starting when the client connected. Windows are advanced when events
+	// are counted or the stats are read, reads do not change the windows.
+	now                            func() time.Time
+	windowStart                    time.Time
+	windowReceived, windowFiltered uint64
+}
+
+func newClientStats(now func() time.Time) *clientStats {
+	return &clientStats{now: now, windowStart: now()}
+}
+
+func (s *clientStats) newEvent() {
+	s.mutex.Lock()
+	s.advance()
+	s.stats.Received++
+	s.windowReceived++
+	s.mutex.Unlock()
+}
+
+func (s *clientStats) published() {
+	s.mutex.Lock()
+	s.stats.Published++
+	s.mutex.Unlock()
+}
+
+func (s *clientStats) filtered() {
+	s.mutex.Lock()
+	s.advance()
+	s.stats.Filtered++
+	s.windowFiltered++
+	s.mutex.Unlock()
+}
+
+func (s *clientStats) dropped() {
+	s.mutex.Lock()
+	s.stats.Dropped++
+	s.mutex.Unlock()
+}
+
+func (s *clientStats) acked(n int) {
+	s.mutex.Lock()
+	s.stats.Acked += uint64(n)
+	s.mutex.Unlock()
+}
+
+func (s *clientStats) snapshot() beat.ClientStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.advance()
+	return s.stats
+}
+
+// advance updates the drop ratio if the current window is complete, and
+// starts the window the current time falls into.
+// Must be called with the mutex held.
+func (s *clientStats) advance() {
+	elapsed := s.now().Sub(s.windowStart)
+	if elapsed < dropRatioWindow {
+		return
+	}
+
+	s.stats.DropRatio = 0
+	if elapsed < 2*dropRatioWindow && s.windowReceived > 0 {
+		s.stats.DropRatio = float64(s.windowFiltered) / float64(s.windowReceived)
+	}
+	// Windows without events in between have a drop ratio of 0.
+	s.windowStart = s.windowStart.Add(elapsed.Truncate(dropRatioWindow))
+	s.windowReceived, s.windowFiltered = 0, 0
+}
+
+// Stats returns the cumulative event counts of the client since it connected.
+// Events are counted as acknowledged once the outputs ACK them, which can
+// happen before Publish returns.
+func (c *client) Stats() beat.ClientStats {
+	return c.stats.snapshot()
+}
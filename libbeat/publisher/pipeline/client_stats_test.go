@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientStatsDropRatio(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	stats := newClientStats(clock.Now)
+
+	received := func(n, filtered int) {
+		for i := 0; i < n; i++ {
+			stats.newEvent()
+		}
+		for i := 0; i < filtered; i++ {
+			stats.filtered()
+		}
+	}
+
+	received(4, 1)
+	assert.Zero(t, stats.snapshot().DropRatio, "drop ratio should be 0 until the first window is complete")
+
+	// Reads do not change the windows.
+	clock.Advance(50 * time.Second)
+	received(4, 1)
+	assert.Zero(t, stats.snapshot().DropRatio)
+	clock.Advance(20 * time.Second)
+	assert.Equal(t, 0.25, stats.snapshot().DropRatio)
+	assert.Equal(t, 0.25, stats.snapshot().DropRatio)
+
+	// The second window started one minute after the client connected.
+	received(2, 2)
+	clock.Advance(50 * time.Second)
+	assert.Equal(t, 1.0, stats.snapshot().DropRatio)
+
+	// Windows without events have a drop ratio of 0.
+	clock.Advance(2 * time.Minute)
+	assert.Zero(t, stats.snapshot().DropRatio)
+}
@@ -18,13 +18,16 @@
 package pipeline
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -191,6 +194,416 @@ func TestClient(t *testing.T) {
 	})
 }
 
+func TestClientAssignSequence(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        10,
+		MaxGetRequest: 10,
+		FlushTimeout:  time.Millisecond,
+	}, 10, nil)
+	pipeline := makePipeline(t, Settings{}, q)
+	defer pipeline.Close()
+
+	client, err := pipeline.ConnectWith(beat.ClientConfig{AssignSequence: true})
+	require.NoError(t, err)
+
+	client.PublishAll([]beat.Event{
+		{Fields: mapstr.M{"n": 1}},
+		{Fields: mapstr.M{"n": 2}},
+		{Fields: mapstr.M{"n": 3}, Sequence: 10},
+		{Fields: mapstr.M{"n": 4}},
+		{Fields: mapstr.M{"n": 5}, Sequence: 5},
+		{Fields: mapstr.M{"n": 6}},
+	})
+	require.NoError(t, client.Close())
+
+	batch, err := q.Get(6)
+	require.NoError(t, err)
+	var sequences []uint64
+	for i := 0; i < batch.Count(); i++ {
+		//nolint:errcheck // it always succeeds
+		e := batch.Entry(i).(publisher.Event)
+		sequences = append(sequences, e.Content.Sequence)
+	}
+	batch.Done()
+
+	assert.Equal(t, []uint64{1, 2, 10, 11, 5, 12}, sequences)
+}
+
+func TestClientSessionID(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        10,
+		MaxGetRequest: 10,
+		FlushTimeout:  time.Millisecond,
+	}, 10, nil)
+	pipeline := makePipeline(t, Settings{}, q)
+	defer pipeline.Close()
+
+	publish := func(cfg beat.ClientConfig) {
+		client, err := pipeline.ConnectWith(cfg)
+		require.NoError(t, err)
+		client.PublishAll([]beat.Event{{Fields: mapstr.M{"n": 1}}, {}})
+		require.NoError(t, client.Close())
+	}
+	publish(beat.ClientConfig{SessionField: "session.id"})
+	publish(beat.ClientConfig{SessionField: "session.id"})
+	publish(beat.ClientConfig{SessionField: "session.id", SessionID: "abc"})
+	publish(beat.ClientConfig{})
+
+	batch, err := q.Get(8)
+	require.NoError(t, err)
+	var ids []interface{}
+	for i := 0; i < batch.Count(); i++ {
+		//nolint:errcheck // it always succeeds
+		e := batch.Entry(i).(publisher.Event)
+		id, _ := e.Content.GetValue("session.id")
+		ids = append(ids, id)
+	}
+	batch.Done()
+
+	require.Len(t, ids, 8)
+	assert.NotEmpty(t, ids[0])
+	assert.Equal(t, ids[0], ids[1], "events of a client must have the same session ID")
+	assert.NotEmpty(t, ids[2])
+	assert.Equal(t, ids[2], ids[3])
+	assert.NotEqual(t, ids[0], ids[2], "every client must get its own session ID")
+	assert.Equal(t, []interface{}{"abc", "abc", nil, nil}, ids[4:])
+}
+
+func TestClientStats(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        10,
+		MaxGetRequest: 10,
+		FlushTimeout:  time.Millisecond,
+	}, 10, nil)
+	pipeline := makePipeline(t, Settings{
+		Processors: testProcessorSupporter{Processor: &splitTestProcessor{}},
+	}, q)
+	defer pipeline.Close()
+
+	c, err := pipeline.ConnectWith(beat.ClientConfig{})
+	require.NoError(t, err)
+	client, ok := c.(beat.StatsClient)
+	require.True(t, ok, "pipeline clients must implement beat.StatsClient")
+	assert.Equal(t, beat.ClientStats{}, client.Stats())
+
+	// The first event is split in two events, the second one is filtered out.
+	client.PublishAll([]beat.Event{
+		{Fields: mapstr.M{"n": 2}},
+		{Fields: mapstr.M{"n": 0}},
+	})
+	assert.Equal(t, beat.ClientStats{
+		Received:  3,
+		Published: 2,
+		Filtered:  1,
+	}, client.Stats())
+
+	batch, err := q.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, 2, batch.Count())
+	batch.Done()
+	require.Eventually(t, func() bool {
+		return client.Stats().Acked == 2
+	}, 5*time.Second, time.Millisecond)
+
+	// Events published after close are dropped.
+	require.NoError(t, client.Close())
+	client.Publish(beat.Event{Fields: mapstr.M{"n": 1}})
+	assert.Equal(t, beat.ClientStats{
+		Received:  4,
+		Published: 2,
+		Filtered:  1,
+		Dropped:   1,
+		Acked:     2,
+	}, client.Stats())
+}
+
+func TestClientSplitEvents(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        10,
+		MaxGetRequest: 10,
+		FlushTimeout:  time.Millisecond,
+	}, 10, nil)
+	pipeline := makePipeline(t, Settings{
+		Processors: testProcessorSupporter{Processor: &splitTestProcessor{}},
+	}, q)
+	defer pipeline.Close()
+
+	listener := &countingEventListener{}
+	clientListener := &mockClientListener{}
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		EventListener:  listener,
+		ClientListener: clientListener,
+	})
+	require.NoError(t, err)
+
+	client.PublishAll([]beat.Event{
+		{Fields: mapstr.M{"n": 3}},
+		{Fields: mapstr.M{"n": 0}},
+		{Fields: mapstr.M{"n": 1}},
+	})
+
+	batch, err := q.Get(10)
+	require.NoError(t, err)
+	assert.Equal(t, 4, batch.Count())
+	batch.Done()
+
+	// ACKs are forwarded asynchronously by the queue
+	require.Eventually(t, func() bool {
+		listener.mu.Lock()
+		defer listener.mu.Unlock()
+		return listener.acked == 4
+	}, 10*time.Second, time.Millisecond)
+	require.NoError(t, client.Close())
+
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	assert.Equal(t, 4, listener.published)
+	assert.Equal(t, 1, listener.filtered)
+	assert.Equal(t, 5, clientListener.eventsTotal)
+	assert.Equal(t, 4, clientListener.eventsPublished)
+	assert.Equal(t, 1, clientListener.eventsFiltered)
+}
+
+func TestClientTimedOutEvents(t *testing.T) {
+	metrics := monitoring.NewRegistry()
+	p, err := New(beat.Info{Logger: logp.NewTestingLogger(t, "")},
+		Monitors{Metrics: metrics},
+		conf.Namespace{},
+		outputs.Group{},
+		Settings{
+			Processors: testProcessorSupporter{Processor: &deadlineTestProcessor{}},
+		},
+	)
+	require.NoError(t, err)
+	p.outputController.queue = &testQueue{
+		producer: func(queue.ProducerConfig) queue.Producer {
+			return &testProducer{
+				publish: func(bool, queue.Entry) (queue.EntryID, bool) {
+					return 0, true
+				},
+			}
+		},
+	}
+	defer p.Close()
+
+	c, err := p.Connect()
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.PublishAll([]beat.Event{
+		{Fields: mapstr.M{"slow": true}},
+		{Fields: mapstr.M{}},
+		{Fields: mapstr.M{"slow": true}},
+	})
+
+	snapshot := monitoring.CollectFlatSnapshot(metrics, monitoring.Full, false)
+	assert.Equal(t, int64(2), snapshot.Ints["pipeline.events.timed_out"])
+	assert.Equal(t, int64(2), snapshot.Ints["pipeline.events.filtered"])
+}
+
+func TestClientPublishChunks(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        20,
+		MaxGetRequest: 20,
+		FlushTimeout:  time.Millisecond,
+	}, 20, nil)
+	pipeline := makePipeline(t, Settings{PublishChunkSize: 3}, q)
+	defer pipeline.Close()
+
+	listener := &countingEventListener{}
+	c, err := pipeline.ConnectWith(beat.ClientConfig{EventListener: listener})
+	require.NoError(t, err)
+	// Other publishers of the client publish between the chunks.
+	yields := 0
+	c.(*client).yield = func() {
+		yields++
+		if assert.True(t, c.(*client).mutex.TryLock(), "the client mutex must be released while yielding") {
+			c.(*client).mutex.Unlock()
+			c.Publish(beat.Event{Fields: mapstr.M{"n": -1}})
+		}
+	}
+
+	events := make([]beat.Event, 7)
+	for i := range events {
+		events[i] = beat.Event{Fields: mapstr.M{"n": i}}
+	}
+	c.PublishAll(events)
+	assert.Equal(t, 2, yields, "the client should yield before the 4th and 7th event")
+
+	_, err = c.(beat.ContextClient).PublishAllWithContext(context.Background(), events[:4])
+	require.NoError(t, err)
+	assert.Equal(t, 3, yields)
+
+	batch, err := q.Get(20)
+	require.NoError(t, err)
+	expected := []int{0, 1, 2, -1, 3, 4, 5, -1, 6, 0, 1, 2, -1, 3}
+	require.Equal(t, len(expected), batch.Count())
+	for i, n := range expected {
+		e := batch.Entry(i).(publisher.Event)
+		assert.Equal(t, n, e.Content.Fields["n"], "events should be published in order")
+	}
+	batch.Done()
+
+	require.Eventually(t, func() bool {
+		listener.mu.Lock()
+		defer listener.mu.Unlock()
+		return listener.acked == len(expected)
+	}, 10*time.Second, time.Millisecond)
+	require.NoError(t, c.Close())
+}
+
+func TestClientCloseFlushesProcessors(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        10,
+		MaxGetRequest: 10,
+		FlushTimeout:  time.Millisecond,
+	}, 10, nil)
+	pipeline := makePipeline(t, Settings{
+		Processors: testProcessorSupporter{Processor: &flushTestProcessor{}},
+	}, q)
+	defer pipeline.Close()
+
+	clientListener := &mockClientListener{}
+	client, err := pipeline.ConnectWith(beat.ClientConfig{ClientListener: clientListener})
+	require.NoError(t, err)
+
+	client.PublishAll([]beat.Event{
+		{Fields: mapstr.M{"n": 1}},
+		{Fields: mapstr.M{"n": 2}},
+	})
+	require.NoError(t, client.Close())
+
+	batch, err := q.Get(10)
+	require.NoError(t, err)
+	require.Equal(t, 2, batch.Count())
+	for i := 0; i < batch.Count(); i++ {
+		//nolint:errcheck // it always succeeds
+		e := batch.Entry(i).(publisher.Event)
+		assert.Equal(t, mapstr.M{"n": i + 1}, e.Content.Fields)
+	}
+	batch.Done()
+
+	assert.Equal(t, 4, clientListener.eventsTotal)
+	assert.Equal(t, 2, clientListener.eventsFiltered)
+	assert.Equal(t, 2, clientListener.eventsPublished)
+}
+
+func TestClientPublishAllWithContext(t *testing.T) {
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil, memqueue.Settings{
+		Events:        2,
+		MaxGetRequest: 2,
+		FlushTimeout:  time.Millisecond,
+	}, 0, nil)
+	pipeline := makePipeline(t, Settings{
+		Processors: testProcessorSupporter{Processor: &splitTestProcessor{}},
+	}, q)
+	defer pipeline.Close()
+
+	clientListener := &mockClientListener{}
+	c, err := pipeline.ConnectWith(beat.ClientConfig{ClientListener: clientListener})
+	require.NoError(t, err)
+	defer c.Close()
+	client, ok := c.(beat.ContextClient)
+	require.True(t, ok, "pipeline clients must implement beat.ContextClient")
+
+	// Nothing consumes from the queue, so it blocks after two events.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results, err := client.PublishAllWithContext(ctx, []beat.Event{
+		{Fields: mapstr.M{"n": 1}},
+		{Fields: mapstr.M{"n": 0}},
+		{Fields: mapstr.M{"n": 1}},
+		{Fields: mapstr.M{"n": 1}},
+		{Fields: mapstr.M{"n": 1}},
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []beat.PublishResult{
+		{Published: true},
+		{Filtered: true},
+		{Published: true},
+		{Err: context.DeadlineExceeded},
+		{Err: context.DeadlineExceeded},
+	}, results)
+
+	// Events not attempted are not reported.
+	assert.Equal(t, 4, clientListener.eventsTotal)
+	assert.Equal(t, 2, clientListener.eventsPublished)
+	assert.Equal(t, 1, clientListener.eventsFiltered)
+	assert.Equal(t, 1, clientListener.eventsDroppedOnPublish)
+
+	// Once the queue has space the client publishes again.
+	batch, err := q.Get(2)
+	require.NoError(t, err)
+	batch.Done()
+	results, err = client.PublishAllWithContext(context.Background(), []beat.Event{{Fields: mapstr.M{"n": 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, []beat.PublishResult{{Published: true}}, results)
+}
+
+func TestClientPublishReportsFullQueue(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), queue.NewQueueObserver(reg), memqueue.Settings{
+		Events:        1,
+		MaxGetRequest: 1,
+		FlushTimeout:  time.Millisecond,
+	}, 0, nil)
+	pipeline := makePipeline(t, Settings{}, q)
+	defer pipeline.Close()
+
+	// Publish blocks until the event is in the queue, so each event needs its
+	// own client to fill the queue and its input channel. Nothing consumes
+	// from the queue.
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		c, err := pipeline.Connect()
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Publish(beat.Event{Fields: mapstr.M{"i": i}})
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return reg.Get("queue.full.events").(*monitoring.Uint).Get() > 0
+	}, 10*time.Second, time.Millisecond, "blocked publish should be reported as queue full")
+
+	for i := 0; i < 30; i++ {
+		batch, err := q.Get(1)
+		require.NoError(t, err)
+		batch.Done()
+	}
+	wg.Wait()
+}
+
+func TestClientCloseWaiterTimeout(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	w := newClientCloseWaiter(clock, time.Minute)
+	w.AddEvent(beat.Event{}, true)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.signalClose()
+		w.wait()
+	}()
+
+	// The waiter gives up on the event not ACKed once the timeout passed on
+	// the clock.
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("waiter returned before the timeout")
+	default:
+	}
+	clock.Advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("waiter did not return after the timeout")
+	}
+}
+
 func TestClientWaitClose(t *testing.T) {
 	logger := logp.NewTestingLogger(t, "")
 	makePipeline := func(settings Settings, qu queue.Queue) *Pipeline {
@@ -468,6 +881,88 @@ func (p processorList) Run(in *beat.Event) (event *beat.Event, err error) {
 	return in, nil
 }
 
+// splitTestProcessor replaces an event with n events, n being the value of
+// the field "n".
+type splitTestProcessor struct{}
+
+func (p *splitTestProcessor) String() string {
+	return "splitTestProcessor"
+}
+
+func (p *splitTestProcessor) Run(in *beat.Event) (*beat.Event, error) {
+	return in, nil
+}
+
+func (p *splitTestProcessor) RunSplit(in *beat.Event) ([]*beat.Event, error) {
+	//nolint:errcheck // the field is always set in tests
+	n := in.Fields["n"].(int)
+	events := make([]*beat.Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, &beat.Event{Fields: mapstr.M{"i": i}})
+	}
+	return events, nil
+}
+
+// deadlineTestProcessor drops events with the slow field as if they
+// exceeded the maximum processing time.
+type deadlineTestProcessor struct{}
+
+func (p *deadlineTestProcessor) String() string {
+	return "deadlineTestProcessor"
+}
+
+func (p *deadlineTestProcessor) Run(in *beat.Event) (*beat.Event, error) {
+	if _, ok := in.Fields["slow"]; ok {
+		return nil, fmt.Errorf("%w of 1s", processing.ErrDeadlineExceeded)
+	}
+	return in, nil
+}
+
+// flushTestProcessor holds back all events until it is flushed.
+type flushTestProcessor struct {
+	events []*beat.Event
+}
+
+func (p *flushTestProcessor) String() string {
+	return "flushTestProcessor"
+}
+
+func (p *flushTestProcessor) Run(in *beat.Event) (*beat.Event, error) {
+	p.events = append(p.events, in)
+	return nil, nil
+}
+
+func (p *flushTestProcessor) Flush() []*beat.Event {
+	events := p.events
+	p.events = nil
+	return events
+}
+
+type countingEventListener struct {
+	mu        sync.Mutex
+	published int
+	filtered  int
+	acked     int
+}
+
+func (l *countingEventListener) AddEvent(_ beat.Event, published bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if published {
+		l.published++
+	} else {
+		l.filtered++
+	}
+}
+
+func (l *countingEventListener) ACKEvents(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.acked += n
+}
+
+func (l *countingEventListener) ClientClosed() {}
+
 type testProcessorSupporter struct {
 	beat.Processor
 }
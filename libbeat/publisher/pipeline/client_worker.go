@@ -20,6 +20,11 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
 
 	"github.com/elastic/beats/v7/libbeat/publisher"
 
@@ -31,29 +36,66 @@ import (
 type worker struct {
 	qu     chan publisher.Batch
 	cancel func()
+
+	// stop is closed by Drain, making the worker exit once the batch it is
+	// publishing, if any, is finished.
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// done is closed when the run loop of the worker returned.
+	done chan struct{}
+
+	// clock measures the drain timeout.
+	clock clockwork.Clock
+
+	closeOnce sync.Once
 }
 
 // clientWorker manages output client of type outputs.Client, not supporting reconnect.
 type clientWorker struct {
-	worker
+	*worker
 	client outputs.Client
 }
 
 // netClientWorker manages reconnectable output clients of type outputs.NetworkClient.
 type netClientWorker struct {
-	worker
+	*worker
 	client outputs.NetworkClient
 
+	// connected is set while the client is connected to the output.
+	connected atomic.Bool
+
 	logger logger
 
 	tracer *apm.Tracer
+
+	// stateListeners are notified when the client connects or disconnects.
+	stateListeners *outputStateListeners
+
+	// reconnectLimiter limits the connection attempts of all output clients.
+	reconnectLimiter *reconnectLimiter
+
+	// slowStart ramps up the publishing rate after a reconnect.
+	slowStart *slowStart
 }
 
-func makeClientWorker(qu chan publisher.Batch, client outputs.Client, logger logger, tracer *apm.Tracer) outputWorker {
+func makeClientWorker(
+	qu chan publisher.Batch,
+	client outputs.Client,
+	logger logger,
+	tracer *apm.Tracer,
+	stateListeners *outputStateListeners,
+	reconnectLimiter *reconnectLimiter,
+	slowStart *slowStart,
+	clock clockwork.Clock,
+) outputWorker {
 	ctx, cancel := context.WithCancel(context.Background())
-	w := worker{
+	w := &worker{
 		qu:     qu,
 		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		clock:  clock,
 	}
 
 	var c interface {
@@ -63,10 +105,13 @@ func makeClientWorker(qu chan publisher.Batch, client outputs.Client, logger log
 
 	if nc, ok := client.(outputs.NetworkClient); ok {
 		c = &netClientWorker{
-			worker: w,
-			client: nc,
-			logger: logger,
-			tracer: tracer,
+			worker:           w,
+			client:           nc,
+			logger:           logger,
+			tracer:           tracer,
+			stateListeners:   stateListeners,
+			reconnectLimiter: reconnectLimiter,
+			slowStart:        slowStart,
 		}
 	} else {
 		c = &clientWorker{worker: w, client: client}
@@ -80,81 +125,207 @@ func (w *worker) close() {
 	w.cancel()
 }
 
-func (w *clientWorker) Close() error {
-	w.worker.close()
-	return w.client.Close()
+// closeClient closes the output client once, as a draining worker can be
+// closed by the pipeline shutting down.
+func (w *worker) closeClient(client outputs.Client) error {
+	var err error
+	w.closeOnce.Do(func() {
+		err = client.Close()
+	})
+	return err
 }
 
-func (w *clientWorker) run(ctx context.Context) {
+// drain stops the worker from taking new batches and waits up to timeout for
+// the batch in progress to be published, before cancelling it.
+func (w *worker) drain(timeout time.Duration) {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	select {
+	case <-w.done:
+	case <-w.clock.After(timeout):
+	}
+	w.close()
+}
+
+func (w *worker) stopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// nextBatch waits for the next batch to publish. It returns nil if the worker
+// is closed or drained.
+func (w *worker) nextBatch(ctx context.Context) publisher.Batch {
 	for {
-		// We wait for either the worker to be closed or for there to be a batch of
-		// events to publish.
-		select {
+		if w.stopped() {
+			return nil
+		}
 
+		select {
 		case <-ctx.Done():
-			return
+			return nil
+
+		case <-w.stop:
+			return nil
 
 		case batch := <-w.qu:
 			if batch == nil {
 				continue
 			}
-			if err := w.client.Publish(ctx, batch); err != nil {
-				return
+			if w.stopped() {
+				// Hand the batch over to the workers that replace this one.
+				batch.Cancelled()
+				return nil
 			}
+			return batch
+		}
+	}
+}
+
+func (w *clientWorker) Close() error {
+	w.worker.close()
+	return w.closeClient(w.client)
+}
+
+func (w *clientWorker) Drain(timeout time.Duration) error {
+	w.worker.drain(timeout)
+	return w.closeClient(w.client)
+}
+
+func (w *clientWorker) String() string {
+	return w.client.String()
+}
+
+// Connected always returns true, as clients not supporting reconnect are
+// always ready to publish.
+func (w *clientWorker) Connected() bool {
+	return true
+}
+
+func (w *clientWorker) run(ctx context.Context) {
+	defer close(w.done)
+
+	for {
+		// We wait for either the worker to be closed or for there to be a batch of
+		// events to publish.
+		batch := w.nextBatch(ctx)
+		if batch == nil {
+			return
+		}
+		if err := w.client.Publish(ctx, batch); err != nil {
+			return
 		}
 	}
 }
 
 func (w *netClientWorker) Close() error {
 	w.worker.close()
-	return w.client.Close()
+	return w.closeClient(w.client)
+}
+
+func (w *netClientWorker) Drain(timeout time.Duration) error {
+	w.worker.drain(timeout)
+	return w.closeClient(w.client)
+}
+
+func (w *netClientWorker) Connected() bool {
+	return w.connected.Load()
+}
+
+func (w *netClientWorker) String() string {
+	return w.client.String()
 }
 
 func (w *netClientWorker) run(ctx context.Context) {
 	var (
 		connected         = false
 		reconnectAttempts = 0
-	)
+		connectedBefore   = false
 
-	for {
-		// We wait for either the worker to be closed or for there to be a batch of
-		// events to publish.
-		select {
+		// ramp limits the publishing rate after a reconnect, nil if the
+		// rate is not limited.
+		ramp *slowStartRamp
+	)
+	defer close(w.done)
+	defer w.connected.Store(false)
+	defer func() { ramp.finish() }()
 
-		case <-ctx.Done():
+	connect := func() {
+		if err := w.reconnectLimiter.wait(ctx); err != nil {
+			// The worker is closed.
 			return
+		}
 
-		case batch := <-w.qu:
-			if batch == nil {
-				continue
+		if reconnectAttempts == 0 {
+			w.logger.Infof("Connecting to %v", w.client)
+		} else {
+			w.logger.Infof("Attempting to reconnect to %v with %d reconnect attempt(s)", w.client, reconnectAttempts)
+		}
+
+		err := w.client.Connect(ctx)
+		connected = err == nil
+		w.connected.Store(connected)
+		if connected {
+			w.logger.Infof("Connection to %v established", w.client)
+			reconnectAttempts = 0
+			w.stateListeners.connected(w.client.String())
+			if connectedBefore {
+				ramp = w.slowStart.begin()
+				if ramp != nil {
+					w.logger.Infof("Ramping up the publishing rate of %v over %v", w.client, w.slowStart.config.Duration)
+				}
 			}
+			connectedBefore = true
+		} else {
+			w.logger.Errorf("Failed to connect to %v: %v", w.client, err)
+			reconnectAttempts++
+		}
+	}
 
-			// Try to (re)connect so we can publish batch
-			if !connected {
-				// Return batch to other output workers while we try to (re)connect
-				batch.Cancelled()
+	// Connect right away, so an idle worker reports its connection state.
+	// Failed connections are retried once there is a batch to publish.
+	connect()
 
-				if reconnectAttempts == 0 {
-					w.logger.Infof("Connecting to %v", w.client)
-				} else {
-					w.logger.Infof("Attempting to reconnect to %v with %d reconnect attempt(s)", w.client, reconnectAttempts)
-				}
+	for {
+		// We wait for either the worker to be closed or for there to be a batch of
+		// events to publish.
+		batch := w.nextBatch(ctx)
+		if batch == nil {
+			return
+		}
 
-				err := w.client.Connect(ctx)
-				connected = err == nil
-				if connected {
-					w.logger.Infof("Connection to %v established", w.client)
-					reconnectAttempts = 0
-				} else {
-					w.logger.Errorf("Failed to connect to %v: %v", w.client, err)
-					reconnectAttempts++
-				}
+		// Try to (re)connect so we can publish batch
+		if !connected {
+			// Return batch to other output workers while we try to (re)connect
+			batch.Cancelled()
+			connect()
+			continue
+		}
 
+		if ramp != nil {
+			ramping, err := ramp.wait(ctx, len(batch.Events()))
+			if err != nil {
+				// The worker is closed.
+				batch.Cancelled()
 				continue
 			}
+			if !ramping {
+				w.logger.Infof("Publishing rate of %v fully ramped up", w.client)
+				ramp = nil
+			}
+		}
 
-			if err := w.publishBatch(ctx, batch); err != nil {
-				connected = false
+		if err := w.publishBatch(ctx, batch); err != nil {
+			connected = false
+			w.connected.Store(false)
+			ramp.finish()
+			ramp = nil
+			if ctx.Err() == nil {
+				// Failures caused by the worker being closed are not reported.
+				w.stateListeners.disconnected(w.client.String(), err)
 			}
 		}
 	}
@@ -18,6 +18,7 @@
 package pipeline
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -29,6 +30,8 @@ import (
 
 	"go.elastic.co/apm/v2/apmtest"
 
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/v7/libbeat/internal/testutil"
@@ -64,7 +67,7 @@ func TestMakeClientWorker(t *testing.T) {
 
 				client := ctor(publishFn)
 
-				worker := makeClientWorker(workQueue, client, logger, nil)
+				worker := makeClientWorker(workQueue, client, logger, nil, nil, nil, nil, clockwork.NewRealClock())
 				defer worker.Close()
 
 				for i := uint(0); i < numBatches; i++ {
@@ -158,7 +161,7 @@ func TestReplaceClientWorker(t *testing.T) {
 				}
 
 				client := ctor(blockingPublishFn)
-				worker := makeClientWorker(workQueue, client, logger, nil)
+				worker := makeClientWorker(workQueue, client, logger, nil, nil, nil, nil, clockwork.NewRealClock())
 
 				// Allow the worker to make *some* progress before we close it
 				timeout := 10 * time.Second
@@ -185,7 +188,7 @@ func TestReplaceClientWorker(t *testing.T) {
 				}
 
 				client = ctor(countingPublishFn)
-				makeClientWorker(workQueue, client, logger, nil)
+				makeClientWorker(workQueue, client, logger, nil, nil, nil, nil, clockwork.NewRealClock())
 				wg.Wait()
 
 				// Make sure that all events have eventually been published
@@ -208,6 +211,74 @@ func TestReplaceClientWorker(t *testing.T) {
 	}
 }
 
+func TestDrainClientWorker(t *testing.T) {
+	tests := map[string]func(mockPublishFn) outputs.Client{
+		"client":         newMockClient,
+		"network_client": newMockNetworkClient,
+	}
+
+	for name, ctor := range tests {
+		t.Run(name, func(t *testing.T) {
+			logger := makeBufLogger(t)
+
+			workQueue := make(chan publisher.Batch)
+			retryer := newStandaloneRetryer(workQueue)
+			defer retryer.close()
+
+			publishing := make(chan struct{})
+			release := make(chan struct{})
+			var publishedOld atomic.Uint64
+			oldClient := ctor(func(batch publisher.Batch) error {
+				close(publishing)
+				<-release
+				publishedOld.Add(uint64(len(batch.Events())))
+				return nil
+			})
+			oldWorker := makeClientWorker(workQueue, oldClient, logger, nil, nil, nil, nil, clockwork.NewRealClock())
+
+			inFlight := randomBatch(10, 20).withRetryer(retryer)
+			go func() { workQueue <- inFlight }()
+			<-publishing
+
+			drained := make(chan struct{})
+			go func() {
+				assert.NoError(t, oldWorker.Drain(time.Minute))
+				close(drained)
+			}()
+
+			// New batches are published by the new worker while the old one
+			// is still busy with its in-flight batch.
+			var publishedNew atomic.Uint64
+			newWorker := makeClientWorker(workQueue, ctor(func(batch publisher.Batch) error {
+				publishedNew.Add(uint64(len(batch.Events())))
+				return nil
+			}), logger, nil, nil, nil, nil, clockwork.NewRealClock())
+			defer newWorker.Close()
+
+			next := randomBatch(10, 20).withRetryer(retryer)
+			go func() { workQueue <- next }()
+			require.True(t, waitUntilTrue(10*time.Second, func() bool {
+				return publishedNew.Load() == uint64(next.Len())
+			}), "new worker should publish new batches")
+
+			select {
+			case <-drained:
+				t.Fatal("Drain returned before the in-flight batch was published")
+			default:
+			}
+
+			close(release)
+			select {
+			case <-drained:
+			case <-time.After(10 * time.Second):
+				t.Fatal("Drain did not return after the in-flight batch was published")
+			}
+			assert.Equal(t, uint64(inFlight.Len()), publishedOld.Load(),
+				"in-flight batch should be published by the old worker")
+		})
+	}
+}
+
 func TestMakeClientTracer(t *testing.T) {
 	testutil.SeedPRNG(t)
 
@@ -231,7 +302,7 @@ func TestMakeClientTracer(t *testing.T) {
 	recorder := apmtest.NewRecordingTracer()
 	defer recorder.Close()
 
-	worker := makeClientWorker(workQueue, client, logger, recorder.Tracer)
+	worker := makeClientWorker(workQueue, client, logger, recorder.Tracer, nil, nil, nil, clockwork.NewRealClock())
 	defer worker.Close()
 
 	for i := 0; i < numBatches; i++ {
@@ -260,6 +331,141 @@ func TestMakeClientTracer(t *testing.T) {
 	}
 }
 
+func TestClientWorkerOutputStateListener(t *testing.T) {
+	logger := makeBufLogger(t)
+	workQueue := make(chan publisher.Batch)
+	retryer := newStandaloneRetryer(workQueue)
+	defer retryer.close()
+
+	publishErr := errors.New("connection reset")
+	var fail atomic.Bool
+	client := newMockNetworkClient(func(batch publisher.Batch) error {
+		if fail.Swap(false) {
+			batch.Retry()
+			return publishErr
+		}
+		batch.ACK()
+		return nil
+	})
+
+	listener := &recordingStateListener{}
+	listeners := &outputStateListeners{}
+	remove := listeners.add(listener)
+
+	worker := makeClientWorker(workQueue, client, logger, nil, listeners, nil, nil, clockwork.NewRealClock())
+	defer worker.Close()
+
+	publish := func() {
+		t.Helper()
+		acked := make(chan struct{})
+		batch := randomBatch(1, 2).withRetryer(retryer)
+		batch.onACK = func() { close(acked) }
+		workQueue <- batch
+		select {
+		case <-acked:
+		case <-time.After(10 * time.Second):
+			t.Fatal("batch has not been published")
+		}
+	}
+
+	publish()
+	assert.Equal(t, []string{"connect mock_client"}, listener.get())
+
+	// The failed batch is retried after reconnecting.
+	fail.Store(true)
+	publish()
+	assert.Equal(t, []string{
+		"connect mock_client",
+		"disconnect mock_client: failed to publish events: connection reset",
+		"connect mock_client",
+	}, listener.get())
+
+	// Removed listeners are not notified anymore.
+	remove()
+	fail.Store(true)
+	publish()
+	assert.Len(t, listener.get(), 3)
+}
+
+func TestClientWorkerSlowStartAfterReconnect(t *testing.T) {
+	logger := makeBufLogger(t)
+	workQueue := make(chan publisher.Batch)
+	retryer := newStandaloneRetryer(workQueue)
+	defer retryer.close()
+
+	var fail atomic.Bool
+	client := newMockNetworkClient(func(batch publisher.Batch) error {
+		if fail.Swap(false) {
+			batch.Retry()
+			return errors.New("connection reset")
+		}
+		batch.ACK()
+		return nil
+	})
+
+	slowStart := newSlowStart(SlowStartConfig{
+		Duration:               time.Hour,
+		InitialEventsPerSecond: 1e6,
+		FinalEventsPerSecond:   1e6,
+	}, clockwork.NewRealClock(), nil)
+
+	worker := makeClientWorker(workQueue, client, logger, nil, nil, nil, slowStart, clockwork.NewRealClock())
+	defer worker.Close()
+
+	publish := func() {
+		t.Helper()
+		acked := make(chan struct{})
+		batch := randomBatch(1, 2).withRetryer(retryer)
+		batch.onACK = func() { close(acked) }
+		workQueue <- batch
+		select {
+		case <-acked:
+		case <-time.After(10 * time.Second):
+			t.Fatal("batch has not been published")
+		}
+	}
+
+	// The first connection is not ramped up.
+	publish()
+	ramping, _ := slowStart.state()
+	assert.Equal(t, 0, ramping)
+
+	fail.Store(true)
+	publish()
+	ramping, rate := slowStart.state()
+	assert.Equal(t, 1, ramping)
+	assert.Equal(t, 1e6, rate)
+
+	require.NoError(t, worker.Close())
+	require.Eventually(t, func() bool {
+		ramping, _ := slowStart.state()
+		return ramping == 0
+	}, 10*time.Second, 10*time.Millisecond, "the ramp must end when the worker is closed")
+}
+
+type recordingStateListener struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *recordingStateListener) OnConnect(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, "connect "+host)
+}
+
+func (l *recordingStateListener) OnDisconnect(host string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, fmt.Sprintf("disconnect %s: %v", host, err))
+}
+
+func (l *recordingStateListener) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
 // bufLogger is a buffered logger. It does not immediately print out log lines; instead it
 // buffers them. To print them out, one must explicitly call it's Flush() method. This is
 // useful when you want to see the logs only when tests fail but not when they pass.
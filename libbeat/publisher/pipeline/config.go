@@ -20,6 +20,7 @@ package pipeline
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/processors"
@@ -35,6 +36,36 @@ type Config struct {
 
 	// Event queue
 	Queue config.Namespace `config:"queue"`
+
+	// Output congestion detection
+	Congestion CongestionConfig `config:"pipeline.congestion"`
+
+	// Warning about outputs not keeping up
+	SlowConsumer SlowConsumerConfig `config:"pipeline.slow_consumer"`
+
+	// Periodic heartbeat event
+	Heartbeat HeartbeatConfig `config:"pipeline.heartbeat"`
+
+	// Logging of sampled dropped events
+	DroppedEventLog DroppedEventLogConfig `config:"pipeline.dropped_event_log"`
+
+	// Maximum number of clients connected at the same time
+	MaxClients int `config:"pipeline.max_clients" validate:"min=0"`
+
+	// Rate limit of the output connection attempts
+	ReconnectLimit ReconnectLimitConfig `config:"pipeline.reconnect_limit"`
+
+	// Ramp up of the output publishing rate after a reconnect
+	SlowStart SlowStartConfig `config:"pipeline.slow_start"`
+
+	// Time the outputs get to acknowledge a batch before it is retried
+	ACKTimeout time.Duration `config:"pipeline.ack_timeout" validate:"min=0"`
+
+	// Separate queue space for the clients dropping events if the queue is full
+	QueuePartitions QueuePartitionsConfig `config:"pipeline.queue_partitions"`
+
+	// Number of events a client processes before letting other clients run
+	PublishChunkSize int `config:"pipeline.publish_chunk_size" validate:"min=0"`
 }
 
 // validateClientConfig checks a ClientConfig can be used with (*Pipeline).ConnectWith.
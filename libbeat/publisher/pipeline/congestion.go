@@ -0,0 +1,220 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+const (
+	defaultCongestionInterval  = time.Second
+	defaultCongestionWindow    = 10
+	defaultCongestionThreshold = 0.5
+)
+
+// CongestionConfig configures the detection of a congested output. When
+// enabled, the pipeline samples the number of events published to the queue
+// and acknowledged by the outputs every Interval. If the moving average of
+// the ACK rate over the last Window samples falls below Threshold times the
+// moving average of the publish rate, the output is considered congested and
+// all connected clients are informed via beat.CongestionListener.
+type CongestionConfig struct {
+	Enabled   bool          `config:"enabled"`
+	Interval  time.Duration `config:"interval" validate:"min=0"`
+	Window    int           `config:"window" validate:"min=0"`
+	Threshold float64       `config:"threshold" validate:"min=0"`
+}
+
+// congestionMonitor compares the publish and ACK rates of the pipeline and
+// informs registered client listeners about congestion state changes.
+type congestionMonitor struct {
+	logger    *logp.Logger
+	clock     clockwork.Clock
+	interval  time.Duration
+	threshold float64
+
+	counter counterSampler
+
+	mutex     sync.Mutex
+	listeners map[*client]beat.CongestionListener
+	congested bool
+
+	// Ring buffers holding the last published/acked deltas.
+	pubSamples []uint64
+	ackSamples []uint64
+	next       int
+	filled     int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newCongestionMonitor creates a congestionMonitor for the given config.
+// If congestion detection is disabled, nil is returned. All methods
+// of congestionMonitor are safe to be called on a nil receiver.
+func newCongestionMonitor(logger *logp.Logger, clock clockwork.Clock, config CongestionConfig, counter *eventCounter) *congestionMonitor {
+	if !config.Enabled {
+		return nil
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultCongestionInterval
+	}
+	window := config.Window
+	if window <= 0 {
+		window = defaultCongestionWindow
+	}
+	threshold := config.Threshold
+	if threshold <= 0 {
+		threshold = defaultCongestionThreshold
+	}
+
+	return &congestionMonitor{
+		logger:     logger,
+		clock:      clock,
+		interval:   interval,
+		threshold:  threshold,
+		counter:    counterSampler{counter: counter},
+		listeners:  map[*client]beat.CongestionListener{},
+		pubSamples: make([]uint64, window),
+		ackSamples: make([]uint64, window),
+		done:       make(chan struct{}),
+	}
+}
+
+func (m *congestionMonitor) start() {
+	if m == nil {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run()
+	}()
+}
+
+func (m *congestionMonitor) close() {
+	if m == nil {
+		return
+	}
+
+	close(m.done)
+	m.wg.Wait()
+}
+
+func (m *congestionMonitor) run() {
+	ticker := m.clock.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.Chan():
+			m.sample(m.counter.sample())
+		}
+	}
+}
+
+// sample records the number of events published and acked during the last
+// interval and updates the congestion state if required.
+func (m *congestionMonitor) sample(published, acked uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.pubSamples[m.next] = published
+	m.ackSamples[m.next] = acked
+
+	m.next = (m.next + 1) % len(m.pubSamples)
+	if m.filled < len(m.pubSamples) {
+		m.filled++
+		// Wait for a full window before making any decision, so short
+		// bursts at startup do not flip the state.
+		if m.filled < len(m.pubSamples) {
+			return
+		}
+	}
+
+	var pubSum, ackSum uint64
+	for i := range m.pubSamples {
+		pubSum += m.pubSamples[i]
+		ackSum += m.ackSamples[i]
+	}
+	if pubSum == 0 && ackSum == 0 {
+		// no activity, keep the current state
+		return
+	}
+
+	congested := float64(ackSum) < m.threshold*float64(pubSum)
+	if congested == m.congested {
+		return
+	}
+
+	m.congested = congested
+	if congested {
+		m.logger.Warnf("Output congestion detected: average ACK rate %.1f/s is below the publish rate %.1f/s",
+			m.rate(ackSum), m.rate(pubSum))
+	} else {
+		m.logger.Info("Output congestion resolved")
+	}
+	for _, listener := range m.listeners {
+		listener.OutputCongested(congested)
+	}
+}
+
+func (m *congestionMonitor) rate(sum uint64) float64 {
+	return float64(sum) / (float64(len(m.pubSamples)) * m.interval.Seconds())
+}
+
+// addClient registers the client listener to be informed about congestion
+// state changes, if it implements beat.CongestionListener. If the output is
+// currently congested, the listener is informed immediately.
+func (m *congestionMonitor) addClient(c *client) {
+	if m == nil {
+		return
+	}
+	listener, ok := c.clientListener.(beat.CongestionListener)
+	if !ok {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.listeners[c] = listener
+	if m.congested {
+		listener.OutputCongested(true)
+	}
+}
+
+func (m *congestionMonitor) removeClient(c *client) {
+	if m == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.listeners, c)
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+type congestionListener struct {
+	mockClientListener
+	states []bool
+}
+
+func (l *congestionListener) OutputCongested(congested bool) {
+	l.states = append(l.states, congested)
+}
+
+func TestCongestionMonitorDisabled(t *testing.T) {
+	m := newCongestionMonitor(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), CongestionConfig{}, nil)
+	require.Nil(t, m)
+
+	// all methods must be safe on a nil monitor
+	m.start()
+	m.addClient(&client{})
+	m.removeClient(&client{})
+	m.close()
+}
+
+func TestCongestionMonitor(t *testing.T) {
+	m := newCongestionMonitor(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), CongestionConfig{
+		Enabled:   true,
+		Window:    3,
+		Threshold: 0.5,
+	}, nil)
+	require.NotNil(t, m)
+
+	listener := &congestionListener{}
+	m.addClient(&client{clientListener: listener})
+
+	step := func(pub, ack uint64) {
+		m.sample(pub, ack)
+	}
+
+	// output keeps up
+	for i := 0; i < 5; i++ {
+		step(100, 100)
+	}
+	assert.Empty(t, listener.states)
+
+	// a single slow interval is not enough to signal congestion
+	step(100, 10)
+	assert.Empty(t, listener.states)
+
+	// sustained slow ACKs signal congestion
+	step(100, 10)
+	step(100, 10)
+	assert.Equal(t, []bool{true}, listener.states)
+
+	// no activity keeps the state
+	step(0, 0)
+	step(0, 0)
+	step(0, 0)
+	assert.Equal(t, []bool{true}, listener.states)
+
+	// output catches up
+	step(100, 300)
+	assert.Equal(t, []bool{true, false}, listener.states)
+
+	// new clients are not informed while not congested
+	other := &congestionListener{}
+	m.addClient(&client{clientListener: other})
+	assert.Empty(t, other.states)
+}
+
+func TestCongestionMonitorInformsNewClients(t *testing.T) {
+	m := newCongestionMonitor(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), CongestionConfig{
+		Enabled: true,
+		Window:  1,
+	}, nil)
+	m.sample(100, 0)
+
+	listener := &congestionListener{}
+	c := &client{clientListener: listener}
+	m.addClient(c)
+	assert.Equal(t, []bool{true}, listener.states)
+
+	m.removeClient(c)
+	m.sample(100, 200)
+	assert.Equal(t, []bool{true}, listener.states)
+}
+
+func TestCongestionMonitorSkipsPlainListeners(t *testing.T) {
+	m := newCongestionMonitor(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), CongestionConfig{
+		Enabled: true,
+		Window:  1,
+	}, nil)
+	m.sample(100, 0)
+
+	// listeners not implementing beat.CongestionListener are not registered
+	m.addClient(&client{clientListener: &mockClientListener{}})
+	assert.Empty(t, m.listeners)
+}
+
+var _ beat.CongestionListener = (*congestionListener)(nil)
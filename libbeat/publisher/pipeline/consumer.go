@@ -19,6 +19,9 @@ package pipeline
 
 import (
 	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
 
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
@@ -31,7 +34,8 @@ import (
 type eventConsumer struct {
 	logger *logp.Logger
 
-	// eventConsumer calls the retryObserver methods eventsRetry and eventsDropped.
+	// eventConsumer calls the retryObserver methods eventsRetry, eventsDropped
+	// and guaranteedEventsDropped.
 	retryObserver retryObserver
 
 	// When the output changes, the new target is sent to the worker routine
@@ -46,6 +50,10 @@ type eventConsumer struct {
 	// eventConsumer.close().
 	done chan struct{}
 
+	// ackTimeouts requeues the events of batches the output did not complete
+	// within the ACK timeout of the target.
+	ackTimeouts *ackTimeouts
+
 	// queueReader is a helper routine that fetches queue batches in a
 	// separate goroutine so we don't block on the control path.
 	queueReader queueReader
@@ -62,6 +70,14 @@ type consumerTarget struct {
 	ch         chan publisher.Batch
 	timeToLive int
 	batchSize  int
+
+	// guaranteedTimeToLive limits the retries of guaranteed events, if
+	// positive.
+	guaranteedTimeToLive int
+
+	// ackTimeout is the time the output gets to complete a batch before its
+	// events are requeued, if positive.
+	ackTimeout time.Duration
 }
 
 // retryRequest is used by ttlBatch to add itself back to the eventConsumer
@@ -74,10 +90,12 @@ type retryRequest struct {
 func newEventConsumer(
 	log *logp.Logger,
 	observer retryObserver,
+	clock clockwork.Clock,
 ) *eventConsumer {
 	c := &eventConsumer{
 		logger:        log,
 		retryObserver: observer,
+		ackTimeouts:   newACKTimeouts(clock, log),
 		queueReader:   makeQueueReader(),
 
 		targetChan: make(chan consumerTarget),
@@ -136,6 +154,8 @@ outerLoop:
 				retryer:    c,
 				batchSize:  target.batchSize,
 				timeToLive: target.timeToLive,
+
+				guaranteedTimeToLive: target.guaranteedTimeToLive,
 			}
 		}
 
@@ -161,6 +181,9 @@ outerLoop:
 		select {
 		case outputChan <- active:
 			// Successfully sent a batch to the output workers
+			if target.ackTimeout > 0 {
+				active.startACKTimeout(c.ackTimeouts, target.ackTimeout)
+			}
 			if len(retryBatches) > 0 {
 				// This was a retry, report it to the observer
 				c.retryObserver.eventsRetry(len(active.Events()))
@@ -177,13 +200,18 @@ outerLoop:
 			pendingRead = false
 
 		case req := <-c.retryChan:
+			req.batch.resetACKTimeout()
 			if req.decreaseTTL {
 				countFailed := len(req.batch.Events())
 
-				alive := req.batch.reduceTTL()
+				alive, guaranteedDropped := req.batch.reduceTTL()
 
 				countDropped := countFailed - len(req.batch.Events())
 				c.retryObserver.eventsDropped(countDropped)
+				if guaranteedDropped > 0 {
+					log.Warnf("Dropped %d guaranteed events after exhausting their retries", guaranteedDropped)
+					c.retryObserver.guaranteedEventsDropped(guaranteedDropped)
+				}
 
 				if !alive {
 					log.Info("Drop batch")
@@ -222,4 +250,5 @@ func (c *eventConsumer) retry(batch *ttlBatch, decreaseTTL bool) {
 func (c *eventConsumer) close() {
 	close(c.done)
 	c.wg.Wait()
+	c.ackTimeouts.close()
 }
@@ -21,6 +21,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jonboulle/clockwork"
+
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common/reload"
 	"github.com/elastic/beats/v7/libbeat/outputs"
@@ -48,6 +50,10 @@ type outputController struct {
 	queueLock       sync.Mutex
 	pendingRequests []producerRequest
 
+	// queueFill tracks the fill level of the queue, it is set together
+	// with queue.
+	queueFill *queueFillObserver
+
 	// This factory will be used to create the queue when needed, unless
 	// it is overridden by output configuration when outputController.Set
 	// is called.
@@ -59,8 +65,37 @@ type outputController struct {
 
 	// Each worker is a goroutine that will read batches from workerChan and
 	// send them to the output.
-	workers    []outputWorker
-	workerChan chan publisher.Batch
+	workers     []outputWorker
+	workersLock sync.Mutex
+	workerChan  chan publisher.Batch
+
+	// Workers replaced by a reload, still finishing their in-flight batch.
+	draining     map[outputWorker]struct{}
+	drainTimeout time.Duration
+
+	// stateListeners are notified by the workers when their output client
+	// connects or disconnects.
+	stateListeners *outputStateListeners
+
+	// reconnectLimiter limits the connection attempts of the output clients,
+	// it is shared by the workers of all output configurations.
+	reconnectLimiter *reconnectLimiter
+
+	// slowStart ramps up the publishing rate of the output clients after a
+	// reconnect, nil if disabled.
+	slowStart *slowStart
+
+	// ackTimeout is the time the output gets to acknowledge a batch before
+	// its events are requeued. 0 means no timeout.
+	ackTimeout time.Duration
+
+	// clock measures the ACK timeout, the time the queue gets to drain on
+	// close and the time the replaced output workers get to drain.
+	clock clockwork.Clock
+
+	// queuePartitions is set if the events of DropIfFull clients are kept in
+	// a separate partition of the queue.
+	queuePartitions bool
 
 	// The InputQueueSize can be set when the Beat is started, in
 	// libbeat/cmd/instance/Settings we need to preserve that
@@ -72,6 +107,7 @@ type outputController struct {
 }
 
 type producerRequest struct {
+	mode         beat.PublishMode
 	config       queue.ProducerConfig
 	responseChan chan queue.Producer
 }
@@ -80,22 +116,41 @@ type producerRequest struct {
 // instances.
 type outputWorker interface {
 	Close() error
+
+	// Connected reports whether the worker's output client is connected.
+	Connected() bool
+
+	// Drain stops the worker from taking new batches, waits up to timeout
+	// for the batch in progress to be finished and closes the worker.
+	Drain(timeout time.Duration) error
+
+	// String returns the name of the worker's output client.
+	String() string
 }
 
+// outputDrainTimeout is the time workers replaced by an output reload get to
+// finish publishing their in-flight batch with the previous configuration.
+const outputDrainTimeout = 30 * time.Second
+
 func newOutputController(
 	beat beat.Info,
 	monitors Monitors,
 	retryObserver retryObserver,
 	queueFactory queue.QueueFactory,
 	inputQueueSize int,
+	clock clockwork.Clock,
 ) (*outputController, error) {
 	controller := &outputController{
 		beat:           beat,
 		monitors:       monitors,
 		queueFactory:   queueFactory,
 		workerChan:     make(chan publisher.Batch),
-		consumer:       newEventConsumer(monitors.Logger, retryObserver),
+		consumer:       newEventConsumer(monitors.Logger, retryObserver, clock),
 		inputQueueSize: inputQueueSize,
+		draining:       map[outputWorker]struct{}{},
+		drainTimeout:   outputDrainTimeout,
+		stateListeners: &outputStateListeners{},
+		clock:          clock,
 	}
 
 	return controller, nil
@@ -113,9 +168,14 @@ func (c *outputController) WaitClose(timeout time.Duration) error {
 	close(c.workerChan)
 
 	// Signal the output workers to close.
+	c.workersLock.Lock()
 	for _, out := range c.workers {
 		out.Close()
 	}
+	for out := range c.draining {
+		out.Close()
+	}
+	c.workersLock.Unlock()
 
 	return nil
 }
@@ -126,10 +186,14 @@ func (c *outputController) Set(outGrp outputs.Group) {
 	// Set consumer to empty target to pause it while we reload
 	c.consumer.setTarget(consumerTarget{})
 
-	// Close old outputWorkers, so they send their remaining events
-	// back to eventConsumer's retry channel
+	c.workersLock.Lock()
+	// Drain old outputWorkers: they stop taking new batches and finish
+	// publishing their in-flight batch with the previous configuration.
+	// Batches they can not finish in time are sent back to eventConsumer's
+	// retry channel, the queue and its pending events are kept.
 	for _, w := range c.workers {
-		w.Close()
+		c.draining[w] = struct{}{}
+		go c.drainWorker(w)
 	}
 
 	// create new output group with the shared work queue
@@ -137,8 +201,9 @@ func (c *outputController) Set(outGrp outputs.Group) {
 	c.workers = make([]outputWorker, len(clients))
 	for i, client := range clients {
 		logger := c.beat.Logger.Named("publisher_pipeline_output")
-		c.workers[i] = makeClientWorker(c.workerChan, client, logger, c.monitors.Tracer)
+		c.workers[i] = makeClientWorker(c.workerChan, client, logger, c.monitors.Tracer, c.stateListeners, c.reconnectLimiter, c.slowStart, c.clock)
 	}
+	c.workersLock.Unlock()
 
 	targetChan := c.workerChan
 	if len(clients) == 0 {
@@ -151,6 +216,11 @@ func (c *outputController) Set(outGrp outputs.Group) {
 		targetChan = nil
 	}
 
+	guaranteedTimeToLive := 0
+	if outGrp.GuaranteedRetry > 0 {
+		guaranteedTimeToLive = outGrp.GuaranteedRetry + 1
+	}
+
 	// Resume consumer targeting the new work queue
 	c.consumer.setTarget(
 		consumerTarget{
@@ -158,9 +228,32 @@ func (c *outputController) Set(outGrp outputs.Group) {
 			ch:         targetChan,
 			batchSize:  outGrp.BatchSize,
 			timeToLive: outGrp.Retry + 1,
+
+			guaranteedTimeToLive: guaranteedTimeToLive,
+			ackTimeout:           c.ackTimeout,
 		})
 }
 
+func (c *outputController) drainWorker(w outputWorker) {
+	w.Drain(c.drainTimeout)
+
+	c.workersLock.Lock()
+	delete(c.draining, w)
+	c.workersLock.Unlock()
+}
+
+// outputNames returns the names of the active output clients.
+func (c *outputController) outputNames() []string {
+	c.workersLock.Lock()
+	defer c.workersLock.Unlock()
+
+	names := make([]string, len(c.workers))
+	for i, w := range c.workers {
+		names[i] = w.String()
+	}
+	return names
+}
+
 // Reload the output
 func (c *outputController) Reload(
 	cfg *reload.ConfigWithMeta,
@@ -196,7 +289,12 @@ func (c *outputController) closeQueue(timeout time.Duration) {
 		c.queue.Close()
 		select {
 		case <-c.queue.Done():
-		case <-time.After(timeout):
+		case <-c.clock.After(timeout):
+			if p, ok := c.queue.(queue.Persister); ok {
+				if err := p.Persist(); err != nil {
+					c.monitors.Logger.Errorf("Failed to persist the queued events: %v", err)
+				}
+			}
 		}
 	}
 	for _, req := range c.pendingRequests {
@@ -210,7 +308,7 @@ func (c *outputController) closeQueue(timeout time.Duration) {
 
 // queueProducer creates a queue producer with the given config, blocking
 // until the queue is created if it does not yet exist.
-func (c *outputController) queueProducer(config queue.ProducerConfig) queue.Producer {
+func (c *outputController) queueProducer(mode beat.PublishMode, config queue.ProducerConfig) queue.Producer {
 	if publishDisabled {
 		// If publishDisabled is set ("-N" command line flag), then no output
 		// will ever be set, and no queue will ever be created. In this case,
@@ -225,11 +323,12 @@ func (c *outputController) queueProducer(config queue.ProducerConfig) queue.Prod
 		// queue doesn't exist we'll need to block until it does, and
 		// in that case we need to manually unlock before we start waiting.
 		defer c.queueLock.Unlock()
-		return c.queue.Producer(config)
+		return c.newProducer(mode, config)
 	}
 	// If there's no queue yet, create a producer request, release the
 	// queue lock, and wait to receive our producer.
 	request := producerRequest{
+		mode:         mode,
 		config:       config,
 		responseChan: make(chan queue.Producer),
 	}
@@ -269,8 +368,11 @@ func (c *outputController) createQueueIfNeeded(outGrp outputs.Group) {
 			pipelineMetrics = c.monitors.Metrics.NewRegistry("pipeline")
 		}
 	}
-	queueObserver := queue.NewQueueObserver(pipelineMetrics)
+	queueObserver := newQueueFillObserver(queue.NewQueueObserver(pipelineMetrics))
 
+	if c.queuePartitions {
+		factory = partitionedQueueFactory(factory, pipelineMetrics)
+	}
 	queue, err := factory(logger, queueObserver, c.inputQueueSize, outGrp.EncoderFactory)
 	if err != nil {
 		logger.Errorf("queue creation failed, falling back to default memory queue, check your queue configuration")
@@ -278,6 +380,7 @@ func (c *outputController) createQueueIfNeeded(outGrp outputs.Group) {
 		queue = memqueue.NewQueue(logger, queueObserver, s, c.inputQueueSize, outGrp.EncoderFactory)
 	}
 	c.queue = queue
+	c.queueFill = queueObserver
 
 	if c.monitors.Telemetry != nil {
 		queueReg := c.monitors.Telemetry.NewRegistry("queue")
@@ -287,7 +390,7 @@ func (c *outputController) createQueueIfNeeded(outGrp outputs.Group) {
 	// Now that we've created a queue, go through and unblock any callers
 	// that are waiting for a producer.
 	for _, req := range c.pendingRequests {
-		req.responseChan <- c.queue.Producer(req.config)
+		req.responseChan <- c.newProducer(req.mode, req.config)
 	}
 	c.pendingRequests = nil
 }
@@ -307,3 +410,12 @@ func (emptyProducer) TryPublish(_ queue.Entry) (queue.EntryID, bool) {
 
 func (emptyProducer) Close() {
 }
+
+// newProducer creates a producer for a client with the given publish mode.
+// Must be called with queueLock held.
+func (c *outputController) newProducer(mode beat.PublishMode, config queue.ProducerConfig) queue.Producer {
+	if q, ok := c.queue.(*partitionedQueue); ok {
+		return q.modeProducer(mode, config)
+	}
+	return c.queue.Producer(config)
+}
@@ -19,6 +19,7 @@ package pipeline
 
 import (
 	"fmt"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -37,6 +38,7 @@ import (
 
 	//"github.com/elastic/beats/v7/libbeat/tests/resources"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -197,6 +199,24 @@ func TestOutputQueueFactoryTakesPrecedence(t *testing.T) {
 	assert.Equal(t, 2, controller.queue.BufferConfig().MaxEvents, "Queue should be created using settings from the output")
 }
 
+func TestCloseQueuePersistsRemainingEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.snapshot")
+	q := memqueue.NewQueue(logp.NewTestingLogger(t, ""), nil,
+		memqueue.Settings{Events: 10, MaxGetRequest: 10, SnapshotPath: path}, 0, nil)
+	_, ok := q.Producer(queue.ProducerConfig{}).Publish(publisher.Event{})
+	require.True(t, ok)
+
+	controller := outputController{queue: q, clock: clockwork.NewRealClock()}
+	controller.closeQueue(0)
+
+	assert.FileExists(t, path, "events left in the queue should be saved")
+	select {
+	case <-q.Done():
+	default:
+		t.Fatal("the queue should be stopped once its events are saved")
+	}
+}
+
 func TestFailedQueueFactoryRevertsToDefault(t *testing.T) {
 	defaultSettings, _ := memqueue.SettingsForUserConfig(nil)
 	failedFactory := func(_ *logp.Logger, _ queue.Observer, _ int, _ queue.EncoderFactory) (queue.Queue, error) {
@@ -243,7 +263,7 @@ func TestQueueProducerBlocksUntilOutputIsSet(t *testing.T) {
 	remaining.Store(producerCount)
 	for i := 0; i < producerCount; i++ {
 		go func() {
-			controller.queueProducer(queue.ProducerConfig{})
+			controller.queueProducer(beat.DefaultGuarantees, queue.ProducerConfig{})
 			remaining.Add(-1)
 		}()
 	}
@@ -0,0 +1,183 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const (
+	defaultDroppedEventLogSampleRate   = 0.01
+	defaultDroppedEventLogLimit        = 10
+	defaultDroppedEventLogInterval     = time.Minute
+	defaultDroppedEventLogMaxFieldSize = 256
+)
+
+// DroppedEventLogConfig configures the logging of events dropped on publish,
+// because the queue is full or the pipeline is shutting down. When enabled,
+// SampleRate (a fraction) of the dropped events is logged to the event log,
+// at most Limit events every Interval. String values longer than
+// MaxFieldSize bytes are truncated.
+type DroppedEventLogConfig struct {
+	Enabled      bool          `config:"enabled"`
+	SampleRate   float64       `config:"sample_rate" validate:"min=0"`
+	Limit        int           `config:"limit" validate:"min=0"`
+	Interval     time.Duration `config:"interval" validate:"min=0"`
+	MaxFieldSize int           `config:"max_field_size" validate:"min=0"`
+}
+
+func (c *DroppedEventLogConfig) Validate() error {
+	if c.SampleRate > 1 {
+		return errors.New("sample_rate must not be greater than 1")
+	}
+	return nil
+}
+
+// droppedEventLogger logs a rate limited sample of the events dropped on
+// publish, giving operators representative examples of the events lost.
+type droppedEventLogger struct {
+	logger       *logp.Logger
+	sampleRate   float64
+	limit        int
+	interval     time.Duration
+	maxFieldSize int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	logged      int
+	suppressed  int
+
+	now    func() time.Time
+	sample func() float64
+}
+
+// newDroppedEventLogger creates a droppedEventLogger for the given config.
+// If logging dropped events is disabled, nil is returned. All methods of
+// droppedEventLogger are safe to be called on a nil receiver.
+func newDroppedEventLogger(logger *logp.Logger, clock clockwork.Clock, config DroppedEventLogConfig) *droppedEventLogger {
+	if !config.Enabled {
+		return nil
+	}
+
+	sampleRate := config.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = defaultDroppedEventLogSampleRate
+	}
+	limit := config.Limit
+	if limit <= 0 {
+		limit = defaultDroppedEventLogLimit
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultDroppedEventLogInterval
+	}
+	maxFieldSize := config.MaxFieldSize
+	if maxFieldSize <= 0 {
+		maxFieldSize = defaultDroppedEventLogMaxFieldSize
+	}
+
+	return &droppedEventLogger{
+		logger:       logger,
+		sampleRate:   sampleRate,
+		limit:        limit,
+		interval:     interval,
+		maxFieldSize: maxFieldSize,
+		now:          clock.Now,
+		sample:       rand.Float64,
+	}
+}
+
+// eventDropped logs the event if it is sampled and the limit of the current
+// interval is not reached yet.
+func (l *droppedEventLogger) eventDropped(event beat.Event, reason string) {
+	if l == nil || l.sample() >= l.sampleRate {
+		return
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	if now.Sub(l.windowStart) >= l.interval {
+		l.windowStart = now
+		l.logged = 0
+	}
+	if l.logged >= l.limit {
+		l.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	l.logged++
+	suppressed := l.suppressed
+	l.suppressed = 0
+	l.mu.Unlock()
+
+	l.logger.Warnw("Dropped event on publish: "+reason,
+		"event", l.truncate(mapstr.M{
+			"@timestamp": event.Timestamp,
+			"@metadata":  event.Meta,
+			"fields":     event.Fields,
+		}),
+		"sampled_events_suppressed", suppressed,
+		logp.TypeKey, logp.EventType)
+}
+
+// truncate returns a copy of v with all strings limited to maxFieldSize bytes.
+func (l *droppedEventLogger) truncate(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if len(v) <= l.maxFieldSize {
+			return v
+		}
+		cut := l.maxFieldSize
+		for cut > 0 && !utf8.RuneStart(v[cut]) {
+			cut--
+		}
+		return v[:cut] + "..."
+	case mapstr.M:
+		out := make(mapstr.M, len(v))
+		for k, val := range v {
+			out[k] = l.truncate(val)
+		}
+		return out
+	case map[string]interface{}:
+		return l.truncate(mapstr.M(v))
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = l.truncate(val)
+		}
+		return out
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = l.truncate(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	zapobserver "go.uber.org/zap/zaptest/observer"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestDroppedEventLoggerDisabled(t *testing.T) {
+	l := newDroppedEventLogger(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), DroppedEventLogConfig{})
+	require.Nil(t, l)
+
+	// all methods must be safe on a nil logger
+	l.eventDropped(beat.Event{}, "queue full")
+}
+
+func TestDroppedEventLoggerConfig(t *testing.T) {
+	c := DroppedEventLogConfig{SampleRate: 1.5}
+	assert.Error(t, c.Validate())
+	c.SampleRate = 1
+	assert.NoError(t, c.Validate())
+}
+
+func TestDroppedEventLogger(t *testing.T) {
+	observed, zapLogs := zapobserver.New(zapcore.InfoLevel)
+	logger, err := logp.ConfigureWithCoreLocal(logp.Config{}, observed)
+	require.NoError(t, err)
+
+	l := newDroppedEventLogger(logger, clockwork.NewRealClock(), DroppedEventLogConfig{
+		Enabled:      true,
+		SampleRate:   0.5,
+		Limit:        2,
+		Interval:     time.Minute,
+		MaxFieldSize: 4,
+	})
+	require.NotNil(t, l)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	samples := []float64{0.1, 0.9, 0.2, 0.3, 0.4}
+	l.sample = func() float64 {
+		s := samples[0]
+		samples = samples[1:]
+		return s
+	}
+
+	event := beat.Event{
+		Timestamp: now,
+		Meta:      mapstr.M{"pipeline": "p"},
+		Fields: mapstr.M{
+			"message": "hello world",
+			"tags":    []string{"abcdef"},
+			"count":   42,
+		},
+	}
+
+	// the second event is not sampled, the fourth exceeds the limit
+	for i := 0; i < 4; i++ {
+		l.eventDropped(event, "queue full")
+	}
+	logs := zapLogs.TakeAll()
+	require.Len(t, logs, 2)
+	for _, entry := range logs {
+		assert.Equal(t, zapcore.WarnLevel, entry.Level)
+		assert.Equal(t, "Dropped event on publish: queue full", entry.Message)
+		fields := entry.ContextMap()
+		assert.Equal(t, logp.EventType, fields[logp.TypeKey])
+		event, ok := fields["event"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, map[string]interface{}{"pipeline": "p"}, event["@metadata"])
+		assert.Equal(t, map[string]interface{}{
+			"message": "hell...",
+			"tags":    []interface{}{"abcd..."},
+			"count":   int64(42),
+		}, event["fields"])
+	}
+
+	// the next interval reports the number of sampled events suppressed
+	now = now.Add(time.Minute)
+	l.eventDropped(event, "client closed")
+	logs = zapLogs.TakeAll()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "Dropped event on publish: client closed", logs[0].Message)
+	assert.EqualValues(t, 1, logs[0].ContextMap()["sampled_events_suppressed"])
+
+	// the original event is not modified
+	assert.Equal(t, "hello world", event.Fields["message"])
+}
+
+func TestDroppedEventLoggerTruncatesUTF8(t *testing.T) {
+	l := &droppedEventLogger{maxFieldSize: 2}
+	assert.Equal(t, "ä...", l.truncate("äöü"))
+	assert.Equal(t, "...", (&droppedEventLogger{maxFieldSize: 1}).truncate("äöü"))
+	assert.Equal(t, "ab", l.truncate("ab"))
+	assert.Equal(t, strings.Repeat("x", 2)+"...", l.truncate(strings.Repeat("x", 10)))
+}
+
+func TestClientLogsDroppedEvents(t *testing.T) {
+	observed, zapLogs := zapobserver.New(zapcore.InfoLevel)
+	logger, err := logp.ConfigureWithCoreLocal(logp.Config{}, observed)
+	require.NoError(t, err)
+
+	q := memqueue.NewQueue(logger, nil, memqueue.Settings{
+		Events:        1,
+		MaxGetRequest: 1,
+		FlushTimeout:  time.Millisecond,
+	}, 0, nil)
+	pipeline := makePipeline(t, Settings{
+		DroppedEventLog: DroppedEventLogConfig{Enabled: true, SampleRate: 1},
+	}, q)
+	defer pipeline.Close()
+
+	client, err := pipeline.ConnectWith(beat.ClientConfig{})
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	// Events published after the client has been closed are dropped.
+	client.Publish(beat.Event{Fields: mapstr.M{"message": "late"}})
+
+	var dropped []zapobserver.LoggedEntry
+	for _, entry := range zapLogs.All() {
+		if strings.HasPrefix(entry.Message, "Dropped event on publish") {
+			dropped = append(dropped, entry)
+		}
+	}
+	require.Len(t, dropped, 1)
+	assert.Equal(t, "Dropped event on publish: client closed", dropped[0].Message)
+	event, ok := dropped[0].ContextMap()["event"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"message": "late"}, event["fields"])
+}
@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import "sync/atomic"
+
+// eventCounter counts the events published to the queue and the events
+// acknowledged by the outputs. It is shared by the monitors comparing the
+// publish and ACK rates of the pipeline. All methods are safe to be called
+// on a nil receiver.
+type eventCounter struct {
+	published atomic.Uint64
+	acked     atomic.Uint64
+}
+
+func (c *eventCounter) eventPublished() {
+	if c != nil {
+		c.published.Add(1)
+	}
+}
+
+func (c *eventCounter) eventsACKed(n int) {
+	if c != nil {
+		c.acked.Add(uint64(n))
+	}
+}
+
+// totals returns the number of events published and acked so far. acked is
+// loaded first, so it never exceeds published if events are ACKed in between.
+func (c *eventCounter) totals() (published, acked uint64) {
+	acked = c.acked.Load()
+	published = c.published.Load()
+	return published, acked
+}
+
+// counterSampler returns the number of events published and acked since the
+// previous sample.
+type counterSampler struct {
+	counter *eventCounter
+
+	lastPublished, lastAcked uint64
+}
+
+func (s *counterSampler) sample() (published, acked uint64) {
+	totalPublished, totalAcked := s.counter.totals()
+	published, acked = totalPublished-s.lastPublished, totalAcked-s.lastAcked
+	s.lastPublished, s.lastAcked = totalPublished, totalAcked
+	return published, acked
+}
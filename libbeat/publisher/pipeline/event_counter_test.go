@@ -0,0 +1,53 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterSampler(t *testing.T) {
+	counter := &eventCounter{}
+	sampler := counterSampler{counter: counter}
+
+	for i := 0; i < 3; i++ {
+		counter.eventPublished()
+	}
+	counter.eventsACKed(2)
+	published, acked := sampler.sample()
+	assert.Equal(t, uint64(3), published)
+	assert.Equal(t, uint64(2), acked)
+
+	// Samples only count the events since the previous sample.
+	counter.eventPublished()
+	counter.eventsACKed(2)
+	published, acked = sampler.sample()
+	assert.Equal(t, uint64(1), published)
+	assert.Equal(t, uint64(2), acked)
+
+	published, acked = counter.totals()
+	assert.Equal(t, uint64(4), published)
+	assert.Equal(t, uint64(4), acked)
+
+	// All methods must be safe on a nil counter.
+	var disabled *eventCounter
+	disabled.eventPublished()
+	disabled.eventsACKed(1)
+}
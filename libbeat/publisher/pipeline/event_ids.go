@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+// eventIDTracker adapts a beat.EventIDListener to the pipeline. Outputs can
+// acknowledge single events by ID through the batch (out of order), while the
+// queue keeps reporting ACKs in publishing order as a count. The tracker
+// reconciles both, so every ID is reported to the listener exactly once.
+type eventIDTracker struct {
+	listener beat.EventIDListener
+
+	mu sync.Mutex
+	// IDs of events in the queue, in publishing order.
+	pending []uint64
+	// IDs already ACKed by the output, but not yet removed from the queue.
+	acked map[uint64]struct{}
+}
+
+func newEventIDTracker(listener beat.EventIDListener) *eventIDTracker {
+	return &eventIDTracker{
+		listener: listener,
+		acked:    map[uint64]struct{}{},
+	}
+}
+
+func (t *eventIDTracker) AddEvent(event beat.Event, published bool) {
+	t.listener.AddEvent(event, published)
+}
+
+func (t *eventIDTracker) ClientClosed() {
+	t.listener.ClientClosed()
+}
+
+// add registers the ID of an event about to be published to the queue.
+func (t *eventIDTracker) add(id uint64) {
+	t.mu.Lock()
+	t.pending = append(t.pending, id)
+	t.mu.Unlock()
+	t.listener.EventIDAssigned(id)
+}
+
+// cancel removes the ID of an event the queue did not accept. It must only be
+// called for the ID passed to the most recent add.
+func (t *eventIDTracker) cancel(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.pending); n > 0 && t.pending[n-1] == id {
+		t.pending = t.pending[:n-1]
+	}
+}
+
+// ACKEventIDs is called by batches when the output acknowledges single events.
+func (t *eventIDTracker) ACKEventIDs(ids []uint64) {
+	t.mu.Lock()
+	for _, id := range ids {
+		t.acked[id] = struct{}{}
+	}
+	t.mu.Unlock()
+	t.listener.ACKEventIDs(ids)
+}
+
+// ACKEvents is called by the queue with the number of events removed from
+// the queue. IDs already reported by ACKEventIDs are not reported again.
+func (t *eventIDTracker) ACKEvents(n int) {
+	t.mu.Lock()
+	if n > len(t.pending) {
+		n = len(t.pending)
+	}
+	ids := make([]uint64, 0, n)
+	for _, id := range t.pending[:n] {
+		if _, ok := t.acked[id]; ok {
+			delete(t.acked, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	t.pending = t.pending[n:]
+	t.mu.Unlock()
+
+	if len(ids) > 0 {
+		t.listener.ACKEventIDs(ids)
+	}
+}
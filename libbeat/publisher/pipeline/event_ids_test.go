@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+type idListener struct {
+	assigned []uint64
+	acked    []uint64
+	counts   int
+}
+
+func (l *idListener) AddEvent(beat.Event, bool) {}
+func (l *idListener) ACKEvents(n int)           { l.counts += n }
+func (l *idListener) ClientClosed()             {}
+func (l *idListener) EventIDAssigned(id uint64) { l.assigned = append(l.assigned, id) }
+func (l *idListener) ACKEventIDs(ids []uint64)  { l.acked = append(l.acked, ids...) }
+
+func TestEventIDTracker(t *testing.T) {
+	listener := &idListener{}
+	tracker := newEventIDTracker(listener)
+
+	for id := uint64(1); id <= 5; id++ {
+		tracker.add(id)
+	}
+	// The queue rejected the last event.
+	tracker.cancel(5)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, listener.assigned)
+
+	// The output acknowledges events out of order.
+	tracker.ACKEventIDs([]uint64{3, 2})
+	assert.Equal(t, []uint64{3, 2}, listener.acked)
+
+	// The queue removes the first three events, only the event not yet
+	// acknowledged by the output is reported.
+	tracker.ACKEvents(3)
+	assert.Equal(t, []uint64{3, 2, 1}, listener.acked)
+
+	tracker.ACKEvents(5)
+	assert.Equal(t, []uint64{3, 2, 1, 4}, listener.acked)
+	assert.Empty(t, tracker.acked, "Reconciled IDs should be forgotten")
+	assert.Zero(t, listener.counts, "ACKEvents must not be forwarded to an EventIDListener")
+}
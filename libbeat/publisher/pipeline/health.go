@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// queueFillObserver wraps the queue.Observer passed to the queue, keeping
+// track of the queue fill level for health reporting.
+type queueFillObserver struct {
+	queue.Observer
+
+	maxEvents, maxBytes       atomic.Int64
+	filledEvents, filledBytes atomic.Int64
+}
+
+func newQueueFillObserver(observer queue.Observer) *queueFillObserver {
+	return &queueFillObserver{Observer: observer}
+}
+
+func (o *queueFillObserver) MaxEvents(value int) {
+	o.maxEvents.Store(int64(value))
+	o.Observer.MaxEvents(value)
+}
+
+func (o *queueFillObserver) MaxBytes(value int) {
+	o.maxBytes.Store(int64(value))
+	o.Observer.MaxBytes(value)
+}
+
+func (o *queueFillObserver) Restore(eventCount int, byteCount int) {
+	o.filledEvents.Store(int64(eventCount))
+	o.filledBytes.Store(int64(byteCount))
+	o.Observer.Restore(eventCount, byteCount)
+}
+
+func (o *queueFillObserver) AddEvent(byteCount int) {
+	o.filledEvents.Add(1)
+	o.filledBytes.Add(int64(byteCount))
+	o.Observer.AddEvent(byteCount)
+}
+
+func (o *queueFillObserver) RemoveEvents(eventCount int, byteCount int) {
+	o.filledEvents.Add(-int64(eventCount))
+	o.filledBytes.Add(-int64(byteCount))
+	o.Observer.RemoveEvents(eventCount, byteCount)
+}
+
+// saturated returns true if the queue reached its configured capacity, in
+// either events or bytes.
+func (o *queueFillObserver) saturated() bool {
+	if maxBytes := o.maxBytes.Load(); maxBytes > 0 && o.filledBytes.Load() >= maxBytes {
+		return true
+	}
+	if maxEvents := o.maxEvents.Load(); maxEvents > 0 && o.filledEvents.Load() >= maxEvents {
+		return true
+	}
+	return false
+}
+
+// Health reports whether the pipeline is ready to accept events, that is the
+// queue has been created and is not saturated, and at least one output client
+// is connected. The details of each component are returned as well.
+func (p *Pipeline) Health() (bool, mapstr.M) {
+	return p.outputController.health()
+}
+
+// queuedEvents returns the number of events in the queue. 0 is returned if
+// the fill level of the queue is unknown.
+func (c *outputController) queuedEvents() int64 {
+	c.queueLock.Lock()
+	fill := c.queueFill
+	c.queueLock.Unlock()
+	if fill == nil {
+		return 0
+	}
+	return fill.filledEvents.Load()
+}
+
+func (c *outputController) health() (bool, mapstr.M) {
+	c.queueLock.Lock()
+	q, fill := c.queue, c.queueFill
+	c.queueLock.Unlock()
+	if fill == nil {
+		// The queue was not created by the controller, its fill level is
+		// unknown.
+		fill = newQueueFillObserver(nil)
+	}
+
+	queueReady := q != nil && !fill.saturated()
+	queueDetails := mapstr.M{
+		"ready": queueReady,
+	}
+	if q != nil {
+		queueDetails["type"] = q.QueueType()
+		queueDetails["filled"] = mapstr.M{
+			"events": fill.filledEvents.Load(),
+			"bytes":  fill.filledBytes.Load(),
+		}
+		queueDetails["max_events"] = fill.maxEvents.Load()
+		queueDetails["max_bytes"] = fill.maxBytes.Load()
+		queueDetails["saturated"] = fill.saturated()
+	}
+
+	c.workersLock.Lock()
+	clients, connected := len(c.workers), 0
+	for _, w := range c.workers {
+		if w.Connected() {
+			connected++
+		}
+	}
+	c.workersLock.Unlock()
+
+	outputReady := connected > 0
+	outputDetails := mapstr.M{
+		"ready":     outputReady,
+		"clients":   clients,
+		"connected": connected,
+	}
+
+	return queueReady && outputReady, mapstr.M{
+		"queue":  queueDetails,
+		"output": outputDetails,
+	}
+}
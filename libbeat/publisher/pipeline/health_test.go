@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestPipelineHealth(t *testing.T) {
+	queueConfig := conf.Namespace{}
+	require.NoError(t, queueConfig.Unpack(conf.MustNewConfigFrom(
+		"mem.events: 32\nmem.flush.min_events: 1\nmem.flush.timeout: 0s")))
+
+	logger := logp.NewTestingLogger(t, "")
+	pipeline, err := New(
+		beat.Info{Logger: logger},
+		Monitors{Logger: logger},
+		queueConfig,
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	// Without an output there's no queue and nothing is connected.
+	ready, details := pipeline.Health()
+	assert.False(t, ready)
+	assert.Equal(t, false, mustGet(t, details, "queue.ready"))
+	assert.Equal(t, 0, mustGet(t, details, "output.connected"))
+
+	unblock := make(chan struct{})
+	pipeline.outputController.Set(outputs.Group{
+		Clients: []outputs.Client{newMockClient(func(batch publisher.Batch) error {
+			<-unblock
+			batch.ACK()
+			return nil
+		})},
+		BatchSize: 1,
+	})
+
+	ready, details = pipeline.Health()
+	assert.True(t, ready, "pipeline should be ready: %v", details)
+	assert.Equal(t, "mem", mustGet(t, details, "queue.type"))
+	assert.Equal(t, 1, mustGet(t, details, "output.connected"))
+
+	client, err := pipeline.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Fill the queue while the output is blocked.
+	for i := 0; i < 32; i++ {
+		client.Publish(beat.Event{})
+	}
+	assert.True(t, waitUntilTrue(5*time.Second, func() bool {
+		ready, _ := pipeline.Health()
+		return !ready
+	}), "saturated queue should make the pipeline not ready")
+	_, details = pipeline.Health()
+	assert.Equal(t, true, mustGet(t, details, "queue.saturated"))
+
+	close(unblock)
+	assert.True(t, waitUntilTrue(5*time.Second, func() bool {
+		ready, _ := pipeline.Health()
+		return ready
+	}), "pipeline should be ready again once the queue drained")
+}
+
+func TestPipelineHealthIdleOutput(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	pipeline, err := New(
+		beat.Info{Logger: logger},
+		Monitors{Logger: logger},
+		conf.Namespace{},
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	// Network clients connect without waiting for events to publish.
+	pipeline.outputController.Set(outputs.Group{
+		Clients: []outputs.Client{newMockNetworkClient(nil)},
+	})
+	assert.True(t, waitUntilTrue(5*time.Second, func() bool {
+		ready, _ := pipeline.Health()
+		return ready
+	}), "idle pipeline should be ready once the output is connected")
+}
+
+func mustGet(t *testing.T, m mapstr.M, key string) interface{} {
+	t.Helper()
+	v, err := m.GetValue(key)
+	require.NoError(t, err)
+	return v
+}
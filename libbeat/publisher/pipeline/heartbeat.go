@@ -0,0 +1,165 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const defaultHeartbeatInterval = 30 * time.Second
+
+// HeartbeatConfig configures the pipeline heartbeat. When enabled, the
+// pipeline publishes a heartbeat event every Interval, allowing consumers
+// downstream to detect a stalled pipeline, even if no input is publishing.
+type HeartbeatConfig struct {
+	Enabled  bool          `config:"enabled"`
+	Interval time.Duration `config:"interval" validate:"min=0"`
+}
+
+// heartbeatEmitter publishes heartbeat events carrying the beat info and the
+// number of events in the queue. Heartbeat events are published with
+// DropIfFull, so they are dropped instead of waiting for space in a full
+// queue.
+type heartbeatEmitter struct {
+	logger   *logp.Logger
+	clock    clockwork.Clock
+	info     beat.Info
+	interval time.Duration
+	counter  *eventCounter
+
+	client beat.Client
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newHeartbeatEmitter creates a heartbeatEmitter for the given config.
+// If the heartbeat is disabled, nil is returned. All methods of
+// heartbeatEmitter are safe to be called on a nil receiver.
+func newHeartbeatEmitter(logger *logp.Logger, clock clockwork.Clock, info beat.Info, config HeartbeatConfig, counter *eventCounter) *heartbeatEmitter {
+	if !config.Enabled {
+		return nil
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+
+	return &heartbeatEmitter{
+		logger:   logger,
+		clock:    clock,
+		info:     info,
+		interval: interval,
+		counter:  counter,
+		done:     make(chan struct{}),
+	}
+}
+
+// start connects the emitter to the pipeline and starts publishing heartbeat
+// events.
+func (h *heartbeatEmitter) start(pipeline beat.Pipeline) {
+	if h == nil {
+		return
+	}
+
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		PublishMode: beat.DropIfFull,
+	})
+	if err != nil {
+		h.logger.Errorf("Failed to connect pipeline heartbeat: %v", err)
+		return
+	}
+	h.client = client
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.run()
+	}()
+}
+
+// close stops publishing heartbeat events and closes the emitter's client.
+// A heartbeat event being published when the queue is full may block until
+// the queue is closed, use wait to wait for the emitter to finish.
+func (h *heartbeatEmitter) close() {
+	if h == nil {
+		return
+	}
+
+	close(h.done)
+	if h.client != nil {
+		h.client.Close()
+	}
+}
+
+// wait blocks until the emitter has stopped publishing.
+func (h *heartbeatEmitter) wait() {
+	if h == nil {
+		return
+	}
+
+	h.wg.Wait()
+}
+
+func (h *heartbeatEmitter) run() {
+	ticker := h.clock.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case now := <-ticker.Chan():
+			h.client.Publish(h.event(now))
+		}
+	}
+}
+
+func (h *heartbeatEmitter) event(now time.Time) beat.Event {
+	published, acked := h.counter.totals()
+
+	return beat.Event{
+		Timestamp: now,
+		Fields: mapstr.M{
+			"message": "Pipeline heartbeat",
+			"event": mapstr.M{
+				"kind":    "metric",
+				"dataset": "beat.heartbeat",
+			},
+			"beat": mapstr.M{
+				"name":    h.info.Name,
+				"type":    h.info.Beat,
+				"version": h.info.Version,
+				"id":      h.info.ID.String(),
+				"pipeline": mapstr.M{
+					"queue": mapstr.M{
+						"events": published - acked,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,133 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestHeartbeatEmitterDisabled(t *testing.T) {
+	h := newHeartbeatEmitter(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), beat.Info{}, HeartbeatConfig{}, nil)
+	require.Nil(t, h)
+
+	// all methods must be safe on a nil emitter
+	h.start(nil)
+	h.close()
+	h.wait()
+}
+
+func TestHeartbeatEmitter(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	q := memqueue.NewQueue(logger, nil, memqueue.Settings{
+		Events:        10,
+		MaxGetRequest: 1,
+		FlushTimeout:  time.Millisecond,
+	}, 10, nil)
+	pipeline := makePipeline(t, Settings{}, q)
+	defer pipeline.Close()
+
+	clock := clockwork.NewFakeClock()
+	h := newHeartbeatEmitter(logger, clock, beat.Info{
+		Name:    "test",
+		Beat:    "testbeat",
+		Version: "1.2.3",
+	}, HeartbeatConfig{Enabled: true, Interval: time.Minute}, pipeline.events)
+	require.NotNil(t, h)
+	pipeline.heartbeat = h
+	h.start(pipeline)
+
+	next := func() mapstr.M {
+		// wait for the ticker, then let the interval pass
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+
+		batch, err := q.Get(1)
+		require.NoError(t, err)
+		require.Equal(t, 1, batch.Count())
+		t.Cleanup(batch.Done)
+		//nolint:errcheck // it always succeeds
+		return batch.Entry(0).(publisher.Event).Content.Fields
+	}
+
+	fields := next()
+	assert.Equal(t, mapstr.M{
+		"message": "Pipeline heartbeat",
+		"event": mapstr.M{
+			"kind":    "metric",
+			"dataset": "beat.heartbeat",
+		},
+		"beat": mapstr.M{
+			"name":    "test",
+			"type":    "testbeat",
+			"version": "1.2.3",
+			"id":      "00000000-0000-0000-0000-000000000000",
+			"pipeline": mapstr.M{
+				"queue": mapstr.M{"events": uint64(0)},
+			},
+		},
+	}, fields)
+
+	// the first heartbeat has not been ACKed yet
+	queued, err := next().GetValue("beat.pipeline.queue.events")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), queued)
+}
+
+func TestHeartbeatEmitterDropsIfFull(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	q := memqueue.NewQueue(logger, nil, memqueue.Settings{
+		Events:        1,
+		MaxGetRequest: 1,
+		FlushTimeout:  time.Millisecond,
+	}, 0, nil)
+	pipeline := makePipeline(t, Settings{}, q)
+
+	h := newHeartbeatEmitter(logger, clockwork.NewRealClock(), beat.Info{}, HeartbeatConfig{Enabled: true, Interval: time.Millisecond}, pipeline.events)
+	pipeline.heartbeat = h
+	h.start(pipeline)
+
+	// No events are consumed from the queue, only the first heartbeat fits
+	// into the queue.
+	require.Eventually(t, func() bool {
+		return pipeline.events.published.Load() == 1
+	}, 10*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, uint64(1), pipeline.events.published.Load())
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		pipeline.Close()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("closing the pipeline blocked on the heartbeat emitter")
+	}
+}
@@ -92,6 +92,37 @@ func LoadWithSettings(
 
 	name := beatInfo.Name
 
+	if !settings.Congestion.Enabled {
+		settings.Congestion = config.Congestion
+	}
+	if !settings.SlowConsumer.Enabled {
+		settings.SlowConsumer = config.SlowConsumer
+	}
+	if !settings.Heartbeat.Enabled {
+		settings.Heartbeat = config.Heartbeat
+	}
+	if !settings.DroppedEventLog.Enabled {
+		settings.DroppedEventLog = config.DroppedEventLog
+	}
+	if settings.MaxClients == 0 {
+		settings.MaxClients = config.MaxClients
+	}
+	if settings.ReconnectLimit.MaxAttemptsPerSecond == 0 {
+		settings.ReconnectLimit = config.ReconnectLimit
+	}
+	if settings.SlowStart.Duration == 0 {
+		settings.SlowStart = config.SlowStart
+	}
+	if settings.ACKTimeout == 0 {
+		settings.ACKTimeout = config.ACKTimeout
+	}
+	if !settings.QueuePartitions.Enabled {
+		settings.QueuePartitions = config.QueuePartitions
+	}
+	if settings.PublishChunkSize == 0 {
+		settings.PublishChunkSize = config.PublishChunkSize
+	}
+
 	out, err := loadOutput(monitors, makeOutput)
 	if err != nil {
 		return nil, err
@@ -18,6 +18,10 @@
 package pipeline
 
 import (
+	"strconv"
+
+	"github.com/jonboulle/clockwork"
+
 	"github.com/elastic/elastic-agent-libs/monitoring"
 )
 
@@ -34,6 +38,11 @@ type pipelineObserver interface {
 	clientConnected()
 	// An open pipeline client received a Close() call.
 	clientClosed()
+	// Returns the histogram recording the publish latency of a newly
+	// connected client, nil if latencies are not recorded.
+	newClientLatency(id uint64) *latencyHistogram
+	// The client with the given ID was closed.
+	removeClientLatency(id uint64)
 }
 
 type clientObserver interface {
@@ -41,6 +50,9 @@ type clientObserver interface {
 	newEvent()
 	// An event was filtered by processors before being published
 	filteredEvent()
+	// An event was dropped because processing it exceeded the maximum
+	// processing time. It is reported by filteredEvent too.
+	timedOutEvent()
 	// An event was published to the queue
 	publishedEvent()
 	// An event was rejected by the queue
@@ -51,6 +63,9 @@ type clientObserver interface {
 type retryObserver interface {
 	// Events encountered too many errors and were permanently dropped.
 	eventsDropped(int)
+	// Guaranteed events exhausted their retries and were permanently dropped.
+	// They are reported by eventsDropped too.
+	guaranteedEventsDropped(int)
 	// Events were sent back to an output worker after an earlier failure.
 	eventsRetry(int)
 }
@@ -64,6 +79,12 @@ type retryObserver interface {
 type metricsObserver struct {
 	metrics *monitoring.Registry
 	vars    metricsObserverVars
+
+	// clock measures the publish latency of the clients.
+	clock clockwork.Clock
+
+	// publish latency of each client, by client ID
+	latency *monitoring.Registry
 }
 
 type metricsObserverVars struct {
@@ -72,12 +93,14 @@ type metricsObserverVars struct {
 
 	// eventsTotal publish/dropped stats
 	eventsTotal, eventsFiltered, eventsPublished, eventsFailed *monitoring.Uint
+	eventsTimedOut                                             *monitoring.Uint
 
 	eventsDropped, eventsRetry *monitoring.Uint // (retryer) drop/retry counters
+	eventsDroppedGuaranteed    *monitoring.Uint
 	activeEvents               *monitoring.Uint
 }
 
-func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
+func newMetricsObserver(metrics *monitoring.Registry, clock clockwork.Clock) *metricsObserver {
 	reg := metrics.GetRegistry("pipeline")
 	if reg == nil {
 		reg = metrics.NewRegistry("pipeline")
@@ -85,6 +108,8 @@ func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
 
 	return &metricsObserver{
 		metrics: metrics,
+		clock:   clock,
+		latency: reg.NewRegistry("publish_latency"),
 		vars: metricsObserverVars{
 			// (Gauge) clients measures the number of open pipeline clients.
 			clients: monitoring.NewUint(reg, "clients"),
@@ -100,6 +125,11 @@ func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
 			// being sent to the queue.
 			eventsFiltered: monitoring.NewUint(reg, "events.filtered"),
 
+			// events.timed_out counts events that were dropped because
+			// processing them exceeded max_processing_time. They are counted
+			// in events.filtered too.
+			eventsTimedOut: monitoring.NewUint(reg, "events.timed_out"),
+
 			// events.failed counts events that were rejected by the queue, or that
 			// were sent via an already-closed pipeline client.
 			eventsFailed: monitoring.NewUint(reg, "events.failed"),
@@ -114,6 +144,12 @@ func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
 			// events.dropped counts events that were dropped because errors from
 			// the output workers exceeded the configured maximum retry count.
 			eventsDropped: monitoring.NewUint(reg, "events.dropped"),
+
+			// events.dropped_guaranteed counts the events published with
+			// GuaranteedSend that were dropped because they exhausted the
+			// retries allowed by guaranteed_max_retries. They are counted
+			// in events.dropped too.
+			eventsDroppedGuaranteed: monitoring.NewUint(reg, "events.dropped_guaranteed"),
 		},
 	}
 }
@@ -134,6 +170,18 @@ func (o *metricsObserver) clientConnected() { o.vars.clients.Inc() }
 // (client) client finished processing close
 func (o *metricsObserver) clientClosed() { o.vars.clients.Dec() }
 
+// (pipeline) client was assigned its ID
+func (o *metricsObserver) newClientLatency(id uint64) *latencyHistogram {
+	h := newLatencyHistogram(o.clock, publishLatencyInterval)
+	monitoring.NewFunc(o.latency, strconv.FormatUint(id, 10), h.report, monitoring.Report)
+	return h
+}
+
+// (client) client finished processing close
+func (o *metricsObserver) removeClientLatency(id uint64) {
+	o.latency.Remove(strconv.FormatUint(id, 10))
+}
+
 //
 // client publish events
 //
@@ -150,6 +198,11 @@ func (o *metricsObserver) filteredEvent() {
 	o.vars.activeEvents.Dec()
 }
 
+// (client) event was dropped because it exceeded the maximum processing time
+func (o *metricsObserver) timedOutEvent() {
+	o.vars.eventsTimedOut.Inc()
+}
+
 // (client) managed to push an event into the publisher pipeline
 func (o *metricsObserver) publishedEvent() {
 	o.vars.eventsPublished.Inc()
@@ -175,6 +228,11 @@ func (o *metricsObserver) eventsDropped(n int) {
 	o.vars.eventsDropped.Add(uint64(n))
 }
 
+// (retryer) number of guaranteed events dropped by retryer
+func (o *metricsObserver) guaranteedEventsDropped(n int) {
+	o.vars.eventsDroppedGuaranteed.Add(uint64(n))
+}
+
 // (retryer) number of events pushed to the output worker queue
 func (o *metricsObserver) eventsRetry(n int) {
 	o.vars.eventsRetry.Add(uint64(n))
@@ -184,13 +242,17 @@ type emptyObserver struct{}
 
 var nilObserver observer = (*emptyObserver)(nil)
 
-func (*emptyObserver) cleanup()            {}
-func (*emptyObserver) clientConnected()    {}
-func (*emptyObserver) clientClosed()       {}
-func (*emptyObserver) newEvent()           {}
-func (*emptyObserver) filteredEvent()      {}
-func (*emptyObserver) publishedEvent()     {}
-func (*emptyObserver) failedPublishEvent() {}
-func (*emptyObserver) eventsACKed(n int)   {}
-func (*emptyObserver) eventsDropped(int)   {}
-func (*emptyObserver) eventsRetry(int)     {}
+func (*emptyObserver) cleanup()                                  {}
+func (*emptyObserver) clientConnected()                          {}
+func (*emptyObserver) clientClosed()                             {}
+func (*emptyObserver) newClientLatency(uint64) *latencyHistogram { return nil }
+func (*emptyObserver) removeClientLatency(uint64)                {}
+func (*emptyObserver) newEvent()                                 {}
+func (*emptyObserver) filteredEvent()                            {}
+func (*emptyObserver) timedOutEvent()                            {}
+func (*emptyObserver) publishedEvent()                           {}
+func (*emptyObserver) failedPublishEvent()                       {}
+func (*emptyObserver) eventsACKed(n int)                         {}
+func (*emptyObserver) eventsDropped(int)                         {}
+func (*emptyObserver) guaranteedEventsDropped(int)               {}
+func (*emptyObserver) eventsRetry(int)                           {}
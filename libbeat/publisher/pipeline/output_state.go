@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/outputs"
+)
+
+// outputStateListeners forwards connection state changes of the output
+// workers to the registered listeners.
+type outputStateListeners struct {
+	mu        sync.Mutex
+	listeners map[uint64]outputs.OutputStateListener
+	nextID    uint64
+}
+
+// AddOutputStateListener registers l to be notified when an output client
+// connects or disconnects. The listener applies to the current output and to
+// outputs created by later reloads. The returned function removes the
+// listener.
+func (p *Pipeline) AddOutputStateListener(l outputs.OutputStateListener) (remove func()) {
+	return p.outputController.stateListeners.add(l)
+}
+
+func (s *outputStateListeners) add(l outputs.OutputStateListener) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listeners == nil {
+		s.listeners = map[uint64]outputs.OutputStateListener{}
+	}
+	id := s.nextID
+	s.nextID++
+	s.listeners[id] = l
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+// current returns the registered listeners, so they can be called without
+// holding the lock.
+func (s *outputStateListeners) current() []outputs.OutputStateListener {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	listeners := make([]outputs.OutputStateListener, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+func (s *outputStateListeners) connected(host string) {
+	for _, l := range s.current() {
+		l.OnConnect(host)
+	}
+}
+
+func (s *outputStateListeners) disconnected(host string, err error) {
+	for _, l := range s.current() {
+		l.OnDisconnect(host, err)
+	}
+}
@@ -0,0 +1,284 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// QueuePartitionsConfig configures separate queue partitions for the events
+// of clients dropping events if the queue is full, such that they never take
+// the queue space needed by the other clients.
+type QueuePartitionsConfig struct {
+	Enabled bool `config:"enabled"`
+}
+
+// The queue partitions. The events of DropIfFull clients are kept apart from
+// the events of clients waiting for queue space.
+const (
+	defaultPartition = iota
+	dropIfFullPartition
+	partitionCount
+)
+
+var partitionNames = [partitionCount]string{"default", "drop_if_full"}
+
+func publishModePartition(mode beat.PublishMode) int {
+	if mode == beat.DropIfFull {
+		return dropIfFullPartition
+	}
+	return defaultPartition
+}
+
+// partitionedQueue is a queue.Queue made of one memory queue per partition.
+// Get returns a batch of the first partition having events.
+type partitionedQueue struct {
+	partitions [partitionCount]queue.Queue
+
+	// Each partition has a reader goroutine, reading a batch when it
+	// receives a request. Results are sent to the results channel.
+	requests [partitionCount]chan int
+	pending  [partitionCount]bool
+	results  chan partitionResult
+
+	done chan struct{}
+}
+
+type partitionResult struct {
+	partition int
+	batch     queue.Batch
+	err       error
+}
+
+// partitionedQueueFactory returns a queue factory creating the partitions of
+// a partitionedQueue with factory. The metrics of each partition are reported
+// under pipeline.partitions.<name>.queue, and added up in the observer of the
+// whole queue. If factory does not create memory queues, no partitions are
+// used and the queue created is returned. Memory queues saving snapshots are
+// not partitioned either, the partitions would share the snapshot file.
+func partitionedQueueFactory(factory queue.QueueFactory, pipelineMetrics *monitoring.Registry) queue.QueueFactory {
+	return func(
+		logger *logp.Logger,
+		observer queue.Observer,
+		inputQueueSize int,
+		encoderFactory queue.EncoderFactory,
+	) (queue.Queue, error) {
+		return newPartitionedQueue(logger, factory, observer, pipelineMetrics, inputQueueSize, encoderFactory)
+	}
+}
+
+func newPartitionedQueue(
+	logger *logp.Logger,
+	factory queue.QueueFactory,
+	observer queue.Observer,
+	pipelineMetrics *monitoring.Registry,
+	inputQueueSize int,
+	encoderFactory queue.EncoderFactory,
+) (queue.Queue, error) {
+	q := &partitionedQueue{
+		results: make(chan partitionResult, partitionCount),
+		done:    make(chan struct{}),
+	}
+	total := &partitionTotals{observer: observer}
+	for i := range q.partitions {
+		partitionObserver := &partitionObserver{
+			Observer: queue.NewQueueObserver(partitionRegistry(pipelineMetrics, partitionNames[i])),
+			total:    total,
+		}
+		partition, err := factory(logger, partitionObserver, inputQueueSize, encoderFactory)
+		if err != nil {
+			for _, created := range q.partitions[:i] {
+				created.Close()
+			}
+			return nil, err
+		}
+		if partition.QueueType() != memqueue.QueueType {
+			logger.Warnf("Queue partitions are not supported by the %v queue, the events of all clients share the queue", partition.QueueType())
+			return partition, nil
+		}
+		if memqueue.SnapshotEnabled(partition) {
+			// The partitions would share the snapshot file, and only the
+			// first one would restore its events.
+			logger.Warnf("Queue partitions are not supported with memory queue snapshots, the events of all clients share the queue")
+			return partition, nil
+		}
+		q.partitions[i] = partition
+		q.requests[i] = make(chan int, 1)
+	}
+
+	for i := range q.partitions {
+		go q.readPartition(i)
+	}
+	go func() {
+		for _, partition := range q.partitions {
+			<-partition.Done()
+		}
+		close(q.done)
+	}()
+	return q, nil
+}
+
+func partitionRegistry(pipelineMetrics *monitoring.Registry, name string) *monitoring.Registry {
+	if pipelineMetrics == nil {
+		return nil
+	}
+	partitions := pipelineMetrics.GetRegistry("partitions")
+	if partitions == nil {
+		partitions = pipelineMetrics.NewRegistry("partitions")
+	}
+	reg := partitions.GetRegistry(name)
+	if reg == nil {
+		reg = partitions.NewRegistry(name)
+	}
+	return reg
+}
+
+func (q *partitionedQueue) readPartition(partition int) {
+	for {
+		select {
+		case eventCount := <-q.requests[partition]:
+			batch, err := q.partitions[partition].Get(eventCount)
+			q.results <- partitionResult{partition: partition, batch: batch, err: err}
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (q *partitionedQueue) Close() error {
+	var errs []error
+	for _, partition := range q.partitions {
+		errs = append(errs, partition.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (q *partitionedQueue) Done() <-chan struct{} {
+	return q.done
+}
+
+func (q *partitionedQueue) QueueType() string {
+	return q.partitions[defaultPartition].QueueType()
+}
+
+func (q *partitionedQueue) BufferConfig() queue.BufferConfig {
+	var config queue.BufferConfig
+	for _, partition := range q.partitions {
+		maxEvents := partition.BufferConfig().MaxEvents
+		if maxEvents <= 0 {
+			return queue.BufferConfig{}
+		}
+		config.MaxEvents += maxEvents
+	}
+	return config
+}
+
+// Producer creates a producer for the default partition.
+func (q *partitionedQueue) Producer(cfg queue.ProducerConfig) queue.Producer {
+	return q.partitions[defaultPartition].Producer(cfg)
+}
+
+// modeProducer creates a producer for the partition of the publish mode.
+func (q *partitionedQueue) modeProducer(mode beat.PublishMode, cfg queue.ProducerConfig) queue.Producer {
+	return q.partitions[publishModePartition(mode)].Producer(cfg)
+}
+
+// Get returns the first batch read from any partition. Get must not be
+// called concurrently, as with the eventConsumer's queueReader.
+func (q *partitionedQueue) Get(eventCount int) (queue.Batch, error) {
+	for i := range q.partitions {
+		if !q.pending[i] {
+			q.pending[i] = true
+			q.requests[i] <- eventCount
+		}
+	}
+	select {
+	case result := <-q.results:
+		q.pending[result.partition] = false
+		return result.batch, result.err
+	case <-q.done:
+		return nil, io.EOF
+	}
+}
+
+// partitionTotals adds up the metrics of all partitions in the observer of
+// the whole queue.
+type partitionTotals struct {
+	observer            queue.Observer
+	maxEvents, maxBytes atomic.Int64
+}
+
+// partitionObserver reports the metrics of a partition to its own observer,
+// and to the observer of the whole queue.
+type partitionObserver struct {
+	queue.Observer
+	total *partitionTotals
+
+	mutex               sync.Mutex
+	maxEvents, maxBytes int
+}
+
+func (o *partitionObserver) MaxEvents(value int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.Observer.MaxEvents(value)
+	o.total.observer.MaxEvents(int(o.total.maxEvents.Add(int64(value - o.maxEvents))))
+	o.maxEvents = value
+}
+
+func (o *partitionObserver) MaxBytes(value int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.Observer.MaxBytes(value)
+	o.total.observer.MaxBytes(int(o.total.maxBytes.Add(int64(value - o.maxBytes))))
+	o.maxBytes = value
+}
+
+func (o *partitionObserver) Restore(eventCount int, byteCount int) {
+	o.Observer.Restore(eventCount, byteCount)
+	o.total.observer.Restore(eventCount, byteCount)
+}
+
+func (o *partitionObserver) AddEvent(byteCount int) {
+	o.Observer.AddEvent(byteCount)
+	o.total.observer.AddEvent(byteCount)
+}
+
+func (o *partitionObserver) ConsumeEvents(eventCount int, byteCount int) {
+	o.Observer.ConsumeEvents(eventCount, byteCount)
+	o.total.observer.ConsumeEvents(eventCount, byteCount)
+}
+
+func (o *partitionObserver) RemoveEvents(eventCount int, byteCount int) {
+	o.Observer.RemoveEvents(eventCount, byteCount)
+	o.total.observer.RemoveEvents(eventCount, byteCount)
+}
+
+func (o *partitionObserver) QueueFull() {
+	o.Observer.QueueFull()
+	o.total.observer.QueueFull()
+}
@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestPartitionedQueue(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	controller := outputController{
+		queueFactory: memqueue.FactoryForSettings(memqueue.Settings{Events: 2}),
+		consumer: &eventConsumer{
+			targetChan:    make(chan consumerTarget, 4),
+			retryObserver: nilObserver,
+		},
+		monitors:        Monitors{Metrics: reg, Logger: logp.NewTestingLogger(t, "")},
+		beat:            beat.Info{Logger: logp.NewTestingLogger(t, "")},
+		queuePartitions: true,
+	}
+	controller.Set(outputs.Group{
+		Clients: []outputs.Client{newMockClient(nil)},
+	})
+	defer controller.queue.Close()
+	require.IsType(t, &partitionedQueue{}, controller.queue)
+	assert.Equal(t, 4, controller.queue.BufferConfig().MaxEvents)
+
+	dropProducer := controller.queueProducer(beat.DropIfFull, queue.ProducerConfig{})
+	defaultProducer := controller.queueProducer(beat.GuaranteedSend, queue.ProducerConfig{})
+
+	// A full drop_if_full partition leaves the default partition alone
+	for i := 0; i < 2; i++ {
+		_, ok := dropProducer.TryPublish(i)
+		require.True(t, ok)
+	}
+	for i := 0; i < 2; i++ {
+		_, ok := defaultProducer.Publish(i)
+		require.True(t, ok, "default partition should have room")
+	}
+
+	assertMetric := func(name string, expected uint64) {
+		t.Helper()
+		entry, ok := reg.Get(name).(*monitoring.Uint)
+		require.True(t, ok, "metric %s must exist", name)
+		assert.Equal(t, expected, entry.Get(), name)
+	}
+	assertMetric("pipeline.queue.max_events", 4)
+	assertMetric("pipeline.queue.filled.events", 4)
+	assertMetric("pipeline.partitions.default.queue.max_events", 2)
+	assertMetric("pipeline.partitions.default.queue.filled.events", 2)
+	assertMetric("pipeline.partitions.drop_if_full.queue.filled.events", 2)
+
+	// Get returns the batches of both partitions
+	events := 0
+	for events < 4 {
+		batch, err := getWithTimeout(t, controller.queue, 10)
+		require.NoError(t, err)
+		events += batch.Count()
+		batch.Done()
+	}
+	assert.Equal(t, 4, events)
+}
+
+func TestPartitionedQueueClose(t *testing.T) {
+	q, err := newPartitionedQueue(
+		logp.NewTestingLogger(t, ""),
+		memqueue.FactoryForSettings(memqueue.Settings{Events: 2}),
+		queue.NewQueueObserver(nil),
+		nil, 0, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, q.Close())
+	select {
+	case <-q.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("partitioned queue should be done after closing it")
+	}
+	_, err = getWithTimeout(t, q, 10)
+	assert.Error(t, err)
+}
+
+func TestPartitionedQueueSnapshot(t *testing.T) {
+	q, err := newPartitionedQueue(
+		logp.NewTestingLogger(t, ""),
+		memqueue.FactoryForSettings(memqueue.Settings{
+			Events:       2,
+			SnapshotPath: filepath.Join(t.TempDir(), "queue.snapshot"),
+		}),
+		queue.NewQueueObserver(nil),
+		nil, 0, nil)
+	require.NoError(t, err)
+	defer q.Close()
+
+	_, partitioned := q.(*partitionedQueue)
+	assert.False(t, partitioned, "queues saving snapshots must not be partitioned")
+	assert.Implements(t, (*queue.Persister)(nil), q)
+}
+
+func getWithTimeout(t *testing.T, q queue.Queue, eventCount int) (queue.Batch, error) {
+	t.Helper()
+	type result struct {
+		batch queue.Batch
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		batch, err := q.Get(eventCount)
+		ch <- result{batch, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.batch, r.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue Get timed out")
+		return nil, nil
+	}
+}
@@ -22,8 +22,13 @@ package pipeline
 
 import (
 	"fmt"
+	"runtime"
+	"sync/atomic"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
+	"github.com/jonboulle/clockwork"
+
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common/acker"
 	"github.com/elastic/beats/v7/libbeat/common/reload"
@@ -31,10 +36,10 @@ import (
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/processing"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
-	"github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
-	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+	_ "github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
 	conf "github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
 )
 
 // Pipeline implementation providint all beats publisher functionality.
@@ -67,6 +72,32 @@ type Pipeline struct {
 	waitCloseTimeout time.Duration
 
 	processors processing.Supporter
+
+	clock clockwork.Clock
+
+	// events counts the events published and acked for the monitors
+	// comparing both rates.
+	events *eventCounter
+
+	congestion *congestionMonitor
+
+	slowConsumer *slowConsumerMonitor
+
+	heartbeat *heartbeatEmitter
+
+	droppedEvents *droppedEventLogger
+
+	clients *clientLimiter
+
+	// Clients currently connected, for inspecting their processors.
+	registry *clientRegistry
+
+	// Source of event IDs for clients with a beat.EventIDListener.
+	eventIDs atomic.Uint64
+
+	// Number of events of a PublishAll call processed before the client
+	// yields. 0 means the client never yields.
+	publishChunkSize int
 }
 
 // Settings is used to pass additional settings to a newly created pipeline instance.
@@ -80,6 +111,49 @@ type Settings struct {
 	Processors processing.Supporter
 
 	InputQueueSize int
+
+	// Congestion configures the detection of congested outputs.
+	Congestion CongestionConfig
+
+	// SlowConsumer configures the warning about outputs not keeping up.
+	SlowConsumer SlowConsumerConfig
+
+	// Heartbeat configures the periodic heartbeat event.
+	Heartbeat HeartbeatConfig
+
+	// DroppedEventLog configures the logging of sampled dropped events.
+	DroppedEventLog DroppedEventLogConfig
+
+	// MaxClients limits the number of clients connected at the same time.
+	// 0 means no limit.
+	MaxClients int
+
+	// ReconnectLimit limits the rate of the output connection attempts.
+	ReconnectLimit ReconnectLimitConfig
+
+	// SlowStart ramps up the publishing rate of the output clients after a
+	// reconnect.
+	SlowStart SlowStartConfig
+
+	// ACKTimeout is the time an output gets to acknowledge a batch before
+	// its events are requeued for retry. 0 disables the timeout.
+	ACKTimeout time.Duration
+
+	// QueuePartitions configures a separate queue partition for the events
+	// of DropIfFull clients.
+	QueuePartitions QueuePartitionsConfig
+
+	// PublishChunkSize is the number of events of a single PublishAll call
+	// a client processes before yielding to other goroutines, so clients
+	// publishing large slices do not starve the other clients. Events
+	// published concurrently to the same client may be published between
+	// the chunks. 0 disables the chunking.
+	PublishChunkSize int
+
+	// Clock is used by the time based features of the pipeline, like the
+	// ACK timeout and the periodic monitors. Tests can set a fake clock to
+	// control the time. Defaults to the real time.
+	Clock clockwork.Clock
 }
 
 // WaitCloseMode enumerates the possible behaviors of WaitClose in a pipeline.
@@ -118,6 +192,10 @@ func New(
 	if monitors.Logger == nil {
 		monitors.Logger = logp.NewLogger("publish")
 	}
+	clock := settings.Clock
+	if clock == nil {
+		clock = clockwork.NewRealClock()
+	}
 
 	p := &Pipeline{
 		beatInfo:         beat,
@@ -125,13 +203,20 @@ func New(
 		observer:         nilObserver,
 		waitCloseTimeout: settings.WaitClose,
 		processors:       settings.Processors,
+		clock:            clock,
+		events:           &eventCounter{},
+		droppedEvents:    newDroppedEventLogger(monitors.Logger, clock, settings.DroppedEventLog),
+		clients:          newClientLimiter(settings.MaxClients),
+		registry:         newClientRegistry(),
+		publishChunkSize: settings.PublishChunkSize,
 	}
+	p.congestion = newCongestionMonitor(monitors.Logger, clock, settings.Congestion, p.events)
 	if settings.WaitCloseMode == WaitOnPipelineClose && settings.WaitClose > 0 {
 		p.waitCloseTimeout = settings.WaitClose
 	}
 
 	if monitors.Metrics != nil {
-		p.observer = newMetricsObserver(monitors.Metrics)
+		p.observer = newMetricsObserver(monitors.Metrics, clock)
 	}
 
 	// Convert the raw queue config to a parsed Settings object that will
@@ -141,17 +226,30 @@ func New(
 	if b := userQueueConfig.Name(); b != "" {
 		queueType = b
 	}
-	queueFactory, err := queueFactoryForUserConfig(queueType, userQueueConfig.Config())
+	queueFactory, err := queue.Load(queueType, userQueueConfig.Config())
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := newOutputController(beat, monitors, p.observer, queueFactory, settings.InputQueueSize)
+	output, err := newOutputController(beat, monitors, p.observer, queueFactory, settings.InputQueueSize, clock)
 	if err != nil {
 		return nil, err
 	}
 	p.outputController = output
+	var pipelineMetrics *monitoring.Registry
+	if monitors.Metrics != nil {
+		pipelineMetrics = monitors.Metrics.GetRegistry("pipeline")
+	}
+	p.outputController.reconnectLimiter = newReconnectLimiter(settings.ReconnectLimit, pipelineMetrics, clock)
+	p.outputController.slowStart = newSlowStart(settings.SlowStart, clock, pipelineMetrics)
+	p.outputController.ackTimeout = settings.ACKTimeout
+	p.outputController.queuePartitions = settings.QueuePartitions.Enabled
 	p.outputController.Set(out)
+	p.congestion.start()
+	p.slowConsumer = newSlowConsumerMonitor(monitors.Logger, clock, settings.SlowConsumer, output.outputNames, p.events)
+	p.slowConsumer.start()
+	p.heartbeat = newHeartbeatEmitter(monitors.Logger, clock, beat, settings.Heartbeat, p.events)
+	p.heartbeat.start(p)
 
 	return p, nil
 }
@@ -161,12 +259,42 @@ func New(
 // for a duration of WaitClose, if there are still active events in the pipeline.
 // Note: clients must be closed before calling Close.
 func (p *Pipeline) Close() error {
+	return p.close(p.waitCloseTimeout)
+}
+
+// Shutdown stops the pipeline like Close, but waits up to timeout for the
+// events in the queue to be published, regardless of the WaitClose setting.
+// The number of events flushed and the number of events left in the queue
+// are logged.
+// Note: clients must be closed before calling Shutdown.
+func (p *Pipeline) Shutdown(timeout time.Duration) error {
+	queued := p.outputController.queuedEvents()
+	p.monitors.Logger.Infof("Flushing %d queued events to the outputs, waiting up to %v", queued, timeout)
+
+	err := p.close(timeout)
+
+	remaining := p.outputController.queuedEvents()
+	flushed := max(queued-remaining, 0)
+	if remaining > 0 {
+		p.monitors.Logger.Warnf("Flushed %d events to the outputs on shutdown, %d events were not flushed", flushed, remaining)
+	} else {
+		p.monitors.Logger.Infof("Flushed %d events to the outputs on shutdown", flushed)
+	}
+	return err
+}
+
+func (p *Pipeline) close(waitCloseTimeout time.Duration) error {
 	log := p.monitors.Logger
 
 	log.Debug("close pipeline")
 
+	p.heartbeat.close()
+
 	// Note: active clients are not closed / disconnected.
-	p.outputController.WaitClose(p.waitCloseTimeout)
+	p.outputController.WaitClose(waitCloseTimeout)
+	p.heartbeat.wait()
+	p.congestion.close()
+	p.slowConsumer.close()
 
 	p.observer.cleanup()
 	return nil
@@ -181,6 +309,8 @@ func (p *Pipeline) Connect() (beat.Client, error) {
 // The client behavior on close and ACK handling can be configured by setting
 // the appropriate fields in provided ClientConfig.
 // If not set otherwise the default publish mode is OutputChooses.
+// ErrTooManyClients is returned if the maximum number of clients configured
+// is already connected.
 //
 // It is responsibility of the caller to close the client.
 func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
@@ -194,6 +324,11 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 		return nil, err
 	}
 
+	if !p.clients.acquire() {
+		p.monitors.Logger.Warnf("Rejected new pipeline client, %d clients are connected already", p.clients.max)
+		return nil, fmt.Errorf("%w: the maximum of %d clients is connected", ErrTooManyClients, p.clients.max)
+	}
+
 	switch cfg.PublishMode {
 	case beat.GuaranteedSend:
 		eventFlags = publisher.GuaranteedSend
@@ -203,8 +338,19 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 
 	waitClose := cfg.WaitClose
 
+	sessionID := cfg.SessionID
+	if cfg.SessionField != "" && sessionID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			p.clients.release()
+			return nil, fmt.Errorf("failed to generate client session ID: %w", err)
+		}
+		sessionID = id.String()
+	}
+
 	processors, err := p.createEventProcessing(cfg.Processing, publishDisabled)
 	if err != nil {
+		p.clients.release()
 		return nil, err
 	}
 
@@ -217,18 +363,35 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 		logger:         p.monitors.Logger,
 		clientListener: clientListener,
 		processors:     processors,
+		publishMode:    cfg.PublishMode,
 		eventFlags:     eventFlags,
 		canDrop:        canDrop,
 		observer:       p.observer,
+		stats:          newClientStats(p.clock.Now),
+		events:         p.events,
+		congestion:     p.congestion,
+		droppedEvents:  p.droppedEvents,
+		clients:        p.clients,
+		registry:       p.registry,
+		assignSequence: cfg.AssignSequence,
+		sessionField:   cfg.SessionField,
+		sessionID:      sessionID,
+		chunkSize:      p.publishChunkSize,
+		yield:          runtime.Gosched,
 	}
 
 	client.isOpen.Store(true)
 
 	ackHandler := cfg.EventListener
+	if idListener, ok := ackHandler.(beat.EventIDListener); ok {
+		client.idTracker = newEventIDTracker(idListener)
+		client.eventIDs = &p.eventIDs
+		ackHandler = client.idTracker
+	}
 
 	var waiter *clientCloseWaiter
 	if waitClose > 0 {
-		waiter = newClientCloseWaiter(waitClose)
+		waiter = newClientCloseWaiter(p.clock, waitClose)
 		if ackHandler == nil {
 			ackHandler = waiter
 		} else {
@@ -238,7 +401,10 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 
 	producerCfg := queue.ProducerConfig{
 		ACK: func(count int) {
+			client.inFlight.Add(-int64(count))
+			client.stats.acked(count)
 			client.observer.eventsACKed(count)
+			client.events.eventsACKed(count)
 			if ackHandler != nil {
 				ackHandler.ACKEvents(count)
 			}
@@ -251,14 +417,18 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 
 	client.eventListener = ackHandler
 	client.waiter = waiter
-	client.producer = p.outputController.queueProducer(producerCfg)
+	client.producer = p.outputController.queueProducer(cfg.PublishMode, producerCfg)
 	if client.producer == nil {
 		// This can only happen if the pipeline was shut down while clients
 		// were still waiting to connect.
+		p.clients.release()
 		return nil, fmt.Errorf("client failed to connect because the pipeline is shutting down")
 	}
 
 	p.observer.clientConnected()
+	p.congestion.addClient(client)
+	p.registry.add(client)
+	client.latency = p.observer.newClientLatency(client.id)
 	return client, nil
 }
 
@@ -266,6 +436,9 @@ func (p *Pipeline) createEventProcessing(cfg beat.ProcessingConfig, noPublish bo
 	if p.processors == nil {
 		return nil, nil
 	}
+	if cfg.Clock == nil {
+		cfg.Clock = p.clock
+	}
 	return p.processors.Create(cfg, noPublish)
 }
 
@@ -274,29 +447,6 @@ func (p *Pipeline) OutputReloader() OutputReloader {
 	return p.outputController
 }
 
-// Parses the given config and returns a QueueFactory based on it.
-// This helper exists to frontload config parsing errors: if there is an
-// error in the queue config, we want it to show up as fatal during
-// initialization, even if the queue itself isn't created until later.
-func queueFactoryForUserConfig(queueType string, userConfig *conf.C) (queue.QueueFactory, error) {
-	switch queueType {
-	case memqueue.QueueType:
-		settings, err := memqueue.SettingsForUserConfig(userConfig)
-		if err != nil {
-			return nil, err
-		}
-		return memqueue.FactoryForSettings(settings), nil
-	case diskqueue.QueueType:
-		settings, err := diskqueue.SettingsForUserConfig(userConfig)
-		if err != nil {
-			return nil, err
-		}
-		return diskqueue.FactoryForSettings(settings), nil
-	default:
-		return nil, fmt.Errorf("unrecognized queue type '%v'", queueType)
-	}
-}
-
 type noopClientListener struct{}
 
 func (n noopClientListener) Closing()                    {}
@@ -22,10 +22,20 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	zapobserver "go.uber.org/zap/zaptest/observer"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/beats/v7/libbeat/tests/resources"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent-libs/mapstr"
 )
 
@@ -77,6 +87,65 @@ func TestPipelineAcceptsAnyNumberOfClients(t *testing.T) {
 // makeDiscardQueue returns a queue that always discards all events
 // the producers are assigned an unique incremental ID, when their
 // close method is called, this ID is returned
+func TestPipelineShutdown(t *testing.T) {
+	run := func(t *testing.T, timeout time.Duration, unblockOutput bool) string {
+		queueConfig := conf.Namespace{}
+		require.NoError(t, queueConfig.Unpack(conf.MustNewConfigFrom(
+			"mem.events: 32\nmem.flush.min_events: 1\nmem.flush.timeout: 0s")))
+
+		observed, zapLogs := zapobserver.New(zapcore.InfoLevel)
+		logger, err := logp.ConfigureWithCoreLocal(logp.Config{}, observed)
+		require.NoError(t, err)
+
+		pipeline, err := New(
+			beat.Info{Logger: logger},
+			Monitors{Logger: logger},
+			queueConfig,
+			outputs.Group{},
+			Settings{},
+		)
+		require.NoError(t, err)
+
+		unblock := make(chan struct{})
+		pipeline.outputController.Set(outputs.Group{
+			Clients: []outputs.Client{newMockClient(func(batch publisher.Batch) error {
+				<-unblock
+				batch.ACK()
+				return nil
+			})},
+			BatchSize: 1,
+		})
+
+		client, err := pipeline.Connect()
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			client.Publish(beat.Event{})
+		}
+		require.NoError(t, client.Close())
+
+		if unblockOutput {
+			close(unblock)
+		} else {
+			defer close(unblock)
+		}
+		require.NoError(t, pipeline.Shutdown(timeout))
+
+		entries := zapLogs.FilterMessageSnippet("on shutdown").All()
+		require.Len(t, entries, 1)
+		return entries[0].Message
+	}
+
+	t.Run("queue flushed", func(t *testing.T) {
+		msg := run(t, 5*time.Second, true)
+		assert.Equal(t, "Flushed 10 events to the outputs on shutdown", msg)
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		msg := run(t, 50*time.Millisecond, false)
+		assert.Equal(t, "Flushed 0 events to the outputs on shutdown, 10 events were not flushed", msg)
+	})
+}
+
 func makeDiscardQueue() queue.Queue {
 	var wg sync.WaitGroup
 	var producerID atomic.Int64
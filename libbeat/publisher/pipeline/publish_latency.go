@@ -0,0 +1,182 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// publishLatencyInterval is the minimum time covered by the reported
+// percentiles. It matches the default period of the metrics logger.
+const publishLatencyInterval = 30 * time.Second
+
+const (
+	// Each power of two is split into latencySubBuckets buckets, keeping the
+	// relative error of the reported values below 1/latencySubBuckets.
+	latencySubBucketBits = 3
+	latencySubBuckets    = 1 << latencySubBucketBits
+
+	// Enough buckets to record latencies of up to 2^40µs (~12 days), larger
+	// values are recorded in the last bucket.
+	latencyBuckets = (40 - latencySubBucketBits + 1) * latencySubBuckets
+)
+
+// latencyHistogram records durations in microseconds into log-linear buckets,
+// similar to an HDR histogram. Recording only updates atomic counters, so it
+// can be used on the hot path of a client without contention.
+//
+// The histogram is reset every interval: report rotates the recorded
+// counts once the interval has passed and reports the percentiles of the
+// last complete interval.
+type latencyHistogram struct {
+	clock    clockwork.Clock
+	interval time.Duration
+	counts   [latencyBuckets]atomic.Uint64
+	max      atomic.Uint64
+
+	mutex sync.Mutex
+	start time.Time
+	last  latencySummary
+}
+
+// latencySummary holds the percentiles of the latencies recorded during an
+// interval.
+type latencySummary struct {
+	count         uint64
+	p50, p95, p99 time.Duration
+	max           time.Duration
+}
+
+func newLatencyHistogram(clock clockwork.Clock, interval time.Duration) *latencyHistogram {
+	return &latencyHistogram{clock: clock, interval: interval, start: clock.Now()}
+}
+
+// record adds a duration to the histogram.
+func (h *latencyHistogram) record(d time.Duration) {
+	us := uint64(max(d.Microseconds(), 0))
+	h.counts[latencyBucket(us)].Add(1)
+	for {
+		current := h.max.Load()
+		if us <= current || h.max.CompareAndSwap(current, us) {
+			return
+		}
+	}
+}
+
+// Reset discards the recorded latencies, including the last reported summary,
+// and starts a new interval.
+func (h *latencyHistogram) Reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.max.Store(0)
+	h.start = h.clock.Now()
+	h.last = latencySummary{}
+}
+
+// summary returns the percentiles of the last complete interval. If the
+// current interval has passed, the recorded latencies are summarized and the
+// histogram is reset for the next interval.
+func (h *latencyHistogram) summary() latencySummary {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.clock.Since(h.start) < h.interval {
+		return h.last
+	}
+
+	var counts [latencyBuckets]uint64
+	var total uint64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Swap(0)
+		total += counts[i]
+	}
+	maxUS := h.max.Swap(0)
+
+	h.start = h.clock.Now()
+	h.last = latencySummary{
+		count: total,
+		p50:   latencyPercentile(counts[:], total, 0.50, maxUS),
+		p95:   latencyPercentile(counts[:], total, 0.95, maxUS),
+		p99:   latencyPercentile(counts[:], total, 0.99, maxUS),
+		max:   time.Duration(maxUS) * time.Microsecond,
+	}
+	return h.last
+}
+
+// report writes the summary of the last complete interval to the monitoring
+// visitor. Latencies are reported in microseconds.
+func (h *latencyHistogram) report(_ monitoring.Mode, v monitoring.Visitor) {
+	s := h.summary()
+	v.OnRegistryStart()
+	defer v.OnRegistryFinished()
+	monitoring.ReportInt(v, "count", int64(s.count))
+	monitoring.ReportInt(v, "p50_us", s.p50.Microseconds())
+	monitoring.ReportInt(v, "p95_us", s.p95.Microseconds())
+	monitoring.ReportInt(v, "p99_us", s.p99.Microseconds())
+	monitoring.ReportInt(v, "max_us", s.max.Microseconds())
+}
+
+// latencyPercentile returns the highest value of the bucket holding the q
+// percentile of total recorded values, capped at maxUS.
+func latencyPercentile(counts []uint64, total uint64, q float64, maxUS uint64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(total))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			return time.Duration(min(latencyBucketHighest(i), maxUS)) * time.Microsecond
+		}
+	}
+	return time.Duration(maxUS) * time.Microsecond
+}
+
+// latencyBucket returns the index of the bucket holding us. Values below
+// 2*latencySubBuckets have their own bucket.
+func latencyBucket(us uint64) int {
+	if us < 2*latencySubBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(us) - latencySubBucketBits - 1
+	i := (shift+1)*latencySubBuckets + int(us>>shift) - latencySubBuckets
+	return min(i, latencyBuckets-1)
+}
+
+// latencyBucketHighest returns the highest value recorded in bucket i.
+func latencyBucketHighest(i int) uint64 {
+	if i < 2*latencySubBuckets {
+		return uint64(i)
+	}
+	shift := i/latencySubBuckets - 1
+	lowest := uint64(i%latencySubBuckets+latencySubBuckets) << shift
+	return lowest + (1 << shift) - 1
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	prev := -1
+	for _, us := range []uint64{0, 1, 15, 16, 17, 31, 32, 1000, 123456, 1 << 39, 1<<40 - 1} {
+		i := latencyBucket(us)
+		assert.GreaterOrEqual(t, i, prev, "buckets must be ordered, value %d", us)
+		prev = i
+
+		highest := latencyBucketHighest(i)
+		assert.GreaterOrEqual(t, highest, us, "value %d", us)
+		assert.LessOrEqual(t, float64(highest-us), float64(us)/latencySubBuckets, "value %d", us)
+	}
+	assert.Equal(t, latencyBuckets-1, latencyBucket(1<<50), "larger values go to the last bucket")
+}
+
+func TestLatencyHistogramSummary(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	h := newLatencyHistogram(clock, time.Hour)
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	// The interval did not pass yet, nothing is reported.
+	assert.Equal(t, latencySummary{}, h.summary())
+
+	clock.Advance(time.Hour)
+	s := h.summary()
+	assert.Equal(t, uint64(100), s.count)
+	assert.Equal(t, 100*time.Millisecond, s.max)
+	assert.InEpsilon(t, 50*time.Millisecond, s.p50, 1.0/latencySubBuckets)
+	assert.InEpsilon(t, 95*time.Millisecond, s.p95, 1.0/latencySubBuckets)
+	assert.InEpsilon(t, 99*time.Millisecond, s.p99, 1.0/latencySubBuckets)
+	assert.LessOrEqual(t, s.p99, s.max)
+
+	// The summary is kept until the next interval passed.
+	h.record(time.Second)
+	assert.Equal(t, s, h.summary())
+
+	clock.Advance(time.Hour)
+	s = h.summary()
+	assert.Equal(t, uint64(1), s.count)
+	assert.Equal(t, time.Second, s.max)
+	assert.Equal(t, time.Second, s.p50)
+
+	h.Reset()
+	assert.Equal(t, latencySummary{}, h.summary())
+	clock.Advance(time.Hour)
+	assert.Equal(t, latencySummary{}, h.summary())
+}
+
+func TestClientPublishLatencyMetrics(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	metrics := monitoring.NewRegistry()
+	p, err := New(beat.Info{Logger: logp.NewTestingLogger(t, "")},
+		Monitors{Metrics: metrics},
+		conf.Namespace{},
+		outputs.Group{},
+		Settings{Clock: clock},
+	)
+	require.NoError(t, err)
+	p.outputController.queue = &testQueue{
+		producer: func(queue.ProducerConfig) queue.Producer {
+			return &testProducer{
+				publish: func(bool, queue.Entry) (queue.EntryID, bool) {
+					return 0, true
+				},
+			}
+		},
+	}
+	defer p.Close()
+
+	c, err := p.Connect()
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		c.Publish(beat.Event{Fields: mapstr.M{"i": i}})
+	}
+
+	require.NotNil(t, c.(*client).latency)
+	clock.Advance(publishLatencyInterval)
+
+	snapshot := monitoring.CollectFlatSnapshot(metrics, monitoring.Full, false)
+	assert.Equal(t, int64(10), snapshot.Ints["pipeline.publish_latency.1.count"])
+	for _, name := range []string{"p50_us", "p95_us", "p99_us", "max_us"} {
+		assert.Contains(t, snapshot.Ints, "pipeline.publish_latency.1."+name)
+	}
+
+	// The metrics of closed clients are removed.
+	require.NoError(t, c.Close())
+	snapshot = monitoring.CollectFlatSnapshot(metrics, monitoring.Full, false)
+	assert.NotContains(t, snapshot.Ints, "pipeline.publish_latency.1.count")
+}
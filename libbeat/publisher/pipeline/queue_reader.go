@@ -35,6 +35,10 @@ type queueReaderRequest struct {
 	retryer    retryer
 	batchSize  int
 	timeToLive int
+
+	// guaranteedTimeToLive limits the retries of guaranteed events, if
+	// positive.
+	guaranteedTimeToLive int
 }
 
 func makeQueueReader() queueReader {
@@ -57,7 +61,7 @@ func (qr *queueReader) run(logger *logp.Logger) {
 		queueBatch, _ := req.queue.Get(req.batchSize)
 		var batch *ttlBatch
 		if queueBatch != nil {
-			batch = newBatch(req.retryer, queueBatch, req.timeToLive)
+			batch = newBatch(req.retryer, queueBatch, req.timeToLive, req.guaranteedTimeToLive)
 		}
 		select {
 		case qr.resp <- batch:
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// reconnectRateWindow is the period over which the reported rate of
+// connection attempts is averaged.
+const reconnectRateWindow = 10 * time.Second
+
+// ReconnectLimitConfig limits the rate of the connection attempts of all the
+// output clients of the pipeline. Up to MaxAttemptsPerSecond attempts are
+// made per second, with bursts of up to Burst attempts. Attempts beyond the
+// limit wait. A MaxAttemptsPerSecond of 0 disables the limit.
+type ReconnectLimitConfig struct {
+	MaxAttemptsPerSecond float64 `config:"max_attempts_per_second" validate:"min=0"`
+	Burst                int     `config:"burst" validate:"min=0"`
+}
+
+// reconnectLimiter is shared by the output workers of the pipeline, such that
+// outputs failing at the same time, for example during a DNS outage, do not
+// flood the network with connection attempts while recovering.
+// All methods are safe to call on a nil reconnectLimiter.
+type reconnectLimiter struct {
+	limiter *rate.Limiter // nil if attempts are not limited
+
+	mutex  sync.Mutex
+	recent []time.Time // attempts made during the last reconnectRateWindow
+
+	attempts *monitoring.Uint
+	waiting  *monitoring.Int
+	now      func() time.Time
+}
+
+// newReconnectLimiter creates a reconnectLimiter for the given config. The
+// connection attempts are reported in the "reconnect" namespace of reg, if
+// not nil.
+func newReconnectLimiter(config ReconnectLimitConfig, reg *monitoring.Registry, clock clockwork.Clock) *reconnectLimiter {
+	l := &reconnectLimiter{now: clock.Now}
+	if config.MaxAttemptsPerSecond > 0 {
+		burst := config.Burst
+		if burst == 0 {
+			burst = int(math.Ceil(config.MaxAttemptsPerSecond))
+		}
+		l.limiter = rate.NewLimiter(rate.Limit(config.MaxAttemptsPerSecond), burst)
+	}
+
+	if reg != nil {
+		reg = reg.NewRegistry("reconnect")
+		// attempts counts the connection attempts of the output clients.
+		l.attempts = monitoring.NewUint(reg, "attempts")
+		// (Gauge) waiting measures the output clients waiting for the rate
+		// limit before connecting.
+		l.waiting = monitoring.NewInt(reg, "waiting")
+		// (Gauge) rate measures the connection attempts per second, averaged
+		// over the last 10 seconds.
+		monitoring.NewFunc(reg, "rate", func(_ monitoring.Mode, v monitoring.Visitor) {
+			v.OnFloat(l.rate())
+		})
+	}
+	return l
+}
+
+// wait blocks until a connection attempt is allowed by the rate limit and
+// records the attempt. An error is returned if ctx is cancelled first.
+func (l *reconnectLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if l.limiter != nil {
+		if l.waiting != nil {
+			l.waiting.Inc()
+			defer l.waiting.Dec()
+		}
+		if err := l.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if l.attempts != nil {
+		l.attempts.Inc()
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.recent = append(l.expire(l.now()), l.now())
+	return nil
+}
+
+// rate returns the number of connection attempts per second during the last
+// reconnectRateWindow.
+func (l *reconnectLimiter) rate() float64 {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.recent = l.expire(l.now())
+	return float64(len(l.recent)) / reconnectRateWindow.Seconds()
+}
+
+// expire drops the attempts older than reconnectRateWindow from recent.
+// It must be called with the mutex held.
+func (l *reconnectLimiter) expire(now time.Time) []time.Time {
+	i := 0
+	for i < len(l.recent) && now.Sub(l.recent[i]) >= reconnectRateWindow {
+		i++
+	}
+	return append(l.recent[:0], l.recent[i:]...)
+}
@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestReconnectLimiterWaits(t *testing.T) {
+	l := newReconnectLimiter(ReconnectLimitConfig{MaxAttemptsPerSecond: 20, Burst: 2}, nil, clockwork.NewRealClock())
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	// The burst is allowed right away, the 2 other attempts wait 50ms each.
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestReconnectLimiterCancelled(t *testing.T) {
+	l := newReconnectLimiter(ReconnectLimitConfig{MaxAttemptsPerSecond: 0.001}, nil, clockwork.NewRealClock())
+	require.NoError(t, l.wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, l.wait(ctx))
+}
+
+func TestReconnectLimiterUnlimited(t *testing.T) {
+	var nilLimiter *reconnectLimiter
+	require.NoError(t, nilLimiter.wait(context.Background()))
+	assert.Zero(t, nilLimiter.rate())
+
+	l := newReconnectLimiter(ReconnectLimitConfig{}, nil, clockwork.NewRealClock())
+	assert.Nil(t, l.limiter)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	assert.Equal(t, 10.0, l.rate())
+}
+
+func TestReconnectLimiterMetrics(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	clock := clockwork.NewFakeClock()
+	l := newReconnectLimiter(ReconnectLimitConfig{}, reg, clock)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	clock.Advance(5 * time.Second)
+	for i := 0; i < 15; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, int64(20), snapshot.Ints["reconnect.attempts"])
+	assert.Equal(t, int64(0), snapshot.Ints["reconnect.waiting"])
+	assert.Equal(t, 2.0, snapshot.Floats["reconnect.rate"])
+
+	// Attempts older than the rate window are not counted anymore.
+	clock.Advance(reconnectRateWindow - time.Second)
+	snapshot = monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, 1.5, snapshot.Floats["reconnect.rate"])
+	assert.Equal(t, int64(20), snapshot.Ints["reconnect.attempts"])
+}
+
+func TestReconnectLimitConfig(t *testing.T) {
+	var config Config
+	err := conf.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.reconnect_limit.max_attempts_per_second": 2.5,
+		"pipeline.reconnect_limit.burst":                   5,
+	}).Unpack(&config)
+	require.NoError(t, err)
+	assert.Equal(t, ReconnectLimitConfig{MaxAttemptsPerSecond: 2.5, Burst: 5}, config.ReconnectLimit)
+
+	err = conf.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.reconnect_limit.max_attempts_per_second": -1,
+	}).Unpack(&config)
+	assert.Error(t, err)
+}
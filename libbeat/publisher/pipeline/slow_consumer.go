@@ -0,0 +1,180 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+const (
+	defaultSlowConsumerMargin      = 0.2
+	defaultSlowConsumerDuration    = time.Minute
+	defaultSlowConsumerLogInterval = 5 * time.Minute
+)
+
+// SlowConsumerConfig configures the slow consumer warning. When enabled, the
+// pipeline compares the number of events published to the queue and
+// acknowledged by the outputs every Duration. If the ACK rate is more than
+// Margin (a fraction of the publish rate) below the publish rate, a warning
+// naming the output is logged, at most once every LogInterval.
+type SlowConsumerConfig struct {
+	Enabled     bool          `config:"enabled"`
+	Margin      float64       `config:"margin" validate:"min=0"`
+	Duration    time.Duration `config:"duration" validate:"min=0"`
+	LogInterval time.Duration `config:"log_interval" validate:"min=0"`
+}
+
+func (c *SlowConsumerConfig) Validate() error {
+	if c.Margin >= 1 {
+		return errors.New("margin must be less than 1")
+	}
+	return nil
+}
+
+// slowConsumerMonitor logs a warning when the outputs ACK events at a lower
+// rate than events are published for a prolonged time.
+type slowConsumerMonitor struct {
+	logger      *logp.Logger
+	clock       clockwork.Clock
+	margin      float64
+	duration    time.Duration
+	logInterval time.Duration
+
+	// outputs returns the names of the active output clients.
+	outputs func() []string
+
+	counter     counterSampler
+	lagging     bool
+	lastWarning time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newSlowConsumerMonitor creates a slowConsumerMonitor for the given config.
+// If the slow consumer warning is disabled, nil is returned. All methods of
+// slowConsumerMonitor are safe to be called on a nil receiver.
+func newSlowConsumerMonitor(
+	logger *logp.Logger,
+	clock clockwork.Clock,
+	config SlowConsumerConfig,
+	outputs func() []string,
+	counter *eventCounter,
+) *slowConsumerMonitor {
+	if !config.Enabled {
+		return nil
+	}
+
+	margin := config.Margin
+	if margin <= 0 {
+		margin = defaultSlowConsumerMargin
+	}
+	duration := config.Duration
+	if duration <= 0 {
+		duration = defaultSlowConsumerDuration
+	}
+	logInterval := config.LogInterval
+	if logInterval <= 0 {
+		logInterval = defaultSlowConsumerLogInterval
+	}
+
+	return &slowConsumerMonitor{
+		logger:      logger,
+		clock:       clock,
+		margin:      margin,
+		duration:    duration,
+		logInterval: logInterval,
+		outputs:     outputs,
+		counter:     counterSampler{counter: counter},
+		done:        make(chan struct{}),
+	}
+}
+
+func (m *slowConsumerMonitor) start() {
+	if m == nil {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run()
+	}()
+}
+
+func (m *slowConsumerMonitor) close() {
+	if m == nil {
+		return
+	}
+
+	close(m.done)
+	m.wg.Wait()
+}
+
+func (m *slowConsumerMonitor) run() {
+	ticker := m.clock.NewTicker(m.duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.Chan():
+			pub, ack := m.counter.sample()
+			m.check(now, pub, ack)
+		}
+	}
+}
+
+// check compares the number of events published and acked since the last
+// check and logs a warning if the outputs are lagging behind.
+func (m *slowConsumerMonitor) check(now time.Time, pub, ack uint64) {
+	lagging := pub > 0 && float64(ack) < (1-m.margin)*float64(pub)
+	if !lagging {
+		if m.lagging {
+			m.logger.Infof("Output %v caught up with the published events", m.outputNames())
+		}
+		m.lagging = false
+		return
+	}
+
+	m.lagging = true
+	if !m.lastWarning.IsZero() && now.Sub(m.lastWarning) < m.logInterval {
+		return
+	}
+	m.lastWarning = now
+
+	seconds := m.duration.Seconds()
+	m.logger.Warnf("Output %v is not keeping up: ACK rate %.1f/s was below the publish rate %.1f/s for the last %v, events are piling up in the queue",
+		m.outputNames(), float64(ack)/seconds, float64(pub)/seconds, m.duration)
+}
+
+func (m *slowConsumerMonitor) outputNames() string {
+	names := m.outputs()
+	if len(names) == 0 {
+		return "<none>"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	zapobserver "go.uber.org/zap/zaptest/observer"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func TestSlowConsumerMonitorDisabled(t *testing.T) {
+	m := newSlowConsumerMonitor(logp.NewTestingLogger(t, ""), clockwork.NewRealClock(), SlowConsumerConfig{}, nil, nil)
+	require.Nil(t, m)
+
+	// all methods must be safe on a nil monitor
+	m.start()
+	m.close()
+}
+
+func TestSlowConsumerMonitor(t *testing.T) {
+	observed, zapLogs := zapobserver.New(zapcore.InfoLevel)
+	logger, err := logp.ConfigureWithCoreLocal(logp.Config{}, observed)
+	require.NoError(t, err)
+
+	m := newSlowConsumerMonitor(logger, clockwork.NewRealClock(), SlowConsumerConfig{
+		Enabled:     true,
+		Margin:      0.2,
+		Duration:    10 * time.Second,
+		LogInterval: time.Minute,
+	}, func() []string { return []string{"elasticsearch(http://localhost:9200)"} }, nil)
+	require.NotNil(t, m)
+
+	now := time.Now()
+	step := func(pub, ack uint64) (warnings, infos int) {
+		now = now.Add(10 * time.Second)
+		m.check(now, pub, ack)
+		for _, entry := range zapLogs.TakeAll() {
+			switch entry.Level {
+			case zapcore.WarnLevel:
+				assert.Contains(t, entry.Message, "elasticsearch(http://localhost:9200)")
+				warnings++
+			case zapcore.InfoLevel:
+				infos++
+			}
+		}
+		return warnings, infos
+	}
+
+	// output keeps up within the margin
+	warnings, _ := step(100, 100)
+	assert.Zero(t, warnings)
+	warnings, _ = step(100, 85)
+	assert.Zero(t, warnings)
+
+	// output falls behind
+	warnings, _ = step(100, 50)
+	assert.Equal(t, 1, warnings)
+
+	// warnings are rate limited
+	for i := 0; i < 4; i++ {
+		warnings, _ = step(100, 50)
+		assert.Zero(t, warnings)
+	}
+
+	// no activity resolves the lag
+	_, infos := step(0, 0)
+	assert.Equal(t, 1, infos)
+
+	// lagging again after the log interval warns again
+	for i := 0; i < 2; i++ {
+		step(100, 100)
+	}
+	warnings, _ = step(100, 10)
+	assert.Equal(t, 1, warnings)
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// SlowStartConfig ramps up the publishing rate of every output client after
+// it reconnects, such that an output recovering from an outage is not flooded
+// with the events queued in the meantime. The rate grows linearly from
+// InitialEventsPerSecond to FinalEventsPerSecond over Duration, and is not
+// limited anymore afterwards. A Duration of 0 disables the slow start.
+type SlowStartConfig struct {
+	Duration               time.Duration `config:"duration" validate:"min=0"`
+	InitialEventsPerSecond float64       `config:"initial_events_per_second" validate:"min=0"`
+	FinalEventsPerSecond   float64       `config:"final_events_per_second" validate:"min=0"`
+}
+
+func (c *SlowStartConfig) Validate() error {
+	if c.Duration == 0 {
+		return nil
+	}
+	if c.InitialEventsPerSecond <= 0 {
+		return errors.New("initial_events_per_second must be positive")
+	}
+	if c.FinalEventsPerSecond < c.InitialEventsPerSecond {
+		return errors.New("final_events_per_second must not be lower than initial_events_per_second")
+	}
+	return nil
+}
+
+// slowStart is shared by the output workers of the pipeline to start a ramp
+// on reconnect, and reports the ramps in progress.
+// All methods are safe to call on a nil slowStart.
+type slowStart struct {
+	config SlowStartConfig
+	clock  clockwork.Clock
+
+	mutex sync.Mutex
+	ramps map[*slowStartRamp]struct{}
+}
+
+// slowStartRamp limits the publishing rate of a single output client.
+type slowStartRamp struct {
+	s     *slowStart
+	start time.Time
+	// next is the time the next batch can be published at.
+	next time.Time
+}
+
+// newSlowStart creates a slowStart for the given config, or returns nil if
+// the slow start is disabled. The ramps are reported in the "slow_start"
+// namespace of reg, if not nil.
+func newSlowStart(config SlowStartConfig, clock clockwork.Clock, reg *monitoring.Registry) *slowStart {
+	if config.Duration <= 0 {
+		return nil
+	}
+	s := &slowStart{
+		config: config,
+		clock:  clock,
+		ramps:  map[*slowStartRamp]struct{}{},
+	}
+
+	if reg != nil {
+		reg = reg.NewRegistry("slow_start")
+		// (Gauge) ramping measures the output clients ramping up after a
+		// reconnect.
+		monitoring.NewFunc(reg, "ramping", func(_ monitoring.Mode, v monitoring.Visitor) {
+			ramping, _ := s.state()
+			v.OnInt(int64(ramping))
+		})
+		// (Gauge) events_per_second measures the total rate of events the
+		// ramping output clients are currently allowed to publish.
+		monitoring.NewFunc(reg, "events_per_second", func(_ monitoring.Mode, v monitoring.Visitor) {
+			_, rate := s.state()
+			v.OnFloat(rate)
+		})
+	}
+	return s
+}
+
+// begin starts a new ramp. It returns nil if the slow start is disabled.
+func (s *slowStart) begin() *slowStartRamp {
+	if s == nil {
+		return nil
+	}
+	now := s.clock.Now()
+	r := &slowStartRamp{s: s, start: now, next: now}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ramps[r] = struct{}{}
+	return r
+}
+
+// state returns the number of ramps in progress and the sum of their rates.
+func (s *slowStart) state() (int, float64) {
+	now := s.clock.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	total := 0.0
+	for r := range s.ramps {
+		if rate, ok := r.rate(now); ok {
+			total += rate
+		}
+	}
+	return len(s.ramps), total
+}
+
+// rate returns the events per second allowed at now, and false if the ramp is
+// over.
+func (r *slowStartRamp) rate(now time.Time) (float64, bool) {
+	config := r.s.config
+	elapsed := now.Sub(r.start)
+	if elapsed >= config.Duration {
+		return 0, false
+	}
+	progress := float64(elapsed) / float64(config.Duration)
+	return config.InitialEventsPerSecond + progress*(config.FinalEventsPerSecond-config.InitialEventsPerSecond), true
+}
+
+// wait blocks until a batch of n events can be published, and reserves the
+// time needed to publish them at the current rate for the next batch. It
+// returns false once the ramp is over, and an error if ctx is cancelled
+// first. wait must not be called concurrently.
+func (r *slowStartRamp) wait(ctx context.Context, n int) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+	clock := r.s.clock
+	if delay := r.next.Sub(clock.Now()); delay > 0 {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-clock.After(delay):
+		}
+	}
+
+	now := clock.Now()
+	rate, ok := r.rate(now)
+	if !ok {
+		r.finish()
+		return false, nil
+	}
+	r.next = now.Add(time.Duration(float64(n) / rate * float64(time.Second)))
+	return true, nil
+}
+
+// finish ends the ramp.
+func (r *slowStartRamp) finish() {
+	if r == nil {
+		return
+	}
+	r.s.mutex.Lock()
+	defer r.s.mutex.Unlock()
+	delete(r.s.ramps, r)
+}
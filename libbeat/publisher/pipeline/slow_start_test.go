@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestSlowStartRamp(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	reg := monitoring.NewRegistry()
+	s := newSlowStart(SlowStartConfig{
+		Duration:               10 * time.Second,
+		InitialEventsPerSecond: 10,
+		FinalEventsPerSecond:   110,
+	}, clock, reg)
+	require.NotNil(t, s)
+
+	r := s.begin()
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, int64(1), snapshot.Ints["slow_start.ramping"])
+	assert.Equal(t, 10.0, snapshot.Floats["slow_start.events_per_second"])
+
+	// The first batch is published right away, and takes 1s at 10 events/s.
+	ramping, err := r.wait(context.Background(), 10)
+	require.NoError(t, err)
+	assert.True(t, ramping)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ramping, err := r.wait(context.Background(), 10)
+		assert.NoError(t, err)
+		assert.True(t, ramping)
+	}()
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("the second batch must wait for the first one")
+	default:
+	}
+	clock.Advance(time.Second)
+	<-done
+
+	// After 1s the rate grew to 20 events/s.
+	snapshot = monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, 20.0, snapshot.Floats["slow_start.events_per_second"])
+	assert.Equal(t, clock.Now().Add(500*time.Millisecond), r.next)
+
+	// The ramp is over after the configured duration.
+	clock.Advance(10 * time.Second)
+	ramping, err = r.wait(context.Background(), 10)
+	require.NoError(t, err)
+	assert.False(t, ramping)
+	snapshot = monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, int64(0), snapshot.Ints["slow_start.ramping"])
+	assert.Equal(t, 0.0, snapshot.Floats["slow_start.events_per_second"])
+}
+
+func TestSlowStartCancelled(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	s := newSlowStart(SlowStartConfig{
+		Duration:               time.Minute,
+		InitialEventsPerSecond: 1,
+		FinalEventsPerSecond:   1,
+	}, clock, nil)
+
+	r := s.begin()
+	defer r.finish()
+	_, err := r.wait(context.Background(), 100)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = r.wait(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSlowStartDisabled(t *testing.T) {
+	s := newSlowStart(SlowStartConfig{}, clockwork.NewFakeClock(), monitoring.NewRegistry())
+	assert.Nil(t, s)
+
+	r := s.begin()
+	assert.Nil(t, r)
+	ramping, err := r.wait(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.False(t, ramping)
+	r.finish()
+}
+
+func TestSlowStartConfig(t *testing.T) {
+	var config Config
+	err := conf.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.slow_start.duration":                  "30s",
+		"pipeline.slow_start.initial_events_per_second": 100,
+		"pipeline.slow_start.final_events_per_second":   1000,
+	}).Unpack(&config)
+	require.NoError(t, err)
+	assert.Equal(t, SlowStartConfig{
+		Duration:               30 * time.Second,
+		InitialEventsPerSecond: 100,
+		FinalEventsPerSecond:   1000,
+	}, config.SlowStart)
+
+	for _, invalid := range []map[string]interface{}{
+		{"pipeline.slow_start.duration": "30s"},
+		{"pipeline.slow_start.duration": "30s", "pipeline.slow_start.initial_events_per_second": 100},
+		{"pipeline.slow_start.duration": "-1s"},
+	} {
+		var config Config
+		assert.Error(t, conf.MustNewConfigFrom(invalid).Unpack(&config), invalid)
+	}
+}
@@ -39,6 +39,10 @@ type generateConfig struct {
 	WaitClose   time.Duration `config:"wait_close"`
 	PublishMode string        `config:"publish_mode"`
 	Watchdog    time.Duration `config:"watchdog"`
+
+	// Verify stamps each event with a checksum and enables the verification
+	// of the events in the test output.
+	Verify bool `config:"verify"`
 }
 
 var defaultGenerateConfig = generateConfig{
@@ -144,16 +148,20 @@ func generate(
 	defer logger.Infof("stop (%v) generator: %v", id, time.Now())
 
 	for cs.Active() {
+		seq := count.Load()
 		event := beat.Event{
 			Timestamp: time.Now(),
 			Fields: mapstr.M{
 				"id":    id,
 				"hello": "world",
-				"count": count.Load(),
+				"count": seq,
 
 				// TODO: more custom event generation?
 			},
 		}
+		if config.Verify {
+			event.Fields["checksum"] = checksum(id, seq, "world")
+		}
 
 		client.Publish(event)
 
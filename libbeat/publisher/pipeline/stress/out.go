@@ -20,18 +20,25 @@ package stress
 import (
 	"context"
 	"math/rand/v2"
+	"sync"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
 )
 
 type testOutput struct {
 	config     testOutputConfig
 	observer   outputs.Observer
 	batchCount int
+
+	// verifier is shared by all clients of the output, nil if verification
+	// is disabled.
+	verifier  *verifier
+	closeOnce sync.Once
 }
 
 type testOutputConfig struct {
@@ -44,6 +51,10 @@ type testOutputConfig struct {
 	Fail        struct {
 		EveryBatch int
 	}
+
+	// Verify checks the ACKed events for gaps, reordering and corruption.
+	// Requires the generator to stamp the events.
+	Verify bool `config:"verify"`
 }
 
 var defaultTestOutputConfig = testOutputConfig{
@@ -61,16 +72,30 @@ func makeTestOutput(_ outputs.IndexManager, beat beat.Info, observer outputs.Obs
 		return outputs.Fail(err)
 	}
 
+	var v *verifier
+	if config.Verify {
+		logger := beat.Logger
+		if logger == nil {
+			logger = logp.L()
+		}
+		v = newVerifier(logger, config.Worker)
+	}
+
 	clients := make([]outputs.Client, config.Worker)
 	for i := range clients {
-		client := &testOutput{config: config, observer: observer}
+		client := &testOutput{config: config, observer: observer, verifier: v}
 		clients[i] = client
 	}
 
 	return outputs.Success(config.Queue, config.BulkMaxSize, config.Retry, nil, clients...)
 }
 
-func (*testOutput) Close() error { return nil }
+func (t *testOutput) Close() error {
+	if t.verifier != nil {
+		t.closeOnce.Do(t.verifier.clientClosed)
+	}
+	return nil
+}
 
 func (t *testOutput) Publish(_ context.Context, batch publisher.Batch) error {
 	config := &t.config
@@ -102,6 +127,10 @@ func (t *testOutput) Publish(_ context.Context, batch publisher.Batch) error {
 
 	// TODO: add support to fail single events at end of batch or randomly
 
+	if t.verifier != nil {
+		t.verifier.verify(batch.Events())
+	}
+
 	// ack complete batch
 	batch.ACK()
 	t.observer.AckedEvents(n)
@@ -61,6 +61,13 @@ func RunTests(
 
 	log := logp.L()
 
+	if config.Generate.Verify && config.Output.IsSet() {
+		// let the test output verify the events stamped by the generators
+		if err := config.Output.Config().SetBool("verify", -1, true); err != nil {
+			return fmt.Errorf("enabling output verification failed: %w", err)
+		}
+	}
+
 	processing, err := processing.MakeDefaultSupport(false, nil)(info, log, cfg)
 	if err != nil {
 		return err
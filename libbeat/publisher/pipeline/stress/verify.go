@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stress
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// checksum computes the checksum of the generated event fields, stamped by
+// the generator if verification is enabled.
+func checksum(id, seq, hello interface{}) uint32 {
+	return crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v/%v/%v", id, seq, hello)))
+}
+
+// verifier checks the events received by the test outputs for gaps,
+// reordering and corruption. Sequence numbers are tracked per generator.
+// With multiple output workers, or with events dropped by the pipeline,
+// gaps and reordering are expected.
+type verifier struct {
+	logger *logp.Logger
+
+	mutex   sync.Mutex
+	next    map[string]uint64
+	clients int
+
+	received   uint64
+	missing    uint64
+	outOfOrder uint64
+	corrupted  uint64
+}
+
+type verifyStats struct {
+	Received   uint64
+	Missing    uint64
+	OutOfOrder uint64
+	Corrupted  uint64
+}
+
+func newVerifier(logger *logp.Logger, clients int) *verifier {
+	return &verifier{
+		logger:  logger.Named("publisher_pipeline_stress_verify"),
+		next:    map[string]uint64{},
+		clients: clients,
+	}
+}
+
+// verify checks the events of an acknowledged batch.
+func (v *verifier) verify(events []publisher.Event) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for i := range events {
+		v.verifyEvent(&events[i].Content)
+	}
+}
+
+func (v *verifier) verifyEvent(event *beat.Event) {
+	v.received++
+
+	id, errID := event.GetValue("id")
+	seq, errSeq := event.GetValue("count")
+	hello, errHello := event.GetValue("hello")
+	sum, errSum := event.GetValue("checksum")
+	if errID != nil || errSeq != nil || errHello != nil || errSum != nil || sum != checksum(id, seq, hello) {
+		v.corrupted++
+		v.logger.Errorf("Corrupted event received: %v", event.Fields)
+		return
+	}
+
+	generator := fmt.Sprint(id)
+	n, ok := seq.(uint64)
+	if !ok {
+		v.corrupted++
+		v.logger.Errorf("Event with invalid sequence received: %v", event.Fields)
+		return
+	}
+
+	next := v.next[generator]
+	switch {
+	case n == next:
+		v.next[generator] = n + 1
+	case n > next:
+		v.missing += n - next
+		v.next[generator] = n + 1
+	default:
+		// Events skipped before have been received late.
+		v.outOfOrder++
+		if v.missing > 0 {
+			v.missing--
+		}
+	}
+}
+
+func (v *verifier) stats() verifyStats {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return verifyStats{
+		Received:   v.received,
+		Missing:    v.missing,
+		OutOfOrder: v.outOfOrder,
+		Corrupted:  v.corrupted,
+	}
+}
+
+// clientClosed reports the verification results once all output clients
+// sharing the verifier have been closed.
+func (v *verifier) clientClosed() {
+	v.mutex.Lock()
+	v.clients--
+	last := v.clients == 0
+	v.mutex.Unlock()
+
+	if last {
+		v.report()
+	}
+}
+
+func (v *verifier) report() {
+	s := v.stats()
+	v.logger.Infof("Verified events: received=%v, missing=%v, out_of_order=%v, corrupted=%v",
+		s.Received, s.Missing, s.OutOfOrder, s.Corrupted)
+}
@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package stress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestVerifier(t *testing.T) {
+	event := func(id int, seq uint64) publisher.Event {
+		return publisher.Event{Content: beat.Event{Fields: mapstr.M{
+			"id":       id,
+			"hello":    "world",
+			"count":    seq,
+			"checksum": checksum(id, seq, "world"),
+		}}}
+	}
+
+	corrupted := event(1, 4)
+	corrupted.Content.Fields["hello"] = "w0rld"
+
+	v := newVerifier(logp.NewTestingLogger(t, ""), 1)
+	v.verify([]publisher.Event{event(0, 0), event(1, 0), event(0, 1), event(1, 1)})
+	assert.Equal(t, verifyStats{Received: 4}, v.stats())
+
+	// gap of two events for generator 0
+	v.verify([]publisher.Event{event(0, 4), event(1, 2)})
+	assert.Equal(t, verifyStats{Received: 6, Missing: 2}, v.stats())
+
+	// one of the missing events is received late
+	v.verify([]publisher.Event{event(0, 2)})
+	assert.Equal(t, verifyStats{Received: 7, Missing: 1, OutOfOrder: 1}, v.stats())
+
+	v.verify([]publisher.Event{corrupted, event(1, 3)})
+	assert.Equal(t, verifyStats{Received: 9, Missing: 1, OutOfOrder: 1, Corrupted: 1}, v.stats())
+}
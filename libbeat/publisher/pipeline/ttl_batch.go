@@ -18,10 +18,13 @@
 package pipeline
 
 import (
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/logp"
 )
 
 type retryer interface {
@@ -40,6 +43,10 @@ type ttlBatch struct {
 	// How many retries until we drop this batch. -1 means it can't be dropped.
 	ttl int
 
+	// How many retries until the events with guaranteed sending requirements
+	// are dropped. 0 or less means they are retried forever.
+	guaranteedTTL int
+
 	// The cached events returned from original.Events(). If some but not
 	// all of the events are ACKed, those ones are removed from the list.
 	events []publisher.Event
@@ -49,6 +56,26 @@ type ttlBatch struct {
 	// all split batches descending from the same original batch will
 	// point to the same metadata.
 	split *batchSplitData
+
+	// ackMutex protects the ACK timeout state below, and the events of the
+	// batch while it is in flight.
+	ackMutex sync.Mutex
+
+	// ackTimer requeues the events if the output does not complete the batch
+	// within the ACK timeout. It is nil unless the batch is in flight.
+	ackTimer *ackTimer
+
+	// ackTimerID identifies the current ACK timeout, such that a timeout firing
+	// after the batch was completed and sent again is ignored.
+	ackTimerID int
+
+	// completed is set once the output completed the batch, it is reset when
+	// the batch is sent to the retry queue of the eventConsumer.
+	completed bool
+
+	// timedOut is set once the ACK timeout expired. The events were requeued
+	// in a new batch then, the late calls of the output are ignored.
+	timedOut bool
 }
 
 type batchSplitData struct {
@@ -61,7 +88,7 @@ type batchSplitData struct {
 	outstandingEvents atomic.Int64
 }
 
-func newBatch(retryer retryer, original queue.Batch, ttl int) *ttlBatch {
+func newBatch(retryer retryer, original queue.Batch, ttl, guaranteedTTL int) *ttlBatch {
 	if original == nil {
 		panic("empty batch")
 	}
@@ -80,10 +107,11 @@ func newBatch(retryer retryer, original queue.Batch, ttl int) *ttlBatch {
 	original.FreeEntries()
 
 	b := &ttlBatch{
-		done:    original.Done,
-		retryer: retryer,
-		ttl:     ttl,
-		events:  events,
+		done:          original.Done,
+		retryer:       retryer,
+		ttl:           ttl,
+		guaranteedTTL: guaranteedTTL,
+		events:        events,
 	}
 	return b
 }
@@ -93,12 +121,69 @@ func (b *ttlBatch) Events() []publisher.Event {
 }
 
 func (b *ttlBatch) ACK() {
+	if !b.complete() {
+		return
+	}
 	// Help the garbage collector clean up the event data a little faster
 	b.events = nil
 	b.done()
 }
 
+// ACKEventIDs acknowledges single events of the batch. Acknowledged events
+// are reported to the client that published them and removed from the batch.
+// The batch is done once all of its events have been acknowledged.
+func (b *ttlBatch) ACKEventIDs(ids []uint64) {
+	if len(ids) == 0 {
+		return
+	}
+
+	b.ackMutex.Lock()
+	if b.timedOut || len(b.events) == 0 {
+		b.ackMutex.Unlock()
+		return
+	}
+
+	wanted := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	acked := map[publisher.EventIDACKer][]uint64{}
+	events := b.events[:0]
+	for _, event := range b.events {
+		if _, ok := wanted[event.ID]; ok && event.ID != 0 {
+			if event.IDACKer != nil {
+				acked[event.IDACKer] = append(acked[event.IDACKer], event.ID)
+			}
+			continue
+		}
+		events = append(events, event)
+	}
+	b.events = events
+
+	var done func()
+	if len(b.events) == 0 {
+		b.events = nil
+		b.stopACKTimeout()
+		done = b.done
+		// Guard against outputs still calling ACK on the completed batch.
+		b.done = func() {}
+	}
+	b.ackMutex.Unlock()
+
+	for acker, ids := range acked {
+		acker.ACKEventIDs(ids)
+	}
+
+	if done != nil {
+		done()
+	}
+}
+
 func (b *ttlBatch) Drop() {
+	if !b.complete() {
+		return
+	}
 	// Help the garbage collector clean up the event data a little faster
 	b.events = nil
 	b.done()
@@ -113,6 +198,10 @@ func (b *ttlBatch) SplitRetry() bool {
 		// This batch is already as small as it can get
 		return false
 	}
+	if !b.complete() {
+		// The events were requeued already
+		return true
+	}
 	splitData := b.split
 	if splitData == nil {
 		// Splitting a previously unsplit batch, create the metadata
@@ -126,18 +215,20 @@ func (b *ttlBatch) SplitRetry() bool {
 	events1 := b.events[:splitIndex]
 	events2 := b.events[splitIndex:]
 	b.retryer.retry(&ttlBatch{
-		events:  events1,
-		done:    splitData.doneCallback(len(events1)),
-		retryer: b.retryer,
-		ttl:     b.ttl,
-		split:   splitData,
+		events:        events1,
+		done:          splitData.doneCallback(len(events1)),
+		retryer:       b.retryer,
+		ttl:           b.ttl,
+		guaranteedTTL: b.guaranteedTTL,
+		split:         splitData,
 	}, false)
 	b.retryer.retry(&ttlBatch{
-		events:  events2,
-		done:    splitData.doneCallback(len(events2)),
-		retryer: b.retryer,
-		ttl:     b.ttl,
-		split:   splitData,
+		events:        events2,
+		done:          splitData.doneCallback(len(events2)),
+		retryer:       b.retryer,
+		ttl:           b.ttl,
+		guaranteedTTL: b.guaranteedTTL,
+		split:         splitData,
 	}, false)
 	return true
 }
@@ -154,46 +245,139 @@ func (splitData *batchSplitData) doneCallback(eventCount int) func() {
 }
 
 func (b *ttlBatch) Retry() {
+	if !b.complete() {
+		return
+	}
 	b.retryer.retry(b, true)
 }
 
 func (b *ttlBatch) Cancelled() {
+	if !b.complete() {
+		return
+	}
 	b.retryer.retry(b, false)
 }
 
 func (b *ttlBatch) RetryEvents(events []publisher.Event) {
+	if !b.complete() {
+		return
+	}
 	b.events = events
-	b.Retry()
+	b.retryer.retry(b, true)
 }
 
-// reduceTTL reduces the time to live for all events that have no 'guaranteed'
-// sending requirements.  reduceTTL returns true if the batch is still alive.
-func (b *ttlBatch) reduceTTL() bool {
-	if b.ttl <= 0 {
-		return true
+// startACKTimeout is called by the eventConsumer after sending the batch to
+// an output. If the output does not complete the batch within timeout, its
+// events are requeued for retry in a new batch.
+func (b *ttlBatch) startACKTimeout(timeouts *ackTimeouts, timeout time.Duration) {
+	b.ackMutex.Lock()
+	defer b.ackMutex.Unlock()
+	if b.completed || b.timedOut {
+		// The output was faster than us
+		return
 	}
+	b.ackTimerID++
+	b.ackTimer = timeouts.start(b, b.ackTimerID, timeout)
+}
 
-	b.ttl--
-	if b.ttl > 0 {
-		return true
+// resetACKTimeout prepares a batch sent back to the eventConsumer by the
+// output for being sent again.
+func (b *ttlBatch) resetACKTimeout() {
+	b.ackMutex.Lock()
+	defer b.ackMutex.Unlock()
+	b.completed = false
+}
+
+// complete marks the batch as completed by the output and stops its ACK
+// timeout. It returns false if the ACK timeout expired already, in which case
+// the events are owned by another batch and the output's call must be
+// ignored.
+func (b *ttlBatch) complete() bool {
+	b.ackMutex.Lock()
+	defer b.ackMutex.Unlock()
+	if b.timedOut {
+		return false
+	}
+	b.stopACKTimeout()
+	return true
+}
+
+// stopACKTimeout must be called with ackMutex held.
+func (b *ttlBatch) stopACKTimeout() {
+	b.completed = true
+	if b.ackTimer != nil {
+		b.ackTimer.stop()
+		b.ackTimer = nil
 	}
+}
 
-	// filter for events with guaranteed send flags
+func (b *ttlBatch) expireACKTimeout(id int, timeout time.Duration, log *logp.Logger) {
+	b.ackMutex.Lock()
+	if b.completed || b.timedOut || id != b.ackTimerID {
+		b.ackMutex.Unlock()
+		return
+	}
+	b.timedOut = true
+	b.ackTimer = nil
+	// The new batch gets its own copy of the events, the output may still be
+	// reading them.
+	requeued := &ttlBatch{
+		done:          b.done,
+		retryer:       b.retryer,
+		ttl:           b.ttl,
+		guaranteedTTL: b.guaranteedTTL,
+		events:        append([]publisher.Event(nil), b.events...),
+		split:         b.split,
+	}
+	b.ackMutex.Unlock()
+
+	log.Warnf("Output did not acknowledge %d events within %v, requeueing them for retry",
+		len(requeued.events), timeout)
+	b.retryer.retry(requeued, true)
+}
+
+// reduceTTL reduces the time to live for all events that have no 'guaranteed'
+// sending requirements, and for the guaranteed events if their number of
+// retries is limited. Events whose time to live expired are removed from the
+// batch. reduceTTL returns true if the batch is still alive, and the number of
+// guaranteed events removed.
+func (b *ttlBatch) reduceTTL() (bool, int) {
+	expired := expireTTL(&b.ttl)
+	guaranteedExpired := expireTTL(&b.guaranteedTTL)
+	if !expired && !guaranteedExpired {
+		return true, 0
+	}
+
+	// filter for events which are still allowed to be retried
+	guaranteedDropped := 0
 	events := b.events[:0]
 	for _, event := range b.events {
-		if event.Guaranteed() {
+		switch {
+		case !event.Guaranteed() && expired:
+		case event.Guaranteed() && guaranteedExpired:
+			guaranteedDropped++
+		default:
 			events = append(events, event)
 		}
 	}
 	b.events = events
 
-	if len(b.events) > 0 {
-		b.ttl = -1 // we need infinite retry for all events left in this batch
-		return true
-	}
+	return len(b.events) > 0, guaranteedDropped
+}
 
-	// all events have been dropped:
-	return false
+// expireTTL decreases a positive time to live and reports whether it just
+// expired. An expired time to live is set to -1, such that the remaining
+// events are retried forever.
+func expireTTL(ttl *int) bool {
+	if *ttl <= 0 {
+		return false
+	}
+	*ttl--
+	if *ttl > 0 {
+		return false
+	}
+	*ttl = -1
+	return true
 }
 
 ///////////////////////////////////////////////////////////////////////
@@ -20,12 +20,15 @@ package pipeline
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/logp"
 )
 
 func TestBatchSplitRetry(t *testing.T) {
@@ -114,7 +117,7 @@ func TestBatchCallsDoneAndFreesEvents(t *testing.T) {
 
 func TestNewBatchFreesEvents(t *testing.T) {
 	queueBatch := &mockQueueBatch{}
-	_ = newBatch(nil, queueBatch, 0)
+	_ = newBatch(nil, queueBatch, 0, 0)
 	assert.Equal(t, 1, queueBatch.freeEntriesCalled, "Creating a new ttlBatch should call FreeEntries on the underlying queue.Batch")
 }
 
@@ -144,3 +147,238 @@ type mockRetryer struct {
 func (r *mockRetryer) retry(batch *ttlBatch, decreaseTTL bool) {
 	r.batches = append(r.batches, batch)
 }
+
+func TestBatchACKEventIDs(t *testing.T) {
+	acker := &mockEventIDACKer{}
+	doneCount := 0
+	batch := ttlBatch{
+		events: []publisher.Event{
+			{ID: 1, IDACKer: acker},
+			{ID: 2, IDACKer: acker},
+			{ID: 3, IDACKer: acker},
+		},
+		retryer: &mockRetryer{},
+		done:    func() { doneCount++ },
+	}
+
+	batch.ACKEventIDs([]uint64{2, 42})
+	assert.Equal(t, [][]uint64{{2}}, acker.acked, "Only events in the batch should be acknowledged")
+	require.Len(t, batch.events, 2, "Acknowledged events should be removed from the batch")
+	assert.Equal(t, uint64(1), batch.events[0].ID)
+	assert.Equal(t, uint64(3), batch.events[1].ID)
+	assert.Equal(t, 0, doneCount, "Batch shouldn't be done while events are pending")
+
+	batch.ACKEventIDs([]uint64{3, 1})
+	assert.Equal(t, [][]uint64{{2}, {1, 3}}, acker.acked)
+	assert.Equal(t, 1, doneCount, "Batch should be done once all events are acknowledged")
+
+	batch.ACK()
+	assert.Equal(t, 1, doneCount, "Completed batch must not signal the queue twice")
+}
+
+type mockEventIDACKer struct {
+	acked [][]uint64
+}
+
+func (m *mockEventIDACKer) ACKEventIDs(ids []uint64) {
+	m.acked = append(m.acked, ids)
+}
+
+func TestBatchReduceTTL(t *testing.T) {
+	makeEvents := func() []publisher.Event {
+		return []publisher.Event{
+			{Flags: publisher.GuaranteedSend},
+			{},
+			{Flags: publisher.GuaranteedSend},
+		}
+	}
+
+	t.Run("guaranteed events are retried forever by default", func(t *testing.T) {
+		batch := &ttlBatch{events: makeEvents(), ttl: 2}
+
+		alive, dropped := batch.reduceTTL()
+		assert.True(t, alive)
+		assert.Zero(t, dropped)
+		assert.Len(t, batch.events, 3)
+
+		alive, dropped = batch.reduceTTL()
+		assert.True(t, alive)
+		assert.Zero(t, dropped)
+		assert.Len(t, batch.events, 2, "the event without guaranteed send must be dropped")
+
+		for i := 0; i < 100; i++ {
+			alive, dropped = batch.reduceTTL()
+			assert.True(t, alive)
+			assert.Zero(t, dropped)
+		}
+		assert.Len(t, batch.events, 2)
+	})
+
+	t.Run("guaranteed events are dropped after their retries", func(t *testing.T) {
+		batch := &ttlBatch{events: makeEvents(), ttl: 1, guaranteedTTL: 3}
+
+		alive, dropped := batch.reduceTTL()
+		assert.True(t, alive)
+		assert.Zero(t, dropped)
+		assert.Len(t, batch.events, 2)
+
+		alive, dropped = batch.reduceTTL()
+		assert.True(t, alive)
+		assert.Zero(t, dropped)
+
+		alive, dropped = batch.reduceTTL()
+		assert.False(t, alive)
+		assert.Equal(t, 2, dropped)
+		assert.Empty(t, batch.events)
+	})
+
+	t.Run("guaranteed events can expire first", func(t *testing.T) {
+		batch := &ttlBatch{events: makeEvents(), ttl: 3, guaranteedTTL: 1}
+
+		alive, dropped := batch.reduceTTL()
+		assert.True(t, alive)
+		assert.Equal(t, 2, dropped)
+		assert.Len(t, batch.events, 1)
+		assert.False(t, batch.events[0].Guaranteed())
+	})
+}
+
+type chanRetryer struct {
+	ch chan *ttlBatch
+}
+
+func (r chanRetryer) retry(batch *ttlBatch, _ bool) {
+	r.ch <- batch
+}
+
+func TestBatchACKTimeout(t *testing.T) {
+	log := logp.NewLogger("test")
+
+	t.Run("expired batch is requeued and ignores late calls", func(t *testing.T) {
+		clock := clockwork.NewFakeClock()
+		timeouts := newACKTimeouts(clock, log)
+		defer timeouts.close()
+		retryer := chanRetryer{ch: make(chan *ttlBatch, 1)}
+		doneCount := 0
+		batch := &ttlBatch{
+			events:  make([]publisher.Event, 3),
+			retryer: retryer,
+			done:    func() { doneCount++ },
+			ttl:     3,
+		}
+		batch.startACKTimeout(timeouts, time.Minute)
+		clock.Advance(time.Minute)
+
+		var requeued *ttlBatch
+		select {
+		case requeued = <-retryer.ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("batch was not requeued after its ACK timeout")
+		}
+		require.NotSame(t, batch, requeued, "requeued events need a new batch")
+		assert.Len(t, requeued.events, 3)
+		assert.Equal(t, 3, requeued.ttl)
+
+		// The late calls of the output must not complete the requeued events
+		batch.ACK()
+		batch.Retry()
+		assert.Zero(t, doneCount)
+		assert.Len(t, retryer.ch, 0)
+
+		requeued.ACK()
+		assert.Equal(t, 1, doneCount)
+	})
+
+	t.Run("completed batch does not expire", func(t *testing.T) {
+		clock := clockwork.NewFakeClock()
+		timeouts := newACKTimeouts(clock, log)
+		defer timeouts.close()
+		retryer := chanRetryer{ch: make(chan *ttlBatch, 1)}
+		doneCount := 0
+		batch := &ttlBatch{
+			events:  make([]publisher.Event, 3),
+			retryer: retryer,
+			done:    func() { doneCount++ },
+		}
+		batch.startACKTimeout(timeouts, time.Minute)
+		clock.Advance(59 * time.Second)
+		batch.ACK()
+		clock.Advance(time.Hour)
+
+		assert.Len(t, retryer.ch, 0)
+		assert.False(t, batch.timedOut)
+		assert.Equal(t, 1, doneCount)
+	})
+
+	t.Run("batch completed before the timeout starts does not expire", func(t *testing.T) {
+		clock := clockwork.NewFakeClock()
+		timeouts := newACKTimeouts(clock, log)
+		defer timeouts.close()
+		retryer := chanRetryer{ch: make(chan *ttlBatch, 1)}
+		batch := &ttlBatch{
+			events:  make([]publisher.Event, 3),
+			retryer: retryer,
+			done:    func() {},
+		}
+		batch.Retry()
+		<-retryer.ch
+		batch.startACKTimeout(timeouts, time.Minute)
+		clock.Advance(time.Hour)
+
+		assert.Len(t, retryer.ch, 0)
+		assert.False(t, batch.timedOut)
+	})
+
+	t.Run("real clock", func(t *testing.T) {
+		timeouts := newACKTimeouts(clockwork.NewRealClock(), log)
+		defer timeouts.close()
+		retryer := chanRetryer{ch: make(chan *ttlBatch, 1)}
+		acked := &ttlBatch{
+			events:  make([]publisher.Event, 3),
+			retryer: retryer,
+			done:    func() {},
+		}
+		acked.startACKTimeout(timeouts, 10*time.Millisecond)
+		acked.ACK()
+
+		batch := &ttlBatch{
+			events:  make([]publisher.Event, 3),
+			retryer: retryer,
+			done:    func() {},
+		}
+		batch.startACKTimeout(timeouts, 10*time.Millisecond)
+		select {
+		case requeued := <-retryer.ch:
+			assert.Len(t, requeued.events, 3)
+		case <-time.After(5 * time.Second):
+			t.Fatal("batch was not requeued after its ACK timeout")
+		}
+		assert.True(t, batch.timedOut)
+		assert.False(t, acked.timedOut)
+	})
+
+	t.Run("retried batch gets a new timeout", func(t *testing.T) {
+		clock := clockwork.NewFakeClock()
+		timeouts := newACKTimeouts(clock, log)
+		defer timeouts.close()
+		retryer := chanRetryer{ch: make(chan *ttlBatch, 1)}
+		batch := &ttlBatch{
+			events:  make([]publisher.Event, 3),
+			retryer: retryer,
+			done:    func() {},
+		}
+		batch.startACKTimeout(timeouts, time.Hour)
+		batch.Retry()
+		<-retryer.ch
+		batch.resetACKTimeout()
+
+		batch.startACKTimeout(timeouts, time.Minute)
+		clock.Advance(time.Minute)
+		select {
+		case requeued := <-retryer.ch:
+			assert.NotSame(t, batch, requeued)
+		case <-time.After(5 * time.Second):
+			t.Fatal("batch was not requeued after its ACK timeout")
+		}
+	})
+}
@@ -0,0 +1,322 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipetool
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+// shardBufferSize is the number of events buffered per shard before Publish
+// blocks.
+const shardBufferSize = 64
+
+// ShardConfig configures how ConnectSharded distributes the events of a
+// single input across multiple pipeline clients.
+type ShardConfig struct {
+	// Shards is the number of pipeline clients events are distributed across.
+	// Each client runs the processors in its own go-routine.
+	Shards int `config:"shards" validate:"min=1"`
+
+	// Key is the event field used to select the client of an event. Events
+	// with the same key value are always published by the same client, such
+	// that their order is preserved. Events are distributed round-robin if Key
+	// is empty or the event does not contain the field.
+	Key string `config:"key"`
+}
+
+// shardedClient publishes the events of a single input through multiple
+// pipeline clients.
+type shardedClient struct {
+	key    string
+	shards []*shard
+	next   atomic.Uint64
+	acker  *shardACKer
+
+	// mu guards sending to the shards against Close closing their channels.
+	mu        sync.RWMutex
+	isOpen    atomic.Bool
+	closeOnce sync.Once
+}
+
+// shard owns one of the pipeline clients and the go-routine publishing to it.
+type shard struct {
+	client   beat.Client
+	listener *shardListener
+	events   chan *shardSlot
+	done     chan struct{}
+}
+
+// ConnectSharded connects cfg.Shards clients to the pipeline and returns a
+// client distributing the published events between them. This allows the
+// events of one busy input to be processed in parallel.
+//
+// If clientCfg has an EventListener, the returned client reports the events to
+// it in the order they have been passed to Publish, as if a single pipeline
+// client was used. EventIDListener is not supported.
+func ConnectSharded(pipeline beat.PipelineConnector, clientCfg beat.ClientConfig, cfg ShardConfig) (beat.Client, error) {
+	if cfg.Shards < 1 {
+		return nil, errors.New("the number of shards must be at least 1")
+	}
+
+	c := &shardedClient{
+		key:   cfg.Key,
+		acker: &shardACKer{listener: clientCfg.EventListener, open: cfg.Shards},
+	}
+	c.isOpen.Store(true)
+	for i := 0; i < cfg.Shards; i++ {
+		s := &shard{
+			listener: &shardListener{acker: c.acker},
+			events:   make(chan *shardSlot, shardBufferSize),
+			done:     make(chan struct{}),
+		}
+
+		shardCfg := clientCfg
+		shardCfg.EventListener = s.listener
+		client, err := pipeline.ConnectWith(shardCfg)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to connect shard %d: %w", i, err)
+		}
+		s.client = client
+
+		c.shards = append(c.shards, s)
+		go s.run()
+	}
+	return c, nil
+}
+
+// Publish passes the event to its shard. Events published after Close are
+// dropped, like events published via a closed pipeline client.
+func (c *shardedClient) Publish(event beat.Event) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.isOpen.Load() {
+		return
+	}
+
+	s := c.shards[c.shardIndex(event)]
+	s.events <- c.acker.add(event)
+}
+
+func (c *shardedClient) PublishAll(events []beat.Event) {
+	for _, event := range events {
+		c.Publish(event)
+	}
+}
+
+// Close waits for the shards to publish the buffered events and closes all
+// pipeline clients.
+func (c *shardedClient) Close() error {
+	var errs []error
+	c.closeOnce.Do(func() {
+		// Senders blocked on a full shard hold mu, the shards keep publishing
+		// until they are done.
+		c.isOpen.Store(false)
+		c.mu.Lock()
+		for _, s := range c.shards {
+			close(s.events)
+		}
+		c.mu.Unlock()
+
+		for _, s := range c.shards {
+			<-s.done
+			if err := s.client.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func (c *shardedClient) shardIndex(event beat.Event) int {
+	n := uint64(len(c.shards))
+	if c.key != "" {
+		if v, err := event.GetValue(c.key); err == nil {
+			h := fnv.New64a()
+			fmt.Fprint(h, v)
+			return int(h.Sum64() % n)
+		}
+	}
+	return int((c.next.Add(1) - 1) % n)
+}
+
+func (s *shard) run() {
+	defer close(s.done)
+	for slot := range s.events {
+		s.listener.setSlot(slot)
+		s.client.Publish(slot.event)
+		s.listener.setSlot(nil)
+		s.listener.acker.processed(slot)
+	}
+}
+
+// shardSlot tracks an event passed to Publish. Once processed, entries holds
+// the events reported by the shard's pipeline client, more than one if a
+// processor has split the event.
+type shardSlot struct {
+	event     beat.Event
+	entries   []*shardEntry
+	processed bool
+}
+
+type shardEntry struct {
+	event     beat.Event
+	published bool
+	acked     bool
+}
+
+// shardListener is the EventListener of a shard's pipeline client. It maps
+// the events reported by the client to the slot being published.
+type shardListener struct {
+	acker *shardACKer
+
+	mu        sync.Mutex
+	slot      *shardSlot
+	published []*shardEntry // published events waiting for their ACK
+}
+
+func (l *shardListener) setSlot(slot *shardSlot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.slot = slot
+}
+
+func (l *shardListener) AddEvent(event beat.Event, published bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := &shardEntry{event: event, published: published}
+	if l.slot != nil {
+		l.slot.entries = append(l.slot.entries, entry)
+	}
+	if published {
+		l.published = append(l.published, entry)
+	}
+}
+
+func (l *shardListener) ACKEvents(n int) {
+	l.mu.Lock()
+	n = min(n, len(l.published))
+	acked := l.published[:n]
+	l.published = l.published[n:]
+	l.mu.Unlock()
+
+	l.acker.ack(acked)
+}
+
+func (l *shardListener) ClientClosed() {
+	l.acker.clientClosed()
+}
+
+// shardACKer reports the events of all shards to the listener of the sharded
+// client, in the order they have been published.
+type shardACKer struct {
+	listener beat.EventListener
+
+	mu        sync.Mutex
+	pending   []*shardSlot  // slots not yet reported to the listener
+	forwarded []*shardEntry // events reported to the listener, waiting for the ACK
+	open      int           // number of shard clients not closed yet
+}
+
+func (a *shardACKer) add(event beat.Event) *shardSlot {
+	slot := &shardSlot{event: event}
+	if a.listener == nil {
+		return slot
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, slot)
+	return slot
+}
+
+// processed marks the slot as processed and reports the events of all
+// processed slots at the head of the pending list to the listener.
+func (a *shardACKer) processed(slot *shardSlot) {
+	if a.listener == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	slot.processed = true
+	for len(a.pending) > 0 && a.pending[0].processed {
+		for _, entry := range a.pending[0].entries {
+			a.listener.AddEvent(entry.event, entry.published)
+			a.forwarded = append(a.forwarded, entry)
+		}
+		a.pending[0] = nil
+		a.pending = a.pending[1:]
+	}
+	a.ackForwarded()
+}
+
+func (a *shardACKer) ack(entries []*shardEntry) {
+	if a.listener == nil || len(entries) == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.acked = true
+	}
+	a.ackForwarded()
+}
+
+// ackForwarded ACKs the published events at the head of the forwarded list
+// that have been ACKed by their shard. Must be called with the mutex held.
+func (a *shardACKer) ackForwarded() {
+	n, i := 0, 0
+	for ; i < len(a.forwarded); i++ {
+		entry := a.forwarded[i]
+		if entry.published && !entry.acked {
+			break
+		}
+		if entry.published {
+			n++
+		}
+		a.forwarded[i] = nil
+	}
+	a.forwarded = a.forwarded[i:]
+	if n > 0 {
+		a.listener.ACKEvents(n)
+	}
+}
+
+func (a *shardACKer) clientClosed() {
+	if a.listener == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.open--
+	if a.open == 0 {
+		a.listener.ClientClosed()
+	}
+}
@@ -0,0 +1,263 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipetool
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// shardTestPipeline creates clients that record the published events and
+// report them to the listener. Events with the drop field set are reported as
+// dropped.
+type shardTestPipeline struct {
+	mu      sync.Mutex
+	clients []*shardTestClient
+}
+
+type shardTestClient struct {
+	listener beat.EventListener
+
+	mu     sync.Mutex
+	events []beat.Event
+	closed bool
+}
+
+func (p *shardTestPipeline) Connect() (beat.Client, error) {
+	return p.ConnectWith(beat.ClientConfig{})
+}
+
+func (p *shardTestPipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := &shardTestClient{listener: cfg.EventListener}
+	p.clients = append(p.clients, c)
+	return c, nil
+}
+
+func (c *shardTestClient) Publish(event beat.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if drop, _ := event.Fields["drop"].(bool); drop {
+		c.listener.AddEvent(event, false)
+		return
+	}
+	c.listener.AddEvent(event, true)
+	c.events = append(c.events, event)
+}
+
+func (c *shardTestClient) PublishAll(events []beat.Event) {
+	for _, event := range events {
+		c.Publish(event)
+	}
+}
+
+func (c *shardTestClient) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.listener.ClientClosed()
+	return nil
+}
+
+func (c *shardTestClient) published() []beat.Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]beat.Event(nil), c.events...)
+}
+
+func shardTestEvent(id int, key string) beat.Event {
+	return beat.Event{Fields: mapstr.M{"id": id, "key": key}}
+}
+
+func TestConnectSharded(t *testing.T) {
+	t.Run("invalid number of shards", func(t *testing.T) {
+		_, err := ConnectSharded(&shardTestPipeline{}, beat.ClientConfig{}, ShardConfig{})
+		assert.Error(t, err)
+	})
+
+	t.Run("round-robin", func(t *testing.T) {
+		pipeline := &shardTestPipeline{}
+		client, err := ConnectSharded(pipeline, beat.ClientConfig{}, ShardConfig{Shards: 3})
+		require.NoError(t, err)
+
+		for i := 0; i < 9; i++ {
+			client.Publish(shardTestEvent(i, ""))
+		}
+		require.NoError(t, client.Close())
+
+		require.Len(t, pipeline.clients, 3)
+		for _, c := range pipeline.clients {
+			assert.Len(t, c.published(), 3)
+			assert.True(t, c.closed)
+		}
+	})
+
+	t.Run("events with the same key are published in order by the same client", func(t *testing.T) {
+		pipeline := &shardTestPipeline{}
+		client, err := ConnectSharded(pipeline, beat.ClientConfig{}, ShardConfig{Shards: 4, Key: "key"})
+		require.NoError(t, err)
+
+		keys := []string{"a", "b", "c", "d", "e"}
+		for i := 0; i < 100; i++ {
+			client.Publish(shardTestEvent(i, keys[i%len(keys)]))
+		}
+		require.NoError(t, client.Close())
+
+		clientOfKey := map[string]int{}
+		lastOfKey := map[string]int{}
+		total := 0
+		for i, c := range pipeline.clients {
+			for _, event := range c.published() {
+				total++
+				key, _ := event.Fields["key"].(string)
+				id, _ := event.Fields["id"].(int)
+				if shard, ok := clientOfKey[key]; ok {
+					assert.Equal(t, shard, i, "key %v published by multiple clients", key)
+					assert.Greater(t, id, lastOfKey[key], "events of key %v out of order", key)
+				}
+				clientOfKey[key] = i
+				lastOfKey[key] = id
+			}
+		}
+		assert.Equal(t, 100, total)
+	})
+
+	t.Run("ACKs are reported in publish order", func(t *testing.T) {
+		var mu sync.Mutex
+		var acked []int
+		added := 0
+		closed := false
+		listener := &shardTestListener{
+			add: func(event beat.Event, _ bool) {
+				mu.Lock()
+				defer mu.Unlock()
+				added++
+			},
+			ack: func(n int) {
+				mu.Lock()
+				defer mu.Unlock()
+				acked = append(acked, n)
+			},
+			closed: func() {
+				mu.Lock()
+				defer mu.Unlock()
+				closed = true
+			},
+		}
+
+		pipeline := &shardTestPipeline{}
+		client, err := ConnectSharded(pipeline, beat.ClientConfig{EventListener: listener}, ShardConfig{Shards: 2})
+		require.NoError(t, err)
+
+		// Round-robin: events 0, 2 and 4 go to the first client, events 1
+		// and 3 to the second one.
+		for i := 0; i < 5; i++ {
+			client.Publish(shardTestEvent(i, ""))
+		}
+		require.NoError(t, client.Close())
+
+		mu.Lock()
+		assert.Equal(t, 5, added)
+		assert.True(t, closed)
+		mu.Unlock()
+
+		// ACKing the second client only can't be reported, as event 0 is
+		// still pending.
+		pipeline.clients[1].listener.ACKEvents(2)
+		mu.Lock()
+		assert.Empty(t, acked)
+		mu.Unlock()
+
+		// ACKing event 0 reports events 0 and 1.
+		pipeline.clients[0].listener.ACKEvents(1)
+		mu.Lock()
+		assert.Equal(t, []int{2}, acked)
+		mu.Unlock()
+
+		// ACKing events 2 and 4 reports the remaining events.
+		pipeline.clients[0].listener.ACKEvents(2)
+		mu.Lock()
+		assert.Equal(t, []int{2, 3}, acked)
+		mu.Unlock()
+	})
+
+	t.Run("dropped events are reported", func(t *testing.T) {
+		var total int
+		listener := acker.Counting(func(n int) { total += n })
+
+		pipeline := &shardTestPipeline{}
+		client, err := ConnectSharded(pipeline, beat.ClientConfig{EventListener: listener}, ShardConfig{Shards: 2})
+		require.NoError(t, err)
+
+		dropped := shardTestEvent(1, "")
+		dropped.Fields["drop"] = true
+		client.Publish(shardTestEvent(0, ""))
+		client.Publish(dropped)
+		client.Publish(shardTestEvent(2, ""))
+		require.NoError(t, client.Close())
+
+		pipeline.clients[0].listener.ACKEvents(1)
+		assert.Equal(t, 2, total)
+		pipeline.clients[0].listener.ACKEvents(1)
+		assert.Equal(t, 3, total)
+	})
+
+	t.Run("events published after close are dropped", func(t *testing.T) {
+		pipeline := &shardTestPipeline{}
+		client, err := ConnectSharded(pipeline, beat.ClientConfig{}, ShardConfig{Shards: 2})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					client.Publish(shardTestEvent(j, ""))
+				}
+			}()
+		}
+		require.NoError(t, client.Close())
+		wg.Wait()
+
+		client.Publish(shardTestEvent(0, ""))
+		published := 0
+		for _, c := range pipeline.clients {
+			published += len(c.published())
+		}
+		assert.LessOrEqual(t, published, 400)
+	})
+}
+
+type shardTestListener struct {
+	add    func(beat.Event, bool)
+	ack    func(int)
+	closed func()
+}
+
+func (l *shardTestListener) AddEvent(event beat.Event, published bool) { l.add(event, published) }
+func (l *shardTestListener) ACKEvents(n int)                           { l.ack(n) }
+func (l *shardTestListener) ClientClosed()                             { l.closed() }
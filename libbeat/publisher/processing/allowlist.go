@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// fieldAllowlist removes all fields from an event that are not matched by
+// any of its entries. An entry matches a field and all fields below it. An
+// entry ending with '*' matches all fields starting with the given prefix.
+// The event timestamp and metadata are not part of the event fields and are
+// always preserved.
+type fieldAllowlist struct {
+	fields   map[string]struct{}
+	prefixes []string
+}
+
+func newFieldAllowlistProcessor(name string, entries []string) (*processorFn, error) {
+	allowlist := &fieldAllowlist{fields: map[string]struct{}{}}
+	for _, entry := range entries {
+		prefix, isPrefix := strings.CutSuffix(entry, "*")
+		if entry == "" || prefix == "" && isPrefix {
+			return nil, fmt.Errorf("invalid field allowlist entry '%v'", entry)
+		}
+		if strings.Contains(prefix, "*") {
+			return nil, fmt.Errorf("invalid field allowlist entry '%v': only a trailing wildcard is supported", entry)
+		}
+		if isPrefix {
+			allowlist.prefixes = append(allowlist.prefixes, prefix)
+		} else {
+			allowlist.fields[entry] = struct{}{}
+		}
+	}
+
+	return newProcessor(name, func(event *beat.Event) (*beat.Event, error) {
+		event.Fields = allowlist.filter(event.Fields, "")
+		return event, nil
+	}), nil
+}
+
+// filter returns a copy of fields containing only the allowed keys. The path
+// of fields in the event is given by parent. Nested maps are copied as well,
+// as they might be shared between events.
+func (a *fieldAllowlist) filter(fields mapstr.M, parent string) mapstr.M {
+	filtered := mapstr.M{}
+	for key, value := range fields {
+		path := key
+		if parent != "" {
+			path = parent + "." + key
+		}
+
+		if a.allows(path) {
+			filtered[key] = value
+			continue
+		}
+
+		if !a.allowsChildOf(path) {
+			continue
+		}
+		if nested, ok := tryToMapStr(value); ok {
+			if nested = a.filter(nested, path); len(nested) > 0 {
+				filtered[key] = nested
+			}
+		}
+	}
+	return filtered
+}
+
+// allows reports if the field at path, and all fields below it, are allowed.
+func (a *fieldAllowlist) allows(path string) bool {
+	if _, ok := a.fields[path]; ok {
+		return true
+	}
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsChildOf reports if some field below path might be allowed.
+func (a *fieldAllowlist) allowsChildOf(path string) bool {
+	path += "."
+	for field := range a.fields {
+		if strings.HasPrefix(field, path) {
+			return true
+		}
+	}
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(prefix, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func tryToMapStr(v interface{}) (mapstr.M, bool) {
+	switch m := v.(type) {
+	case mapstr.M:
+		return m, true
+	case map[string]interface{}:
+		return mapstr.M(m), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestFieldAllowlist(t *testing.T) {
+	cases := map[string]struct {
+		allowlist []string
+		fields    mapstr.M
+		want      mapstr.M
+	}{
+		"keeps subtree of allowed field": {
+			allowlist: []string{"host"},
+			fields:    mapstr.M{"host": mapstr.M{"name": "a", "ip": "b"}, "message": "c"},
+			want:      mapstr.M{"host": mapstr.M{"name": "a", "ip": "b"}},
+		},
+		"keeps nested field only": {
+			allowlist: []string{"host.name"},
+			fields:    mapstr.M{"host": mapstr.M{"name": "a", "ip": "b"}},
+			want:      mapstr.M{"host": mapstr.M{"name": "a"}},
+		},
+		"prefix wildcard": {
+			allowlist: []string{"http.request.h*"},
+			fields: mapstr.M{
+				"http": map[string]interface{}{
+					"request": mapstr.M{"headers": "a", "host": "b", "body": "c"},
+				},
+			},
+			want: mapstr.M{
+				"http": mapstr.M{
+					"request": mapstr.M{"headers": "a", "host": "b"},
+				},
+			},
+		},
+		"dotted keys": {
+			allowlist: []string{"labels.app"},
+			fields:    mapstr.M{"labels.app": "a", "labels.env": "b"},
+			want:      mapstr.M{"labels.app": "a"},
+		},
+		"removes empty parents": {
+			allowlist: []string{"host.name"},
+			fields:    mapstr.M{"host": mapstr.M{"ip": "b"}},
+			want:      mapstr.M{},
+		},
+		"removes leaf on allowed path": {
+			allowlist: []string{"host.name"},
+			fields:    mapstr.M{"host": "a"},
+			want:      mapstr.M{},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := newFieldAllowlistProcessor("test", test.allowlist)
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: test.fields, Meta: mapstr.M{"index": "test"}})
+			require.NoError(t, err)
+			assert.Equal(t, test.want, event.Fields)
+			assert.Equal(t, mapstr.M{"index": "test"}, event.Meta)
+		})
+	}
+}
+
+func TestFieldAllowlistDoesNotModifySharedFields(t *testing.T) {
+	shared := mapstr.M{"name": "a", "ip": "b"}
+
+	p, err := newFieldAllowlistProcessor("test", []string{"host.name"})
+	require.NoError(t, err)
+
+	_, err = p.Run(&beat.Event{Fields: mapstr.M{"host": shared}})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"name": "a", "ip": "b"}, shared)
+}
+
+func TestFieldAllowlistInvalidEntries(t *testing.T) {
+	for _, entry := range []string{"", "*", "a*b", "a.*.c"} {
+		_, err := newFieldAllowlistProcessor("test", []string{entry})
+		assert.Errorf(t, err, "entry '%v' must be rejected", entry)
+	}
+}
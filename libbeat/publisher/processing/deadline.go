@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+// ErrDeadlineExceeded is returned for events dropped because processing them
+// exceeded the maximum processing time.
+var ErrDeadlineExceeded = errors.New("processing exceeded the maximum processing time")
+
+// deadlineProcessor runs a processor group with a maximum processing time
+// per event. Processors can not be interrupted, so the deadline is checked
+// before each processor is run. Once it is exceeded the remaining processors
+// are skipped and the event is dropped.
+type deadlineProcessor struct {
+	processors *group
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+func newDeadlineProcessor(processors *group, timeout time.Duration, clock clockwork.Clock) *deadlineProcessor {
+	if clock == nil {
+		clock = clockwork.NewRealClock()
+	}
+	return &deadlineProcessor{
+		processors: processors,
+		timeout:    timeout,
+		now:        clock.Now,
+	}
+}
+
+func (p *deadlineProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	event, split, err := p.run(event)
+	if split != nil {
+		return split[0], err
+	}
+	return event, err
+}
+
+// RunSplit runs the processors with the deadline, returning all events
+// created if the event is split.
+func (p *deadlineProcessor) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	event, split, err := p.run(event)
+	if split != nil {
+		return split, err
+	}
+	if event == nil {
+		return nil, err
+	}
+	return []*beat.Event{event}, err
+}
+
+func (p *deadlineProcessor) run(event *beat.Event) (*beat.Event, []*beat.Event, error) {
+	deadline := p.now().Add(p.timeout)
+	return p.processors.run(event, func(proc beat.Processor) error {
+		if p.now().After(deadline) {
+			return fmt.Errorf("%w of %v before running %v", ErrDeadlineExceeded, p.timeout, proc)
+		}
+		return nil
+	})
+}
+
+// Flush returns the events held back by the processors.
+func (p *deadlineProcessor) Flush() []*beat.Event {
+	return p.processors.Flush()
+}
+
+func (p *deadlineProcessor) Close() error {
+	return p.processors.Close()
+}
+
+func (p *deadlineProcessor) String() string {
+	return fmt.Sprintf("deadline=%v{%v}", p.timeout, p.processors)
+}
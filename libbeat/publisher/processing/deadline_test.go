@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestDeadlineProcessor(t *testing.T) {
+	log := logp.NewTestingLogger(t, "")
+
+	clock := clockwork.NewFakeClock()
+	global := newGroup("global", log)
+	global.add(newProcessor("slow", func(event *beat.Event) (*beat.Event, error) {
+		if _, err := event.GetValue("slow"); err == nil {
+			clock.Advance(time.Second)
+		}
+		return event, nil
+	}))
+	global.add(newAnnotateProcessor("last", func(event *beat.Event) {
+		event.Fields["last"] = true
+	}))
+
+	processors := newGroup("processPipeline", log)
+	processors.add(newAnnotateProcessor("fast", func(event *beat.Event) {
+		event.Fields["fast"] = true
+	}))
+	processors.add(newNestedProcessor(global))
+	p := newDeadlineProcessor(processors, 50*time.Millisecond, clock)
+
+	t.Run("event within deadline", func(t *testing.T) {
+		event, err := p.Run(&beat.Event{Fields: mapstr.M{}})
+		require.NoError(t, err)
+		require.NotNil(t, event)
+		assert.Equal(t, mapstr.M{"fast": true, "last": true}, event.Fields)
+	})
+
+	t.Run("event exceeding deadline is dropped", func(t *testing.T) {
+		fields := mapstr.M{"slow": true}
+		events, err := p.RunSplit(&beat.Event{Fields: fields})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDeadlineExceeded))
+		assert.Contains(t, err.Error(), "last")
+		assert.Empty(t, events)
+		assert.NotContains(t, fields, "last", "processors after the deadline must not run")
+	})
+}
+
+func TestCreateWithMaxProcessingTime(t *testing.T) {
+	support, err := MakeDefaultSupport(true, nil)(beat.Info{}, logp.NewTestingLogger(t, ""), config.NewConfig())
+	require.NoError(t, err)
+
+	prog, err := support.Create(beat.ProcessingConfig{MaxProcessingTime: time.Second}, false)
+	require.NoError(t, err)
+	assert.IsType(t, &deadlineProcessor{}, prog)
+
+	event, err := prog.Run(&beat.Event{Fields: mapstr.M{"message": "hello"}})
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, "hello", event.Fields["message"])
+}
@@ -60,6 +60,9 @@ type builder struct {
 	// global pipeline processors
 	processors *group
 
+	// global field allowlist, applied after all processors
+	fieldAllowlist beat.Processor
+
 	alwaysCopy bool
 }
 
@@ -91,8 +94,8 @@ func MakeDefaultObserverSupport(normalize bool) SupportFactory {
 // MakeDefaultSupport creates a new SupportFactory for use with the publisher pipeline.
 // If normalize is set, events will be normalized first before being presented
 // to the actual processors.
-// The Supporter will apply the global `fields`, `fields_under_root`, `tags`
-// and `processor` settings to the event processing pipeline to be generated.
+// The Supporter will apply the global `fields`, `fields_under_root`, `tags`,
+// `field_allowlist` and `processor` settings to the event processing pipeline to be generated.
 // Use WithFields, WithBeatMeta, and other to declare the builtin fields to be added
 // to each event. Builtin fields can be modified using global `processors`, and `fields` only.
 // the fleetDefaultProcessors argument will set the given global-level processors if the beat is currently running under fleet,
@@ -107,6 +110,7 @@ func MakeDefaultSupport(
 			mapstr.EventMetadata `config:",inline"`      // Fields and tags to add to each event.
 			Processors           processors.PluginConfig `config:"processors"`
 			TimeSeries           bool                    `config:"timeseries.enabled"`
+			FieldAllowlist       []string                `config:"field_allowlist"`
 		}{}
 		if err := beatCfg.Unpack(&cfg); err != nil {
 			return nil, err
@@ -129,11 +133,36 @@ func MakeDefaultSupport(
 		if err != nil {
 			return nil, fmt.Errorf("error initializing processors: %w", err)
 		}
+		if err := checkGlobalProcessors(processors.List); err != nil {
+			return nil, err
+		}
 
-		return newBuilder(info, log, processors, cfg.EventMetadata, modifiers, !normalize, cfg.TimeSeries)
+		b, err := newBuilder(info, log, processors, cfg.EventMetadata, modifiers, !normalize, cfg.TimeSeries)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.FieldAllowlist) > 0 {
+			b.fieldAllowlist, err = newFieldAllowlistProcessor("globalFieldAllowlist", cfg.FieldAllowlist)
+			if err != nil {
+				return nil, fmt.Errorf("error initializing field_allowlist: %w", err)
+			}
+		}
+		return b, nil
 	}
 }
 
+// checkGlobalProcessors rejects processors holding back events. The global
+// processors are shared by all clients, so events of different clients would
+// be mixed and a client being closed would flush the events of other clients.
+func checkGlobalProcessors(list []beat.Processor) error {
+	for _, p := range list {
+		if processors.HoldsEvents(p) {
+			return fmt.Errorf("processor %v holds back events and can not be used as a global processor, configure it on the inputs instead", p)
+		}
+	}
+	return nil
+}
+
 // WithFields creates a modifier with the given default builtin fields.
 func WithFields(fields mapstr.M) modifier {
 	return builtinModifier(func(_ beat.Info) mapstr.M {
@@ -286,8 +315,9 @@ func (b *builder) Processors() []string {
 //  7. (P) add builtins
 //  8. (P) pipeline processors list
 //  9. (P) timeseries mangling
-//  10. (P) (if publish/debug enabled) log event
-//  11. (P) (if output disabled) dropEvent
+//  10. (P, C) field allowlists
+//  11. (P) (if publish/debug enabled) log event
+//  12. (P) (if output disabled) dropEvent
 func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor, error) {
 	var (
 		// pipeline processors
@@ -301,20 +331,25 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 	needsCopy := b.alwaysCopy || localProcessors != nil || b.processors != nil
 
 	builtin := b.builtinMeta
-	if cfg.DisableHost {
+	switch {
+	case cfg.MinimalMetadata:
+		builtin = nil
+	case cfg.DisableHost:
 		tmp := builtin.Clone()
 		delete(tmp, "host")
 		builtin = tmp
 	}
 
 	var clientFields mapstr.M
-	for _, mod := range b.modifiers {
-		m := mod.ClientFields(b.info, cfg)
-		if len(m) > 0 {
-			if clientFields == nil {
-				clientFields = mapstr.M{}
+	if !cfg.MinimalMetadata {
+		for _, mod := range b.modifiers {
+			m := mod.ClientFields(b.info, cfg)
+			if len(m) > 0 {
+				if clientFields == nil {
+					clientFields = mapstr.M{}
+				}
+				clientFields.DeepUpdate(m.Clone())
 			}
-			clientFields.DeepUpdate(m.Clone())
 		}
 	}
 	if len(clientFields) > 0 {
@@ -324,11 +359,11 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 	}
 
 	// setup 1: generalize/normalize output (P)
+	normalize := !b.skipNormalize
 	if cfg.EventNormalization != nil {
-		if *cfg.EventNormalization {
-			processors.add(newGeneralizeProcessor(cfg.KeepNull, b.log))
-		}
-	} else if !b.skipNormalize {
+		normalize = *cfg.EventNormalization
+	}
+	if normalize && !cfg.MinimalMetadata {
 		processors.add(newGeneralizeProcessor(cfg.KeepNull, b.log))
 	}
 
@@ -381,7 +416,7 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 	// setup 8: pipeline processors list
 	if b.processors != nil {
 		// Add the global pipeline as a function processor, so clients cannot close it
-		processors.add(newProcessor(b.processors.title, b.processors.Run))
+		processors.add(newNestedProcessor(b.processors))
 	}
 
 	// setup 9: time series metadata
@@ -389,16 +424,31 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 		processors.add(timeseries.NewTimeSeriesProcessor(b.timeseriesFields))
 	}
 
-	// setup 10: debug print final event (P)
+	// setup 10: remove all fields not in the field allowlists (P, C)
+	if b.fieldAllowlist != nil {
+		processors.add(b.fieldAllowlist)
+	}
+	if len(cfg.FieldAllowlist) > 0 {
+		allowlist, err := newFieldAllowlistProcessor("clientFieldAllowlist", cfg.FieldAllowlist)
+		if err != nil {
+			return nil, err
+		}
+		processors.add(allowlist)
+	}
+
+	// setup 11: debug print final event (P)
 	if b.log.IsDebug() || management.UnderAgent() {
 		processors.add(debugPrintProcessor(b.info, b.log))
 	}
 
-	// setup 11: drop all events if outputs are disabled (P)
+	// setup 12: drop all events if outputs are disabled (P)
 	if drop {
 		processors.add(dropDisabledProcessor)
 	}
 
+	if cfg.MaxProcessingTime > 0 {
+		return newDeadlineProcessor(processors, cfg.MaxProcessingTime, cfg.Clock), nil
+	}
 	return processors, nil
 }
 
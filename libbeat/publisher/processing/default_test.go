@@ -38,6 +38,8 @@ import (
 	_ "github.com/elastic/beats/v7/libbeat/processors/add_docker_metadata"
 	_ "github.com/elastic/beats/v7/libbeat/processors/add_host_metadata"
 	_ "github.com/elastic/beats/v7/libbeat/processors/add_kubernetes_metadata"
+	_ "github.com/elastic/beats/v7/libbeat/processors/multiline"
+	_ "github.com/elastic/beats/v7/libbeat/processors/split"
 )
 
 func TestGenerateProcessorList(t *testing.T) {
@@ -244,6 +246,19 @@ func TestProcessorsConfigs(t *testing.T) {
 				"tags":   []string{"tag"},
 			},
 		},
+		"with beat default fields and minimal metadata": {
+			factory: MakeDefaultBeatSupport(true),
+			global:  `{fields: {global: a}, fields_under_root: true, tags: [tag]}`,
+			local: beat.ProcessingConfig{
+				MinimalMetadata: true,
+			},
+			event: `{"value": "abc"}`,
+			want: mapstr.M{
+				"value":  "abc",
+				"global": "a",
+				"tags":   []string{"tag"},
+			},
+		},
 		"with observer default fields": {
 			factory: MakeDefaultObserverSupport(false),
 			global:  `{fields: {global: a, observer.foo: bar}, fields_under_root: true, tags: [tag]}`,
@@ -263,6 +278,42 @@ func TestProcessorsConfigs(t *testing.T) {
 				"tags":   []string{"tag"},
 			},
 		},
+		"user global field allowlist": {
+			global:  "{fields: {global: a}, fields_under_root: true, field_allowlist: [value, 'observer.host*']}",
+			factory: MakeDefaultSupport(true, nil, WithObserverMeta()),
+			local: beat.ProcessingConfig{
+				Meta: mapstr.M{"index": "test"},
+			},
+			event: `{"value": "abc", "other": "def"}`,
+			want: mapstr.M{
+				"value": "abc",
+				"observer": mapstr.M{
+					"hostname": "test.host.name",
+				},
+			},
+			wantMeta: mapstr.M{"index": "test"},
+		},
+		"beat local field allowlist": {
+			local: beat.ProcessingConfig{
+				Fields:         mapstr.M{"local": mapstr.M{"a": 1, "b": 2}},
+				FieldAllowlist: []string{"local.a", "value"},
+			},
+			event: `{"value": "abc", "other": "def"}`,
+			want: mapstr.M{
+				"value": "abc",
+				"local": mapstr.M{"a": 1},
+			},
+		},
+		"user global and beat local field allowlists": {
+			global: "{field_allowlist: [value, other]}",
+			local: beat.ProcessingConfig{
+				FieldAllowlist: []string{"value"},
+			},
+			event: `{"value": "abc", "other": "def"}`,
+			want: mapstr.M{
+				"value": "abc",
+			},
+		},
 	}
 
 	for name, test := range cases {
@@ -311,6 +362,7 @@ func TestEventNormalizationOverride(t *testing.T) {
 	testCases := []struct {
 		skipNormalize          bool
 		normalizeOverride      *bool
+		minimalMetadata        bool
 		hasGeneralizeProcessor bool
 	}{
 		{skipNormalize: false, normalizeOverride: nil, hasGeneralizeProcessor: true},
@@ -319,13 +371,17 @@ func TestEventNormalizationOverride(t *testing.T) {
 		{skipNormalize: true, normalizeOverride: nil, hasGeneralizeProcessor: false},
 		{skipNormalize: true, normalizeOverride: boolPtr(false), hasGeneralizeProcessor: false},
 		{skipNormalize: true, normalizeOverride: boolPtr(true), hasGeneralizeProcessor: true},
+		{skipNormalize: false, normalizeOverride: boolPtr(true), minimalMetadata: true, hasGeneralizeProcessor: false},
 	}
 
 	for _, tc := range testCases {
 		builder, err := newBuilder(beat.Info{}, logp.NewLogger(""), nil, mapstr.EventMetadata{}, nil, tc.skipNormalize, false)
 		require.NoError(t, err)
 
-		processor, err := builder.Create(beat.ProcessingConfig{EventNormalization: tc.normalizeOverride}, false)
+		processor, err := builder.Create(beat.ProcessingConfig{
+			EventNormalization: tc.normalizeOverride,
+			MinimalMetadata:    tc.minimalMetadata,
+		}, false)
 		require.NoError(t, err)
 		group := processor.(*group)
 
@@ -437,6 +493,101 @@ func TestDynamicFields(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestProcessingSplit(t *testing.T) {
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"split": map[string]interface{}{"field": "items", "target_field": "item"}},
+			{"add_tags": map[string]interface{}{"tags": []string{"split"}}},
+		},
+	})
+	factory, err := MakeDefaultSupport(true, nil)(beat.Info{}, logp.NewTestingLogger(t, ""), cfg)
+	require.NoError(t, err)
+	defer factory.Close()
+
+	for name, maxProcessingTime := range map[string]time.Duration{
+		"without deadline": 0,
+		"with deadline":    time.Second,
+	} {
+		t.Run(name, func(t *testing.T) {
+			prog, err := factory.Create(beat.ProcessingConfig{MaxProcessingTime: maxProcessingTime}, false)
+			require.NoError(t, err)
+
+			events, err := processors.RunSplit(prog, &beat.Event{Fields: mapstr.M{
+				"message": "batch",
+				"items":   []interface{}{"a", "b", "c"},
+			}})
+			require.NoError(t, err)
+			require.Len(t, events, 3)
+			for i, item := range []string{"a", "b", "c"} {
+				assert.Equal(t, mapstr.M{
+					"message": "batch",
+					"item":    item,
+					"tags":    []string{"split"},
+				}, events[i].Fields)
+			}
+
+			events, err = processors.RunSplit(prog, &beat.Event{Fields: mapstr.M{"message": "single"}})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, mapstr.M{"message": "single", "tags": []string{"split"}}, events[0].Fields)
+		})
+	}
+}
+
+func TestProcessingFlush(t *testing.T) {
+	factory, err := MakeDefaultSupport(true, nil)(beat.Info{}, logp.NewTestingLogger(t, ""), config.NewConfig())
+	require.NoError(t, err)
+	defer factory.Close()
+
+	for name, maxProcessingTime := range map[string]time.Duration{
+		"without deadline": 0,
+		"with deadline":    time.Second,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var pluginConfig processors.PluginConfig
+			require.NoError(t, config.MustNewConfigFrom([]map[string]interface{}{
+				{"multiline": map[string]interface{}{"pattern": "^\\s", "match": "after"}},
+				{"add_tags": map[string]interface{}{"tags": []string{"joined"}}},
+			}).Unpack(&pluginConfig))
+			local, err := processors.New(pluginConfig)
+			require.NoError(t, err)
+
+			prog, err := factory.Create(beat.ProcessingConfig{
+				Processor:         local,
+				MaxProcessingTime: maxProcessingTime,
+			}, false)
+			require.NoError(t, err)
+
+			for _, line := range []string{"first", "  second"} {
+				events, err := processors.RunSplit(prog, &beat.Event{Fields: mapstr.M{"message": line}})
+				require.NoError(t, err)
+				assert.Empty(t, events)
+			}
+
+			flusher, ok := prog.(processors.Flusher)
+			require.True(t, ok, "processors holding back events must be flushable")
+			events := flusher.Flush()
+			require.Len(t, events, 1)
+			assert.Equal(t, mapstr.M{
+				"message": "first\n  second",
+				"log":     mapstr.M{"flags": []string{"multiline"}},
+				"tags":    []string{"joined"},
+			}, events[0].Fields)
+			assert.Empty(t, flusher.Flush())
+		})
+	}
+}
+
+func TestProcessingGlobalHoldingEvents(t *testing.T) {
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"multiline": map[string]interface{}{"pattern": "^\\s"}},
+		},
+	})
+	_, err := MakeDefaultSupport(true, nil)(beat.Info{}, logp.NewTestingLogger(t, ""), cfg)
+	assert.ErrorContains(t, err, "can not be used as a global processor")
+}
+
 func TestProcessingClose(t *testing.T) {
 	factory, err := MakeDefaultSupport(true, nil)(beat.Info{}, logp.L(), config.NewConfig())
 	require.NoError(t, err)
@@ -488,6 +639,41 @@ func TestProcessingDiagnostics(t *testing.T) {
 	assert.Empty(t, p)
 }
 
+func TestProcessingChain(t *testing.T) {
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"add_tags": map[string]interface{}{"tags": []string{"global"}}},
+		},
+	})
+	factory, err := MakeDefaultSupport(true, nil)(beat.Info{}, logp.L(), cfg)
+	require.NoError(t, err)
+	defer factory.Close()
+
+	client := processors.NewList(nil)
+	client.AddProcessor(&processorWithClose{})
+
+	for name, maxProcessingTime := range map[string]time.Duration{
+		"without deadline": 0,
+		"with deadline":    time.Second,
+	} {
+		t.Run(name, func(t *testing.T) {
+			prog, err := factory.Create(beat.ProcessingConfig{
+				Processor:         client,
+				MaxProcessingTime: maxProcessingTime,
+			}, false)
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{
+				"generalizeEvent",
+				"processorWithClose",
+				"add_tags=global",
+			}, Chain(prog))
+		})
+	}
+
+	assert.Empty(t, Chain(nil))
+}
+
 func fromJSON(in string) mapstr.M {
 	var tmp mapstr.M
 	err := json.Unmarshal([]byte(in), &tmp)
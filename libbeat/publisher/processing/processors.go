@@ -42,6 +42,10 @@ type group struct {
 type processorFn struct {
 	name string
 	fn   func(event *beat.Event) (*beat.Event, error)
+
+	// nested is set if fn runs a group, so the processors of the group
+	// can be reported individually when processing times out.
+	nested *group
 }
 
 func newGeneralizeProcessor(keepNull bool, logger *logp.Logger) *processorFn {
@@ -117,15 +121,109 @@ func (p *group) All() []beat.Processor {
 	return p.list
 }
 
+// Chain returns the descriptions of the processors run by p, in the order
+// they are applied. Groups and processor lists are flattened, such that
+// every entry describes a single processor.
+func Chain(p beat.Processor) []string {
+	chain := []string{}
+	appendChain(&chain, p)
+	return chain
+}
+
+func appendChain(chain *[]string, p beat.Processor) {
+	switch p := p.(type) {
+	case nil:
+		return
+	case *processorFn:
+		if p.nested != nil {
+			appendChain(chain, p.nested)
+			return
+		}
+	case *deadlineProcessor:
+		appendChain(chain, p.processors)
+		return
+	case interface{ All() []beat.Processor }:
+		for _, sub := range p.All() {
+			appendChain(chain, sub)
+		}
+		return
+	}
+	*chain = append(*chain, p.String())
+}
+
 func (p *group) Run(event *beat.Event) (*beat.Event, error) {
+	event, split, err := p.run(event, nil)
+	if split != nil {
+		// Run can return only one event, use RunSplit to get all events
+		// created by a split.
+		return split[0], err
+	}
+	return event, err
+}
+
+// RunSplit executes the processors in the group, returning all events
+// created if a processor in the group splits the event.
+func (p *group) RunSplit(event *beat.Event) ([]*beat.Event, error) {
+	event, split, err := p.run(event, nil)
+	if split != nil {
+		return split, err
+	}
+	if event == nil {
+		return nil, err
+	}
+	return []*beat.Event{event}, err
+}
+
+// Flush collects the events held back by processors in the group, running
+// them through the processors following the processor that held them back.
+func (p *group) Flush() []*beat.Event {
+	if p == nil {
+		return nil
+	}
+	var out []*beat.Event
+	for i, sub := range p.list {
+		if events := flushProcessor(sub); len(events) > 0 {
+			events, _ = p.runEach(i+1, events, nil)
+			out = append(out, events...)
+		}
+	}
+	return out
+}
+
+// flushProcessor flushes a single processor, descending into nested groups.
+func flushProcessor(p beat.Processor) []*beat.Event {
+	switch nested := p.(type) {
+	case *group:
+		return nested.Flush()
+	case *processorFn:
+		return nested.nested.Flush()
+	case processors.Flusher:
+		return nested.Flush()
+	}
+	return nil
+}
+
+// run executes the processors in the group. If track is set it is called
+// with each processor right before it is run, descending into nested groups.
+// If track returns an error the event is dropped without running the
+// remaining processors. If the event is split into multiple events, these
+// are returned in split and event is nil.
+func (p *group) run(event *beat.Event, track func(beat.Processor) error) (*beat.Event, []*beat.Event, error) {
 	if p == nil || len(p.list) == 0 {
-		return event, nil
+		return event, nil, nil
 	}
+	return p.runFrom(0, event, track)
+}
 
-	for _, sub := range p.list {
-		var err error
+func (p *group) runFrom(start int, event *beat.Event, track func(beat.Processor) error) (*beat.Event, []*beat.Event, error) {
+	for i := start; i < len(p.list); i++ {
+		sub := p.list[i]
 
-		event, err = sub.Run(event)
+		var (
+			split []*beat.Event
+			err   error
+		)
+		event, split, err = runTracked(sub, event, track)
 		if err != nil {
 			// XXX: We don't drop the event, but continue filtering here if the most
 			//      recent processor did return an event.
@@ -135,18 +233,89 @@ func (p *group) Run(event *beat.Event) (*beat.Event, error) {
 			p.log.Debugf("Fail to apply processor %s: %s", p, err)
 		}
 
+		if split != nil {
+			events, err := p.runEach(i+1, split, track)
+			event, split = splitResult(events)
+			return event, split, err
+		}
+
 		if event == nil {
-			return nil, err
+			return nil, nil, err
+		}
+	}
+
+	return event, nil, nil
+}
+
+// runEach runs the processors following the processor at index start - 1 on
+// each of the events created by it. The error returned is the first error
+// that caused an event to be dropped.
+func (p *group) runEach(start int, events []*beat.Event, track func(beat.Processor) error) ([]*beat.Event, error) {
+	var firstErr error
+	out := make([]*beat.Event, 0, len(events))
+	for _, event := range events {
+		// errors have already been logged by runFrom
+		event, split, err := p.runFrom(start, event, track)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if split != nil {
+			out = append(out, split...)
+		} else if event != nil {
+			out = append(out, event)
+		}
+	}
+	return out, firstErr
+}
+
+// runTracked runs a single processor. Nested groups are run directly, such
+// that the processors in the group can split the event.
+func runTracked(p beat.Processor, event *beat.Event, track func(beat.Processor) error) (*beat.Event, []*beat.Event, error) {
+	switch nested := p.(type) {
+	case *group:
+		return nested.run(event, track)
+	case *processorFn:
+		if nested.nested != nil {
+			return nested.nested.run(event, track)
 		}
 	}
+	if track != nil {
+		if err := track(p); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if s, ok := p.(processors.Splitter); ok {
+		events, err := s.RunSplit(event)
+		event, split := splitResult(events)
+		return event, split, err
+	}
+
+	event, err := p.Run(event)
+	return event, nil, err
+}
 
-	return event, nil
+// splitResult returns a single event if no more than one event is in events.
+// Otherwise all events are returned in split.
+func splitResult(events []*beat.Event) (event *beat.Event, split []*beat.Event) {
+	switch len(events) {
+	case 0:
+		return nil, nil
+	case 1:
+		return events[0], nil
+	default:
+		return nil, events
+	}
 }
 
 func newProcessor(name string, fn func(*beat.Event) (*beat.Event, error)) *processorFn {
 	return &processorFn{name: name, fn: fn}
 }
 
+func newNestedProcessor(g *group) *processorFn {
+	return &processorFn{name: g.title, fn: g.Run, nested: g}
+}
+
 func newAnnotateProcessor(name string, fn func(*beat.Event)) *processorFn {
 	return newProcessor(name, func(event *beat.Event) (*beat.Event, error) {
 		fn(event)
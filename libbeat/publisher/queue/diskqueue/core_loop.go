@@ -116,6 +116,7 @@ func (dq *diskQueue) handleProducerWriteRequest(request producerWriteRequest) {
 	} else {
 		// The queue is too full. Either add the request to blockedProducers,
 		// or send an immediate reject.
+		dq.observer.QueueFull()
 		if request.shouldBlock {
 			dq.blockedProducers = append(dq.blockedProducers, request)
 		} else {
@@ -977,6 +977,32 @@ func TestObserverAddEvent(t *testing.T) {
 	assertRegistryUint(t, reg, "queue.added.bytes", eventFrame.sizeOnDisk(), "handleProducerWriteRequest should report the added bytes")
 }
 
+func TestObserverQueueFull(t *testing.T) {
+	// Check that write requests rejected or blocked because the queue is
+	// full are reported to the metrics observer.
+	reg := monitoring.NewRegistry()
+	dq := diskQueue{
+		settings: Settings{
+			MaxBufferSize:   1000,
+			MaxSegmentSize:  1000,
+			WriteAheadLimit: 10,
+		},
+		segments: diskQueueSegments{
+			reading: []*queueSegment{{byteCount: 900}},
+		},
+		observer: queue.NewQueueObserver(reg),
+	}
+	for _, shouldBlock := range []bool{false, true} {
+		dq.handleProducerWriteRequest(producerWriteRequest{
+			frame:        &writeFrame{serialized: make([]byte, 200)},
+			shouldBlock:  shouldBlock,
+			responseChan: make(chan bool, 1),
+		})
+	}
+	assertRegistryUint(t, reg, "queue.full.events", 2, "Rejected and blocked write requests should be reported")
+	assertRegistryUint(t, reg, "queue.added.events", 0, "Rejected and blocked write requests shouldn't be added")
+}
+
 func TestObserverDeleteSegment(t *testing.T) {
 	// Check that the results of segment deletions are reported to the
 	// metrics observer.
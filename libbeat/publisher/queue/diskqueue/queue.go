@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
 )
 
@@ -88,6 +89,20 @@ type diskQueue struct {
 	done chan struct{}
 }
 
+func init() {
+	queue.RegisterType(QueueType, factoryForUserConfig)
+}
+
+// factoryForUserConfig parses the user configuration and wraps the
+// resulting Settings in a QueueFactory.
+func factoryForUserConfig(cfg *config.C) (queue.QueueFactory, error) {
+	settings, err := SettingsForUserConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return FactoryForSettings(settings), nil
+}
+
 // FactoryForSettings is a simple wrapper around NewQueue so a concrete
 // Settings object can be wrapped in a queue-agnostic interface for
 // later use by the pipeline.
@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// Reader reads the events pending in a disk queue, oldest first, without
+// modifying the queue. It neither writes the state file nor deletes
+// segments, so it can inspect the queue of a running Beat. Events written
+// to the queue after the Reader has been created are not returned.
+type Reader struct {
+	settings Settings
+
+	// The segments not read yet, starting with the current one.
+	segments []*queueSegment
+
+	// The position to start reading the first segment at, or 0 to start
+	// after its header.
+	startPosition uint64
+
+	// The helper reading frames from the current segment.
+	frames *readerLoop
+
+	// The open handle of segments[0], nil if no segment is open.
+	handle *segmentReader
+
+	// The number of bytes left to read from the current segment.
+	remaining uint64
+}
+
+// NewReader creates a Reader for the events of the queue at the path given
+// in settings that have not been acknowledged yet.
+func NewReader(logger *logp.Logger, settings Settings) (*Reader, error) {
+	position, err := queuePositionFromPath(settings.stateFilePath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warnf("Couldn't load most recent queue position: %v", err)
+	}
+	if position.frameIndex == 0 {
+		// See NewQueue: older state files may lack the frame index, in which
+		// case the segment is read from its beginning.
+		position.byteIndex = 0
+	}
+
+	segments, err := scanExistingSegments(logger, settings.directoryPath())
+	if err != nil {
+		return nil, err
+	}
+	// Segments older than the queue position are acknowledged already.
+	for len(segments) > 0 && segments[0].id < position.segmentID {
+		segments = segments[1:]
+	}
+	if len(segments) > 0 && segments[0].id != position.segmentID {
+		position.byteIndex = 0
+	}
+
+	return &Reader{
+		settings:      settings,
+		segments:      segments,
+		startPosition: position.byteIndex,
+		frames:        &readerLoop{settings: settings, decoder: newEventDecoder()},
+	}, nil
+}
+
+// Next returns the next pending event. It returns io.EOF once all events
+// have been read.
+func (r *Reader) Next() (publisher.Event, error) {
+	for {
+		if r.handle == nil {
+			if len(r.segments) == 0 {
+				return publisher.Event{}, io.EOF
+			}
+			if err := r.openSegment(); err != nil {
+				return publisher.Event{}, err
+			}
+		}
+		if r.remaining == 0 {
+			r.closeSegment()
+			continue
+		}
+
+		frame, err := r.frames.nextFrame(r.handle, r.remaining)
+		if err != nil {
+			return publisher.Event{}, fmt.Errorf("error reading segment %d: %w", r.segments[0].id, err)
+		}
+		r.remaining -= frame.bytesOnDisk
+		event, ok := frame.event.(publisher.Event)
+		if !ok {
+			return publisher.Event{}, fmt.Errorf("unexpected event type %T in segment %d", frame.event, r.segments[0].id)
+		}
+		return event, nil
+	}
+}
+
+// Close releases the file handle of the segment being read.
+func (r *Reader) Close() error {
+	if r.handle == nil {
+		return nil
+	}
+	err := r.handle.Close()
+	r.handle = nil
+	return err
+}
+
+func (r *Reader) openSegment() error {
+	segment := r.segments[0]
+	handle, err := segment.getReader(r.settings)
+	if err != nil {
+		return err
+	}
+
+	start := r.startPosition
+	if start == 0 {
+		start = segment.headerSize()
+	}
+	r.startPosition = 0
+	if _, err := handle.Seek(int64(start), io.SeekStart); err != nil {
+		handle.Close()
+		return fmt.Errorf("couldn't seek in segment %d: %w", segment.id, err)
+	}
+
+	r.frames.decoder.serializationFormat = handle.serializationFormat
+	r.handle = handle
+	r.remaining = 0
+	if segment.byteCount > start {
+		r.remaining = segment.byteCount - start
+	}
+	return nil
+}
+
+func (r *Reader) closeSegment() {
+	r.handle.Close()
+	r.handle = nil
+	r.segments = r.segments[1:]
+}
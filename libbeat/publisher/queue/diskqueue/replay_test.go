@@ -0,0 +1,98 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestReader(t *testing.T) {
+	logger := logp.NewTestingLogger(t, "")
+	settings := DefaultSettings()
+	settings.Path = t.TempDir()
+	// Small segments, such that the events span several segments.
+	settings.MaxSegmentSize = 512
+
+	q, err := NewQueue(logger, nil, settings, nil)
+	require.NoError(t, err)
+	producer := q.Producer(queue.ProducerConfig{})
+	for i := 0; i < 20; i++ {
+		_, ok := producer.Publish(publisher.Event{
+			Content: beat.Event{Fields: mapstr.M{"n": i}},
+		})
+		require.True(t, ok)
+	}
+
+	// Acknowledge the first 5 events. Once the other events have been read
+	// by a consumer, they have been written to disk.
+	for acked := 0; acked < 5; {
+		batch, err := q.Get(5 - acked)
+		require.NoError(t, err)
+		acked += batch.Count()
+		batch.Done()
+	}
+	for read := 5; read < 20; {
+		batch, err := q.Get(20 - read)
+		require.NoError(t, err)
+		read += batch.Count()
+	}
+	defer q.Close()
+
+	before := readQueueFiles(t, settings.Path)
+	require.Greater(t, len(before), 2, "events must span several segments")
+
+	reader, err := NewReader(logger, settings)
+	require.NoError(t, err)
+	for i := 5; i < 20; i++ {
+		event, err := reader.Next()
+		require.NoError(t, err)
+		n, err := event.Content.GetValue("n")
+		require.NoError(t, err)
+		assert.EqualValues(t, i, n)
+	}
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+	require.NoError(t, reader.Close())
+
+	assert.Equal(t, before, readQueueFiles(t, settings.Path), "the reader must not modify the queue")
+}
+
+func readQueueFiles(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		require.NoError(t, err)
+		files[entry.Name()] = content
+	}
+	return files
+}
@@ -121,7 +121,7 @@ type queueSegment struct {
 }
 
 type segmentHeader struct {
-	// The schema version for this segment file. Current schema version is 2.
+	// The schema version for this segment file. Current schema version is 3.
 	version uint32
 
 	// If the segment file has been completely written, this field contains
@@ -141,7 +141,9 @@ type WriteCloseSyncer interface {
 	Sync() error
 }
 
-const currentSegmentVersion = 2
+// Schema version 3 has the same header as version 2, the events written
+// include the event sequence number.
+const currentSegmentVersion = 3
 
 // Segment headers are currently a 4-byte version, a 4-byte frame count and 1-byte options.
 // In contexts where the segment may have been created by an earlier version,
@@ -249,7 +251,7 @@ func (segment *queueSegment) getReader(queueSettings Settings) (*segmentReader,
 		sr.serializationFormat = SerializationJSON
 	}
 
-	// Version 1 is CBOR, Version 2 could be CBOR or ProtoBuf, the
+	// Version 1 is CBOR, Version 2 and 3 could be CBOR or ProtoBuf, the
 	// options control which
 	if header.version > 0 {
 		sr.serializationFormat = SerializationCBOR
@@ -519,7 +521,7 @@ func (w *segmentWriter) WriteHeader(options uint32) error {
 	}
 
 	//write version
-	err = binary.Write(w.dst, binary.LittleEndian, uint32(2))
+	err = binary.Write(w.dst, binary.LittleEndian, uint32(currentSegmentVersion))
 	if err != nil {
 		return fmt.Errorf("could not write version to segment: %w", err)
 	}
@@ -60,6 +60,9 @@ type entry struct {
 	Flags     uint32
 	Meta      mapstr.M
 	Fields    mapstr.M
+
+	// Sequence is only written to segments of schema version >= 3.
+	Sequence uint64
 }
 
 func newEventEncoder(format SerializationFormat) *eventEncoder {
@@ -107,6 +110,7 @@ func (e *eventEncoder) encode_publisher_event(event publisher.Event) ([]byte, er
 		Flags:     uint32(event.Flags),
 		Meta:      event.Content.Meta,
 		Fields:    event.Content.Fields,
+		Sequence:  event.Content.Sequence,
 	})
 	if err != nil {
 		e.reset()
@@ -121,6 +125,42 @@ func (e *eventEncoder) encode_publisher_event(event publisher.Event) ([]byte, er
 	return result, nil
 }
 
+// EventEncoder serializes events to CBOR, the format of the events in the
+// segment files. It allows other queues saving events to disk to share the
+// format. It is not safe for concurrent use.
+type EventEncoder struct {
+	encoder *eventEncoder
+}
+
+// NewEventEncoder creates an EventEncoder.
+func NewEventEncoder() *EventEncoder {
+	return &EventEncoder{encoder: newEventEncoder(SerializationCBOR)}
+}
+
+// Encode returns the serialized event.
+func (e *EventEncoder) Encode(event publisher.Event) ([]byte, error) {
+	return e.encoder.encode_publisher_event(event)
+}
+
+// EventDecoder reads events serialized by EventEncoder. It is not safe for
+// concurrent use.
+type EventDecoder struct {
+	decoder *eventDecoder
+}
+
+// NewEventDecoder creates an EventDecoder.
+func NewEventDecoder() *EventDecoder {
+	d := newEventDecoder()
+	d.serializationFormat = SerializationCBOR
+	return &EventDecoder{decoder: d}
+}
+
+// Decode returns the event serialized in data.
+func (d *EventDecoder) Decode(data []byte) (publisher.Event, error) {
+	copy(d.decoder.Buffer(len(data)), data)
+	return d.decoder.decodeJSONAndCBOR()
+}
+
 func newEventDecoder() *eventDecoder {
 	d := &eventDecoder{}
 	d.reset()
@@ -186,6 +226,7 @@ func (d *eventDecoder) decodeJSONAndCBOR() (publisher.Event, error) {
 			Timestamp: time.Unix(0, to.Timestamp),
 			Fields:    to.Fields,
 			Meta:      to.Meta,
+			Sequence:  to.Sequence,
 		},
 	}, nil
 }
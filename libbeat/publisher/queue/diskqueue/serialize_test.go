@@ -0,0 +1,59 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestEventSerializationRoundTrip(t *testing.T) {
+	event := publisher.Event{
+		Flags: publisher.GuaranteedSend,
+		Content: beat.Event{
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			Meta:      mapstr.M{"pipeline": "test"},
+			Fields:    mapstr.M{"message": "hello"},
+			Sequence:  42,
+		},
+	}
+
+	encoded, err := newEventEncoder(SerializationCBOR).encode(event)
+	require.NoError(t, err)
+
+	decoder := newEventDecoder()
+	decoder.serializationFormat = SerializationCBOR
+	copy(decoder.Buffer(len(encoded)), encoded)
+	decoded, err := decoder.Decode()
+	require.NoError(t, err)
+
+	got, ok := decoded.(publisher.Event)
+	require.True(t, ok)
+	assert.Equal(t, event.Flags, got.Flags)
+	assert.True(t, event.Content.Timestamp.Equal(got.Content.Timestamp))
+	assert.Equal(t, event.Content.Meta, got.Content.Meta)
+	assert.Equal(t, event.Content.Fields, got.Content.Fields)
+	assert.Equal(t, uint64(42), got.Content.Sequence)
+}
@@ -25,9 +25,14 @@ package memqueue
 type ackLoop struct {
 	broker *broker
 
-	// A list of batches given to queue consumers,
+	// A list of batches given to queue consumers, ordered by position,
 	// used to maintain sequencing of event acknowledgements.
 	pendingBatches batchList
+
+	// The position of the oldest event not acknowledged yet. Only the batch
+	// starting at front can be acknowledged, later batches wait for it even
+	// if they are done, which happens if the queue drains LIFO.
+	front int
 }
 
 func newACKLoop(broker *broker) *ackLoop {
@@ -37,7 +42,10 @@ func newACKLoop(broker *broker) *ackLoop {
 func (l *ackLoop) run() {
 	b := l.broker
 	for {
-		nextBatchChan := l.pendingBatches.nextBatchChannel()
+		var nextBatchChan chan batchDoneMsg
+		if head := l.pendingBatches.front(); head != nil && head.start == l.front {
+			nextBatchChan = head.doneChan
+		}
 
 		select {
 		case <-b.ctx.Done():
@@ -46,7 +54,9 @@ func (l *ackLoop) run() {
 
 		case chanList := <-b.consumedChan:
 			// New batches have been generated, add them to the pending list
-			l.pendingBatches.concat(&chanList)
+			for !chanList.empty() {
+				l.pendingBatches.insertSorted(chanList.pop())
+			}
 
 		case <-nextBatchChan:
 			// The oldest outstanding batch has been acknowledged, advance our
@@ -70,6 +80,7 @@ func (l *ackLoop) handleBatchSig() int {
 		// report acks to waiting clients
 		l.processACK(ackedBatches, count)
 	}
+	l.front += count
 
 	for !ackedBatches.empty() {
 		// Release finished batch structs into the shared memory pool
@@ -88,13 +99,19 @@ func (l *ackLoop) collectAcked() batchList {
 
 	acks := l.pendingBatches.pop()
 	ackedBatches.append(acks)
+	next := acks.start + acks.count
 
 	done := false
 	for !l.pendingBatches.empty() && !done {
 		acks := l.pendingBatches.front()
+		if acks.start != next {
+			// The events in between have not been consumed yet.
+			break
+		}
 		select {
 		case <-acks.doneChan:
 			ackedBatches.append(l.pendingBatches.pop())
+			next += acks.count
 
 		default:
 			done = true
@@ -135,8 +152,12 @@ func (l *ackLoop) processACK(lst batchList, N int) {
 			entry.producer = nil
 		}
 	}
-	// Signal runLoop to delete the events
-	l.broker.deleteChan <- N
+	// Signal runLoop to delete the events, unless the queue was stopped
+	// after saving them to a snapshot.
+	select {
+	case l.broker.deleteChan <- N:
+	case <-l.broker.ctx.Done():
+	}
 
 	// The events have been removed; notify their listeners.
 	for _, f := range ackCallbacks {
@@ -19,11 +19,17 @@ package memqueue
 
 import (
 	"context"
+	"errors"
 	"io"
+	"io/fs"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/jonboulle/clockwork"
+
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	c "github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/logp"
 )
 
@@ -42,6 +48,7 @@ const (
 type broker struct {
 	settings Settings
 	logger   *logp.Logger
+	observer queue.Observer
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -62,12 +69,22 @@ type broker struct {
 	// Producers send requests to pushChan to add events to the queue.
 	pushChan chan pushRequest
 
+	// Producers publishing with a context send requests to the unbuffered
+	// syncPushChan, so they can give up as long as the runLoop did not accept
+	// the event.
+	syncPushChan chan pushRequest
+
 	// Consumers send requests to getChan to read events from the queue.
 	getChan chan getRequest
 
 	// Close triggers a queue close by sending to closeChan.
 	closeChan chan struct{}
 
+	// Persist sends to persistChan to save the remaining events to the
+	// snapshot file and stop the queue. The result is sent back on the
+	// given channel.
+	persistChan chan chan error
+
 	///////////////////////////
 	// internal channels
 
@@ -99,12 +116,31 @@ type Settings struct {
 	// The number of events the queue can hold.
 	Events int
 
+	// If positive, the approximate number of bytes the queue can hold.
+	// Producers are blocked once either Events or MaxBytes is reached.
+	MaxBytes int
+
 	// The most events that will ever be returned from one Get request.
 	MaxGetRequest int
 
 	// If positive, the amount of time the queue will wait to fill up
 	// a batch if a Get request asks for more events than we have.
 	FlushTimeout time.Duration
+
+	// If set, Get requests return the newest events first. Events are still
+	// acknowledged to their producers in the order they were published, so
+	// the oldest events hold back the ACKs and the space of newer events.
+	LIFO bool
+
+	// If set, Persist saves the events left in the queue to this file, and
+	// the events saved in it are restored when the queue is created. The
+	// queue keeps a copy of the events before they are encoded for the
+	// output until they are acknowledged.
+	SnapshotPath string
+
+	// Clock measures the flush timeout. Defaults to the real time, tests can
+	// set a fake clock to control the time.
+	Clock clockwork.Clock
 }
 
 type queueEntry struct {
@@ -112,6 +148,10 @@ type queueEntry struct {
 	eventSize int
 	id        queue.EntryID
 
+	// The unencoded event, kept until it is acknowledged if the queue saves
+	// its events to a snapshot.
+	raw queue.Entry
+
 	producer   *ackProducer
 	producerID producerID // The order of this entry within its producer
 }
@@ -122,7 +162,9 @@ type batch struct {
 	// Next batch in the containing batchList
 	next *batch
 
-	// Position and length of the events within the queue buffer
+	// Position and length of the events within the queue. start counts all
+	// events ever inserted, the position in the buffer is start modulo the
+	// buffer size.
 	start, count int
 
 	// batch.Done() sends to doneChan, where ackLoop reads it and handles
@@ -135,6 +177,20 @@ type batchList struct {
 	tail *batch
 }
 
+func init() {
+	queue.RegisterType(QueueType, factoryForUserConfig)
+}
+
+// factoryForUserConfig parses the user configuration and wraps the
+// resulting Settings in a QueueFactory.
+func factoryForUserConfig(cfg *c.C) (queue.QueueFactory, error) {
+	settings, err := SettingsForUserConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return FactoryForSettings(settings), nil
+}
+
 // FactoryForSettings is a simple wrapper around NewQueue so a concrete
 // Settings object can be wrapped in a queue-agnostic interface for
 // later use by the pipeline.
@@ -152,6 +208,13 @@ func FactoryForSettings(settings Settings) queue.QueueFactory {
 // NewQueue creates a new broker based in-memory queue holding up to sz number of events.
 // If waitOnClose is set to true, the broker will block on Close, until all internal
 // workers handling incoming messages and ACKs have been shut down.
+//
+// The queue keeps the events of each producer in FIFO order, even when several
+// producers publish concurrently: Publish and TryPublish only return once the
+// runLoop has inserted the event, and the runLoop handles push requests in the
+// order they arrive. Events of different producers may be interleaved
+// arbitrarily. A producer used concurrently by multiple goroutines (the
+// pipeline client serializes its calls) gives no ordering guarantee.
 func NewQueue(
 	logger *logp.Logger,
 	observer queue.Observer,
@@ -205,22 +268,34 @@ func newQueue(
 		settings.MaxGetRequest = settings.Events
 	}
 
+	if settings.Clock == nil {
+		settings.Clock = clockwork.NewRealClock()
+	}
+
 	if logger == nil {
 		logger = logp.NewLogger("memqueue")
 	}
+	if settings.LIFO {
+		logger.Warn("The memory queue sends the newest events first (drain_order: lifo). " +
+			"Older events are delayed while newer events are available and may never be " +
+			"published if the outputs do not catch up.")
+	}
 
 	b := &broker{
 		settings: settings,
 		logger:   logger,
+		observer: observer,
 
 		buf: make([]queueEntry, settings.Events),
 
 		encoderFactory: encoderFactory,
 
 		// broker API channels
-		pushChan:  make(chan pushRequest, chanSize),
-		getChan:   make(chan getRequest),
-		closeChan: make(chan struct{}),
+		pushChan:     make(chan pushRequest, chanSize),
+		syncPushChan: make(chan pushRequest),
+		getChan:      make(chan getRequest),
+		closeChan:    make(chan struct{}),
+		persistChan:  make(chan chan error),
 
 		// internal runLoop and ackLoop channels
 		consumedChan: make(chan batchList),
@@ -233,10 +308,41 @@ func newQueue(
 	b.ackLoop = newACKLoop(b)
 
 	observer.MaxEvents(settings.Events)
+	observer.MaxBytes(settings.MaxBytes)
+
+	if settings.SnapshotPath != "" {
+		b.restoreSnapshot()
+	}
 
 	return b
 }
 
+// restoreSnapshot inserts the events saved to the snapshot file by a previous
+// queue and removes the file. Incomplete or corrupted files are skipped.
+func (b *broker) restoreSnapshot() {
+	path := b.settings.SnapshotPath
+	events, err := readSnapshot(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		b.logger.Warnf("Skipping the memory queue snapshot %s: %v", path, err)
+	} else {
+		entries := make([]queue.Entry, len(events))
+		for i, event := range events {
+			entries[i] = event
+		}
+		restored := b.runLoop.restore(entries)
+		b.logger.Infof("Restored %d events from the memory queue snapshot %s", restored, path)
+		if dropped := len(events) - restored; dropped > 0 {
+			b.logger.Warnf("Dropped %d events of the memory queue snapshot %s exceeding the queue size", dropped, path)
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		b.logger.Warnf("Failed to remove the memory queue snapshot %s: %v", path, err)
+	}
+}
+
 func (b *broker) Close() error {
 	b.closeChan <- struct{}{}
 	return nil
@@ -246,6 +352,31 @@ func (b *broker) Done() <-chan struct{} {
 	return b.ctx.Done()
 }
 
+// Persist saves the events that were not acknowledged yet to the snapshot
+// file and stops the queue, if the queue has a snapshot path. It must be
+// called after Close. Events acknowledged after Persist are still reported
+// to their producers, they are published again after a restart.
+func (b *broker) Persist() error {
+	if b.settings.SnapshotPath == "" {
+		return nil
+	}
+	resp := make(chan error, 1)
+	select {
+	case b.persistChan <- resp:
+		return <-resp
+	case <-b.ctx.Done():
+		// All events were acknowledged, there is nothing to save.
+		return nil
+	}
+}
+
+// SnapshotEnabled returns whether q is a memory queue saving its events to
+// a snapshot file.
+func SnapshotEnabled(q queue.Queue) bool {
+	b, ok := q.(*broker)
+	return ok && b.settings.SnapshotPath != ""
+}
+
 func (b *broker) QueueType() string {
 	return QueueType
 }
@@ -311,20 +442,6 @@ func (l *batchList) prepend(b *batch) {
 	}
 }
 
-func (l *batchList) concat(other *batchList) {
-	if other.head == nil {
-		return
-	}
-
-	if l.head == nil {
-		*l = *other
-		return
-	}
-
-	l.tail.next = other.head
-	l.tail = other.tail
-}
-
 func (l *batchList) append(b *batch) {
 	if l.head == nil {
 		l.head = b
@@ -334,6 +451,25 @@ func (l *batchList) append(b *batch) {
 	l.tail = b
 }
 
+// insertSorted inserts b keeping the list ordered by position. Batches of a
+// queue draining FIFO are always appended.
+func (l *batchList) insertSorted(b *batch) {
+	if l.head == nil || l.tail.start < b.start {
+		l.append(b)
+		return
+	}
+	if b.start < l.head.start {
+		l.prepend(b)
+		return
+	}
+	prev := l.head
+	for prev.next != nil && prev.next.start < b.start {
+		prev = prev.next
+	}
+	b.next = prev.next
+	prev.next = b
+}
+
 func (l *batchList) empty() bool {
 	return l.head == nil
 }
@@ -342,13 +478,6 @@ func (l *batchList) front() *batch {
 	return l.head
 }
 
-func (l *batchList) nextBatchChannel() chan batchDoneMsg {
-	if l.head == nil {
-		return nil
-	}
-	return l.head.doneChan
-}
-
 func (l *batchList) pop() *batch {
 	ch := l.head
 	if ch != nil {
@@ -401,6 +530,8 @@ func (b *batch) Entry(i int) queue.Entry {
 func (b *batch) FreeEntries() {
 	// This signals that the event data has been copied out of the batch, and is
 	// safe to free from the queue buffer, so set all the event pointers to nil.
+	// The unencoded events kept for the snapshot are freed when they are
+	// acknowledged.
 	for i := 0; i < b.count; i++ {
 		index := (b.start + i) % len(b.queue.buf)
 		b.queue.buf[index].event = nil
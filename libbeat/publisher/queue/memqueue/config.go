@@ -22,29 +22,66 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
 	c "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/paths"
 )
 
 type config struct {
 	Events int `config:"events" validate:"min=32"`
+	// MaxBytes bounds the approximate size of the buffered events. It is
+	// applied in addition to Events, whichever limit is reached first
+	// blocks producers. 0 disables the limit.
+	MaxBytes cfgtype.ByteSize `config:"max_bytes"`
 	// This field is named MaxGetRequest because its logical effect is to give
 	// a maximum on the number of events a Get request can return, but the
 	// user-exposed name is "flush.min_events" for backwards compatibility,
 	// since it used to control buffer size in the internal buffer chain.
 	MaxGetRequest int           `config:"flush.min_events" validate:"min=0"`
 	FlushTimeout  time.Duration `config:"flush.timeout"`
+	// DrainOrder selects whether the oldest (fifo) or newest (lifo) events
+	// are sent to the outputs first.
+	DrainOrder string `config:"drain_order"`
+	// Snapshot saves the events left in the queue on shutdown, to publish
+	// them after a restart.
+	Snapshot snapshotConfig `config:"snapshot"`
 }
 
+type snapshotConfig struct {
+	Enabled bool `config:"enabled"`
+	// Path of the snapshot file, relative paths are resolved against the
+	// data path of the beat.
+	Path string `config:"path"`
+}
+
+const (
+	drainFIFO = "fifo"
+	drainLIFO = "lifo"
+)
+
 var defaultConfig = config{
 	Events:        3200,
 	MaxGetRequest: 1600,
 	FlushTimeout:  10 * time.Second,
+	DrainOrder:    drainFIFO,
+	Snapshot: snapshotConfig{
+		Path: "queue.snapshot",
+	},
 }
 
 func (c *config) Validate() error {
 	if c.MaxGetRequest > c.Events {
 		return errors.New("flush.min_events must be less events")
 	}
+	if c.MaxBytes < 0 {
+		return errors.New("max_bytes must not be negative")
+	}
+	if c.DrainOrder != drainFIFO && c.DrainOrder != drainLIFO {
+		return fmt.Errorf("invalid drain_order '%s', must be one of %s or %s", c.DrainOrder, drainFIFO, drainLIFO)
+	}
+	if c.Snapshot.Enabled && c.Snapshot.Path == "" {
+		return errors.New("snapshot.path must not be empty")
+	}
 	return nil
 }
 
@@ -57,10 +94,17 @@ func SettingsForUserConfig(cfg *c.C) (Settings, error) {
 			return Settings{}, fmt.Errorf("couldn't unpack memory queue config: %w", err)
 		}
 	}
+	var snapshotPath string
+	if config.Snapshot.Enabled {
+		snapshotPath = paths.Resolve(paths.Data, config.Snapshot.Path)
+	}
 	//nolint:gosimple // Actually want this conversion to be explicit since the types aren't definitionally equal.
 	return Settings{
 		Events:        config.Events,
+		MaxBytes:      int(config.MaxBytes),
 		MaxGetRequest: config.MaxGetRequest,
 		FlushTimeout:  config.FlushTimeout,
+		LIFO:          config.DrainOrder == drainLIFO,
+		SnapshotPath:  snapshotPath,
 	}, nil
 }
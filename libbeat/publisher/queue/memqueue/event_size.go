@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memqueue
+
+import (
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// Rough in-memory overhead of the structures holding event data, used to
+// approximate the size of events that were not encoded by the output.
+const (
+	eventOverhead = 256 // publisher.Event and its queue entry
+	valueOverhead = 16  // interface or string header
+)
+
+// approximateSize estimates the in-memory size of an unencoded queue entry.
+// The estimate counts the lengths of all keys, strings and byte slices in
+// the event, plus a fixed overhead per value. It is not exact, but scales
+// with the amount of data held by the event.
+func approximateSize(entry queue.Entry) int {
+	var event *publisher.Event
+	switch e := entry.(type) {
+	case publisher.Event:
+		event = &e
+	case *publisher.Event:
+		event = e
+	default:
+		return eventOverhead
+	}
+	return eventOverhead + valueSize(event.Content.Meta) + valueSize(event.Content.Fields)
+}
+
+func valueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return valueOverhead + len(v)
+	case []byte:
+		return valueOverhead + len(v)
+	case mapstr.M:
+		return mapSize(v)
+	case map[string]interface{}:
+		return mapSize(v)
+	case []interface{}:
+		size := valueOverhead
+		for _, elem := range v {
+			size += valueSize(elem)
+		}
+		return size
+	case []string:
+		size := valueOverhead
+		for _, elem := range v {
+			size += valueOverhead + len(elem)
+		}
+		return size
+	case []mapstr.M:
+		size := valueOverhead
+		for _, elem := range v {
+			size += mapSize(elem)
+		}
+		return size
+	default:
+		// Numbers, booleans, timestamps and other scalars.
+		return valueOverhead
+	}
+}
+
+func mapSize(m map[string]interface{}) int {
+	size := valueOverhead
+	for k, v := range m {
+		size += valueOverhead + len(k) + valueSize(v)
+	}
+	return size
+}
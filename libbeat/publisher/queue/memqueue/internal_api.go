@@ -28,6 +28,9 @@ type pushRequest struct {
 	// early encoding, 0 otherwise.
 	eventSize int
 
+	// The unencoded event, kept if the queue saves its events to a snapshot.
+	raw queue.Entry
+
 	// The producer that generated this event, or nil if this producer does
 	// not require ack callbacks.
 	producer *ackProducer
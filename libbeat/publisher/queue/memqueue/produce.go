@@ -18,6 +18,8 @@
 package memqueue
 
 import (
+	"context"
+
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/elastic-agent-libs/logp"
 )
@@ -36,10 +38,20 @@ type ackProducer struct {
 
 type openState struct {
 	log          *logp.Logger
+	observer     queue.Observer
 	done         chan struct{}
 	queueClosing <-chan struct{}
 	events       chan pushRequest
+	syncEvents   chan pushRequest
 	encoder      queue.Encoder
+
+	// If set, the unencoded event is sent to the queue along with the
+	// encoded one, so it can be saved to the snapshot.
+	keepRaw bool
+
+	// If the queue has a byte limit but events are not encoded, their size
+	// is approximated before they are sent to the queue.
+	estimateSize bool
 }
 
 // producerID stores the order of events within a single producer, so multiple
@@ -58,10 +70,14 @@ type ackHandler func(count int)
 func newProducer(b *broker, cb ackHandler, encoder queue.Encoder) queue.Producer {
 	openState := openState{
 		log:          b.logger,
+		observer:     b.observer,
 		done:         make(chan struct{}),
 		queueClosing: b.closingChan,
 		events:       b.pushChan,
+		syncEvents:   b.syncPushChan,
 		encoder:      encoder,
+		keepRaw:      b.settings.SnapshotPath != "",
+		estimateSize: encoder == nil && b.settings.MaxBytes > 0,
 	}
 
 	if cb != nil {
@@ -83,6 +99,10 @@ func (p *forgetfulProducer) Publish(event queue.Entry) (queue.EntryID, bool) {
 	return p.openState.publish(p.makePushRequest(event))
 }
 
+func (p *forgetfulProducer) PublishWithContext(ctx context.Context, event queue.Entry) (queue.EntryID, bool) {
+	return p.openState.publishWithContext(ctx, p.makePushRequest(event))
+}
+
 func (p *forgetfulProducer) TryPublish(event queue.Entry) (queue.EntryID, bool) {
 	return p.openState.tryPublish(p.makePushRequest(event))
 }
@@ -110,6 +130,14 @@ func (p *ackProducer) Publish(event queue.Entry) (queue.EntryID, bool) {
 	return id, published
 }
 
+func (p *ackProducer) PublishWithContext(ctx context.Context, event queue.Entry) (queue.EntryID, bool) {
+	id, published := p.openState.publishWithContext(ctx, p.makePushRequest(event))
+	if published {
+		p.producedCount++
+	}
+	return id, published
+}
+
 func (p *ackProducer) TryPublish(event queue.Entry) (queue.EntryID, bool) {
 	id, published := p.openState.tryPublish(p.makePushRequest(event))
 	if published {
@@ -126,40 +154,95 @@ func (st *openState) Close() {
 	close(st.done)
 }
 
-func (st *openState) publish(req pushRequest) (queue.EntryID, bool) {
-	// If we were given an encoder callback for incoming events, apply it before
-	// sending the entry to the queue.
+// encode applies the encoder callback for incoming events if we were given
+// one, before the entry is sent to the queue. Otherwise the size of the event
+// is approximated if the queue needs it to enforce its byte limit.
+func (st *openState) encode(req *pushRequest) {
+	if st.keepRaw {
+		req.raw = req.event
+	}
 	if st.encoder != nil {
 		req.event, req.eventSize = st.encoder.EncodeEntry(req.event)
+	} else if st.estimateSize {
+		req.eventSize = approximateSize(req.event)
 	}
+}
+
+func (st *openState) publish(req pushRequest) (queue.EntryID, bool) {
+	st.encode(&req)
 	select {
 	case st.events <- req:
-		// The events channel is buffered, which means we may successfully
-		// write to it even if the queue is shutting down. To avoid blocking
-		// forever during shutdown, we also have to wait on the queue's
-		// shutdown channel.
+	case <-st.done:
+		st.events = nil
+		return 0, false
+	case <-st.queueClosing:
+		st.events = nil
+		return 0, false
+	default:
+		// The queue stopped accepting events because it is full, report the
+		// blocked producer before waiting for space.
+		st.observer.QueueFull()
 		select {
-		case resp := <-req.resp:
-			return resp, true
+		case st.events <- req:
+		case <-st.done:
+			st.events = nil
+			return 0, false
 		case <-st.queueClosing:
 			st.events = nil
 			return 0, false
 		}
-	case <-st.done:
+	}
+	// The events channel is buffered, which means we may successfully
+	// write to it even if the queue is shutting down. To avoid blocking
+	// forever during shutdown, we also have to wait on the queue's
+	// shutdown channel.
+	select {
+	case resp := <-req.resp:
+		return resp, true
+	case <-st.queueClosing:
 		st.events = nil
 		return 0, false
+	}
+}
+
+// publishWithContext is like publish, but gives up if ctx is done before the
+// queue accepts the request. The request is handed to the runLoop through an
+// unbuffered channel, which the runLoop only reads when it has space for the
+// event, so an abandoned request can never be inserted later on.
+func (st *openState) publishWithContext(ctx context.Context, req pushRequest) (queue.EntryID, bool) {
+	st.encode(&req)
+	select {
+	case st.syncEvents <- req:
+	case <-st.done:
+		st.syncEvents = nil
+		return 0, false
 	case <-st.queueClosing:
-		st.events = nil
+		st.syncEvents = nil
 		return 0, false
+	case <-ctx.Done():
+		return 0, false
+	default:
+		// The queue stopped accepting events because it is full, report the
+		// blocked producer before waiting for space.
+		st.observer.QueueFull()
+		select {
+		case st.syncEvents <- req:
+		case <-st.done:
+			st.syncEvents = nil
+			return 0, false
+		case <-st.queueClosing:
+			st.syncEvents = nil
+			return 0, false
+		case <-ctx.Done():
+			return 0, false
+		}
 	}
+	// The runLoop received the request and always responds right away.
+	return <-req.resp, true
 }
 
 func (st *openState) tryPublish(req pushRequest) (queue.EntryID, bool) {
-	// If we were given an encoder callback for incoming events, apply it before
-	// sending the entry to the queue.
-	if st.encoder != nil {
-		req.event, req.eventSize = st.encoder.EncodeEntry(req.event)
-	}
+	st.encode(&req)
 	select {
 	case st.events <- req:
 		// The events channel is buffered, which means we may successfully
@@ -178,6 +261,7 @@ func (st *openState) tryPublish(req pushRequest) (queue.EntryID, bool) {
 		return 0, false
 	default:
 		st.log.Debugf("Dropping event, queue is blocked")
+		st.observer.QueueFull()
 		return 0, false
 	}
 }
@@ -18,6 +18,7 @@
 package memqueue
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"math"
@@ -32,6 +33,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue/queuetest"
+	c "github.com/elastic/elastic-agent-libs/config"
 )
 
 var seed int64
@@ -149,6 +151,42 @@ func TestProducerDoesNotBlockWhenQueueClosed(t *testing.T) {
 		"test not flagged as successful, p.Publish likely blocked indefinitely")
 }
 
+func TestProducerPublishWithContext(t *testing.T) {
+	q := NewQueue(nil, nil,
+		Settings{
+			Events:        2,
+			MaxGetRequest: 2,
+			FlushTimeout:  time.Millisecond,
+		}, 0, nil)
+	defer q.Close()
+
+	p, ok := q.Producer(queue.ProducerConfig{ACK: func(count int) {}}).(queue.ContextProducer)
+	require.True(t, ok, "memqueue producers must implement queue.ContextProducer")
+
+	for i := 0; i < 2; i++ {
+		_, ok := p.PublishWithContext(context.Background(), fmt.Sprintf("Event %d", i))
+		require.True(t, ok, "publishing to a queue with space must succeed")
+	}
+
+	// The queue is full, the publish attempt must give up at the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, ok = p.PublishWithContext(ctx, "Event 2")
+	assert.False(t, ok, "publishing to a full queue must fail once ctx is done")
+
+	// The abandoned event must not have been inserted once space is freed.
+	batch, err := q.Get(2)
+	require.NoError(t, err)
+	batch.Done()
+	_, ok = p.PublishWithContext(context.Background(), "Event 3")
+	require.True(t, ok)
+
+	batch, err = q.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, 1, batch.Count())
+	assert.Equal(t, "Event 3", batch.Entry(0))
+}
+
 func TestProducerClosePreservesEventCount(t *testing.T) {
 	// Check for https://github.com/elastic/beats/issues/37702, a problem
 	// where canceling a producer while it was waiting on a response
@@ -227,6 +265,52 @@ func TestProducerClosePreservesEventCount(t *testing.T) {
 	assert.False(t, activeEvents.Load() < 0, "active event count should never be negative")
 }
 
+func TestProducerOrderingUnderConcurrentPublish(t *testing.T) {
+	const producers = 8
+	const eventsPerProducer = 1000
+
+	q := NewQueue(nil, nil,
+		Settings{
+			Events:        64,
+			MaxGetRequest: 16,
+			FlushTimeout:  time.Millisecond,
+		}, 4, nil)
+	defer q.Close()
+
+	type entry struct {
+		producer int
+		seq      int
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func(producer int) {
+			defer wg.Done()
+			p := q.Producer(queue.ProducerConfig{})
+			for seq := 0; seq < eventsPerProducer; seq++ {
+				_, ok := p.Publish(entry{producer: producer, seq: seq})
+				assert.True(t, ok, "Queue publish must succeed")
+			}
+		}(i)
+	}
+
+	next := make([]int, producers)
+	for received := 0; received < producers*eventsPerProducer; {
+		batch, err := q.Get(16)
+		require.NoError(t, err, "Queue read must succeed")
+		for i := 0; i < batch.Count(); i++ {
+			e, ok := batch.Entry(i).(entry)
+			require.True(t, ok, "unexpected queue entry %v", batch.Entry(i))
+			require.Equal(t, next[e.producer], e.seq, "events of producer %v out of order", e.producer)
+			next[e.producer]++
+		}
+		received += batch.Count()
+		batch.Done()
+	}
+	wg.Wait()
+}
+
 func makeTestQueue(sz, minEvents int, flushTimeout time.Duration) queuetest.QueueFactory {
 	return func(_ *testing.T) queue.Queue {
 		return NewQueue(nil, nil, Settings{
@@ -263,6 +347,96 @@ func TestAdjustInputQueueSize(t *testing.T) {
 	})
 }
 
+func TestSettingsForUserConfigMaxBytes(t *testing.T) {
+	settings, err := SettingsForUserConfig(c.MustNewConfigFrom(map[string]interface{}{
+		"events":    4096,
+		"max_bytes": "64MiB",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 4096, settings.Events)
+	assert.Equal(t, 64*1024*1024, settings.MaxBytes)
+
+	settings, err = SettingsForUserConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, settings.MaxBytes, "byte limit should be disabled by default")
+}
+
+func TestSettingsForUserConfigDrainOrder(t *testing.T) {
+	settings, err := SettingsForUserConfig(nil)
+	require.NoError(t, err)
+	assert.False(t, settings.LIFO, "queue should drain FIFO by default")
+
+	settings, err = SettingsForUserConfig(c.MustNewConfigFrom(map[string]interface{}{
+		"drain_order": "lifo",
+	}))
+	require.NoError(t, err)
+	assert.True(t, settings.LIFO)
+
+	_, err = SettingsForUserConfig(c.MustNewConfigFrom(map[string]interface{}{
+		"drain_order": "random",
+	}))
+	assert.Error(t, err)
+}
+
+func TestLIFODrainOrder(t *testing.T) {
+	q := NewQueue(nil, nil, Settings{
+		Events:        10,
+		MaxGetRequest: 10,
+		LIFO:          true,
+	}, 0, nil)
+	defer q.Close()
+
+	var acked atomic.Int64
+	p := q.Producer(queue.ProducerConfig{ACK: func(count int) { acked.Add(int64(count)) }})
+	publish := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			_, ok := p.Publish(i)
+			require.True(t, ok, "Queue publish must succeed")
+		}
+	}
+	get := func(count int) (queue.Batch, []interface{}) {
+		t.Helper()
+		batch, err := q.Get(count)
+		require.NoError(t, err)
+		var events []interface{}
+		for i := 0; i < batch.Count(); i++ {
+			events = append(events, batch.Entry(i))
+		}
+		return batch, events
+	}
+
+	publish(0, 8)
+	newest, events := get(3)
+	assert.Equal(t, []interface{}{5, 6, 7}, events, "newest events must be returned first")
+
+	// Events published later are returned before the older ones.
+	publish(8, 10)
+	batch, events := get(5)
+	assert.Equal(t, []interface{}{8, 9}, events)
+	batch.Done()
+	newest.Done()
+
+	// The ACKs of newer events wait for the oldest events.
+	oldest, events := get(10)
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4}, events)
+	time.Sleep(10 * time.Millisecond)
+	assert.Zero(t, acked.Load(), "events must be acknowledged in publishing order")
+
+	oldest.Done()
+	require.Eventually(t, func() bool { return acked.Load() == 10 }, time.Second, time.Millisecond)
+
+	// Positions keep working after the buffer wrapped around.
+	publish(10, 14)
+	batch, events = get(2)
+	assert.Equal(t, []interface{}{12, 13}, events)
+	batch.Done()
+	batch, events = get(2)
+	assert.Equal(t, []interface{}{10, 11}, events)
+	batch.Done()
+	require.Eventually(t, func() bool { return acked.Load() == 14 }, time.Second, time.Millisecond)
+}
+
 func TestBatchFreeEntries(t *testing.T) {
 	const queueSize = 10
 	const batchSize = 5
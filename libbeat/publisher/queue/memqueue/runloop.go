@@ -18,6 +18,7 @@
 package memqueue
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
@@ -40,11 +41,25 @@ type runLoop struct {
 	// The total number of events in the queue.
 	eventCount int
 
+	// The total size in bytes of the events in the queue, as reported by the
+	// encoder or approximated if the queue has a byte limit.
+	byteCount int
+
 	// The number of consumed events waiting for acknowledgment. The next Get
 	// request will return events starting at position
-	// (bufPos + consumedCount) % len(buf).
+	// (bufPos + consumedCount) % len(buf), unless the queue drains LIFO.
 	consumedCount int
 
+	// The number of events ever deleted, which is the position of the oldest
+	// event in the queue. Batch positions are based on it, so they keep
+	// increasing when the buffer wraps around.
+	deletedCount int
+
+	// If the queue drains LIFO, unconsumed holds the ranges of events not
+	// sent to consumers yet, ordered by position. Get requests are served
+	// from the end of the last range.
+	unconsumed []eventRange
+
 	// The list of batches that have been consumed and are waiting to be sent
 	// to ackLoop for acknowledgment handling. (This list doesn't contain all
 	// outstanding batches, only the ones not yet forwarded to ackLoop.)
@@ -55,10 +70,10 @@ type runLoop struct {
 	// pendingGetRequest stores the request until we're ready to handle it.
 	pendingGetRequest *getRequest
 
-	// This timer tracks the configured flush timeout when we will respond
+	// getTimeout fires after the configured flush timeout when we will respond
 	// to a pending getRequest even if we can't fill the requested event count.
-	// It is active if and only if pendingGetRequest is non-nil.
-	getTimer *time.Timer
+	// It is set if and only if pendingGetRequest is non-nil.
+	getTimeout <-chan time.Time
 
 	// closing is set when a close request is received. Once closing is true,
 	// the queue will not accept any new events, but will continue responding
@@ -71,21 +86,15 @@ type runLoop struct {
 	nextEntryID queue.EntryID
 }
 
+// eventRange is a range of event positions, from start to end exclusive.
+type eventRange struct {
+	start, end int
+}
+
 func newRunLoop(broker *broker, observer queue.Observer) *runLoop {
-	var timer *time.Timer
-
-	// Create the timer we'll use for get requests, but stop it until a
-	// get request is active.
-	if broker.settings.FlushTimeout > 0 {
-		timer = time.NewTimer(broker.settings.FlushTimeout)
-		if !timer.Stop() {
-			<-timer.C
-		}
-	}
 	return &runLoop{
 		broker:   broker,
 		observer: observer,
-		getTimer: timer,
 	}
 }
 
@@ -98,10 +107,11 @@ func (l *runLoop) run() {
 // Perform one iteration of the queue's main run loop. Broken out into a
 // standalone helper function to allow testing of loop invariants.
 func (l *runLoop) runIteration() {
-	var pushChan chan pushRequest
+	var pushChan, syncPushChan chan pushRequest
 	// Push requests are enabled if the queue isn't full or closing.
-	if l.eventCount < len(l.broker.buf) && !l.closing {
+	if !l.full() && !l.closing {
 		pushChan = l.broker.pushChan
+		syncPushChan = l.broker.syncPushChan
 	}
 
 	var getChan chan getRequest
@@ -118,12 +128,6 @@ func (l *runLoop) runIteration() {
 		consumedChan = l.broker.consumedChan
 	}
 
-	var timeoutChan <-chan time.Time
-	// Enable the timeout channel if a get request is waiting for events
-	if l.pendingGetRequest != nil {
-		timeoutChan = l.getTimer.C
-	}
-
 	select {
 	case <-l.broker.closeChan:
 		l.closing = true
@@ -138,6 +142,9 @@ func (l *runLoop) runIteration() {
 	case req := <-pushChan: // producer pushing new event
 		l.handleInsert(&req)
 
+	case req := <-syncPushChan: // producer pushing new event with a deadline
+		l.handleInsert(&req)
+
 	case req := <-getChan: // consumer asking for next batch
 		l.handleGetRequest(&req)
 
@@ -149,9 +156,14 @@ func (l *runLoop) runIteration() {
 	case count := <-l.broker.deleteChan:
 		l.handleDelete(count)
 
-	case <-timeoutChan:
-		// The get timer has expired, handle the blocked request
-		l.getTimer.Stop()
+	case resp := <-l.broker.persistChan:
+		resp <- l.handlePersist()
+		l.broker.ctxCancel()
+		return
+
+	case <-l.getTimeout:
+		// The get timeout has expired, handle the blocked request
+		l.getTimeout = nil
 		l.handleGetReply(l.pendingGetRequest)
 		l.pendingGetRequest = nil
 	}
@@ -163,13 +175,25 @@ func (l *runLoop) runIteration() {
 	}
 }
 
+// full returns true if the queue reached its event count or byte limit.
+// The byte limit is checked before inserting an event, so the queue may
+// exceed it by up to one event. This way an event larger than the limit is
+// still accepted by an empty queue instead of blocking forever.
+func (l *runLoop) full() bool {
+	if l.eventCount >= len(l.broker.buf) {
+		return true
+	}
+	maxBytes := l.broker.settings.MaxBytes
+	return maxBytes > 0 && l.byteCount >= maxBytes
+}
+
 func (l *runLoop) handleGetRequest(req *getRequest) {
 	if req.entryCount <= 0 || req.entryCount > l.broker.settings.MaxGetRequest {
 		req.entryCount = l.broker.settings.MaxGetRequest
 	}
 	if l.getRequestShouldBlock(req) {
 		l.pendingGetRequest = req
-		l.getTimer.Reset(l.broker.settings.FlushTimeout)
+		l.getTimeout = l.broker.settings.Clock.After(l.broker.settings.FlushTimeout)
 		return
 	}
 	l.handleGetReply(req)
@@ -193,8 +217,13 @@ func (l *runLoop) handleGetReply(req *getRequest) {
 		batchSize = eventsAvailable
 	}
 
-	startIndex := l.bufPos + l.consumedCount
-	batch := newBatch(l.broker, startIndex, batchSize)
+	var start int
+	if l.broker.settings.LIFO && batchSize > 0 {
+		start, batchSize = l.takeNewest(batchSize)
+	} else {
+		start = l.deletedCount + l.consumedCount
+	}
+	batch := newBatch(l.broker, start, batchSize)
 
 	batchBytes := 0
 	for i := 0; i < batchSize; i++ {
@@ -208,16 +237,36 @@ func (l *runLoop) handleGetReply(req *getRequest) {
 	l.observer.ConsumeEvents(batchSize, batchBytes)
 }
 
+// takeNewest removes up to count of the newest unconsumed events from the
+// unconsumed ranges. It returns the position of the first event taken and
+// the number of events taken, which is less than count if the newest range
+// is shorter.
+func (l *runLoop) takeNewest(count int) (start, taken int) {
+	last := &l.unconsumed[len(l.unconsumed)-1]
+	taken = min(count, last.end-last.start)
+	start = last.end - taken
+	last.end = start
+	if last.start == last.end {
+		l.unconsumed = l.unconsumed[:len(l.unconsumed)-1]
+	}
+	return start, taken
+}
+
 func (l *runLoop) handleDelete(count int) {
 	byteCount := 0
 	for i := 0; i < count; i++ {
-		entry := l.broker.buf[(l.bufPos+i)%len(l.broker.buf)]
+		entry := &l.broker.buf[(l.bufPos+i)%len(l.broker.buf)]
 		byteCount += entry.eventSize
+		// Event data is usually cleared in batch.FreeEntries when the events
+		// are vended, the unencoded events kept for a snapshot are not.
+		entry.event = nil
+		entry.raw = nil
 	}
-	// Advance position and counters. Event data was already cleared in
-	// batch.FreeEntries when the events were vended.
+	// Advance position and counters.
 	l.bufPos = (l.bufPos + count) % len(l.broker.buf)
 	l.eventCount -= count
+	l.deletedCount += count
+	l.byteCount -= byteCount
 	l.consumedCount -= count
 	l.observer.RemoveEvents(count, byteCount)
 }
@@ -229,6 +278,7 @@ func (l *runLoop) handleInsert(req *pushRequest) {
 
 	l.nextEntryID++
 	l.eventCount++
+	l.byteCount += req.eventSize
 
 	// See if this gave us enough for a new batch
 	l.maybeUnblockGetRequest()
@@ -241,19 +291,71 @@ func (l *runLoop) maybeUnblockGetRequest() {
 	if getRequest := l.pendingGetRequest; getRequest != nil {
 		if !l.getRequestShouldBlock(getRequest) {
 			l.pendingGetRequest = nil
-			if !l.getTimer.Stop() {
-				<-l.getTimer.C
-			}
+			l.getTimeout = nil
 			l.handleGetReply(getRequest)
 		}
 	}
 }
 
+// handlePersist saves the events in the queue to the snapshot file, oldest
+// first, including the events sent to consumers but not acknowledged yet.
+func (l *runLoop) handlePersist() error {
+	entries := make([]queue.Entry, l.eventCount)
+	for i := range entries {
+		entries[i] = l.broker.buf[(l.bufPos+i)%len(l.broker.buf)].raw
+	}
+	skipped, err := writeSnapshot(l.broker.settings.SnapshotPath, entries)
+	if err != nil {
+		return fmt.Errorf("failed to write memory queue snapshot: %w", err)
+	}
+	if skipped > 0 {
+		l.broker.logger.Warnf("%d events of the memory queue could not be saved to the snapshot", skipped)
+	}
+	l.broker.logger.Infof("Saved %d events of the memory queue to the snapshot %s",
+		len(entries)-skipped, l.broker.settings.SnapshotPath)
+	return nil
+}
+
+// restore inserts entries saved by a previous queue, up to the queue size.
+// They have no producer to acknowledge and are encoded like the events of
+// the producers. It returns the number of entries inserted. Must be called
+// before the queue workers are started.
+func (l *runLoop) restore(entries []queue.Entry) int {
+	var encoder queue.Encoder
+	if l.broker.encoderFactory != nil {
+		encoder = l.broker.encoderFactory()
+	}
+	count := min(len(entries), len(l.broker.buf))
+	for _, entry := range entries[:count] {
+		req := pushRequest{event: entry, raw: entry}
+		if encoder != nil {
+			req.event, req.eventSize = encoder.EncodeEntry(entry)
+		} else if l.broker.settings.MaxBytes > 0 {
+			req.eventSize = approximateSize(entry)
+		}
+		l.insert(&req, l.nextEntryID)
+		l.nextEntryID++
+		l.eventCount++
+		l.byteCount += req.eventSize
+	}
+	return count
+}
+
 func (l *runLoop) insert(req *pushRequest, id queue.EntryID) {
+	if l.broker.settings.LIFO {
+		pos := l.deletedCount + l.eventCount
+		if n := len(l.unconsumed); n > 0 && l.unconsumed[n-1].end == pos {
+			l.unconsumed[n-1].end++
+		} else {
+			l.unconsumed = append(l.unconsumed, eventRange{start: pos, end: pos + 1})
+		}
+	}
+
 	index := (l.bufPos + l.eventCount) % len(l.broker.buf)
 	l.broker.buf[index] = queueEntry{
 		event:      req.event,
 		eventSize:  req.eventSize,
+		raw:        req.raw,
 		id:         id,
 		producer:   req.producer,
 		producerID: req.producerID,
@@ -20,15 +20,19 @@ package memqueue
 import (
 	"context"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
 	"github.com/elastic/elastic-agent-libs/monitoring"
 )
 
@@ -119,6 +123,52 @@ func TestFlushSettingsBlockPartialBatches(t *testing.T) {
 	assert.Equal(t, 101, rl.consumedCount, "Queue should have a consumedCount of 101 after adding an event unblocked the pending get request")
 }
 
+func TestFlushTimeoutUnblocksPartialBatches(t *testing.T) {
+	// A Get request that can't be filled is answered with the available
+	// events once the flush timeout expires.
+	logger := logp.NewTestingLogger(t, "")
+	clock := clockwork.NewFakeClock()
+	broker := newQueue(
+		logger.Named("testing"),
+		nil,
+		Settings{
+			Events:        1000,
+			MaxGetRequest: 500,
+			FlushTimeout:  10 * time.Second,
+			Clock:         clock,
+		},
+		10, nil)
+
+	producer := newProducer(broker, nil, nil)
+	rl := broker.runLoop
+	inserted := make(chan struct{})
+	go func() {
+		defer close(inserted)
+		for i := 0; i < 100; i++ {
+			rl.runIteration()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, ok := producer.Publish("some event")
+		require.True(t, ok, "Queue publish call must succeed")
+	}
+	<-inserted
+
+	go func() {
+		_, _ = broker.Get(101)
+	}()
+	rl.runIteration()
+	require.NotNil(t, rl.pendingGetRequest, "Queue should have a pending get request since the queue doesn't have the requested event count")
+
+	clock.Advance(9 * time.Second)
+	assert.NotNil(t, rl.pendingGetRequest, "Get request must wait for the flush timeout")
+
+	clock.Advance(time.Second)
+	rl.runIteration()
+	assert.Nil(t, rl.pendingGetRequest, "Queue should have no pending get request after the flush timeout expired")
+	assert.Equal(t, 100, rl.consumedCount, "Queue should have a consumedCount of 100 after the flush timeout expired")
+}
+
 func TestClosedEmptyQueueDoesNotBlockGet(t *testing.T) {
 	broker := newQueue(
 		logp.NewLogger("testing"),
@@ -220,6 +270,109 @@ func TestObserverRemoveEvents(t *testing.T) {
 	assertRegistryUint(t, reg, "queue.removed.bytes", deleteCount*123, "Deleting from the queue should report the removed bytes")
 }
 
+func TestByteLimitBlocksProducers(t *testing.T) {
+	// With a byte limit, the queue should stop accepting events once the
+	// approximate size of the buffered events reaches the limit, even if the
+	// event count limit is far away.
+	reg := monitoring.NewRegistry()
+	broker := newQueue(
+		logp.NewTestingLogger(t, "testing"),
+		queue.NewQueueObserver(reg),
+		Settings{
+			Events:        1000,
+			MaxBytes:      2000,
+			MaxGetRequest: 500,
+			FlushTimeout:  10 * time.Second,
+		},
+		10, nil)
+
+	producer := newProducer(broker, nil, nil)
+	rl := broker.runLoop
+	event := publisher.Event{Content: beat.Event{
+		Fields: mapstr.M{"message": strings.Repeat("a", 1000)},
+	}}
+	for i := 0; i < 2; i++ {
+		require.False(t, rl.full(), "Queue should accept events below the byte limit")
+		go rl.runIteration()
+		_, ok := producer.Publish(event)
+		require.True(t, ok, "Queue publish call must succeed")
+	}
+
+	assert.Equal(t, 2, rl.eventCount)
+	assert.Equal(t, 2*approximateSize(event), rl.byteCount)
+	assert.True(t, rl.full(), "Queue should be full once the byte limit is reached")
+	assertRegistryUint(t, reg, "queue.filled.bytes", uint64(rl.byteCount), "Queue should report the buffered bytes")
+	assertRegistryUint(t, reg, "queue.max_bytes", 2000, "Queue should report the byte limit")
+
+	// Removing the events frees their bytes again.
+	go func() {
+		_, _ = broker.Get(2)
+	}()
+	rl.runIteration()
+	broker.deleteChan = make(chan int, 1)
+	broker.deleteChan <- 2
+	rl.runIteration()
+	assert.Equal(t, 0, rl.byteCount)
+	assert.False(t, rl.full(), "Queue should accept events after they were removed")
+}
+
+func TestObserverQueueFull(t *testing.T) {
+	// Confirm that publish attempts rejected or blocked because the queue
+	// isn't accepting events are reported in queue.full.events.
+	reg := monitoring.NewRegistry()
+	broker := newQueue(
+		logp.NewTestingLogger(t, "testing"),
+		queue.NewQueueObserver(reg),
+		Settings{Events: 1000, MaxGetRequest: 500},
+		10, nil)
+
+	// Without a running runLoop, the queue accepts events until its input
+	// channel is full.
+	for i := 0; i < cap(broker.pushChan); i++ {
+		broker.pushChan <- pushRequest{}
+	}
+
+	producer := newProducer(broker, nil, nil)
+	_, ok := producer.TryPublish("event")
+	assert.False(t, ok, "TryPublish on a full queue should fail")
+	assertRegistryUint(t, reg, "queue.full.events", 1, "Rejected publish should be reported")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = producer.Publish("event")
+	}()
+	require.Eventually(t, func() bool {
+		return reg.Get("queue.full.events").(*monitoring.Uint).Get() == 2
+	}, 10*time.Second, time.Millisecond, "Blocked publish should be reported")
+
+	// Without a running runLoop, publishing with a context always blocks.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_, _ = producer.(queue.ContextProducer).PublishWithContext(ctx, "event")
+	}()
+	require.Eventually(t, func() bool {
+		return reg.Get("queue.full.events").(*monitoring.Uint).Get() == 3
+	}, 10*time.Second, time.Millisecond, "Blocked publish with context should be reported")
+	producer.Close()
+	<-done
+}
+
+func TestApproximateSize(t *testing.T) {
+	small := publisher.Event{Content: beat.Event{Fields: mapstr.M{"message": "a"}}}
+	large := publisher.Event{Content: beat.Event{Fields: mapstr.M{
+		"message": strings.Repeat("a", 1000),
+		"tags":    []string{"one", "two"},
+		"nested":  mapstr.M{"count": 42, "data": []byte("bytes")},
+	}}}
+
+	assert.Greater(t, approximateSize(small), eventOverhead)
+	assert.Greater(t, approximateSize(large), approximateSize(small)+1000)
+	assert.Equal(t, approximateSize(large), approximateSize(&large))
+	assert.Equal(t, eventOverhead, approximateSize("not an event"))
+}
+
 func assertRegistryUint(t *testing.T, reg *monitoring.Registry, key string, expected uint64, message string) {
 	t.Helper()
 
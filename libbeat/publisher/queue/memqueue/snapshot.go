@@ -0,0 +1,151 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memqueue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
+)
+
+// A snapshot file starts with a header holding snapshotMagic, the format
+// version and the number of events, followed by each event as its length
+// and its encoding, shared with the disk queue segments. The file ends with the CRC32 checksum of all the
+// preceding bytes, so incomplete or corrupted files are detected.
+var snapshotMagic = [4]byte{'M', 'Q', 'S', 'N'}
+
+// Version 2 adds the event sequence to the encoded events. Version 1 files
+// are still read, their events have no sequence.
+const (
+	snapshotVersion    uint32 = 2
+	snapshotHeaderSize        = len(snapshotMagic) + 4 + 4
+	snapshotFooterSize        = 4
+)
+
+var errSnapshotCorrupt = errors.New("snapshot file is incomplete or corrupted")
+
+// writeSnapshot saves the publisher.Events among entries to the file at
+// path, replacing it atomically. It returns the number of entries that could
+// not be saved.
+func writeSnapshot(path string, entries []queue.Entry) (int, error) {
+	var events [][]byte
+	skipped := 0
+	enc := diskqueue.NewEventEncoder()
+	for _, entry := range entries {
+		event, ok := entry.(publisher.Event)
+		if !ok {
+			skipped++
+			continue
+		}
+		data, err := enc.Encode(event)
+		if err != nil {
+			skipped++
+			continue
+		}
+		events = append(events, data)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(snapshotMagic[:])
+	buf.Write(binary.LittleEndian.AppendUint32(nil, snapshotVersion))
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(events))))
+	for _, data := range events {
+		buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+		buf.Write(data)
+	}
+	buf.Write(binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(buf.Bytes())))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return skipped, err
+	}
+	tmpPath := path + ".tmp"
+	if err := writeFileSync(tmpPath, buf.Bytes()); err != nil {
+		return skipped, err
+	}
+	return skipped, os.Rename(tmpPath, path)
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readSnapshot returns the events saved in the snapshot file at path, in the
+// order they were queued. It returns errSnapshotCorrupt if the file is
+// incomplete or corrupted.
+func readSnapshot(path string) ([]publisher.Event, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < snapshotHeaderSize+snapshotFooterSize ||
+		!bytes.Equal(data[:len(snapshotMagic)], snapshotMagic[:]) {
+		return nil, errSnapshotCorrupt
+	}
+	body := data[:len(data)-snapshotFooterSize]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return nil, errSnapshotCorrupt
+	}
+	if version := binary.LittleEndian.Uint32(body[4:]); version != 1 && version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	count := binary.LittleEndian.Uint32(body[8:])
+	events := make([]publisher.Event, 0, min(int(count), len(body)))
+	dec := diskqueue.NewEventDecoder()
+	pos := snapshotHeaderSize
+	for i := uint32(0); i < count; i++ {
+		if len(body)-pos < 4 {
+			return nil, errSnapshotCorrupt
+		}
+		size := int(binary.LittleEndian.Uint32(body[pos:]))
+		pos += 4
+		if len(body)-pos < size {
+			return nil, errSnapshotCorrupt
+		}
+		event, err := dec.Decode(body[pos : pos+size])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errSnapshotCorrupt, err)
+		}
+		events = append(events, event)
+		pos += size
+	}
+	if pos != len(body) {
+		return nil, errSnapshotCorrupt
+	}
+	return events, nil
+}
@@ -0,0 +1,179 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memqueue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	c "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// markEncoder sets EncodedEvent to the message of the event and clears its
+// content, like the output encoders do.
+type markEncoder struct{}
+
+func (markEncoder) EncodeEntry(entry queue.Entry) (queue.Entry, int) {
+	event := entry.(publisher.Event)
+	event.EncodedEvent, _ = event.Content.Fields.GetValue("message")
+	event.Content = beat.Event{}
+	return event, 1
+}
+
+func markEncoderFactory() queue.Encoder { return markEncoder{} }
+
+func snapshotTestEvent(msg string) publisher.Event {
+	return publisher.Event{
+		Flags: publisher.GuaranteedSend,
+		Content: beat.Event{
+			Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 42, time.UTC),
+			Meta:      mapstr.M{"_id": msg},
+			Fields:    mapstr.M{"message": msg},
+			Sequence:  uint64(len(msg)),
+		},
+	}
+}
+
+func TestSnapshotPersistAndRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.snapshot")
+	settings := Settings{Events: 10, MaxGetRequest: 10, SnapshotPath: path}
+
+	q := NewQueue(nil, nil, settings, 0, markEncoderFactory)
+	p := q.Producer(queue.ProducerConfig{})
+	for _, msg := range []string{"a", "b", "c"} {
+		_, ok := p.Publish(snapshotTestEvent(msg))
+		require.True(t, ok)
+	}
+
+	// Events sent to consumers but not acknowledged are saved as well.
+	batch, err := q.Get(1)
+	require.NoError(t, err)
+	event := batch.Entry(0).(publisher.Event)
+	assert.Equal(t, "a", event.EncodedEvent, "events must be encoded by the producer")
+	batch.FreeEntries()
+
+	require.NoError(t, q.Close())
+	require.NoError(t, q.Persist())
+	<-q.Done()
+
+	// The events are saved before they were encoded.
+	saved, err := readSnapshot(path)
+	require.NoError(t, err)
+	require.Len(t, saved, 3)
+	for i, msg := range []string{"a", "b", "c"} {
+		want := snapshotTestEvent(msg)
+		assert.Equal(t, want.Flags, saved[i].Flags)
+		assert.True(t, want.Content.Timestamp.Equal(saved[i].Content.Timestamp))
+		assert.Equal(t, want.Content.Meta, saved[i].Content.Meta)
+		assert.Equal(t, want.Content.Fields, saved[i].Content.Fields)
+		assert.Equal(t, want.Content.Sequence, saved[i].Content.Sequence)
+	}
+
+	q = NewQueue(nil, nil, settings, 0, markEncoderFactory)
+	defer q.Close()
+	assert.NoFileExists(t, path, "the snapshot must be removed once restored")
+
+	batch, err = q.Get(10)
+	require.NoError(t, err)
+	require.Equal(t, 3, batch.Count())
+	for i, msg := range []string{"a", "b", "c"} {
+		event := batch.Entry(i).(publisher.Event)
+		assert.Equal(t, msg, event.EncodedEvent, "restored events must be encoded")
+		assert.Equal(t, publisher.GuaranteedSend, event.Flags)
+	}
+}
+
+func TestSnapshotRestoreSkipsCorruptFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.snapshot")
+	_, err := writeSnapshot(path, []queue.Entry{snapshotTestEvent("a"), snapshotTestEvent("b")})
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for name, corrupt := range map[string][]byte{
+		"empty":     {},
+		"truncated": data[:len(data)-10],
+		"modified":  append(append([]byte{}, data[:20]...), append([]byte{0xff}, data[21:]...)...),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, corrupt, 0o600))
+
+			_, err := readSnapshot(path)
+			assert.ErrorIs(t, err, errSnapshotCorrupt)
+
+			q := newQueue(nil, nil, Settings{Events: 10, MaxGetRequest: 10, SnapshotPath: path}, 0, nil)
+			assert.Zero(t, q.runLoop.eventCount, "no events must be restored")
+			assert.NoFileExists(t, path)
+		})
+	}
+}
+
+func TestSnapshotRestoreUpToQueueSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.snapshot")
+	entries := []queue.Entry{snapshotTestEvent("a"), snapshotTestEvent("b"), 42, snapshotTestEvent("c")}
+	skipped, err := writeSnapshot(path, entries)
+	require.NoError(t, err)
+	assert.Equal(t, 1, skipped, "entries that are not events can not be saved")
+
+	q := newQueue(nil, nil, Settings{Events: 2, MaxGetRequest: 2, SnapshotPath: path}, 0, nil)
+	assert.Equal(t, 2, q.runLoop.eventCount)
+	assert.Equal(t, "a", q.buf[0].event.(publisher.Event).Content.Fields["message"])
+	assert.Equal(t, "b", q.buf[1].event.(publisher.Event).Content.Fields["message"])
+}
+
+func TestPersistWithoutSnapshotPath(t *testing.T) {
+	q := NewQueue(nil, nil, Settings{Events: 10, MaxGetRequest: 10}, 0, nil)
+	_, ok := q.Producer(queue.ProducerConfig{}).Publish(1)
+	require.True(t, ok)
+
+	require.NoError(t, q.Close())
+	assert.NoError(t, q.Persist())
+	select {
+	case <-q.Done():
+		t.Fatal("the queue must keep running if it has no snapshot path")
+	default:
+	}
+}
+
+func TestSettingsForUserConfigSnapshot(t *testing.T) {
+	settings, err := SettingsForUserConfig(nil)
+	require.NoError(t, err)
+	assert.Empty(t, settings.SnapshotPath, "snapshots should be disabled by default")
+
+	settings, err = SettingsForUserConfig(c.MustNewConfigFrom(map[string]interface{}{
+		"snapshot.enabled": true,
+		"snapshot.path":    "/var/lib/beat/queue.snapshot",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/beat/queue.snapshot", settings.SnapshotPath)
+
+	_, err = SettingsForUserConfig(c.MustNewConfigFrom(map[string]interface{}{
+		"snapshot.enabled": true,
+		"snapshot.path":    "",
+	}))
+	assert.Error(t, err)
+}
@@ -36,6 +36,11 @@ type Observer interface {
 	AddEvent(byteCount int)
 	ConsumeEvents(eventCount int, byteCount int)
 	RemoveEvents(eventCount int, byteCount int)
+
+	// QueueFull is called whenever a producer's event is rejected, or the
+	// producer is blocked, because the queue is full. It may be called
+	// concurrently from producer goroutines.
+	QueueFull()
 }
 
 type queueObserver struct {
@@ -49,6 +54,9 @@ type queueObserver struct {
 	removedEvents  *monitoring.Uint
 	removedBytes   *monitoring.Uint
 
+	// Number of publish attempts rejected or blocked by a full queue.
+	fullEvents *monitoring.Uint
+
 	filledEvents *monitoring.Uint  // gauge
 	filledBytes  *monitoring.Uint  // gauge
 	filledPct    *monitoring.Float // gauge
@@ -89,6 +97,8 @@ func NewQueueObserver(metrics *monitoring.Registry) Observer {
 		removedEvents:  monitoring.NewUint(queueMetrics, "removed.events"),
 		removedBytes:   monitoring.NewUint(queueMetrics, "removed.bytes"),
 
+		fullEvents: monitoring.NewUint(queueMetrics, "full.events"),
+
 		filledEvents: monitoring.NewUint(queueMetrics, "filled.events"), // gauge
 		filledBytes:  monitoring.NewUint(queueMetrics, "filled.bytes"),  // gauge
 		filledPct:    monitoring.NewFloat(queueMetrics, "filled.pct"),   // gauge
@@ -137,6 +147,10 @@ func (ob *queueObserver) RemoveEvents(eventCount int, byteCount int) {
 	ob.updateFilledPct()
 }
 
+func (ob *queueObserver) QueueFull() {
+	ob.fullEvents.Inc()
+}
+
 func (ob *queueObserver) updateFilledPct() {
 	if maxBytes := ob.maxBytes.Get(); maxBytes > 0 {
 		ob.filledPct.Set(float64(ob.filledBytes.Get()) / float64(maxBytes))
@@ -151,3 +165,4 @@ func (nilObserver) Restore(_ int, _ int)       {}
 func (nilObserver) AddEvent(_ int)             {}
 func (nilObserver) ConsumeEvents(_ int, _ int) {}
 func (nilObserver) RemoveEvents(_ int, _ int)  {}
+func (nilObserver) QueueFull()                 {}
@@ -18,6 +18,8 @@
 package queue
 
 import (
+	"context"
+
 	"github.com/elastic/elastic-agent-libs/logp"
 )
 
@@ -55,6 +57,14 @@ type Queue interface {
 	Get(eventCount int) (Batch, error)
 }
 
+// Persister is an optional extension of Queue for queues that can save the
+// events they still hold on shutdown, to publish them after a restart.
+type Persister interface {
+	// Persist saves the events that were not acknowledged yet and stops the
+	// queue. It is called after Close, if the queue did not finish in time.
+	Persist() error
+}
+
 // If encoderFactory is provided, then the resulting queue must use it to
 // encode queued events before returning them.
 type QueueFactory func(
@@ -105,6 +115,17 @@ type Producer interface {
 	Close()
 }
 
+// ContextProducer is an optional extension of Producer for queues that can
+// abort a blocked publish attempt.
+type ContextProducer interface {
+	Producer
+
+	// PublishWithContext adds an entry to the queue like Publish, but gives up
+	// and returns false if ctx is done while waiting for the queue to accept
+	// the entry.
+	PublishWithContext(ctx context.Context, entry Entry) (EntryID, bool)
+}
+
 // Batch of entries (usually publisher.Event) to be returned to Consumers.
 // The `Done` method will tell the queue that the batch has been consumed and
 // its entries can be acknowledged and discarded.
@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package queue
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+var queueReg = map[string]Factory{}
+
+// Factory is used by queue implementations to parse their user configuration
+// into a QueueFactory. Parsing happens when the pipeline or output is
+// configured, so configuration errors are reported before the queue is
+// created.
+type Factory func(cfg *config.C) (QueueFactory, error)
+
+// RegisterType registers a new queue type. Queue implementations register
+// themselves from an init function, so a queue becomes available by importing
+// its package.
+func RegisterType(name string, f Factory) {
+	if queueReg[name] != nil {
+		panic(fmt.Errorf("queue type '%v' exists already", name))
+	}
+	queueReg[name] = f
+}
+
+// FindFactory finds a queue type its factory if available.
+func FindFactory(name string) Factory {
+	return queueReg[name]
+}
+
+// Load parses the user configuration of the named queue type and returns the
+// QueueFactory to create the queue with.
+func Load(name string, cfg *config.C) (QueueFactory, error) {
+	factory := FindFactory(name)
+	if factory == nil {
+		return nil, fmt.Errorf("unrecognized queue type '%v'", name)
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,59 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package queue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func TestRegisterType(t *testing.T) {
+	const name = "test-queue"
+	t.Cleanup(func() { delete(queueReg, name) })
+
+	var unpacked struct {
+		Size int `config:"size"`
+	}
+	RegisterType(name, func(cfg *config.C) (QueueFactory, error) {
+		if err := cfg.Unpack(&unpacked); err != nil {
+			return nil, err
+		}
+		return func(*logp.Logger, Observer, int, EncoderFactory) (Queue, error) {
+			return nil, errors.New("not implemented")
+		}, nil
+	})
+
+	assert.Panics(t, func() {
+		RegisterType(name, func(*config.C) (QueueFactory, error) { return nil, nil })
+	})
+
+	cfg := config.MustNewConfigFrom(map[string]interface{}{"size": 42})
+	factory, err := Load(name, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, factory)
+	assert.Equal(t, 42, unpacked.Size)
+
+	_, err = Load("unknown", cfg)
+	assert.ErrorContains(t, err, "unrecognized queue type 'unknown'")
+}
@@ -75,5 +75,6 @@ func Initialize(settings instance.Settings) *cmd.BeatsRootCmd {
 	rootCmd := cmd.GenRootCmdWithSettings(beater.DefaultCreator(), settings)
 	rootCmd.AddCommand(cmd.GenModulesCmd(Name, "", BuildModulesManager))
 	rootCmd.TestCmd.AddCommand(test.GenTestModulesCmd(Name, "", beater.DefaultTestModulesCreator()))
+	test.AddTestConfigCmd(rootCmd.TestCmd, settings, beater.DefaultCreator(), BuildModulesManager)
 	return rootCmd
 }
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	"github.com/elastic/beats/v7/libbeat/cmd"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/metricbeat/mb"
+	"github.com/elastic/beats/v7/metricbeat/mb/module"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/testing"
+)
+
+// configChecker validates a module configuration without running it.
+type configChecker interface {
+	CheckConfig(*conf.C) error
+}
+
+// AddTestConfigCmd replaces the generic config test command of testCmd with
+// one also checking the module config files enabled in the modules directory.
+func AddTestConfigCmd(
+	testCmd *cobra.Command,
+	settings instance.Settings,
+	create beat.Creator,
+	modulesManager func(*beat.Beat) (cmd.ModulesManager, error),
+) {
+	for _, c := range testCmd.Commands() {
+		if c.Name() == "config" {
+			testCmd.RemoveCommand(c)
+		}
+	}
+	testCmd.AddCommand(GenTestConfigCmd(settings, create, modulesManager))
+}
+
+// GenTestConfigCmd returns a command checking the main configuration and each
+// module config file enabled in the modules directory. Modules and metricsets
+// are created, but not started.
+func GenTestConfigCmd(
+	settings instance.Settings,
+	create beat.Creator,
+	modulesManager func(*beat.Beat) (cmd.ModulesManager, error),
+) *cobra.Command {
+	return &cobra.Command{
+		Use:   "config",
+		Short: "Test configuration settings, including the modules directory",
+		Run: func(cmd *cobra.Command, args []string) {
+			b, err := instance.NewInitializedBeat(settings)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing beat: %s\n", err)
+				os.Exit(1)
+			}
+
+			// A publisher is needed for modules that add their own pipelines
+			b.Beat.Publisher = newPublisher()
+			if _, err := create(&b.Beat, b.Beat.BeatConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing %s: %s\n", b.Beat.Info.Beat, err)
+				os.Exit(1)
+			}
+			fmt.Println("Config OK") //nolint:forbidigo // required to give feedback to user
+
+			if _, err := b.Beat.BeatConfig.String("config.modules.path", -1); err != nil {
+				// No modules directory to check.
+				return
+			}
+			manager, err := modulesManager(&b.Beat)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading the modules directory: %s\n", err)
+				os.Exit(1)
+			}
+
+			factory := module.NewFactory(b.Beat.Info, mb.Registry)
+			if !checkModuleConfigs(testing.NewConsoleDriver(os.Stdout), manager, factory) {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// checkModuleConfigs checks every module in the enabled config files of
+// manager, reporting the result of each file to driver. It returns false if
+// any file is invalid.
+func checkModuleConfigs(driver testing.Driver, manager cmd.ModulesManager, checker configChecker) bool {
+	ok := true
+	for _, file := range manager.ListEnabled() {
+		driver.Run(file.Path, func(driver testing.Driver) {
+			configs, err := cfgfile.LoadList(file.Path)
+			if err != nil {
+				ok = false
+				driver.Error("load", err)
+				return
+			}
+
+			for i, c := range configs {
+				name, _ := c.String("module", -1)
+				field := fmt.Sprintf("%s #%d", name, i+1)
+				if !c.Enabled() {
+					driver.Info(field, "disabled")
+					continue
+				}
+				err := checker.CheckConfig(c)
+				if err != nil {
+					ok = false
+				}
+				driver.Error(field, err)
+			}
+		})
+	}
+	return ok
+}
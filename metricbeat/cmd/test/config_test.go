@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	conf "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	mbtesting "github.com/elastic/elastic-agent-libs/testing"
+)
+
+// moduleChecker accepts all modules except the ones named invalid.
+type moduleChecker struct{}
+
+func (moduleChecker) CheckConfig(c *conf.C) error {
+	if name, _ := c.String("module", -1); name == "invalid" {
+		return errors.New("unknown module")
+	}
+	return nil
+}
+
+func TestCheckModuleConfigs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"system.yml":            "- module: system\n- module: system\n  enabled: false\n",
+		"broken.yml":            "- module: invalid\n",
+		"syntax.yml":            "- module: [\n",
+		"disabled.yml.disabled": "- module: invalid\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	manager, err := cfgfile.NewGlobManager(filepath.Join(dir, "*.yml"), ".yml", ".disabled", logp.NewTestingLogger(t, ""))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	ok := checkModuleConfigs(mbtesting.NewConsoleDriver(&out), manager, moduleChecker{})
+	assert.False(t, ok, "invalid files must fail the check")
+
+	report := out.String()
+	assert.Contains(t, report, filepath.Join(dir, "system.yml"))
+	assert.Contains(t, report, "system #1... OK")
+	assert.Contains(t, report, "system #2: disabled")
+	assert.Contains(t, report, "invalid #1... ERROR unknown module")
+	assert.Contains(t, report, "load... ERROR")
+	assert.NotContains(t, report, "disabled.yml", "disabled files must not be checked")
+}
+
+func TestCheckModuleConfigsValid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "system.yml"), []byte("- module: system\n"), 0o600))
+
+	manager, err := cfgfile.NewGlobManager(filepath.Join(dir, "*.yml"), ".yml", ".disabled", logp.NewTestingLogger(t, ""))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.True(t, checkModuleConfigs(mbtesting.NewConsoleDriver(&out), manager, moduleChecker{}))
+}
@@ -20,7 +20,9 @@ package mb
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/joeshaw/multierror"
@@ -89,6 +91,17 @@ func newBaseModuleFromConfig(rawConfig *conf.C) (BaseModule, error) {
 
 	baseModule.name = strings.ToLower(baseModule.config.Module)
 
+	if periods := baseModule.config.MetricSetPeriods; len(periods) > 0 {
+		// Metricset names are case-insensitive, as in the metricsets list.
+		baseModule.config.MetricSetPeriods = make(map[string]time.Duration, len(periods))
+		for name, period := range periods {
+			if period <= 0 {
+				return baseModule, fmt.Errorf("invalid period for metricset '%s' of module '%s': period must be positive", name, baseModule.name)
+			}
+			baseModule.config.MetricSetPeriods[strings.ToLower(name)] = period
+		}
+	}
+
 	err = mustNotContainDuplicates(baseModule.config.Hosts)
 	if err != nil {
 		return baseModule, fmt.Errorf("invalid hosts for module '%s': %w", baseModule.name, err)
@@ -172,6 +185,12 @@ func newBaseMetricSets(r *Register, m Module) ([]BaseMetricSet, error) {
 		}
 	}
 
+	for name := range m.Config().MetricSetPeriods {
+		if !slices.ContainsFunc(metricSetNames, func(n string) bool { return strings.EqualFold(n, name) }) {
+			return nil, fmt.Errorf("period configured for metricset '%s', which is not enabled in module '%s'", name, m.Name())
+		}
+	}
+
 	var metricsets []BaseMetricSet
 	for _, name := range metricSetNames {
 		name = strings.ToLower(name)
@@ -168,6 +168,20 @@ type Closer interface {
 	Close() error
 }
 
+// Aggregator is an optional interface that a periodically fetched MetricSet
+// can implement to summarize the events of each fetch in one event per group,
+// for example per topic. Summary events are only reported when aggregation is
+// enabled in the module configuration.
+type Aggregator interface {
+	// AggregationKey returns the group of the event, and false if the event
+	// is not part of any group.
+	AggregationKey(event Event) (key string, ok bool)
+
+	// Aggregate returns the summary event of the group with the given key,
+	// built from the events of the group reported in a fetch.
+	Aggregate(key string, events []Event) Event
+}
+
 // Reporter is used by a MetricSet to report events, errors, or errors with
 // metadata. The methods return false if and only if publishing failed because
 // the MetricSet is being closed.
@@ -388,15 +402,28 @@ type ModuleConfig struct {
 	Raw         bool          `config:"raw"`
 	Query       QueryParams   `config:"query"`
 	ServiceName string        `config:"service.name"`
+
+	// MetricSetPeriods overrides Period for the metricsets listed, by name.
+	MetricSetPeriods map[string]time.Duration `config:"metricset_periods"`
 }
 
 func (c ModuleConfig) String() string {
 	return fmt.Sprintf(`{Module:"%v", MetricSets:%v, Enabled:%v, `+
-		`ID:"%s", Hosts:[%v hosts], Period:"%v", Timeout:"%v", Raw:%v, Query:%v}`,
-		c.Module, c.MetricSets, c.Enabled, c.ID, len(c.Hosts), c.Period, c.Timeout,
+		`ID:"%s", Hosts:[%v hosts], Period:"%v", MetricSetPeriods:%v, Timeout:"%v", Raw:%v, Query:%v}`,
+		c.Module, c.MetricSets, c.Enabled, c.ID, len(c.Hosts), c.Period, c.MetricSetPeriods, c.Timeout,
 		c.Raw, c.Query)
 }
 
+// MetricSetPeriod returns the period at which the named metricset is fetched.
+// This is the period configured in MetricSetPeriods for the metricset, if
+// any, and the module Period otherwise.
+func (c ModuleConfig) MetricSetPeriod(name string) time.Duration {
+	if period, ok := c.MetricSetPeriods[name]; ok {
+		return period
+	}
+	return c.Period
+}
+
 func (c ModuleConfig) GoString() string { return c.String() }
 
 // QueryParams is a convenient map[string]interface{} wrapper to implement the String interface which returns the
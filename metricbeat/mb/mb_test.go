@@ -21,6 +21,7 @@ package mb
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -194,6 +195,41 @@ func TestNewModulesWithDefaultMetricSet(t *testing.T) {
 	}
 }
 
+// TestNewModulesMetricSetPeriods verifies that the period of a metricset can
+// be overridden and that overrides for metricsets not enabled are rejected.
+func TestNewModulesMetricSetPeriods(t *testing.T) {
+	r := newTestRegistry(t)
+
+	c := newConfig(t, map[string]interface{}{
+		"module":            moduleName,
+		"metricsets":        []string{metricSetName},
+		"period":            "10s",
+		"metricset_periods": map[string]interface{}{strings.ToUpper(metricSetName): "5m"},
+	})
+
+	m, metricSets, err := NewModule(c, r)
+	require.NoError(t, err)
+	require.Len(t, metricSets, 1)
+	assert.Equal(t, 5*time.Minute, m.Config().MetricSetPeriod(metricSetName))
+	assert.Equal(t, 10*time.Second, m.Config().MetricSetPeriod("other"))
+
+	c = newConfig(t, map[string]interface{}{
+		"module":            moduleName,
+		"metricsets":        []string{metricSetName},
+		"metricset_periods": map[string]interface{}{"other": "5m"},
+	})
+	_, _, err = NewModule(c, r)
+	assert.ErrorContains(t, err, "period configured for metricset 'other', which is not enabled")
+
+	c = newConfig(t, map[string]interface{}{
+		"module":            moduleName,
+		"metricsets":        []string{metricSetName},
+		"metricset_periods": map[string]interface{}{metricSetName: "0s"},
+	})
+	_, _, err = NewModule(c, r)
+	assert.ErrorContains(t, err, "period must be positive")
+}
+
 func TestNewModulesHostParser(t *testing.T) {
 	const (
 		name = "HostParser"
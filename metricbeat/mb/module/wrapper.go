@@ -45,6 +45,9 @@ const (
 
 	// Failure threshold config key
 	failureThresholdKey = "failure_threshold"
+
+	// Aggregation config key
+	aggregationKey = "aggregation"
 )
 
 var (
@@ -76,6 +79,18 @@ type metricSetWrapper struct {
 
 	periodic         bool // Set to true if this metricset is a periodic fetcher
 	failureThreshold uint // threshold of consecutive errors needed to set the stream as degraded
+
+	aggregation aggregationConfig // summary events settings, used if the metricset is an mb.Aggregator
+}
+
+// aggregationConfig configures the summary events of metricsets implementing
+// mb.Aggregator.
+type aggregationConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// KeepEvents keeps reporting the aggregated events along with the
+	// summary events. Otherwise only the summary events are reported.
+	KeepEvents bool `config:"keep_events"`
 }
 
 // stats bundles common metricset stats.
@@ -128,12 +143,26 @@ func createWrapper(module mb.Module, metricSets []mb.MetricSet, options ...Optio
 		failureThreshold = *streamHealthSettings.FailureThreshold
 	}
 
+	aggregationSettings := struct {
+		Aggregation aggregationConfig `config:"aggregation"`
+	}{
+		Aggregation: aggregationConfig{KeepEvents: true},
+	}
+	if err := module.UnpackConfig(&aggregationSettings); err != nil {
+		return nil, fmt.Errorf("unpacking %s config: %w", aggregationKey, err)
+	}
+
 	for i, metricSet := range metricSets {
 		wrapper.metricSets[i] = &metricSetWrapper{
 			MetricSet:        metricSet,
 			module:           wrapper,
 			stats:            getMetricSetStats(wrapper.Name(), metricSet.Name()),
 			failureThreshold: failureThreshold,
+			aggregation:      aggregationSettings.Aggregation,
+		}
+		if _, ok := metricSet.(mb.Aggregator); aggregationSettings.Aggregation.Enabled && !ok {
+			logp.Warn("Aggregation is enabled, but metricset %s/%s does not support it",
+				module.Name(), metricSet.Name())
 		}
 	}
 	return wrapper, nil
@@ -251,7 +280,7 @@ func (msw *metricSetWrapper) startPeriodicFetching(ctx context.Context, reporter
 	msw.fetch(ctx, reporter)
 
 	// Start timer for future fetches.
-	t := time.NewTicker(msw.Module().Config().Period)
+	t := time.NewTicker(msw.Module().Config().MetricSetPeriod(msw.Name()))
 	defer t.Stop()
 	for {
 		select {
@@ -267,6 +296,12 @@ func (msw *metricSetWrapper) startPeriodicFetching(ctx context.Context, reporter
 // the result using the publisher client. This method will recover from panics
 // and log a stack track if one occurs.
 func (msw *metricSetWrapper) fetch(ctx context.Context, reporter reporter) {
+	if aggregator, ok := msw.MetricSet.(mb.Aggregator); ok && msw.aggregation.Enabled {
+		aggregating := newAggregatingReporter(msw, reporter, aggregator)
+		defer aggregating.flush()
+		reporter = aggregating
+	}
+
 	switch fetcher := msw.MetricSet.(type) {
 	case mb.ReportingMetricSet: //nolint:staticcheck // ReportingMetricSet is deprecated but not removed
 		reporter.StartFetchTimer()
@@ -359,6 +394,68 @@ func (r *eventReporter) V1() mb.PushReporter { //nolint:staticcheck // PushRepor
 }
 func (r *eventReporter) V2() mb.PushReporterV2 { return reporterV2{r} }
 
+// aggregatingReporter wraps the reporter of a fetch to collect the events of
+// an mb.Aggregator by group. The summary event of each group is reported by
+// flush, once the fetch is done.
+type aggregatingReporter struct {
+	reporter
+	msw        *metricSetWrapper
+	aggregator mb.Aggregator
+	groups     map[string][]mb.Event
+	keys       []string // Group keys, in the order they were first seen.
+}
+
+func newAggregatingReporter(msw *metricSetWrapper, r reporter, aggregator mb.Aggregator) *aggregatingReporter {
+	return &aggregatingReporter{
+		reporter:   r,
+		msw:        msw,
+		aggregator: aggregator,
+		groups:     map[string][]mb.Event{},
+	}
+}
+
+func (r *aggregatingReporter) V1() mb.PushReporter { //nolint:staticcheck // PushReporter is deprecated but not removed
+	return reporterV1{v2: r.V2(), module: r.msw.module.Name()}
+}
+func (r *aggregatingReporter) V2() mb.PushReporterV2 { return aggregatingReporterV2{r} }
+
+// flush reports the summary event of every group seen in the fetch.
+func (r *aggregatingReporter) flush() {
+	out := r.reporter.V2()
+	for _, key := range r.keys {
+		if !out.Event(r.aggregator.Aggregate(key, r.groups[key])) {
+			return
+		}
+	}
+}
+
+type aggregatingReporterV2 struct {
+	*aggregatingReporter
+}
+
+func (r aggregatingReporterV2) Done() <-chan struct{} { return r.reporter.V2().Done() }
+func (r aggregatingReporterV2) Error(err error) bool  { return r.Event(mb.Event{Error: err}) }
+func (r aggregatingReporterV2) Event(event mb.Event) bool {
+	if event.Error == nil {
+		if key, ok := r.aggregator.AggregationKey(event); ok {
+			if _, found := r.groups[key]; !found {
+				r.keys = append(r.keys, key)
+			}
+			r.groups[key] = append(r.groups[key], event)
+			if !r.msw.aggregation.KeepEvents {
+				// Still tell the metricset to stop when it is being closed.
+				select {
+				case <-r.Done():
+					return false
+				default:
+					return true
+				}
+			}
+		}
+	}
+	return r.reporter.V2().Event(event)
+}
+
 // channelContext implements context.Context by wrapping a channel
 type channelContext struct {
 	done <-chan struct{}
@@ -404,7 +501,7 @@ func (r reporterV2) Event(event mb.Event) bool {
 		event.Took = time.Since(r.start)
 	}
 	if r.msw.periodic {
-		event.Period = r.msw.Module().Config().Period
+		event.Period = r.msw.Module().Config().MetricSetPeriod(r.msw.Name())
 	}
 
 	if event.Timestamp.IsZero() {
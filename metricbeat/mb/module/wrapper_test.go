@@ -37,6 +37,7 @@ const (
 	moduleName           = "fake"
 	reportingFetcherName = "ReportingFetcher"
 	pushMetricSetName    = "PushMetricSet"
+	aggregatorName       = "Aggregator"
 )
 
 // fakeMetricSet
@@ -44,6 +45,7 @@ const (
 func init() {
 	mb.Registry.MustAddMetricSet(moduleName, reportingFetcherName, newFakeReportingFetcher)
 	mb.Registry.MustAddMetricSet(moduleName, pushMetricSetName, newFakePushMetricSet)
+	mb.Registry.MustAddMetricSet(moduleName, aggregatorName, newFakeAggregator)
 }
 
 // ReportingFetcher
@@ -80,6 +82,41 @@ func newFakePushMetricSet(base mb.BaseMetricSet) (mb.MetricSet, error) {
 	return r, nil
 }
 
+// Aggregator
+
+type fakeAggregator struct {
+	mb.BaseMetricSet
+}
+
+func (ms *fakeAggregator) Fetch(r mb.ReporterV2) {
+	for i, group := range []string{"a", "b", "a"} {
+		r.Event(mb.Event{MetricSetFields: mapstr.M{"group": group, "metric": i + 1}})
+	}
+	r.Event(mb.Event{MetricSetFields: mapstr.M{"metric": 10}})
+}
+
+func (ms *fakeAggregator) AggregationKey(event mb.Event) (string, bool) {
+	group, err := event.MetricSetFields.GetValue("group")
+	if err != nil {
+		return "", false
+	}
+	return group.(string), true
+}
+
+func (ms *fakeAggregator) Aggregate(key string, events []mb.Event) mb.Event {
+	total := 0
+	for _, event := range events {
+		metric, _ := event.MetricSetFields.GetValue("metric")
+		total += metric.(int)
+	}
+	return mb.Event{MetricSetFields: mapstr.M{"summary": mapstr.M{"group": key, "metric": total}}}
+}
+
+func newFakeAggregator(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	var r mb.ReportingMetricSetV2 = &fakeAggregator{BaseMetricSet: base}
+	return r, nil
+}
+
 // test utilities
 
 func newTestRegistry(t testing.TB) *mb.Register {
@@ -89,6 +126,8 @@ func newTestRegistry(t testing.TB) *mb.Register {
 	require.NoError(t, err)
 	err = r.AddMetricSet(moduleName, pushMetricSetName, newFakePushMetricSet)
 	require.NoError(t, err)
+	err = r.AddMetricSet(moduleName, aggregatorName, newFakeAggregator)
+	require.NoError(t, err)
 	return r
 }
 
@@ -231,3 +270,70 @@ func TestNewWrapperForMetricSet(t *testing.T) {
 		assert.Fail(t, "received unexpected event")
 	}
 }
+
+func TestWrapperOfAggregator(t *testing.T) {
+	cases := map[string]struct {
+		aggregation map[string]interface{}
+		expected    []string
+	}{
+		"aggregation disabled": {
+			expected: []string{
+				`{"group":"a","metric":1}`,
+				`{"group":"b","metric":2}`,
+				`{"group":"a","metric":3}`,
+				`{"metric":10}`,
+			},
+		},
+		"summary events along with the events": {
+			aggregation: map[string]interface{}{"enabled": true},
+			expected: []string{
+				`{"group":"a","metric":1}`,
+				`{"group":"b","metric":2}`,
+				`{"group":"a","metric":3}`,
+				`{"metric":10}`,
+				`{"summary":{"group":"a","metric":4}}`,
+				`{"summary":{"group":"b","metric":2}}`,
+			},
+		},
+		"summary events only": {
+			aggregation: map[string]interface{}{"enabled": true, "keep_events": false},
+			expected: []string{
+				`{"metric":10}`,
+				`{"summary":{"group":"a","metric":4}}`,
+				`{"summary":{"group":"b","metric":2}}`,
+			},
+		},
+	}
+
+	registry := newTestRegistry(t)
+
+	for title, c := range cases {
+		t.Run(title, func(t *testing.T) {
+			config := map[string]interface{}{
+				"module":     moduleName,
+				"metricsets": []string{aggregatorName},
+				"hosts":      []string{"alpha"},
+			}
+			if c.aggregation != nil {
+				config["aggregation"] = c.aggregation
+			}
+
+			m, err := module.NewWrapper(newConfig(t, config), registry)
+			require.NoError(t, err)
+
+			done := make(chan struct{})
+			defer close(done)
+
+			output := m.Start(done)
+
+			var fields []string
+			for range c.expected {
+				event := <-output
+				metricset, err := event.Fields.GetValue(moduleName + ".aggregator")
+				require.NoError(t, err)
+				fields = append(fields, metricset.(mapstr.M).String())
+			}
+			assert.Equal(t, c.expected, fields)
+		})
+	}
+}
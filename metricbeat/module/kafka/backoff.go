@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kafka
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// errBackoff is returned by Connect while the metricset is backing off after
+// connection failures.
+var errBackoff = errors.New("backing off after connection failures")
+
+// fetchBackoff delays connection attempts exponentially after connection or
+// metadata failures, so a broker that is down does not produce an error on
+// every fetch. Repeated identical errors are only reported once.
+type fetchBackoff struct {
+	init, max time.Duration
+	now       func() time.Time
+	logger    *logp.Logger
+
+	failures int
+	current  time.Duration
+	next     time.Time
+	lastErr  string
+
+	consecutiveFailures *monitoring.Int
+	backoffMs           *monitoring.Int
+}
+
+func newFetchBackoff(init, max time.Duration, metrics *monitoring.Registry, logger *logp.Logger) *fetchBackoff {
+	b := &fetchBackoff{
+		init:   init,
+		max:    max,
+		now:    time.Now,
+		logger: logger,
+	}
+	if metrics != nil {
+		reg := metrics.GetRegistry("connection")
+		if reg == nil {
+			reg = metrics.NewRegistry("connection")
+		}
+		b.consecutiveFailures = monitoring.NewInt(reg, "consecutive_failures")
+		b.backoffMs = monitoring.NewInt(reg, "backoff_ms")
+	}
+	return b
+}
+
+// active returns true if connection attempts must be skipped.
+func (b *fetchBackoff) active() bool {
+	return b.failures > 0 && b.now().Before(b.next)
+}
+
+// failure records a failed fetch and returns the error to report, or nil if
+// the error must be suppressed because it is the same as the previous one or
+// because the fetch was skipped while backing off.
+func (b *fetchBackoff) failure(err error) error {
+	if errors.Is(err, errBackoff) {
+		return nil
+	}
+
+	b.failures++
+	if b.current == 0 {
+		b.current = b.init
+	} else {
+		b.current *= 2
+	}
+	if b.max > 0 && b.current > b.max {
+		b.current = b.max
+	}
+	b.next = b.now().Add(b.current)
+	b.updateMetrics()
+
+	msg := err.Error()
+	if msg == b.lastErr {
+		b.logger.Debugf("Fetch failed again (%d consecutive failures), next attempt in %v: %v", b.failures, b.current, err)
+		return nil
+	}
+	b.lastErr = msg
+	return err
+}
+
+// success records a successful fetch and logs the recovery if previous
+// fetches failed.
+func (b *fetchBackoff) success() {
+	if b.failures == 0 {
+		return
+	}
+	b.logger.Infof("Connection to kafka recovered after %d consecutive failures", b.failures)
+	b.failures = 0
+	b.current = 0
+	b.next = time.Time{}
+	b.lastErr = ""
+	b.updateMetrics()
+}
+
+func (b *fetchBackoff) updateMetrics() {
+	if b.consecutiveFailures == nil {
+		return
+	}
+	b.consecutiveFailures.Set(int64(b.failures))
+	b.backoffMs.Set(b.current.Milliseconds())
+}
@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestFetchBackoff(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	reg := monitoring.NewRegistry()
+	b := newFetchBackoff(10*time.Second, 30*time.Second, reg, logp.NewTestingLogger(t, ""))
+	b.now = func() time.Time { return now }
+	failures := reg.Get("connection.consecutive_failures").(*monitoring.Int)
+	backoffMs := reg.Get("connection.backoff_ms").(*monitoring.Int)
+
+	errDown := errors.New("connection refused")
+	assert.False(t, b.active())
+
+	// The first error is reported, repeated identical ones are suppressed.
+	assert.Equal(t, errDown, b.failure(errDown))
+	assert.True(t, b.active())
+	assert.Equal(t, int64(1), failures.Get())
+	assert.Equal(t, int64(10000), backoffMs.Get())
+
+	now = now.Add(10 * time.Second)
+	assert.False(t, b.active())
+	assert.NoError(t, b.failure(errDown))
+	assert.Equal(t, int64(20000), backoffMs.Get())
+
+	// Skipped fetches don't count as failures.
+	now = now.Add(10 * time.Second)
+	assert.True(t, b.active())
+	assert.NoError(t, b.failure(errBackoff))
+	assert.Equal(t, int64(2), failures.Get())
+
+	// The backoff is capped, and new errors are reported.
+	now = now.Add(10 * time.Second)
+	assert.False(t, b.active())
+	errTimeout := errors.New("i/o timeout")
+	assert.Equal(t, errTimeout, b.failure(errTimeout))
+	assert.Equal(t, int64(30000), backoffMs.Get())
+
+	// Recovering resets the backoff.
+	now = now.Add(30 * time.Second)
+	assert.False(t, b.active())
+	b.success()
+	assert.False(t, b.active())
+	assert.Equal(t, int64(0), failures.Get())
+	assert.Equal(t, int64(0), backoffMs.Get())
+	assert.Equal(t, errTimeout, b.failure(errTimeout), "errors after a recovery must be reported again")
+}
@@ -74,6 +74,12 @@ type MemberDescription struct {
 
 const noID = -1
 
+// debugReplicaID is the replica ID used by debugging consumers.
+const debugReplicaID = -2
+
+// fetchMaxBytes limits the size of the response when fetching single records.
+const fetchMaxBytes = 1024 * 1024
+
 // NewBroker creates a new unconnected kafka Broker connection instance.
 func NewBroker(host string, settings BrokerSettings) *Broker {
 	cfg := sarama.NewConfig()
@@ -91,6 +97,8 @@ func NewBroker(host string, settings BrokerSettings) *Broker {
 		cfg.Net.SASL.User = user
 		cfg.Net.SASL.Password = settings.Password
 		settings.Sasl.ConfigureSarama(cfg)
+	} else if settings.Sasl.IsOAuth() {
+		settings.Sasl.ConfigureSarama(cfg)
 	}
 	cfg.Version, _ = settings.Version.Get()
 
@@ -198,6 +206,48 @@ func (b *Broker) PartitionOffset(
 	return block.Offsets[0], nil
 }
 
+// FetchRecordHeaders fetches the record stored at offset in a partition
+// and returns its headers. The broker must be the leader of the partition.
+// Headers are only available for records written with the v2 message format
+// (Kafka 0.11 and newer).
+func (b *Broker) FetchRecordHeaders(topic string, partition int32, offset int64) ([]*sarama.RecordHeader, error) {
+	req := &sarama.FetchRequest{
+		Version:     4,
+		MinBytes:    1,
+		MaxBytes:    fetchMaxBytes,
+		MaxWaitTime: 100,
+		Isolation:   sarama.ReadUncommitted,
+	}
+	req.AddBlock(topic, partition, offset, fetchMaxBytes, -1)
+
+	resp, err := b.broker.Fetch(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+
+	block := resp.GetBlock(topic, partition)
+	if block == nil {
+		return nil, fmt.Errorf("no fetch response for partition %v:%v", topic, partition)
+	}
+	if block.Err != sarama.ErrNoError {
+		return nil, block.Err
+	}
+
+	for _, records := range block.RecordsSet {
+		batch := records.RecordBatch
+		if batch == nil {
+			// Legacy message sets carry no headers.
+			continue
+		}
+		for _, record := range batch.Records {
+			if batch.FirstOffset+record.OffsetDelta == offset {
+				return record.Headers, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("record at offset %v not found in partition %v:%v", offset, topic, partition)
+}
+
 // ListGroups lists all groups managed by the broker. Other consumer
 // groups might be managed by other brokers.
 func (b *Broker) ListGroups() ([]string, error) {
@@ -290,6 +340,41 @@ func (b *Broker) FetchPartitionOffsetFromTheLeader(topic string, partitionID int
 	return offset, nil
 }
 
+// FetchPartitionOffsetFromBroker fetches the OffsetNewest of a partition from
+// the broker with the given ID. The broker must host a replica of the
+// partition. Followers answer with the end of their local log, which might
+// lag slightly behind the leader.
+func (b *Broker) FetchPartitionOffsetFromBroker(brokerID int32, topic string, partitionID int32) (int64, error) {
+	broker, err := b.client.Broker(brokerID)
+	if err != nil {
+		return -1, fmt.Errorf("broker %v not available: %w", brokerID, err)
+	}
+
+	req := &sarama.OffsetRequest{}
+	// Requests from debugging consumers are also served by follower replicas.
+	req.SetReplicaID(debugReplicaID)
+	req.AddBlock(topic, partitionID, sarama.OffsetNewest, 1)
+	resp, err := broker.GetAvailableOffsets(req)
+	if err != nil {
+		return -1, fmt.Errorf("get available offsets from broker %v failed: %w", brokerID, err)
+	}
+
+	block := resp.GetBlock(topic, partitionID)
+	if block == nil {
+		return -1, fmt.Errorf("no offset response for partition %v:%v", topic, partitionID)
+	}
+	if len(block.Offsets) == 0 {
+		return -1, fmt.Errorf("block offsets is empty: %w", block.Err)
+	}
+	return block.Offsets[0], nil
+}
+
+// PartitionReplicas returns the IDs of the brokers hosting a replica of
+// the partition.
+func (b *Broker) PartitionReplicas(topic string, partitionID int32) ([]int32, error) {
+	return b.client.Replicas(topic, partitionID)
+}
+
 // ID returns the broker ID or -1 if the broker id is unknown.
 func (b *Broker) ID() int32 {
 	if b.id == noID {
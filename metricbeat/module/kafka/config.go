@@ -33,6 +33,10 @@ type metricsetConfig struct {
 	Password string            `config:"password"`
 	ClientID string            `config:"client_id"`
 	Sasl     kafka.SaslConfig  `config:"sasl"`
+
+	// MaxFailureBackoff is the longest time fetches are skipped after
+	// consecutive connection failures.
+	MaxFailureBackoff time.Duration `config:"failure_backoff.max" validate:"min=0"`
 }
 
 var defaultConfig = metricsetConfig{
@@ -42,6 +46,8 @@ var defaultConfig = metricsetConfig{
 	Username: "",
 	Password: "",
 	ClientID: "metricbeat",
+
+	MaxFailureBackoff: 5 * time.Minute,
 }
 
 func (c *metricsetConfig) Validate() error {
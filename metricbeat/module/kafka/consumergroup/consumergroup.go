@@ -37,8 +37,9 @@ func init() {
 type MetricSet struct {
 	*kafka.MetricSet
 
-	topics nameSet
-	groups nameSet
+	topics  nameSet
+	groups  nameSet
+	offsets *offsetFetcher
 }
 
 type groupAssignment struct {
@@ -63,6 +64,10 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 	config := struct {
 		Groups []string `config:"groups"`
 		Topics []string `config:"topics"`
+
+		// OffsetBrokers pins the requests for the newest partition offsets
+		// to a set of brokers. If empty the partition leaders are queried.
+		OffsetBrokers []int32 `config:"offset_brokers"`
 	}{}
 	if err := base.Module().UnpackConfig(&config); err != nil {
 		return nil, err
@@ -72,6 +77,7 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 		MetricSet: ms,
 		groups:    makeNameSet(config.Groups...),
 		topics:    makeNameSet(config.Topics...),
+		offsets:   newOffsetFetcher(config.OffsetBrokers, base.Metrics()),
 	}, nil
 }
 
@@ -79,9 +85,10 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 func (m *MetricSet) Fetch(r mb.ReporterV2) error {
 	broker, err := m.Connect()
 	if err != nil {
-		return fmt.Errorf("error in connect: %w", err)
+		return m.FetchFailed(fmt.Errorf("error in connect: %w", err))
 	}
 	defer broker.Close()
+	m.FetchSucceeded()
 
 	brokerInfo := mapstr.M{
 		"id":      broker.ID(),
@@ -110,7 +117,7 @@ func (m *MetricSet) Fetch(r mb.ReporterV2) error {
 			MetricSetFields: event,
 		})
 	}
-	err = fetchGroupInfo(emitEvent, broker, m.groups.pred(), m.topics.pred())
+	err = fetchGroupInfo(emitEvent, broker, m.offsets, m.groups.pred(), m.topics.pred())
 	if err != nil {
 		return fmt.Errorf("error in fetch: %w", err)
 	}
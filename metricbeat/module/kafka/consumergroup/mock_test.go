@@ -30,6 +30,8 @@ type mockClient struct {
 	describeGroups                  func(group []string) (map[string]kafka.GroupDescription, error)
 	fetchGroupOffsets               func(group string) (*sarama.OffsetFetchResponse, error)
 	getPartitionOffsetFromTheLeader func(topic string, partitionID int32) (int64, error)
+	getPartitionOffsetFromBroker    func(brokerID int32, topic string, partitionID int32) (int64, error)
+	partitionReplicas               func(topic string, partitionID int32) ([]int32, error)
 }
 
 type mockState struct {
@@ -48,6 +50,12 @@ func defaultMockClient(state mockState) *mockClient {
 		getPartitionOffsetFromTheLeader: func(topic string, partitionID int32) (int64, error) {
 			return 42, nil
 		},
+		getPartitionOffsetFromBroker: func(brokerID int32, topic string, partitionID int32) (int64, error) {
+			return 42, nil
+		},
+		partitionReplicas: func(topic string, partitionID int32) ([]int32, error) {
+			return []int32{1, 2, 3}, nil
+		},
 	}
 }
 
@@ -151,3 +159,9 @@ func (c *mockClient) FetchGroupOffsets(group string, partitions map[string][]int
 func (c *mockClient) FetchPartitionOffsetFromTheLeader(topic string, partitionID int32) (int64, error) {
 	return c.getPartitionOffsetFromTheLeader(topic, partitionID)
 }
+func (c *mockClient) FetchPartitionOffsetFromBroker(brokerID int32, topic string, partitionID int32) (int64, error) {
+	return c.getPartitionOffsetFromBroker(brokerID, topic, partitionID)
+}
+func (c *mockClient) PartitionReplicas(topic string, partitionID int32) ([]int32, error) {
+	return c.partitionReplicas(topic, partitionID)
+}
@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package consumergroup
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// leaderRequests is the name of the counter of offset requests sent to the
+// partition leaders.
+const leaderRequests = "leader"
+
+// offsetFetcher fetches the newest offsets of partitions. By default the
+// offsets are requested from the partition leaders. If a set of brokers is
+// configured, the requests are distributed between the brokers of the set
+// hosting a replica of the partition.
+// The number of requests sent to each broker is counted in the metricset
+// registry under offset_requests.<broker id>.
+type offsetFetcher struct {
+	brokers []int32
+
+	mu       sync.Mutex
+	registry *monitoring.Registry
+	requests map[string]*monitoring.Uint
+}
+
+func newOffsetFetcher(brokers []int32, metrics *monitoring.Registry) *offsetFetcher {
+	f := &offsetFetcher{
+		brokers:  brokers,
+		requests: map[string]*monitoring.Uint{},
+	}
+	if metrics != nil {
+		f.registry = metrics.GetRegistry("offset_requests")
+		if f.registry == nil {
+			f.registry = metrics.NewRegistry("offset_requests")
+		}
+	}
+	return f
+}
+
+// fetch returns the newest offset of a partition. If none of the configured
+// brokers hosts a replica of the partition, the leader is queried.
+func (f *offsetFetcher) fetch(b client, topic string, partitionID int32) (int64, error) {
+	if len(f.brokers) == 0 {
+		f.count(leaderRequests)
+		return b.FetchPartitionOffsetFromTheLeader(topic, partitionID)
+	}
+
+	replicas, err := b.PartitionReplicas(topic, partitionID)
+	if err != nil {
+		return -1, err
+	}
+
+	var candidates []int32
+	for _, id := range f.brokers {
+		for _, replica := range replicas {
+			if id == replica {
+				candidates = append(candidates, id)
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		f.count(leaderRequests)
+		return b.FetchPartitionOffsetFromTheLeader(topic, partitionID)
+	}
+
+	// Spread the partitions of a topic over the candidates, always picking
+	// the same broker for a partition.
+	id := candidates[int(partitionID)%len(candidates)]
+	f.count(strconv.Itoa(int(id)))
+	return b.FetchPartitionOffsetFromBroker(id, topic, partitionID)
+}
+
+func (f *offsetFetcher) count(broker string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counter, ok := f.requests[broker]
+	if !ok {
+		if f.registry == nil {
+			counter = &monitoring.Uint{}
+		} else if existing, ok := f.registry.Get(broker).(*monitoring.Uint); ok {
+			counter = existing
+		} else {
+			counter = monitoring.NewUint(f.registry, broker)
+		}
+		f.requests[broker] = counter
+	}
+	counter.Inc()
+}
@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package consumergroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestOffsetFetcher(t *testing.T) {
+	type request struct {
+		broker    int32
+		partition int32
+	}
+
+	newClient := func(requests *[]request) *mockClient {
+		return defaultMockClient(mockState{}).with(func(c *mockClient) {
+			c.getPartitionOffsetFromTheLeader = func(topic string, partitionID int32) (int64, error) {
+				*requests = append(*requests, request{-1, partitionID})
+				return 42, nil
+			}
+			c.getPartitionOffsetFromBroker = func(brokerID int32, topic string, partitionID int32) (int64, error) {
+				*requests = append(*requests, request{brokerID, partitionID})
+				return 42, nil
+			}
+			c.partitionReplicas = func(topic string, partitionID int32) ([]int32, error) {
+				if partitionID == 3 {
+					return []int32{4}, nil
+				}
+				return []int32{1, 2, 3}, nil
+			}
+		})
+	}
+
+	counter := func(t *testing.T, reg *monitoring.Registry, name string) uint64 {
+		t.Helper()
+		v, ok := reg.Get("offset_requests." + name).(*monitoring.Uint)
+		require.True(t, ok, "missing counter %v", name)
+		return v.Get()
+	}
+
+	t.Run("leader by default", func(t *testing.T) {
+		var requests []request
+		reg := monitoring.NewRegistry()
+		f := newOffsetFetcher(nil, reg)
+		b := newClient(&requests)
+
+		for partition := int32(0); partition < 3; partition++ {
+			offset, err := f.fetch(b, "topic", partition)
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), offset)
+		}
+		assert.Equal(t, []request{{-1, 0}, {-1, 1}, {-1, 2}}, requests)
+		assert.Equal(t, uint64(3), counter(t, reg, leaderRequests))
+	})
+
+	t.Run("pinned brokers", func(t *testing.T) {
+		var requests []request
+		reg := monitoring.NewRegistry()
+		f := newOffsetFetcher([]int32{2, 3, 5}, reg)
+		b := newClient(&requests)
+
+		for partition := int32(0); partition < 4; partition++ {
+			_, err := f.fetch(b, "topic", partition)
+			require.NoError(t, err)
+		}
+
+		// Broker 5 hosts no replica, partition 3 has no replica on the
+		// configured brokers and falls back to the leader.
+		assert.Equal(t, []request{{2, 0}, {3, 1}, {2, 2}, {-1, 3}}, requests)
+		assert.Equal(t, uint64(2), counter(t, reg, "2"))
+		assert.Equal(t, uint64(1), counter(t, reg, "3"))
+		assert.Equal(t, uint64(1), counter(t, reg, leaderRequests))
+		assert.Nil(t, reg.Get("offset_requests.5"))
+	})
+
+	t.Run("reuses registered counters", func(t *testing.T) {
+		var requests []request
+		reg := monitoring.NewRegistry()
+		b := newClient(&requests)
+
+		for i := 0; i < 2; i++ {
+			f := newOffsetFetcher(nil, reg)
+			_, err := f.fetch(b, "topic", 0)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, uint64(2), counter(t, reg, leaderRequests))
+	})
+}
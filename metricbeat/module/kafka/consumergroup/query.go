@@ -29,11 +29,14 @@ type client interface {
 	DescribeGroups(group []string) (map[string]kafka.GroupDescription, error)
 	FetchGroupOffsets(group string, partitions map[string][]int32) (*sarama.OffsetFetchResponse, error)
 	FetchPartitionOffsetFromTheLeader(topic string, partitionID int32) (int64, error)
+	FetchPartitionOffsetFromBroker(brokerID int32, topic string, partitionID int32) (int64, error)
+	PartitionReplicas(topic string, partitionID int32) ([]int32, error)
 }
 
 func fetchGroupInfo(
 	emit func(mapstr.M),
 	b client,
+	offsets *offsetFetcher,
 	groupsFilter, topicsFilter func(string) bool,
 ) error {
 	type result struct {
@@ -113,7 +116,7 @@ func fetchGroupInfo(
 
 		for topic, partitions := range ret.off.Blocks {
 			for partition, info := range partitions {
-				partitionOffset, err := getPartitionOffsetFromTheLeader(b, topic, partition)
+				partitionOffset, err := offsets.fetch(b, topic, partition)
 				if err != nil {
 					logp.Err("failed to fetch offset for (topic, partition): ('%v', %v)", topic, partition)
 					continue
@@ -151,14 +154,6 @@ func fetchGroupInfo(
 	return err
 }
 
-func getPartitionOffsetFromTheLeader(b client, topic string, partitionID int32) (int64, error) {
-	offset, err := b.FetchPartitionOffsetFromTheLeader(topic, partitionID)
-	if err != nil {
-		return -1, err
-	}
-	return offset, nil
-}
-
 func listGroups(b client, filter func(string) bool) ([]string, error) {
 	groups, err := b.ListGroups()
 	if err != nil {
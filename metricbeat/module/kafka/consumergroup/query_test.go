@@ -219,7 +219,7 @@ func TestFetchGroupInfo(t *testing.T) {
 
 		groups := makeNameSet(test.groups...).pred()
 		topics := makeNameSet(test.topics...).pred()
-		err := fetchGroupInfo(collectEvents, test.client, groups, topics)
+		err := fetchGroupInfo(collectEvents, test.client, newOffsetFetcher(nil, nil), groups, topics)
 		if err != nil {
 			switch {
 			case test.err == nil:
@@ -32,5 +32,5 @@ func init() {
 // AssetKafka returns asset data.
 // This is the base64 encoded zlib format compressed contents of module/kafka.
 func AssetKafka() string {
-	return "eJzUWk1z2zYTvvtX7OTkHMLcfXhn3iadjpumyaTpTKcXDgQsRdQgwACgbPnXdwCQFL9EEqLcNjrZFHafB/uFxVJv4AGPd/BAsgdyA2C5FXgHrz64/1/dADA0VPPSciXv4H83AAD+OygUqwTeAJhcaZtSJTO+v4OMCOOeahRIDN7B3qnNOApm7rz4G5CkwBOk+9hj6ZZqVZX1kwncvpquqp1WD6jbx1P6zuoMnx+8BninpKkK1PCTE4V7mSldECcAOTkg7BAlaCQMMq0KuK3FciKZ4HLfU2lzBNro81ReJ50Fw71098NZ73GzH6EGELNb6myLs5tJHMKYRmMmwR7w+Kj0kMg6PMIOqC03yFqIkc+sKjlN3N8jv42hZ2C/Oj1e5zkM1FrphCo2RhpYdBHGqwKnKhmjlURb7mSTnv9ikT43aoCzWRS/u3QCa95+PbDfJf9WIXAGKvMRW57QpX8QbLjMI+TgP0MHiGT+vwCaXKUg1LFboNWcmpDgodTV3/z88Y+ObFvgdmjJyrwudkjkXEZ9dAvA5sSCzbkBPKC0wI1DIxYZWLU6WRtQjd8qNDahOZESRfKtwgoTw59xjsnXHMGtaRxRawEvva46DQmUWrGKYpIRLpClJerUIFVytsY4HppYzyMIQq2n0WugRA2TmgKxTChiZ5llaGl+OS8quHOT19IaymmrNF6BXd9uS6RkVexQz5jrCjZaz2HWNNFMSsGpP40TgYShTlEgdf8PVY0YhfXQrPeu2wBfSSqQyDSWRi13DToGjXFUnpV6QCxRJ4wbqqREapdo/KnUBy8DVCh3StfKNgTrmA4+lVwv1pgTlbD+Zbi4lk1JcVzPppF4ETrmKGmMj3wO1b7dxkWofZKJyuTpRMiNs0btwa++JEDrBg9twmWyO1o0TWldguWSqoLLPTipUGXdhr3Ci0moysaxUJXdq2uz0PgXUossjkojdTUqBRpD9mhSPtuO9JxRy2yDv044XAB6BfdfgHotd0dCb3XvCrgGqrnhxvXa7T17ottuv/tO+23f66wqrwWXvKiKkFHEwmPOad6fGxiUzPTbJwNWARlfcdYERgjDWvtiG0cOqF1InNo5L9+wY5ApDQRMiZRnnNZ3sw39LlWabaFXazgRPHGZ5BpJMLZwNfeDxmo+z9w9VvWcHJvc5CkVZHYQ5IOLPPngakNpLLOE1DYsKVVFwUdXh7MbVllm0HUsXsrtt+1mIin4IeF2+A+dWeNqO68vou1F8FTWQjEND/zKC2rqsIRGTzXPKeoPZ5vPBRPJFUPC4Uj1fbt4Eij4bt2AYYDUWr/2P5dWdQZIO3Tp5/r6eQZF/3yJ2iytjFVFNw4sAUYsAWN1d0A8idyITaR3pAUE2fuC1+7+bajNlAhahZONGF+EGM8y1CgpuoP1EVEO5m61MYlkQwPPb2Zy6rp+K+PZsi8FLYe3J4bdoey8ff1FapLOMENW8Pm/MXwvkTX3MxdZLsJ8D1F3NC3JgfRUqsFcusFCFI74vguk7t/DbTCcQWsdvcA24ez1tLG6NHJlhua6lEhP1VnAAt1Rnm7fPpcWtSRi8AamBuhWodE8e0vBnVISX2xnauAlcXogXJCdwFqvaUa6e35A2ZnjR8aoxEecCY+JBF9B1n1+9YqbwjN8ETCk2TGbYC9D6JNXvExo0pnnSsDF/jy9H3IHS6zXwuTxBYz0Sxhpcga34Wry+ryn6lHqC7D4EjRP0zhfbqU5Spou0dopJcYXypXM7iXjlLiLAM8aA7ibJpdUVAxZ84KLyzeOTDtuRle54fb+ty+rdmLSBQ+/yCZsO2Jfpni2MYAr+P/HthcIB7C/ObtjbyJd2wwNL0giRxifa6mpEUb73Xc6wiDNkZHuKtcepv4Gu3QLssoSAaRQlfRVMsi6I1fp4eR9cVBBLM1Tw58xJYfFu+65QYU51+6tAi7I09pL9mrgmZekVGmWGpRs1ezo/PDDYW8dwqQarT5eTMRqjqxWVY+wthLyVeM/RCgMgeqR3L/srNg0aeww/glADGBEeiwBLv10YJXfT8ZtCvpVfiFgSiUNXs4gyG+gwFX6SPhijLWQ928/gRMAy4tY/0a/JemPOMMLE1VZP3ywHVaRPOr52Sqrl6eDeNUbjL8DAAD//1EPWgk="
+	return "eJzUWk+v2zYSv/tTDHpKgES5LPbwDgts28Xu27RNkXaBxV4UWhzJ7KNIh6T84nz6BSmS1h/K+mO/okXeIZY58/vNcGY0HPotPOH5AZ5I+UR2AIYZjg/wzXv7+ZsdAEVdKHY0TIoH+NsOAMB9B7WkDccdgD5IZfJCipJVD1ASru1ThRyJxgeorNqSIaf6wYm/BUFqvEDaf+Z8tEuVbI7+SQK3r6araq/kE6r4OKVvUmf7963TAN9JoZsaFfzTUoFHUUpVE2s8HMgJYY8oQCGhUCpZwysvdiCCciaqnkpzQCiCPkflddZZMLSlaw+jvcfBHi4HEFdN6pjF6C6JQyhVqPVArAV7wvOzVHQTHqEnVIZppBFiN8Q28siKzNq7m4e+Avur1eN0TmGgUlJlhaS4m/HoLIxTBVZVNkY7EmWYjZWM0RuQfg5qgNGrKM66nNGV/us8BviPYJ8bBEZBli5io3pgwj1wKAt4tDn4+9ABIqj71IJmI3JbCoKP3RqNYoVuE7wtdf6bf//4345sLHB7NGRhXtd7JKL3zYDDj3YBmAMxYA5MA55QGGAaFHJikIKRA/EpF19AFX5uUJusOBAhkGefG2ww0+wrXmPy6wHBrgkb4bWAkx4IJiN8TOCoJG0KzErCONL8iCrXWEhB53goYhyPVhC8nqBXwxEVJDW1xEouibnKrERTHLbzKjiz2+S0BJ1gtTUK78Cu77c5UqKp96iuuGsji66PlnO46prVTI6cFe5tnHEkFFWOHAv7Wc8xatdDWO+27gb4RhQcicjX0vBy96CjUWvria9SPiEeUWWU6UIKgYWZo/E/Kd87GSi4tG9pr+yGYB3TwS9HpnA5lXb9y3CxLZsU/LycTZB4ETr6LIrlVHwO+b29jQuXVVbyRh/yRMiNOHBZgVu9JUB9g4cmYyLbnw3qUFrnYJkoZM1EBVbKQTuDncLNJGRj1rGQjankvVko/A0Lg3QdlSB1Nyo1ak0q1DkTizfDy9wGf59w2AB6h+3fgHqv7V4Jfev2LoALUOGEu67XjufsRLcdv/uT9tuuUVpUXmsmWN3ULriAGHg+sOLQnxtoFFT32ycNRgIZH3GmdqrLzcayzr12OsePnFCRqtvOOfnAjkIpFRDQRyxYyQp/Ntv8blJYSEVvoec1XAheuCS5riS4tnCF80Hwmiti9hwre5u8kkVNvuScVHPgNfnigiugwFhmDik2LHkh65oZPYcZDJZlqdGAl7L2xm5mJQU3JLwd/n1n1rgUekURDcDR16GYtg/cygXoATmoGZbQBYW1P0qaUhRrabW0kjKa5J+qg1Ol3o9Uv4+Lk0Dt3iXBRgOGAVKwNuw/E0Z2Bkh7tOln+/qoJMmg7r9fVhlbNNrITs5ZXUCJIaCN6g6Ik8hBLJHeKz3ASeUKXrT+nat3UBBeNO2bjWhXhCgrS1QoCjvcNs92vt2fu3ln2olbVD/YpKQxyanrclP6gWz/uVIQOby7MOwOZcPiJKX2IJWkM8yQBXz+rjWrBNJwPrORZSPMzex8RxNJDqRTqXY13eaicMT3u5bU4/fwqnWcRmMsvZZtxujrqGKSxkFqcyciPVWTgDXW++EQeRMqEwaVIPwSs26HPUC3CgXo1FatLrgpJeuL7ZUauCVOT4Rxsufo9eow0q3YCcXF7mxljAp8xivhkUjwBWTt309OsWcbyE7S7LiN05ch9IHTRYR2KVaxjfBN41RfrAtip8/jfmSW4kfffQSkMPGlodGMVEEzW+gdfYUnJht/tngzUlrI+tjY94SrY1ZCdLdFZ/BB8LMdikpllz0fUMAnT8GdfvSnkVKmAYUNRTrjNN3UNVHne6XAL606u3WkPaW8uTCXoujMM7whpKoUVm7W/AnYsPOEaEbrhYuD28GuBqLQG8G+Il2bWlHfGPj2aP4pHpcuMCGo/WVfWDrkFSLsRVkFECCxcHXv/t64/+qm9pyT2i5dje61NT6GbTMju0ntauL4hDEo6nreM9NZvijTFzprNuOTnnJzBmtpUqc9qHUsXZDey9OZE21yL7xLuWRDTv9gOZjggmBitOANuOl9Ibm9Y+nWsbhkpNLfyvRtPbiHE9YuS+fk+/weSfOh90biPY9kk3S8RQmNLR+5/218h2T/2i/yVY1Ymvi/fJlMM4dvmSDqDCfCG2xr6Z5o/OtfAIVt8+fCbWqHN8fa5ScZ9iy3dvvbsJp09/bt/8Eptj/eeNVOA19nkyT87eULsPjYak7TmOTDhL0Ey+do7aXk4xnuQmaPgtr7YtTAynB9a9OYiYI3FGn4TQkTby2ZeMOLYLPq1eMvHxdZov018O9rhIm32lFskuLkWfwe+/+PePxua6xrKO1JM6ZhthsS8u8rtZtLzh7+z14qdWsQv/uT3hrEZiffN7Z3yd3Q+BoLO/E00hAOpJaNcK+BVtaecuVE9zzuOgKDPbH3Fpp9xZycqjnkqbsBPTVhWQRcky9zwGGuvRh4FNkBt70tyO0Vy6Lrmun7Bou9/U7e81Bo1HkzEaMYUq/K3xrdSshVjT8Qofbq1t+CbaF0x81amybBD+Nf3a0BXJEec4BXssL5d9G+X5wbCvrlkvIGD+ujFBq3M2jlb6DAZP5MmJkDj5CP7z6AFQDDalyJtfqHCeGyyQlB+xsF2Rg37zcdVit5+OZ7kdej4Qt/NPD/AQDLq13W"
 }
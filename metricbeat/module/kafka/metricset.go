@@ -27,7 +27,8 @@ import (
 // MetricSet is the base metricset for all Kafka metricsets
 type MetricSet struct {
 	mb.BaseMetricSet
-	broker *Broker
+	broker  *Broker
+	backoff *fetchBackoff
 }
 
 // MetricSetOptions are the options of a Kafka metricset
@@ -70,12 +71,31 @@ func NewMetricSet(base mb.BaseMetricSet, options MetricSetOptions) (*MetricSet,
 	return &MetricSet{
 		BaseMetricSet: base,
 		broker:        NewBroker(base.Host(), cfg),
+		backoff: newFetchBackoff(base.Module().Config().Period, config.MaxFailureBackoff,
+			base.Metrics(), base.Logger()),
 	}, nil
 
 }
 
-// Connect connects with a kafka broker
+// Connect connects with a kafka broker. After failed fetches, no connection
+// is attempted until the backoff expires.
 func (m *MetricSet) Connect() (*Broker, error) {
+	if m.backoff.active() {
+		return m.broker, errBackoff
+	}
 	err := m.broker.Connect()
 	return m.broker, err
 }
+
+// FetchFailed records a connection or metadata failure and returns the error
+// to report from Fetch. It returns nil for repeated identical errors and for
+// fetches skipped while backing off.
+func (m *MetricSet) FetchFailed(err error) error {
+	return m.backoff.failure(err)
+}
+
+// FetchSucceeded records that the broker could be reached, resetting the
+// backoff.
+func (m *MetricSet) FetchSucceeded() {
+	m.backoff.success()
+}
@@ -18,8 +18,11 @@
 package partition
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"github.com/elastic/beats/v7/metricbeat/mb"
 	"github.com/elastic/beats/v7/metricbeat/mb/parse"
@@ -41,7 +44,11 @@ func init() {
 type MetricSet struct {
 	*kafka.MetricSet
 
-	topics []string
+	topics         []string
+	messageHeaders bool
+
+	// rates is set if message rates are computed from the newest offsets.
+	rates *rateTracker
 }
 
 var errFailQueryOffset = errors.New("operation failed")
@@ -60,15 +67,24 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 	}
 
 	config := struct {
-		Topics []string `config:"topics"`
+		Topics         []string `config:"topics"`
+		MessageHeaders bool     `config:"message_headers"`
+		MessageRates   bool     `config:"message_rates"`
 	}{}
 	if err := base.Module().UnpackConfig(&config); err != nil {
 		return nil, err
 	}
 
+	var rates *rateTracker
+	if config.MessageRates {
+		rates = newRateTracker()
+	}
+
 	return &MetricSet{
-		MetricSet: ms,
-		topics:    config.Topics,
+		MetricSet:      ms,
+		topics:         config.Topics,
+		messageHeaders: config.MessageHeaders,
+		rates:          rates,
 	}, nil
 }
 
@@ -76,19 +92,26 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 func (m *MetricSet) Fetch(r mb.ReporterV2) error {
 	broker, err := m.Connect()
 	if err != nil {
-		return fmt.Errorf("error in connect: %w", err)
+		return m.FetchFailed(fmt.Errorf("error in connect: %w", err))
 	}
 	defer broker.Close()
 
 	topics, err := broker.GetTopicsMetadata(m.topics...)
 	if err != nil {
-		return fmt.Errorf("error getting topic metadata: %w", err)
+		return m.FetchFailed(fmt.Errorf("error getting topic metadata: %w", err))
 	}
+	m.FetchSucceeded()
 	if len(topics) == 0 {
 		debugf("no topic could be read, check ACLs")
 		return nil
 	}
 
+	if m.rates != nil {
+		// Partitions not seen in this fetch are forgotten, so they get no
+		// rate computed from stale offsets if they show up again.
+		defer m.rates.done()
+	}
+
 	evtBroker := mapstr.M{
 		"id":      broker.ID(),
 		"address": broker.AdvertisedAddr(),
@@ -118,6 +141,7 @@ func (m *MetricSet) Fetch(r mb.ReporterV2) error {
 
 				// Get oldest and newest available offsets
 				offOldest, offNewest, offOK, err := queryOffsetRange(broker, id, topic.Name, partition.ID)
+				queried := time.Now()
 
 				if !offOK {
 					if err == nil {
@@ -162,6 +186,27 @@ func (m *MetricSet) Fetch(r mb.ReporterV2) error {
 					},
 				}
 
+				if m.rates != nil {
+					rate, ok := m.rates.rate(partitionTopicBrokerID, offsetSample{newest: offNewest, timestamp: queried})
+					if ok {
+						event["messages_per_second"] = rate
+					}
+				}
+
+				if m.messageHeaders && partition.Leader == id && offNewest > offOldest {
+					lastOffset := offNewest - 1
+					headers, err := broker.FetchRecordHeaders(topic.Name, partition.ID, lastOffset)
+					if err != nil {
+						m.Logger().Warnf("failed to fetch last message of kafka partition (%v:%v): %v",
+							topic.Name, partition.ID, err)
+					} else {
+						event["last_message"] = mapstr.M{
+							"offset":  lastOffset,
+							"headers": headersToMapStr(headers),
+						}
+					}
+				}
+
 				sent := r.Event(mb.Event{
 					ModuleFields: mapstr.M{
 						"broker": evtBroker,
@@ -201,6 +246,23 @@ func queryOffsetRange(
 	return oldest, newest, okOld && okNew, nil
 }
 
+// headersToMapStr converts kafka record headers to fields. Values that are
+// not valid UTF-8 are base64 encoded.
+func headersToMapStr(headers []*sarama.RecordHeader) mapstr.M {
+	fields := mapstr.M{}
+	for _, h := range headers {
+		if h == nil {
+			continue
+		}
+		if utf8.Valid(h.Value) {
+			fields[string(h.Key)] = string(h.Value)
+		} else {
+			fields[string(h.Key)] = base64.StdEncoding.EncodeToString(h.Value)
+		}
+	}
+	return fields
+}
+
 func hasID(id int32, lst []int32) bool {
 	for _, other := range lst {
 		if id == other {
@@ -125,7 +125,38 @@ func TestTopic(t *testing.T) {
 	assert.True(t, offsetBefore+n == offsetAfter)
 }
 
-func generateKafkaData(t *testing.T, host string, topic string) {
+func TestMessageHeaders(t *testing.T) {
+	service := compose.EnsureUp(t, "kafka",
+		compose.UpWithTimeout(600*time.Second),
+		compose.UpWithAdvertisedHostEnvFileForPort(9092),
+	)
+
+	id := strconv.Itoa(rand.Int())
+	testTopic := fmt.Sprintf("test-metricbeat-headers-%s", id)
+
+	generateKafkaData(t, service.HostForPort(9092), testTopic,
+		sarama.RecordHeader{Key: []byte("trace.id"), Value: []byte("abc123")},
+		sarama.RecordHeader{Key: []byte("binary"), Value: []byte{0xff, 0x00}},
+	)
+
+	config := getConfig(service.HostForPort(9092), testTopic)
+	config["message_headers"] = true
+	f := mbtest.NewReportingMetricSetV2Error(t, config)
+	data, err := mbtest.ReportingFetchV2Error(f)
+	if err != nil {
+		t.Fatal("fetch", err)
+	}
+
+	var headers mapstr.M
+	for _, event := range data {
+		if v, err := event.MetricSetFields.GetValue("last_message.headers"); err == nil {
+			headers, _ = v.(mapstr.M)
+		}
+	}
+	assert.Equal(t, mapstr.M{"trace.id": "abc123", "binary": "/wA="}, headers)
+}
+
+func generateKafkaData(t *testing.T, host string, topic string, headers ...sarama.RecordHeader) {
 	t.Logf("Send Kafka Event to topic: %v", topic)
 
 	config := sarama.NewConfig()
@@ -151,8 +182,9 @@ func generateKafkaData(t *testing.T, host string, topic string) {
 	defer producer.Close()
 
 	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.StringEncoder("Hello World"),
+		Topic:   topic,
+		Value:   sarama.StringEncoder("Hello World"),
+		Headers: headers,
 	}
 
 	_, _, err = producer.SendMessage(msg)
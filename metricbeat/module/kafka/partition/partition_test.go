@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/metricbeat/mb"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/sarama"
+)
+
+func TestHeadersToMapStr(t *testing.T) {
+	headers := []*sarama.RecordHeader{
+		{Key: []byte("trace.id"), Value: []byte("abc123")},
+		{Key: []byte("binary"), Value: []byte{0xff, 0x00}},
+		{Key: []byte("empty")},
+		nil,
+	}
+
+	assert.Equal(t, mapstr.M{
+		"trace.id": "abc123",
+		"binary":   "/wA=",
+		"empty":    "",
+	}, headersToMapStr(headers))
+}
+
+func TestRateTracker(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(newest int64, seconds int) offsetSample {
+		return offsetSample{newest: newest, timestamp: start.Add(time.Duration(seconds) * time.Second)}
+	}
+	tracker := newRateTracker()
+
+	// First fetch, there are no previous offsets.
+	_, ok := tracker.rate("0-foo-1", sample(100, 0))
+	assert.False(t, ok)
+	_, ok = tracker.rate("1-foo-1", sample(500, 0))
+	assert.False(t, ok)
+	tracker.done()
+
+	// Second fetch, partition 1 was removed and partition 2 added.
+	rate, ok := tracker.rate("0-foo-1", sample(300, 10))
+	assert.True(t, ok)
+	assert.Equal(t, 20.0, rate)
+	_, ok = tracker.rate("2-foo-1", sample(50, 10))
+	assert.False(t, ok)
+	tracker.done()
+
+	// Third fetch, partition 1 is back but its old offsets were forgotten,
+	// and the topic of partition 0 was recreated.
+	_, ok = tracker.rate("1-foo-1", sample(600, 20))
+	assert.False(t, ok, "offsets of partitions missing in a fetch must be forgotten")
+	_, ok = tracker.rate("0-foo-1", sample(10, 20))
+	assert.False(t, ok, "offsets going backwards must not produce a rate")
+	rate, ok = tracker.rate("2-foo-1", sample(50, 20))
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, rate)
+	tracker.done()
+
+	// Fourth fetch, the recreated topic is tracked again.
+	rate, ok = tracker.rate("0-foo-1", sample(30, 25))
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, rate)
+}
+
+func TestAggregate(t *testing.T) {
+	broker := mapstr.M{"id": int32(1), "address": "localhost:9092"}
+	event := func(topic string, partition int32, leader bool, oldest, newest int64, rate float64) mb.Event {
+		fields := mapstr.M{
+			"id":        partition,
+			"partition": mapstr.M{"is_leader": leader},
+			"offset":    mapstr.M{"oldest": oldest, "newest": newest},
+		}
+		if rate > 0 {
+			fields["messages_per_second"] = rate
+		}
+		return mb.Event{
+			ModuleFields:    mapstr.M{"broker": broker, "topic": mapstr.M{"name": topic}},
+			MetricSetFields: fields,
+		}
+	}
+	m := &MetricSet{}
+
+	key, ok := m.AggregationKey(event("foo", 0, true, 0, 10, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "foo", key)
+	_, ok = m.AggregationKey(mb.Event{MetricSetFields: mapstr.M{"id": 0}})
+	assert.False(t, ok)
+
+	summary := m.Aggregate("foo", []mb.Event{
+		event("foo", 0, true, 10, 110, 2.5),
+		event("foo", 0, false, 10, 110, 2.5),
+		event("foo", 1, true, 0, 50, 0.5),
+	})
+	assert.Equal(t, mapstr.M{"broker": broker, "topic": mapstr.M{"name": "foo"}}, summary.ModuleFields)
+	assert.Equal(t, mapstr.M{
+		"summary": mapstr.M{
+			"partitions":          int64(2),
+			"messages":            int64(150),
+			"messages_per_second": 3.0,
+		},
+	}, summary.MetricSetFields)
+
+	// Without message rates.
+	summary = m.Aggregate("bar", []mb.Event{event("bar", 0, true, 0, 5, 0)})
+	assert.Equal(t, mapstr.M{
+		"summary": mapstr.M{
+			"partitions": int64(1),
+			"messages":   int64(5),
+		},
+	}, summary.MetricSetFields)
+}
@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import "time"
+
+// offsetSample is the newest offset of a partition replica at the time it
+// was queried.
+type offsetSample struct {
+	newest    int64
+	timestamp time.Time
+}
+
+// rateTracker computes the rate of messages produced to each partition
+// replica from the newest offsets of consecutive fetches.
+type rateTracker struct {
+	// Samples of the previous fetch.
+	previous map[string]offsetSample
+	// Samples of the current fetch.
+	current map[string]offsetSample
+}
+
+func newRateTracker() *rateTracker {
+	return &rateTracker{
+		previous: map[string]offsetSample{},
+		current:  map[string]offsetSample{},
+	}
+}
+
+// rate records the sample for key and returns the messages per second since
+// the previous fetch. It returns false if there is no sample of key from the
+// previous fetch, such as for new partitions, or if the offset went backwards,
+// such as when a topic is recreated.
+func (t *rateTracker) rate(key string, sample offsetSample) (float64, bool) {
+	t.current[key] = sample
+
+	prev, found := t.previous[key]
+	if !found || sample.newest < prev.newest {
+		return 0, false
+	}
+	elapsed := sample.timestamp.Sub(prev.timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return float64(sample.newest-prev.newest) / elapsed, true
+}
+
+// done finishes a fetch. Samples of partitions that were not part of the
+// fetch are dropped.
+func (t *rateTracker) done() {
+	t.previous = t.current
+	t.current = make(map[string]offsetSample, len(t.previous))
+}
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import (
+	"github.com/elastic/beats/v7/metricbeat/mb"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// AggregationKey groups the partition events by topic. Events without topic
+// name are not aggregated.
+func (m *MetricSet) AggregationKey(event mb.Event) (string, bool) {
+	name, err := event.ModuleFields.GetValue("topic.name")
+	if err != nil {
+		return "", false
+	}
+	topic, ok := name.(string)
+	return topic, ok
+}
+
+// Aggregate returns the summary event of a topic. Only the events of the
+// partition leaders are summarized, so every partition is counted once even
+// if events are reported for all its replicas.
+func (m *MetricSet) Aggregate(topic string, events []mb.Event) mb.Event {
+	var partitions, messages int64
+	var rate float64
+	hasRate := false
+	for _, event := range events {
+		isLeader, _ := event.MetricSetFields.GetValue("partition.is_leader")
+		if leader, _ := isLeader.(bool); !leader {
+			continue
+		}
+		partitions++
+
+		newest, _ := event.MetricSetFields.GetValue("offset.newest")
+		oldest, _ := event.MetricSetFields.GetValue("offset.oldest")
+		if newest, ok := newest.(int64); ok {
+			if oldest, ok := oldest.(int64); ok {
+				messages += newest - oldest
+			}
+		}
+
+		if r, err := event.MetricSetFields.GetValue("messages_per_second"); err == nil {
+			if r, ok := r.(float64); ok {
+				rate += r
+				hasRate = true
+			}
+		}
+	}
+
+	summary := mapstr.M{
+		"partitions": partitions,
+		"messages":   messages,
+	}
+	if hasRate {
+		summary["messages_per_second"] = rate
+	}
+
+	moduleFields := mapstr.M{
+		"topic": mapstr.M{"name": topic},
+	}
+	if len(events) > 0 {
+		if broker, found := events[0].ModuleFields["broker"]; found {
+			moduleFields["broker"] = broker
+		}
+	}
+
+	return mb.Event{
+		ModuleFields: moduleFields,
+		MetricSetFields: mapstr.M{
+			"summary": summary,
+		},
+	}
+}
@@ -169,8 +169,11 @@ func test(url *url.URL) error {
 }
 
 func runWithMetrics(ctx v2.Context, cfg config, pub inputcursor.Publisher, crsr *inputcursor.Cursor) error {
-	reg, unreg := inputmon.NewInputRegistry("httpjson", ctx.ID, nil)
+	reg, unreg, visible := inputmon.NewInputRegistryWithStatus("httpjson", ctx.ID, nil)
 	defer unreg()
+	if !visible {
+		ctx.Logger.Warn("input has no id, its metrics will not be available in the HTTP monitoring endpoint")
+	}
 	return run(ctx, cfg, pub, crsr, reg)
 }
 
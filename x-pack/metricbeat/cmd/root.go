@@ -51,6 +51,7 @@ func Initialize() *cmd.BeatsRootCmd {
 	rootCmd := cmd.GenRootCmdWithSettings(beater.DefaultCreator(), settings)
 	rootCmd.AddCommand(cmd.GenModulesCmd(Name, "", mbcmd.BuildModulesManager))
 	rootCmd.TestCmd.AddCommand(test.GenTestModulesCmd(Name, "", beater.DefaultTestModulesCreator()))
+	test.AddTestConfigCmd(rootCmd.TestCmd, settings, beater.DefaultCreator(), mbcmd.BuildModulesManager)
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		management.ConfigTransform.SetTransform(metricbeatCfg)
 	}